@@ -34,13 +34,14 @@ var helpText = func(colors logger.Colors) string {
   --bundle              Bundle all dependencies into the output files
   --define:K=V          Substitute K with V while parsing
   --external:M          Exclude module M from the bundle (can use * wildcards)
-  --format=...          Output format (iife | cjs | esm, no default when not
-                        bundling, otherwise default is iife when platform
-                        is browser and cjs when platform is node)
+  --format=...          Output format (iife | cjs | esm | system | umd, no
+                        default when not bundling, otherwise default is iife
+                        when platform is browser and cjs when platform is
+                        node)
   --loader:X=L          Use loader L to load file extension X, where L is
                         one of: base64 | binary | copy | css | dataurl |
-                        empty | file | global-css | js | json | jsx |
-                        local-css | text | ts | tsx
+                        empty | file | global-css | html | js | json |
+                        jsx | local-css | text | ts | tsx
   --minify              Minify the output (sets all --minify-* flags)
   --outdir=...          The output directory (for multiple entry points)
   --outfile=...         The output file (for one entry point)
@@ -65,11 +66,18 @@ var helpText = func(colors logger.Colors) string {
                             (default "[name]-[hash]")
   --banner:T=...            Text to be prepended to each output file of type T
                             where T is one of: css | js
+  --bigint-library=...      Convert BigInt literals and operators into calls
+                            into this JSBI-compatible module when the target
+                            doesn't support BigInt
+  --cache-dir=...           Reuse parsed files from earlier builds by storing
+                            them in this directory
   --certfile=...            Certificate for serving HTTPS (see also "--keyfile")
   --charset=utf8            Do not escape UTF-8 code points
   --chunk-names=...         Path template to use for code splitting chunks
                             (default "[name]-[hash]")
   --color=...               Force use of color terminal escapes (true | false)
+  --css-module-types=...    Write TypeScript types next to each CSS module
+                            imported from JS (none | d.ts | d.css.ts)
   --cors-origin=...         Allow cross-origin requests from this origin
   --drop:...                Remove certain constructs (console | debugger)
   --drop-labels=...         Remove labeled statements with these label names
@@ -77,7 +85,9 @@ var helpText = func(colors logger.Colors) string {
                             (default "[dir]/[name]", can also use "[hash]")
   --footer:T=...            Text to be appended to each output file of type T
                             where T is one of: css | js
-  --global-name=...         The name of the global for the IIFE format
+  --global-name=...         The name of the global for the IIFE and UMD formats
+  --hmr                     Swap out changed modules without reloading the page
+                            when used with "--serve" (requires --bundle)
   --ignore-annotations      Enable this to work with packages that have
                             incorrect tree-shaking annotations
   --inject:F                Import the file F into all input files and
@@ -96,16 +106,22 @@ var helpText = func(colors logger.Colors) string {
                             eof | linked | external, default eof when bundling
                             and inline otherwise)
   --line-limit=...          Lines longer than this will be wrap onto a new line
+  --local-css-names=...     Name template to use for "local-css" loader names
+                            (placeholders: [dir] [name] [local] [hash])
   --log-level=...           Disable logging (verbose | debug | info | warning |
                             error | silent, default info)
   --log-limit=...           Maximum message count or 0 to disable (default 6)
   --log-override:X=Y        Use log level Y for log messages with identifier X
+  --lower-top-level-await   Allow top-level await with the "cjs" and "iife"
+                            formats using async module initializers
   --main-fields=...         Override the main file order in package.json
                             (default "browser,module,main" when platform is
                             browser and "main,module" when platform is node)
   --mangle-cache=...        Save "mangle props" decisions to a JSON file
   --mangle-props=...        Rename all properties matching a regular expression
   --mangle-quoted=...       Enable renaming of quoted properties (true | false)
+  --manual-chunk:N=P        Put files matching P (a package name or a path glob)
+                            into a chunk named N (requires --splitting)
   --metafile=...            Write metadata about the build to a JSON file
                             (see also: ` + colors.Underline + `https://esbuild.github.io/analyze/` + colors.Reset + `)
   --minify-whitespace       Remove whitespace in output files
@@ -131,6 +147,9 @@ var helpText = func(colors logger.Colors) string {
   --tree-shaking=...        Force tree shaking on or off (false | true)
   --tsconfig=...            Use this tsconfig.json file instead of other ones
   --tsconfig-raw=...        Override all tsconfig.json files with this string
+  --umd-external:M=G        Load external module M from the global variable G
+                            with the UMD format (can also be a list such as
+                            "amd:id,cjs:name,global:G")
   --version                 Print the current version (` + esbuildVersion + `) and exit
   --watch-delay=...         Wait before watch mode rebuilds (in milliseconds)
 
@@ -158,6 +177,7 @@ var helpText = func(colors logger.Colors) string {
 
 func main() {
 	logger.API = logger.CLIAPI
+	api_helpers.ESBuildVersion = esbuildVersion
 
 	osArgs := os.Args[1:]
 	heapFile := ""
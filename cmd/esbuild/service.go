@@ -813,6 +813,12 @@ func resolveKindToString(kind api.ResolveKind) string {
 	case api.ResolveCSSURLToken:
 		return "url-token"
 
+	// HTML
+	case api.ResolveHTMLScriptTag:
+		return "script-tag"
+	case api.ResolveHTMLLinkTag:
+		return "link-tag"
+
 	default:
 		panic("Internal error")
 	}
@@ -840,6 +846,12 @@ func stringToResolveKind(kind string) (api.ResolveKind, bool) {
 		return api.ResolveCSSComposesFrom, true
 	case "url-token":
 		return api.ResolveCSSURLToken, true
+
+	// HTML
+	case "script-tag":
+		return api.ResolveHTMLScriptTag, true
+	case "link-tag":
+		return api.ResolveHTMLLinkTag, true
 	}
 
 	return api.ResolveNone, false
@@ -855,6 +867,10 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 
 	var onResolveCallbacks []filteredCallback
 	var onLoadCallbacks []filteredCallback
+	var onTransformCallbacks []filteredCallback
+	var onManualChunkCallbacks []filteredCallback
+	var onOutputCallbacks []filteredCallback
+	var onLocalCSSNameCallbacks []filteredCallback
 	hasOnEnd := false
 
 	filteredCallbacks := func(pluginName string, kind string, items []interface{}) (result []filteredCallback, err error) {
@@ -864,11 +880,12 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 			if err != nil {
 				return nil, err
 			}
+			namespace, _ := item["namespace"].(string) // Not all callbacks have a namespace
 			result = append(result, filteredCallback{
 				pluginName: pluginName,
 				id:         item["id"].(int),
 				filter:     filter,
-				namespace:  item["namespace"].(string),
+				namespace:  namespace,
 			})
 		}
 		return
@@ -893,6 +910,30 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 		} else {
 			onLoadCallbacks = append(onLoadCallbacks, callbacks...)
 		}
+
+		if callbacks, err := filteredCallbacks(pluginName, "onTransform", p["onTransform"].([]interface{})); err != nil {
+			return nil, false, err
+		} else {
+			onTransformCallbacks = append(onTransformCallbacks, callbacks...)
+		}
+
+		if callbacks, err := filteredCallbacks(pluginName, "onManualChunk", p["onManualChunk"].([]interface{})); err != nil {
+			return nil, false, err
+		} else {
+			onManualChunkCallbacks = append(onManualChunkCallbacks, callbacks...)
+		}
+
+		if callbacks, err := filteredCallbacks(pluginName, "onOutput", p["onOutput"].([]interface{})); err != nil {
+			return nil, false, err
+		} else {
+			onOutputCallbacks = append(onOutputCallbacks, callbacks...)
+		}
+
+		if callbacks, err := filteredCallbacks(pluginName, "onLocalCSSName", p["onLocalCSSName"].([]interface{})); err != nil {
+			return nil, false, err
+		} else {
+			onLocalCSSNameCallbacks = append(onLocalCSSNameCallbacks, callbacks...)
+		}
 	}
 
 	// We want to minimize the amount of IPC traffic. Instead of adding one Go
@@ -1147,6 +1188,220 @@ func (service *serviceType) convertPlugins(key int, jsPlugins interface{}, activ
 					return result, nil
 				})
 			}
+
+			// Each "OnTransform" callback is registered separately so that the source
+			// maps returned by each one are chained together in order
+			for _, item := range onTransformCallbacks {
+				item := item
+				build.OnTransform(api.OnTransformOptions{Filter: item.filter.String(), Namespace: item.namespace}, func(args api.OnTransformArgs) (api.OnTransformResult, error) {
+					result := api.OnTransformResult{PluginName: item.pluginName}
+					request := map[string]interface{}{
+						"command":   "on-transform",
+						"key":       key,
+						"id":        item.id,
+						"path":      args.Path,
+						"namespace": args.Namespace,
+						"suffix":    args.Suffix,
+						"contents":  args.Contents,
+						"loader":    cli_helpers.LoaderToString(args.Loader),
+					}
+					if args.SourceMap != "" {
+						request["sourceMap"] = args.SourceMap
+					}
+					response, ok := service.sendRequest(request).(map[string]interface{})
+					if !ok {
+						return result, errors.New("The service was stopped")
+					}
+
+					if value, ok := response["error"]; ok {
+						return result, errors.New(value.(string))
+					}
+					if value, ok := response["pluginName"]; ok {
+						result.PluginName = value.(string)
+					}
+					if value, ok := response["contents"]; ok {
+						contents := value.(string)
+						result.Contents = &contents
+					}
+					if value, ok := response["sourceMap"]; ok {
+						sourceMap := value.(string)
+						result.SourceMap = &sourceMap
+					}
+					if value, ok := response["errors"]; ok {
+						result.Errors = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["warnings"]; ok {
+						result.Warnings = decodeMessages(value.([]interface{}))
+					}
+
+					return result, nil
+				})
+			}
+
+			// Only register "OnManualChunk" if needed
+			if len(onManualChunkCallbacks) > 0 {
+				build.OnManualChunk(api.OnManualChunkOptions{Filter: ".*"}, func(args api.OnManualChunkArgs) (api.OnManualChunkResult, error) {
+					var ids []interface{}
+					applyPath := logger.Path{Text: args.Path, Namespace: args.Namespace}
+					for _, item := range onManualChunkCallbacks {
+						if config.PluginAppliesToPath(applyPath, item.filter, item.namespace) {
+							ids = append(ids, item.id)
+						}
+					}
+
+					result := api.OnManualChunkResult{}
+					if len(ids) == 0 {
+						return result, nil
+					}
+
+					response, ok := service.sendRequest(map[string]interface{}{
+						"command":   "on-manual-chunk",
+						"key":       key,
+						"ids":       ids,
+						"path":      args.Path,
+						"namespace": args.Namespace,
+					}).(map[string]interface{})
+					if !ok {
+						return result, errors.New("The service was stopped")
+					}
+
+					if value, ok := response["id"]; ok {
+						id := value.(int)
+						for _, item := range onManualChunkCallbacks {
+							if item.id == id {
+								result.PluginName = item.pluginName
+								break
+							}
+						}
+					}
+					if value, ok := response["error"]; ok {
+						return result, errors.New(value.(string))
+					}
+					if value, ok := response["pluginName"]; ok {
+						result.PluginName = value.(string)
+					}
+					if value, ok := response["chunkName"]; ok {
+						result.ChunkName = value.(string)
+					}
+					if value, ok := response["errors"]; ok {
+						result.Errors = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["warnings"]; ok {
+						result.Warnings = decodeMessages(value.([]interface{}))
+					}
+
+					return result, nil
+				})
+			}
+
+			// Only register "OnLocalCSSName" if needed
+			if len(onLocalCSSNameCallbacks) > 0 {
+				build.OnLocalCSSName(api.OnLocalCSSNameOptions{Filter: ".*"}, func(args api.OnLocalCSSNameArgs) (api.OnLocalCSSNameResult, error) {
+					var ids []interface{}
+					applyPath := logger.Path{Text: args.Path, Namespace: args.Namespace}
+					for _, item := range onLocalCSSNameCallbacks {
+						if config.PluginAppliesToPath(applyPath, item.filter, item.namespace) {
+							ids = append(ids, item.id)
+						}
+					}
+
+					result := api.OnLocalCSSNameResult{}
+					if len(ids) == 0 {
+						return result, nil
+					}
+
+					response, ok := service.sendRequest(map[string]interface{}{
+						"command":   "on-local-css-name",
+						"key":       key,
+						"ids":       ids,
+						"path":      args.Path,
+						"namespace": args.Namespace,
+						"local":     args.Local,
+					}).(map[string]interface{})
+					if !ok {
+						return result, errors.New("The service was stopped")
+					}
+
+					if value, ok := response["id"]; ok {
+						id := value.(int)
+						for _, item := range onLocalCSSNameCallbacks {
+							if item.id == id {
+								result.PluginName = item.pluginName
+								break
+							}
+						}
+					}
+					if value, ok := response["error"]; ok {
+						return result, errors.New(value.(string))
+					}
+					if value, ok := response["pluginName"]; ok {
+						result.PluginName = value.(string)
+					}
+					if value, ok := response["name"]; ok {
+						result.Name = value.(string)
+					}
+					if value, ok := response["errors"]; ok {
+						result.Errors = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["warnings"]; ok {
+						result.Warnings = decodeMessages(value.([]interface{}))
+					}
+
+					return result, nil
+				})
+			}
+
+			// Each "OnOutput" callback is registered separately so that the source
+			// maps returned by each one are chained together in order
+			for _, item := range onOutputCallbacks {
+				item := item
+				build.OnOutput(api.OnOutputOptions{Filter: item.filter.String()}, func(args api.OnOutputArgs) (api.OnOutputResult, error) {
+					result := api.OnOutputResult{PluginName: item.pluginName}
+					modules := make([]interface{}, len(args.Modules))
+					for i, module := range args.Modules {
+						modules[i] = module
+					}
+					request := map[string]interface{}{
+						"command":    "on-output",
+						"key":        key,
+						"id":         item.id,
+						"path":       args.Path,
+						"contents":   args.Contents,
+						"entryPoint": args.EntryPoint,
+						"modules":    modules,
+					}
+					if args.SourceMap != "" {
+						request["sourceMap"] = args.SourceMap
+					}
+					response, ok := service.sendRequest(request).(map[string]interface{})
+					if !ok {
+						return result, errors.New("The service was stopped")
+					}
+
+					if value, ok := response["error"]; ok {
+						return result, errors.New(value.(string))
+					}
+					if value, ok := response["pluginName"]; ok {
+						result.PluginName = value.(string)
+					}
+					if value, ok := response["contents"]; ok {
+						contents := value.(string)
+						result.Contents = &contents
+					}
+					if value, ok := response["sourceMap"]; ok {
+						sourceMap := value.(string)
+						result.SourceMap = &sourceMap
+					}
+					if value, ok := response["errors"]; ok {
+						result.Errors = decodeMessages(value.([]interface{}))
+					}
+					if value, ok := response["warnings"]; ok {
+						result.Warnings = decodeMessages(value.([]interface{}))
+					}
+
+					return result, nil
+				})
+			}
 		},
 	}}, hasOnEnd, nil
 }
@@ -1,7 +1,7 @@
 // This file processes data from https://caniuse.com
 
 import lite = require('caniuse-lite')
-import { CSSFeature, CSSPrefixMap, CSSProperty, Engine, JSFeature, PrefixData, Support, SupportMap } from './index'
+import { CSSFeature, CSSPrefixedSyntax, CSSPrefixMap, CSSProperty, CSSSyntaxPrefixMap, Engine, JSFeature, PrefixData, Support, SupportMap } from './index'
 
 const enum StatusCode {
   Almost = 'a',
@@ -47,9 +47,23 @@ const cssPrefixFeatures: Record<string, CSSProperty> = {
   'text-size-adjust': 'DTextSizeAdjust',
 }
 
+const cssSyntaxPrefixFeatures: Record<string, CSSPrefixedSyntax> = {
+  'css-animation': 'AtKeyframes',
+  'css-any-link': 'PseudoAnyLink',
+  'css-cross-fade': 'CrossFadeFunction',
+  'css-gradients': 'GradientFunctions',
+  'css-image-set': 'ImageSetFunction',
+  'css-placeholder': 'PseudoPlaceholder',
+  'css-placeholder-shown': 'PseudoPlaceholderShown',
+  'css-selection': 'PseudoSelection',
+  'flexbox': 'DisplayFlex',
+  'fullscreen': 'PseudoFullscreen',
+}
+
 export const js: SupportMap<JSFeature> = {} as SupportMap<JSFeature>
 export const css: SupportMap<CSSFeature> = {} as SupportMap<CSSFeature>
 export const cssPrefix: CSSPrefixMap = {}
+export const cssSyntaxPrefix: CSSSyntaxPrefixMap = {}
 
 const compareVersions = (aStr: string, bStr: string): number => {
   const a = aStr.split('.')
@@ -97,52 +111,57 @@ const addFeatures = <F extends string>(map: SupportMap<F>, features: Record<stri
 addFeatures(js, jsFeatures)
 addFeatures(css, cssFeatures)
 
-for (const feature in cssPrefixFeatures) {
-  const prefixData: PrefixData[] = []
-  const entry = lite.feature(lite.features[feature])
-
-  for (const agent in entry.stats) {
-    const engine = supportedAgents[agent]
-    if (!engine) continue
-
-    const model = lite.agents[agent]!
-    const versionRanges = entry.stats[agent]
-    const sortedVersions: { version: string, prefix: string | null }[] = []
-    const prefixes = new Set<string>()
-
-    for (const versionRange in versionRanges) {
-      const statusCodes = versionRanges[versionRange].split(' ')
-      const prefix = statusCodes.includes(StatusCode.Prefix)
-        ? (model.prefix_exceptions && model.prefix_exceptions[versionRange]) || model.prefix
-        : null
-      for (const version of versionRange.split('-')) {
-        // Filter out bogus versions such as "TP" for Safari
-        if (/^\d+(\.\d+)*$/.test(version)) {
-          sortedVersions.push({ version, prefix })
+const addPrefixFeatures = <K extends string>(map: Partial<Record<K, PrefixData[]>>, features: Record<string, K>): void => {
+  for (const feature in features) {
+    const prefixData: PrefixData[] = []
+    const entry = lite.feature(lite.features[feature])
+
+    for (const agent in entry.stats) {
+      const engine = supportedAgents[agent]
+      if (!engine) continue
+
+      const model = lite.agents[agent]!
+      const versionRanges = entry.stats[agent]
+      const sortedVersions: { version: string, prefix: string | null }[] = []
+      const prefixes = new Set<string>()
+
+      for (const versionRange in versionRanges) {
+        const statusCodes = versionRanges[versionRange].split(' ')
+        const prefix = statusCodes.includes(StatusCode.Prefix)
+          ? (model.prefix_exceptions && model.prefix_exceptions[versionRange]) || model.prefix
+          : null
+        for (const version of versionRange.split('-')) {
+          // Filter out bogus versions such as "TP" for Safari
+          if (/^\d+(\.\d+)*$/.test(version)) {
+            sortedVersions.push({ version, prefix })
+          }
+        }
+        if (prefix !== null) {
+          prefixes.add(prefix)
         }
       }
-      if (prefix !== null) {
-        prefixes.add(prefix)
-      }
-    }
 
-    sortedVersions.sort((a, b) => compareVersions(a.version, b.version))
+      sortedVersions.sort((a, b) => compareVersions(a.version, b.version))
 
-    for (const prefix of prefixes) {
-      // Find the version after the latest version that requires the prefix (if there even is one)
-      let i = sortedVersions.length
-      while (i > 0 && sortedVersions[i - 1].prefix !== prefix) {
-        i--
-      }
+      for (const prefix of prefixes) {
+        // Find the version after the latest version that requires the prefix (if there even is one)
+        let i = sortedVersions.length
+        while (i > 0 && sortedVersions[i - 1].prefix !== prefix) {
+          i--
+        }
 
-      // Add an entry for this prefix combination
-      const result: PrefixData = { engine, prefix }
-      if (i < sortedVersions.length) {
-        result.withoutPrefix = sortedVersions[i].version.split('.').map(x => +x)
+        // Add an entry for this prefix combination
+        const result: PrefixData = { engine, prefix }
+        if (i < sortedVersions.length) {
+          result.withoutPrefix = sortedVersions[i].version.split('.').map(x => +x)
+        }
+        prefixData.push(result)
       }
-      prefixData.push(result)
     }
-  }
 
-  cssPrefix[cssPrefixFeatures[feature]] = prefixData
+    map[features[feature]] = prefixData
+  }
 }
+
+addPrefixFeatures(cssPrefix, cssPrefixFeatures)
+addPrefixFeatures(cssSyntaxPrefix, cssSyntaxPrefixFeatures)
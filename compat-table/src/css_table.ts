@@ -1,7 +1,7 @@
 // This file generates "internal/compat/css_table.go"
 
 import fs = require('fs')
-import { Engine, CSSFeature, VersionRange, VersionRangeMap, CSSPrefixMap, PrefixData, CSSProperty } from './index'
+import { Engine, CSSFeature, VersionRange, VersionRangeMap, CSSPrefixMap, PrefixData, CSSProperty, CSSSyntaxPrefixMap, CSSPrefixedSyntax } from './index'
 
 const cssFeatureString = (feature: string): string => {
   return feature.replace(/([A-Z]+)/g, '-$1').slice(1).toLowerCase().replace(/[-_]+/g, '-')
@@ -49,10 +49,10 @@ const cssPrefixMap = (entries: PrefixData[]) => {
 
 const generatedByComment = `// This file was automatically generated by "css_table.ts"`
 
-export const generateTableForCSS = (map: VersionRangeMap<CSSFeature>, prefixes: CSSPrefixMap): void => {
+export const generateTableForCSS = (map: VersionRangeMap<CSSFeature>, prefixes: CSSPrefixMap, syntaxPrefixes: CSSSyntaxPrefixMap): void => {
   const prefixNames = new Set<string>()
-  for (const property in prefixes) {
-    for (const { prefix } of prefixes[property as CSSProperty]!) {
+  for (const entries of [...Object.values(prefixes), ...Object.values(syntaxPrefixes)]) {
+    for (const { prefix } of entries!) {
       prefixNames.add(cssPrefixName(prefix))
     }
   }
@@ -66,7 +66,7 @@ import (
 \t"github.com/evanw/esbuild/internal/css_ast"
 )
 
-type CSSFeature uint16
+type CSSFeature uint32
 
 const (
 ${Object.keys(map).sort().map((feature, i) => `\t${feature}${i ? '' : ' CSSFeature = 1 << iota'}`).join('\n')}
@@ -133,19 +133,7 @@ ${Object.keys(prefixes).sort().map(property => `\tcss_ast.${property}: ${cssPref
 
 func CSSPrefixData(constraints map[Engine]Semver) (entries map[css_ast.D]CSSPrefix) {
 \tfor property, items := range cssPrefixTable {
-\t\tprefixes := NoPrefix
-\t\tfor engine, version := range constraints {
-\t\t\tif !engine.IsBrowser() {
-\t\t\t\t// Specifying "--target=es2020" shouldn't affect CSS
-\t\t\t\tcontinue
-\t\t\t}
-\t\t\tfor _, item := range items {
-\t\t\t\tif item.engine == engine && (item.withoutPrefix == v{} || compareVersions(item.withoutPrefix, version) > 0) {
-\t\t\t\t\tprefixes |= item.prefix
-\t\t\t\t}
-\t\t\t}
-\t\t}
-\t\tif prefixes != NoPrefix {
+\t\tif prefixes := prefixesForConstraints(items, constraints); prefixes != NoPrefix {
 \t\t\tif entries == nil {
 \t\t\t\tentries = make(map[css_ast.D]CSSPrefix)
 \t\t\t}
@@ -154,5 +142,44 @@ func CSSPrefixData(constraints map[Engine]Semver) (entries map[css_ast.D]CSSPref
 \t}
 \treturn
 }
+
+// These are CSS features other than properties that may need vendor prefixes
+type CSSPrefixedSyntax uint8
+
+const (
+${Object.keys(syntaxPrefixes).sort().map((syntax, i) => `\t${syntax}${i ? '' : ' CSSPrefixedSyntax = iota'}`).join('\n')}
+)
+
+var cssSyntaxPrefixTable = map[CSSPrefixedSyntax][]prefixData{
+${Object.keys(syntaxPrefixes).sort().map(syntax => `\t${syntax}: ${cssPrefixMap(syntaxPrefixes[syntax as CSSPrefixedSyntax]!)},`).join('\n')}
+}
+
+func CSSSyntaxPrefixData(constraints map[Engine]Semver) (entries map[CSSPrefixedSyntax]CSSPrefix) {
+\tfor syntax, items := range cssSyntaxPrefixTable {
+\t\tif prefixes := prefixesForConstraints(items, constraints); prefixes != NoPrefix {
+\t\t\tif entries == nil {
+\t\t\t\tentries = make(map[CSSPrefixedSyntax]CSSPrefix)
+\t\t\t}
+\t\t\tentries[syntax] = prefixes
+\t\t}
+\t}
+\treturn
+}
+
+func prefixesForConstraints(items []prefixData, constraints map[Engine]Semver) CSSPrefix {
+\tprefixes := NoPrefix
+\tfor engine, version := range constraints {
+\t\tif !engine.IsBrowser() {
+\t\t\t// Specifying "--target=es2020" shouldn't affect CSS
+\t\t\tcontinue
+\t\t}
+\t\tfor _, item := range items {
+\t\t\tif item.engine == engine && (item.withoutPrefix == v{} || compareVersions(item.withoutPrefix, version) > 0) {
+\t\t\t\tprefixes |= item.prefix
+\t\t\t}
+\t\t}
+\t}
+\treturn prefixes
+}
 `)
 }
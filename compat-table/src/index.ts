@@ -91,7 +91,11 @@ export const jsFeatures = {
 
 export type CSSFeature = keyof typeof cssFeatures
 export const cssFeatures = {
+  AtScope: true,
+  CascadeLayers: true,
   ColorFunctions: true,
+  ColorMix: true,
+  CustomMedia: true,
   GradientDoublePosition: true,
   GradientInterpolation: true,
   GradientMidpoints: true,
@@ -100,9 +104,12 @@ export const cssFeatures = {
   InlineStyle: true,
   InsetProperty: true,
   IsPseudoClass: true,
+  LightDark: true,
+  MediaRange: true,
   Modern_RGB_HSL: true,
   Nesting: true,
   RebeccaPurple: true,
+  RelativeColors: true,
 }
 
 export type CSSProperty = keyof typeof cssProperties
@@ -141,6 +148,24 @@ export const cssProperties = {
   DWidth: true,
 }
 
+export type CSSPrefixedSyntax = keyof typeof cssPrefixedSyntaxes
+export const cssPrefixedSyntaxes = {
+  AtKeyframes: true,
+  CrossFadeFunction: true,
+  DisplayFlex: true,
+  GradientFunctions: true,
+  ImageSetFunction: true,
+  PseudoAnyLink: true,
+  PseudoBackdrop: true,
+  PseudoFileSelectorButton: true,
+  PseudoFullscreen: true,
+  PseudoPlaceholder: true,
+  PseudoPlaceholderShown: true,
+  PseudoReadOnly: true,
+  PseudoReadWrite: true,
+  PseudoSelection: true,
+}
+
 export interface Support {
   force?: boolean
   passed?: number
@@ -162,6 +187,7 @@ export type SupportMap<F extends string> = Record<F, Partial<Record<Engine, Reco
 export type VersionRangeMap<F extends string> = Partial<Record<F, Partial<Record<Engine, VersionRange[]>>>>
 export type WhyNotMap<F extends string> = Partial<Record<F, Partial<Record<Engine, string[]>>>>
 export type CSSPrefixMap = Partial<Record<CSSProperty, PrefixData[]>>
+export type CSSSyntaxPrefixMap = Partial<Record<CSSPrefixedSyntax, PrefixData[]>>
 
 const compareVersions = (a: number[], b: number[]): number => {
   let diff = a[0] - b[0]
@@ -194,12 +220,12 @@ const mergeSupportMaps = <F extends string>(to: SupportMap<F>, from: SupportMap<
   }
 }
 
-const mergePrefixMaps = (to: CSSPrefixMap, from: CSSPrefixMap): void => {
-  for (const property in from) {
-    if (property in to) {
-      throw new Error(`Merge conflict with property=${property}`)
+const mergePrefixMaps = <K extends string>(to: Partial<Record<K, PrefixData[]>>, from: Partial<Record<K, PrefixData[]>>): void => {
+  for (const key in from) {
+    if (key in to) {
+      throw new Error(`Merge conflict with key=${key}`)
     }
-    to[property as CSSProperty] = from[property as CSSProperty]
+    to[key] = from[key]
   }
 }
 
@@ -551,8 +577,12 @@ mergeSupportMaps(css, mdn.css)
 mergePrefixMaps(cssPrefix, caniuse.cssPrefix)
 mergePrefixMaps(cssPrefix, mdn.cssPrefix)
 
+const cssSyntaxPrefix: CSSSyntaxPrefixMap = {}
+mergePrefixMaps(cssSyntaxPrefix, caniuse.cssSyntaxPrefix)
+mergePrefixMaps(cssSyntaxPrefix, mdn.cssSyntaxPrefix)
+
 // MDN data is wrong here, Firefox 127 still has gradient interpolation rendering bugs: https://bugzilla.mozilla.org/show_bug.cgi?id=1904106
 css.GradientInterpolation.Firefox = {}
 
 const [cssVersionRanges] = supportMapToVersionRanges(css)
-generateTableForCSS(cssVersionRanges, cssPrefix)
+generateTableForCSS(cssVersionRanges, cssPrefix, cssSyntaxPrefix)
@@ -1,7 +1,7 @@
 // This file processes data from https://developer.mozilla.org/en-US/docs/Web
 
 import bcd, { BrowserName, SupportBlock } from '@mdn/browser-compat-data'
-import { CSSFeature, CSSPrefixMap, CSSProperty, Engine, JSFeature, PrefixData, Support, SupportMap } from './index'
+import { CSSFeature, CSSPrefixedSyntax, CSSPrefixMap, CSSProperty, CSSSyntaxPrefixMap, Engine, JSFeature, PrefixData, Support, SupportMap } from './index'
 
 const supportedEnvironments: Record<string, Engine> = {
   chrome: 'Chrome',
@@ -26,6 +26,8 @@ const jsFeatures: Partial<Record<JSFeature, string>> = {
 }
 
 const cssFeatures: Partial<Record<CSSFeature, string | string[]>> = {
+  AtScope: 'css.at-rules.scope',
+  CascadeLayers: 'css.at-rules.layer',
   ColorFunctions: [
     'css.types.color.color',
     'css.types.color.lab',
@@ -33,6 +35,7 @@ const cssFeatures: Partial<Record<CSSFeature, string | string[]>> = {
     'css.types.color.oklab',
     'css.types.color.oklch',
   ],
+  ColorMix: 'css.types.color.color-mix',
   GradientDoublePosition: [
     'css.types.gradient.conic-gradient.doubleposition',
     'css.types.gradient.linear-gradient.doubleposition',
@@ -63,6 +66,8 @@ const cssFeatures: Partial<Record<CSSFeature, string | string[]>> = {
   HexRGBA: 'css.types.color.rgb_hexadecimal_notation.alpha_hexadecimal_notation',
   HWB: 'css.types.color.hwb',
   InsetProperty: 'css.properties.inset',
+  LightDark: 'css.types.color.light-dark',
+  MediaRange: 'css.at-rules.media.range_syntax',
   Modern_RGB_HSL: [
     'css.types.color.hsl.alpha_parameter',
     'css.types.color.hsl.space_separated_parameters',
@@ -72,6 +77,15 @@ const cssFeatures: Partial<Record<CSSFeature, string | string[]>> = {
   ],
   Nesting: 'css.selectors.nesting',
   RebeccaPurple: 'css.types.color.named-color.rebeccapurple',
+  RelativeColors: [
+    'css.types.color.hsl.relative_syntax',
+    'css.types.color.hwb.relative_syntax',
+    'css.types.color.lab.relative_syntax',
+    'css.types.color.lch.relative_syntax',
+    'css.types.color.oklab.relative_syntax',
+    'css.types.color.oklch.relative_syntax',
+    'css.types.color.rgb.relative_syntax',
+  ],
 }
 
 const similarPrefixedProperty: Record<string, { prefix: string, property: string }> = {
@@ -103,14 +117,31 @@ const cssPrefixFeatures: Record<string, CSSProperty> = {
   'css.properties.width.stretch': 'DWidth',
 }
 
+const cssSyntaxPrefixFeatures: Record<string, CSSPrefixedSyntax> = {
+  'css.selectors.backdrop': 'PseudoBackdrop',
+  'css.selectors.file-selector-button': 'PseudoFileSelectorButton',
+  'css.selectors.read-only': 'PseudoReadOnly',
+  'css.selectors.read-write': 'PseudoReadWrite',
+}
+
 const alternativeNameToPrefix: Record<string, string> = {
   '-webkit-fill-available': '-webkit-',
   '-moz-available': '-moz-',
 }
 
+// Selectors such as "::-webkit-file-upload-button" are listed by MDN as
+// alternative names instead of as prefixes
+const prefixForAlternativeName = (name: string): string => {
+  if (name in alternativeNameToPrefix) return alternativeNameToPrefix[name]
+  const match = /^:*(-[a-z]+-)/.exec(name)
+  if (!match) throw new Error(`Unexpected alternative name "${name}"`)
+  return match[1]
+}
+
 export const js: SupportMap<JSFeature> = {} as SupportMap<JSFeature>
 export const css: SupportMap<CSSFeature> = {} as SupportMap<CSSFeature>
 export const cssPrefix: CSSPrefixMap = {}
+export const cssSyntaxPrefix: CSSSyntaxPrefixMap = {}
 
 const isSemver = /^\d+(?:\.\d+(?:\.\d+)?)?$/
 
@@ -180,103 +211,108 @@ const addFeatures = <F extends string>(map: SupportMap<F>, features: Partial<Rec
 addFeatures(js, jsFeatures)
 addFeatures(css, cssFeatures)
 
-for (const fullKey in cssPrefixFeatures) {
-  const prefixData: PrefixData[] = []
-  const support: SupportBlock = extractProperty(bcd, fullKey).__compat.support
-
-  for (const env in support) {
-    const engine = supportedEnvironments[env]
-
-    if (engine) {
-      let entries = support[env as BrowserName]!
-      if (!Array.isArray(entries)) entries = [entries]
-
-      // Figure out which version this property can be used unprefixed, if any.
-      // This assumes that support for these CSS properties is never removed.
-      // This assumption is wrong (Edge removed many features when it changed
-      // its engine from EdgeHTML to Blink, basically becoming another browser)
-      // but we ignore those cases for now.
-      let version_unprefixed: string | undefined
-      for (const { prefix, flags, version_added, version_removed, alternative_name } of entries) {
-        if (!prefix && !alternative_name && !flags && typeof version_added === 'string' && !version_removed && isSemver.test(version_added)) {
-          version_unprefixed = version_added
+const addPrefixFeatures = <K extends string>(map: Partial<Record<K, PrefixData[]>>, features: Record<string, K>): void => {
+  for (const fullKey in features) {
+    const prefixData: PrefixData[] = []
+    const support: SupportBlock = extractProperty(bcd, fullKey).__compat.support
+
+    for (const env in support) {
+      const engine = supportedEnvironments[env]
+
+      if (engine) {
+        let entries = support[env as BrowserName]!
+        if (!Array.isArray(entries)) entries = [entries]
+
+        // Figure out which version this property can be used unprefixed, if any.
+        // This assumes that support for these CSS properties is never removed.
+        // This assumption is wrong (Edge removed many features when it changed
+        // its engine from EdgeHTML to Blink, basically becoming another browser)
+        // but we ignore those cases for now.
+        let version_unprefixed: string | undefined
+        for (const { prefix, flags, version_added, version_removed, alternative_name } of entries) {
+          if (!prefix && !alternative_name && !flags && typeof version_added === 'string' && !version_removed && isSemver.test(version_added)) {
+            version_unprefixed = version_added
+          }
         }
-      }
 
-      type PrefixRange = { prefix: string, start: string, end?: string }
-      const ranges: PrefixRange[] = []
-
-      // The MDN dataset sometimes doesn't list prefixes if the values for the
-      // prefixed property are sufficiently different. In that case, we may need
-      // to search for the prefix information within another property instead.
-      const similar = similarPrefixedProperty[fullKey]
-      if (similar) {
-        const similarSupport: SupportBlock = extractProperty(bcd, similar.property).__compat.support
-        const similarEntries = similarSupport[env as BrowserName]
-        if (!similarEntries) continue
-        entries = Array.isArray(similarEntries) ? similarEntries : [similarEntries]
-      }
-
-      // Find all version ranges where a given prefix is supported
-      for (let i = 0; i < entries.length; i++) {
-        let { prefix, flags, version_added, version_removed, alternative_name } = entries[i]
+        type PrefixRange = { prefix: string, start: string, end?: string }
+        const ranges: PrefixRange[] = []
 
+        // The MDN dataset sometimes doesn't list prefixes if the values for the
+        // prefixed property are sufficiently different. In that case, we may need
+        // to search for the prefix information within another property instead.
+        const similar = similarPrefixedProperty[fullKey]
         if (similar) {
-          if (prefix) throw new Error(`Unexpected prefix "${prefix}" for similar property "${similar.property}"`)
-          prefix = similar.prefix
+          const similarSupport: SupportBlock = extractProperty(bcd, similar.property).__compat.support
+          const similarEntries = similarSupport[env as BrowserName]
+          if (!similarEntries) continue
+          entries = Array.isArray(similarEntries) ? similarEntries : [similarEntries]
         }
 
-        if ((prefix || alternative_name) && !flags && typeof version_added === 'string' && isSemver.test(version_added)) {
-          const range: PrefixRange = { prefix: prefix || alternativeNameToPrefix[alternative_name!], start: version_added }
-          let withoutPrefix: string | undefined
+        // Find all version ranges where a given prefix is supported
+        for (let i = 0; i < entries.length; i++) {
+          let { prefix, flags, version_added, version_removed, alternative_name } = entries[i]
 
-          // The prefix is no longer needed if support for the feature was removed
-          if (typeof version_removed === 'string' && isSemver.test(version_removed)) {
-            withoutPrefix = version_removed
+          if (similar) {
+            if (prefix) throw new Error(`Unexpected prefix "${prefix}" for similar property "${similar.property}"`)
+            prefix = similar.prefix
           }
 
-          // The prefix is no longer needed if it can be used unprefixed
-          if (version_unprefixed && (!withoutPrefix || compareVersions(version_unprefixed, withoutPrefix) < 0)) {
-            withoutPrefix = version_unprefixed
-          }
+          if ((prefix || alternative_name) && !flags && typeof version_added === 'string' && isSemver.test(version_added)) {
+            const range: PrefixRange = { prefix: prefix || prefixForAlternativeName(alternative_name!), start: version_added }
+            let withoutPrefix: string | undefined
 
-          if (withoutPrefix) {
-            if (compareVersions(version_added, withoutPrefix) === 0) {
-              // No prefix is needed if support for the property with and without the prefix was added simultaneously
-              continue
+            // The prefix is no longer needed if support for the feature was removed
+            if (typeof version_removed === 'string' && isSemver.test(version_removed)) {
+              withoutPrefix = version_removed
+            }
+
+            // The prefix is no longer needed if it can be used unprefixed
+            if (version_unprefixed && (!withoutPrefix || compareVersions(version_unprefixed, withoutPrefix) < 0)) {
+              withoutPrefix = version_unprefixed
+            }
+
+            if (withoutPrefix) {
+              if (compareVersions(version_added, withoutPrefix) === 0) {
+                // No prefix is needed if support for the property with and without the prefix was added simultaneously
+                continue
+              }
+              range.end = withoutPrefix
             }
-            range.end = withoutPrefix
-          }
 
-          ranges.push(range)
+            ranges.push(range)
+          }
         }
-      }
 
-      // Sort earlier versions first, then sort prefixes for equal versions lexicographically
-      ranges.sort((a, b) => compareVersions(a.start, b.start) || +(a.prefix > b.prefix) - +(a.prefix < b.prefix))
+        // Sort earlier versions first, then sort prefixes for equal versions lexicographically
+        ranges.sort((a, b) => compareVersions(a.start, b.start) || +(a.prefix > b.prefix) - +(a.prefix < b.prefix))
 
-      for (let i = 0; i < ranges.length; i++) {
-        const { prefix, start, end } = ranges[i]
+        for (let i = 0; i < ranges.length; i++) {
+          const { prefix, start, end } = ranges[i]
 
-        // Skip this prefix if it's entirely covered by the previous prefix.
-        // Sometimes engines add support for multiple prefixes at a time. For
-        // example, in version 12 Edge added support for both "-ms-user-select"
-        // and "-webkit-user-select", so we don't need to generate both.
-        if (i > 0) {
-          const prev = ranges[i - 1]
-          if (compareVersions(start, prev.start) >= 0 && (!prev.end || (end && compareVersions(end, prev.end) <= 0))) {
-            continue
+          // Skip this prefix if it's entirely covered by the previous prefix.
+          // Sometimes engines add support for multiple prefixes at a time. For
+          // example, in version 12 Edge added support for both "-ms-user-select"
+          // and "-webkit-user-select", so we don't need to generate both.
+          if (i > 0) {
+            const prev = ranges[i - 1]
+            if (compareVersions(start, prev.start) >= 0 && (!prev.end || (end && compareVersions(end, prev.end) <= 0))) {
+              continue
+            }
           }
-        }
 
-        const data: PrefixData = { engine, prefix: prefix.replace(/^-|-$/g, '') }
-        if (end) {
-          data.withoutPrefix = end.split('.').map((x: string) => +x)
+          const data: PrefixData = { engine, prefix: prefix.replace(/^-|-$/g, '') }
+          if (end) {
+            data.withoutPrefix = end.split('.').map((x: string) => +x)
+          }
+          prefixData.push(data)
         }
-        prefixData.push(data)
       }
     }
-  }
 
-  cssPrefix[cssPrefixFeatures[fullKey]] = prefixData
+    map[features[fullKey]] = prefixData
+  }
 }
+
+addPrefixFeatures(cssPrefix, cssPrefixFeatures)
+addPrefixFeatures(cssSyntaxPrefix, cssSyntaxPrefixFeatures)
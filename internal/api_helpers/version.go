@@ -0,0 +1,5 @@
+package api_helpers
+
+// This is set by the CLI to the version of esbuild from "version.txt". It's
+// empty when esbuild is used as a Go library.
+var ESBuildVersion string
@@ -32,11 +32,17 @@ const (
 	// A CSS "@import" rule
 	ImportAt
 
-	// A CSS "composes" declaration
+	// A CSS "composes" declaration or an ICSS "@value ... from" rule
 	ImportComposesFrom
 
 	// A CSS "url(...)" token
 	ImportURL
+
+	// An HTML "<script type="module" src="...">" tag
+	ImportHTMLScript
+
+	// An HTML "<link rel="stylesheet" href="...">" tag
+	ImportHTMLLink
 )
 
 func (kind ImportKind) StringForMetafile() string {
@@ -55,6 +61,10 @@ func (kind ImportKind) StringForMetafile() string {
 		return "composes-from"
 	case ImportURL:
 		return "url-token"
+	case ImportHTMLScript:
+		return "script-tag"
+	case ImportHTMLLink:
+		return "link-tag"
 	case ImportEntryPoint:
 		return "entry-point"
 	default:
@@ -70,9 +80,17 @@ func (kind ImportKind) IsFromCSS() bool {
 	return false
 }
 
+func (kind ImportKind) IsFromHTML() bool {
+	switch kind {
+	case ImportHTMLScript, ImportHTMLLink:
+		return true
+	}
+	return false
+}
+
 func (kind ImportKind) MustResolveToCSS() bool {
 	switch kind {
-	case ImportAt, ImportComposesFrom:
+	case ImportAt, ImportComposesFrom, ImportHTMLLink:
 		return true
 	}
 	return false
@@ -154,6 +172,10 @@ const (
 	// Unique keys are randomly-generated strings that are used to replace paths
 	// in the source code after it's printed. These must not ever be split apart.
 	ContainsUniqueKey
+
+	// If true, this is an "import()" of another chunk generated by code
+	// splitting. Output formats without "import()" load these differently.
+	IsChunkImport
 )
 
 func (flags ImportRecordFlags) Has(flag ImportRecordFlags) bool {
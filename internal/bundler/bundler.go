@@ -30,6 +30,7 @@ import (
 	"github.com/evanw/esbuild/internal/fs"
 	"github.com/evanw/esbuild/internal/graph"
 	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/html_parser"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
 	"github.com/evanw/esbuild/internal/js_parser"
@@ -236,6 +237,34 @@ func parseFile(args parseArgs) {
 		source.Contents = ""
 	}
 
+	// Let plugins transform the contents now that the loader is known
+	var transformSourceMap *sourcemap.SourceMap
+	if loader.CanHaveSourceMap() {
+		var ok bool
+		transformSourceMap, ok = runOnTransformPlugins(
+			args.options.Plugins,
+			args.fs,
+			args.log,
+			&source,
+			loader,
+			args.importSource,
+			args.importPathRange,
+			args.options.SourceMap != config.SourceMapNone,
+			args.options.ExcludeSourcesContent,
+			args.options.ASCIIOnly,
+			args.options.LogPathStyle,
+		)
+		if !ok {
+			if args.inject != nil {
+				args.inject <- config.InjectedFile{
+					Source: source,
+				}
+			}
+			args.results <- parseResult{}
+			return
+		}
+	}
+
 	result := parseResult{
 		file: scannerFile{
 			inputFile: graph.InputFile{
@@ -300,6 +329,11 @@ func parseFile(args parseArgs) {
 		result.file.inputFile.Repr = &graph.CSSRepr{AST: ast}
 		result.ok = true
 
+	case config.LoaderHTML:
+		ast := html_parser.Parse(source)
+		result.file.inputFile.Repr = &graph.HTMLRepr{AST: ast}
+		result.ok = true
+
 	case config.LoaderJSON, config.LoaderWithTypeJSON:
 		expr, ok := args.caches.JSONCache.Parse(args.log, source, js_parser.JSONOptions{
 			UnsupportedJSFeatures: args.options.UnsupportedJSFeatures,
@@ -513,7 +547,7 @@ func parseFile(args parseArgs) {
 							// Forbid bundling of imports with explicit phases
 							if record.Phase != ast.EvaluationPhase {
 								reportExplicitPhaseImport(args.log, &tracker, record.Range,
-									record.Phase, allAreExternal, args.options.OutputFormat)
+									record.Phase, allAreExternal, &args.options)
 							}
 						} else {
 							args.log.AddError(&tracker, record.Range, fmt.Sprintf("Could not resolve %s", prettyPath))
@@ -613,7 +647,7 @@ func parseFile(args parseArgs) {
 					// Forbid bundling of imports with explicit phases
 					if record.Phase != ast.EvaluationPhase {
 						reportExplicitPhaseImport(args.log, &tracker, record.Range,
-							record.Phase, entry.resolveResult.PathPair.IsExternal, args.options.OutputFormat)
+							record.Phase, entry.resolveResult.PathPair.IsExternal, &args.options)
 					}
 
 					result.resolveResults[importRecordIndex] = entry.resolveResult
@@ -714,6 +748,21 @@ func parseFile(args parseArgs) {
 					result.file.inputFile.InputSourceMap = sourceMap
 				}
 			}
+
+			// The source map from "OnTransform" plugins maps back to the contents of
+			// the file before it was transformed. If that file had a source map
+			// comment, then the source map from that comment continues the chain.
+			if transformSourceMap != nil {
+				if inputSourceMap := result.file.inputFile.InputSourceMap; inputSourceMap != nil {
+					transformSourceMap = &sourcemap.SourceMap{
+						Sources:        inputSourceMap.Sources,
+						SourcesContent: inputSourceMap.SourcesContent,
+						Names:          inputSourceMap.Names,
+						Mappings:       sourcemap.ChainMappings(inputSourceMap, transformSourceMap),
+					}
+				}
+				result.file.inputFile.InputSourceMap = transformSourceMap
+			}
 		}
 	}
 
@@ -765,19 +814,29 @@ func reportExplicitPhaseImport(
 	r logger.Range,
 	phase ast.ImportPhase,
 	isExternal bool,
-	format config.Format,
+	options *config.Options,
 ) {
 	var phaseText string
 	switch phase {
 	case ast.DeferPhase:
+		// Deferred imports of bundled modules are implemented by the linker, so
+		// only external deferred imports need to be passed through as-is
+		if !isExternal {
+			return
+		}
+		if options.OutputFormat == config.FormatESModule && options.UnsupportedJSFeatures.Has(compat.ImportDefer) {
+			where := config.PrettyPrintTargetEnvironment(options.OriginalTargetEnv, options.UnsupportedJSFeatureOverridesMask)
+			log.AddError(tracker, r, fmt.Sprintf("Deferred imports are not available in %s unless they are bundled", where))
+			return
+		}
 		phaseText = "deferred"
 	case ast.SourcePhase:
 		phaseText = "source phase"
 	default:
 		return
 	}
-	if format != config.FormatESModule {
-		log.AddError(tracker, r, fmt.Sprintf("Bundling %s imports with the %q output format is not supported", phaseText, format.String()))
+	if options.OutputFormat != config.FormatESModule {
+		log.AddError(tracker, r, fmt.Sprintf("Bundling %s imports with the %q output format is not supported", phaseText, options.OutputFormat.String()))
 	} else if !isExternal {
 		log.AddError(tracker, r, fmt.Sprintf("Bundling with %s imports is not supported unless they are external", phaseText))
 	}
@@ -1292,6 +1351,127 @@ func runOnLoadPlugins(
 	return loaderPluginResult{loader: config.LoaderNone}, true
 }
 
+// Transform plugins run in order after the file has been loaded. Each one
+// sees the output of the previous one. The source maps returned by each
+// callback are chained together into a single source map that maps the final
+// contents back to the contents of the file before it was transformed.
+func runOnTransformPlugins(
+	plugins []config.Plugin,
+	fs fs.FS,
+	log logger.Log,
+	source *logger.Source,
+	loader config.Loader,
+	importSource *logger.Source,
+	importPathRange logger.Range,
+	hasSourceMap bool,
+	excludeSourcesContent bool,
+	asciiOnly bool,
+	logPathStyle logger.PathStyle,
+) (*sourcemap.SourceMap, bool) {
+	var transformSourceMap *sourcemap.SourceMap
+	originalContents := source.Contents
+
+	for _, plugin := range plugins {
+		for _, onTransform := range plugin.OnTransform {
+			if !config.PluginAppliesToPath(source.KeyPath, onTransform.Filter, onTransform.Namespace) {
+				continue
+			}
+
+			transformArgs := config.OnTransformArgs{
+				Path:     source.KeyPath,
+				Contents: source.Contents,
+				Loader:   loader,
+			}
+			if transformSourceMap != nil {
+				transformArgs.SourceMap = string(transformSourceMap.JSON(asciiOnly))
+			}
+
+			result := onTransform.Callback(transformArgs)
+			pluginName := result.PluginName
+			if pluginName == "" {
+				pluginName = plugin.Name
+			}
+			if logPluginMessages(fs, log, pluginName, result.Msgs, result.ThrownError, importSource, importPathRange) {
+				return nil, false
+			}
+
+			// If new contents are returned without a source map, the contents are
+			// assumed to have the same layout and the chain is left unchanged
+			if hasSourceMap && result.SourceMap != nil {
+				deferLog := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+				outer := js_parser.ParseSourceMap(deferLog, logger.Source{
+					KeyPath:     source.KeyPath,
+					PrettyPaths: source.PrettyPaths,
+					Contents:    *result.SourceMap,
+				})
+				if msgs := deferLog.Done(); outer == nil && len(msgs) > 0 {
+					notes := make([]logger.MsgData, 0, len(msgs))
+					for _, msg := range msgs {
+						notes = append(notes, logger.MsgData{Text: msg.Data.Text})
+					}
+					tracker := logger.MakeLineColumnTracker(importSource)
+					log.AddMsg(logger.Msg{
+						PluginName: pluginName,
+						Kind:       logger.Error,
+						Data: tracker.MsgData(importPathRange, fmt.Sprintf("Invalid source map returned for %q",
+							source.PrettyPaths.Select(logPathStyle))),
+						Notes: notes,
+					})
+					return nil, false
+				}
+				if outer == nil {
+					outer = &sourcemap.SourceMap{}
+				}
+
+				if transformSourceMap == nil {
+					// The first source map refers to the original contents of this file
+					var sourcesContent []sourcemap.SourceContent
+					if !excludeSourcesContent {
+						sourcesContent = []sourcemap.SourceContent{{Value: helpers.StringToUTF16(originalContents)}}
+					}
+					mappings := make([]sourcemap.Mapping, len(outer.Mappings))
+					for i, mapping := range outer.Mappings {
+						mapping.SourceIndex = 0
+						mappings[i] = mapping
+					}
+					transformSourceMap = &sourcemap.SourceMap{
+						Sources:        []string{sourceURLForPath(source.KeyPath)},
+						SourcesContent: sourcesContent,
+						Names:          outer.Names,
+						Mappings:       mappings,
+					}
+				} else {
+					transformSourceMap = &sourcemap.SourceMap{
+						Sources:        transformSourceMap.Sources,
+						SourcesContent: transformSourceMap.SourcesContent,
+						Names:          transformSourceMap.Names,
+						Mappings:       sourcemap.ChainMappings(transformSourceMap, outer),
+					}
+				}
+			}
+
+			if result.Contents != nil {
+				source.Contents = *result.Contents
+			}
+		}
+	}
+
+	return transformSourceMap, true
+}
+
+// Source maps encode sources as URLs. Paths that aren't in the "file"
+// namespace are joined with their namespace to form something URL-like.
+func sourceURLForPath(path logger.Path) string {
+	if path.Namespace == "file" {
+		return helpers.FileURLFromFilePath(path.Text).String()
+	}
+	text := path.Text
+	if path.Namespace != "" {
+		text = fmt.Sprintf("%s:%s", path.Namespace, text)
+	}
+	return text + path.IgnoredSuffix
+}
+
 // Identify the path by its lowercase absolute path name with Windows-specific
 // slashes substituted for standard slashes. This should hopefully avoid path
 // issues on Windows where multiple different paths can refer to the same
@@ -1321,6 +1501,10 @@ type scanner struct {
 
 	options config.Options
 
+	// The resolved path of the "bigintLibrary" module. This module implements
+	// big integers, so it's parsed without converting them into library calls.
+	bigIntLibraryPath logger.Path
+
 	// Also not guarded by a mutex for the same reason
 	remaining int
 }
@@ -1478,6 +1662,7 @@ func ScanBundle(
 	}
 
 	s.preprocessInjectedFiles()
+	s.resolveBigIntLibrary()
 
 	if options.CancelFlag.DidCancel() {
 		return Bundle{options: options}
@@ -1580,6 +1765,11 @@ func (s *scanner) maybeParseFile(
 		optionsClone.ModuleTypeData.Type = js_ast.ModuleUnknown
 	}
 
+	// Don't convert big integers into calls to the library that implements them
+	if s.bigIntLibraryPath.Text != "" && path == s.bigIntLibraryPath {
+		optionsClone.BigIntLibrary = ""
+	}
+
 	// Enable bundling for injected files so we always do tree shaking. We
 	// never want to include unnecessary code from injected files since they
 	// are essentially bundled. However, if we do this we should skip the
@@ -1822,6 +2012,19 @@ func (s *scanner) preprocessInjectedFiles() {
 	s.options.InjectedFiles = injectedFiles
 }
 
+func (s *scanner) resolveBigIntLibrary() {
+	if s.options.BigIntLibrary == "" || s.options.Mode != config.ModeBundle {
+		return
+	}
+
+	// Like injected files, the library is resolved relative to the working
+	// directory. Failures are ignored here since they will be reported again
+	// by the import statements that are generated for the library.
+	if result, _ := s.res.Resolve(s.fs.Cwd(), s.options.BigIntLibrary, ast.ImportStmt); result != nil && !result.PathPair.IsExternal {
+		s.bigIntLibraryPath = result.PathPair.Primary
+	}
+}
+
 func (s *scanner) addEntryPoints(entryPoints []EntryPoint) []graph.EntryPoint {
 	s.timer.Begin("Add entry points")
 	defer s.timer.End("Add entry points")
@@ -2192,6 +2395,10 @@ func (s *scanner) scanAllDependencies() {
 						record.SourceIndex = ast.MakeIndex32(sourceIndex)
 						s.results[sourceIndex] = s.generateResultForGlobResolve(sourceIndex, globResults.absPath,
 							&result.file.inputFile.Source, record.Range, with, record.GlobPattern.Kind, record.Phase, globResults, record.AssertOrWith)
+
+						// The import phase now belongs to the imports inside the generated
+						// glob module. The glob module itself is evaluated normally.
+						record.Phase = ast.EvaluationPhase
 					}
 					continue
 				}
@@ -2513,7 +2720,55 @@ func (s *scanner) processScannedFiles(entryPointMeta []graph.EntryPoint) []scann
 							{Text: "You need to either reconfigure esbuild to ensure that the loader for this file is \"json\" or you need to remove this import assertion."}})
 				}
 
+				// HTML files can only be entry points
+				if _, ok := otherFile.inputFile.Repr.(*graph.HTMLRepr); ok && !record.Kind.IsFromHTML() {
+					s.log.AddErrorWithNotes(&tracker, record.Range,
+						fmt.Sprintf("Cannot import %q",
+							otherFile.inputFile.Source.PrettyPaths.Select(s.options.LogPathStyle)),
+						[]logger.MsgData{{Text: fmt.Sprintf(
+							"The file %q was loaded with the %q loader, which can only be used for entry points.",
+							otherFile.inputFile.Source.PrettyPaths.Select(s.options.LogPathStyle),
+							config.LoaderToString[otherFile.inputFile.Loader])}})
+					continue
+				}
+
+				// Each file referenced by an HTML file becomes a separate output file
+				if record.Kind.IsFromHTML() && (s.options.AbsOutputFile != "" || s.options.WriteToStdout) {
+					s.log.AddErrorWithNotes(&tracker, record.Range,
+						fmt.Sprintf("Cannot reference %q without an output directory configured",
+							otherFile.inputFile.Source.PrettyPaths.Select(s.options.LogPathStyle)),
+						[]logger.MsgData{{Text: "Files referenced by HTML files are written to separate output files, " +
+							"so you must use \"outdir\" instead of \"outfile\" when bundling HTML files."}})
+					continue
+				}
+
 				switch record.Kind {
+				case ast.ImportHTMLScript:
+					// A "<script>" tag must reference a JavaScript file
+					if _, ok := otherFile.inputFile.Repr.(*graph.JSRepr); !ok || otherFile.inputFile.Loader == config.LoaderEmpty {
+						s.log.AddErrorWithNotes(&tracker, record.Range,
+							fmt.Sprintf("Cannot use %q as a script",
+								otherFile.inputFile.Source.PrettyPaths.Select(s.options.LogPathStyle)),
+							[]logger.MsgData{{Text: fmt.Sprintf(
+								"A \"<script>\" tag can only reference a JavaScript file and %q is not a JavaScript file (it was loaded with the %q loader).",
+								otherFile.inputFile.Source.PrettyPaths.Select(s.options.LogPathStyle),
+								config.LoaderToString[otherFile.inputFile.Loader])}})
+						continue
+					}
+
+				case ast.ImportHTMLLink:
+					// A "<link rel="stylesheet">" tag must reference a CSS file
+					if _, ok := otherFile.inputFile.Repr.(*graph.CSSRepr); !ok {
+						s.log.AddErrorWithNotes(&tracker, record.Range,
+							fmt.Sprintf("Cannot use %q as a stylesheet",
+								otherFile.inputFile.Source.PrettyPaths.Select(s.options.LogPathStyle)),
+							[]logger.MsgData{{Text: fmt.Sprintf(
+								"A \"<link rel=\"stylesheet\">\" tag can only reference a CSS file and %q is not a CSS file (it was loaded with the %q loader).",
+								otherFile.inputFile.Source.PrettyPaths.Select(s.options.LogPathStyle),
+								config.LoaderToString[otherFile.inputFile.Loader])}})
+						continue
+					}
+
 				case ast.ImportComposesFrom:
 					// Using a JavaScript file with CSS "composes" is not allowed
 					if _, ok := otherFile.inputFile.Repr.(*graph.JSRepr); ok && otherFile.inputFile.Loader != config.LoaderEmpty {
@@ -2898,6 +3153,7 @@ func DefaultExtensionToLoaderMap() map[string]config.Loader {
 		".tsx":        config.LoaderTSX,
 		".css":        config.LoaderCSS,
 		".module.css": config.LoaderLocalCSS,
+		".html":       config.LoaderHTML,
 		".json":       config.LoaderJSON,
 		".txt":        config.LoaderText,
 	}
@@ -2946,6 +3202,9 @@ func applyOptionDefaults(options *config.Options) {
 		compat.ClassField|compat.ClassPrivateAccessor|compat.ClassPrivateBrandCheck|compat.ClassPrivateField|
 			compat.ClassPrivateMethod|compat.ClassPrivateStaticAccessor|compat.ClassPrivateStaticField|
 			compat.ClassPrivateStaticMethod|compat.ClassStaticBlocks|compat.ClassStaticField)
+	fixInvalidUnsupportedJSFeatureOverrides(options, compat.Destructuring, compat.ObjectRestSpread|compat.NestedRestBinding)
+	fixInvalidUnsupportedJSFeatureOverrides(options, compat.RestArgument, compat.Arrow)
+	fixInvalidUnsupportedJSFeatureOverrides(options, compat.ArraySpread, compat.OptionalChain)
 
 	// If we're not building for the browser, automatically disable support for
 	// inline </script> and </style> tags if there aren't currently any overrides
@@ -2957,6 +3216,12 @@ func applyOptionDefaults(options *config.Options) {
 			options.UnsupportedCSSFeatures |= compat.InlineStyle
 		}
 	}
+
+	// No browser supports "@custom-media" yet, so always substitute these
+	// definitions unless support has been explicitly enabled
+	if !options.UnsupportedCSSFeatureOverridesMask.Has(compat.CustomMedia) {
+		options.UnsupportedCSSFeatures |= compat.CustomMedia
+	}
 }
 
 func fixInvalidUnsupportedJSFeatureOverrides(options *config.Options, implies compat.JSFeature, implied compat.JSFeature) {
@@ -1,6 +1,7 @@
 package bundler_tests
 
 import (
+	"regexp"
 	"testing"
 
 	"github.com/evanw/esbuild/internal/compat"
@@ -2357,6 +2358,83 @@ func TestDeduplicateRulesGlobalVsLocalNames(t *testing.T) {
 	})
 }
 
+func TestMergeRules(t *testing.T) {
+	// These are done as bundler tests instead of parser tests because rule
+	// merging happens during linking (so that it has effects across files)
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/yes0.css": "a { color: red } b { margin: 0 } a { padding: 0 }",
+			"/yes1.css": "a { color: red } b { color: blue } a { margin: 0 }",
+			"/yes2.css": "a { margin-top: 0; margin-bottom: 0 } a { margin-left: 0; margin-right: 0 }",
+			"/yes3.css": "@media print { a { color: red } b { margin: 0 } a { padding: 0 } }",
+			"/yes4.css": "a { color: red } /* comment */ a { padding: 0 }",
+			"/yes5.css": "a { color: red } a { color: blue }",
+			"/yes6.css": "a { color: red } b { margin: 0 } a { color: var(--color) }",
+			"/yes7.css": "a { color: red !important } a { color: blue }",
+
+			"/no0.css": "a { color: red } b { color: blue } a { color: green }",
+			"/no1.css": "a { margin-left: 0 } b { margin-inline-start: 1px } a { margin-right: 0 }",
+			"/no2.css": "a { top: 0 } b { inset: 1px } a { left: 0 }",
+			"/no3.css": "a { color: red } @media print { b { color: blue } } a { margin: 0 }",
+			"/no4.css": "a { color: red } b { all: unset } a { margin: 0 }",
+			"/no5.css": "a { color: red } b { & c { margin: 0 } } a { margin: 0 }",
+
+			"/across-files.css":   "@import 'across-files-0.css'; @import 'across-files-1.css'; @import 'across-files-2.css';",
+			"/across-files-0.css": ".a { color: red }",
+			"/across-files-1.css": ".b { color: red } .c { margin: 0 }",
+			"/across-files-2.css": ".c { padding: 0 }",
+
+			"/across-files-url.css":   "@import 'across-files-url-0.css'; @import 'across-files-url-1.css';",
+			"/across-files-url-0.css": ".a { color: red }",
+			"/across-files-url-1.css": ".a { background: url(http://example.com/a.png) }",
+		},
+		entryPaths: []string{
+			"/yes0.css",
+			"/yes1.css",
+			"/yes2.css",
+			"/yes3.css",
+			"/yes4.css",
+			"/yes5.css",
+			"/yes6.css",
+			"/yes7.css",
+
+			"/no0.css",
+			"/no1.css",
+			"/no2.css",
+			"/no3.css",
+			"/no4.css",
+			"/no5.css",
+
+			"/across-files.css",
+			"/across-files-url.css",
+		},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			MinifySyntax: true,
+		},
+	})
+}
+
+func TestMergeRulesWithSourceMap(t *testing.T) {
+	// Rules must not be merged across files if there's a source map since that
+	// would move declarations from one file into another
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": "@import 'a.css'; @import 'b.css'; .a { margin: 0 } .a { padding: 0 }",
+			"/a.css":     ".a { color: red }",
+			"/b.css":     ".b { color: red }",
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.css",
+			MinifySyntax:  true,
+			SourceMap:     config.SourceMapExternalWithoutComment,
+		},
+	})
+}
+
 // This test makes sure JS files that import local CSS names using the
 // wrong name (e.g. a typo) get a warning so that the problem is noticed.
 func TestUndefinedImportWarningCSS(t *testing.T) {
@@ -2651,3 +2729,658 @@ func TestCSSAssetPathsWithSpacesBundle(t *testing.T) {
 		},
 	})
 }
+
+func TestCSSCustomMediaBundle(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": `
+				@import "./media.css";
+				@media (--small) { .small { color: red } }
+				@media screen and (--portrait), print { .portrait { color: green } }
+				@media not (--either) { .neither { color: blue } }
+				@media (--undefined) { .undefined { color: red } }
+				.nested { @media (--small) { color: red } }
+			`,
+			"/media.css": `
+				@custom-media --small (width < 600px), print;
+				@custom-media --portrait (--narrow) and (orientation: portrait);
+				@custom-media --narrow (width <= 300px);
+				@custom-media --either (hover) or (pointer: fine);
+			`,
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:                   config.ModeBundle,
+			AbsOutputFile:          "/out.css",
+			UnsupportedCSSFeatures: compat.MediaRange | compat.Nesting,
+		},
+	})
+}
+
+func TestCSSCustomMediaConflictingDefinitions(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import "./b.css"
+				import "./a.css"
+				import "./c.css"
+			`,
+			"/a.css": `
+				@custom-media --small (width < 100px);
+				@media (--small) { .a { color: red } }
+			`,
+			"/b.css": `
+				@custom-media --small (width < 200px);
+				@media (--small) { .b { color: green } }
+			`,
+			"/c.css": `
+				@import "./b.css";
+				@media (--small) { .c { color: blue } }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                   config.ModeBundle,
+			AbsOutputDir:           "/out",
+			UnsupportedCSSFeatures: compat.MediaRange | compat.CustomMedia,
+		},
+	})
+}
+
+func TestCSSCustomMediaSupported(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": `
+				@custom-media --small (width < 600px);
+				@media (--small) { .small { color: red } }
+			`,
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:                               config.ModeBundle,
+			AbsOutputFile:                      "/out.css",
+			UnsupportedCSSFeatureOverridesMask: compat.CustomMedia,
+		},
+	})
+}
+
+func TestCSSLowerCascadeLayers(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": `
+				@layer base, components;
+				@import "./reset.css" layer(reset);
+				@layer components {
+					.button { color: red }
+					.button::before { content: "" }
+				}
+				@layer base {
+					#app .button { color: blue }
+					@layer inner { a { color: green } }
+				}
+				@media screen {
+					@layer components { .wide { color: red } }
+					@layer base;
+				}
+				@layer { .anonymous { color: red } }
+				.unlayered, .other:where(#id) { color: black }
+			`,
+			"/reset.css": `
+				html { margin: 0 }
+			`,
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:                   config.ModeBundle,
+			AbsOutputFile:          "/out.css",
+			UnsupportedCSSFeatures: compat.CascadeLayers,
+		},
+	})
+}
+
+func TestCSSLowerCascadeLayersImportant(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": `
+				@layer base, components;
+				@layer base {
+					.button { color: red !important; background: red }
+				}
+				@layer components {
+					.button { color: blue !important }
+					.button { .icon { fill: blue !important; stroke: blue } }
+				}
+				.button { color: black !important }
+			`,
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:                   config.ModeBundle,
+			AbsOutputFile:          "/out.css",
+			UnsupportedCSSFeatures: compat.CascadeLayers,
+		},
+	})
+}
+
+func TestCSSLowerCascadeLayersNoLayers(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": `
+				#app .button { color: blue }
+			`,
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:                   config.ModeBundle,
+			AbsOutputFile:          "/out.css",
+			UnsupportedCSSFeatures: compat.CascadeLayers,
+		},
+	})
+}
+
+func TestCSSAtScopeLocalNames(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import * as styles from "./styles.css"
+				console.log(styles)
+			`,
+			"/styles.css": `
+				@scope (.card) to (.content) {
+					img { border: 1px solid }
+					.title { color: red }
+				}
+				@scope (:global(.page)) {
+					.card { margin: 0 }
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".css": config.LoaderLocalCSS,
+			},
+		},
+	})
+}
+
+func TestCSSLowerAtScope(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.css": `
+				@scope (.card, .panel) to (.content) {
+					:scope { padding: 1em }
+					img { border: 1px solid }
+					> .title::before { content: "#" }
+					@media (width >= 600px) { img { border-width: 2px } }
+				}
+				.list {
+					@scope (& > li) { color: red }
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.css"},
+		options: config.Options{
+			Mode:                   config.ModeBundle,
+			AbsOutputFile:          "/out.css",
+			UnsupportedCSSFeatures: compat.AtScope,
+		},
+	})
+}
+
+func TestImportCSSFromJSValues(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import styles from "./styles.css"
+				console.log(styles)
+			`,
+			"/styles.css": `
+				@value primary: #f00;
+				@value gap 4px;
+				@value small: (max-width: 599px);
+				@value (secondary, tertiary as accent) from "./colors.css";
+				@value icon from "./icons.css";
+				@value border: gap solid accent;
+
+				:export {
+					doubleGap: calc(gap * 2);
+				}
+
+				.button {
+					color: primary;
+					border: border;
+					background: icon;
+				}
+				@media small {
+					.button { color: secondary }
+				}
+			`,
+			"/colors.css": `
+				@value base from "./base.css";
+				@value secondary: base;
+				@value tertiary: blue;
+			`,
+			"/base.css": `
+				:export { base: green }
+			`,
+			"/icons.css": `
+				@value icon: url(./icon.png);
+			`,
+			"/icon.png": `...`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".css": config.LoaderLocalCSS,
+				".png": config.LoaderFile,
+			},
+		},
+	})
+}
+
+func TestImportCSSFromJSValuesUndefined(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import styles from "./styles.css"
+				console.log(styles)
+			`,
+			"/styles.css": `
+				@value primary, missing as other from "./colors.css";
+				.button { color: primary; background: other }
+			`,
+			"/colors.css": `
+				@value primary: red;
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".css": config.LoaderLocalCSS,
+			},
+		},
+		expectedCompileLog: `styles.css: ERROR: No value named "missing" is defined in "colors.css"
+`,
+	})
+}
+
+func TestLocalCSSNamesTemplate(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/project/entry.js": `
+				import a from "./src/button.css"
+				import b from "./src/nested/button.css"
+				import c from "./other.css"
+				console.log(a, b, c)
+			`,
+			"/project/src/button.css": `
+				.button { color: red }
+				.button:hover { color: blue }
+				@keyframes fade { to { opacity: 0 } }
+			`,
+			"/project/src/nested/button.css": `
+				.button { composes: button from "../button.css"; background: green }
+				:global(.src_button_button) { color: black }
+			`,
+			"/project/other.css": `
+				.other.this-one { color: red }
+			`,
+		},
+		entryPaths:    []string{"/project/entry.js"},
+		absWorkingDir: "/project",
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".css": config.LoaderLocalCSS,
+			},
+			LocalCSSNameTemplate: []config.PathTemplate{
+				{Data: "", Placeholder: config.DirPlaceholder},
+				{Data: "_", Placeholder: config.NamePlaceholder},
+				{Data: "_", Placeholder: config.LocalPlaceholder},
+			},
+		},
+	})
+}
+
+func TestLocalCSSNamesTemplateHash(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/project/entry.js": `
+				import a from "./a.css"
+				import b from "./b.css"
+				console.log(a, b)
+			`,
+			"/project/a.css": `
+				.foo { color: red }
+				.bar { color: blue }
+			`,
+			"/project/b.css": `
+				.foo { color: green }
+			`,
+		},
+		entryPaths:    []string{"/project/entry.js"},
+		absWorkingDir: "/project",
+		options: config.Options{
+			Mode:              config.ModeBundle,
+			AbsOutputDir:      "/out",
+			MinifyIdentifiers: true,
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".css": config.LoaderLocalCSS,
+			},
+			LocalCSSNameTemplate: []config.PathTemplate{
+				{Data: "", Placeholder: config.HashPlaceholder},
+			},
+		},
+	})
+}
+
+func TestLocalCSSNamesPlugin(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import a from "./a.css"
+				import b from "./b.css"
+				console.log(a, b)
+			`,
+			"/a.css": `
+				.foo { color: red }
+				.bar { color: blue }
+				.baz { color: green }
+			`,
+			"/b.css": `
+				.foo { color: green }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".css": config.LoaderLocalCSS,
+			},
+			LocalCSSNameTemplate: []config.PathTemplate{
+				{Data: "tpl-", Placeholder: config.LocalPlaceholder},
+			},
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnLocalCSSName: []config.OnLocalCSSName{{
+					Filter: regexp.MustCompile(`a\.css$`),
+					Callback: func(args config.OnLocalCSSNameArgs) config.OnLocalCSSNameResult {
+						if args.Local == "foo" {
+							return config.OnLocalCSSNameResult{Name: "plugin-" + args.Local}
+						}
+						return config.OnLocalCSSNameResult{}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestLocalCSSNamesPluginErrors(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import styles from "./styles.css"
+				console.log(styles)
+			`,
+			"/styles.css": `
+				.foo { color: red }
+				.bar { color: blue }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":  config.LoaderJS,
+				".css": config.LoaderLocalCSS,
+			},
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnLocalCSSName: []config.OnLocalCSSName{{
+					Filter: regexp.MustCompile(`\.css$`),
+					Callback: func(args config.OnLocalCSSNameArgs) config.OnLocalCSSNameResult {
+						if args.Local == "foo" {
+							return config.OnLocalCSSNameResult{Name: "not valid"}
+						}
+						return config.OnLocalCSSNameResult{Name: "1bar"}
+					},
+				}},
+			}},
+		},
+		expectedCompileLog: `ERROR: Invalid local CSS name: "1bar"
+ERROR: Invalid local CSS name: "not valid"
+`,
+	})
+}
+
+func TestCSSModuleTypes(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/entry.js": `
+				import button from "./button.module.css"
+				import global from "./theme.global.css"
+				import plain from "./plain.css"
+				console.log(button, global, plain)
+			`,
+			"/src/button.module.css": `
+				@value primary: red;
+				.button { composes: base from "./shared/base.module.css"; composes: small; color: primary }
+				.small { font-size: 12px }
+				.is-active, .class { color: blue }
+			`,
+			"/src/shared/base.module.css": `
+				.base { margin: 0 }
+			`,
+			"/src/theme.global.css": `
+				:local(.local) { color: red }
+				.global { color: blue }
+			`,
+			"/src/plain.css": `
+				.plain { color: red }
+			`,
+		},
+		entryPaths: []string{"/src/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			ExtensionToLoader: map[string]config.Loader{
+				".js":         config.LoaderJS,
+				".css":        config.LoaderCSS,
+				".module.css": config.LoaderLocalCSS,
+				".global.css": config.LoaderGlobalCSS,
+			},
+			CSSModuleTypes: config.CSSModuleTypesDTS,
+		},
+	})
+}
+
+func TestCSSModuleTypesDCSSTS(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { foo } from "./styles.module.css"
+				console.log(foo)
+			`,
+			"/styles.module.css": `
+				.foo { color: red }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputDir:   "/out",
+			CSSModuleTypes: config.CSSModuleTypesDCSSTS,
+		},
+	})
+}
+
+func TestCSSModuleTypesMetafile(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { foo } from "./styles.module.css"
+				import { bar } from "pkg/styles.module.css"
+				console.log(foo, bar)
+			`,
+			"/styles.module.css": `
+				.foo { color: red }
+			`,
+			"/node_modules/pkg/styles.module.css": `
+				.bar { color: blue }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:           config.ModeBundle,
+			AbsOutputDir:   "/out",
+			CSSModuleTypes: config.CSSModuleTypesDTS,
+			NeedsMetafile:  true,
+		},
+	})
+}
+
+func TestCSSTreeShakingUnusedLocalNames(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import styles from "./button.module.css"
+				import { card } from "./card.module.css"
+				console.log(styles.button, card)
+				if (false) console.log(styles.dead)
+			`,
+			"/button.module.css": `
+				.button { composes: base; color: red }
+				.base { margin: 0 }
+				.unused { color: blue }
+				.button, .unused { padding: 0 }
+				.unused:hover, .unused .button { color: green }
+				.button:not(.unused) { color: yellow }
+				.dead { color: black }
+				@media (min-width: 100px) {
+					.unused { color: orange }
+				}
+				.button {
+					& .unused { color: purple }
+				}
+			`,
+			"/card.module.css": `
+				.card { color: red }
+				.title { composes: heading from "./heading.module.css" }
+			`,
+			"/heading.module.css": `
+				.heading { font-weight: bold }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			TreeShaking:  true,
+			ExtensionToLoader: map[string]config.Loader{
+				".js":         config.LoaderJS,
+				".module.css": config.LoaderLocalCSS,
+			},
+		},
+	})
+}
+
+func TestCSSTreeShakingEscapedStyles(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import a from "./a.module.css"
+				import b from "./b.module.css"
+				import * as c from "./c.module.css"
+				import d from "./d.module.css"
+				console.log({ ...a }, b[key], c, d.used)
+			`,
+			"/a.module.css": `
+				.a1 { color: red }
+				.a2 { color: red }
+			`,
+			"/b.module.css": `
+				.b1 { color: red }
+				.b2 { color: red }
+			`,
+			"/c.module.css": `
+				.c1 { color: red }
+				.c2 { color: red }
+			`,
+			"/d.module.css": `
+				.used { color: red }
+				.unused { color: red }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			TreeShaking:  true,
+			ExtensionToLoader: map[string]config.Loader{
+				".js":         config.LoaderJS,
+				".module.css": config.LoaderLocalCSS,
+			},
+		},
+	})
+}
+
+func TestCSSTreeShakingConservativeImports(t *testing.T) {
+	css_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import "./a.module.css"
+				import {} from "./b.module.css"
+				import * as c from "./c.module.css"
+				import d from "./d.module.css"
+				console.log(c.c1, d.used, import("./e.module.css"))
+			`,
+			"/a.module.css": `
+				.a1 { color: red }
+				.a2 { color: red }
+			`,
+			"/b.module.css": `
+				.b1 { color: red }
+				.b2 { color: red }
+			`,
+			"/c.module.css": `
+				.c1 { color: red }
+				.c2 { color: red }
+			`,
+			"/d.module.css": `
+				.used { color: red }
+				.unused { color: red }
+			`,
+			"/e.module.css": `
+				.e1 { color: red }
+				.e2 { color: red }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			TreeShaking:  true,
+			ExtensionToLoader: map[string]config.Loader{
+				".js":         config.LoaderJS,
+				".module.css": config.LoaderLocalCSS,
+			},
+		},
+	})
+}
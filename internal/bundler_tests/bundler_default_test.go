@@ -1,6 +1,8 @@
 package bundler_tests
 
 import (
+	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 	"testing"
@@ -11,6 +13,7 @@ import (
 	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/sourcemap"
 )
 
 var default_suite = suite{
@@ -240,6 +243,382 @@ func TestExportFormsIIFE(t *testing.T) {
 	})
 }
 
+func TestExportFormsSystem(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export default 123
+				export var v = 234
+				export let l = 234
+				export const c = 234
+				export {Class as C}
+				export function Fn() {}
+				export class Class {}
+				export * from './a'
+				export * as b from './b'
+			`,
+			"/a.js": "export const abc = undefined",
+			"/b.js": "export const xyz = null",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemExternalImports(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import def, { a, b as m } from 'ext-a'
+				import * as ns from 'ext-b'
+				import 'ext-c'
+				import { c } from 'ext-a'
+				export * from 'ext-d'
+				export { x } from 'ext-e'
+				export let y = 1
+				console.log(def, a, m, ns, c, import.meta.url)
+				import('ext-f').then(console.log)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"ext-a": true,
+					"ext-b": true,
+					"ext-c": true,
+					"ext-d": true,
+					"ext-e": true,
+					"ext-f": true,
+				}},
+			},
+		},
+	})
+}
+
+func TestSystemLiveExports(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { inc } from './counter'
+				export { count, count as alias } from './counter'
+				export let local = 0
+				export function reset() {
+					local = 0
+					local += 1
+					local++
+					let old = local--
+					return [old, local++, new (local = Foo)]
+				}
+				inc()
+			`,
+			"/counter.js": `
+				export let count = 0
+				export function inc() {
+					count++
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemLiveExportsDestructuring(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export let x, y, z, other
+				export { x as alias }
+				export function update(arr, obj) {
+					[x, y] = arr;
+					({ x, y: [z = 1, ...other] } = obj);
+					({ ...x } = obj);
+					let value = [y] = arr
+					return [value, ([z] = arr) && z, () => ({ x } = obj)]
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemLiveExportsForInOf(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export let x, y, z
+				export async function update(arr, obj) {
+					for (x of arr) console.log(x)
+					for ([y, { z }] of arr) {
+						console.log(y, z)
+					}
+					for (x in obj) ;
+					for await (y of arr) {}
+					for (let local of arr) x = local
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemLiveExportsMinifyWhitespace(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export let x, y
+				export function update(arr) {
+					[x, y] = arr
+					for (x of arr) ;
+					for ([x] of arr) y = x
+					return [y] = arr
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			OutputFormat:     config.FormatSystem,
+			MinifyWhitespace: true,
+			AbsOutputFile:    "/out.js",
+		},
+	})
+}
+
+func TestSystemSplitting(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { shared, setShared } from './shared'
+				setShared(1)
+				console.log(shared, import('./b'))
+			`,
+			"/b.js": `
+				import { shared } from './shared'
+				export default shared
+			`,
+			"/shared.js": `
+				export let shared = 0
+				export function setShared(value) { shared = value }
+			`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSystemMinifyWhitespace(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { m, e } from 'ext'
+				export * from 'ext2'
+				export let x = m + e
+				x++
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			OutputFormat:     config.FormatSystem,
+			MinifyWhitespace: true,
+			AbsOutputFile:    "/out.js",
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"ext":  true,
+					"ext2": true,
+				}},
+			},
+		},
+	})
+}
+
+func TestSystemTopLevelAwait(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export const value = await import('./foo')
+			`,
+			"/foo.js": `
+				export default await Promise.resolve(1)
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatSystem,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestSystemHoistedDeclarations(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { other } from './other'
+				export function fn() { return [other(), nested, later, Class] }
+				export default function () {}
+				if (other) { var nested = 1 }
+				export let later = 2
+				export class Class {}
+			`,
+			"/using.js": `
+				export function fn() { return res }
+				using res = null
+			`,
+		},
+		entryPaths: []string{"/entry.js", "/using.js"},
+		options: config.Options{
+			Mode:         config.ModeConvertFormat,
+			OutputFormat: config.FormatSystem,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestExportFormsUMD(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				export default 123
+				export var v = 234
+				export let l = 234
+				export const c = 234
+				export {Class as C}
+				export function Fn() {}
+				export class Class {}
+				export * from './a'
+				export * as b from './b'
+			`,
+			"/a.js": "export const abc = undefined",
+			"/b.js": "export const xyz = null",
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			GlobalName:    []string{"globalName"},
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestUMDExternals(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import React from 'react'
+				import { debounce } from 'lodash'
+				import 'polyfill'
+				const dom = require('react-dom')
+				export let render = debounce(() => dom.render(React.createElement('div')))
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			GlobalName:    []string{"MyLib", "widgets"},
+			AbsOutputFile: "/out.js",
+			UMDExternals: map[string]config.UMDExternal{
+				"react":    {AMD: "vendor/react", Global: []string{"React"}},
+				"lodash":   {CommonJS: "lodash-es", Global: []string{"_"}},
+				"polyfill": {Global: []string{"Polyfills", "core"}},
+			},
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"react":     true,
+					"lodash":    true,
+					"polyfill":  true,
+					"react-dom": true,
+				}},
+			},
+		},
+		expectedCompileLog: `entry.js: WARNING: Guessing the global variable "react_dom" for the external module "react-dom"
+NOTE: You can use the "umdExternals" setting to configure the global variable that the "umd" format uses for this module when there is no module loader.
+`,
+	})
+}
+
+func TestUMDCommonJSEntry(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				module.exports = require('ext').value
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatUMD,
+			AbsOutputFile: "/out.js",
+			UMDExternals: map[string]config.UMDExternal{
+				"ext": {Global: []string{"Ext"}},
+			},
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"ext": true,
+				}},
+			},
+		},
+	})
+}
+
+func TestUMDMinifyWhitespace(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { x } from 'ext'
+				export let y = x
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:             config.ModeBundle,
+			OutputFormat:     config.FormatUMD,
+			MinifyWhitespace: true,
+			GlobalName:       []string{"lib"},
+			AbsOutputFile:    "/out.js",
+			UMDExternals: map[string]config.UMDExternal{
+				"ext": {Global: []string{"Ext"}},
+			},
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{Exact: map[string]bool{
+					"ext": true,
+				}},
+			},
+		},
+	})
+}
+
 func TestExportFormsWithMinifyIdentifiersAndNoBundle(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -4351,6 +4730,128 @@ func TestTopLevelAwaitAllowedImportWithSplitting(t *testing.T) {
 	})
 }
 
+func TestTopLevelAwaitLoweredCJS(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { a } from './a'
+				import './sync'
+				export let result = a + 1
+				for await (let x of [result]) console.log(x)
+			`,
+			"/a.js": `
+				import { b } from './b'
+				await sleep()
+				export let a = b * 10
+			`,
+			"/b.js": `
+				export let b = await Promise.resolve(4)
+			`,
+			"/sync.js": `
+				console.log('sync')
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:               config.ModeBundle,
+			OutputFormat:       config.FormatCommonJS,
+			AbsOutputFile:      "/out.js",
+			LowerTopLevelAwait: true,
+		},
+	})
+}
+
+func TestTopLevelAwaitLoweredIIFE(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import './before'
+				import { value } from './async'
+				export { value }
+			`,
+			"/before.js": `
+				console.log('before')
+			`,
+			"/async.js": `
+				export let value = await fetch('/value')
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:               config.ModeBundle,
+			OutputFormat:       config.FormatIIFE,
+			GlobalName:         []string{"globalName"},
+			AbsOutputFile:      "/out.js",
+			LowerTopLevelAwait: true,
+		},
+	})
+}
+
+func TestTopLevelAwaitLoweredESMTarget(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { value } from './async'
+				export { value }
+			`,
+			"/async.js": `
+				export let value = await fetch('/value')
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			OutputFormat:          config.FormatESModule,
+			UnsupportedJSFeatures: compat.TopLevelAwait,
+			AbsOutputFile:         "/out.js",
+			LowerTopLevelAwait:    true,
+		},
+	})
+}
+
+func TestTopLevelAwaitLoweredNoAsyncAwait(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				await foo
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			OutputFormat:          config.FormatCommonJS,
+			UnsupportedJSFeatures: compat.AsyncAwait | compat.TopLevelAwait,
+			AbsOutputFile:         "/out.js",
+			LowerTopLevelAwait:    true,
+		},
+		expectedScanLog: `entry.js: ERROR: Top-level await is not available in the configured target environment
+`,
+	})
+}
+
+func TestTopLevelAwaitLoweredForbiddenRequire(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				require('./a')
+			`,
+			"/a.js": `
+				await 0
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:               config.ModeBundle,
+			OutputFormat:       config.FormatCommonJS,
+			AbsOutputFile:      "/out.js",
+			LowerTopLevelAwait: true,
+		},
+		expectedScanLog: `entry.js: ERROR: This require call is not allowed because the imported file "a.js" contains a top-level await
+a.js: NOTE: The top-level await in "a.js" is here:
+`,
+	})
+}
+
 func TestAssignToImport(t *testing.T) {
 	default_suite.expectBundled(t, bundled{
 		files: map[string]string{
@@ -9358,3 +9859,362 @@ func TestResolveExtensionsOrderIssue4053(t *testing.T) {
 		},
 	})
 }
+
+func TestPluginOnOutput(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { shared } from './shared'
+				console.log('a', shared)
+			`,
+			"/b.js": `
+				import { shared } from './shared'
+				console.log('b', shared)
+			`,
+			"/shared.js": `export let shared = 'shared'`,
+			"/style.css": `a { color: red }`,
+		},
+		entryPaths: []string{"/a.js", "/b.js", "/style.css"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnOutput: []config.OnOutput{{
+					Filter: regexp.MustCompile(`\.js$`),
+					Callback: func(args config.OnOutputArgs) config.OnOutputResult {
+						base := func(path string) string {
+							return path[strings.LastIndexAny(path, "/\\")+1:]
+						}
+						modules := make([]string, len(args.Modules))
+						for i, module := range args.Modules {
+							modules[i] = base(module)
+						}
+						contents := strings.ReplaceAll(args.Contents, "console.log", "console.info")
+						contents += "// path: " + base(args.Path) + "\n"
+						contents += "// entry point: " + base(args.EntryPoint) + "\n"
+						contents += "// modules: " + strings.Join(modules, ", ") + "\n"
+						return config.OnOutputResult{Contents: &contents}
+					},
+				}, {
+					Filter: regexp.MustCompile(`\.js$`),
+					Callback: func(args config.OnOutputArgs) config.OnOutputResult {
+						contents := args.Contents + "// second callback\n"
+						return config.OnOutputResult{Contents: &contents}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestPluginOnOutputErrors(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `console.log('a')`,
+			"/b.js": `console.log('b')`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			SourceMap:    config.SourceMapExternalWithoutComment,
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnOutput: []config.OnOutput{{
+					Filter: regexp.MustCompile(`a\.js$`),
+					Callback: func(args config.OnOutputArgs) config.OnOutputResult {
+						return config.OnOutputResult{ThrownError: errors.New("failed")}
+					},
+				}, {
+					Filter: regexp.MustCompile(`b\.js$`),
+					Callback: func(args config.OnOutputArgs) config.OnOutputResult {
+						sourceMap := `{"version": 3, "sources": ["b.js"], "mappings": "A!"}`
+						return config.OnOutputResult{SourceMap: &sourceMap}
+					},
+				}},
+			}},
+		},
+		expectedCompileLog: `ERROR: failed
+ERROR: Invalid source map returned for "out/b.js"
+NOTE: Bad "mappings" data in source map at character 1: Missing source index
+`,
+	})
+}
+
+func TestPluginOnOutputSourceMap(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { foo } from './foo'
+				console.log(foo())
+			`,
+			"/foo.js": `
+				export function foo() {
+					return 'foo'
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			SourceMap:     config.SourceMapLinkedWithComment,
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnOutput: []config.OnOutput{{
+					Filter: regexp.MustCompile(`.*`),
+					Callback: func(args config.OnOutputArgs) config.OnOutputResult {
+						// Insert a line at the top and map every character to where it was
+						var mappings []sourcemap.Mapping
+						for line, text := range strings.Split(args.Contents, "\n") {
+							for column := range text {
+								mappings = append(mappings, sourcemap.Mapping{
+									GeneratedLine:   int32(line + 1),
+									GeneratedColumn: int32(column),
+									OriginalLine:    int32(line),
+									OriginalColumn:  int32(column),
+								})
+							}
+						}
+						contents := "// banner\n" + args.Contents
+						sourceMap := `{"version": 3, "sources": ["out.js"], "names": [], "mappings": "` +
+							string(sourcemap.EncodeMappings(mappings)) + `"}`
+						return config.OnOutputResult{Contents: &contents, SourceMap: &sourceMap}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestPluginOnOutputErrorAfterChange(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `console.log('entry')`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			Plugins: []config.Plugin{{
+				Name: "banner",
+				OnOutput: []config.OnOutput{{
+					Filter: regexp.MustCompile(`.*`),
+					Callback: func(args config.OnOutputArgs) config.OnOutputResult {
+						contents := "// banner\n" + args.Contents
+						return config.OnOutputResult{Contents: &contents}
+					},
+				}},
+			}, {
+				Name: "fail",
+				OnOutput: []config.OnOutput{{
+					Filter: regexp.MustCompile(`.*`),
+					Callback: func(args config.OnOutputArgs) config.OnOutputResult {
+						return config.OnOutputResult{ThrownError: errors.New("failed")}
+					},
+				}},
+			}},
+		},
+		expectedCompileLog: `ERROR: failed
+NOTE: The changes made to this file by earlier "onOutput" callbacks from plugin "banner" were discarded
+`,
+	})
+}
+
+// Tools that prepend a banner often only generate one mapping per line, which
+// points to the start of the original line. The start of an indented line has
+// no mapping of its own in the chunk's source map, so the closest earlier one
+// should be used instead of dropping the line.
+func TestPluginOnOutputSourceMapBanner(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { foo } from './foo'
+				console.log(foo())
+			`,
+			"/foo.js": `
+				export function foo() {
+					return 'foo'
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			SourceMap:     config.SourceMapLinkedWithComment,
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnOutput: []config.OnOutput{{
+					Filter: regexp.MustCompile(`.*`),
+					Callback: func(args config.OnOutputArgs) config.OnOutputResult {
+						var mappings []sourcemap.Mapping
+						for line := range strings.Split(args.Contents, "\n") {
+							mappings = append(mappings, sourcemap.Mapping{
+								GeneratedLine: int32(line + 3),
+								OriginalLine:  int32(line),
+							})
+						}
+						contents := "/*\n * banner\n */\n" + args.Contents
+						sourceMap := `{"version": 3, "sources": ["out.js"], "names": [], "mappings": "` +
+							string(sourcemap.EncodeMappings(mappings)) + `"}`
+						return config.OnOutputResult{Contents: &contents, SourceMap: &sourceMap}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestPluginOnTransform(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import './style.css'
+				import { version } from 'virtual:version'
+				console.log(__VERSION__, version)
+			`,
+			"/style.css": `a { color: __COLOR__ }`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			Plugins: []config.Plugin{{
+				Name: "virtual",
+				OnResolve: []config.OnResolve{{
+					Filter: regexp.MustCompile(`^virtual:`),
+					Callback: func(args config.OnResolveArgs) config.OnResolveResult {
+						return config.OnResolveResult{Path: logger.Path{Text: args.Path, Namespace: "virtual"}}
+					},
+				}},
+				OnLoad: []config.OnLoad{{
+					Filter: regexp.MustCompile(`.*`),
+					Callback: func(args config.OnLoadArgs) config.OnLoadResult {
+						contents := `export let version = __VERSION__`
+						return config.OnLoadResult{Contents: &contents}
+					},
+					Namespace: "virtual",
+				}},
+			}, {
+				Name: "transforms",
+				OnTransform: []config.OnTransform{{
+					Filter: regexp.MustCompile(`.*`),
+					Callback: func(args config.OnTransformArgs) config.OnTransformResult {
+						contents := strings.ReplaceAll(args.Contents, "__VERSION__", `"1.2.3"`)
+						contents = strings.ReplaceAll(contents, "__COLOR__", "red")
+						return config.OnTransformResult{Contents: &contents}
+					},
+				}, {
+					Filter: regexp.MustCompile(`.*`),
+					Callback: func(args config.OnTransformArgs) config.OnTransformResult {
+						// This callback sees the output of the previous one
+						comment := fmt.Sprintf("loader=%s version=%v", config.LoaderToString[args.Loader], strings.Contains(args.Contents, "1.2.3"))
+						contents := "/*! " + comment + " */\n" + args.Contents
+						return config.OnTransformResult{Contents: &contents}
+					},
+				}, {
+					Filter:    regexp.MustCompile(`.*`),
+					Namespace: "file",
+					Callback: func(args config.OnTransformArgs) config.OnTransformResult {
+						return config.OnTransformResult{} // Returning nothing leaves the contents alone
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestPluginOnTransformErrors(t *testing.T) {
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import './a'
+				import './b'
+			`,
+			"/a.js": `console.log('a')`,
+			"/b.js": `console.log('b')`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			SourceMap:     config.SourceMapExternalWithoutComment,
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnTransform: []config.OnTransform{{
+					Filter: regexp.MustCompile(`a\.js$`),
+					Callback: func(args config.OnTransformArgs) config.OnTransformResult {
+						return config.OnTransformResult{ThrownError: errors.New("failed")}
+					},
+				}, {
+					Filter: regexp.MustCompile(`b\.js$`),
+					Callback: func(args config.OnTransformArgs) config.OnTransformResult {
+						sourceMap := `{"version": 3, "sources": ["b.js"], "mappings": "A!"}`
+						return config.OnTransformResult{SourceMap: &sourceMap}
+					},
+				}},
+			}},
+		},
+		expectedScanLog: `entry.js: ERROR: failed
+entry.js: ERROR: Invalid source map returned for "b.js"
+NOTE: Bad "mappings" data in source map at character 1: Missing source index
+`,
+	})
+}
+
+func TestPluginOnTransformSourceMap(t *testing.T) {
+	// Insert a line at the top and map every character to where it was
+	insertLine := func(line string) config.OnTransform {
+		return config.OnTransform{
+			Filter: regexp.MustCompile(`.*`),
+			Callback: func(args config.OnTransformArgs) config.OnTransformResult {
+				var mappings []sourcemap.Mapping
+				for line, text := range strings.Split(args.Contents, "\n") {
+					for column := range text {
+						mappings = append(mappings, sourcemap.Mapping{
+							GeneratedLine:   int32(line + 1),
+							GeneratedColumn: int32(column),
+							OriginalLine:    int32(line),
+							OriginalColumn:  int32(column),
+						})
+					}
+				}
+				contents := line + "\n" + args.Contents
+				sourceMap := `{"version": 3, "sources": ["input"], "names": [], "mappings": "` +
+					string(sourcemap.EncodeMappings(mappings)) + `"}`
+				return config.OnTransformResult{Contents: &contents, SourceMap: &sourceMap}
+			},
+		}
+	}
+
+	default_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { foo } from './foo'
+				console.log(foo())
+			`,
+			"/foo.js": `
+				export function foo() {
+					return 'foo'
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.js",
+			SourceMap:     config.SourceMapLinkedWithComment,
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnTransform: []config.OnTransform{
+					insertLine("console.log('first')"),
+					insertLine("console.log('second')"),
+				},
+			}},
+		},
+	})
+}
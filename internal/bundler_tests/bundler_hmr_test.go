@@ -0,0 +1,112 @@
+package bundler_tests
+
+import (
+	"testing"
+
+	"github.com/evanw/esbuild/internal/config"
+)
+
+var hmr_suite = suite{
+	name: "hmr",
+}
+
+func TestHMRWrapsAllModules(t *testing.T) {
+	hmr_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { render } from './app'
+				render()
+			`,
+			"/app.js": `
+				import { count } from './state'
+				export function render() {
+					console.log(format(count))
+				}
+				function format(x) {
+					return '[' + x + ']'
+				}
+				import.meta.hot.accept()
+			`,
+			"/state.js": `
+				export let count = import.meta.hot.data.count || 0
+				import.meta.hot.dispose(data => {
+					data.count = count
+				})
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			AbsOutputFile:        "/out.js",
+			HotModuleReplacement: true,
+		},
+	})
+}
+
+func TestHMRFormatIIFE(t *testing.T) {
+	hmr_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { value } from './value'
+				console.log(value)
+				if (import.meta.hot) import.meta.hot.accept()
+			`,
+			"/value.js": `
+				export default 1
+				export const value = 2
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			OutputFormat:         config.FormatIIFE,
+			AbsOutputFile:        "/out.js",
+			HotModuleReplacement: true,
+		},
+	})
+}
+
+func TestHMRCommonJS(t *testing.T) {
+	hmr_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import lib from './lib.cjs'
+				export const x = lib()
+			`,
+			"/lib.cjs": `
+				module.exports = () => 123
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			OutputFormat:         config.FormatESModule,
+			AbsOutputFile:        "/out.js",
+			HotModuleReplacement: true,
+		},
+	})
+}
+
+func TestHMRMinify(t *testing.T) {
+	hmr_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { foo } from './foo'
+				foo()
+			`,
+			"/foo.js": `
+				export function foo() {
+					import.meta.hot.accept()
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                 config.ModeBundle,
+			AbsOutputFile:        "/out.js",
+			MinifyWhitespace:     true,
+			MinifySyntax:         true,
+			HotModuleReplacement: true,
+		},
+	})
+}
@@ -0,0 +1,239 @@
+package bundler_tests
+
+import (
+	"testing"
+
+	"github.com/evanw/esbuild/internal/config"
+)
+
+var html_suite = suite{
+	name: "html",
+}
+
+func TestHTMLEntryPoint(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `<!DOCTYPE html>
+<html>
+  <head>
+    <link rel="stylesheet" href="./style.css">
+    <script type="module" src="./main.js"></script>
+  </head>
+  <body></body>
+</html>
+`,
+			"/src/main.js": `
+				import { greet } from './greet'
+				greet()
+			`,
+			"/src/greet.js": `
+				export function greet() { console.log('hi') }
+			`,
+			"/src/style.css": `
+				body { color: red }
+			`,
+		},
+		entryPaths: []string{"/src/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestHTMLEntryPointRootRelative(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/app/index.html": `<!DOCTYPE html>
+<html>
+  <head>
+    <link rel="stylesheet" href="/src/style.css">
+    <script type="module" src="/src/main.js"></script>
+    <script src="//cdn.example.com/lib.js"></script>
+  </head>
+  <body></body>
+</html>
+`,
+			"/app/src/main.js": `
+				console.log('main')
+			`,
+			"/app/src/style.css": `
+				body { color: red }
+			`,
+		},
+		entryPaths: []string{"/app/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestHTMLEntryPointHashedNames(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/index.html": `<script type="module" src="main.js"></script>`,
+			"/src/main.js":    `console.log('main')`,
+		},
+		entryPaths: []string{"/src/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+			EntryPathTemplate: []config.PathTemplate{
+				// "[dir]/[name]-[hash]"
+				{Data: "./", Placeholder: config.DirPlaceholder},
+				{Data: "/", Placeholder: config.NamePlaceholder},
+				{Data: "-", Placeholder: config.HashPlaceholder},
+			},
+		},
+	})
+}
+
+func TestHTMLEntryPointImportedCSS(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `<html>
+  <body>
+    <script type="module" src="./app.js"></script>
+  </body>
+</html>
+`,
+			"/app.js": `
+				import './app.css'
+				console.log('app')
+			`,
+			"/app.css": `
+				.app { color: blue }
+			`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestHTMLEntryPointSplittingPreload(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.html": `<head>
+	<script type="module" src="./a.js"></script>
+</head>
+`,
+			"/b.html": `<head>
+	<script type="module" src="./b.js"></script>
+</head>
+`,
+			"/a.js": `
+				import { shared } from './shared'
+				console.log('a', shared)
+				import('./lazy')
+			`,
+			"/b.js": `
+				import { shared } from './shared'
+				console.log('b', shared)
+				import('./lazy')
+			`,
+			"/shared.js": `export let shared = 123`,
+			"/lazy.js":   `console.log('lazy')`,
+		},
+		entryPaths: []string{"/a.html", "/b.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			CodeSplitting: true,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestHTMLEntryPointIgnoredTags(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `<html>
+<head>
+  <!-- <script type="module" src="./commented.js"></script> -->
+  <script src="./classic.js"></script>
+  <script type="module" src="https://example.com/remote.js"></script>
+  <link rel="icon" href="./favicon.ico">
+  <link rel=stylesheet href=style.css>
+  <script>
+    document.write('<script type="module" src="./inline.js"></script>')
+  </script>
+</head>
+</html>
+`,
+			"/style.css": `a { color: red }`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+	})
+}
+
+func TestHTMLEntryPointMetafile(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `<script type="module" src="./main.js"></script>`,
+			"/main.js": `
+				import './main.css'
+				console.log('main')
+			`,
+			"/main.css": `main { display: block }`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputDir:  "/out",
+			NeedsMetafile: true,
+		},
+	})
+}
+
+func TestHTMLEntryPointErrors(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `
+				<script type="module" src="./style.css"></script>
+				<link rel="stylesheet" href="./main.js">
+			`,
+			"/other.js": `
+				import './index.html'
+			`,
+			"/main.js":   `console.log('main')`,
+			"/style.css": `a { color: red }`,
+		},
+		entryPaths: []string{"/index.html", "/other.js"},
+		options: config.Options{
+			Mode:         config.ModeBundle,
+			AbsOutputDir: "/out",
+		},
+		expectedScanLog: `index.html: ERROR: Cannot use "style.css" as a script
+NOTE: A "<script>" tag can only reference a JavaScript file and "style.css" is not a JavaScript file (it was loaded with the "css" loader).
+index.html: ERROR: Cannot use "main.js" as a stylesheet
+NOTE: A "<link rel="stylesheet">" tag can only reference a CSS file and "main.js" is not a CSS file (it was loaded with the "js" loader).
+other.js: ERROR: Cannot import "index.html"
+NOTE: The file "index.html" was loaded with the "html" loader, which can only be used for entry points.
+`,
+	})
+}
+
+func TestHTMLEntryPointOutfile(t *testing.T) {
+	html_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/index.html": `<script type="module" src="./main.js"></script>`,
+			"/main.js":    `console.log('main')`,
+		},
+		entryPaths: []string{"/index.html"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			AbsOutputFile: "/out.html",
+		},
+		expectedScanLog: `index.html: ERROR: Cannot reference "main.js" without an output directory configured
+NOTE: Files referenced by HTML files are written to separate output files, so you must use "outdir" instead of "outfile" when bundling HTML files.
+`,
+	})
+}
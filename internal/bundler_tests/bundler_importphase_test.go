@@ -144,13 +144,6 @@ func TestImportDeferInternalESM(t *testing.T) {
 			OutputFormat:  config.FormatESModule,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: ERROR: Bundling with deferred imports is not supported unless they are external
-entry.js: ERROR: Bundling with deferred imports is not supported unless they are external
-entry.js: ERROR: Bundling with deferred imports is not supported unless they are external
-entry.js: ERROR: Bundling with deferred imports is not supported unless they are external
-entry.js: ERROR: Bundling with deferred imports is not supported unless they are external
-entry.js: ERROR: Bundling with deferred imports is not supported unless they are external
-`,
 	})
 }
 
@@ -178,13 +171,6 @@ func TestImportDeferInternalCommonJS(t *testing.T) {
 			OutputFormat:  config.FormatCommonJS,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: ERROR: Bundling deferred imports with the "cjs" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "cjs" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "cjs" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "cjs" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "cjs" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "cjs" output format is not supported
-`,
 	})
 }
 
@@ -212,12 +198,76 @@ func TestImportDeferInternalIIFE(t *testing.T) {
 			OutputFormat:  config.FormatIIFE,
 			AbsOutputFile: "/out.js",
 		},
-		expectedScanLog: `entry.js: ERROR: Bundling deferred imports with the "iife" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "iife" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "iife" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "iife" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "iife" output format is not supported
-entry.js: ERROR: Bundling deferred imports with the "iife" output format is not supported
+	})
+}
+
+func TestImportDeferInternalLowered(t *testing.T) {
+	importphase_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import defer * as esm from './esm.js'
+				import defer * as cjs from './cjs.js'
+				console.log(esm.foo, cjs.bar, import.defer('./esm.js'))
+			`,
+			"/esm.js": `export let foo = 123`,
+			"/cjs.js": `exports.bar = 123`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			OutputFormat:          config.FormatIIFE,
+			AbsOutputFile:         "/out.js",
+			UnsupportedJSFeatures: es(5),
+		},
+	})
+}
+
+func TestImportDeferInternalTopLevelAwait(t *testing.T) {
+	importphase_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import defer * as foo from './foo.js'
+				import defer * as bar from './bar.js'
+				console.log(foo.foo, bar.bar)
+			`,
+			"/foo.js": `export let foo = await 123`,
+			"/bar.js": `export let bar = 123`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputFile: "/out.js",
+		},
+	})
+}
+
+func TestImportDeferExternalUnsupported(t *testing.T) {
+	importphase_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import defer * as foo from 'foo'
+				import defer * as bar from './bar.js'
+				console.log(foo, bar, import.defer('foo'), import.defer(baz))
+			`,
+			"/bar.js": `export let bar = 123`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			OutputFormat:          config.FormatESModule,
+			AbsOutputFile:         "/out.js",
+			OriginalTargetEnv:     "es2020",
+			UnsupportedJSFeatures: es(2020),
+			ExternalSettings: config.ExternalSettings{
+				PreResolve: config.ExternalMatchers{
+					Exact: map[string]bool{"foo": true},
+				},
+			},
+		},
+		expectedScanLog: `entry.js: ERROR: Deferred imports are not available in the configured target environment (es2020) unless they are bundled
+entry.js: ERROR: Deferred imports are not available in the configured target environment (es2020) unless they are bundled
+entry.js: ERROR: Deferred imports are not available in the configured target environment (es2020) unless they are bundled
 `,
 	})
 }
@@ -921,14 +921,75 @@ func TestLowerAsyncES5(t *testing.T) {
 			UnsupportedJSFeatures: es(5),
 			AbsOutputFile:         "/out.js",
 		},
-		expectedScanLog: `arrow-1.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-arrow-2.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-export-def-1.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-export-def-2.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-fn-expr.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-fn-stmt.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-obj-method.js: ERROR: Transforming async functions to the configured target environment is not supported yet
-`,
+	})
+}
+
+func TestLowerGeneratorsES5(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				function* gen(x) {
+					try {
+						while (x--) {
+							if (yield x) break
+						}
+					} catch (e) {
+						yield* other(e)
+					} finally {
+						console.log('done')
+					}
+				}
+				async function fn(list) {
+					for await (const x of list) console.log(x)
+					return await list.length
+				}
+				export { gen, fn }
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModePassThrough,
+			UnsupportedJSFeatures: es(5),
+			AbsOutputFile:         "/out.js",
+		},
+	})
+}
+
+func TestLowerClassesAndBlockScopingES5(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import Derived from './derived'
+				const fns = []
+				for (let i = 0; i < 3; i++) fns.push(() => new Derived(i))
+				for (const [key, fn] of fns.entries()) console.log(key, fn().sum())
+			`,
+			"/derived.js": `
+				import { Base } from './base'
+				export default class extends Base {
+					y = 2
+					constructor(x = 1, ...rest) {
+						super(x, ...rest)
+					}
+					sum() {
+						return super.sum() + this.y
+					}
+				}
+			`,
+			"/base.js": `
+				export class Base {
+					constructor(x) { this.x = x }
+					sum() { return this.x }
+					static create({ x, ...rest }) { return new this(x) }
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			UnsupportedJSFeatures: es(5),
+			AbsOutputFile:         "/out.js",
+		},
 	})
 }
 
@@ -2337,6 +2398,40 @@ func TestLowerRegExpNameCollision(t *testing.T) {
 			AbsOutputFile:         "/out.js",
 			UnsupportedJSFeatures: es(2021),
 		},
+		expectedScanLog: `entry.js: WARNING: The regular expression flag "d" is not available in the configured target environment
+NOTE: This regular expression literal has been converted to a "new RegExp()" constructor to avoid generating code with a syntax error. However, you will need to include a polyfill for "RegExp" for your code to have the correct behavior at run-time.
+`,
+	})
+}
+
+func TestLowerBigIntLibrary(t *testing.T) {
+	lower_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { mod } from './mod'
+				const two = 2n
+				console.log(mod(two ** 64n + 1n, BigInt(1e9)), -1n, x * two)
+			`,
+			"/mod.js": `
+				export const mod = (a, b) => BigInt.asUintN(64, a) % b
+			`,
+			"/node_modules/jsbi/index.js": `
+				// The library itself must not be rewritten
+				export default {
+					BigInt: x => typeof BigInt === 'function' ? BigInt(x) : fallback(x),
+				}
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			AbsOutputFile:         "/out.js",
+			UnsupportedJSFeatures: es(2019),
+			BigIntLibrary:         "jsbi",
+		},
+		expectedScanLog: `entry.js: WARNING: This operator can't be converted into a call to the big integer library
+NOTE: Both operands must be known to be big integers at compile time. Consider using "BigInt()" to convert the other operand into a big integer.
+`,
 	})
 }
 
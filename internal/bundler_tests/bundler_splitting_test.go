@@ -1,8 +1,11 @@
 package bundler_tests
 
 import (
+	"errors"
+	"regexp"
 	"testing"
 
+	"github.com/evanw/esbuild/internal/compat"
 	"github.com/evanw/esbuild/internal/config"
 )
 
@@ -597,3 +600,312 @@ func TestEdgeCaseIssue2793WithoutSplitting(t *testing.T) {
 		},
 	})
 }
+
+func TestSplittingManualChunksPackage(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/a.js": `
+				import { render } from 'ui-lib'
+				import { shared } from './shared'
+				render(shared)
+			`,
+			"/src/b.js": `
+				import { render } from 'ui-lib'
+				render('b')
+			`,
+			"/src/c.js": `
+				import { shared } from './shared'
+				console.log(shared)
+			`,
+			"/src/shared.js": `export let shared = 'shared'`,
+			"/node_modules/ui-lib/index.js": `
+				import { h } from './h'
+				export function render(x) { return h(x) }
+			`,
+			"/node_modules/ui-lib/h.js": `export function h(x) { return [x] }`,
+		},
+		entryPaths: []string{"/src/a.js", "/src/b.js", "/src/c.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{{
+				Name:     "vendor",
+				Patterns: []*regexp.Regexp{regexp.MustCompile(`/node_modules/ui-lib/`)},
+			}},
+		},
+	})
+}
+
+func TestSplittingManualChunksPullsInDependencies(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/src/a.js": `
+				import { format } from './utils/format'
+				console.log(format('a'))
+			`,
+			"/src/b.js": `
+				import { helper } from './helper'
+				console.log(helper)
+			`,
+			"/src/utils/format.js": `
+				import { helper } from '../helper'
+				export let format = x => helper + x
+			`,
+			"/src/helper.js": `export let helper = 'helper'`,
+		},
+		entryPaths: []string{"/src/a.js", "/src/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ChunkPathTemplate: []config.PathTemplate{
+				// "chunks/[name]-[hash]"
+				{Data: "chunks/", Placeholder: config.NamePlaceholder},
+				{Data: "-", Placeholder: config.HashPlaceholder},
+			},
+			ManualChunks: []config.ManualChunk{{
+				Name:     "utils",
+				Patterns: []*regexp.Regexp{regexp.MustCompile(`/src/utils/`)},
+			}},
+		},
+	})
+}
+
+func TestSplittingManualChunksCommonJS(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { value } from 'cjs-lib'
+				console.log(value)
+			`,
+			"/node_modules/cjs-lib/index.js": `exports.value = 123`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{{
+				Name:     "vendor",
+				Patterns: []*regexp.Regexp{regexp.MustCompile(`/node_modules/cjs-lib/`)},
+			}},
+		},
+	})
+}
+
+func TestSplittingManualChunksPlugin(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import { x } from './x'
+				import { y } from './y'
+				console.log(x, y)
+			`,
+			"/b.js": `
+				import { y } from './y'
+				console.log(y)
+			`,
+			"/x.js": `export let x = 'x'`,
+			"/y.js": `export let y = 'y'`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{{
+				Name:     "ignored",
+				Patterns: []*regexp.Regexp{regexp.MustCompile(`/x\.js$`)},
+			}},
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnManualChunk: []config.OnManualChunk{{
+					Filter: regexp.MustCompile(`[xy]\.js$`),
+					Callback: func(args config.OnManualChunkArgs) config.OnManualChunkResult {
+						return config.OnManualChunkResult{ChunkName: "from-plugin"}
+					},
+				}},
+			}},
+		},
+	})
+}
+
+func TestSplittingManualChunksPluginErrors(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import './x'
+				import './y'
+			`,
+			"/x.js": `console.log('x')`,
+			"/y.js": `console.log('y')`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			Plugins: []config.Plugin{{
+				Name: "plugin",
+				OnManualChunk: []config.OnManualChunk{{
+					Filter: regexp.MustCompile(`x\.js$`),
+					Callback: func(args config.OnManualChunkArgs) config.OnManualChunkResult {
+						return config.OnManualChunkResult{ChunkName: "../escape"}
+					},
+				}, {
+					Filter: regexp.MustCompile(`y\.js$`),
+					Callback: func(args config.OnManualChunkArgs) config.OnManualChunkResult {
+						return config.OnManualChunkResult{ThrownError: errors.New("failed")}
+					},
+				}},
+			}},
+		},
+		expectedCompileLog: `ERROR: failed
+ERROR: Invalid manual chunk name: "../escape"
+`,
+	})
+}
+
+func TestSplittingManualChunksCycle(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `
+				import { a } from './a'
+				import { b } from './b'
+				console.log(a, b)
+			`,
+			"/a.js": `
+				import { b } from './b'
+				export let a = () => b
+			`,
+			"/b.js": `
+				import { a } from './a'
+				export let b = () => a
+			`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatESModule,
+			AbsOutputDir:  "/out",
+			ManualChunks: []config.ManualChunk{
+				{Name: "a", Patterns: []*regexp.Regexp{regexp.MustCompile(`/a\.js$`)}},
+				{Name: "b", Patterns: []*regexp.Regexp{regexp.MustCompile(`/b\.js$`)}},
+			},
+		},
+		expectedCompileLog: `ERROR: Cannot create the manual chunk "b" because it results in a circular import between chunks
+NOTE: This happens when code in a manual chunk imports code that's in another chunk which imports the manual chunk. You may be able to fix this by moving the shared code into a manual chunk too.
+`,
+	})
+}
+
+func TestSplittingSharedES6IntoCommonJS(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {count, inc} from "./shared.js"
+				inc()
+				console.log(count)
+				export let a = import("./lazy.js")
+			`,
+			"/b.js": `
+				import {count} from "./shared.js"
+				import "./side-effect.js"
+				console.log(count)
+			`,
+			"/shared.js": `
+				export let count = 0
+				export function inc() { count++ }
+			`,
+			"/side-effect.js": `console.log('side effect')`,
+			"/lazy.js":        `export default 'lazy'`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatCommonJS,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingSharedES6IntoIIFE(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {count, inc} from "./shared.js"
+				inc()
+				console.log(count)
+				export let a = import("./lazy.js")
+			`,
+			"/b.js": `
+				import {count} from "./shared.js"
+				import "./side-effect.js"
+				console.log(count)
+			`,
+			"/shared.js": `
+				export let count = 0
+				export function inc() { count++ }
+			`,
+			"/side-effect.js": `console.log('side effect')`,
+			"/lazy.js":        `export default 'lazy'`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatIIFE,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingDynamicCommonJSIntoIIFE(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/entry.js": `import("./foo.js").then(({default: {bar}}) => console.log(bar))`,
+			"/foo.js":   `exports.bar = 123`,
+		},
+		entryPaths: []string{"/entry.js"},
+		options: config.Options{
+			Mode:          config.ModeBundle,
+			CodeSplitting: true,
+			OutputFormat:  config.FormatIIFE,
+			AbsOutputDir:  "/out",
+		},
+	})
+}
+
+func TestSplittingSharedES6IntoIIFEMinify(t *testing.T) {
+	splitting_suite.expectBundled(t, bundled{
+		files: map[string]string{
+			"/a.js": `
+				import {foo} from "./shared.js"
+				console.log(foo)
+			`,
+			"/b.js": `
+				import {foo} from "./shared.js"
+				console.log(foo)
+			`,
+			"/shared.js": `export let foo = 123`,
+		},
+		entryPaths: []string{"/a.js", "/b.js"},
+		options: config.Options{
+			Mode:                  config.ModeBundle,
+			CodeSplitting:         true,
+			OutputFormat:          config.FormatIIFE,
+			MinifyIdentifiers:     true,
+			MinifyWhitespace:      true,
+			UnsupportedJSFeatures: compat.Arrow,
+			AbsOutputDir:          "/out",
+		},
+	})
+}
@@ -60,6 +60,14 @@ func MakeCacheSet() *CacheSet {
 	}
 }
 
+// Parser output is also stored on the file system when this is enabled. That
+// allows it to be reused across process restarts (see "DiskCache").
+func (c *CacheSet) EnableDiskCache(disk *DiskCache) {
+	c.CSSCache.disk = disk
+	c.JSONCache.disk = disk
+	c.JSCache.disk = disk
+}
+
 type SourceIndexCache struct {
 	globEntries     map[uint64]uint32
 	entries         map[sourceIndexKey]uint32
@@ -28,6 +28,7 @@ import (
 
 type CSSCache struct {
 	entries map[logger.Path]*cssCacheEntry
+	disk    *DiskCache
 	mutex   sync.Mutex
 }
 
@@ -55,9 +56,15 @@ func (c *CSSCache) Parse(log logger.Log, source logger.Source, options css_parse
 	}
 
 	// Cache miss
-	tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
-	ast := css_parser.Parse(tempLog, source, options)
-	msgs := tempLog.Done()
+	var file cssCacheFile
+	diskPath, diskHit := c.disk.load(cacheFileCSS, source, &options, &file)
+	if !diskHit {
+		tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
+		file.AST = css_parser.Parse(tempLog, source, options)
+		file.Msgs = tempLog.Done()
+		c.disk.store(diskPath, source, &file)
+	}
+	ast, msgs := file.AST, file.Msgs
 	for _, msg := range msgs {
 		log.AddMsg(msg)
 	}
@@ -82,6 +89,7 @@ func (c *CSSCache) Parse(log logger.Log, source logger.Source, options css_parse
 
 type JSONCache struct {
 	entries map[logger.Path]*jsonCacheEntry
+	disk    *DiskCache
 	mutex   sync.Mutex
 }
 
@@ -110,9 +118,15 @@ func (c *JSONCache) Parse(log logger.Log, source logger.Source, options js_parse
 	}
 
 	// Cache miss
-	tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
-	expr, ok := js_parser.ParseJSON(tempLog, source, options)
-	msgs := tempLog.Done()
+	var file jsonCacheFile
+	diskPath, diskHit := c.disk.load(cacheFileJSON, source, &options, &file)
+	if !diskHit {
+		tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
+		file.Expr, file.OK = js_parser.ParseJSON(tempLog, source, options)
+		file.Msgs = tempLog.Done()
+		c.disk.store(diskPath, source, &file)
+	}
+	expr, ok, msgs := file.Expr, file.OK, file.Msgs
 	for _, msg := range msgs {
 		log.AddMsg(msg)
 	}
@@ -138,6 +152,7 @@ func (c *JSONCache) Parse(log logger.Log, source logger.Source, options js_parse
 
 type JSCache struct {
 	entries map[logger.Path]*jsCacheEntry
+	disk    *DiskCache
 	mutex   sync.Mutex
 }
 
@@ -166,9 +181,15 @@ func (c *JSCache) Parse(log logger.Log, source logger.Source, options js_parser.
 	}
 
 	// Cache miss
-	tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
-	ast, ok := js_parser.Parse(tempLog, source, options)
-	msgs := tempLog.Done()
+	var file jsCacheFile
+	diskPath, diskHit := c.disk.load(cacheFileJS, source, &options, &file)
+	if !diskHit {
+		tempLog := logger.NewDeferLog(logger.DeferLogAll, log.Overrides)
+		file.AST, file.OK = js_parser.Parse(tempLog, source, options)
+		file.Msgs = tempLog.Done()
+		c.disk.store(diskPath, source, &file)
+	}
+	ast, ok, msgs := file.AST, file.OK, file.Msgs
 	for _, msg := range msgs {
 		log.AddMsg(msg)
 	}
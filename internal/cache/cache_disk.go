@@ -0,0 +1,342 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"unsafe"
+
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_parser"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/runtime"
+)
+
+// This cache stores the output of the parsers in a directory on the file
+// system so that it can be reused by later builds, even ones that happen in
+// a different process. It sits underneath the in-memory caches above and is
+// only consulted when they miss.
+//
+// Each entry is stored in a file whose name is a hash of everything that the
+// parser output depends on:
+//
+//   - The contents and paths of the source file
+//   - The parser options, including all defines
+//   - The version of esbuild, so that a new release invalidates the cache
+//   - The cache format version below, which must be incremented whenever the
+//     parser output changes in a way that the type layout doesn't capture
+//   - The layout of all types involved in the parser output
+//
+// So entries never need to be invalidated. They are just never looked up
+// again. Nothing is ever deleted from the cache directory, so it's safe to
+// delete it at any time.
+
+type DiskCache struct {
+	dir          string
+	versionHash  [32]byte
+	pointerCache pointerHashCache
+}
+
+type pointerHashCache struct {
+	hashes map[unsafe.Pointer][32]byte
+	mutex  sync.Mutex
+}
+
+func (c *pointerHashCache) hashOf(key unsafe.Pointer, compute func() [32]byte) [32]byte {
+	if c == nil {
+		return compute()
+	}
+	c.mutex.Lock()
+	hash, ok := c.hashes[key]
+	c.mutex.Unlock()
+	if !ok {
+		hash = compute()
+		c.mutex.Lock()
+		c.hashes[key] = hash
+		c.mutex.Unlock()
+	}
+	return hash
+}
+
+type jsCacheFile struct {
+	Msgs []logger.Msg
+	AST  js_ast.AST
+	OK   bool
+}
+
+type cssCacheFile struct {
+	Msgs []logger.Msg
+	AST  css_ast.AST
+}
+
+type jsonCacheFile struct {
+	Msgs []logger.Msg
+	Expr js_ast.Expr
+	OK   bool
+}
+
+type cacheFileKind uint8
+
+const (
+	cacheFileJS cacheFileKind = iota
+	cacheFileCSS
+	cacheFileJSON
+)
+
+var diskCacheSerializer *serializer
+var diskCacheSerializerOnce sync.Once
+
+func getDiskCacheSerializer() *serializer {
+	diskCacheSerializerOnce.Do(func() {
+		diskCacheSerializer = newSerializer([]reflect.Type{
+			reflect.TypeOf(jsCacheFile{}),
+			reflect.TypeOf(cssCacheFile{}),
+			reflect.TypeOf(jsonCacheFile{}),
+			reflect.TypeOf(logger.Source{}),
+			reflect.TypeOf(js_parser.Options{}),
+			reflect.TypeOf(js_parser.JSONOptions{}),
+			reflect.TypeOf(css_parser.Options{}),
+		}, []reflect.Type{
+			// Bindings
+			reflect.TypeOf(&js_ast.BMissing{}),
+			reflect.TypeOf(&js_ast.BIdentifier{}),
+			reflect.TypeOf(&js_ast.BArray{}),
+			reflect.TypeOf(&js_ast.BObject{}),
+
+			// Expressions
+			reflect.TypeOf(&js_ast.EArray{}),
+			reflect.TypeOf(&js_ast.EUnary{}),
+			reflect.TypeOf(&js_ast.EBinary{}),
+			reflect.TypeOf(&js_ast.EBoolean{}),
+			reflect.TypeOf(&js_ast.ESuper{}),
+			reflect.TypeOf(&js_ast.ENull{}),
+			reflect.TypeOf(&js_ast.EUndefined{}),
+			reflect.TypeOf(&js_ast.EThis{}),
+			reflect.TypeOf(&js_ast.ENew{}),
+			reflect.TypeOf(&js_ast.ENewTarget{}),
+			reflect.TypeOf(&js_ast.EImportMeta{}),
+			reflect.TypeOf(&js_ast.ECall{}),
+			reflect.TypeOf(&js_ast.EDot{}),
+			reflect.TypeOf(&js_ast.EIndex{}),
+			reflect.TypeOf(&js_ast.EArrow{}),
+			reflect.TypeOf(&js_ast.EFunction{}),
+			reflect.TypeOf(&js_ast.EClass{}),
+			reflect.TypeOf(&js_ast.EIdentifier{}),
+			reflect.TypeOf(&js_ast.EImportIdentifier{}),
+			reflect.TypeOf(&js_ast.EPrivateIdentifier{}),
+			reflect.TypeOf(&js_ast.ENameOfSymbol{}),
+			reflect.TypeOf(&js_ast.EJSXElement{}),
+			reflect.TypeOf(&js_ast.EJSXText{}),
+			reflect.TypeOf(&js_ast.EMissing{}),
+			reflect.TypeOf(&js_ast.ENumber{}),
+			reflect.TypeOf(&js_ast.EBigInt{}),
+			reflect.TypeOf(&js_ast.EObject{}),
+			reflect.TypeOf(&js_ast.ESpread{}),
+			reflect.TypeOf(&js_ast.EString{}),
+			reflect.TypeOf(&js_ast.ETemplate{}),
+			reflect.TypeOf(&js_ast.ERegExp{}),
+			reflect.TypeOf(&js_ast.EInlinedEnum{}),
+			reflect.TypeOf(&js_ast.EAnnotation{}),
+			reflect.TypeOf(&js_ast.EAwait{}),
+			reflect.TypeOf(&js_ast.EYield{}),
+			reflect.TypeOf(&js_ast.EIf{}),
+			reflect.TypeOf(&js_ast.ERequireString{}),
+			reflect.TypeOf(&js_ast.ERequireResolveString{}),
+			reflect.TypeOf(&js_ast.EImportString{}),
+			reflect.TypeOf(&js_ast.EImportCall{}),
+
+			// Statements
+			reflect.TypeOf(&js_ast.SBlock{}),
+			reflect.TypeOf(&js_ast.SComment{}),
+			reflect.TypeOf(&js_ast.SDebugger{}),
+			reflect.TypeOf(&js_ast.SDirective{}),
+			reflect.TypeOf(&js_ast.SEmpty{}),
+			reflect.TypeOf(&js_ast.STypeScript{}),
+			reflect.TypeOf(&js_ast.SExportClause{}),
+			reflect.TypeOf(&js_ast.SExportFrom{}),
+			reflect.TypeOf(&js_ast.SExportDefault{}),
+			reflect.TypeOf(&js_ast.SExportStar{}),
+			reflect.TypeOf(&js_ast.SExportEquals{}),
+			reflect.TypeOf(&js_ast.SLazyExport{}),
+			reflect.TypeOf(&js_ast.SExpr{}),
+			reflect.TypeOf(&js_ast.SEnum{}),
+			reflect.TypeOf(&js_ast.SNamespace{}),
+			reflect.TypeOf(&js_ast.SFunction{}),
+			reflect.TypeOf(&js_ast.SClass{}),
+			reflect.TypeOf(&js_ast.SLabel{}),
+			reflect.TypeOf(&js_ast.SIf{}),
+			reflect.TypeOf(&js_ast.SFor{}),
+			reflect.TypeOf(&js_ast.SForIn{}),
+			reflect.TypeOf(&js_ast.SForOf{}),
+			reflect.TypeOf(&js_ast.SDoWhile{}),
+			reflect.TypeOf(&js_ast.SWhile{}),
+			reflect.TypeOf(&js_ast.SWith{}),
+			reflect.TypeOf(&js_ast.STry{}),
+			reflect.TypeOf(&js_ast.SSwitch{}),
+			reflect.TypeOf(&js_ast.SImport{}),
+			reflect.TypeOf(&js_ast.SReturn{}),
+			reflect.TypeOf(&js_ast.SThrow{}),
+			reflect.TypeOf(&js_ast.SLocal{}),
+			reflect.TypeOf(&js_ast.SBreak{}),
+			reflect.TypeOf(&js_ast.SContinue{}),
+
+			// TypeScript namespace members
+			reflect.TypeOf(&js_ast.TSNamespaceMemberProperty{}),
+			reflect.TypeOf(&js_ast.TSNamespaceMemberNamespace{}),
+			reflect.TypeOf(&js_ast.TSNamespaceMemberEnumNumber{}),
+			reflect.TypeOf(&js_ast.TSNamespaceMemberEnumString{}),
+
+			// CSS rules
+			reflect.TypeOf(&css_ast.RAtCharset{}),
+			reflect.TypeOf(&css_ast.RAtImport{}),
+			reflect.TypeOf(&css_ast.RAtKeyframes{}),
+			reflect.TypeOf(&css_ast.RKnownAt{}),
+			reflect.TypeOf(&css_ast.RUnknownAt{}),
+			reflect.TypeOf(&css_ast.RSelector{}),
+			reflect.TypeOf(&css_ast.RQualified{}),
+			reflect.TypeOf(&css_ast.RDeclaration{}),
+			reflect.TypeOf(&css_ast.RBadDeclaration{}),
+			reflect.TypeOf(&css_ast.RComment{}),
+			reflect.TypeOf(&css_ast.RAtLayer{}),
+			reflect.TypeOf(&css_ast.RAtCustomMedia{}),
+			reflect.TypeOf(&css_ast.RAtScope{}),
+
+			// CSS subclass selectors
+			reflect.TypeOf(&css_ast.SSHash{}),
+			reflect.TypeOf(&css_ast.SSClass{}),
+			reflect.TypeOf(&css_ast.SSAttribute{}),
+			reflect.TypeOf(&css_ast.SSPseudoClass{}),
+			reflect.TypeOf(&css_ast.SSPseudoClassWithSelectorList{}),
+		})
+	})
+	return diskCacheSerializer
+}
+
+// Increment this when the parser output changes without any change to the
+// layout of the types involved (e.g. a new lowering transform). This matters
+// for development builds, which all report the same esbuild version.
+const diskCacheFormatVersion = 1
+
+func computeVersionHash(esbuildVersion string) [32]byte {
+	var versionHash [32]byte
+	layoutHash := getDiskCacheSerializer().layoutHash()
+	hasher := sha256.New()
+	hasher.Write([]byte(fmt.Sprintf("%s:%d:", esbuildVersion, diskCacheFormatVersion)))
+	hasher.Write(layoutHash[:])
+	copy(versionHash[:], hasher.Sum(nil))
+	return versionHash
+}
+
+// The version of esbuild isn't passed in when esbuild is used as a library,
+// so it comes from the module information in the binary instead
+func buildVersion() string {
+	const modulePath = "github.com/evanw/esbuild"
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if info.Main.Path == modulePath {
+			return info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				if dep.Replace != nil {
+					return dep.Replace.Version
+				}
+				return dep.Version
+			}
+		}
+	}
+	return "unknown"
+}
+
+// The cache is keyed on "esbuildVersion" (the contents of "version.txt") and
+// the format version above. This fails if the directory can't be created.
+func NewDiskCache(dir string, esbuildVersion string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if esbuildVersion == "" {
+		esbuildVersion = buildVersion()
+	}
+	return &DiskCache{
+		dir:         dir,
+		versionHash: computeVersionHash(esbuildVersion),
+		pointerCache: pointerHashCache{
+			hashes: make(map[unsafe.Pointer][32]byte),
+		},
+	}, nil
+}
+
+// The cache key includes everything about the source except for its source
+// index, which is substituted back in when the file is loaded
+func (c *DiskCache) pathForKey(kind cacheFileKind, source logger.Source, options interface{}) (string, bool) {
+	s := getDiskCacheSerializer()
+	source.Index = 0
+	sourceHash, err := s.fingerprint(&source, nil)
+	if err != nil {
+		return "", false
+	}
+	optionsHash, err := s.fingerprint(options, &c.pointerCache)
+	if err != nil {
+		return "", false
+	}
+	hasher := sha256.New()
+	hasher.Write(c.versionHash[:])
+	hasher.Write([]byte{byte(kind)})
+	hasher.Write(sourceHash[:])
+	hasher.Write(optionsHash[:])
+	name := hex.EncodeToString(hasher.Sum(nil))
+	return filepath.Join(c.dir, name[:2], name[2:]), true
+}
+
+func (c *DiskCache) load(kind cacheFileKind, source logger.Source, options interface{}, value interface{}) (path string, ok bool) {
+	// Don't bother caching the runtime. It's parsed differently from other
+	// files and it's already cached in memory.
+	if c == nil || source.Index == runtime.SourceIndex {
+		return "", false
+	}
+	path, ok = c.pathForKey(kind, source, options)
+	if !ok {
+		return "", false
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return path, false
+	}
+	if err := getDiskCacheSerializer().deserialize(string(contents), value, source.Index); err != nil {
+		return path, false
+	}
+	return path, true
+}
+
+// Errors are ignored since this is just an optimization. The file is written
+// to a temporary file first and then renamed so that other processes never
+// observe a partially-written file.
+func (c *DiskCache) store(path string, source logger.Source, value interface{}) {
+	if c == nil || path == "" {
+		return
+	}
+	contents, err := getDiskCacheSerializer().serialize(value, source.Index, len(source.Contents)*2)
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	file, err := ioutil.TempFile(dir, "tmp-")
+	if err != nil {
+		return
+	}
+	_, writeErr := file.Write(contents)
+	closeErr := file.Close()
+	if writeErr != nil || closeErr != nil || os.Rename(file.Name(), path) != nil {
+		os.Remove(file.Name())
+	}
+}
@@ -0,0 +1,272 @@
+package cache
+
+import (
+	goast "go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/config"
+	"github.com/evanw/esbuild/internal/css_parser"
+	"github.com/evanw/esbuild/internal/css_printer"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_parser"
+	"github.com/evanw/esbuild/internal/js_printer"
+	"github.com/evanw/esbuild/internal/logger"
+	"github.com/evanw/esbuild/internal/renamer"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func newDiskCacheForTest(t *testing.T) (*DiskCache, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "esbuild-cache-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	disk, err := NewDiskCache(dir, "0.0.0-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return disk, dir
+}
+
+func countCacheFiles(t *testing.T, dir string) int {
+	t.Helper()
+	count := 0
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	return count
+}
+
+func printJS(tree js_ast.AST, sourceIndex uint32) string {
+	symbols := ast.NewSymbolMap(int(sourceIndex) + 1)
+	symbols.SymbolsForSource[sourceIndex] = tree.Symbols
+	r := renamer.NewNoOpRenamer(symbols)
+	return string(js_printer.Print(tree, symbols, r, js_printer.Options{}).JS)
+}
+
+func parseJS(t *testing.T, caches *CacheSet, contents string, sourceIndex uint32, options *config.Options) (js_ast.AST, string) {
+	t.Helper()
+	log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+	source := test.SourceForTest(contents)
+	source.Index = sourceIndex
+	tree, ok := caches.JSCache.Parse(log, source, js_parser.OptionsFromConfig(options))
+	if !ok {
+		t.Fatal("Parse error")
+	}
+	text := ""
+	for _, msg := range log.Done() {
+		text += msg.String(logger.OutputOptions{}, logger.TerminalInfo{})
+	}
+	return tree, text
+}
+
+func TestDiskCacheJS(t *testing.T) {
+	disk, dir := newDiskCacheForTest(t)
+	defer os.RemoveAll(dir)
+	contents := `
+		import { a } from 'a'
+		export class Foo extends a {
+			#x = 1
+			static { this.y = 2 }
+			async *m() { for await (const [p, { q }] of this) yield p?.q ?? ` + "`x${q}`" + ` }
+		}
+		namespace ns { export enum E { A = 1, B = 'b' } }
+		export default function () { return /re/g }
+		delete ns
+	`
+	options := config.Options{
+		Mode:         config.ModeBundle,
+		OutputFormat: config.FormatESModule,
+		TS:           config.TSOptions{Parse: true},
+	}
+
+	// The first parse writes to the cache
+	caches := MakeCacheSet()
+	caches.EnableDiskCache(disk)
+	expectedTree, expectedLog := parseJS(t, caches, contents, 1, &options)
+	expectedJS := printJS(expectedTree, 1)
+	if count := countCacheFiles(t, dir); count != 1 {
+		t.Fatalf("Expected 1 cache file, got %d", count)
+	}
+
+	// The second parse reads from the cache and uses a different source index
+	caches = MakeCacheSet()
+	caches.EnableDiskCache(disk)
+	tree, log := parseJS(t, caches, contents, 5, &options)
+	test.AssertEqualWithDiff(t, printJS(tree, 5), expectedJS)
+	test.AssertEqualWithDiff(t, log, expectedLog)
+	test.AssertEqual(t, tree.ModuleScope.Members["Foo"].Ref.SourceIndex, uint32(5))
+	for _, child := range tree.ModuleScope.Children {
+		if child.Parent != tree.ModuleScope {
+			t.Fatal("Expected the scope tree to be preserved")
+		}
+	}
+	if count := countCacheFiles(t, dir); count != 1 {
+		t.Fatalf("Expected 1 cache file, got %d", count)
+	}
+
+	// Different options must not reuse the cached file
+	options.MinifySyntax = true
+	caches = MakeCacheSet()
+	caches.EnableDiskCache(disk)
+	parseJS(t, caches, contents, 1, &options)
+	if count := countCacheFiles(t, dir); count != 2 {
+		t.Fatalf("Expected 2 cache files, got %d", count)
+	}
+
+	// So must different defines
+	options.Defines = &config.ProcessedDefines{
+		IdentifierDefines: map[string]config.DefineData{
+			"ns": {DefineExpr: &config.DefineExpr{Constant: &js_ast.ENull{}}},
+		},
+	}
+	caches = MakeCacheSet()
+	caches.EnableDiskCache(disk)
+	parseJS(t, caches, contents, 1, &options)
+	if count := countCacheFiles(t, dir); count != 3 {
+		t.Fatalf("Expected 3 cache files, got %d", count)
+	}
+
+	// So must a different version of esbuild
+	otherDisk, err := NewDiskCache(dir, "0.0.1-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	caches = MakeCacheSet()
+	caches.EnableDiskCache(otherDisk)
+	parseJS(t, caches, contents, 1, &options)
+	if count := countCacheFiles(t, dir); count != 4 {
+		t.Fatalf("Expected 4 cache files, got %d", count)
+	}
+}
+
+func TestDiskCacheInvalidData(t *testing.T) {
+	disk, dir := newDiskCacheForTest(t)
+	defer os.RemoveAll(dir)
+	contents := `export let x = [1, 2, 3].map(y => y * 2)`
+	options := config.Options{Mode: config.ModeBundle}
+
+	caches := MakeCacheSet()
+	caches.EnableDiskCache(disk)
+	expectedTree, _ := parseJS(t, caches, contents, 1, &options)
+	expectedJS := printJS(expectedTree, 1)
+
+	// Corrupt every file in the cache
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			data, _ := ioutil.ReadFile(path)
+			ioutil.WriteFile(path, data[:len(data)/2], 0644)
+		}
+		return nil
+	})
+
+	// This should fall back to parsing the file
+	caches = MakeCacheSet()
+	caches.EnableDiskCache(disk)
+	tree, _ := parseJS(t, caches, contents, 1, &options)
+	test.AssertEqualWithDiff(t, printJS(tree, 1), expectedJS)
+}
+
+func TestDiskCacheCSS(t *testing.T) {
+	disk, dir := newDiskCacheForTest(t)
+	defer os.RemoveAll(dir)
+	source := test.SourceForTest(`
+		@import "foo.css";
+		.foo { composes: bar; color: red }
+		.bar:hover > #baz[data-x="y"] { color: blue }
+		@media (min-width: 100px) { .foo { color: green } }
+	`)
+	options := css_parser.OptionsFromConfig(config.LoaderLocalCSS, &config.Options{})
+
+	print := func(sourceIndex uint32) string {
+		source.Index = sourceIndex
+		caches := MakeCacheSet()
+		caches.EnableDiskCache(disk)
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		tree := caches.CSSCache.Parse(log, source, options)
+		symbols := ast.NewSymbolMap(int(sourceIndex) + 1)
+		symbols.SymbolsForSource[sourceIndex] = tree.Symbols
+		return string(css_printer.Print(tree, symbols, css_printer.Options{}).CSS)
+	}
+
+	expected := print(1)
+	test.AssertEqualWithDiff(t, print(3), expected)
+	if count := countCacheFiles(t, dir); count != 1 {
+		t.Fatalf("Expected 1 cache file, got %d", count)
+	}
+}
+
+// Reflection can't enumerate the types in a package, so this finds every AST
+// node type that's stored in an interface by parsing the source code instead.
+// Each one must be registered with the serializer or it can't be cached.
+func TestDiskCacheRegistersAllNodeTypes(t *testing.T) {
+	registered := make(map[string]bool)
+	for _, pointerType := range getDiskCacheSerializer().interfaceTypes {
+		elem := pointerType.elemType
+		registered[elem.PkgPath()+"."+elem.Name()] = true
+	}
+
+	check := func(dir string, pkgPath string, isNode func(*goast.FuncDecl) bool) {
+		fset := token.NewFileSet()
+		pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+			return !strings.HasSuffix(info.Name(), "_test.go")
+		}, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for _, pkg := range pkgs {
+			for _, file := range pkg.Files {
+				for _, decl := range file.Decls {
+					fn, ok := decl.(*goast.FuncDecl)
+					if !ok || fn.Recv == nil || len(fn.Recv.List) != 1 || !isNode(fn) {
+						continue
+					}
+					star, ok := fn.Recv.List[0].Type.(*goast.StarExpr)
+					if !ok {
+						continue
+					}
+					name := star.X.(*goast.Ident).Name
+					count++
+					if !registered[pkgPath+"."+name] {
+						t.Errorf("The type %q must be registered with the disk cache serializer", path.Base(pkgPath)+"."+name)
+					}
+				}
+			}
+		}
+		if count == 0 {
+			t.Fatalf("Failed to find any node types in %q", dir)
+		}
+	}
+
+	// JavaScript bindings, expressions, and statements have a marker method
+	check("../js_ast", "github.com/evanw/esbuild/internal/js_ast", func(fn *goast.FuncDecl) bool {
+		switch fn.Name.Name {
+		case "isBinding", "isExpr", "isStmt":
+			return true
+		}
+		return false
+	})
+
+	// CSS rules and subclass selectors implement "Equal" for their interface
+	check("../css_ast", "github.com/evanw/esbuild/internal/css_ast", func(fn *goast.FuncDecl) bool {
+		if fn.Name.Name != "Equal" || len(fn.Type.Params.List) == 0 {
+			return false
+		}
+		if ident, ok := fn.Type.Params.List[0].Type.(*goast.Ident); ok {
+			return ident.Name == "R" || ident.Name == "SS"
+		}
+		return false
+	})
+}
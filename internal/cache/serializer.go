@@ -0,0 +1,718 @@
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"unsafe"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/config"
+)
+
+// This is a binary serializer for the output of the parsers. It's used by the
+// on-disk cache. It's not a general-purpose serializer. It works by walking
+// the memory layout of the types using reflection, so it also includes all
+// unexported fields. This means it's a good idea to make sure that everything
+// reachable from the parser output is plain data.
+//
+// There are a few things this handles that other serializers don't:
+//
+//   - Pointer identity is preserved. If the same pointer is reachable from two
+//     places, both places will point to the same object after deserializing.
+//     This is also what makes cycles such as "Scope.Parent" work. This is not
+//     done for pointers stored in interfaces (i.e. AST nodes) since there are
+//     a lot of them, they form a tree, and tracking them is slow.
+//
+//   - Symbol references are stored relative to the source index of the file.
+//     Source indices are assigned in the order that files are discovered, so
+//     they can be different for the same file in different builds.
+//
+//   - Map entries can be written in sorted order. That makes the output
+//     deterministic, which is important because the serialized form of the
+//     parser options is hashed to form the cache key. This is only done when
+//     computing a fingerprint since sorting is slow.
+//
+// There is no type information in the serialized data. Instead there is a
+// hash of the layout of all types involved, which is included in the cache
+// key. So any change to these types invalidates the whole cache.
+
+type typeCodec struct {
+	encode func(e *encoder, p unsafe.Pointer)
+	decode func(d *decoder, p unsafe.Pointer)
+}
+
+type serializer struct {
+	codecs         map[reflect.Type]*typeCodec
+	interfaceTypes []*pointerType
+	interfaceIDs   map[reflect.Type]uint32
+	layout         []byte
+}
+
+// Only pointers can be stored in interfaces. This is faster because it avoids
+// allocating a temporary value to hold the dynamic value of the interface.
+type pointerType struct {
+	typ      reflect.Type
+	elemType reflect.Type
+	elem     *typeCodec
+
+	// All zero-sized objects may have the same address, so their identity
+	// can't be preserved
+	preserveIdentity bool
+}
+
+type encoder struct {
+	*serializer
+	buf          []byte
+	pointers     map[unsafe.Pointer]uint32
+	pointerCache *pointerHashCache
+	sourceIndex  uint32
+	sortMaps     bool
+}
+
+type decoder struct {
+	*serializer
+	buf         string
+	pointers    []decodedPointer
+	sourceIndex uint32
+}
+
+type decodedPointer struct {
+	ptr unsafe.Pointer
+	typ reflect.Type
+}
+
+// This has the same memory layout as a Go slice
+type sliceHeader struct {
+	data unsafe.Pointer
+	len  int
+	cap  int
+}
+
+type serializerError struct {
+	err error
+}
+
+var refType = reflect.TypeOf(ast.Ref{})
+var regexpType = reflect.TypeOf((*regexp.Regexp)(nil))
+var definesType = reflect.TypeOf((*config.ProcessedDefines)(nil))
+
+// All types that are stored in an interface must be listed when creating the
+// serializer. Their position in the list is used to identify them.
+func newSerializer(roots []reflect.Type, interfaceTypes []reflect.Type) *serializer {
+	s := &serializer{
+		codecs:         make(map[reflect.Type]*typeCodec),
+		interfaceTypes: make([]*pointerType, len(interfaceTypes)),
+		interfaceIDs:   make(map[reflect.Type]uint32),
+	}
+	for i, t := range interfaceTypes {
+		if t.Kind() != reflect.Ptr {
+			panic("Internal error")
+		}
+		s.interfaceTypes[i] = &pointerType{typ: t, elemType: t.Elem()}
+		s.interfaceIDs[t] = uint32(i)
+	}
+	for _, t := range roots {
+		s.codecFor(t)
+	}
+	for i, t := range interfaceTypes {
+		s.layout = append(s.layout, fmt.Sprintf("interface %s\n", t.String())...)
+		s.interfaceTypes[i].elem = s.codecFor(t.Elem())
+	}
+	return s
+}
+
+// This is a fingerprint of the layout of all types involved
+func (s *serializer) layoutHash() [32]byte {
+	return sha256.Sum256(s.layout)
+}
+
+func (s *serializer) codecFor(t reflect.Type) *typeCodec {
+	if c, ok := s.codecs[t]; ok {
+		return c
+	}
+
+	// Register the codec before building it so that recursive types work
+	c := &typeCodec{}
+	s.codecs[t] = c
+	s.layout = append(s.layout, fmt.Sprintf("%s %d %d\n", t.String(), t.Kind(), t.Size())...)
+
+	switch t.Kind() {
+	case reflect.Bool:
+		c.encode = func(e *encoder, p unsafe.Pointer) {
+			if *(*bool)(p) {
+				e.buf = append(e.buf, 1)
+			} else {
+				e.buf = append(e.buf, 0)
+			}
+		}
+		c.decode = func(d *decoder, p unsafe.Pointer) {
+			switch d.readByte() {
+			case 0:
+				*(*bool)(p) = false
+			case 1:
+				*(*bool)(p) = true
+			default:
+				d.fail()
+			}
+		}
+
+	case reflect.Int:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeVarint(int64(*(*int)(p))) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*int)(p) = int(d.readVarint()) }
+	case reflect.Int8:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeVarint(int64(*(*int8)(p))) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*int8)(p) = int8(d.readVarint()) }
+	case reflect.Int16:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeVarint(int64(*(*int16)(p))) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*int16)(p) = int16(d.readVarint()) }
+	case reflect.Int32:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeVarint(int64(*(*int32)(p))) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*int32)(p) = int32(d.readVarint()) }
+	case reflect.Int64:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeVarint(*(*int64)(p)) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*int64)(p) = d.readVarint() }
+
+	case reflect.Uint:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeUvarint(uint64(*(*uint)(p))) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*uint)(p) = uint(d.readUvarint()) }
+	case reflect.Uint8:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.buf = append(e.buf, *(*uint8)(p)) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*uint8)(p) = d.readByte() }
+	case reflect.Uint16:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeUvarint(uint64(*(*uint16)(p))) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*uint16)(p) = uint16(d.readUvarint()) }
+	case reflect.Uint32:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeUvarint(uint64(*(*uint32)(p))) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*uint32)(p) = uint32(d.readUvarint()) }
+	case reflect.Uint64:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeUvarint(*(*uint64)(p)) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*uint64)(p) = d.readUvarint() }
+
+	case reflect.Float32:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeUvarint(uint64(math.Float32bits(*(*float32)(p)))) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*float32)(p) = math.Float32frombits(uint32(d.readUvarint())) }
+	case reflect.Float64:
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.writeUvarint(math.Float64bits(*(*float64)(p))) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { *(*float64)(p) = math.Float64frombits(d.readUvarint()) }
+
+	case reflect.String:
+		c.encode = func(e *encoder, p unsafe.Pointer) {
+			text := *(*string)(p)
+			e.writeUvarint(uint64(len(text)))
+			e.buf = append(e.buf, text...)
+		}
+		c.decode = func(d *decoder, p unsafe.Pointer) {
+			*(*string)(p) = d.readString(d.readLength())
+		}
+
+	case reflect.Array:
+		elem := s.codecFor(t.Elem())
+		count := uintptr(t.Len())
+		size := t.Elem().Size()
+		c.encode = func(e *encoder, p unsafe.Pointer) {
+			for i := uintptr(0); i < count; i++ {
+				elem.encode(e, unsafe.Pointer(uintptr(p)+i*size))
+			}
+		}
+		c.decode = func(d *decoder, p unsafe.Pointer) {
+			for i := uintptr(0); i < count; i++ {
+				elem.decode(d, unsafe.Pointer(uintptr(p)+i*size))
+			}
+		}
+
+	case reflect.Slice:
+		s.buildSlice(c, t)
+
+	case reflect.Map:
+		s.buildMap(c, t)
+
+	case reflect.Ptr:
+		s.buildPointer(c, t)
+
+	case reflect.Interface:
+		s.buildInterface(c, t)
+
+	case reflect.Struct:
+		s.buildStruct(c, t)
+
+	default:
+		// Functions, channels, and unsafe pointers can't be serialized
+		c.encode = func(e *encoder, p unsafe.Pointer) { e.unsupported(t) }
+		c.decode = func(d *decoder, p unsafe.Pointer) { d.fail() }
+	}
+
+	return c
+}
+
+func (s *serializer) buildSlice(c *typeCodec, t reflect.Type) {
+	elem := s.codecFor(t.Elem())
+	size := t.Elem().Size()
+
+	// Slices are written as their length plus one so that nil slices can be
+	// distinguished from empty slices
+	c.encode = func(e *encoder, p unsafe.Pointer) {
+		h := (*sliceHeader)(p)
+		if h.data == nil {
+			e.writeUvarint(0)
+			return
+		}
+		e.writeUvarint(uint64(h.len) + 1)
+		for i := 0; i < h.len; i++ {
+			elem.encode(e, unsafe.Pointer(uintptr(h.data)+uintptr(i)*size))
+		}
+	}
+
+	c.decode = func(d *decoder, p unsafe.Pointer) {
+		n := d.readUvarint()
+		if n == 0 {
+			*(*sliceHeader)(p) = sliceHeader{}
+			return
+		}
+		count := d.checkLength(n-1, size)
+		reflect.NewAt(t, p).Elem().Set(reflect.MakeSlice(t, count, count))
+		h := (*sliceHeader)(p)
+		for i := 0; i < count; i++ {
+			elem.decode(d, unsafe.Pointer(uintptr(h.data)+uintptr(i)*size))
+		}
+	}
+}
+
+func (s *serializer) buildMap(c *typeCodec, t reflect.Type) {
+	keyType := t.Key()
+	valueType := t.Elem()
+	key := s.codecFor(keyType)
+	value := s.codecFor(valueType)
+	keyIsPlainData := isPlainData(keyType)
+
+	type mapEntry struct {
+		key   []byte
+		value reflect.Value
+	}
+
+	c.encode = func(e *encoder, p unsafe.Pointer) {
+		m := reflect.NewAt(t, p).Elem()
+		if m.IsNil() {
+			e.writeUvarint(0)
+			return
+		}
+		e.writeUvarint(uint64(m.Len()) + 1)
+		iter := m.MapRange()
+
+		if !e.sortMaps {
+			k := reflect.New(keyType).Elem()
+			v := reflect.New(valueType).Elem()
+			for iter.Next() {
+				k.Set(iter.Key())
+				v.Set(iter.Value())
+				key.encode(e, unsafe.Pointer(k.UnsafeAddr()))
+				value.encode(e, unsafe.Pointer(v.UnsafeAddr()))
+			}
+			return
+		}
+		if !keyIsPlainData {
+			e.unsupported(keyType)
+		}
+
+		// Sort the entries by their serialized keys to make the output deterministic
+		entries := make([]mapEntry, 0, m.Len())
+		keyEncoder := encoder{serializer: e.serializer, sourceIndex: e.sourceIndex}
+		temp := reflect.New(keyType).Elem()
+		for iter.Next() {
+			temp.Set(iter.Key())
+			start := len(keyEncoder.buf)
+			key.encode(&keyEncoder, unsafe.Pointer(temp.UnsafeAddr()))
+			entries = append(entries, mapEntry{key: keyEncoder.buf[start:len(keyEncoder.buf):len(keyEncoder.buf)], value: iter.Value()})
+		}
+		sort.Slice(entries, func(i int, j int) bool {
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+
+		temp = reflect.New(valueType).Elem()
+		for _, entry := range entries {
+			e.buf = append(e.buf, entry.key...)
+			temp.Set(entry.value)
+			value.encode(e, unsafe.Pointer(temp.UnsafeAddr()))
+		}
+	}
+
+	c.decode = func(d *decoder, p unsafe.Pointer) {
+		n := d.readUvarint()
+		if n == 0 {
+			reflect.NewAt(t, p).Elem().Set(reflect.Zero(t))
+			return
+		}
+		count := d.checkLength(n-1, 1)
+		m := reflect.MakeMapWithSize(t, count)
+		k := reflect.New(keyType).Elem()
+		v := reflect.New(valueType).Elem()
+		for i := 0; i < count; i++ {
+			key.decode(d, unsafe.Pointer(k.UnsafeAddr()))
+			value.decode(d, unsafe.Pointer(v.UnsafeAddr()))
+			m.SetMapIndex(k, v)
+		}
+		reflect.NewAt(t, p).Elem().Set(m)
+	}
+}
+
+func (s *serializer) buildPointer(c *typeCodec, t reflect.Type) {
+	// Regular expressions are only present in the parser options. They are
+	// serialized as their source text.
+	if t == regexpType {
+		c.encode = func(e *encoder, p unsafe.Pointer) {
+			if re := *(**regexp.Regexp)(p); re == nil {
+				e.writeUvarint(0)
+			} else {
+				text := re.String()
+				e.writeUvarint(uint64(len(text)) + 1)
+				e.buf = append(e.buf, text...)
+			}
+		}
+		c.decode = func(d *decoder, p unsafe.Pointer) {
+			n := d.readUvarint()
+			if n == 0 {
+				*(**regexp.Regexp)(p) = nil
+				return
+			}
+			re, err := regexp.Compile(d.readString(d.checkLength(n-1, 1)))
+			if err != nil {
+				d.fail()
+			}
+			*(**regexp.Regexp)(p) = re
+		}
+		return
+	}
+
+	elemType := t.Elem()
+	elem := s.codecFor(elemType)
+
+	// The defines are also only present in the parser options. They are the
+	// same object for every file in the build and they are very large, so a
+	// hash of their contents is serialized instead. Use "pointerHashCache" to
+	// avoid hashing them once per file.
+	if t == definesType {
+		c.encode = func(e *encoder, p unsafe.Pointer) {
+			ptr := *(*unsafe.Pointer)(p)
+			if ptr == nil {
+				e.writeUvarint(0)
+				return
+			}
+			hash := e.pointerCache.hashOf(ptr, func() [32]byte {
+				inner := encoder{serializer: e.serializer, pointers: make(map[unsafe.Pointer]uint32), sourceIndex: e.sourceIndex, sortMaps: true}
+				elem.encode(&inner, ptr)
+				return sha256.Sum256(inner.buf)
+			})
+			e.writeUvarint(1)
+			e.buf = append(e.buf, hash[:]...)
+		}
+		c.decode = func(d *decoder, p unsafe.Pointer) { d.fail() }
+		return
+	}
+
+	pt := &pointerType{typ: t, elemType: elemType, elem: elem, preserveIdentity: elemType.Size() != 0}
+	c.encode = func(e *encoder, p unsafe.Pointer) {
+		e.encodePointer(pt, *(*unsafe.Pointer)(p))
+	}
+	c.decode = func(d *decoder, p unsafe.Pointer) {
+		*(*unsafe.Pointer)(p) = d.decodePointer(pt)
+	}
+}
+
+// Pointers are written as zero for nil, one for a new object followed by that
+// object, and two or more for a reference to an earlier object
+func (e *encoder) encodePointer(pt *pointerType, ptr unsafe.Pointer) {
+	if ptr == nil {
+		e.writeUvarint(0)
+		return
+	}
+	if !pt.preserveIdentity {
+		e.writeUvarint(1)
+		pt.elem.encode(e, ptr)
+		return
+	}
+	if id, ok := e.pointers[ptr]; ok {
+		e.writeUvarint(uint64(id) + 2)
+		return
+	}
+	e.pointers[ptr] = uint32(len(e.pointers))
+	e.writeUvarint(1)
+	pt.elem.encode(e, ptr)
+}
+
+func (d *decoder) decodePointer(pt *pointerType) unsafe.Pointer {
+	switch n := d.readUvarint(); n {
+	case 0:
+		return nil
+
+	case 1:
+		ptr := unsafe.Pointer(reflect.New(pt.elemType).Pointer())
+		if pt.preserveIdentity {
+			d.pointers = append(d.pointers, decodedPointer{ptr: ptr, typ: pt.typ})
+		}
+		pt.elem.decode(d, ptr)
+		return ptr
+
+	default:
+		id := n - 2
+		if id >= uint64(len(d.pointers)) || d.pointers[id].typ != pt.typ {
+			d.fail()
+		}
+		return d.pointers[id].ptr
+	}
+}
+
+func (s *serializer) buildInterface(c *typeCodec, t reflect.Type) {
+	implements := make([]bool, len(s.interfaceTypes))
+	for i, it := range s.interfaceTypes {
+		implements[i] = it.typ.Implements(t)
+	}
+
+	c.encode = func(e *encoder, p unsafe.Pointer) {
+		v := reflect.NewAt(t, p).Elem()
+		if v.IsNil() {
+			e.writeUvarint(0)
+			return
+		}
+		dynamic := v.Elem()
+		id, ok := e.interfaceIDs[dynamic.Type()]
+		if !ok {
+			e.unsupported(dynamic.Type())
+		}
+		e.writeUvarint(uint64(id) + 1)
+		e.encodePointer(e.interfaceTypes[id], unsafe.Pointer(dynamic.Pointer()))
+	}
+
+	c.decode = func(d *decoder, p unsafe.Pointer) {
+		n := d.readUvarint()
+		if n == 0 {
+			reflect.NewAt(t, p).Elem().Set(reflect.Zero(t))
+			return
+		}
+		id := n - 1
+		if id >= uint64(len(implements)) || !implements[id] {
+			d.fail()
+		}
+		pt := d.interfaceTypes[id]
+		ptr := d.decodePointer(pt)
+		reflect.NewAt(t, p).Elem().Set(reflect.NewAt(pt.elemType, ptr))
+	}
+}
+
+func (s *serializer) buildStruct(c *typeCodec, t reflect.Type) {
+	// Symbol references are relative to the source index of the file. They are
+	// written as zero for the current file and the source index plus one for
+	// any other file.
+	if t == refType {
+		c.encode = func(e *encoder, p unsafe.Pointer) {
+			ref := (*ast.Ref)(p)
+			if ref.SourceIndex == e.sourceIndex {
+				e.writeUvarint(0)
+			} else {
+				e.writeUvarint(uint64(ref.SourceIndex) + 1)
+			}
+			e.writeUvarint(uint64(ref.InnerIndex))
+		}
+		c.decode = func(d *decoder, p unsafe.Pointer) {
+			ref := (*ast.Ref)(p)
+			if n := d.readUvarint(); n == 0 {
+				ref.SourceIndex = d.sourceIndex
+			} else {
+				ref.SourceIndex = uint32(n - 1)
+			}
+			ref.InnerIndex = uint32(d.readUvarint())
+		}
+		return
+	}
+
+	type fieldCodec struct {
+		codec  *typeCodec
+		offset uintptr
+	}
+	fields := make([]fieldCodec, 0, t.NumField())
+	for i, n := 0, t.NumField(); i < n; i++ {
+		field := t.Field(i)
+		s.layout = append(s.layout, fmt.Sprintf("  %s %s %d\n", field.Name, field.Type.String(), field.Offset)...)
+		fields = append(fields, fieldCodec{codec: s.codecFor(field.Type), offset: field.Offset})
+	}
+
+	c.encode = func(e *encoder, p unsafe.Pointer) {
+		for _, field := range fields {
+			field.codec.encode(e, unsafe.Pointer(uintptr(p)+field.offset))
+		}
+	}
+	c.decode = func(d *decoder, p unsafe.Pointer) {
+		for _, field := range fields {
+			field.codec.decode(d, unsafe.Pointer(uintptr(p)+field.offset))
+		}
+	}
+}
+
+// Map keys are serialized separately for sorting, so they can't contain
+// anything that depends on the rest of the serialized data
+func isPlainData(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return false
+	case reflect.Array:
+		return isPlainData(t.Elem())
+	case reflect.Struct:
+		for i, n := 0, t.NumField(); i < n; i++ {
+			if !isPlainData(t.Field(i).Type) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// The value must be a pointer to a type that was passed to "newSerializer"
+func (s *serializer) serialize(value interface{}, sourceIndex uint32, sizeHint int) ([]byte, error) {
+	e := encoder{
+		serializer:  s,
+		buf:         make([]byte, 0, sizeHint),
+		pointers:    make(map[unsafe.Pointer]uint32),
+		sourceIndex: sourceIndex,
+	}
+	if err := e.encodeRoot(value); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// This is a hash of the serialized value. Unlike "serialize", this is always
+// the same for the same value. The value must not contain any symbol refs
+// since there is no source index.
+func (s *serializer) fingerprint(value interface{}, pointerCache *pointerHashCache) ([32]byte, error) {
+	e := encoder{
+		serializer:   s,
+		pointers:     make(map[unsafe.Pointer]uint32),
+		pointerCache: pointerCache,
+		sortMaps:     true,
+	}
+	if err := e.encodeRoot(value); err != nil {
+		return [32]byte{}, err
+	}
+	return sha256.Sum256(e.buf), nil
+}
+
+func (e *encoder) encodeRoot(value interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if failure, ok := r.(serializerError); ok {
+				err = failure.err
+				return
+			}
+			panic(r)
+		}
+	}()
+	v := reflect.ValueOf(value)
+	e.codecs[v.Type().Elem()].encode(e, unsafe.Pointer(v.Pointer()))
+	return nil
+}
+
+// The value must be a pointer to a type that was passed to "newSerializer".
+// Invalid data results in an error instead of a crash (but only if that data
+// was also produced by this serializer with the same types). The data is a
+// string so that strings in the result can share its memory.
+func (s *serializer) deserialize(data string, value interface{}, sourceIndex uint32) (err error) {
+	v := reflect.ValueOf(value)
+	d := decoder{
+		serializer:  s,
+		buf:         data,
+		sourceIndex: sourceIndex,
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if failure, ok := r.(serializerError); ok {
+				err = failure.err
+				return
+			}
+			panic(r)
+		}
+	}()
+	s.codecs[v.Type().Elem()].decode(&d, unsafe.Pointer(v.Pointer()))
+	if len(d.buf) != 0 {
+		d.fail()
+	}
+	return nil
+}
+
+var errInvalidData = errors.New("Invalid cache data")
+
+func (e *encoder) unsupported(t reflect.Type) {
+	panic(serializerError{err: fmt.Errorf("Cannot serialize type %s", t.String())})
+}
+
+func (e *encoder) writeUvarint(value uint64) {
+	for value >= 0x80 {
+		e.buf = append(e.buf, byte(value)|0x80)
+		value >>= 7
+	}
+	e.buf = append(e.buf, byte(value))
+}
+
+func (e *encoder) writeVarint(value int64) {
+	e.writeUvarint(uint64(value<<1) ^ uint64(value>>63))
+}
+
+func (d *decoder) fail() {
+	panic(serializerError{err: errInvalidData})
+}
+
+func (d *decoder) readByte() byte {
+	if len(d.buf) == 0 {
+		d.fail()
+	}
+	c := d.buf[0]
+	d.buf = d.buf[1:]
+	return c
+}
+
+func (d *decoder) readString(n int) string {
+	if n > len(d.buf) {
+		d.fail()
+	}
+	result := d.buf[:n]
+	d.buf = d.buf[n:]
+	return result
+}
+
+func (d *decoder) readUvarint() uint64 {
+	var value uint64
+	for shift := uint(0); ; shift += 7 {
+		if shift >= 64 {
+			d.fail()
+		}
+		c := d.readByte()
+		value |= uint64(c&0x7F) << shift
+		if c < 0x80 {
+			return value
+		}
+	}
+}
+
+func (d *decoder) readVarint() int64 {
+	value := d.readUvarint()
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+func (d *decoder) readLength() int {
+	return d.checkLength(d.readUvarint(), 1)
+}
+
+// Every element takes up at least one byte unless it has a size of zero, so
+// this avoids huge allocations when the data is invalid
+func (d *decoder) checkLength(n uint64, elemSize uintptr) int {
+	if elemSize > 0 && n > uint64(len(d.buf)) {
+		d.fail()
+	}
+	if n > math.MaxInt32 {
+		d.fail()
+	}
+	return int(n)
+}
@@ -41,6 +41,8 @@ func ParseLoader(text string) (api.Loader, *ErrorWithNote) {
 		return api.LoaderFile, nil
 	case "global-css":
 		return api.LoaderGlobalCSS, nil
+	case "html":
+		return api.LoaderHTML, nil
 	case "js":
 		return api.LoaderJS, nil
 	case "json":
@@ -58,7 +60,48 @@ func ParseLoader(text string) (api.Loader, *ErrorWithNote) {
 	default:
 		return api.LoaderNone, MakeErrorWithNote(
 			fmt.Sprintf("Invalid loader value: %q", text),
-			"Valid values are \"base64\", \"binary\", \"copy\", \"css\", \"dataurl\", \"empty\", \"file\", \"global-css\", \"js\", \"json\", \"jsx\", \"local-css\", \"text\", \"ts\", or \"tsx\".",
+			"Valid values are \"base64\", \"binary\", \"copy\", \"css\", \"dataurl\", \"empty\", \"file\", \"global-css\", \"html\", \"js\", \"json\", \"jsx\", \"local-css\", \"text\", \"ts\", or \"tsx\".",
 		)
 	}
 }
+
+func LoaderToString(loader api.Loader) string {
+	switch loader {
+	case api.LoaderBase64:
+		return "base64"
+	case api.LoaderBinary:
+		return "binary"
+	case api.LoaderCopy:
+		return "copy"
+	case api.LoaderCSS:
+		return "css"
+	case api.LoaderDataURL:
+		return "dataurl"
+	case api.LoaderDefault:
+		return "default"
+	case api.LoaderEmpty:
+		return "empty"
+	case api.LoaderFile:
+		return "file"
+	case api.LoaderGlobalCSS:
+		return "global-css"
+	case api.LoaderHTML:
+		return "html"
+	case api.LoaderJS:
+		return "js"
+	case api.LoaderJSON:
+		return "json"
+	case api.LoaderJSX:
+		return "jsx"
+	case api.LoaderLocalCSS:
+		return "local-css"
+	case api.LoaderText:
+		return "text"
+	case api.LoaderTS:
+		return "ts"
+	case api.LoaderTSX:
+		return "tsx"
+	default:
+		return "none"
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"github.com/evanw/esbuild/internal/css_ast"
 )
 
-type CSSFeature uint16
+type CSSFeature uint32
 
 const (
-	ColorFunctions CSSFeature = 1 << iota
+	AtScope CSSFeature = 1 << iota
+	CascadeLayers
+	ColorFunctions
+	ColorMix
+	CustomMedia
 	GradientDoublePosition
 	GradientInterpolation
 	GradientMidpoints
@@ -18,13 +22,20 @@ const (
 	InlineStyle
 	InsetProperty
 	IsPseudoClass
+	LightDark
+	MediaRange
 	Modern_RGB_HSL
 	Nesting
 	RebeccaPurple
+	RelativeColors
 )
 
 var StringToCSSFeature = map[string]CSSFeature{
+	"at-scope":                 AtScope,
+	"cascade-layers":           CascadeLayers,
 	"color-functions":          ColorFunctions,
+	"color-mix":                ColorMix,
+	"custom-media":             CustomMedia,
 	"gradient-double-position": GradientDoublePosition,
 	"gradient-interpolation":   GradientInterpolation,
 	"gradient-midpoints":       GradientMidpoints,
@@ -33,9 +44,12 @@ var StringToCSSFeature = map[string]CSSFeature{
 	"inline-style":             InlineStyle,
 	"inset-property":           InsetProperty,
 	"is-pseudo-class":          IsPseudoClass,
+	"light-dark":               LightDark,
+	"media-range":              MediaRange,
 	"modern-rgb-hsl":           Modern_RGB_HSL,
 	"nesting":                  Nesting,
 	"rebecca-purple":           RebeccaPurple,
+	"relative-colors":          RelativeColors,
 }
 
 func (features CSSFeature) Has(feature CSSFeature) bool {
@@ -47,6 +61,22 @@ func (features CSSFeature) ApplyOverrides(overrides CSSFeature, mask CSSFeature)
 }
 
 var cssTable = map[CSSFeature]map[Engine][]versionRange{
+	AtScope: {
+		Chrome:  {{start: v{118, 0, 0}}},
+		Edge:    {{start: v{118, 0, 0}}},
+		Firefox: {{start: v{146, 0, 0}}},
+		IOS:     {{start: v{17, 4, 0}}},
+		Opera:   {{start: v{104, 0, 0}}},
+		Safari:  {{start: v{17, 4, 0}}},
+	},
+	CascadeLayers: {
+		Chrome:  {{start: v{99, 0, 0}}},
+		Edge:    {{start: v{99, 0, 0}}},
+		Firefox: {{start: v{97, 0, 0}}},
+		IOS:     {{start: v{15, 4, 0}}},
+		Opera:   {{start: v{85, 0, 0}}},
+		Safari:  {{start: v{15, 4, 0}}},
+	},
 	ColorFunctions: {
 		Chrome:  {{start: v{111, 0, 0}}},
 		Edge:    {{start: v{111, 0, 0}}},
@@ -55,6 +85,15 @@ var cssTable = map[CSSFeature]map[Engine][]versionRange{
 		Opera:   {{start: v{97, 0, 0}}},
 		Safari:  {{start: v{15, 4, 0}}},
 	},
+	ColorMix: {
+		Chrome:  {{start: v{111, 0, 0}}},
+		Edge:    {{start: v{111, 0, 0}}},
+		Firefox: {{start: v{113, 0, 0}}},
+		IOS:     {{start: v{16, 2, 0}}},
+		Opera:   {{start: v{97, 0, 0}}},
+		Safari:  {{start: v{16, 2, 0}}},
+	},
+	CustomMedia: {},
 	GradientDoublePosition: {
 		Chrome:  {{start: v{72, 0, 0}}},
 		Edge:    {{start: v{79, 0, 0}}},
@@ -111,6 +150,22 @@ var cssTable = map[CSSFeature]map[Engine][]versionRange{
 		Opera:   {{start: v{75, 0, 0}}},
 		Safari:  {{start: v{14, 0, 0}}},
 	},
+	LightDark: {
+		Chrome:  {{start: v{123, 0, 0}}},
+		Edge:    {{start: v{123, 0, 0}}},
+		Firefox: {{start: v{120, 0, 0}}},
+		IOS:     {{start: v{17, 5, 0}}},
+		Opera:   {{start: v{109, 0, 0}}},
+		Safari:  {{start: v{17, 5, 0}}},
+	},
+	MediaRange: {
+		Chrome:  {{start: v{104, 0, 0}}},
+		Edge:    {{start: v{104, 0, 0}}},
+		Firefox: {{start: v{63, 0, 0}}},
+		IOS:     {{start: v{16, 4, 0}}},
+		Opera:   {{start: v{91, 0, 0}}},
+		Safari:  {{start: v{16, 4, 0}}},
+	},
 	Modern_RGB_HSL: {
 		Chrome:  {{start: v{66, 0, 0}}},
 		Edge:    {{start: v{79, 0, 0}}},
@@ -136,6 +191,14 @@ var cssTable = map[CSSFeature]map[Engine][]versionRange{
 		Opera:   {{start: v{25, 0, 0}}},
 		Safari:  {{start: v{9, 0, 0}}},
 	},
+	RelativeColors: {
+		Chrome:  {{start: v{119, 0, 0}}},
+		Edge:    {{start: v{119, 0, 0}}},
+		Firefox: {{start: v{128, 0, 0}}},
+		IOS:     {{start: v{18, 0, 0}}},
+		Opera:   {{start: v{105, 0, 0}}},
+		Safari:  {{start: v{18, 0, 0}}},
+	},
 }
 
 // Return all features that are not available in at least one environment
@@ -380,19 +443,7 @@ var cssPrefixTable = map[css_ast.D][]prefixData{
 
 func CSSPrefixData(constraints map[Engine]Semver) (entries map[css_ast.D]CSSPrefix) {
 	for property, items := range cssPrefixTable {
-		prefixes := NoPrefix
-		for engine, version := range constraints {
-			if !engine.IsBrowser() {
-				// Specifying "--target=es2020" shouldn't affect CSS
-				continue
-			}
-			for _, item := range items {
-				if item.engine == engine && (item.withoutPrefix == v{} || compareVersions(item.withoutPrefix, version) > 0) {
-					prefixes |= item.prefix
-				}
-			}
-		}
-		if prefixes != NoPrefix {
+		if prefixes := prefixesForConstraints(items, constraints); prefixes != NoPrefix {
 			if entries == nil {
 				entries = make(map[css_ast.D]CSSPrefix)
 			}
@@ -401,3 +452,146 @@ func CSSPrefixData(constraints map[Engine]Semver) (entries map[css_ast.D]CSSPref
 	}
 	return
 }
+
+// These are CSS features other than properties that may need vendor prefixes
+type CSSPrefixedSyntax uint8
+
+const (
+	AtKeyframes CSSPrefixedSyntax = iota
+	CrossFadeFunction
+	DisplayFlex
+	GradientFunctions
+	ImageSetFunction
+	PseudoAnyLink
+	PseudoBackdrop
+	PseudoFileSelectorButton
+	PseudoFullscreen
+	PseudoPlaceholder
+	PseudoPlaceholderShown
+	PseudoReadOnly
+	PseudoReadWrite
+	PseudoSelection
+)
+
+var cssSyntaxPrefixTable = map[CSSPrefixedSyntax][]prefixData{
+	AtKeyframes: {
+		{engine: Chrome, prefix: WebkitPrefix, withoutPrefix: v{43, 0, 0}},
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{16, 0, 0}},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{9, 0, 0}},
+		{engine: Opera, prefix: WebkitPrefix, withoutPrefix: v{30, 0, 0}},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{9, 0, 0}},
+	},
+	CrossFadeFunction: {
+		{engine: Chrome, prefix: WebkitPrefix},
+		{engine: Edge, prefix: WebkitPrefix},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{10, 0, 0}},
+		{engine: Opera, prefix: WebkitPrefix},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{10, 0, 0}},
+	},
+	DisplayFlex: {
+		{engine: Chrome, prefix: WebkitPrefix, withoutPrefix: v{29, 0, 0}},
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{22, 0, 0}},
+		{engine: IE, prefix: MsPrefix, withoutPrefix: v{11, 0, 0}},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{9, 0, 0}},
+		{engine: Opera, prefix: WebkitPrefix, withoutPrefix: v{17, 0, 0}},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{9, 0, 0}},
+	},
+	GradientFunctions: {
+		{engine: Chrome, prefix: WebkitPrefix, withoutPrefix: v{26, 0, 0}},
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{16, 0, 0}},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{7, 0, 0}},
+		{engine: Opera, prefix: OPrefix, withoutPrefix: v{12, 1, 0}},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{7, 0, 0}},
+	},
+	ImageSetFunction: {
+		{engine: Chrome, prefix: WebkitPrefix, withoutPrefix: v{113, 0, 0}},
+		{engine: Edge, prefix: WebkitPrefix, withoutPrefix: v{113, 0, 0}},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{14, 0, 0}},
+		{engine: Opera, prefix: WebkitPrefix, withoutPrefix: v{99, 0, 0}},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{14, 0, 0}},
+	},
+	PseudoAnyLink: {
+		{engine: Chrome, prefix: WebkitPrefix, withoutPrefix: v{65, 0, 0}},
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{50, 0, 0}},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{9, 0, 0}},
+		{engine: Opera, prefix: WebkitPrefix, withoutPrefix: v{52, 0, 0}},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{9, 0, 0}},
+	},
+	PseudoBackdrop: {
+		{engine: Chrome, prefix: WebkitPrefix, withoutPrefix: v{37, 0, 0}},
+		{engine: Edge, prefix: MsPrefix, withoutPrefix: v{79, 0, 0}},
+		{engine: IE, prefix: MsPrefix},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{15, 4, 0}},
+		{engine: Opera, prefix: WebkitPrefix, withoutPrefix: v{24, 0, 0}},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{15, 4, 0}},
+	},
+	PseudoFileSelectorButton: {
+		{engine: Chrome, prefix: WebkitPrefix, withoutPrefix: v{89, 0, 0}},
+		{engine: Edge, prefix: WebkitPrefix, withoutPrefix: v{89, 0, 0}},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{14, 5, 0}},
+		{engine: Opera, prefix: WebkitPrefix, withoutPrefix: v{75, 0, 0}},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{14, 1, 0}},
+	},
+	PseudoFullscreen: {
+		{engine: Chrome, prefix: WebkitPrefix, withoutPrefix: v{71, 0, 0}},
+		{engine: Edge, prefix: MsPrefix, withoutPrefix: v{79, 0, 0}},
+		{engine: Edge, prefix: WebkitPrefix, withoutPrefix: v{79, 0, 0}},
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{64, 0, 0}},
+		{engine: IE, prefix: MsPrefix},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{16, 4, 0}},
+		{engine: Opera, prefix: WebkitPrefix, withoutPrefix: v{58, 0, 0}},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{16, 4, 0}},
+	},
+	PseudoPlaceholder: {
+		{engine: Chrome, prefix: WebkitPrefix, withoutPrefix: v{57, 0, 0}},
+		{engine: Edge, prefix: MsPrefix, withoutPrefix: v{79, 0, 0}},
+		{engine: Edge, prefix: WebkitPrefix, withoutPrefix: v{79, 0, 0}},
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{51, 0, 0}},
+		{engine: IE, prefix: MsPrefix},
+		{engine: IOS, prefix: WebkitPrefix, withoutPrefix: v{10, 3, 0}},
+		{engine: Opera, prefix: WebkitPrefix, withoutPrefix: v{44, 0, 0}},
+		{engine: Safari, prefix: WebkitPrefix, withoutPrefix: v{10, 1, 0}},
+	},
+	PseudoPlaceholderShown: {
+		{engine: Edge, prefix: MsPrefix, withoutPrefix: v{79, 0, 0}},
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{51, 0, 0}},
+		{engine: IE, prefix: MsPrefix},
+	},
+	PseudoReadOnly: {
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{78, 0, 0}},
+	},
+	PseudoReadWrite: {
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{78, 0, 0}},
+	},
+	PseudoSelection: {
+		{engine: Firefox, prefix: MozPrefix, withoutPrefix: v{62, 0, 0}},
+	},
+}
+
+func CSSSyntaxPrefixData(constraints map[Engine]Semver) (entries map[CSSPrefixedSyntax]CSSPrefix) {
+	for syntax, items := range cssSyntaxPrefixTable {
+		if prefixes := prefixesForConstraints(items, constraints); prefixes != NoPrefix {
+			if entries == nil {
+				entries = make(map[CSSPrefixedSyntax]CSSPrefix)
+			}
+			entries[syntax] = prefixes
+		}
+	}
+	return
+}
+
+func prefixesForConstraints(items []prefixData, constraints map[Engine]Semver) CSSPrefix {
+	prefixes := NoPrefix
+	for engine, version := range constraints {
+		if !engine.IsBrowser() {
+			// Specifying "--target=es2020" shouldn't affect CSS
+			continue
+		}
+		for _, item := range items {
+			if item.engine == engine && (item.withoutPrefix == v{} || compareVersions(item.withoutPrefix, version) > 0) {
+				prefixes |= item.prefix
+			}
+		}
+	}
+	return prefixes
+}
@@ -181,6 +181,19 @@ func (lc LegalComments) HasExternalFile() bool {
 	return lc == LegalCommentsLinkedWithComment || lc == LegalCommentsExternalWithoutComment
 }
 
+type CSSModuleTypes uint8
+
+const (
+	CSSModuleTypesNone CSSModuleTypes = iota
+
+	// "button.module.css" => "button.module.css.d.ts"
+	CSSModuleTypesDTS
+
+	// "button.module.css" => "button.module.d.css.ts" (this form requires the
+	// "allowArbitraryExtensions" setting in TypeScript 5.0+)
+	CSSModuleTypesDCSSTS
+)
+
 type Loader uint8
 
 const (
@@ -194,6 +207,7 @@ const (
 	LoaderEmpty
 	LoaderFile
 	LoaderGlobalCSS
+	LoaderHTML
 	LoaderJS
 	LoaderJSON
 	LoaderWithTypeJSON // Has a "with { type: 'json' }" attribute
@@ -216,6 +230,7 @@ var LoaderToString = []string{
 	"empty",
 	"file",
 	"global-css",
+	"html",
 	"js",
 	"json",
 	"json",
@@ -317,10 +332,45 @@ const (
 	//   export {...};
 	//
 	FormatESModule
+
+	// The SystemJS format looks like this:
+	//
+	//   System.register([...dependencies], function(exports, module) {
+	//     "use strict";
+	//     return {
+	//       setters: [...],
+	//       execute: function() {
+	//         ... bundled code ...
+	//         exports({...});
+	//       }
+	//     };
+	//   });
+	//
+	// It's generated from the same code as the ES module format. The linker
+	// and printer convert import and export statements at the very end.
+	FormatSystem
+
+	// The UMD format looks like this:
+	//
+	//   (function(root, factory) {
+	//     if (typeof define === "function" && define.amd) {
+	//       define([...dependencies], factory);
+	//     } else if (typeof module === "object" && module.exports) {
+	//       module.exports = factory(...requires);
+	//     } else {
+	//       root.globalName = factory(...globals);
+	//     }
+	//   })(typeof self !== "undefined" ? self : this, function(...dependencies) {
+	//     ... bundled code ...
+	//     return exports;
+	//   });
+	//
+	// Each external module is passed to the factory function as an argument.
+	FormatUMD
 )
 
 func (f Format) KeepESMImportExportSyntax() bool {
-	return f == FormatPreserve || f == FormatESModule
+	return f == FormatPreserve || f == FormatESModule || f == FormatSystem
 }
 
 func (f Format) String() string {
@@ -331,10 +381,22 @@ func (f Format) String() string {
 		return "cjs"
 	case FormatESModule:
 		return "esm"
+	case FormatSystem:
+		return "system"
+	case FormatUMD:
+		return "umd"
 	}
 	return ""
 }
 
+// This is how an external module is loaded by each of the three ways the UMD
+// format can be used. Empty fields mean the default for that import path.
+type UMDExternal struct {
+	AMD      string   // The module identifier passed to "define()"
+	CommonJS string   // The module name passed to "require()"
+	Global   []string // The property path on the global object
+}
+
 type StdinInfo struct {
 	Contents      string
 	SourceFile    string
@@ -442,6 +504,7 @@ type Options struct {
 	OutputExtensionJS  string
 	OutputExtensionCSS string
 	GlobalName         []string
+	UMDExternals       map[string]UMDExternal
 	TSConfigPath       string
 	TSConfigRaw        string
 	ExtensionToLoader  map[string]Loader
@@ -460,6 +523,18 @@ type Options struct {
 	ChunkPathTemplate []PathTemplate
 	AssetPathTemplate []PathTemplate
 
+	// This controls the names that local CSS names from the "local-css" loader
+	// are renamed to. If this is empty, the default naming scheme is used.
+	LocalCSSNameTemplate []PathTemplate
+
+	// If enabled, a TypeScript declaration file is written next to each CSS
+	// module that's imported from JavaScript. It lists the exported names.
+	CSSModuleTypes CSSModuleTypes
+
+	// These force matching files into named chunks when code splitting. They
+	// are sorted by name so that earlier chunks take precedence.
+	ManualChunks []ManualChunk
+
 	Plugins    []Plugin
 	SourceRoot string
 	Stdin      *StdinInfo
@@ -467,6 +542,7 @@ type Options struct {
 	LineLimit  int
 
 	CSSPrefixData          map[css_ast.D]compat.CSSPrefix
+	CSSSyntaxPrefixData    map[compat.CSSPrefixedSyntax]compat.CSSPrefix
 	UnsupportedJSFeatures  compat.JSFeature
 	UnsupportedCSSFeatures compat.CSSFeature
 
@@ -495,6 +571,21 @@ type Options struct {
 	// If true, make sure to generate a single file that can be written to stdout
 	WriteToStdout bool
 
+	// If true, every module is wrapped in a closure that can be re-run with new
+	// code at run-time. This also enables the "import.meta.hot" API.
+	HotModuleReplacement bool
+
+	// If true, top-level await is allowed when the output format or target
+	// doesn't support it. Modules that use it (and every module that imports
+	// them) are wrapped in async initializers instead.
+	LowerTopLevelAwait bool
+
+	// If non-empty, big integer literals and operators on values that are known
+	// to be big integers are converted into calls into this JSBI-compatible
+	// module when the target doesn't support BigInt. The module is imported
+	// using a default import.
+	BigIntLibrary string
+
 	OmitRuntimeForTests    bool
 	OmitJSXRuntimeForTests bool
 	ASCIIOnly              bool
@@ -600,6 +691,10 @@ const (
 	// The original extension of the file, or the name of the output file
 	// (e.g. "css", "svg", "png")
 	ExtPlaceholder
+
+	// The original name of a local CSS class or identifier. This is only valid
+	// in the template for local CSS names.
+	LocalPlaceholder
 )
 
 type PathTemplate struct {
@@ -608,10 +703,11 @@ type PathTemplate struct {
 }
 
 type PathPlaceholders struct {
-	Dir  *string
-	Name *string
-	Hash *string
-	Ext  *string
+	Dir   *string
+	Name  *string
+	Hash  *string
+	Ext   *string
+	Local *string
 }
 
 func (placeholders PathPlaceholders) Get(placeholder PathPlaceholder) *string {
@@ -624,6 +720,8 @@ func (placeholders PathPlaceholders) Get(placeholder PathPlaceholder) *string {
 		return placeholders.Hash
 	case ExtPlaceholder:
 		return placeholders.Ext
+	case LocalPlaceholder:
+		return placeholders.Local
 	}
 	return nil
 }
@@ -645,6 +743,8 @@ func TemplateToString(template []PathTemplate) string {
 			sb.WriteString("[hash]")
 		case ExtPlaceholder:
 			sb.WriteString("[ext]")
+		case LocalPlaceholder:
+			sb.WriteString("[local]")
 		}
 	}
 	return sb.String()
@@ -771,10 +871,14 @@ func PluginAppliesToPath(path logger.Path, filter *regexp.Regexp, namespace stri
 // Plugin API
 
 type Plugin struct {
-	Name      string
-	OnStart   []OnStart
-	OnResolve []OnResolve
-	OnLoad    []OnLoad
+	Name           string
+	OnStart        []OnStart
+	OnResolve      []OnResolve
+	OnLoad         []OnLoad
+	OnTransform    []OnTransform
+	OnManualChunk  []OnManualChunk
+	OnOutput       []OnOutput
+	OnLocalCSSName []OnLocalCSSName
 }
 
 type OnStart struct {
@@ -846,6 +950,131 @@ type OnLoadResult struct {
 	Loader Loader
 }
 
+type OnTransform struct {
+	Filter    *regexp.Regexp
+	Callback  func(OnTransformArgs) OnTransformResult
+	Name      string
+	Namespace string
+}
+
+type OnTransformArgs struct {
+	Path     logger.Path
+	Contents string
+	Loader   Loader
+
+	// If an earlier callback returned a source map, this maps the contents back
+	// to the contents of the file before it was transformed. It's only present
+	// when source maps are enabled.
+	SourceMap string
+}
+
+type OnTransformResult struct {
+	PluginName string
+
+	Contents  *string
+	SourceMap *string
+
+	Msgs        []logger.Msg
+	ThrownError error
+}
+
+type OnManualChunk struct {
+	Filter    *regexp.Regexp
+	Callback  func(OnManualChunkArgs) OnManualChunkResult
+	Name      string
+	Namespace string
+}
+
+type OnManualChunkArgs struct {
+	Path logger.Path
+}
+
+type OnManualChunkResult struct {
+	PluginName string
+	ChunkName  string
+
+	Msgs        []logger.Msg
+	ThrownError error
+}
+
+type OnLocalCSSName struct {
+	Filter    *regexp.Regexp
+	Callback  func(OnLocalCSSNameArgs) OnLocalCSSNameResult
+	Name      string
+	Namespace string
+}
+
+type OnLocalCSSNameArgs struct {
+	Path  logger.Path
+	Local string
+}
+
+type OnLocalCSSNameResult struct {
+	PluginName string
+	Name       string
+
+	Msgs        []logger.Msg
+	ThrownError error
+}
+
+type OnOutput struct {
+	Filter   *regexp.Regexp
+	Callback func(OnOutputArgs) OnOutputResult
+	Name     string
+}
+
+type OnOutputArgs struct {
+	// This is the absolute path of the output file. The final hash isn't known
+	// yet so the "[hash]" placeholder is left in the path unsubstituted.
+	Path string
+
+	// Paths to other output files are unique keys at this point that are
+	// substituted with the final paths later. They must be left unmodified.
+	Contents  string
+	SourceMap string
+
+	EntryPoint string
+	Modules    []string
+}
+
+type OnOutputResult struct {
+	PluginName string
+
+	Contents  *string
+	SourceMap *string
+
+	Msgs        []logger.Msg
+	ThrownError error
+}
+
+type ManualChunk struct {
+	Name string
+
+	// Each pattern is matched against the absolute path of the file with
+	// forward slashes. Package names are converted into patterns that match
+	// any path inside a "node_modules" directory for that package.
+	Patterns []*regexp.Regexp
+}
+
+func (chunk ManualChunk) Matches(path logger.Path) bool {
+	if path.Namespace != "file" {
+		return false
+	}
+	text := strings.ReplaceAll(path.Text, "\\", "/")
+	for _, re := range chunk.Patterns {
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
+
+// Manual chunk names are substituted for "[name]" in the chunk path template,
+// so they must not be able to escape from the output directory.
+func IsValidManualChunkName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\")
+}
+
 func PrettyPrintTargetEnvironment(originalTargetEnv string, unsupportedJSFeatureOverridesMask compat.JSFeature) (where string) {
 	where = "the configured target environment"
 	overrides := ""
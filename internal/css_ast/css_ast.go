@@ -36,6 +36,14 @@ type AST struct {
 	GlobalScope          map[string]ast.LocRef
 	Composes             map[ast.Ref]*Composes
 
+	// These are the ICSS values defined by "@value" rules and ":export" blocks
+	// in CSS modules, in the order they were defined. They are exposed to
+	// JavaScript as additional exports of the CSS module. Values that were
+	// imported from another file have an entry in "ImportedValues" and are
+	// resolved by the linker.
+	Values         []ICSSValue
+	ImportedValues map[string]ImportedICSSValue
+
 	// These contain all layer names in the file. It can be used to replace the
 	// layer-related side effects of importing this file. They are split into two
 	// groups (those before and after "@import" rules) so that the linker can put
@@ -72,6 +80,21 @@ type Composes struct {
 	Properties map[string]logger.Loc
 }
 
+type ICSSValue struct {
+	Name    string
+	NameLoc logger.Loc
+	Value   []Token
+}
+
+// This is a value imported from another file using "@value ... from":
+//
+//	@value primary, secondary as accent from "./colors.css";
+type ImportedICSSValue struct {
+	Alias             string
+	AliasLoc          logger.Loc
+	ImportRecordIndex uint32
+}
+
 type ImportedComposesName struct {
 	Alias             string
 	AliasLoc          logger.Loc
@@ -753,6 +776,141 @@ func (r *RAtLayer) Hash() (uint32, bool) {
 	return hash, true
 }
 
+// This is a "@custom-media --name <media-query-list>;" rule. The query list
+// is stored as tokens and is only parsed when the definition is substituted
+// into a "@media" rule that references it.
+type RAtCustomMedia struct {
+	Name    string
+	NameLoc logger.Loc
+	Prelude []Token
+}
+
+func (a *RAtCustomMedia) Equal(rule R, check *CrossFileEqualityCheck) bool {
+	b, ok := rule.(*RAtCustomMedia)
+	return ok && a.Name == b.Name && TokensEqual(a.Prelude, b.Prelude, check)
+}
+
+func (r *RAtCustomMedia) Hash() (uint32, bool) {
+	hash := uint32(14)
+	hash = helpers.HashCombineString(hash, r.Name)
+	hash = HashTokens(hash, r.Prelude)
+	return hash, true
+}
+
+// This is a "@scope (start) to (end) { ... }" rule. Either selector list is
+// nil if it was omitted. The scope root is the parent element of the owner
+// node of the style sheet when there is no start selector list.
+type RAtScope struct {
+	Start         []ComplexSelector
+	End           []ComplexSelector
+	Rules         []Rule
+	CloseBraceLoc logger.Loc
+}
+
+func (a *RAtScope) Equal(rule R, check *CrossFileEqualityCheck) bool {
+	b, ok := rule.(*RAtScope)
+	return ok && (a.Start == nil) == (b.Start == nil) && (a.End == nil) == (b.End == nil) &&
+		ComplexSelectorsEqual(a.Start, b.Start, check) && ComplexSelectorsEqual(a.End, b.End, check) &&
+		RulesEqual(a.Rules, b.Rules, check)
+}
+
+func (r *RAtScope) Hash() (uint32, bool) {
+	hash := uint32(15)
+	hash = helpers.HashCombine(hash, uint32(len(r.Start)))
+	hash = HashComplexSelectors(hash, r.Start)
+	hash = helpers.HashCombine(hash, uint32(len(r.End)))
+	hash = HashComplexSelectors(hash, r.End)
+	hash = HashRules(hash, r.Rules)
+	return hash, true
+}
+
+// This is a single query in a "@media" prelude such as "only screen and
+// (width >= 600px)". The preludes of "@media" rules are still stored as tokens
+// so that queries are printed exactly as they were written. This tree is only
+// constructed when a query needs to be transformed (e.g. when lowering range
+// syntax or when substituting "@custom-media" definitions).
+type MediaQuery struct {
+	// This is nil if the query only has a media type
+	Condition MediaCondition
+
+	// This is empty if the query doesn't have a media type
+	Type string
+
+	Modifier MediaModifier
+}
+
+type MediaModifier uint8
+
+const (
+	MediaModifierNone MediaModifier = iota
+	MediaModifierNot
+	MediaModifierOnly
+)
+
+type MediaCondition interface {
+	isMediaCondition()
+}
+
+// "not (color)"
+type MediaNot struct {
+	Inner MediaCondition
+}
+
+// "(color) and (hover)"
+type MediaAnd struct {
+	Terms []MediaCondition
+}
+
+// "(color) or (hover)"
+type MediaOr struct {
+	Terms []MediaCondition
+}
+
+// This is either "(name: value)" or "(name)". References to "@custom-media"
+// definitions are boolean features with a name that starts with "--".
+type MediaFeature struct {
+	Name     string
+	Value    []Token // This is nil for boolean features
+	NameLoc  logger.Loc
+	ParenLoc logger.Loc
+}
+
+// This is a range such as "(width >= 600px)" or "(400px < width <= 700px)".
+// Either side may be omitted but not both.
+type MediaRange struct {
+	Name     string
+	Left     []Token
+	Right    []Token
+	LeftOp   MediaRangeOp
+	RightOp  MediaRangeOp
+	NameLoc  logger.Loc
+	ParenLoc logger.Loc
+}
+
+type MediaRangeOp uint8
+
+const (
+	MediaRangeNone MediaRangeOp = iota
+	MediaRangeLt
+	MediaRangeLe
+	MediaRangeGt
+	MediaRangeGe
+	MediaRangeEq
+)
+
+// This is something in parentheses that isn't understood, which is called
+// "<general-enclosed>" in the specification. It's passed through unmodified.
+type MediaGeneral struct {
+	Token Token
+}
+
+func (*MediaNot) isMediaCondition()     {}
+func (*MediaAnd) isMediaCondition()     {}
+func (*MediaOr) isMediaCondition()      {}
+func (*MediaFeature) isMediaCondition() {}
+func (*MediaRange) isMediaCondition()   {}
+func (*MediaGeneral) isMediaCondition() {}
+
 type ComplexSelector struct {
 	Selectors []CompoundSelector
 }
@@ -90,6 +90,7 @@ func (p *parser) processDeclarations(rules []css_ast.Rule, composesContext *comp
 	didWarnAboutComposes := false
 	wouldClipColorFlag := false
 	var declarationKeys map[string]struct{}
+	var darkRules []css_ast.Rule
 
 	// Don't automatically generate the "inset" property if it's not supported
 	if p.options.unsupportedCSSFeatures.Has(compat.InsetProperty) {
@@ -142,6 +143,14 @@ func (p *parser) processDeclarations(rules []css_ast.Rule, composesContext *comp
 			wouldClipColor = &wouldClipColorFlag
 		}
 
+		// "light-dark()" can only be lowered inside a style rule since it relies
+		// on nesting a "@media" rule within the style rule
+		if p.options.unsupportedCSSFeatures.Has(compat.LightDark) && p.inSelectorSubtree > 0 {
+			if dark, ok := lowerLightDark(decl); ok && !isDeclarationOverridden(decl, rules[i+1:]) {
+				darkRules = append(darkRules, css_ast.Rule{Loc: rule.Loc, Data: dark})
+			}
+		}
+
 		switch decl.Key {
 		case css_ast.DComposes:
 			// Only process "composes" directives if we're in "local-css" or
@@ -380,6 +389,9 @@ func (p *parser) processDeclarations(rules []css_ast.Rule, composesContext *comp
 				rewrittenRules = p.insertPrefixedDeclaration(rewrittenRules, "-o-", rule.Loc, decl, declarationKeys)
 			}
 		}
+		if p.options.cssSyntaxPrefixData != nil {
+			rewrittenRules = p.insertPrefixedValues(rewrittenRules, rule.Loc, decl)
+		}
 
 		// If this loop iteration would have clipped a color, the out-of-gamut
 		// colors will not be clipped and this flag will be set. We then set up the
@@ -406,6 +418,12 @@ func (p *parser) processDeclarations(rules []css_ast.Rule, composesContext *comp
 		}
 	}
 
+	// Add the dark versions of any "light-dark()" declarations at the end
+	if darkRules != nil {
+		p.nestingIsPresent = true
+		rewrittenRules = append(rewrittenRules, p.prefersDarkColorSchemeRule(darkRules[0].Loc, p.processDeclarations(darkRules, nil)))
+	}
+
 	// Compact removed rules
 	if p.options.minifySyntax {
 		end := 0
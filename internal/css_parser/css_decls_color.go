@@ -306,7 +306,25 @@ func (p *parser) lowerAndMinifyColor(token css_ast.Token, wouldClipColor *bool)
 		}
 
 	case css_lexer.TFunction:
+		// "rgb(from red r g b / 50%)" => "rgba(255, 0, 0, 0.5)"
+		if isRelativeColor(token) {
+			if p.options.unsupportedCSSFeatures.Has(compat.RelativeColors) {
+				if color, ok := parseDerivedColor(token); ok {
+					return p.generateDerivedColor(token, color, wouldClipColor)
+				}
+			}
+			return token
+		}
+
 		switch strings.ToLower(text) {
+		case "color-mix":
+			// "color-mix(in srgb, red, blue)" => "#800080"
+			if p.options.unsupportedCSSFeatures.Has(compat.ColorMix) {
+				if color, ok := parseDerivedColor(token); ok {
+					return p.generateDerivedColor(token, color, wouldClipColor)
+				}
+			}
+
 		case "rgb", "rgba", "hsl", "hsla":
 			if p.options.unsupportedCSSFeatures.Has(compat.Modern_RGB_HSL) {
 				args := *token.Children
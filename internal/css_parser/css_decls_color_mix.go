@@ -0,0 +1,368 @@
+package css_parser
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_lexer"
+	"github.com/evanw/esbuild/internal/helpers"
+)
+
+// This is like "parseColor" but also evaluates "color-mix()" and relative
+// colors such as "rgb(from red r g b / 50%)". These are only evaluated when
+// all of their inputs are constant. Anything that depends on the environment
+// (e.g. "var()", "currentcolor", or "calc()") causes this to fail.
+func parseDerivedColor(token css_ast.Token) (parsedColor, bool) {
+	switch token.Kind {
+	case css_lexer.TIdent:
+		if strings.EqualFold(token.Text, "transparent") {
+			return parsedColor{hex: 0}, true
+		}
+
+	case css_lexer.TFunction:
+		if strings.EqualFold(token.Text, "color-mix") {
+			return parseColorMix(token)
+		}
+		if isRelativeColor(token) {
+			return parseRelativeColor(token)
+		}
+	}
+	return parseColor(token)
+}
+
+func isRelativeColor(token css_ast.Token) bool {
+	if token.Kind == css_lexer.TFunction && token.Children != nil {
+		if args := *token.Children; len(args) > 0 && args[0].Kind == css_lexer.TIdent && strings.EqualFold(args[0].Text, "from") {
+			return true
+		}
+	}
+	return false
+}
+
+// Derived colors can be outside of the sRGB gamut even when their inputs are
+// not, so they are printed using "color()" if that's supported
+func (p *parser) generateDerivedColor(token css_ast.Token, color parsedColor, wouldClipColor *bool) css_ast.Token {
+	if !p.options.unsupportedCSSFeatures.Has(compat.ColorFunctions) {
+		if hex, ok := tryToConvertToHexWithoutClipping(color.x, color.y, color.z, color.hex); ok {
+			return p.tryToGenerateColor(token, parsedColor{hex: hex}, nil)
+		}
+		result := makeColorToken(token.Loc, color.x, color.y, color.z, helpers.NewF64(float64(color.hex)).DivConst(255))
+		result.Whitespace = token.Whitespace
+		return result
+	}
+	return p.tryToGenerateColor(token, color, wouldClipColor)
+}
+
+func parsedColorToXYZ(color parsedColor) (x F64, y F64, z F64, alpha F64) {
+	alpha = helpers.NewF64(float64(color.hex & 255)).DivConst(255)
+	if color.hasColorSpace {
+		return color.x, color.y, color.z, alpha
+	}
+	r := helpers.NewF64(float64(hexR(color.hex))).DivConst(255)
+	g := helpers.NewF64(float64(hexG(color.hex))).DivConst(255)
+	b := helpers.NewF64(float64(hexB(color.hex))).DivConst(255)
+	x, y, z = lin_srgb_to_xyz(lin_srgb(r, g, b))
+	return
+}
+
+func xyzToParsedColor(x F64, y F64, z F64, alpha F64) parsedColor {
+	return parsedColor{hasColorSpace: true, x: x, y: y, z: z, hex: floatToByte(alpha.Value())}
+}
+
+// Returns the index of the hue channel for polar color spaces
+func hueIndex(colorSpace colorSpace) int {
+	switch colorSpace {
+	case colorSpace_hsl, colorSpace_hwb:
+		return 0
+	case colorSpace_lch, colorSpace_oklch:
+		return 2
+	}
+	return -1
+}
+
+// Reference: https://drafts.csswg.org/css-color-5/#color-mix
+func parseColorMix(token css_ast.Token) (parsedColor, bool) {
+	var groups [][]css_ast.Token
+	start := 0
+	args := *token.Children
+	for i, t := range args {
+		if t.Kind == css_lexer.TComma {
+			groups = append(groups, args[start:i])
+			start = i + 1
+		}
+	}
+	groups = append(groups, args[start:])
+
+	// "color-mix(in oklch longer hue, red, blue)"
+	colorSpace := colorSpace_oklab
+	hueMethod := shorterHue
+	switch len(groups) {
+	case 2:
+	case 3:
+		remaining, space, method, ok := removeColorInterpolation(groups[0])
+		if !ok || len(remaining) > 0 {
+			return parsedColor{}, false
+		}
+		colorSpace, hueMethod = space, method
+		groups = groups[1:]
+	default:
+		return parsedColor{}, false
+	}
+
+	// "red 40%" or "40% red"
+	var colors [2]parsedColor
+	var percents [2]float64
+	var hasPercent [2]bool
+	for i, group := range groups {
+		var colorToken css_ast.Token
+		switch len(group) {
+		case 1:
+			colorToken = group[0]
+		case 2:
+			percent := group[1]
+			colorToken = group[0]
+			if group[0].Kind == css_lexer.TPercentage {
+				percent, colorToken = group[0], group[1]
+			}
+			value, ok := percent.NumberOrFractionForPercentage(100, css_ast.AllowAnyPercentage)
+			if !ok || percent.Kind != css_lexer.TPercentage || value < 0 || value > 100 {
+				return parsedColor{}, false
+			}
+			percents[i] = value
+			hasPercent[i] = true
+		default:
+			return parsedColor{}, false
+		}
+		color, ok := parseDerivedColor(colorToken)
+		if !ok {
+			return parsedColor{}, false
+		}
+		colors[i] = color
+	}
+
+	// Normalize the percentages
+	if !hasPercent[0] && !hasPercent[1] {
+		percents[0], percents[1] = 50, 50
+	} else if !hasPercent[1] {
+		percents[1] = 100 - percents[0]
+	} else if !hasPercent[0] {
+		percents[0] = 100 - percents[1]
+	}
+	sum := percents[0] + percents[1]
+	if sum == 0 {
+		return parsedColor{}, false
+	}
+	alphaMultiplier := 1.0
+	if sum < 100 {
+		alphaMultiplier = sum / 100
+	}
+	t := helpers.NewF64(percents[1] / sum)
+
+	// Convert both colors into the interpolation color space. Mixing two sRGB
+	// colors in an sRGB-based color space avoids going through XYZ so that the
+	// result isn't affected by rounding errors (e.g. "#800080" vs. "#7f0080").
+	var v [2][3]F64
+	var alphas [2]F64
+	isSRGB := !colors[0].hasColorSpace && !colors[1].hasColorSpace &&
+		(colorSpace == colorSpace_srgb || colorSpace == colorSpace_hsl || colorSpace == colorSpace_hwb)
+	for i, color := range colors {
+		x, y, z, alpha := parsedColorToXYZ(color)
+		if isSRGB {
+			r := helpers.NewF64(float64(hexR(color.hex))).DivConst(255)
+			g := helpers.NewF64(float64(hexG(color.hex))).DivConst(255)
+			b := helpers.NewF64(float64(hexB(color.hex))).DivConst(255)
+			switch colorSpace {
+			case colorSpace_srgb:
+				v[i][0], v[i][1], v[i][2] = r, g, b
+			case colorSpace_hsl:
+				v[i][0], v[i][1], v[i][2] = rgb_to_hsl(r, g, b)
+			case colorSpace_hwb:
+				v[i][0], v[i][1], v[i][2] = rgb_to_hwb(r, g, b)
+			}
+		} else {
+			v[i][0], v[i][1], v[i][2] = xyz_to_colorSpace(x, y, z, colorSpace)
+		}
+		alphas[i] = alpha
+	}
+
+	// A missing hue takes on the hue of the other color
+	if h := hueIndex(colorSpace); h != -1 {
+		if v[0][h].IsNaN() && v[1][h].IsNaN() {
+			v[0][h], v[1][h] = helpers.NewF64(0), helpers.NewF64(0)
+		} else if v[0][h].IsNaN() {
+			v[0][h] = v[1][h]
+		} else if v[1][h].IsNaN() {
+			v[1][h] = v[0][h]
+		}
+	}
+
+	a0, a1, a2 := premultiply(v[0][0], v[0][1], v[0][2], alphas[0], colorSpace)
+	b0, b1, b2 := premultiply(v[1][0], v[1][1], v[1][2], alphas[1], colorSpace)
+	v0, v1, v2 := interpolateColors(a0, a1, a2, b0, b1, b2, colorSpace, hueMethod, t)
+	alpha := helpers.Lerp(alphas[0], alphas[1], t)
+	v0, v1, v2 = unpremultiply(v0, v1, v2, alpha, colorSpace)
+	alpha = alpha.MulConst(alphaMultiplier)
+	if isSRGB {
+		switch colorSpace {
+		case colorSpace_hsl:
+			v0, v1, v2 = hsl_to_rgb(v0, v1, v2)
+		case colorSpace_hwb:
+			v0, v1, v2 = hwb_to_rgb(v0, v1, v2)
+		}
+		return parsedColor{hex: packRGBA(v0, v1, v2, floatToByte(alpha.Value()))}, true
+	}
+	x, y, z := colorSpace_to_xyz(v0, v1, v2, colorSpace)
+	return xyzToParsedColor(x, y, z, alpha), true
+}
+
+type relativeColorChannel struct {
+	name string
+
+	// The value of a percentage of 100%
+	percentReference float64
+
+	isHue bool
+}
+
+// Reference: https://drafts.csswg.org/css-color-5/#relative-colors
+func parseRelativeColor(token css_ast.Token) (parsedColor, bool) {
+	args := *token.Children
+	if len(args) < 2 {
+		return parsedColor{}, false
+	}
+	origin, ok := parseDerivedColor(args[1])
+	if !ok {
+		return parsedColor{}, false
+	}
+	args = args[2:]
+
+	var colorSpace colorSpace
+	var channels [3]relativeColorChannel
+	scale := 1.0 // The channel values are divided by this before conversion
+
+	switch strings.ToLower(token.Text) {
+	case "rgb", "rgba":
+		colorSpace = colorSpace_srgb
+		channels = [3]relativeColorChannel{{name: "r", percentReference: 255}, {name: "g", percentReference: 255}, {name: "b", percentReference: 255}}
+		scale = 255
+
+	case "hsl", "hsla":
+		colorSpace = colorSpace_hsl
+		channels = [3]relativeColorChannel{{name: "h", isHue: true}, {name: "s", percentReference: 100}, {name: "l", percentReference: 100}}
+
+	case "hwb":
+		colorSpace = colorSpace_hwb
+		channels = [3]relativeColorChannel{{name: "h", isHue: true}, {name: "w", percentReference: 100}, {name: "b", percentReference: 100}}
+
+	case "lab":
+		colorSpace = colorSpace_lab
+		channels = [3]relativeColorChannel{{name: "l", percentReference: 100}, {name: "a", percentReference: 125}, {name: "b", percentReference: 125}}
+
+	case "lch":
+		colorSpace = colorSpace_lch
+		channels = [3]relativeColorChannel{{name: "l", percentReference: 100}, {name: "c", percentReference: 150}, {name: "h", isHue: true}}
+
+	case "oklab":
+		colorSpace = colorSpace_oklab
+		channels = [3]relativeColorChannel{{name: "l", percentReference: 1}, {name: "a", percentReference: 0.4}, {name: "b", percentReference: 0.4}}
+
+	case "oklch":
+		colorSpace = colorSpace_oklch
+		channels = [3]relativeColorChannel{{name: "l", percentReference: 1}, {name: "c", percentReference: 0.4}, {name: "h", isHue: true}}
+
+	case "color":
+		if len(args) == 0 || args[0].Kind != css_lexer.TIdent {
+			return parsedColor{}, false
+		}
+		rgb := [3]relativeColorChannel{{name: "r", percentReference: 1}, {name: "g", percentReference: 1}, {name: "b", percentReference: 1}}
+		xyz := [3]relativeColorChannel{{name: "x", percentReference: 1}, {name: "y", percentReference: 1}, {name: "z", percentReference: 1}}
+		switch strings.ToLower(args[0].Text) {
+		case "a98-rgb":
+			colorSpace, channels = colorSpace_a98_rgb, rgb
+		case "display-p3":
+			colorSpace, channels = colorSpace_display_p3, rgb
+		case "prophoto-rgb":
+			colorSpace, channels = colorSpace_prophoto_rgb, rgb
+		case "rec2020":
+			colorSpace, channels = colorSpace_rec2020, rgb
+		case "srgb":
+			colorSpace, channels = colorSpace_srgb, rgb
+		case "srgb-linear":
+			colorSpace, channels = colorSpace_srgb_linear, rgb
+		case "xyz", "xyz-d65":
+			colorSpace, channels = colorSpace_xyz_d65, xyz
+		case "xyz-d50":
+			colorSpace, channels = colorSpace_xyz_d50, xyz
+		default:
+			return parsedColor{}, false
+		}
+		args = args[1:]
+
+	default:
+		return parsedColor{}, false
+	}
+
+	// "r g b" or "r g b / alpha"
+	var alphaToken css_ast.Token
+	switch len(args) {
+	case 3:
+	case 5:
+		if args[3].Kind != css_lexer.TDelimSlash {
+			return parsedColor{}, false
+		}
+		alphaToken = args[4]
+	default:
+		return parsedColor{}, false
+	}
+
+	// Resolve the channel keywords using the origin color
+	x, y, z, originAlpha := parsedColorToXYZ(origin)
+	var keywords [3]F64
+	keywords[0], keywords[1], keywords[2] = xyz_to_colorSpace(x, y, z, colorSpace)
+	for i := range keywords {
+		if keywords[i].IsNaN() {
+			keywords[i] = helpers.NewF64(0)
+		}
+		keywords[i] = keywords[i].MulConst(scale)
+	}
+	resolve := func(t css_ast.Token, channel relativeColorChannel) (F64, bool) {
+		if t.Kind == css_lexer.TIdent {
+			for i, c := range channels {
+				if strings.EqualFold(t.Text, c.name) {
+					return keywords[i], true
+				}
+			}
+			if strings.EqualFold(t.Text, "alpha") {
+				return originAlpha, true
+			}
+			return F64{}, false
+		}
+		if channel.isHue {
+			degrees, ok := degreesForAngle(t)
+			return helpers.NewF64(degrees), ok
+		}
+		value, ok := t.NumberOrFractionForPercentage(channel.percentReference, css_ast.AllowAnyPercentage)
+		return helpers.NewF64(value), ok
+	}
+
+	var values [3]F64
+	for i, channel := range channels {
+		value, ok := resolve(args[i], channel)
+		if !ok {
+			return parsedColor{}, false
+		}
+		values[i] = value.DivConst(scale)
+	}
+	alpha := originAlpha
+	if alphaToken.Kind != css_lexer.T(0) {
+		value, ok := resolve(alphaToken, relativeColorChannel{percentReference: 1})
+		if !ok {
+			return parsedColor{}, false
+		}
+		alpha = value
+	}
+
+	x, y, z = colorSpace_to_xyz(values[0], values[1], values[2], colorSpace)
+	return xyzToParsedColor(x, y, z, alpha), true
+}
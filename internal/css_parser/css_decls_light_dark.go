@@ -0,0 +1,115 @@
+package css_parser
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_lexer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This lowers "light-dark()" by using the light color in the declaration and
+// then returning a copy of the declaration that uses the dark color instead.
+// The caller is expected to wrap the copy in a nested rule like this:
+//
+//	a { color: light-dark(white, black) }
+//
+// becomes:
+//
+//	a {
+//	  color: white;
+//	  @media (prefers-color-scheme: dark) { color: black }
+//	}
+//
+// Note that this isn't exactly the same because "light-dark()" depends on the
+// "color-scheme" property instead of on the user's preference. But it's the
+// closest approximation that works in older browsers.
+func lowerLightDark(decl *css_ast.RDeclaration) (*css_ast.RDeclaration, bool) {
+	light, ok := substituteLightDark(decl.Value, 0)
+	if !ok {
+		return nil, false
+	}
+	dark, _ := substituteLightDark(css_ast.CloneTokensWithoutImportRecords(decl.Value), 1)
+	decl.Value = light
+	clone := *decl
+	clone.Value = dark
+	return &clone, true
+}
+
+// This returns a copy of the tokens with each "light-dark()" replaced by one
+// of its arguments. It fails if there are no valid "light-dark()" calls.
+func substituteLightDark(tokens []css_ast.Token, which int) ([]css_ast.Token, bool) {
+	var result []css_ast.Token
+	didSubstitute := false
+
+	for _, t := range tokens {
+		if t.Kind == css_lexer.TFunction && strings.EqualFold(t.Text, "light-dark") && t.Children != nil {
+			if args, ok := splitLightDarkArgs(*t.Children); ok {
+				start := len(result)
+				result = append(result, args[which]...)
+				first, last := &result[start], &result[len(result)-1]
+				first.Whitespace = (first.Whitespace & ^css_ast.WhitespaceBefore) | (t.Whitespace & css_ast.WhitespaceBefore)
+				last.Whitespace = (last.Whitespace & ^css_ast.WhitespaceAfter) | (t.Whitespace & css_ast.WhitespaceAfter)
+				didSubstitute = true
+				continue
+			}
+		}
+
+		if t.Children != nil {
+			if children, ok := substituteLightDark(*t.Children, which); ok {
+				t.Children = &children
+				didSubstitute = true
+			}
+		}
+		result = append(result, t)
+	}
+
+	return result, didSubstitute
+}
+
+func splitLightDarkArgs(tokens []css_ast.Token) (args [2][]css_ast.Token, ok bool) {
+	for i, t := range tokens {
+		if t.Kind == css_lexer.TComma {
+			if args[0] != nil {
+				return
+			}
+			args[0] = tokens[:i]
+			args[1] = tokens[i+1:]
+		}
+	}
+	ok = len(args[0]) > 0 && len(args[1]) > 0
+	return
+}
+
+// The dark copy of a declaration is omitted if a later declaration for the
+// same property would override it, since it would otherwise come last
+func isDeclarationOverridden(decl *css_ast.RDeclaration, after []css_ast.Rule) bool {
+	for _, rule := range after {
+		if later, ok := rule.Data.(*css_ast.RDeclaration); ok {
+			if decl.Key != css_ast.DUnknown && later.Key == decl.Key {
+				return true
+			}
+			if decl.Key == css_ast.DUnknown && later.KeyText == decl.KeyText {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *parser) prefersDarkColorSchemeRule(loc logger.Loc, rules []css_ast.Rule) css_ast.Rule {
+	value := css_ast.Token{Loc: loc, Kind: css_lexer.TIdent, Text: "dark"}
+	if !p.options.minifyWhitespace {
+		value.Whitespace = css_ast.WhitespaceBefore
+	}
+	children := []css_ast.Token{
+		{Loc: loc, Kind: css_lexer.TIdent, Text: "prefers-color-scheme"},
+		{Loc: loc, Kind: css_lexer.TColon, Text: ":"},
+		value,
+	}
+	return css_ast.Rule{Loc: loc, Data: &css_ast.RKnownAt{
+		AtToken: "media",
+		Prelude: []css_ast.Token{{Loc: loc, Kind: css_lexer.TOpenParen, Text: "(", Children: &children}},
+		Rules:   rules,
+	}}
+}
@@ -0,0 +1,373 @@
+package css_parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_lexer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This file implements the parts of ICSS (Interoperable CSS) that are used by
+// CSS modules to share values with JavaScript and with other CSS files:
+//
+//	@value primary: #f00;
+//	@value secondary, tertiary as accent from "./colors.css";
+//	:export { headerHeight: 64px }
+//
+// These rules are removed from the output. Each name becomes an additional
+// export of the CSS module, and each "@value" name is substituted into the
+// declarations and at-rule preludes of the file that defines or imports it.
+// Imported values are substituted by the linker once all files are known.
+
+// Returns false if this isn't a valid "@value" rule, in which case it should
+// be parsed as an unknown at-rule instead
+func (p *parser) parseAtValue() bool {
+	atIndex := p.index
+	atRange := p.current().Range
+	p.advance()
+
+	// Find the end of the rule
+	start := p.index
+	for {
+		if kind := p.current().Kind; kind == css_lexer.TSemicolon || kind == css_lexer.TOpenBrace ||
+			kind == css_lexer.TCloseBrace || kind == css_lexer.TEndOfFile {
+			break
+		}
+		p.parseComponentValue()
+	}
+	if p.peek(css_lexer.TOpenBrace) || p.peek(css_lexer.TCloseBrace) {
+		p.index = atIndex
+		return false
+	}
+	tokens, _ := p.convertTokensHelper(p.tokens[start:p.index], css_lexer.TEndOfFile, convertTokensOpts{allowImports: true})
+	p.expect(css_lexer.TSemicolon)
+
+	if len(tokens) == 0 {
+		p.log.AddID(logger.MsgID_CSS_CSSSyntaxError, logger.Warning, &p.tracker, atRange, "Expected a name after \"@value\"")
+		return true
+	}
+
+	// Handle imports: "@value a, b as c from "./file.css";"
+	if n := len(tokens); n >= 3 && tokens[n-2].Kind == css_lexer.TIdent && strings.EqualFold(tokens[n-2].Text, "from") {
+		p.parseAtValueImport(tokens[:n-2], tokens[n-1])
+		return true
+	}
+
+	// Handle definitions: "@value name: value;" or "@value name value;"
+	name := tokens[0]
+	if name.Kind != css_lexer.TIdent {
+		p.log.AddID(logger.MsgID_CSS_CSSSyntaxError, logger.Warning, &p.tracker, logger.Range{Loc: name.Loc},
+			"Expected a name after \"@value\"")
+		return true
+	}
+	value := tokens[1:]
+	if len(value) > 0 && value[0].Kind == css_lexer.TColon {
+		value = value[1:]
+	}
+	if len(value) > 0 {
+		value = append([]css_ast.Token{}, value...)
+		value[0].Whitespace &= ^css_ast.WhitespaceBefore
+		value[len(value)-1].Whitespace &= ^css_ast.WhitespaceAfter
+	}
+
+	// Values can reference values that were defined earlier in the same file
+	value, _ = p.substituteLocalICSSValues(value)
+	p.defineICSSValue(name.Text, name.Loc, value)
+	return true
+}
+
+func (p *parser) parseAtValueImport(names []css_ast.Token, path css_ast.Token) {
+	var importRecordIndex uint32
+	switch path.Kind {
+	case css_lexer.TString:
+		importRecordIndex = uint32(len(p.importRecords))
+		p.importRecords = append(p.importRecords, ast.ImportRecord{
+			Kind:  ast.ImportComposesFrom,
+			Path:  logger.Path{Text: path.Text},
+			Range: p.source.RangeOfString(path.Loc),
+		})
+
+	case css_lexer.TIdent:
+		// The path can also be a value containing a string:
+		//
+		//   @value colors: "./colors.css";
+		//   @value primary from colors;
+		//
+		if index, ok := p.icssValues[path.Text]; ok {
+			if value := p.values[index].Value; len(value) == 1 && value[0].Kind == css_lexer.TString {
+				importRecordIndex = uint32(len(p.importRecords))
+				p.importRecords = append(p.importRecords, ast.ImportRecord{
+					Kind:  ast.ImportComposesFrom,
+					Path:  logger.Path{Text: value[0].Text},
+					Range: css_lexer.RangeOfIdentifier(p.source, path.Loc),
+				})
+				break
+			}
+		}
+		p.log.AddID(logger.MsgID_CSS_CSSSyntaxError, logger.Warning, &p.tracker, css_lexer.RangeOfIdentifier(p.source, path.Loc),
+			fmt.Sprintf("Expected a string containing a path but found %q", path.Text))
+		return
+
+	default:
+		p.log.AddID(logger.MsgID_CSS_CSSSyntaxError, logger.Warning, &p.tracker, logger.Range{Loc: path.Loc},
+			fmt.Sprintf("Expected a string containing a path but found %s", path.Kind.String()))
+		return
+	}
+
+	// The list of names may be wrapped in parentheses
+	if len(names) == 1 && names[0].Kind == css_lexer.TOpenParen && names[0].Children != nil {
+		names = *names[0].Children
+	}
+
+	for len(names) > 0 {
+		// Each name is either "name" or "name as alias"
+		end := 0
+		for end < len(names) && names[end].Kind != css_lexer.TComma {
+			end++
+		}
+		group := names[:end]
+		if end < len(names) {
+			end++
+		}
+		names = names[end:]
+
+		if len(group) == 1 && group[0].Kind == css_lexer.TIdent {
+			p.importICSSValue(group[0].Text, group[0].Text, group[0].Loc, importRecordIndex)
+		} else if len(group) == 3 && group[0].Kind == css_lexer.TIdent && group[1].Kind == css_lexer.TIdent &&
+			strings.EqualFold(group[1].Text, "as") && group[2].Kind == css_lexer.TIdent {
+			p.importICSSValue(group[2].Text, group[0].Text, group[0].Loc, importRecordIndex)
+		} else {
+			var loc logger.Loc
+			if len(group) > 0 {
+				loc = group[0].Loc
+			} else {
+				loc = path.Loc
+			}
+			p.log.AddID(logger.MsgID_CSS_CSSSyntaxError, logger.Warning, &p.tracker, logger.Range{Loc: loc},
+				"Expected a name or \"name as alias\" in \"@value\" import")
+		}
+	}
+}
+
+// Returns false if this isn't an ":export" block, in which case it should be
+// parsed as a normal style rule instead
+func (p *parser) parseICSSExport() bool {
+	if !p.peek(css_lexer.TColon) || p.next().Kind != css_lexer.TIdent ||
+		!strings.EqualFold(p.at(p.index+1).DecodedText(p.source.Contents), "export") {
+		return false
+	}
+	index := p.index + 2
+	for p.at(index).Kind == css_lexer.TWhitespace {
+		index++
+	}
+	if p.at(index).Kind != css_lexer.TOpenBrace {
+		return false
+	}
+	matchingLoc := p.at(index).Range.Loc
+	p.index = index + 1
+
+	// Parse "name: value" pairs without interpreting them as CSS properties
+	for {
+		switch p.current().Kind {
+		case css_lexer.TWhitespace, css_lexer.TSemicolon:
+			p.advance()
+			continue
+
+		case css_lexer.TCloseBrace, css_lexer.TEndOfFile:
+			p.expectWithMatchingLoc(css_lexer.TCloseBrace, matchingLoc)
+			return true
+		}
+
+		nameLoc := p.current().Range.Loc
+		name := p.decoded()
+		ok := p.expect(css_lexer.TIdent)
+		if ok {
+			p.eat(css_lexer.TWhitespace)
+			ok = p.expect(css_lexer.TColon)
+		}
+		start := p.index
+		for {
+			if kind := p.current().Kind; kind == css_lexer.TSemicolon || kind == css_lexer.TCloseBrace || kind == css_lexer.TEndOfFile {
+				break
+			}
+			p.parseComponentValue()
+		}
+		if !ok {
+			continue
+		}
+		value, _ := p.convertTokensHelper(p.tokens[start:p.index], css_lexer.TEndOfFile, convertTokensOpts{allowImports: true})
+		if len(value) > 0 {
+			value[0].Whitespace &= ^css_ast.WhitespaceBefore
+			value[len(value)-1].Whitespace &= ^css_ast.WhitespaceAfter
+		}
+		value, _ = p.substituteLocalICSSValues(value)
+		p.defineICSSValue(name, nameLoc, value)
+	}
+}
+
+func (p *parser) defineICSSValue(name string, nameLoc logger.Loc, value []css_ast.Token) {
+	if value == nil {
+		value = []css_ast.Token{}
+	}
+	delete(p.importedValues, name)
+	if index, ok := p.icssValues[name]; ok {
+		p.values[index] = css_ast.ICSSValue{Name: name, NameLoc: nameLoc, Value: value}
+		return
+	}
+	if p.icssValues == nil {
+		p.icssValues = make(map[string]int)
+	}
+	p.icssValues[name] = len(p.values)
+	p.values = append(p.values, css_ast.ICSSValue{Name: name, NameLoc: nameLoc, Value: value})
+}
+
+func (p *parser) importICSSValue(name string, alias string, aliasLoc logger.Loc, importRecordIndex uint32) {
+	if p.importedValues == nil {
+		p.importedValues = make(map[string]css_ast.ImportedICSSValue)
+	}
+	p.importedValues[name] = css_ast.ImportedICSSValue{Alias: alias, AliasLoc: aliasLoc, ImportRecordIndex: importRecordIndex}
+
+	// Imported values are exported too, but their value is filled in later
+	if index, ok := p.icssValues[name]; ok {
+		p.values[index] = css_ast.ICSSValue{Name: name, NameLoc: aliasLoc}
+		return
+	}
+	if p.icssValues == nil {
+		p.icssValues = make(map[string]int)
+	}
+	p.icssValues[name] = len(p.values)
+	p.values = append(p.values, css_ast.ICSSValue{Name: name, NameLoc: aliasLoc})
+}
+
+func (p *parser) substituteLocalICSSValues(tokens []css_ast.Token) ([]css_ast.Token, bool) {
+	return SubstituteICSSValuesInTokens(tokens, p.lookupLocalICSSValue)
+}
+
+func (p *parser) lookupLocalICSSValue(name string) ([]css_ast.Token, bool) {
+	if _, ok := p.importedValues[name]; ok {
+		// Imported values are substituted by the linker
+		return nil, false
+	}
+	if index, ok := p.icssValues[name]; ok {
+		return p.values[index].Value, true
+	}
+	return nil, false
+}
+
+// This substitutes "@value" names into the declarations and at-rule preludes
+// of the given rules. The rules passed in may be shared with other output
+// files, so any rule that needs to change is cloned instead of being modified
+// in place.
+func SubstituteICSSValues(rules []css_ast.Rule, lookup func(name string) ([]css_ast.Token, bool)) ([]css_ast.Rule, bool) {
+	var result []css_ast.Rule
+	didChange := false
+
+	for i, rule := range rules {
+		switch data := rule.Data.(type) {
+		case *css_ast.RDeclaration:
+			if value, ok := SubstituteICSSValuesInTokens(data.Value, lookup); ok {
+				clone := *data
+				clone.Value = value
+				rule.Data = &clone
+			}
+
+		case *css_ast.RKnownAt:
+			prelude, preludeChanged := SubstituteICSSValuesInTokens(data.Prelude, lookup)
+			childRules, childrenChanged := SubstituteICSSValues(data.Rules, lookup)
+			if preludeChanged || childrenChanged {
+				clone := *data
+				clone.Prelude = prelude
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RSelector:
+			if childRules, ok := SubstituteICSSValues(data.Rules, lookup); ok {
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RQualified:
+			if childRules, ok := SubstituteICSSValues(data.Rules, lookup); ok {
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RAtLayer:
+			if childRules, ok := SubstituteICSSValues(data.Rules, lookup); ok {
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RAtScope:
+			if childRules, ok := SubstituteICSSValues(data.Rules, lookup); ok {
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+		}
+
+		if rule.Data != rules[i].Data && !didChange {
+			result = append(make([]css_ast.Rule, 0, len(rules)), rules[:i]...)
+			didChange = true
+		}
+		if didChange {
+			result = append(result, rule)
+		}
+	}
+
+	if !didChange {
+		return rules, false
+	}
+	return result, true
+}
+
+// Every identifier with the same name as a value is replaced with the tokens
+// for that value
+func SubstituteICSSValuesInTokens(tokens []css_ast.Token, lookup func(name string) ([]css_ast.Token, bool)) ([]css_ast.Token, bool) {
+	var result []css_ast.Token
+	didChange := false
+
+	for i, t := range tokens {
+		var replacement []css_ast.Token
+		isReplaced := false
+
+		if t.Kind == css_lexer.TIdent {
+			if value, ok := lookup(t.Text); ok {
+				replacement = css_ast.CloneTokensWithoutImportRecords(value)
+				if len(replacement) > 0 {
+					replacement[0].Whitespace |= t.Whitespace & css_ast.WhitespaceBefore
+					replacement[len(replacement)-1].Whitespace |= t.Whitespace & css_ast.WhitespaceAfter
+				}
+				isReplaced = true
+			}
+		} else if t.Children != nil {
+			if children, ok := SubstituteICSSValuesInTokens(*t.Children, lookup); ok {
+				t.Children = &children
+				replacement = []css_ast.Token{t}
+				isReplaced = true
+			}
+		}
+
+		if isReplaced && !didChange {
+			result = append(make([]css_ast.Token, 0, len(tokens)), tokens[:i]...)
+			didChange = true
+		}
+		if isReplaced {
+			result = append(result, replacement...)
+		} else if didChange {
+			result = append(result, t)
+		}
+	}
+
+	if !didChange {
+		return tokens, false
+	}
+	return result, true
+}
@@ -0,0 +1,363 @@
+package css_parser
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_lexer"
+)
+
+// This removes all "@layer" rules from the given files for browsers that
+// don't support cascade layers. The priority of each layer is preserved by
+// increasing the specificity of the selectors in later layers using repeated
+// ":not(#\#)" pseudo-classes. Each of these has the specificity of an ID
+// selector but matches every element (since no element has an ID of "#").
+//
+// The files must be passed in the order they appear in the output since that
+// order determines the order of the layers. The ASTs are modified in place
+// but the rules they point to are cloned before being modified, since they
+// may be shared with other output files.
+//
+// The layer order is reversed for "!important" declarations, so they are moved
+// into a separate copy of their rule that uses the reversed order.
+func LowerCascadeLayers(asts []css_ast.AST) {
+	root := &cascadeLayer{}
+	maxIDs := 0
+
+	// Determine the order of all layers and the maximum specificity
+	for _, tree := range asts {
+		if count := root.collectLayers(tree.Rules); count > maxIDs {
+			maxIDs = count
+		}
+	}
+	if len(root.children) == 0 {
+		return
+	}
+
+	// Each layer must have a higher specificity than any selector in the layer
+	// before it. Unlayered rules come after all layers.
+	rank := 0
+	root.assignRanks(&rank)
+	lowering := cascadeLayerLowering{boostPerRank: maxIDs + 1, maxRank: root.rank}
+	for i := range asts {
+		asts[i].Rules = lowering.lowerRules(asts[i].Rules, root)
+	}
+}
+
+type cascadeLayer struct {
+	children []*cascadeLayer
+	byName   map[string]*cascadeLayer
+
+	// Anonymous layers can't be looked up by name, so they are matched up by
+	// the order in which they appear instead
+	anonymous     []*cascadeLayer
+	nextAnonymous int
+
+	rank int
+}
+
+func (layer *cascadeLayer) child(name string) *cascadeLayer {
+	if child, ok := layer.byName[name]; ok {
+		return child
+	}
+	child := &cascadeLayer{}
+	if layer.byName == nil {
+		layer.byName = make(map[string]*cascadeLayer)
+	}
+	layer.byName[name] = child
+	layer.children = append(layer.children, child)
+	return child
+}
+
+func (layer *cascadeLayer) childForPath(path []string) *cascadeLayer {
+	for _, name := range path {
+		layer = layer.child(name)
+	}
+	return layer
+}
+
+// Returns the maximum number of IDs in any selector
+func (layer *cascadeLayer) collectLayers(rules []css_ast.Rule) int {
+	maxIDs := 0
+	for _, rule := range rules {
+		count := 0
+		if names, layerRules, ok := layerFromRule(rule); ok {
+			if layerRules == nil {
+				// "@layer a, b;"
+				for _, path := range names {
+					layer.childForPath(path)
+				}
+			} else if len(names) == 0 {
+				// "@layer { ... }"
+				anonymous := &cascadeLayer{}
+				layer.children = append(layer.children, anonymous)
+				layer.anonymous = append(layer.anonymous, anonymous)
+				count = anonymous.collectLayers(layerRules)
+			} else {
+				// "@layer a { ... }"
+				count = layer.childForPath(names[0]).collectLayers(layerRules)
+			}
+		} else {
+			switch r := rule.Data.(type) {
+			case *css_ast.RKnownAt:
+				count = layer.collectLayers(r.Rules)
+
+			case *css_ast.RAtScope:
+				count = layer.collectLayers(r.Rules)
+
+			case *css_ast.RSelector:
+				count = maxIDsInSelectorRule(r)
+			}
+		}
+		if count > maxIDs {
+			maxIDs = count
+		}
+	}
+	return maxIDs
+}
+
+// Nested layers have a lower priority than the rules directly in the parent
+// layer, so the parent layer is ranked after all of its children
+func (layer *cascadeLayer) assignRanks(rank *int) {
+	for _, child := range layer.children {
+		child.assignRanks(rank)
+	}
+	layer.rank = *rank
+	*rank++
+}
+
+func maxIDsInSelectorRule(r *css_ast.RSelector) int {
+	maxIDs := 0
+	for _, sel := range r.Selectors {
+		if count := countIDsInComplexSelector(sel); count > maxIDs {
+			maxIDs = count
+		}
+	}
+
+	// Nested rules add their specificity to the specificity of the parent rule
+	maxNested := 0
+	for _, rule := range r.Rules {
+		if nested, ok := rule.Data.(*css_ast.RSelector); ok {
+			if count := maxIDsInSelectorRule(nested); count > maxNested {
+				maxNested = count
+			}
+		}
+	}
+	return maxIDs + maxNested
+}
+
+func countIDsInComplexSelector(sel css_ast.ComplexSelector) int {
+	count := 0
+	for _, compound := range sel.Selectors {
+		for _, ss := range compound.SubclassSelectors {
+			switch s := ss.Data.(type) {
+			case *css_ast.SSHash:
+				count++
+
+			case *css_ast.SSPseudoClassWithSelectorList:
+				// ":where()" never adds any specificity
+				if s.Kind == css_ast.PseudoClassWhere {
+					continue
+				}
+				maxIDs := 0
+				for _, inner := range s.Selectors {
+					if innerCount := countIDsInComplexSelector(inner); innerCount > maxIDs {
+						maxIDs = innerCount
+					}
+				}
+				count += maxIDs
+			}
+		}
+	}
+	return count
+}
+
+type cascadeLayerLowering struct {
+	boostPerRank int
+	maxRank      int
+}
+
+func (l *cascadeLayerLowering) lowerRules(rules []css_ast.Rule, layer *cascadeLayer) []css_ast.Rule {
+	result := make([]css_ast.Rule, 0, len(rules))
+	for _, rule := range rules {
+		if names, layerRules, ok := layerFromRule(rule); ok {
+			if layerRules == nil {
+				// The order of these layers has already been taken into account
+				continue
+			}
+			var child *cascadeLayer
+			if len(names) == 0 {
+				// Anonymous layers are visited in the same order as before
+				child = layer.anonymous[layer.nextAnonymous]
+				layer.nextAnonymous++
+			} else {
+				child = layer.childForPath(names[0])
+			}
+			result = append(result, l.lowerRules(layerRules, child)...)
+			continue
+		}
+
+		switch r := rule.Data.(type) {
+		case *css_ast.RKnownAt:
+			if r.Rules != nil {
+				clone := *r
+				clone.Rules = l.lowerRules(r.Rules, layer)
+				if len(clone.Rules) == 0 && len(r.Rules) > 0 {
+					// Remove rules that only contained "@layer" statements
+					continue
+				}
+				rule.Data = &clone
+			}
+
+		case *css_ast.RAtScope:
+			clone := *r
+			clone.Rules = l.lowerRules(r.Rules, layer)
+			if len(clone.Rules) == 0 && len(r.Rules) > 0 {
+				// Remove rules that only contained "@layer" statements
+				continue
+			}
+			rule.Data = &clone
+
+		case *css_ast.RSelector:
+			// "!important" declarations in earlier layers win over the ones in later
+			// layers, and unlayered ones lose to all of them. So they are split off
+			// into a copy of the rule that uses the reverse order.
+			if normal, important := splitImportantDeclarations(r.Rules); len(important) > 0 {
+				if len(normal) > 0 {
+					result = append(result, css_ast.Rule{Loc: rule.Loc, Data: l.boostSelectorRule(r, normal, layer.rank)})
+				}
+				result = append(result, css_ast.Rule{Loc: rule.Loc, Data: l.boostSelectorRule(r, important, l.maxRank-layer.rank)})
+				continue
+			}
+			if layer.rank > 0 {
+				rule.Data = l.boostSelectorRule(r, r.Rules, layer.rank)
+			}
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+func (l *cascadeLayerLowering) boostSelectorRule(r *css_ast.RSelector, rules []css_ast.Rule, rank int) *css_ast.RSelector {
+	clone := *r
+	clone.Rules = rules
+	if boost := rank * l.boostPerRank; boost > 0 {
+		clone.Selectors = make([]css_ast.ComplexSelector, len(r.Selectors))
+		for i, sel := range r.Selectors {
+			clone.Selectors[i] = boostSpecificity(sel, boost)
+		}
+	}
+	return &clone
+}
+
+// This separates "!important" declarations from everything else, including
+// the ones in nested rules. The important list is empty if there are none.
+func splitImportantDeclarations(rules []css_ast.Rule) (normal []css_ast.Rule, important []css_ast.Rule) {
+	for _, rule := range rules {
+		switch r := rule.Data.(type) {
+		case *css_ast.RDeclaration:
+			if r.Important {
+				important = append(important, rule)
+				continue
+			}
+
+		case *css_ast.RSelector:
+			if innerNormal, innerImportant := splitImportantDeclarations(r.Rules); len(innerImportant) > 0 {
+				if len(innerNormal) > 0 {
+					clone := *r
+					clone.Rules = innerNormal
+					normal = append(normal, css_ast.Rule{Loc: rule.Loc, Data: &clone})
+				}
+				clone := *r
+				clone.Rules = innerImportant
+				important = append(important, css_ast.Rule{Loc: rule.Loc, Data: &clone})
+				continue
+			}
+
+		case *css_ast.RKnownAt:
+			if innerNormal, innerImportant := splitImportantDeclarations(r.Rules); len(innerImportant) > 0 {
+				if len(innerNormal) > 0 {
+					clone := *r
+					clone.Rules = innerNormal
+					normal = append(normal, css_ast.Rule{Loc: rule.Loc, Data: &clone})
+				}
+				clone := *r
+				clone.Rules = innerImportant
+				important = append(important, css_ast.Rule{Loc: rule.Loc, Data: &clone})
+				continue
+			}
+		}
+		normal = append(normal, rule)
+	}
+	return
+}
+
+// The linker generates "@layer" rules for "@import" conditions as known
+// at-rules with the layer name in the prelude, so handle those too
+func layerFromRule(rule css_ast.Rule) ([][]string, []css_ast.Rule, bool) {
+	switch r := rule.Data.(type) {
+	case *css_ast.RAtLayer:
+		return r.Names, r.Rules, true
+
+	case *css_ast.RKnownAt:
+		if !strings.EqualFold(r.AtToken, "layer") {
+			break
+		}
+		if len(r.Prelude) == 0 {
+			return nil, r.Rules, true
+		}
+		var path []string
+		for i, t := range r.Prelude {
+			if i&1 == 0 {
+				if t.Kind != css_lexer.TIdent {
+					return nil, nil, false
+				}
+				path = append(path, t.Text)
+			} else if t.Kind != css_lexer.TDelimDot {
+				return nil, nil, false
+			}
+		}
+		if len(r.Prelude)&1 == 0 {
+			return nil, nil, false
+		}
+		return [][]string{path}, r.Rules, true
+	}
+
+	return nil, nil, false
+}
+
+func boostSpecificity(sel css_ast.ComplexSelector, ids int) css_ast.ComplexSelector {
+	compounds := append([]css_ast.CompoundSelector{}, sel.Selectors...)
+	last := &compounds[len(compounds)-1]
+
+	// Pseudo-elements must come last, so insert before any pseudo-elements
+	insertAt := len(last.SubclassSelectors)
+	for i, ss := range last.SubclassSelectors {
+		if pseudo, ok := ss.Data.(*css_ast.SSPseudoClass); ok && (pseudo.IsElement || isLegacyPseudoElement(pseudo.Name)) {
+			insertAt = i
+			break
+		}
+	}
+
+	subclass := make([]css_ast.SubclassSelector, 0, len(last.SubclassSelectors)+ids)
+	subclass = append(subclass, last.SubclassSelectors[:insertAt]...)
+	for i := 0; i < ids; i++ {
+		subclass = append(subclass, css_ast.SubclassSelector{
+			Data: &css_ast.SSPseudoClass{
+				Name: "not",
+				Args: []css_ast.Token{{Kind: css_lexer.THash, Text: "#"}},
+			},
+		})
+	}
+	subclass = append(subclass, last.SubclassSelectors[insertAt:]...)
+	last.SubclassSelectors = subclass
+	return css_ast.ComplexSelector{Selectors: compounds}
+}
+
+func isLegacyPseudoElement(name string) bool {
+	switch name {
+	case "before", "after", "first-line", "first-letter":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,863 @@
+package css_parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_lexer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+type mediaFeatureType uint8
+
+const (
+	mediaFeatureLength mediaFeatureType = iota + 1
+	mediaFeatureResolution
+	mediaFeatureInteger
+	mediaFeatureRatio
+)
+
+// These are the media features that are allowed to use range syntax, which
+// also means they have "min-" and "max-" prefixed forms.
+// Specification: https://drafts.csswg.org/mediaqueries-4/#mq-range-context
+var mediaRangeFeatures = map[string]mediaFeatureType{
+	"aspect-ratio":        mediaFeatureRatio,
+	"color":               mediaFeatureInteger,
+	"color-index":         mediaFeatureInteger,
+	"device-aspect-ratio": mediaFeatureRatio,
+	"device-height":       mediaFeatureLength,
+	"device-width":        mediaFeatureLength,
+	"height":              mediaFeatureLength,
+	"monochrome":          mediaFeatureInteger,
+	"resolution":          mediaFeatureResolution,
+	"width":               mediaFeatureLength,
+}
+
+// This is a media query along with the tokens it was parsed from. The tokens
+// are used when printing unless the query has been modified, which preserves
+// the original formatting of queries that weren't transformed.
+type parsedMediaQuery struct {
+	tokens []css_ast.Token // This is nil if the query was modified
+	query  css_ast.MediaQuery
+}
+
+func parseMediaQueryList(tokens []css_ast.Token) ([]parsedMediaQuery, bool) {
+	if len(tokens) == 0 {
+		return nil, false
+	}
+	var queries []parsedMediaQuery
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && tokens[i].Kind != css_lexer.TComma {
+			continue
+		}
+		query, ok := parseMediaQuery(tokens[start:i])
+		if !ok {
+			return nil, false
+		}
+		queries = append(queries, parsedMediaQuery{query: query, tokens: tokens[start:i]})
+		start = i + 1
+	}
+	return queries, true
+}
+
+// Specification: https://drafts.csswg.org/mediaqueries-4/#mq-syntax
+func parseMediaQuery(tokens []css_ast.Token) (query css_ast.MediaQuery, ok bool) {
+	if len(tokens) == 0 {
+		return
+	}
+
+	// Parse "[not | only]? <media-type> [and <media-condition-without-or>]?"
+	if first := tokens[0]; first.Kind == css_lexer.TIdent {
+		if lower := strings.ToLower(first.Text); lower != "not" || (len(tokens) > 1 && tokens[1].Kind == css_lexer.TIdent) {
+			if lower == "not" {
+				query.Modifier = css_ast.MediaModifierNot
+				tokens = tokens[1:]
+			} else if lower == "only" {
+				query.Modifier = css_ast.MediaModifierOnly
+				tokens = tokens[1:]
+			}
+			if len(tokens) == 0 || tokens[0].Kind != css_lexer.TIdent {
+				return
+			}
+			switch strings.ToLower(tokens[0].Text) {
+			case "and", "layer", "not", "only", "or":
+				return
+			}
+			query.Type = tokens[0].Text
+			tokens = tokens[1:]
+			if len(tokens) == 0 {
+				ok = true
+				return
+			}
+			if tokens[0].Kind != css_lexer.TIdent || !strings.EqualFold(tokens[0].Text, "and") {
+				return
+			}
+			cond, rest, condOk := parseMediaCondition(tokens[1:], false)
+			if !condOk || len(rest) > 0 {
+				return
+			}
+			query.Condition = cond
+			ok = true
+			return
+		}
+	}
+
+	// Otherwise, parse "<media-condition>"
+	cond, rest, condOk := parseMediaCondition(tokens, true)
+	if !condOk || len(rest) > 0 {
+		return
+	}
+	query.Condition = cond
+	ok = true
+	return
+}
+
+func parseMediaCondition(tokens []css_ast.Token, allowOr bool) (css_ast.MediaCondition, []css_ast.Token, bool) {
+	if len(tokens) == 0 {
+		return nil, nil, false
+	}
+
+	// "not <media-in-parens>"
+	if t := tokens[0]; t.Kind == css_lexer.TIdent && strings.EqualFold(t.Text, "not") {
+		if len(tokens) < 2 {
+			return nil, nil, false
+		}
+		inner, ok := parseMediaInParens(tokens[1])
+		if !ok {
+			return nil, nil, false
+		}
+		return &css_ast.MediaNot{Inner: inner}, tokens[2:], true
+	}
+
+	// "<media-in-parens> [and <media-in-parens>]*"
+	// "<media-in-parens> [or <media-in-parens>]*"
+	first, ok := parseMediaInParens(tokens[0])
+	if !ok {
+		return nil, nil, false
+	}
+	tokens = tokens[1:]
+	if len(tokens) == 0 || tokens[0].Kind != css_lexer.TIdent {
+		return first, tokens, true
+	}
+	op := strings.ToLower(tokens[0].Text)
+	if op != "and" && (op != "or" || !allowOr) {
+		return first, tokens, true
+	}
+	terms := []css_ast.MediaCondition{first}
+	for len(tokens) > 0 && tokens[0].Kind == css_lexer.TIdent && strings.EqualFold(tokens[0].Text, op) {
+		if len(tokens) < 2 {
+			return nil, nil, false
+		}
+		term, ok := parseMediaInParens(tokens[1])
+		if !ok {
+			return nil, nil, false
+		}
+		terms = append(terms, term)
+		tokens = tokens[2:]
+	}
+	if op == "and" {
+		return &css_ast.MediaAnd{Terms: terms}, tokens, true
+	}
+	return &css_ast.MediaOr{Terms: terms}, tokens, true
+}
+
+func parseMediaInParens(token css_ast.Token) (css_ast.MediaCondition, bool) {
+	switch token.Kind {
+	case css_lexer.TFunction:
+		return &css_ast.MediaGeneral{Token: token}, true
+
+	case css_lexer.TOpenParen:
+		children := *token.Children
+
+		// Handle a nested condition such as "((color) and (hover))"
+		if len(children) > 1 || (len(children) == 1 && children[0].Kind != css_lexer.TIdent) {
+			if first := children[0]; first.Kind == css_lexer.TOpenParen || first.Kind == css_lexer.TFunction ||
+				(first.Kind == css_lexer.TIdent && strings.EqualFold(first.Text, "not")) {
+				if cond, rest, ok := parseMediaCondition(children, true); ok && len(rest) == 0 {
+					return cond, true
+				}
+				return &css_ast.MediaGeneral{Token: token}, true
+			}
+		}
+
+		if feature, ok := parseMediaFeature(token); ok {
+			return feature, true
+		}
+		return &css_ast.MediaGeneral{Token: token}, true
+	}
+
+	return nil, false
+}
+
+func parseMediaFeature(token css_ast.Token) (css_ast.MediaCondition, bool) {
+	children := *token.Children
+
+	// "(name)"
+	if len(children) == 1 && children[0].Kind == css_lexer.TIdent {
+		return &css_ast.MediaFeature{Name: children[0].Text, NameLoc: children[0].Loc, ParenLoc: token.Loc}, true
+	}
+
+	// "(name: value)"
+	if len(children) >= 3 && children[0].Kind == css_lexer.TIdent && children[1].Kind == css_lexer.TColon {
+		return &css_ast.MediaFeature{
+			Name:     children[0].Text,
+			Value:    trimMediaTokens(children[2:]),
+			NameLoc:  children[0].Loc,
+			ParenLoc: token.Loc,
+		}, true
+	}
+
+	// Split the contents on the comparison operators
+	var parts [][]css_ast.Token
+	var ops []css_ast.MediaRangeOp
+	start := 0
+	for i := 0; i < len(children); {
+		op, n := mediaRangeOpAt(children, i)
+		if n == 0 {
+			i++
+			continue
+		}
+		parts = append(parts, children[start:i])
+		ops = append(ops, op)
+		i += n
+		start = i
+	}
+	parts = append(parts, children[start:])
+	for _, part := range parts {
+		if len(part) == 0 {
+			return nil, false
+		}
+	}
+
+	switch len(ops) {
+	case 1:
+		// "(name < value)"
+		if left := parts[0]; len(left) == 1 && left[0].Kind == css_lexer.TIdent {
+			return &css_ast.MediaRange{Name: left[0].Text, RightOp: ops[0], Right: trimMediaTokens(parts[1]), NameLoc: left[0].Loc, ParenLoc: token.Loc}, true
+		}
+
+		// "(value < name)"
+		if right := parts[1]; len(right) == 1 && right[0].Kind == css_lexer.TIdent {
+			return &css_ast.MediaRange{Name: right[0].Text, LeftOp: ops[0], Left: trimMediaTokens(parts[0]), NameLoc: right[0].Loc, ParenLoc: token.Loc}, true
+		}
+
+	case 2:
+		// "(value < name < value)"
+		isLess := ops[0] == css_ast.MediaRangeLt || ops[0] == css_ast.MediaRangeLe
+		isGreater := ops[0] == css_ast.MediaRangeGt || ops[0] == css_ast.MediaRangeGe
+		if middle := parts[1]; len(middle) == 1 && middle[0].Kind == css_lexer.TIdent &&
+			((isLess && (ops[1] == css_ast.MediaRangeLt || ops[1] == css_ast.MediaRangeLe)) ||
+				(isGreater && (ops[1] == css_ast.MediaRangeGt || ops[1] == css_ast.MediaRangeGe))) {
+			return &css_ast.MediaRange{
+				Name:     middle[0].Text,
+				Left:     trimMediaTokens(parts[0]),
+				LeftOp:   ops[0],
+				RightOp:  ops[1],
+				Right:    trimMediaTokens(parts[2]),
+				NameLoc:  middle[0].Loc,
+				ParenLoc: token.Loc,
+			}, true
+		}
+	}
+
+	return nil, false
+}
+
+func mediaRangeOpAt(tokens []css_ast.Token, i int) (css_ast.MediaRangeOp, int) {
+	t := tokens[i]
+	hasEqualsAfter := i+1 < len(tokens) && tokens[i+1].Kind == css_lexer.TDelimEquals &&
+		(t.Whitespace&css_ast.WhitespaceAfter) == 0 && (tokens[i+1].Whitespace&css_ast.WhitespaceBefore) == 0
+
+	switch {
+	case t.Kind == css_lexer.TDelim && t.Text == "<":
+		if hasEqualsAfter {
+			return css_ast.MediaRangeLe, 2
+		}
+		return css_ast.MediaRangeLt, 1
+
+	case t.Kind == css_lexer.TDelimGreaterThan:
+		if hasEqualsAfter {
+			return css_ast.MediaRangeGe, 2
+		}
+		return css_ast.MediaRangeGt, 1
+
+	case t.Kind == css_lexer.TDelimEquals:
+		return css_ast.MediaRangeEq, 1
+	}
+
+	return css_ast.MediaRangeNone, 0
+}
+
+func trimMediaTokens(tokens []css_ast.Token) []css_ast.Token {
+	tokens = append([]css_ast.Token{}, tokens...)
+	tokens[0].Whitespace &= ^css_ast.WhitespaceBefore
+	tokens[len(tokens)-1].Whitespace &= ^css_ast.WhitespaceAfter
+	return tokens
+}
+
+func flipMediaRangeOp(op css_ast.MediaRangeOp) css_ast.MediaRangeOp {
+	switch op {
+	case css_ast.MediaRangeLt:
+		return css_ast.MediaRangeGt
+	case css_ast.MediaRangeLe:
+		return css_ast.MediaRangeGe
+	case css_ast.MediaRangeGt:
+		return css_ast.MediaRangeLt
+	case css_ast.MediaRangeGe:
+		return css_ast.MediaRangeLe
+	}
+	return op
+}
+
+func isCustomMediaReference(cond css_ast.MediaCondition) (string, bool) {
+	if feature, ok := cond.(*css_ast.MediaFeature); ok && feature.Value == nil && strings.HasPrefix(feature.Name, "--") {
+		return feature.Name, true
+	}
+	return "", false
+}
+
+// This converts "(400px <= width <= 700px)" into "(min-width: 400px) and
+// (max-width: 700px)" for browsers that don't support range syntax. The
+// tokens are returned unmodified if there's nothing to lower.
+func (p *parser) lowerMediaQueryRanges(tokens []css_ast.Token) []css_ast.Token {
+	queries, ok := parseMediaQueryList(tokens)
+	if !ok {
+		return tokens
+	}
+	didChange := false
+	for i, q := range queries {
+		if cond, ok := p.lowerMediaCondition(q.query.Condition); ok {
+			queries[i].query.Condition = cond
+			queries[i].tokens = nil
+			didChange = true
+		}
+	}
+	if !didChange {
+		return tokens
+	}
+	return mediaQueryListToTokens(queries, tokens[0].Loc, p.options.minifyWhitespace)
+}
+
+func (p *parser) lowerMediaCondition(cond css_ast.MediaCondition) (css_ast.MediaCondition, bool) {
+	switch c := cond.(type) {
+	case *css_ast.MediaNot:
+		if inner, ok := p.lowerMediaCondition(c.Inner); ok {
+			return &css_ast.MediaNot{Inner: inner}, true
+		}
+
+	case *css_ast.MediaAnd:
+		var terms []css_ast.MediaCondition
+		didChange := false
+		for _, term := range c.Terms {
+			if lowered, ok := p.lowerMediaCondition(term); ok {
+				didChange = true
+
+				// "(a) and (1px < width < 2px)" => "(a) and (min-width: 1.001px) and (max-width: 1.999px)"
+				if and, ok := lowered.(*css_ast.MediaAnd); ok {
+					terms = append(terms, and.Terms...)
+					continue
+				}
+				term = lowered
+			}
+			terms = append(terms, term)
+		}
+		if didChange {
+			return &css_ast.MediaAnd{Terms: terms}, true
+		}
+
+	case *css_ast.MediaOr:
+		terms := make([]css_ast.MediaCondition, len(c.Terms))
+		didChange := false
+		for i, term := range c.Terms {
+			if lowered, ok := p.lowerMediaCondition(term); ok {
+				didChange = true
+				term = lowered
+			}
+			terms[i] = term
+		}
+		if didChange {
+			return &css_ast.MediaOr{Terms: terms}, true
+		}
+
+	case *css_ast.MediaRange:
+		kind, ok := mediaRangeFeatures[strings.ToLower(c.Name)]
+		if !ok {
+			break
+		}
+		var terms []css_ast.MediaCondition
+		if c.Left != nil {
+			feature, ok := p.lowerMediaRangeBound(c, kind, flipMediaRangeOp(c.LeftOp), c.Left)
+			if !ok {
+				break
+			}
+			terms = append(terms, feature)
+		}
+		if c.Right != nil {
+			feature, ok := p.lowerMediaRangeBound(c, kind, c.RightOp, c.Right)
+			if !ok {
+				break
+			}
+			terms = append(terms, feature)
+		}
+		if len(terms) == 1 {
+			return terms[0], true
+		}
+		return &css_ast.MediaAnd{Terms: terms}, true
+	}
+
+	return cond, false
+}
+
+func (p *parser) lowerMediaRangeBound(r *css_ast.MediaRange, kind mediaFeatureType, op css_ast.MediaRangeOp, value []css_ast.Token) (*css_ast.MediaFeature, bool) {
+	name := r.Name
+	switch op {
+	case css_ast.MediaRangeGe, css_ast.MediaRangeGt:
+		name = "min-" + name
+	case css_ast.MediaRangeLe, css_ast.MediaRangeLt:
+		name = "max-" + name
+	}
+
+	// Strict inequalities have no direct equivalent, so they are approximated
+	// by adjusting the value slightly. This only works for a single integer,
+	// length, or resolution. Ratios such as "16/9" can't be adjusted this way.
+	if op == css_ast.MediaRangeGt || op == css_ast.MediaRangeLt {
+		var token css_ast.Token
+		var number string
+		var unit string
+		delta := 0.001
+		isValid := false
+		if len(value) == 1 {
+			token = value[0]
+			switch kind {
+			case mediaFeatureInteger:
+				if token.Kind == css_lexer.TNumber && !strings.ContainsAny(token.Text, ".eE") {
+					number = token.Text
+					delta = 1
+					isValid = true
+				}
+			case mediaFeatureLength, mediaFeatureResolution:
+				if token.Kind == css_lexer.TDimension {
+					number = token.DimensionValue()
+					unit = token.DimensionUnit()
+					isValid = true
+				}
+			}
+		}
+		float, err := strconv.ParseFloat(number, 64)
+		if !isValid || err != nil {
+			p.reportUnsupportedMediaRange(r)
+			return nil, false
+		}
+		if op == css_ast.MediaRangeLt {
+			delta = -delta
+		}
+		float = math.Round((float+delta)*1e6) / 1e6
+		number = strconv.FormatFloat(float, 'f', -1, 64)
+		if p.options.minifySyntax {
+			if text, ok := mangleNumber(number); ok {
+				number = text
+			}
+		}
+		token.Text = number + unit
+		token.UnitOffset = uint16(len(number))
+		value = []css_ast.Token{token}
+		return &css_ast.MediaFeature{Name: name, Value: value, NameLoc: r.ParenLoc, ParenLoc: r.ParenLoc}, true
+	}
+
+	return &css_ast.MediaFeature{Name: name, Value: value, NameLoc: r.ParenLoc, ParenLoc: r.ParenLoc}, true
+}
+
+func (p *parser) reportUnsupportedMediaRange(r *css_ast.MediaRange) {
+	text := fmt.Sprintf("Transforming this %q range is not supported in the configured target environment", r.Name)
+	if p.options.originalTargetEnv != "" {
+		text = fmt.Sprintf("%s (%s)", text, p.options.originalTargetEnv)
+	}
+	p.log.AddIDWithNotes(logger.MsgID_CSS_UnsupportedAtMedia, logger.Warning, &p.tracker, logger.Range{Loc: r.NameLoc, Len: int32(len(r.Name))}, text, []logger.MsgData{{
+		Text: "Only a single integer, length, or resolution can be used with \"<\" or \">\" when transforming range syntax into \"min-\" and \"max-\" prefixed features.",
+	}})
+}
+
+func mediaQueryListToTokens(queries []parsedMediaQuery, loc logger.Loc, minifyWhitespace bool) []css_ast.Token {
+	var tokens []css_ast.Token
+	for i, q := range queries {
+		queryTokens := q.tokens
+		if queryTokens == nil {
+			queryTokens = mediaQueryToTokens(q.query, loc)
+		} else {
+			queryTokens = trimMediaTokens(queryTokens)
+		}
+		if i > 0 {
+			comma := css_ast.Token{Kind: css_lexer.TComma, Text: ",", Loc: queryTokens[0].Loc}
+			if !minifyWhitespace {
+				comma.Whitespace = css_ast.WhitespaceAfter
+				queryTokens[0].Whitespace |= css_ast.WhitespaceBefore
+			}
+			tokens = append(tokens, comma)
+		}
+		tokens = append(tokens, queryTokens...)
+	}
+	return tokens
+}
+
+func mediaQueryToTokens(query css_ast.MediaQuery, loc logger.Loc) []css_ast.Token {
+	var tokens []css_ast.Token
+	switch query.Modifier {
+	case css_ast.MediaModifierNot:
+		tokens = appendMediaIdent(tokens, "not", loc)
+	case css_ast.MediaModifierOnly:
+		tokens = appendMediaIdent(tokens, "only", loc)
+	}
+	if query.Type != "" {
+		tokens = appendMediaIdent(tokens, query.Type, loc)
+		if query.Condition == nil {
+			return tokens
+		}
+		tokens = appendMediaIdent(tokens, "and", loc)
+
+		// "screen and (a) or (b)" is invalid, so this must be parenthesized
+		if _, ok := query.Condition.(*css_ast.MediaOr); ok {
+			return appendMediaTokens(tokens, mediaConditionToTokens(query.Condition, loc, true))
+		}
+	}
+	return appendMediaTokens(tokens, mediaConditionToTokens(query.Condition, loc, false))
+}
+
+func mediaConditionToTokens(cond css_ast.MediaCondition, loc logger.Loc, isNested bool) []css_ast.Token {
+	switch c := cond.(type) {
+	case *css_ast.MediaNot, *css_ast.MediaAnd, *css_ast.MediaOr:
+		if isNested {
+			children := mediaConditionToTokens(cond, loc, false)
+			return []css_ast.Token{{Kind: css_lexer.TOpenParen, Text: "(", Loc: loc, Children: &children}}
+		}
+		var tokens []css_ast.Token
+		switch c := c.(type) {
+		case *css_ast.MediaNot:
+			tokens = appendMediaIdent(tokens, "not", loc)
+			tokens = appendMediaTokens(tokens, mediaConditionToTokens(c.Inner, loc, true))
+		case *css_ast.MediaAnd:
+			for i, term := range c.Terms {
+				if i > 0 {
+					tokens = appendMediaIdent(tokens, "and", loc)
+				}
+				tokens = appendMediaTokens(tokens, mediaConditionToTokens(term, loc, true))
+			}
+		case *css_ast.MediaOr:
+			for i, term := range c.Terms {
+				if i > 0 {
+					tokens = appendMediaIdent(tokens, "or", loc)
+				}
+				tokens = appendMediaTokens(tokens, mediaConditionToTokens(term, loc, true))
+			}
+		}
+		return tokens
+
+	case *css_ast.MediaFeature:
+		children := []css_ast.Token{{Kind: css_lexer.TIdent, Text: c.Name, Loc: c.NameLoc}}
+		if c.Value != nil {
+			value := trimMediaTokens(c.Value)
+			value[0].Whitespace |= css_ast.WhitespaceBefore
+			children = append(children, css_ast.Token{Kind: css_lexer.TColon, Text: ":", Loc: c.NameLoc})
+			children = append(children, value...)
+		}
+		return []css_ast.Token{{Kind: css_lexer.TOpenParen, Text: "(", Loc: c.ParenLoc, Children: &children}}
+
+	case *css_ast.MediaRange:
+		var children []css_ast.Token
+		if c.Left != nil {
+			children = appendMediaTokens(children, c.Left)
+			children = appendMediaTokens(children, mediaRangeOpToTokens(c.LeftOp, c.ParenLoc))
+		}
+		children = appendMediaIdent(children, c.Name, c.ParenLoc)
+		if c.Right != nil {
+			children = appendMediaTokens(children, mediaRangeOpToTokens(c.RightOp, c.ParenLoc))
+			children = appendMediaTokens(children, c.Right)
+		}
+		return []css_ast.Token{{Kind: css_lexer.TOpenParen, Text: "(", Loc: c.ParenLoc, Children: &children}}
+
+	case *css_ast.MediaGeneral:
+		return []css_ast.Token{c.Token}
+	}
+
+	return nil
+}
+
+func mediaRangeOpToTokens(op css_ast.MediaRangeOp, loc logger.Loc) []css_ast.Token {
+	equals := css_ast.Token{Kind: css_lexer.TDelimEquals, Text: "=", Loc: loc}
+	switch op {
+	case css_ast.MediaRangeLt:
+		return []css_ast.Token{{Kind: css_lexer.TDelim, Text: "<", Loc: loc}}
+	case css_ast.MediaRangeLe:
+		return []css_ast.Token{{Kind: css_lexer.TDelim, Text: "<", Loc: loc}, equals}
+	case css_ast.MediaRangeGt:
+		return []css_ast.Token{{Kind: css_lexer.TDelimGreaterThan, Text: ">", Loc: loc}}
+	case css_ast.MediaRangeGe:
+		return []css_ast.Token{{Kind: css_lexer.TDelimGreaterThan, Text: ">", Loc: loc}, equals}
+	}
+	return []css_ast.Token{equals}
+}
+
+func appendMediaIdent(tokens []css_ast.Token, text string, loc logger.Loc) []css_ast.Token {
+	return appendMediaTokens(tokens, []css_ast.Token{{Kind: css_lexer.TIdent, Text: text, Loc: loc}})
+}
+
+// Each group of tokens is separated from the previous one by whitespace
+func appendMediaTokens(tokens []css_ast.Token, group []css_ast.Token) []css_ast.Token {
+	if len(group) == 0 {
+		return tokens
+	}
+	group = trimMediaTokens(group)
+	if len(tokens) > 0 {
+		group[0].Whitespace |= css_ast.WhitespaceBefore
+	}
+	return append(tokens, group...)
+}
+
+// This substitutes "@custom-media" definitions into "@media" rules and
+// removes the "@custom-media" rules themselves. The rules passed in may be
+// shared with other output files, so any rule that needs to change is cloned
+// instead of being modified in place.
+func SubstituteCustomMedia(rules []css_ast.Rule, definitions map[string]*css_ast.RAtCustomMedia, minifyWhitespace bool) []css_ast.Rule {
+	r := customMediaResolver{
+		definitions:      definitions,
+		resolved:         make(map[string][]css_ast.MediaQuery),
+		visiting:         make(map[string]bool),
+		minifyWhitespace: minifyWhitespace,
+	}
+	rules, _ = r.substituteRules(rules)
+	return rules
+}
+
+type customMediaResolver struct {
+	definitions      map[string]*css_ast.RAtCustomMedia
+	resolved         map[string][]css_ast.MediaQuery
+	visiting         map[string]bool
+	minifyWhitespace bool
+}
+
+func (r *customMediaResolver) substituteRules(rules []css_ast.Rule) ([]css_ast.Rule, bool) {
+	var result []css_ast.Rule
+	didChange := false
+
+	for i, rule := range rules {
+		switch data := rule.Data.(type) {
+		case *css_ast.RAtCustomMedia:
+			if !didChange {
+				result = append(make([]css_ast.Rule, 0, len(rules)), rules[:i]...)
+				didChange = true
+			}
+			continue
+
+		case *css_ast.RKnownAt:
+			prelude, preludeChanged := data.Prelude, false
+			if strings.EqualFold(data.AtToken, "media") {
+				prelude, preludeChanged = r.substitutePrelude(data.Prelude)
+			}
+			childRules, childrenChanged := r.substituteRules(data.Rules)
+			if preludeChanged || childrenChanged {
+				clone := *data
+				clone.Prelude = prelude
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RSelector:
+			if childRules, ok := r.substituteRules(data.Rules); ok {
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RQualified:
+			if childRules, ok := r.substituteRules(data.Rules); ok {
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RAtLayer:
+			if childRules, ok := r.substituteRules(data.Rules); ok {
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RAtScope:
+			if childRules, ok := r.substituteRules(data.Rules); ok {
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+		}
+
+		if rule.Data != rules[i].Data && !didChange {
+			result = append(make([]css_ast.Rule, 0, len(rules)), rules[:i]...)
+			didChange = true
+		}
+		if didChange {
+			result = append(result, rule)
+		}
+	}
+
+	if !didChange {
+		return rules, false
+	}
+	return result, true
+}
+
+func (r *customMediaResolver) substitutePrelude(tokens []css_ast.Token) ([]css_ast.Token, bool) {
+	if !tokensReferenceCustomMedia(tokens) {
+		return tokens, false
+	}
+	queries, ok := parseMediaQueryList(tokens)
+	if !ok {
+		return tokens, false
+	}
+	queries, ok = r.substituteQueries(queries)
+	if !ok {
+		return tokens, false
+	}
+	return mediaQueryListToTokens(queries, tokens[0].Loc, r.minifyWhitespace), true
+}
+
+func (r *customMediaResolver) substituteQueries(queries []parsedMediaQuery) ([]parsedMediaQuery, bool) {
+	var result []parsedMediaQuery
+	didChange := false
+
+	for _, q := range queries {
+		// A query that is only a reference is replaced by the whole definition
+		if q.query.Modifier == css_ast.MediaModifierNone && q.query.Type == "" {
+			if name, ok := isCustomMediaReference(q.query.Condition); ok {
+				if definition, ok := r.resolve(name); ok {
+					for _, query := range definition {
+						result = append(result, parsedMediaQuery{query: query})
+					}
+					didChange = true
+					continue
+				}
+			}
+		}
+
+		// Otherwise, try to substitute references within the condition
+		if q.query.Condition != nil {
+			if cond, ok := r.substituteCondition(q.query.Condition); ok {
+				q.query.Condition = cond
+				q.tokens = nil
+				didChange = true
+			}
+		}
+		result = append(result, q)
+	}
+
+	return result, didChange
+}
+
+func (r *customMediaResolver) substituteCondition(cond css_ast.MediaCondition) (css_ast.MediaCondition, bool) {
+	switch c := cond.(type) {
+	case *css_ast.MediaNot:
+		if inner, ok := r.substituteCondition(c.Inner); ok {
+			return &css_ast.MediaNot{Inner: inner}, true
+		}
+
+	case *css_ast.MediaAnd:
+		var terms []css_ast.MediaCondition
+		didChange := false
+		for _, term := range c.Terms {
+			if substituted, ok := r.substituteCondition(term); ok {
+				didChange = true
+				if and, ok := substituted.(*css_ast.MediaAnd); ok {
+					terms = append(terms, and.Terms...)
+					continue
+				}
+				term = substituted
+			}
+			terms = append(terms, term)
+		}
+		if didChange {
+			return &css_ast.MediaAnd{Terms: terms}, true
+		}
+
+	case *css_ast.MediaOr:
+		var terms []css_ast.MediaCondition
+		didChange := false
+		for _, term := range c.Terms {
+			if substituted, ok := r.substituteCondition(term); ok {
+				didChange = true
+				if or, ok := substituted.(*css_ast.MediaOr); ok {
+					terms = append(terms, or.Terms...)
+					continue
+				}
+				term = substituted
+			}
+			terms = append(terms, term)
+		}
+		if didChange {
+			return &css_ast.MediaOr{Terms: terms}, true
+		}
+
+	case *css_ast.MediaFeature:
+		// A reference inside a larger condition can only be substituted if the
+		// definition is a single condition without a media type
+		if name, ok := isCustomMediaReference(c); ok {
+			if definition, ok := r.resolve(name); ok && len(definition) == 1 &&
+				definition[0].Modifier == css_ast.MediaModifierNone && definition[0].Type == "" && definition[0].Condition != nil {
+				return definition[0].Condition, true
+			}
+		}
+	}
+
+	return cond, false
+}
+
+func (r *customMediaResolver) resolve(name string) ([]css_ast.MediaQuery, bool) {
+	if queries, ok := r.resolved[name]; ok {
+		return queries, queries != nil
+	}
+	definition, ok := r.definitions[name]
+	if !ok || r.visiting[name] {
+		// Leave undefined and cyclic references alone
+		return nil, false
+	}
+	r.visiting[name] = true
+	defer delete(r.visiting, name)
+
+	var queries []css_ast.MediaQuery
+	if len(definition.Prelude) == 1 && definition.Prelude[0].Kind == css_lexer.TIdent {
+		// "@custom-media --name true;" and "@custom-media --name false;"
+		switch strings.ToLower(definition.Prelude[0].Text) {
+		case "true":
+			queries = []css_ast.MediaQuery{{Type: "all"}}
+		case "false":
+			queries = []css_ast.MediaQuery{{Modifier: css_ast.MediaModifierNot, Type: "all"}}
+		}
+	}
+	if queries == nil {
+		if parsed, ok := parseMediaQueryList(definition.Prelude); ok {
+			parsed, _ = r.substituteQueries(parsed)
+			for _, q := range parsed {
+				queries = append(queries, q.query)
+			}
+		}
+	}
+
+	r.resolved[name] = queries
+	return queries, queries != nil
+}
+
+func tokensReferenceCustomMedia(tokens []css_ast.Token) bool {
+	for _, t := range tokens {
+		if t.Kind == css_lexer.TOpenParen {
+			children := *t.Children
+			if len(children) == 1 && children[0].Kind == css_lexer.TIdent && strings.HasPrefix(children[0].Text, "--") {
+				return true
+			}
+			if tokensReferenceCustomMedia(children) {
+				return true
+			}
+		}
+	}
+	return false
+}
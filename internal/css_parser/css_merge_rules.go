@@ -0,0 +1,509 @@
+package css_parser
+
+import (
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_lexer"
+)
+
+type MergeRulesOptions struct {
+	Symbols                ast.SymbolMap
+	UnsupportedCSSFeatures compat.CSSFeature
+	MinifyWhitespace       bool
+
+	// Rules from different files can only be merged if this is true. Moving
+	// declarations from one file into a rule from another file would otherwise
+	// mess up the source map for that file.
+	AllowMergingAcrossFiles bool
+}
+
+// This merges style rules once all files in an output file have been joined
+// together, which the parser can't do because it only sees one file at a time.
+// Style rules with the same selectors are merged together:
+//
+//	a { color: red }
+//	b { margin: 0 }
+//	a { padding: 0 }
+//
+// becomes:
+//
+//	a { color: red; padding: 0 }
+//	b { margin: 0 }
+//
+// This is only done if none of the rules in between set a property that
+// could interact with the moved declarations, since moving a declaration
+// earlier changes the cascade order. Adjacent style rules with the same
+// declarations also have their selectors merged together. Longhand properties
+// in merged rules are then compacted into shorthand properties if possible.
+//
+// The files must be passed in the order they appear in the output. The ASTs
+// are modified in place but the rules they point to are cloned before being
+// modified, since they may be shared with other output files.
+func MergeRules(asts []css_ast.AST, options MergeRulesOptions) {
+	var slots []mergeSlot
+	for i := range asts {
+		rules := append([]css_ast.Rule{}, asts[i].Rules...)
+		for j := range rules {
+			mergeRulesInBlock(&rules[j], options)
+			slots = append(slots, mergeSlot{rule: &rules[j], file: i})
+		}
+		asts[i].Rules = rules
+	}
+
+	if mergeRulesInSlots(slots, options) {
+		for i := range asts {
+			asts[i].Rules = removeEmptySlots(asts[i].Rules)
+		}
+	}
+}
+
+type mergeSlot struct {
+	rule *css_ast.Rule
+	file int
+}
+
+// Rules nested inside blocks such as "@media" are only merged with rules
+// inside the same block
+func mergeRulesInBlock(rule *css_ast.Rule, options MergeRulesOptions) {
+	switch r := rule.Data.(type) {
+	case *css_ast.RKnownAt:
+		if rules, ok := mergeRulesInList(r.Rules, options); ok {
+			clone := *r
+			clone.Rules = rules
+			rule.Data = &clone
+		}
+
+	case *css_ast.RAtLayer:
+		if rules, ok := mergeRulesInList(r.Rules, options); ok {
+			clone := *r
+			clone.Rules = rules
+			rule.Data = &clone
+		}
+	}
+}
+
+func mergeRulesInList(rules []css_ast.Rule, options MergeRulesOptions) ([]css_ast.Rule, bool) {
+	if len(rules) == 0 {
+		return nil, false
+	}
+	clone := append([]css_ast.Rule{}, rules...)
+	slots := make([]mergeSlot, len(clone))
+	didChange := false
+	for i := range clone {
+		mergeRulesInBlock(&clone[i], options)
+		if clone[i].Data != rules[i].Data {
+			didChange = true
+		}
+		slots[i] = mergeSlot{rule: &clone[i]}
+	}
+	if mergeRulesInSlots(slots, options) {
+		return removeEmptySlots(clone), true
+	}
+	return clone, didChange
+}
+
+func mergeRulesInSlots(slots []mergeSlot, options MergeRulesOptions) bool {
+	bySelectors := make(map[uint32][]int)
+	prev := -1
+	didMerge := false
+
+	for j, slot := range slots {
+		switch r := slot.rule.Data.(type) {
+		case *css_ast.RComment:
+			// Comments don't affect the cascade
+			continue
+
+		case *css_ast.RSelector:
+			if !ruleOnlyHasDeclarations(r) {
+				break
+			}
+			hash := css_ast.HashComplexSelectors(0, r.Selectors)
+
+			// Try to merge this rule into an earlier rule with the same selectors
+			if k, ok := findRuleToMergeInto(slots, bySelectors[hash], j, options); ok {
+				target := slots[k].rule.Data.(*css_ast.RSelector)
+				clone := *target
+				clone.Rules = compactMergedDeclarations(append(append([]css_ast.Rule{}, target.Rules...), r.Rules...), options)
+				slots[k].rule.Data = &clone
+				*slot.rule = css_ast.Rule{}
+				didMerge = true
+				continue
+			}
+
+			// Try to merge this rule into the previous rule if the declarations match
+			if prev != -1 {
+				if target, ok := slots[prev].rule.Data.(*css_ast.RSelector); ok && ruleOnlyHasDeclarations(target) &&
+					css_ast.RulesEqual(target.Rules, r.Rules, nil) && isSafeSelectors(target.Selectors) && isSafeSelectors(r.Selectors) &&
+					canMoveAcrossFiles(slots[prev], slot, r, options) {
+					clone := *target
+					clone.Selectors = append([]css_ast.ComplexSelector{}, target.Selectors...)
+					for _, sel := range r.Selectors {
+						if !containsComplexSelector(clone.Selectors, sel, options) {
+							clone.Selectors = append(clone.Selectors, sel)
+						}
+					}
+					slots[prev].rule.Data = &clone
+					*slot.rule = css_ast.Rule{}
+					didMerge = true
+					newHash := css_ast.HashComplexSelectors(0, clone.Selectors)
+					bySelectors[newHash] = append(bySelectors[newHash], prev)
+					continue
+				}
+			}
+
+			bySelectors[hash] = append(bySelectors[hash], j)
+			prev = j
+			continue
+		}
+
+		// Nothing can be merged across any other kind of rule
+		bySelectors = make(map[uint32][]int)
+		prev = -1
+	}
+
+	return didMerge
+}
+
+func findRuleToMergeInto(slots []mergeSlot, candidates []int, j int, options MergeRulesOptions) (int, bool) {
+	source := slots[j].rule.Data.(*css_ast.RSelector)
+
+	for i := len(candidates) - 1; i >= 0; i-- {
+		k := candidates[i]
+		target, ok := slots[k].rule.Data.(*css_ast.RSelector)
+		if !ok || !complexSelectorsEqual(target.Selectors, source.Selectors, options) {
+			continue
+		}
+		if !canMoveAcrossFiles(slots[k], slots[j], source, options) {
+			return 0, false
+		}
+
+		// The declarations are moved earlier, so they must not interact with
+		// anything that was declared by the rules in between
+		for _, between := range slots[k+1 : j] {
+			switch r := between.rule.Data.(type) {
+			case nil, *css_ast.RComment:
+			case *css_ast.RSelector:
+				if !ruleOnlyHasDeclarations(r) || declarationsMayInteract(r.Rules, source.Rules) {
+					return 0, false
+				}
+			default:
+				return 0, false
+			}
+		}
+		return k, true
+	}
+
+	return 0, false
+}
+
+func canMoveAcrossFiles(target mergeSlot, source mergeSlot, r *css_ast.RSelector, options MergeRulesOptions) bool {
+	if target.file == source.file {
+		return true
+	}
+
+	// Symbols and import records are stored per file, so tokens that refer to
+	// them can't be moved into another file
+	if !options.AllowMergingAcrossFiles || !complexSelectorsAreFileIndependent(r.Selectors) {
+		return false
+	}
+	for _, rule := range r.Rules {
+		if !tokensAreFileIndependent(rule.Data.(*css_ast.RDeclaration).Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func ruleOnlyHasDeclarations(r *css_ast.RSelector) bool {
+	for _, rule := range r.Rules {
+		if _, ok := rule.Data.(*css_ast.RDeclaration); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func containsComplexSelector(selectors []css_ast.ComplexSelector, sel css_ast.ComplexSelector, options MergeRulesOptions) bool {
+	for _, other := range selectors {
+		if complexSelectorsEqual([]css_ast.ComplexSelector{other}, []css_ast.ComplexSelector{sel}, options) {
+			return true
+		}
+	}
+	return false
+}
+
+// Global names in different files use different symbols, so they must be
+// compared using the symbol map. This is only done for selectors that can
+// be moved across files since the equality check doesn't have the import
+// records or the source indices needed to compare anything else.
+func complexSelectorsEqual(a []css_ast.ComplexSelector, b []css_ast.ComplexSelector, options MergeRulesOptions) bool {
+	var check *css_ast.CrossFileEqualityCheck
+	if complexSelectorsAreFileIndependent(a) && complexSelectorsAreFileIndependent(b) {
+		check = &css_ast.CrossFileEqualityCheck{Symbols: options.Symbols}
+	}
+	return css_ast.ComplexSelectorsEqual(a, b, check)
+}
+
+func complexSelectorsAreFileIndependent(selectors []css_ast.ComplexSelector) bool {
+	for _, complex := range selectors {
+		for _, compound := range complex.Selectors {
+			for _, ss := range compound.SubclassSelectors {
+				switch s := ss.Data.(type) {
+				case *css_ast.SSPseudoClass:
+					if !tokensAreFileIndependent(s.Args) {
+						return false
+					}
+				case *css_ast.SSPseudoClassWithSelectorList:
+					if !complexSelectorsAreFileIndependent(s.Selectors) {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+func tokensAreFileIndependent(tokens []css_ast.Token) bool {
+	for _, t := range tokens {
+		if t.Kind == css_lexer.TURL || t.Kind == css_lexer.TSymbol {
+			return false
+		}
+		if t.Children != nil && !tokensAreFileIndependent(*t.Children) {
+			return false
+		}
+	}
+	return true
+}
+
+// This is intentionally conservative. Properties interact if they belong to
+// the same family, which is determined by the first word of the property name
+// without any vendor prefix. This covers shorthand properties and their
+// longhands (e.g. "margin" and "margin-top") as well as logical properties
+// and their physical equivalents (e.g. "margin-inline-start" and
+// "margin-left"). Some families are combined below for properties that
+// interact despite having different names.
+func declarationsMayInteract(a []css_ast.Rule, b []css_ast.Rule) bool {
+	for _, ra := range a {
+		declA := ra.Data.(*css_ast.RDeclaration)
+		for _, rb := range b {
+			declB := rb.Data.(*css_ast.RDeclaration)
+			if propertiesMayInteract(declA.KeyText, declB.KeyText) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func propertiesMayInteract(a string, b string) bool {
+	a = strings.ToLower(a)
+	b = strings.ToLower(b)
+
+	// Custom properties only interact with themselves
+	if strings.HasPrefix(a, "--") || strings.HasPrefix(b, "--") {
+		return a == b
+	}
+
+	// The "all" property interacts with everything
+	if a == "all" || b == "all" {
+		return true
+	}
+
+	return propertyFamily(a) == propertyFamily(b)
+}
+
+var combinedPropertyFamilies = map[string]string{
+	// "inset" and the physical and logical offsets
+	"bottom": "inset",
+	"left":   "inset",
+	"right":  "inset",
+	"top":    "inset",
+
+	// Physical and logical sizes
+	"block":  "width",
+	"height": "width",
+	"inline": "width",
+	"max":    "width",
+	"min":    "width",
+
+	// "font" resets "line-height"
+	"line": "font",
+
+	// Alignment shorthands
+	"align":   "place",
+	"justify": "place",
+
+	// Multi-column layout
+	"columns": "column",
+
+	// "gap" was originally called "grid-gap"
+	"gap": "grid",
+	"row": "grid",
+
+	// "page-break-*" is an alias for "break-*"
+	"page": "break",
+
+	// "word-wrap" is an alias for "overflow-wrap"
+	"word": "overflow",
+
+	// "white-space" is a shorthand for "text-wrap-mode"
+	"white": "text",
+}
+
+func propertyFamily(key string) string {
+	// Remove a vendor prefix such as "-webkit-"
+	if strings.HasPrefix(key, "-") {
+		if i := strings.IndexByte(key[1:], '-'); i != -1 {
+			key = key[i+2:]
+		}
+	}
+
+	if i := strings.IndexByte(key, '-'); i != -1 {
+		key = key[:i]
+	}
+	if family, ok := combinedPropertyFamilies[key]; ok {
+		return family
+	}
+	return key
+}
+
+// This removes declarations that are overridden by a later declaration and
+// compacts longhand properties into shorthand properties. It's similar to
+// what the parser does for a single rule, but is needed again here because
+// the declarations may have come from different rules.
+func compactMergedDeclarations(rules []css_ast.Rule, options MergeRulesOptions) []css_ast.Rule {
+	margin := boxTracker{key: css_ast.DMargin, keyText: "margin", allowAuto: true}
+	padding := boxTracker{key: css_ast.DPadding, keyText: "padding", allowAuto: false}
+	inset := boxTracker{key: css_ast.DInset, keyText: "inset", allowAuto: true}
+	borderRadius := borderRadiusTracker{}
+	rewrittenRules := make([]css_ast.Rule, 0, len(rules))
+
+	// Don't automatically generate the "inset" property if it's not supported
+	if options.UnsupportedCSSFeatures.Has(compat.InsetProperty) {
+		inset.key = css_ast.DUnknown
+		inset.keyText = ""
+	}
+
+	for i, rule := range rules {
+		decl := rule.Data.(*css_ast.RDeclaration)
+
+		// Removing the earlier of two identical declarations doesn't change
+		// anything. The same is true if the later declaration sets the same
+		// property to a value that every browser understands.
+		isOverridden := false
+		for _, later := range rules[i+1:] {
+			if decl.Equal(later.Data, nil) || declarationOverrides(later.Data.(*css_ast.RDeclaration), decl) {
+				isOverridden = true
+				break
+			}
+		}
+		if isOverridden {
+			continue
+		}
+
+		// The trackers below may modify the declaration, so it must be cloned
+		clone := *decl
+		clone.Value = append([]css_ast.Token{}, decl.Value...)
+		decl = &clone
+		rewrittenRules = append(rewrittenRules, css_ast.Rule{Loc: rule.Loc, Data: decl})
+
+		switch decl.Key {
+		case css_ast.DMargin:
+			margin.mangleSides(rewrittenRules, decl, options.MinifyWhitespace)
+		case css_ast.DMarginTop:
+			margin.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxTop)
+		case css_ast.DMarginRight:
+			margin.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxRight)
+		case css_ast.DMarginBottom:
+			margin.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxBottom)
+		case css_ast.DMarginLeft:
+			margin.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxLeft)
+
+		case css_ast.DPadding:
+			padding.mangleSides(rewrittenRules, decl, options.MinifyWhitespace)
+		case css_ast.DPaddingTop:
+			padding.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxTop)
+		case css_ast.DPaddingRight:
+			padding.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxRight)
+		case css_ast.DPaddingBottom:
+			padding.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxBottom)
+		case css_ast.DPaddingLeft:
+			padding.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxLeft)
+
+		case css_ast.DInset:
+			inset.mangleSides(rewrittenRules, decl, options.MinifyWhitespace)
+		case css_ast.DTop:
+			inset.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxTop)
+		case css_ast.DRight:
+			inset.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxRight)
+		case css_ast.DBottom:
+			inset.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxBottom)
+		case css_ast.DLeft:
+			inset.mangleSide(rewrittenRules, decl, options.MinifyWhitespace, boxLeft)
+
+		case css_ast.DBorderRadius:
+			borderRadius.mangleCorners(rewrittenRules, decl, options.MinifyWhitespace)
+		case css_ast.DBorderTopLeftRadius:
+			borderRadius.mangleCorner(rewrittenRules, decl, options.MinifyWhitespace, borderRadiusTopLeft)
+		case css_ast.DBorderTopRightRadius:
+			borderRadius.mangleCorner(rewrittenRules, decl, options.MinifyWhitespace, borderRadiusTopRight)
+		case css_ast.DBorderBottomRightRadius:
+			borderRadius.mangleCorner(rewrittenRules, decl, options.MinifyWhitespace, borderRadiusBottomRight)
+		case css_ast.DBorderBottomLeftRadius:
+			borderRadius.mangleCorner(rewrittenRules, decl, options.MinifyWhitespace, borderRadiusBottomLeft)
+		}
+	}
+
+	return removeEmptySlots(rewrittenRules)
+}
+
+// Declarations are often repeated on purpose as a fallback for browsers that
+// don't support a newer value (e.g. "color: red; color: lab(50% 20 30)"). So
+// a later declaration only overrides an earlier one if its value is known to
+// be supported everywhere. This is currently only done for simple colors.
+func declarationOverrides(later *css_ast.RDeclaration, earlier *css_ast.RDeclaration) bool {
+	if later.Key != earlier.Key || later.Key == css_ast.DUnknown || (earlier.Important && !later.Important) || len(later.Value) != 1 {
+		return false
+	}
+
+	switch later.Key {
+	case css_ast.DBackgroundColor,
+		css_ast.DBorderBottomColor,
+		css_ast.DBorderColor,
+		css_ast.DBorderLeftColor,
+		css_ast.DBorderRightColor,
+		css_ast.DBorderTopColor,
+		css_ast.DColor,
+		css_ast.DOutlineColor:
+
+		switch token := later.Value[0]; token.Kind {
+		case css_lexer.THash:
+			// Only "#rgb" and "#rrggbb" since "#rgba" and "#rrggbbaa" are newer
+			if len(token.Text) == 3 || len(token.Text) == 6 {
+				_, ok := parseHex(token.Text)
+				return ok
+			}
+
+		case css_lexer.TIdent:
+			_, ok := colorNameToHex[strings.ToLower(token.Text)]
+			return ok
+		}
+	}
+
+	return false
+}
+
+func removeEmptySlots(rules []css_ast.Rule) []css_ast.Rule {
+	end := 0
+	for _, rule := range rules {
+		if rule.Data != nil {
+			rules[end] = rule
+			end++
+		}
+	}
+	return rules[:end]
+}
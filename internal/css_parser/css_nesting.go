@@ -83,6 +83,13 @@ func (p *parser) lowerNestingInRule(rule css_ast.Rule, results []css_ast.Rule) [
 			rules = p.lowerNestingInRule(child, rules)
 		}
 		r.Rules = rules
+
+	case *css_ast.RAtScope:
+		var rules []css_ast.Rule
+		for _, child := range r.Rules {
+			rules = p.lowerNestingInRule(child, rules)
+		}
+		r.Rules = rules
 	}
 
 	return append(results, rule)
@@ -310,11 +317,60 @@ func (p *parser) lowerNestingInRuleWithContext(rule css_ast.Rule, context *lower
 		r.Rules = childContext.loweredRules
 		context.loweredRules = append(context.loweredRules, rule)
 		return css_ast.Rule{}
+
+	case *css_ast.RAtScope:
+		// "div { @scope (& > .a) {} }" => "@scope (div > .a) {}"
+		// "div { @scope { color: red } }" => "@scope (div) { :scope { color: red } }"
+		if r.Start == nil {
+			r.Start = context.parentSelectors
+		} else {
+			r.Start = p.substituteAmpersandsInScopeSelectors(r.Start, context.parentSelectors)
+		}
+		r.End = p.substituteAmpersandsInScopeSelectors(r.End, context.parentSelectors)
+
+		// Inside the scope, "&" refers to the scope root instead of the parent
+		scope := []css_ast.ComplexSelector{{
+			Selectors: []css_ast.CompoundSelector{{
+				SubclassSelectors: []css_ast.SubclassSelector{{
+					Range: logger.Range{Loc: rule.Loc},
+					Data:  &css_ast.SSPseudoClass{Name: "scope"},
+				}},
+			}},
+		}}
+		childContext := lowerNestingContext{parentSelectors: scope}
+		r.Rules = p.lowerNestingInRulesAndReturnRemaining(r.Rules, &childContext)
+		if len(r.Rules) > 0 {
+			childContext.loweredRules = append([]css_ast.Rule{{Loc: rule.Loc, Data: &css_ast.RSelector{
+				Selectors: scope,
+				Rules:     r.Rules,
+			}}}, childContext.loweredRules...)
+		}
+		if len(childContext.loweredRules) > 0 {
+			r.Rules = childContext.loweredRules
+			context.loweredRules = append(context.loweredRules, rule)
+		}
+		return css_ast.Rule{}
 	}
 
 	return rule
 }
 
+func (p *parser) substituteAmpersandsInScopeSelectors(selectors []css_ast.ComplexSelector, parentSelectors []css_ast.ComplexSelector) []css_ast.ComplexSelector {
+	if selectors == nil {
+		return nil
+	}
+	replacementFn := p.multipleComplexSelectorsToSingleComplexSelector(parentSelectors)
+	result := make([]css_ast.ComplexSelector, 0, len(selectors))
+	for _, sel := range selectors {
+		substituted := make([]css_ast.CompoundSelector, 0, len(sel.Selectors))
+		for _, x := range sel.Selectors {
+			substituted = p.substituteAmpersandsInCompoundSelector(x, replacementFn, substituted, keepLeadingCombinator)
+		}
+		result = append(result, css_ast.ComplexSelector{Selectors: substituted})
+	}
+	return result
+}
+
 type leadingCombinatorStrip uint8
 
 const (
@@ -328,6 +384,7 @@ func (p *parser) substituteAmpersandsInCompoundSelector(
 	results []css_ast.CompoundSelector,
 	strip leadingCombinatorStrip,
 ) []css_ast.CompoundSelector {
+	var remainingNestingSelectorLocs []logger.Loc
 	for _, nestingSelectorLoc := range sel.NestingSelectorLocs {
 		replacement := replacementFn(nestingSelectorLoc)
 
@@ -384,12 +441,15 @@ func (p *parser) substituteAmpersandsInCompoundSelector(
 		// Insert the subclass selectors
 		subclassSelectorPrefix = append(subclassSelectorPrefix, single.SubclassSelectors...)
 
+		// Keep any "&" in the replacement (only happens when lowering "@scope")
+		remainingNestingSelectorLocs = append(remainingNestingSelectorLocs, single.NestingSelectorLocs...)
+
 		// Write the changes back
 		if len(subclassSelectorPrefix) > 0 {
 			sel.SubclassSelectors = append(subclassSelectorPrefix, sel.SubclassSelectors...)
 		}
 	}
-	sel.NestingSelectorLocs = nil
+	sel.NestingSelectorLocs = remainingNestingSelectorLocs
 
 	// "div { :is(&.foo) {} }" => ":is(div.foo) {}"
 	for _, ss := range sel.SubclassSelectors {
@@ -25,6 +25,9 @@ type parser struct {
 	importRecords     []ast.ImportRecord
 	symbols           []ast.Symbol
 	composes          map[ast.Ref]*css_ast.Composes
+	values            []css_ast.ICSSValue
+	icssValues        map[string]int
+	importedValues    map[string]css_ast.ImportedICSSValue
 	localSymbols      []ast.LocRef
 	localScope        map[string]ast.LocRef
 	globalScope       map[string]ast.LocRef
@@ -41,12 +44,14 @@ type parser struct {
 	prevError         logger.Loc
 	options           Options
 	nestingIsPresent  bool
+	scopeIsPresent    bool
 	makeLocalSymbols  bool
 	hasSeenAtImport   bool
 }
 
 type Options struct {
-	cssPrefixData map[css_ast.D]compat.CSSPrefix
+	cssPrefixData       map[css_ast.D]compat.CSSPrefix
+	cssSyntaxPrefixData map[compat.CSSPrefixedSyntax]compat.CSSPrefix
 
 	// This is an embedded struct. Always access these directly instead of off
 	// the name "optionsThatSupportStructuralEquality". This is only grouped like
@@ -81,7 +86,8 @@ func OptionsFromConfig(loader config.Loader, options *config.Options) Options {
 	}
 
 	return Options{
-		cssPrefixData: options.CSSPrefixData,
+		cssPrefixData:       options.CSSPrefixData,
+		cssSyntaxPrefixData: options.CSSSyntaxPrefixData,
 
 		optionsThatSupportStructuralEquality: optionsThatSupportStructuralEquality{
 			minifySyntax:           options.MinifySyntax,
@@ -116,6 +122,16 @@ func (a *Options) Equal(b *Options) bool {
 		}
 	}
 
+	// Compare "cssSyntaxPrefixData"
+	if len(a.cssSyntaxPrefixData) != len(b.cssSyntaxPrefixData) {
+		return false
+	}
+	for k, va := range a.cssSyntaxPrefixData {
+		if vb, ok := b.cssSyntaxPrefixData[k]; !ok || va != vb {
+			return false
+		}
+	}
+
 	return true
 }
 
@@ -142,6 +158,12 @@ func Parse(log logger.Log, source logger.Source, options Options) css_ast.AST {
 		parseSelectors: true,
 	})
 	p.expect(css_lexer.TEndOfFile)
+
+	// Substitute "@value" names that were defined in this file
+	if len(p.values) > 0 {
+		rules, _ = SubstituteICSSValues(rules, p.lookupLocalICSSValue)
+	}
+
 	return css_ast.AST{
 		Rules:                rules,
 		CharFreq:             p.computeCharacterFrequency(),
@@ -153,6 +175,8 @@ func Parse(log logger.Log, source logger.Source, options Options) css_ast.AST {
 		LocalScope:           p.localScope,
 		GlobalScope:          p.globalScope,
 		Composes:             p.composes,
+		Values:               p.values,
+		ImportedValues:       p.importedValues,
 		LayersPreImport:      p.layersPreImport,
 		LayersPostImport:     p.layersPostImport,
 	}
@@ -406,6 +430,12 @@ loop:
 			continue
 
 		case css_lexer.TAtKeyword:
+			// ICSS "@value" rules are removed from the output
+			if context.isTopLevel && p.options.symbolMode != symbolModeDisabled &&
+				strings.EqualFold(p.decoded(), "value") && p.parseAtValue() {
+				continue
+			}
+
 			rule := p.parseAtRule(atRuleContext)
 
 			// Disallow "@charset" and "@import" after other rules
@@ -461,6 +491,11 @@ loop:
 			}
 		}
 
+		// ICSS ":export" blocks are removed from the output
+		if context.isTopLevel && p.options.symbolMode != symbolModeDisabled && p.parseICSSExport() {
+			continue
+		}
+
 		if atRuleContext.importValidity == atRuleValid {
 			atRuleContext.afterLoc = p.current().Range.Loc
 			atRuleContext.charsetValidity = atRuleInvalidAfter
@@ -494,6 +529,14 @@ loop:
 		}
 	}
 
+	// Lower "@scope" rules if they're not supported (but only at the top level)
+	if p.scopeIsPresent && p.options.unsupportedCSSFeatures.Has(compat.AtScope) && context.isTopLevel {
+		rules = p.lowerAtScopeInRules(rules)
+	}
+
+	if p.options.cssSyntaxPrefixData != nil {
+		rules = p.insertPrefixedRules(rules)
+	}
 	if p.options.minifySyntax {
 		rules = p.mangleRules(rules, context.isTopLevel)
 	}
@@ -516,6 +559,9 @@ func (p *parser) parseListOfDeclarations(opts listOfDeclarationsOpts) (list []cs
 
 		case css_lexer.TEndOfFile, css_lexer.TCloseBrace:
 			list = p.processDeclarations(list, opts.composesContext)
+			if foundNesting && p.options.cssSyntaxPrefixData != nil {
+				list = p.insertPrefixedRules(list)
+			}
 			if p.options.minifySyntax {
 				list = p.mangleRules(list, false /* isTopLevel */)
 
@@ -662,6 +708,11 @@ next:
 				}
 			}
 
+		case *css_ast.RAtScope:
+			if len(r.Rules) == 0 {
+				continue
+			}
+
 		case *css_ast.RSelector:
 			if len(r.Rules) == 0 {
 				continue
@@ -1256,6 +1307,9 @@ abortRuleParser:
 					if n := len(conditions.Media); n > 0 {
 						conditions.Media[0].Whitespace &= ^css_ast.WhitespaceBefore
 						conditions.Media[n-1].Whitespace &= ^css_ast.WhitespaceAfter
+						if p.options.unsupportedCSSFeatures.Has(compat.MediaRange) {
+							conditions.Media = p.lowerMediaQueryRanges(conditions.Media)
+						}
 					}
 				}
 
@@ -1281,6 +1335,86 @@ abortRuleParser:
 			}
 		}
 
+	case "custom-media":
+		// Parse "@custom-media --name <media-query-list>;"
+		p.eat(css_lexer.TWhitespace)
+		nameLoc := p.current().Range.Loc
+		name := p.decoded()
+		if !p.peek(css_lexer.TIdent) || !strings.HasPrefix(name, "--") {
+			break
+		}
+		p.advance()
+		queriesStart := p.index
+		for {
+			if kind := p.current().Kind; kind == css_lexer.TSemicolon || kind == css_lexer.TOpenBrace ||
+				kind == css_lexer.TCloseBrace || kind == css_lexer.TEndOfFile {
+				break
+			}
+			p.parseComponentValue()
+		}
+		if !p.peek(css_lexer.TSemicolon) {
+			break // Avoid parsing an invalid "@custom-media" rule
+		}
+		queries := p.convertTokens(p.tokens[queriesStart:p.index])
+		if len(queries) == 0 {
+			break
+		}
+		p.advance()
+		if p.options.unsupportedCSSFeatures.Has(compat.MediaRange) {
+			queries = p.lowerMediaQueryRanges(queries)
+		}
+		return css_ast.Rule{Loc: atRange.Loc, Data: &css_ast.RAtCustomMedia{Name: name, NameLoc: nameLoc, Prelude: queries}}
+
+	case "scope":
+		// Reference: https://drafts.csswg.org/css-cascade-6/#scoped-styles
+
+		// Parse the optional scope root "(<scope-start>)"
+		var start []css_ast.ComplexSelector
+		p.eat(css_lexer.TWhitespace)
+		if p.peek(css_lexer.TOpenParen) {
+			list, ok := p.parseScopeSelectorList()
+			if !ok {
+				break
+			}
+			start = list
+			p.eat(css_lexer.TWhitespace)
+		}
+
+		// Parse the optional scope limit "to (<scope-end>)"
+		var end []css_ast.ComplexSelector
+		if p.peek(css_lexer.TIdent) && strings.EqualFold(p.decoded(), "to") {
+			p.advance()
+			p.eat(css_lexer.TWhitespace)
+			list, ok := p.parseScopeSelectorList()
+			if !ok {
+				break
+			}
+			end = list
+			p.eat(css_lexer.TWhitespace)
+		}
+
+		// Parse the block using the current context, just like "@media"
+		matchingLoc := p.current().Range.Loc
+		if !p.eat(css_lexer.TOpenBrace) {
+			break
+		}
+		p.scopeIsPresent = true
+		var rules []css_ast.Rule
+		if context.isDeclarationList {
+			rules = p.parseListOfDeclarations(listOfDeclarationsOpts{
+				canInlineNoOpNesting: context.canInlineNoOpNesting,
+			})
+		} else {
+			rules = p.parseListOfRules(ruleContext{
+				parseSelectors: true,
+			})
+		}
+		closeBraceLoc := p.current().Range.Loc
+		if !p.expectWithMatchingLoc(css_lexer.TCloseBrace, matchingLoc) {
+			closeBraceLoc = logger.Loc{}
+		}
+		return css_ast.Rule{Loc: atRange.Loc, Data: &css_ast.RAtScope{Start: start, End: end, Rules: rules, CloseBraceLoc: closeBraceLoc}}
+
 	case "keyframes", "-webkit-keyframes", "-moz-keyframes", "-ms-keyframes", "-o-keyframes":
 		p.eat(css_lexer.TWhitespace)
 		nameLoc := p.current().Range.Loc
@@ -1616,6 +1750,9 @@ prelude:
 		// Push the "@media" conditions
 		isAtMedia := lowerAtToken == "media"
 		if isAtMedia {
+			if p.options.unsupportedCSSFeatures.Has(compat.MediaRange) {
+				prelude = p.lowerMediaQueryRanges(prelude)
+			}
 			p.enclosingAtMedia = append(p.enclosingAtMedia, prelude)
 		}
 
@@ -1688,6 +1825,32 @@ func (p *parser) expectValidLayerNameIdent() (string, bool) {
 	return text, true
 }
 
+// This parses the parenthesized selector list in "@scope (.a) to (.b)"
+func (p *parser) parseScopeSelectorList() ([]css_ast.ComplexSelector, bool) {
+	matchingLoc := p.current().Range.Loc
+	if !p.expect(css_lexer.TOpenParen) {
+		return nil, false
+	}
+	p.eat(css_lexer.TWhitespace)
+
+	// Contain the effects of ":local" and ":global"
+	oldLocal := p.makeLocalSymbols
+	list, ok := p.parseSelectorList(parseSelectorOpts{
+		stopOnCloseParen:    true,
+		noLeadingCombinator: true,
+	})
+	p.makeLocalSymbols = oldLocal
+	if !ok {
+		return nil, false
+	}
+
+	p.eat(css_lexer.TWhitespace)
+	if !p.expectWithMatchingLoc(css_lexer.TCloseParen, matchingLoc) {
+		return nil, false
+	}
+	return list, true
+}
+
 func (p *parser) convertTokens(tokens []css_lexer.Token) []css_ast.Token {
 	result, _ := p.convertTokensHelper(tokens, css_lexer.TEndOfFile, convertTokensOpts{})
 	return result
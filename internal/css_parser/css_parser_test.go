@@ -2,6 +2,7 @@ package css_parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/evanw/esbuild/internal/ast"
@@ -59,16 +60,18 @@ func expectPrintedLowerUnsupported(t *testing.T, unsupportedCSSFeatures compat.C
 
 func expectPrintedWithAllPrefixes(t *testing.T, contents string, expected string, expectedLog string) {
 	t.Helper()
+	engines := map[compat.Engine]compat.Semver{
+		compat.Chrome:  {Parts: []int{0}},
+		compat.Edge:    {Parts: []int{0}},
+		compat.Firefox: {Parts: []int{0}},
+		compat.IE:      {Parts: []int{0}},
+		compat.IOS:     {Parts: []int{0}},
+		compat.Opera:   {Parts: []int{0}},
+		compat.Safari:  {Parts: []int{0}},
+	}
 	expectPrintedCommon(t, contents+" [prefixed]", contents, expected, expectedLog, config.LoaderCSS, config.Options{
-		CSSPrefixData: compat.CSSPrefixData(map[compat.Engine]compat.Semver{
-			compat.Chrome:  {Parts: []int{0}},
-			compat.Edge:    {Parts: []int{0}},
-			compat.Firefox: {Parts: []int{0}},
-			compat.IE:      {Parts: []int{0}},
-			compat.IOS:     {Parts: []int{0}},
-			compat.Opera:   {Parts: []int{0}},
-			compat.Safari:  {Parts: []int{0}},
-		}),
+		CSSPrefixData:       compat.CSSPrefixData(engines),
+		CSSSyntaxPrefixData: compat.CSSSyntaxPrefixData(engines),
 	})
 }
 
@@ -727,6 +730,64 @@ func TestLowerColor(t *testing.T) {
 	expectPrintedLowerMangle(t, "a { color: hwb(0.75turn 20% 40% / 0.75) }", "a {\n  color: rgba(102, 51, 153, .75);\n}\n", "")
 }
 
+func TestLowerColorMix(t *testing.T) {
+	expectPrinted(t, "a { color: color-mix(in srgb, red, blue) }", "a {\n  color: color-mix(in srgb, red, blue);\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in srgb, red, blue) }", "a {\n  color: #800080;\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in srgb, red 25%, blue) }", "a {\n  color: #4000bf;\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in srgb, 25% red, blue 25%) }", "a {\n  color: rgba(128, 0, 128, .5);\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in srgb, white, transparent) }", "a {\n  color: rgba(255, 255, 255, .5);\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in hsl longer hue, red, blue) }", "a {\n  color: #00ff00;\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(red, blue) }", "a {\n  color: #8c53a2;\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in oklch, red 40%, blue) }", "a {\n  color: #9d00cd;\n  color: color-mix(in oklch, red 40%, blue);\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.ColorMix, "a { color: color-mix(in oklch, red 40%, blue) }", "a {\n  color: color(xyz 0.267 0.111 0.665);\n}\n", "")
+	expectPrintedLowerMangle(t, "a { color: color-mix(in srgb, red, blue) }", "a {\n  color: purple;\n}\n", "")
+
+	// Non-constant or invalid inputs are left alone
+	expectPrintedLower(t, "a { color: color-mix(in srgb, var(--x), blue) }", "a {\n  color: color-mix(in srgb, var(--x), blue);\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in srgb, currentcolor, blue) }", "a {\n  color: color-mix(in srgb, currentcolor, blue);\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in srgb, red 0%, blue 0%) }", "a {\n  color: color-mix(in srgb, red 0%, blue 0%);\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in srgb, red 150%, blue) }", "a {\n  color: color-mix(in srgb, red 150%, blue);\n}\n", "")
+	expectPrintedLower(t, "a { color: color-mix(in foo, red, blue) }", "a {\n  color: color-mix(in foo, red, blue);\n}\n", "")
+}
+
+func TestLowerRelativeColor(t *testing.T) {
+	expectPrinted(t, "a { color: rgb(from red r g b / 50%) }", "a {\n  color: rgb(from red r g b / 50%);\n}\n", "")
+	expectPrintedLower(t, "a { color: rgb(from red r g b / 50%) }", "a {\n  color: rgba(255, 0, 0, .5);\n}\n", "")
+	expectPrintedLower(t, "a { color: rgb(from #123456 b g r) }", "a {\n  color: #563412;\n}\n", "")
+	expectPrintedLower(t, "a { color: rgb(from #123456 r 100% 0) }", "a {\n  color: #12ff00;\n}\n", "")
+	expectPrintedLower(t, "a { color: hsl(from red 180deg s l) }", "a {\n  color: #00ffff;\n}\n", "")
+	expectPrintedLower(t, "a { color: lch(from white l c h / alpha) }", "a {\n  color: #ffffff;\n}\n", "")
+	expectPrintedLower(t, "a { color: color(from red srgb r g b / 0.5) }", "a {\n  color: rgba(255, 0, 0, .5);\n}\n", "")
+	expectPrintedLower(t, "a { color: rgb(from color-mix(in srgb, red, blue) r g b / 50%) }", "a {\n  color: rgba(128, 0, 128, .5);\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.RelativeColors, "a { color: oklch(from blue l c 0) }", "a {\n  color: color(xyz 0.215 0.069 0.075);\n}\n", "")
+
+	// Non-constant or invalid inputs are left alone
+	expectPrintedLower(t, "a { color: rgb(from var(--x) r g b / 50%) }", "a {\n  color: rgb(from var(--x) r g b / 50%);\n}\n", "")
+	expectPrintedLower(t, "a { color: hsl(from red calc(h + 180) s l) }", "a {\n  color: hsl(from red calc(h + 180) s l);\n}\n", "")
+	expectPrintedLower(t, "a { color: rgb(from red r g) }", "a {\n  color: rgb(from red r g);\n}\n", "")
+	expectPrintedLower(t, "a { color: rgb(from red x y z) }", "a {\n  color: rgb(from red x y z);\n}\n", "")
+}
+
+func TestLowerLightDark(t *testing.T) {
+	expectPrinted(t, "a { color: light-dark(white, black) }", "a {\n  color: light-dark(white, black);\n}\n", "")
+	expectPrintedLower(t, "a { color: light-dark(white, black) }",
+		"a {\n  color: white;\n}\n@media (prefers-color-scheme: dark) {\n  a {\n    color: black;\n  }\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.LightDark, "a { color: light-dark(white, black) }",
+		"a {\n  color: white;\n  @media (prefers-color-scheme: dark) {\n    color: black;\n  }\n}\n", "")
+	expectPrintedLower(t, "a { border: 1px solid light-dark(#eee, #111); color: light-dark(color-mix(in srgb, red, white), red) }",
+		"a {\n  border: 1px solid #eee;\n  color: #ff8080;\n}\n@media (prefers-color-scheme: dark) {\n  a {\n    border: 1px solid #111;\n    color: red;\n  }\n}\n", "")
+	expectPrintedLower(t, "a { &:hover { color: light-dark(white, black) } }",
+		"a:hover {\n  color: white;\n}\n@media (prefers-color-scheme: dark) {\n  a:hover {\n    color: black;\n  }\n}\n", "")
+	expectPrintedLowerMinify(t, "a { color: light-dark(white, black) }", "a{color:white}@media (prefers-color-scheme:dark){a{color:black}}", "")
+
+	// A later declaration for the same property overrides the dark color
+	expectPrintedLower(t, "a { color: light-dark(white, black); color: red }", "a {\n  color: white;\n  color: red;\n}\n", "")
+
+	// This can't be lowered outside of a style rule
+	expectPrintedLower(t, "@font-face { color: light-dark(white, black) }", "@font-face {\n  color: light-dark(white, black);\n}\n", "")
+	expectPrintedLower(t, "a { color: light-dark(white) }", "a {\n  color: light-dark(white);\n}\n", "")
+}
+
 func TestBackground(t *testing.T) {
 	expectPrinted(t, "a { background: #11223344 }", "a {\n  background: #11223344;\n}\n", "")
 	expectPrintedMangle(t, "a { background: #11223344 }", "a {\n  background: #1234;\n}\n", "")
@@ -842,6 +903,7 @@ func TestGradient(t *testing.T) {
 			"(\n      color-mix(in lab, red, green) calc(1px),\n      color-mix(in lab, red, green) calc(2px),"+
 			"\n      color-mix(in lab, blue, red) calc(98%),\n      color-mix(in lab, blue, red) calc(99%));\n}\n", "")
 		expectPrintedLowerMangle(t, code, "a {\n  background:\n    "+gradient+
+			"(\n      #a06c00 1px,\n      #a06c00 2px,\n      #bc0086 98%,\n      #bc0086 99%);\n  background:\n    "+gradient+
 			"(\n      color-mix(in lab, red, green) 1px,\n      color-mix(in lab, red, green) 2px,"+
 			"\n      color-mix(in lab, blue, red) 98%,\n      color-mix(in lab, blue, red) 99%);\n}\n", "")
 
@@ -1807,6 +1869,60 @@ func TestAtLayer(t *testing.T) {
 		"<stdin>: WARNING: \"@charset\" must be the first rule in the file\n<stdin>: NOTE: This rule cannot come before a \"@charset\" rule\n")
 }
 
+func TestAtScope(t *testing.T) {
+	expectPrinted(t, "@scope { a { color: red } }", "@scope {\n  a {\n    color: red;\n  }\n}\n", "")
+	expectPrinted(t, "@scope (.a) { b { color: red } }", "@scope (.a) {\n  b {\n    color: red;\n  }\n}\n", "")
+	expectPrinted(t, "@scope (.a, #b) to (.c > d) {}", "@scope (.a, #b) to (.c > d) {\n}\n", "")
+	expectPrinted(t, "@SCOPE (.a) TO (.b) {}", "@scope (.a) to (.b) {\n}\n", "")
+	expectPrinted(t, "@scope to (.b) {}", "@scope to (.b) {\n}\n", "")
+	expectPrinted(t, "@scope(.a)to(.b){}", "@scope (.a)to(.b) {\n}\n", "")
+	expectPrinted(t, "@scope (.a) { :scope { color: red } }", "@scope (.a) {\n  :scope {\n    color: red;\n  }\n}\n", "")
+	expectPrinted(t, "@scope (.a) to {}", "@scope (.a) to {\n}\n", "<stdin>: WARNING: Expected \"(\" but found \"{\"\n")
+	expectPrinted(t, "@scope (> .a) {}", "@scope (> .a) {\n}\n", "<stdin>: WARNING: Unexpected \">\"\n")
+	expectPrinted(t, "@scope .a {}", "@scope .a {\n}\n", "")
+
+	// Check minification
+	expectPrintedMinify(t, "@scope (.a) to (.b) { c { color: red } }", "@scope(.a) to (.b){c{color:red}}", "")
+	expectPrintedMinify(t, "@scope to (.b) { c { color: red } }", "@scope to (.b){c{color:red}}", "")
+	expectPrintedMinify(t, "@scope { c { color: red } }", "@scope{c{color:red}}", "")
+	expectPrintedMangle(t, "@scope (.a) { b {} }", "", "")
+	expectPrintedMangle(t, "@scope (.a) { b { color: red } }", "@scope (.a) {\n  b {\n    color: red;\n  }\n}\n", "")
+
+	// Check local names
+	expectPrintedLocal(t, "@scope (.a) to (.b) { .c { color: red } }", "@scope (.a) to (.b) {\n  .c {\n    color: red;\n  }\n}\n", "")
+	expectPrintedLocal(t, "@scope (:global(.a)) to (.b) {}", "@scope (.a) to (.b) {\n}\n", "")
+
+	// Check lowering
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) { b { color: red } }", ".a b {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) { > b { color: red } }", ".a > b {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) { :scope { color: red } }", ".a {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) { & b { color: red } }", ".a b {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) { :is(:scope > b) { color: red } }", ":is(.a > b) {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a, .b) { c, d { color: red } }", ".a c,\n.a d,\n.b c,\n.b d {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) to (.b) { c { color: red } }",
+		".a c:not(.a .b *, .a .b) {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) to (.b) { c::before { color: red } }",
+		".a c:not(.a .b *, .a .b)::before {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) to (:scope > .b) { c { color: red } }",
+		".a c:not(.a > .b *, .a > .b) {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@media screen { @scope (.a) { b { color: red } } }",
+		"@media screen {\n  .a b {\n    color: red;\n  }\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) { @media screen { b { color: red } } }",
+		"@media screen {\n  .a b {\n    color: red;\n  }\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope (.a) { @scope (.b) { c { color: red } } }",
+		".a .b c {\n  color: red;\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, ".a { @scope (& > .b) to (.c) { color: red; d { color: blue } } }",
+		".a {\n  & > .b:not(& > .b .c *, & > .b .c) {\n    color: red;\n  }\n  & > .b d:not(& > .b .c *, & > .b .c) {\n    color: blue;\n  }\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, ".a { @scope { color: red } }", ".a {\n  & {\n    color: red;\n  }\n}\n", "")
+	expectPrintedLowerUnsupported(t, compat.AtScope, "@scope { a { color: red } }", "@scope {\n  a {\n    color: red;\n  }\n}\n",
+		"<stdin>: WARNING: Transforming this \"@scope\" rule is not supported in the configured target environment\n"+
+			"NOTE: A \"@scope\" rule without a scope root can only be transformed when it's nested inside a style rule.\n")
+
+	// Check lowering together with nesting
+	expectPrintedLower(t, ".a { @scope (& > .b) { color: red; c { color: blue } } }",
+		".a > .b {\n  color: red;\n}\n.a > .b c {\n  color: blue;\n}\n", "")
+}
+
 func TestEmptyRule(t *testing.T) {
 	expectPrinted(t, "div {}", "div {\n}\n", "")
 	expectPrinted(t, "@media screen {}", "@media screen {\n}\n", "")
@@ -2486,6 +2602,70 @@ func TestMangleAtMedia(t *testing.T) {
 	expectPrintedMangle(t, "@media screen { a { color: red } } @media screen { b { color: red } }", "@media screen {\n  a {\n    color: red;\n  }\n}\n@media screen {\n  b {\n    color: red;\n  }\n}\n", "")
 }
 
+func TestLowerAtMediaRange(t *testing.T) {
+	expectPrinted(t, "@media (width >= 600px) {}", "@media (width >= 600px) {\n}\n", "")
+	expectPrintedLower(t, "@media (width >= 600px) {}", "@media (min-width: 600px) {\n}\n", "")
+	expectPrintedLower(t, "@media (width <= 600px) {}", "@media (max-width: 600px) {\n}\n", "")
+	expectPrintedLower(t, "@media (width = 600px) {}", "@media (width: 600px) {\n}\n", "")
+	expectPrintedLower(t, "@media (width > 600px) {}", "@media (min-width: 600.001px) {\n}\n", "")
+	expectPrintedLower(t, "@media (width < 600px) {}", "@media (max-width: 599.999px) {\n}\n", "")
+	expectPrintedLower(t, "@media (600px <= width) {}", "@media (min-width: 600px) {\n}\n", "")
+	expectPrintedLower(t, "@media (600px > width) {}", "@media (max-width: 599.999px) {\n}\n", "")
+	expectPrintedLower(t, "@media (400px <= width <= 800px) {}", "@media (min-width: 400px) and (max-width: 800px) {\n}\n", "")
+	expectPrintedLower(t, "@media (800px > width > 400px) {}", "@media (max-width: 799.999px) and (min-width: 400.001px) {\n}\n", "")
+	expectPrintedLower(t, "@media (WIDTH>=600px) {}", "@media (min-WIDTH: 600px) {\n}\n", "")
+	expectPrintedLower(t, "@media (height < 2.5em) {}", "@media (max-height: 2.499em) {\n}\n", "")
+	expectPrintedLower(t, "@media (resolution >= 2dppx) {}", "@media (min-resolution: 2dppx) {\n}\n", "")
+	expectPrintedLower(t, "@media (aspect-ratio > 16/9) {}", "@media (aspect-ratio > 16/9) {\n}\n", "<stdin>: WARNING: Transforming this \"aspect-ratio\" range is not supported in the configured target environment\nNOTE: Only a single integer, length, or resolution can be used with \"<\" or \">\" when transforming range syntax into \"min-\" and \"max-\" prefixed features.\n")
+	expectPrintedLower(t, "@media (1 < aspect-ratio) {}", "@media (1 < aspect-ratio) {\n}\n", "<stdin>: WARNING: Transforming this \"aspect-ratio\" range is not supported in the configured target environment\nNOTE: Only a single integer, length, or resolution can be used with \"<\" or \">\" when transforming range syntax into \"min-\" and \"max-\" prefixed features.\n")
+	expectPrintedLower(t, "@media (device-aspect-ratio < 2) {}", "@media (device-aspect-ratio < 2) {\n}\n", "<stdin>: WARNING: Transforming this \"device-aspect-ratio\" range is not supported in the configured target environment\nNOTE: Only a single integer, length, or resolution can be used with \"<\" or \">\" when transforming range syntax into \"min-\" and \"max-\" prefixed features.\n")
+	expectPrintedLower(t, "@media (aspect-ratio >= 16/9) {}", "@media (min-aspect-ratio: 16/9) {\n}\n", "")
+	expectPrintedLower(t, "@media (color > 2) {}", "@media (min-color: 3) {\n}\n", "")
+	expectPrintedLower(t, "@media (color-index < 256) {}", "@media (max-color-index: 255) {\n}\n", "")
+	expectPrintedLower(t, "@media (color > 2.5) {}", "@media (color > 2.5) {\n}\n", "<stdin>: WARNING: Transforming this \"color\" range is not supported in the configured target environment\nNOTE: Only a single integer, length, or resolution can be used with \"<\" or \">\" when transforming range syntax into \"min-\" and \"max-\" prefixed features.\n")
+	expectPrintedLower(t, "@media (resolution > 2dppx) {}", "@media (min-resolution: 2.001dppx) {\n}\n", "")
+	expectPrintedLower(t, "@media (width > 0) {}", "@media (width > 0) {\n}\n", "<stdin>: WARNING: Transforming this \"width\" range is not supported in the configured target environment\nNOTE: Only a single integer, length, or resolution can be used with \"<\" or \">\" when transforming range syntax into \"min-\" and \"max-\" prefixed features.\n")
+	expectPrintedLower(t, "@media (width > calc(1px + 2em)) {}", "@media (width > calc(1px + 2em)) {\n}\n", "<stdin>: WARNING: Transforming this \"width\" range is not supported in the configured target environment\nNOTE: Only a single integer, length, or resolution can be used with \"<\" or \">\" when transforming range syntax into \"min-\" and \"max-\" prefixed features.\n")
+	expectPrintedLower(t, "@media (width >= calc(1px + 2em)) {}", "@media (min-width: calc(1px + 2em)) {\n}\n", "")
+	expectPrintedLower(t, "@media (foo >= 1px) {}", "@media (foo >= 1px) {\n}\n", "")
+	expectPrintedLower(t, "@media (width > = 1px) {}", "@media (width > = 1px) {\n}\n", "")
+
+	// Lowered ranges in a larger query
+	expectPrintedLower(t, "@media screen and (400px <= width <= 800px) {}", "@media screen and (min-width: 400px) and (max-width: 800px) {\n}\n", "")
+	expectPrintedLower(t, "@media screen and (color) and (400px < width < 800px) {}",
+		"@media screen and (color) and (min-width: 400.001px) and (max-width: 799.999px) {\n}\n", "")
+	expectPrintedLower(t, "@media (color) or (400px <= width <= 800px) {}", "@media (color) or ((min-width: 400px) and (max-width: 800px)) {\n}\n", "")
+	expectPrintedLower(t, "@media not (400px <= width <= 800px) {}", "@media not ((min-width: 400px) and (max-width: 800px)) {\n}\n", "")
+	expectPrintedLower(t, "@media print, (width < 600px), screen {}", "@media print, (max-width: 599.999px), screen {\n}\n", "")
+	expectPrintedLower(t, "@media only screen and ((width >= 1px) or (height >= 1px)) {}",
+		"@media only screen and ((min-width: 1px) or (min-height: 1px)) {\n}\n", "")
+	expectPrintedLower(t, "a { @media (width >= 600px) { color: red } }", "@media (min-width: 600px) {\n  a {\n    color: red;\n  }\n}\n", "")
+	expectPrintedLower(t, "@import \"foo.css\" screen and (width >= 600px);", "@import \"foo.css\" screen and (min-width: 600px);\n", "")
+
+	// Invalid queries are left alone
+	expectPrintedLower(t, "@media screen or (width >= 600px) {}", "@media screen or (width >= 600px) {\n}\n", "")
+	expectPrintedLower(t, "@media (width >= 600px) and (color) or (hover) {}", "@media (width >= 600px) and (color) or (hover) {\n}\n", "")
+
+	// Minification
+	expectPrintedLowerMinify(t, "@media (400px <= width <= 800px), print { a { color: red } }",
+		"@media (min-width: 400px) and (max-width: 800px),print{a{color:red}}", "")
+	expectPrintedLowerMangle(t, "@media (width > 0.5em) {}", "", "")
+	expectPrintedLowerMangle(t, "@media (width > 0.5em) { a { color: red } }", "@media (min-width: .501em) {\n  a {\n    color: red;\n  }\n}\n", "")
+}
+
+func TestAtCustomMedia(t *testing.T) {
+	expectPrinted(t, "@custom-media --small (width < 600px);", "@custom-media --small (width < 600px);\n", "")
+	expectPrinted(t, "@custom-media --small screen, print;", "@custom-media --small screen, print;\n", "")
+	expectPrinted(t, "@custom-media --on true;", "@custom-media --on true;\n", "")
+	expectPrintedMinify(t, "@custom-media --small (width < 600px) ;", "@custom-media --small (width < 600px);", "")
+	expectPrintedLower(t, "@custom-media --small (width < 600px);", "@custom-media --small (max-width: 599.999px);\n", "")
+
+	// These are invalid and are passed through as unknown rules
+	expectPrinted(t, "@custom-media small (width < 600px);", "@custom-media small (width < 600px);\n", "")
+	expectPrinted(t, "@custom-media --small;", "@custom-media --small;\n", "")
+	expectPrinted(t, "@custom-media --small (width < 600px) {}", "@custom-media --small (width < 600px) {}\n", "")
+}
+
 func TestFontWeight(t *testing.T) {
 	expectPrintedMangle(t, "a { font-weight: normal }", "a {\n  font-weight: 400;\n}\n", "")
 	expectPrintedMangle(t, "a { font-weight: bold }", "a {\n  font-weight: 700;\n}\n", "")
@@ -2696,6 +2876,72 @@ func TestPrefixInsertion(t *testing.T) {
 		"a {\n  width: -webkit-fill-available;\n  width: -moz-available;\n  width: stretch;\n}\n", "")
 }
 
+func TestSyntaxPrefixInsertion(t *testing.T) {
+	// Selectors
+	expectPrintedWithAllPrefixes(t, "input::placeholder { color: gray }",
+		"input::-webkit-input-placeholder {\n  color: gray;\n}\ninput::-moz-placeholder {\n  color: gray;\n}\n"+
+			"input:-ms-input-placeholder {\n  color: gray;\n}\ninput::placeholder {\n  color: gray;\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a:hover, b:fullscreen { color: red }",
+		"b:-webkit-full-screen {\n  color: red;\n}\nb:-moz-full-screen {\n  color: red;\n}\nb:-ms-fullscreen {\n  color: red;\n}\n"+
+			"a:hover,\nb:fullscreen {\n  color: red;\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a:not(:any-link) { color: red }",
+		"a:not(:-webkit-any-link) {\n  color: red;\n}\na:not(:-moz-any-link) {\n  color: red;\n}\na:not(:any-link) {\n  color: red;\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "::selection { color: red }",
+		"::-moz-selection {\n  color: red;\n}\n::selection {\n  color: red;\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "::file-selector-button { color: red }",
+		"::-webkit-file-upload-button {\n  color: red;\n}\n::file-selector-button {\n  color: red;\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a { &:read-only { color: red } }",
+		"a {\n  &:-moz-read-only {\n    color: red;\n  }\n  &:read-only {\n    color: red;\n  }\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "::-moz-selection { color: red } ::selection { color: red }",
+		"::-moz-selection {\n  color: red;\n}\n::selection {\n  color: red;\n}\n", "")
+
+	// At-rules
+	expectPrintedWithAllPrefixes(t, "@keyframes x { to { color: red } }",
+		"@-webkit-keyframes x {\n  to {\n    color: red;\n  }\n}\n@-moz-keyframes x {\n  to {\n    color: red;\n  }\n}\n"+
+			"@keyframes x {\n  to {\n    color: red;\n  }\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "@-webkit-keyframes x { to { color: red } } @keyframes x { to { color: red } }",
+		"@-webkit-keyframes x {\n  to {\n    color: red;\n  }\n}\n@-moz-keyframes x {\n  to {\n    color: red;\n  }\n}\n"+
+			"@keyframes x {\n  to {\n    color: red;\n  }\n}\n", "")
+
+	// Values
+	expectPrintedWithAllPrefixes(t, "a { display: flex }",
+		"a {\n  display: -webkit-box;\n  display: -webkit-flex;\n  display: -moz-box;\n  display: -ms-flexbox;\n  display: flex;\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a { display: inline-flex }",
+		"a {\n  display: -webkit-inline-box;\n  display: -webkit-inline-flex;\n  display: -moz-inline-box;\n  display: -ms-inline-flexbox;\n  display: inline-flex;\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a { display: -ms-flexbox; display: flex }",
+		"a {\n  display: -ms-flexbox;\n  display: -webkit-box;\n  display: -webkit-flex;\n  display: -moz-box;\n  display: flex;\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a { display: block }", "a {\n  display: block;\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a { background: image-set(url(a.png) 1x, url(b.png) 2x) }",
+		"a {\n  background: -webkit-image-set(url(a.png) 1x, url(b.png) 2x);\n  background: image-set(url(a.png) 1x, url(b.png) 2x);\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a { background: cross-fade(url(a.png), url(b.png)) }",
+		"a {\n  background: -webkit-cross-fade(url(a.png), url(b.png));\n  background: cross-fade(url(a.png), url(b.png));\n}\n", "")
+
+	// Gradients
+	expectGradient := func(name string, args string, legacyArgs string) {
+		t.Helper()
+		expected := "a {\n"
+		for _, prefix := range []string{"-webkit-", "-moz-", "-o-"} {
+			expected += "  b:\n    " + prefix + name + "(\n      " + strings.ReplaceAll(legacyArgs, ", ", ",\n      ") + ");\n"
+		}
+		expected += "  b:\n    " + name + "(\n      " + strings.ReplaceAll(args, ", ", ",\n      ") + ");\n}\n"
+		expectPrintedWithAllPrefixes(t, "a { b: "+name+"("+args+") }", expected, "")
+	}
+	expectGradient("linear-gradient", "red, blue, green", "red, blue, green")
+	expectGradient("linear-gradient", "to right, red, blue", "left, red, blue")
+	expectGradient("repeating-linear-gradient", "to top left, red, blue", "bottom right, red, blue")
+	expectGradient("linear-gradient", "180deg, red, blue", "270deg, red, blue")
+	expectGradient("linear-gradient", "0.25turn, red, blue", "0deg, red, blue")
+	expectGradient("radial-gradient", "circle at top, red, blue", "top, circle, red, blue")
+	expectGradient("radial-gradient", "at 10% 20%, red, blue", "10% 20%, red, blue")
+	expectGradient("radial-gradient", "circle, red, blue", "circle, red, blue")
+	expectPrintedWithAllPrefixes(t, "a { b: linear-gradient(to right in oklab, red, blue) }",
+		"a {\n  b:\n    linear-gradient(\n      to right in oklab,\n      red,\n      blue);\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a { b: linear-gradient(to right, red) }",
+		"a {\n  b: -webkit-linear-gradient(left, red);\n  b: -moz-linear-gradient(left, red);\n  b: -o-linear-gradient(left, red);\n  b: linear-gradient(to right, red);\n}\n", "")
+	expectPrintedWithAllPrefixes(t, "a { b: -webkit-linear-gradient(left, red); b: linear-gradient(to right, red) }",
+		"a {\n  b: -webkit-linear-gradient(left, red);\n  b: -moz-linear-gradient(left, red);\n  b: -o-linear-gradient(left, red);\n  b: linear-gradient(to right, red);\n}\n", "")
+}
+
 func TestNthChild(t *testing.T) {
 	for _, nth := range []string{"nth-child", "nth-last-child"} {
 		expectPrinted(t, ":"+nth+"(x) {}", ":"+nth+"(x) {\n}\n", "<stdin>: WARNING: Unexpected \"x\"\n")
@@ -2795,6 +3041,40 @@ func TestNthChild(t *testing.T) {
 	}
 }
 
+func TestICSSValues(t *testing.T) {
+	// These are only special in CSS modules
+	expectPrinted(t, "@value a: red; .b { color: a }", "@value a: red;\n.b {\n  color: a;\n}\n", "")
+	expectPrinted(t, ":export { a: red }", ":export {\n  a: red;\n}\n", "")
+
+	expectPrintedLocal(t, "@value a: red; .b { color: a }", ".b {\n  color: red;\n}\n", "")
+	expectPrintedLocal(t, "@value a red; .b { color: a }", ".b {\n  color: red;\n}\n", "")
+	expectPrintedLocal(t, "@VALUE a:red; .b { color: a }", ".b {\n  color: red;\n}\n", "")
+	expectPrintedLocal(t, ".b { color: a } @value a: red;", ".b {\n  color: red;\n}\n", "")
+	expectPrintedLocal(t, "@value a: 1px solid red; .b { border: a }", ".b {\n  border: 1px solid red;\n}\n", "")
+	expectPrintedLocal(t, "@value a: 1px; .b { margin: a calc(a * 2) }", ".b {\n  margin: 1px calc(1px * 2);\n}\n", "")
+	expectPrintedLocal(t, "@value a: 1px; @value b: a a; .c { margin: b }", ".c {\n  margin: 1px 1px;\n}\n", "")
+	expectPrintedLocal(t, "@value small: (max-width: 599px); @media small { .b { color: red } }",
+		"@media (max-width: 599px) {\n  .b {\n    color: red;\n  }\n}\n", "")
+	expectPrintedLocal(t, "@value a: red; .b { .c { color: a } }", ".b {\n  .c {\n    color: red;\n  }\n}\n", "")
+	expectPrintedLocal(t, "@value a from \"./file.css\"; .b { color: a }", ".b {\n  color: a;\n}\n", "")
+	expectPrintedLocal(t, "@value a: red; @value a from \"./file.css\"; .b { color: a }", ".b {\n  color: a;\n}\n", "")
+	expectPrintedLocal(t, "@value a from \"./file.css\"; @value a: red; .b { color: a }", ".b {\n  color: red;\n}\n", "")
+	expectPrintedLocal(t, ":export { a: red; b: 1px solid } .c { color: red }", ".c {\n  color: red;\n}\n", "")
+	expectPrintedLocal(t, ":EXPORT{a:red}", "", "")
+	expectPrintedLocal(t, ":export .a { color: red }", ":export .a {\n  color: red;\n}\n", "")
+	expectPrintedLocal(t, ".a { @value b: red; }", ".a {\n  @value b: red;\n}\n", "")
+
+	// Check syntax errors
+	expectPrintedLocal(t, "@value;", "", "<stdin>: WARNING: Expected a name after \"@value\"\n")
+	expectPrintedLocal(t, "@value 1px;", "", "<stdin>: WARNING: Expected a name after \"@value\"\n")
+	expectPrintedLocal(t, "@value a from b;", "", "<stdin>: WARNING: Expected a string containing a path but found \"b\"\n")
+	expectPrintedLocal(t, "@value a b c from \"./file.css\";", "",
+		"<stdin>: WARNING: Expected a name or \"name as alias\" in \"@value\" import\n")
+	expectPrintedLocal(t, "@value a { color: red }", "@value a { color: red }\n", "")
+	expectPrintedLocal(t, ":export { 1px: red; a: blue }", "", "<stdin>: WARNING: Expected identifier but found \"1px\"\n")
+	expectPrintedLocal(t, ":export { a red; b: blue }", "", "<stdin>: WARNING: Expected \":\"\n")
+}
+
 func TestComposes(t *testing.T) {
 	expectPrinted(t, ".foo { composes: bar; color: red }", ".foo {\n  composes: bar;\n  color: red;\n}\n", "")
 	expectPrinted(t, ".foo .bar { composes: bar; color: red }", ".foo .bar {\n  composes: bar;\n  color: red;\n}\n", "")
@@ -0,0 +1,488 @@
+package css_parser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_lexer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// Prefixed copies are always generated in this order, which matches the order
+// used for prefixed properties
+var prefixesInOrder = []struct {
+	prefix compat.CSSPrefix
+	text   string
+}{
+	{prefix: compat.WebkitPrefix, text: "-webkit-"},
+	{prefix: compat.KhtmlPrefix, text: "-khtml-"},
+	{prefix: compat.MozPrefix, text: "-moz-"},
+	{prefix: compat.MsPrefix, text: "-ms-"},
+	{prefix: compat.OPrefix, text: "-o-"},
+}
+
+type prefixedPseudoName struct {
+	name      string
+	isElement bool
+}
+
+type prefixedPseudo struct {
+	syntax    compat.CSSPrefixedSyntax
+	isElement bool
+	names     map[compat.CSSPrefix]prefixedPseudoName
+}
+
+// Some of these aren't just the unprefixed name with a prefix added. Note that
+// Internet Explorer only supports ":-ms-input-placeholder" as a pseudo-class.
+var prefixedPseudos = map[string]prefixedPseudo{
+	"any-link": {syntax: compat.PseudoAnyLink, names: map[compat.CSSPrefix]prefixedPseudoName{
+		compat.WebkitPrefix: {name: "-webkit-any-link"},
+		compat.MozPrefix:    {name: "-moz-any-link"},
+	}},
+	"backdrop": {syntax: compat.PseudoBackdrop, isElement: true, names: map[compat.CSSPrefix]prefixedPseudoName{
+		compat.WebkitPrefix: {name: "-webkit-backdrop", isElement: true},
+		compat.MsPrefix:     {name: "-ms-backdrop", isElement: true},
+	}},
+	"file-selector-button": {syntax: compat.PseudoFileSelectorButton, isElement: true, names: map[compat.CSSPrefix]prefixedPseudoName{
+		compat.WebkitPrefix: {name: "-webkit-file-upload-button", isElement: true},
+	}},
+	"fullscreen": {syntax: compat.PseudoFullscreen, names: map[compat.CSSPrefix]prefixedPseudoName{
+		compat.WebkitPrefix: {name: "-webkit-full-screen"},
+		compat.MozPrefix:    {name: "-moz-full-screen"},
+		compat.MsPrefix:     {name: "-ms-fullscreen"},
+	}},
+	"placeholder": {syntax: compat.PseudoPlaceholder, isElement: true, names: map[compat.CSSPrefix]prefixedPseudoName{
+		compat.WebkitPrefix: {name: "-webkit-input-placeholder", isElement: true},
+		compat.MozPrefix:    {name: "-moz-placeholder", isElement: true},
+		compat.MsPrefix:     {name: "-ms-input-placeholder"},
+	}},
+	"placeholder-shown": {syntax: compat.PseudoPlaceholderShown, names: map[compat.CSSPrefix]prefixedPseudoName{
+		compat.MozPrefix: {name: "-moz-placeholder"},
+		compat.MsPrefix:  {name: "-ms-input-placeholder"},
+	}},
+	"read-only": {syntax: compat.PseudoReadOnly, names: map[compat.CSSPrefix]prefixedPseudoName{
+		compat.MozPrefix: {name: "-moz-read-only"},
+	}},
+	"read-write": {syntax: compat.PseudoReadWrite, names: map[compat.CSSPrefix]prefixedPseudoName{
+		compat.MozPrefix: {name: "-moz-read-write"},
+	}},
+	"selection": {syntax: compat.PseudoSelection, isElement: true, names: map[compat.CSSPrefix]prefixedPseudoName{
+		compat.MozPrefix: {name: "-moz-selection", isElement: true},
+	}},
+}
+
+var prefixedValueFunctions = map[string]compat.CSSPrefixedSyntax{
+	"cross-fade":                compat.CrossFadeFunction,
+	"image-set":                 compat.ImageSetFunction,
+	"linear-gradient":           compat.GradientFunctions,
+	"radial-gradient":           compat.GradientFunctions,
+	"repeating-linear-gradient": compat.GradientFunctions,
+	"repeating-radial-gradient": compat.GradientFunctions,
+}
+
+// This inserts a prefixed copy of each style rule that uses a selector that
+// needs a prefix, and of each "@keyframes" rule that needs a prefix. A copy is
+// needed for each prefix because browsers drop the whole rule if any selector
+// in it is unrecognized.
+func (p *parser) insertPrefixedRules(rules []css_ast.Rule) []css_ast.Rule {
+	var result []css_ast.Rule
+
+	for i, rule := range rules {
+		var prefixed []css_ast.Rule
+
+		switch r := rule.Data.(type) {
+		case *css_ast.RSelector:
+			for _, item := range prefixesInOrder {
+				if clone := p.prefixSelectorRule(r, item.prefix); clone != nil && !hasSelectorRule(rules, clone.Selectors) {
+					prefixed = append(prefixed, css_ast.Rule{Loc: rule.Loc, Data: clone})
+				}
+			}
+
+		case *css_ast.RAtKeyframes:
+			if !strings.EqualFold(r.AtToken, "keyframes") {
+				break
+			}
+			prefixes := p.options.cssSyntaxPrefixData[compat.AtKeyframes]
+			for _, item := range prefixesInOrder {
+				if (prefixes&item.prefix) != 0 && !hasKeyframesRule(rules, item.text+"keyframes", r) {
+					clone := *r
+					clone.AtToken = item.text + "keyframes"
+					prefixed = append(prefixed, css_ast.Rule{Loc: rule.Loc, Data: &clone})
+					p.symbols[r.Name.Ref.InnerIndex].UseCountEstimate++
+				}
+			}
+		}
+
+		if prefixed != nil && result == nil {
+			result = append(make([]css_ast.Rule, 0, len(rules)+len(prefixed)), rules[:i]...)
+		}
+		if result != nil {
+			result = append(result, prefixed...)
+			result = append(result, rule)
+		}
+	}
+
+	if result == nil {
+		return rules
+	}
+	return result
+}
+
+func hasSelectorRule(rules []css_ast.Rule, selectors []css_ast.ComplexSelector) bool {
+	for _, rule := range rules {
+		if r, ok := rule.Data.(*css_ast.RSelector); ok && len(r.Selectors) == len(selectors) {
+			equal := true
+			for i, sel := range r.Selectors {
+				if !sel.Equal(selectors[i], nil) {
+					equal = false
+					break
+				}
+			}
+			if equal {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasKeyframesRule(rules []css_ast.Rule, atToken string, keyframes *css_ast.RAtKeyframes) bool {
+	for _, rule := range rules {
+		if r, ok := rule.Data.(*css_ast.RAtKeyframes); ok && r.Name.Ref == keyframes.Name.Ref && strings.EqualFold(r.AtToken, atToken) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns a copy of the rule containing only the selectors that changed when
+// adding the prefix, or nil if no selectors changed
+func (p *parser) prefixSelectorRule(r *css_ast.RSelector, prefix compat.CSSPrefix) *css_ast.RSelector {
+	var selectors []css_ast.ComplexSelector
+	for _, sel := range r.Selectors {
+		if prefixed, ok := p.prefixComplexSelector(sel, prefix); ok {
+			selectors = append(selectors, prefixed)
+		}
+	}
+	if selectors == nil {
+		return nil
+	}
+	clone := *r
+	clone.Selectors = selectors
+	return &clone
+}
+
+func (p *parser) prefixComplexSelector(sel css_ast.ComplexSelector, prefix compat.CSSPrefix) (css_ast.ComplexSelector, bool) {
+	var compounds []css_ast.CompoundSelector
+
+	for i, compound := range sel.Selectors {
+		var subclasses []css_ast.SubclassSelector
+
+		for j, ss := range compound.SubclassSelectors {
+			var data css_ast.SS
+
+			switch s := ss.Data.(type) {
+			case *css_ast.SSPseudoClass:
+				if pseudo, ok := prefixedPseudos[strings.ToLower(s.Name)]; ok && pseudo.isElement == s.IsElement && s.Args == nil &&
+					(p.options.cssSyntaxPrefixData[pseudo.syntax]&prefix) != 0 {
+					if name, ok := pseudo.names[prefix]; ok {
+						data = &css_ast.SSPseudoClass{Name: name.name, IsElement: name.isElement}
+					}
+				}
+
+			case *css_ast.SSPseudoClassWithSelectorList:
+				// "a:not(:fullscreen)" => "a:not(:-webkit-full-screen)"
+				var inner []css_ast.ComplexSelector
+				for k, innerSel := range s.Selectors {
+					if prefixed, ok := p.prefixComplexSelector(innerSel, prefix); ok {
+						if inner == nil {
+							inner = append([]css_ast.ComplexSelector{}, s.Selectors...)
+						}
+						inner[k] = prefixed
+					}
+				}
+				if inner != nil {
+					clone := *s
+					clone.Selectors = inner
+					data = &clone
+				}
+			}
+
+			if data != nil {
+				if subclasses == nil {
+					subclasses = append([]css_ast.SubclassSelector{}, compound.SubclassSelectors...)
+				}
+				subclasses[j] = css_ast.SubclassSelector{Range: ss.Range, Data: data}
+			}
+		}
+
+		if subclasses != nil {
+			if compounds == nil {
+				compounds = append([]css_ast.CompoundSelector{}, sel.Selectors...)
+			}
+			compounds[i].SubclassSelectors = subclasses
+		}
+	}
+
+	if compounds == nil {
+		return sel, false
+	}
+	return css_ast.ComplexSelector{Selectors: compounds}, true
+}
+
+// This handles prefixes that apply to a value instead of to a property, such
+// as "display: -webkit-flex" and "-webkit-image-set()". The prefixed copies
+// are inserted before the latest declaration.
+func (p *parser) insertPrefixedValues(rules []css_ast.Rule, loc logger.Loc, decl *css_ast.RDeclaration) []css_ast.Rule {
+	// "display: flex" => "display: -webkit-box; display: -webkit-flex; display: flex"
+	if decl.Key == css_ast.DDisplay {
+		if len(decl.Value) == 1 && decl.Value[0].Kind == css_lexer.TIdent {
+			if prefixes, ok := p.options.cssSyntaxPrefixData[compat.DisplayFlex]; ok {
+				var isInline bool
+				switch strings.ToLower(decl.Value[0].Text) {
+				case "flex":
+				case "inline-flex":
+					isInline = true
+				default:
+					return rules
+				}
+				for _, item := range prefixesInOrder {
+					if (prefixes & item.prefix) != 0 {
+						for _, text := range prefixedDisplayFlex(item.prefix, isInline) {
+							value := []css_ast.Token{decl.Value[0]}
+							value[0].Text = text
+							rules = insertDeclarationWithValue(rules, loc, decl, value)
+						}
+					}
+				}
+			}
+		}
+		return rules
+	}
+
+	for _, item := range prefixesInOrder {
+		if value, ok := p.prefixValueFunctions(decl.Value, item.prefix, item.text); ok {
+			rules = insertDeclarationWithValue(rules, loc, decl, value)
+		}
+	}
+	return rules
+}
+
+func prefixedDisplayFlex(prefix compat.CSSPrefix, isInline bool) []string {
+	switch prefix {
+	case compat.WebkitPrefix:
+		// Older WebKit browsers only support the 2009 version of the specification
+		if isInline {
+			return []string{"-webkit-inline-box", "-webkit-inline-flex"}
+		}
+		return []string{"-webkit-box", "-webkit-flex"}
+
+	case compat.MozPrefix:
+		if isInline {
+			return []string{"-moz-inline-box"}
+		}
+		return []string{"-moz-box"}
+
+	case compat.MsPrefix:
+		if isInline {
+			return []string{"-ms-inline-flexbox"}
+		}
+		return []string{"-ms-flexbox"}
+	}
+	return nil
+}
+
+func insertDeclarationWithValue(rules []css_ast.Rule, loc logger.Loc, decl *css_ast.RDeclaration, value []css_ast.Token) []css_ast.Rule {
+	// Don't insert a prefixed declaration if there already is one
+	for _, rule := range rules {
+		if prevDecl, ok := rule.Data.(*css_ast.RDeclaration); ok && prevDecl.KeyText == decl.KeyText && css_ast.TokensEqual(prevDecl.Value, value, nil) {
+			return rules
+		}
+	}
+
+	// Overwrite the latest declaration with the prefixed declaration
+	rules[len(rules)-1] = css_ast.Rule{Loc: loc, Data: &css_ast.RDeclaration{
+		KeyText:   decl.KeyText,
+		KeyRange:  decl.KeyRange,
+		Key:       decl.Key,
+		Value:     value,
+		Important: decl.Important,
+	}}
+
+	// Re-add the latest declaration after the inserted declaration
+	return append(rules, css_ast.Rule{Loc: loc, Data: decl})
+}
+
+// This returns a copy of the tokens with the prefix added to all functions
+// that need it, or false if there are no such functions
+func (p *parser) prefixValueFunctions(tokens []css_ast.Token, prefix compat.CSSPrefix, prefixText string) ([]css_ast.Token, bool) {
+	var result []css_ast.Token
+
+	for i, t := range tokens {
+		changed := false
+
+		if t.Children != nil {
+			if children, ok := p.prefixValueFunctions(*t.Children, prefix, prefixText); ok {
+				t.Children = &children
+				changed = true
+			}
+		}
+
+		if t.Kind == css_lexer.TFunction && t.Children != nil {
+			lower := strings.ToLower(t.Text)
+			if syntax, ok := prefixedValueFunctions[lower]; ok && (p.options.cssSyntaxPrefixData[syntax]&prefix) != 0 {
+				ok = true
+				if syntax == compat.GradientFunctions {
+					var children []css_ast.Token
+					if children, ok = p.legacyGradientArgs(lower, *t.Children, t.Loc); ok {
+						t.Children = &children
+					}
+				}
+				if ok {
+					t.Text = prefixText + t.Text
+					changed = true
+				}
+			}
+		}
+
+		if changed && result == nil {
+			result = append(make([]css_ast.Token, 0, len(tokens)), tokens[:i]...)
+		}
+		if result != nil {
+			result = append(result, t)
+		}
+	}
+
+	if result == nil {
+		return tokens, false
+	}
+	return result, true
+}
+
+// Prefixed gradients use an older syntax where the direction is the starting
+// point instead of the ending point, angles start from the "east" and go
+// counter-clockwise, and the position for radial gradients comes first:
+//
+//	"linear-gradient(to right, red, blue)" => "-webkit-linear-gradient(left, red, blue)"
+//	"linear-gradient(30deg, red, blue)" => "-webkit-linear-gradient(60deg, red, blue)"
+//	"radial-gradient(circle at top, red, blue)" => "-webkit-radial-gradient(top, circle, red, blue)"
+//
+// Gradients that can't be represented in the old syntax are not prefixed.
+func (p *parser) legacyGradientArgs(name string, tokens []css_ast.Token, loc logger.Loc) ([]css_ast.Token, bool) {
+	end := 0
+	for end < len(tokens) && tokens[end].Kind != css_lexer.TComma {
+		end++
+	}
+	first, rest := tokens[:end], tokens[end:]
+	if len(first) == 0 {
+		return nil, false
+	}
+	for _, t := range first {
+		// Color interpolation methods are newer than the old syntax
+		if t.Kind == css_lexer.TIdent && strings.EqualFold(t.Text, "in") {
+			return nil, false
+		}
+	}
+
+	if strings.HasSuffix(name, "linear-gradient") {
+		if first[0].Kind == css_lexer.TDimension {
+			if len(first) != 1 {
+				return nil, false
+			}
+			degrees, ok := angleInDegrees(first[0])
+			if !ok {
+				return nil, false
+			}
+			degrees = math.Mod(90-degrees, 360)
+			if degrees < 0 {
+				degrees += 360
+			}
+			text := strconv.FormatFloat(math.Round(degrees*1e6)/1e6, 'f', -1, 64)
+			if p.options.minifySyntax {
+				if mangled, ok := mangleNumber(text); ok {
+					text = mangled
+				}
+			}
+			angle := first[0]
+			angle.Text = text + "deg"
+			angle.UnitOffset = uint16(len(text))
+			return append([]css_ast.Token{angle}, rest...), true
+		}
+
+		if first[0].Kind == css_lexer.TIdent && strings.EqualFold(first[0].Text, "to") {
+			if len(first) < 2 || len(first) > 3 {
+				return nil, false
+			}
+			sides := append([]css_ast.Token{}, first[1:]...)
+			for i, t := range sides {
+				if t.Kind != css_lexer.TIdent {
+					return nil, false
+				}
+				switch strings.ToLower(t.Text) {
+				case "left":
+					sides[i].Text = "right"
+				case "right":
+					sides[i].Text = "left"
+				case "top":
+					sides[i].Text = "bottom"
+				case "bottom":
+					sides[i].Text = "top"
+				default:
+					return nil, false
+				}
+			}
+			sides[0].Whitespace &= ^css_ast.WhitespaceBefore
+			return append(sides, rest...), true
+		}
+
+		// There is no direction, which means the default direction of "to bottom"
+		return tokens, true
+	}
+
+	// Radial gradients: "shape size at position" => "position, shape size"
+	at := -1
+	for i, t := range first {
+		if t.Kind == css_lexer.TIdent && strings.EqualFold(t.Text, "at") {
+			at = i
+			break
+		}
+	}
+	if at == -1 {
+		return tokens, true
+	}
+	shape, position := first[:at], first[at+1:]
+	if len(position) == 0 {
+		return nil, false
+	}
+	result := make([]css_ast.Token, 0, len(tokens)+1)
+	result = append(result, position...)
+	result[0].Whitespace &= ^css_ast.WhitespaceBefore
+	result[len(result)-1].Whitespace &= ^css_ast.WhitespaceAfter
+	if len(shape) > 0 {
+		result = append(result, p.commaToken(loc))
+		result = append(result, shape...)
+		result[len(result)-1].Whitespace &= ^css_ast.WhitespaceAfter
+	}
+	return append(result, rest...), true
+}
+
+func angleInDegrees(token css_ast.Token) (float64, bool) {
+	value, err := strconv.ParseFloat(token.DimensionValue(), 64)
+	if err != nil {
+		return 0, false
+	}
+	switch strings.ToLower(token.DimensionUnit()) {
+	case "deg":
+		return value, true
+	case "grad":
+		return value * 0.9, true
+	case "rad":
+		return value * (180 / math.Pi), true
+	case "turn":
+		return value * 360, true
+	}
+	return 0, false
+}
@@ -0,0 +1,247 @@
+package css_parser
+
+import (
+	"fmt"
+
+	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/css_lexer"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This removes "@scope" rules for browsers that don't support them. Each
+// style rule inside the scope is rewritten to be a descendant of the scope
+// root, and each scope limit is excluded using ":not()":
+//
+//	@scope (.card) to (.content) {
+//	  img { border: 1px solid }
+//	}
+//
+// becomes:
+//
+//	.card img:not(.card .content *, .card .content) {
+//	  border: 1px solid;
+//	}
+//
+// This is an approximation. The scope proximity part of the cascade can't be
+// represented, and the specificity of the scope root is added to every rule.
+func (p *parser) lowerAtScopeInRules(rules []css_ast.Rule) []css_ast.Rule {
+	result := make([]css_ast.Rule, 0, len(rules))
+	for _, rule := range rules {
+		switch r := rule.Data.(type) {
+		case *css_ast.RKnownAt:
+			if r.Rules != nil {
+				r.Rules = p.lowerAtScopeInRules(r.Rules)
+			}
+
+		case *css_ast.RAtLayer:
+			if r.Rules != nil {
+				r.Rules = p.lowerAtScopeInRules(r.Rules)
+			}
+
+		case *css_ast.RSelector:
+			r.Rules = p.lowerAtScopeInNestedRules(r.Rules)
+
+		case *css_ast.RAtScope:
+			// Lower any nested "@scope" rules first
+			r.Rules = p.lowerAtScopeInRules(r.Rules)
+
+			// A top-level "@scope" rule without a prelude is scoped to the parent
+			// of the "<style>" element that contains it, which can't be expressed
+			// using a selector
+			if r.Start == nil {
+				p.reportUnsupportedAtScope(rule.Loc)
+				break
+			}
+
+			result = append(result, p.lowerScopedRules(r.Start, r.End, r.Rules, rule.Loc)...)
+			continue
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+// Nested "@scope" rules are rewritten in place. A missing scope root refers
+// to the parent style rule, which is "&" here.
+func (p *parser) lowerAtScopeInNestedRules(rules []css_ast.Rule) []css_ast.Rule {
+	var result []css_ast.Rule
+	for i, rule := range rules {
+		switch r := rule.Data.(type) {
+		case *css_ast.RKnownAt:
+			if r.Rules != nil {
+				r.Rules = p.lowerAtScopeInNestedRules(r.Rules)
+			}
+
+		case *css_ast.RAtLayer:
+			if r.Rules != nil {
+				r.Rules = p.lowerAtScopeInNestedRules(r.Rules)
+			}
+
+		case *css_ast.RSelector:
+			r.Rules = p.lowerAtScopeInNestedRules(r.Rules)
+
+		case *css_ast.RAtScope:
+			if result == nil {
+				result = append(make([]css_ast.Rule, 0, len(rules)), rules[:i]...)
+			}
+			start := r.Start
+			if start == nil {
+				start = []css_ast.ComplexSelector{{Selectors: []css_ast.CompoundSelector{{NestingSelectorLocs: []logger.Loc{rule.Loc}}}}}
+			}
+
+			// "@scope (.a) { color: red }" => "@scope (.a) { :scope { color: red } }"
+			var body []css_ast.Rule
+			var decls []css_ast.Rule
+			for _, child := range p.lowerAtScopeInNestedRules(r.Rules) {
+				if _, ok := child.Data.(*css_ast.RDeclaration); ok {
+					decls = append(decls, child)
+				} else {
+					body = append(body, child)
+				}
+			}
+			if len(decls) > 0 {
+				scope := css_ast.ComplexSelector{Selectors: []css_ast.CompoundSelector{{
+					SubclassSelectors: []css_ast.SubclassSelector{{
+						Range: logger.Range{Loc: rule.Loc},
+						Data:  &css_ast.SSPseudoClass{Name: "scope"},
+					}},
+				}}}
+				body = append([]css_ast.Rule{{Loc: rule.Loc, Data: &css_ast.RSelector{
+					Selectors: []css_ast.ComplexSelector{scope},
+					Rules:     decls,
+				}}}, body...)
+			}
+
+			result = append(result, p.lowerScopedRules(start, r.End, body, rule.Loc)...)
+			continue
+		}
+		if result != nil {
+			result = append(result, rule)
+		}
+	}
+	if result == nil {
+		return rules
+	}
+	return result
+}
+
+func (p *parser) lowerScopedRules(start []css_ast.ComplexSelector, end []css_ast.ComplexSelector, rules []css_ast.Rule, loc logger.Loc) []css_ast.Rule {
+	result := make([]css_ast.Rule, 0, len(rules))
+	for _, rule := range rules {
+		switch r := rule.Data.(type) {
+		case *css_ast.RKnownAt:
+			if r.Rules != nil {
+				r.Rules = p.lowerScopedRules(start, end, r.Rules, loc)
+			}
+
+		case *css_ast.RAtLayer:
+			if r.Rules != nil {
+				r.Rules = p.lowerScopedRules(start, end, r.Rules, loc)
+			}
+
+		case *css_ast.RSelector:
+			// Every selector is combined with every scope root
+			selectors := make([]css_ast.ComplexSelector, 0, len(start)*len(r.Selectors))
+			for _, root := range start {
+				for _, sel := range r.Selectors {
+					selectors = append(selectors, p.scopeComplexSelector(sel, root, end, rule.Loc))
+				}
+			}
+			r.Selectors = selectors
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+func (p *parser) scopeComplexSelector(sel css_ast.ComplexSelector, root css_ast.ComplexSelector, end []css_ast.ComplexSelector, loc logger.Loc) css_ast.ComplexSelector {
+	// ":scope" refers to the scope root, so treat it the same as "&"
+	sel = sel.Clone()
+	replaceScopeWithNestingSelector(sel.Selectors)
+
+	// "@scope (.a) { .b {} }" => ".a .b {}"
+	// "@scope (.a) { > .b {} }" => ".a > .b {}"
+	if sel.IsRelative() {
+		sel.Selectors = append([]css_ast.CompoundSelector{{NestingSelectorLocs: []logger.Loc{loc}}}, sel.Selectors...)
+	}
+
+	replacementFn := func(logger.Loc) css_ast.ComplexSelector {
+		return root.Clone()
+	}
+	substituted := make([]css_ast.CompoundSelector, 0, len(sel.Selectors))
+	for _, compound := range sel.Selectors {
+		substituted = p.substituteAmpersandsInCompoundSelector(compound, replacementFn, substituted, keepLeadingCombinator)
+	}
+
+	// "@scope (.a) to (.b) { .c {} }" => ".a .c:not(.a .b *, .a .b) {}"
+	if len(end) > 0 {
+		limits := make([]css_ast.ComplexSelector, 0, 2*len(end))
+		for _, limit := range end {
+			scopedLimit := p.scopeComplexSelector(limit, root, nil, loc)
+			inside := scopedLimit.Clone()
+			inside.Selectors = append(inside.Selectors, css_ast.CompoundSelector{
+				TypeSelector: &css_ast.NamespacedName{Name: css_ast.NameToken{Kind: css_lexer.TDelimAsterisk, Text: "*"}},
+			})
+			limits = append(limits, inside, scopedLimit)
+		}
+		last := &substituted[len(substituted)-1]
+
+		// Pseudo-elements must come last, so insert before any pseudo-elements
+		insertAt := len(last.SubclassSelectors)
+		for i, ss := range last.SubclassSelectors {
+			if pseudo, ok := ss.Data.(*css_ast.SSPseudoClass); ok && (pseudo.IsElement || isLegacyPseudoElement(pseudo.Name)) {
+				insertAt = i
+				break
+			}
+		}
+
+		subclass := make([]css_ast.SubclassSelector, 0, len(last.SubclassSelectors)+1)
+		subclass = append(subclass, last.SubclassSelectors[:insertAt]...)
+		subclass = append(subclass, css_ast.SubclassSelector{
+			Range: logger.Range{Loc: loc},
+			Data: &css_ast.SSPseudoClassWithSelectorList{
+				Kind:      css_ast.PseudoClassNot,
+				Selectors: limits,
+			},
+		})
+		subclass = append(subclass, last.SubclassSelectors[insertAt:]...)
+		last.SubclassSelectors = subclass
+	}
+
+	return css_ast.ComplexSelector{Selectors: substituted}
+}
+
+func replaceScopeWithNestingSelector(compounds []css_ast.CompoundSelector) {
+	for i := range compounds {
+		compound := &compounds[i]
+		n := 0
+		for _, ss := range compound.SubclassSelectors {
+			switch s := ss.Data.(type) {
+			case *css_ast.SSPseudoClass:
+				if s.Name == "scope" && !s.IsElement && len(s.Args) == 0 {
+					compound.NestingSelectorLocs = append(compound.NestingSelectorLocs, ss.Range.Loc)
+					continue
+				}
+
+			case *css_ast.SSPseudoClassWithSelectorList:
+				for _, inner := range s.Selectors {
+					replaceScopeWithNestingSelector(inner.Selectors)
+				}
+			}
+			compound.SubclassSelectors[n] = ss
+			n++
+		}
+		compound.SubclassSelectors = compound.SubclassSelectors[:n]
+	}
+}
+
+func (p *parser) reportUnsupportedAtScope(loc logger.Loc) {
+	text := "Transforming this \"@scope\" rule is not supported in the configured target environment"
+	if p.options.originalTargetEnv != "" {
+		text = fmt.Sprintf("%s (%s)", text, p.options.originalTargetEnv)
+	}
+	r := logger.Range{Loc: loc, Len: int32(len("@scope"))}
+	p.log.AddIDWithNotes(logger.MsgID_CSS_UnsupportedAtScope, logger.Warning, &p.tracker, r, text, []logger.MsgData{{
+		Text: "A \"@scope\" rule without a scope root can only be transformed when it's nested inside a style rule.",
+	}})
+}
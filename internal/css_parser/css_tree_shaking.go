@@ -0,0 +1,138 @@
+package css_parser
+
+import (
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/css_ast"
+)
+
+// This removes selectors that can never match because they require a local
+// name (i.e. a class or an id) that is never used, and then removes any style
+// rules that end up without selectors. For example, if "b" is unused:
+//
+//	.a, .b { color: red }
+//	.b:hover { color: blue }
+//
+// becomes:
+//
+//	.a { color: red }
+//
+// Names inside pseudo-classes such as ":not()" are ignored since a missing
+// name can cause those to match. The rules passed in may be shared with other
+// output files, so any rule that needs to change is cloned instead of being
+// modified in place.
+func RemoveRulesWithUnusedLocalNames(rules []css_ast.Rule, unused map[ast.Ref]bool) []css_ast.Rule {
+	rules, _ = removeRulesWithUnusedLocalNames(rules, unused)
+	return rules
+}
+
+func removeRulesWithUnusedLocalNames(rules []css_ast.Rule, unused map[ast.Ref]bool) ([]css_ast.Rule, bool) {
+	var result []css_ast.Rule
+	didChange := false
+
+	for i, rule := range rules {
+		remove := false
+
+		switch data := rule.Data.(type) {
+		case *css_ast.RSelector:
+			selectors, selectorsChanged := removeSelectorsWithUnusedLocalNames(data.Selectors, unused)
+			if len(selectors) == 0 {
+				remove = true
+				break
+			}
+			childRules, childrenChanged := removeRulesWithUnusedLocalNames(data.Rules, unused)
+			if childrenChanged && len(childRules) == 0 {
+				remove = true
+				break
+			}
+			if selectorsChanged || childrenChanged {
+				clone := *data
+				clone.Selectors = selectors
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RKnownAt:
+			if childRules, ok := removeRulesWithUnusedLocalNames(data.Rules, unused); ok {
+				if len(childRules) == 0 && atKnownRuleCanBeRemovedIfEmpty[data.AtToken] {
+					remove = true
+					break
+				}
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RAtScope:
+			if childRules, ok := removeRulesWithUnusedLocalNames(data.Rules, unused); ok {
+				if len(childRules) == 0 {
+					remove = true
+					break
+				}
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+
+		case *css_ast.RAtLayer:
+			// Empty "@layer" blocks are kept since they affect the layer order
+			if childRules, ok := removeRulesWithUnusedLocalNames(data.Rules, unused); ok {
+				clone := *data
+				clone.Rules = childRules
+				rule.Data = &clone
+			}
+		}
+
+		if !didChange && (remove || rule.Data != rules[i].Data) {
+			result = append(make([]css_ast.Rule, 0, len(rules)), rules[:i]...)
+			didChange = true
+		}
+		if didChange && !remove {
+			result = append(result, rule)
+		}
+	}
+
+	if didChange {
+		return result, true
+	}
+	return rules, false
+}
+
+func removeSelectorsWithUnusedLocalNames(selectors []css_ast.ComplexSelector, unused map[ast.Ref]bool) ([]css_ast.ComplexSelector, bool) {
+	var result []css_ast.ComplexSelector
+	didChange := false
+
+	for i, sel := range selectors {
+		if complexSelectorUsesUnusedLocalName(sel, unused) {
+			if !didChange {
+				result = append(make([]css_ast.ComplexSelector, 0, len(selectors)), selectors[:i]...)
+				didChange = true
+			}
+		} else if didChange {
+			result = append(result, sel)
+		}
+	}
+
+	if didChange {
+		return result, true
+	}
+	return selectors, false
+}
+
+func complexSelectorUsesUnusedLocalName(sel css_ast.ComplexSelector, unused map[ast.Ref]bool) bool {
+	for _, compound := range sel.Selectors {
+		for _, ss := range compound.SubclassSelectors {
+			switch s := ss.Data.(type) {
+			case *css_ast.SSClass:
+				if unused[s.Name.Ref] {
+					return true
+				}
+
+			case *css_ast.SSHash:
+				if unused[s.Name.Ref] {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -88,6 +88,18 @@ func Print(tree css_ast.AST, symbols ast.SymbolMap, options Options) PrintResult
 	return result
 }
 
+// This prints a list of tokens by itself. It's used for the values of ICSS
+// exports, which are exposed to JavaScript as strings.
+func PrintTokens(tokens []css_ast.Token, importRecords []ast.ImportRecord, symbols ast.SymbolMap, options Options) string {
+	p := printer{
+		options:       options,
+		symbols:       symbols,
+		importRecords: importRecords,
+	}
+	p.printTokens(tokens, printTokensOpts{})
+	return string(p.css)
+}
+
 func (p *printer) recordImportPathForMetafile(importRecordIndex uint32) {
 	if p.options.NeedsMetafile {
 		record := p.importRecords[importRecordIndex]
@@ -263,6 +275,36 @@ func (p *printer) printRule(rule css_ast.Rule, indent int32, omitTrailingSemicol
 			p.printRuleBlock(r.Rules, indent, r.CloseBraceLoc)
 		}
 
+	case *css_ast.RAtCustomMedia:
+		p.print("@custom-media ")
+		p.printIdent(r.Name, identNormal, mayNeedWhitespaceAfter)
+
+		// Always print a space here since "--name(" would be a function token
+		p.print(" ")
+		p.printTokens(r.Prelude, printTokensOpts{})
+		p.print(";")
+
+	case *css_ast.RAtScope:
+		p.print("@scope")
+		if r.Start != nil {
+			if !p.options.MinifyWhitespace {
+				p.print(" ")
+			}
+			p.print("(")
+			p.printComplexSelectors(r.Start, indent, layoutSingleLine)
+			p.print(")")
+		}
+		if r.End != nil {
+			// Always print a space here since "to(" would be a function token
+			p.print(" to (")
+			p.printComplexSelectors(r.End, indent, layoutSingleLine)
+			p.print(")")
+		}
+		if !p.options.MinifyWhitespace {
+			p.print(" ")
+		}
+		p.printRuleBlock(r.Rules, indent, r.CloseBraceLoc)
+
 	case *css_ast.RUnknownAt:
 		p.print("@")
 		whitespace := mayNeedWhitespaceAfter
@@ -956,7 +998,7 @@ func functionMultiLineCommaPeriod(token css_ast.Token) uint8 {
 			}
 		}
 
-		switch strings.ToLower(token.Text) {
+		switch strings.ToLower(withoutVendorPrefix(token.Text)) {
 		case "linear-gradient", "radial-gradient", "conic-gradient",
 			"repeating-linear-gradient", "repeating-radial-gradient", "repeating-conic-gradient":
 			if commaCount >= 2 {
@@ -977,6 +1019,17 @@ func functionMultiLineCommaPeriod(token css_ast.Token) uint8 {
 	return 0
 }
 
+// Prefixed gradients such as "-webkit-linear-gradient()" are printed the same
+// way as the unprefixed ones
+func withoutVendorPrefix(name string) string {
+	if len(name) > 1 && name[0] == '-' && name[1] != '-' {
+		if end := strings.IndexByte(name[1:], '-'); end != -1 {
+			return name[end+2:]
+		}
+	}
+	return name
+}
+
 func (p *printer) printTokens(tokens []css_ast.Token, opts printTokensOpts) bool {
 	hasWhitespaceAfter := len(tokens) > 0 && (tokens[0].Whitespace&css_ast.WhitespaceBefore) != 0
 
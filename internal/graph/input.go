@@ -11,6 +11,7 @@ import (
 	"github.com/evanw/esbuild/internal/ast"
 	"github.com/evanw/esbuild/internal/config"
 	"github.com/evanw/esbuild/internal/css_ast"
+	"github.com/evanw/esbuild/internal/html_ast"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/resolver"
@@ -43,6 +44,28 @@ type OutputFile struct {
 	AbsPath      string
 	Contents     []byte
 	IsExecutable bool
+
+	// This is only present for JavaScript entry point chunks when hot module
+	// replacement is enabled. The development server uses it to send the code
+	// for the modules that changed instead of reloading the whole page.
+	HMR *HMRChunk
+}
+
+type HMRChunk struct {
+	// The id of the entry point module. Each chunk only applies updates that
+	// are addressed to its own entry point.
+	EntryID string
+
+	// This is a hash of everything in the chunk except for the code of each
+	// module. Updates can only be applied at run-time if this hasn't changed.
+	ShellHash uint64
+
+	Modules []HMRModule
+}
+
+type HMRModule struct {
+	ID   string
+	Code []byte
 }
 
 type SideEffects struct {
@@ -117,6 +140,14 @@ func (repr *CSSRepr) ImportRecords() *[]ast.ImportRecord {
 	return &repr.AST.ImportRecords
 }
 
+type HTMLRepr struct {
+	AST html_ast.AST
+}
+
+func (repr *HTMLRepr) ImportRecords() *[]ast.ImportRecord {
+	return &repr.AST.ImportRecords
+}
+
 type CopyRepr struct {
 	// The URL that replaces the contents of any import record paths for this file
 	URLForCode string
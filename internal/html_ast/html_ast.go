@@ -0,0 +1,39 @@
+package html_ast
+
+import (
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// HTML files are not transformed by esbuild. The only thing that's parsed is
+// the set of tags that reference other files that esbuild knows how to bundle.
+// Everything else is copied to the output verbatim, so the AST just stores the
+// locations of the tags and attribute values that need to be rewritten.
+
+type AST struct {
+	ImportRecords []ast.ImportRecord
+	Tags          []Tag
+}
+
+type TagKind uint8
+
+const (
+	// A "<script type="module" src="...">" tag
+	TagModuleScript TagKind = iota
+
+	// A "<link rel="stylesheet" href="...">" tag
+	TagStylesheet
+)
+
+type Tag struct {
+	// This is the range of the whole opening tag. Any additional tags generated
+	// by the linker (e.g. preload hints) are inserted in front of this.
+	Range logger.Range
+
+	// This is the range of the attribute value including any quotes. It's
+	// replaced by the quoted path to the output file for the import record.
+	ValueRange logger.Range
+
+	ImportRecordIndex uint32
+	Kind              TagKind
+}
@@ -0,0 +1,224 @@
+package html_parser
+
+import (
+	"html"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/html_ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// This isn't a full HTML parser. It's just a tokenizer that's good enough to
+// find the tags that reference other files. It skips over comments and the
+// contents of raw text elements such as "<script>" and "<style>" so that tags
+// inside them aren't mistaken for real tags.
+
+type parser struct {
+	source logger.Source
+	text   string
+	ast    html_ast.AST
+}
+
+type attribute struct {
+	name       string
+	value      string
+	valueRange logger.Range
+	hasValue   bool
+}
+
+func Parse(source logger.Source) html_ast.AST {
+	p := parser{
+		source: source,
+		text:   source.Contents,
+	}
+	p.parse()
+	return p.ast
+}
+
+func (p *parser) parse() {
+	i := 0
+	for {
+		lt := strings.IndexByte(p.text[i:], '<')
+		if lt < 0 {
+			return
+		}
+		i += lt
+		rest := p.text[i:]
+
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				return
+			}
+			i += 4 + end + 3
+
+		case strings.HasPrefix(rest, "<!"), strings.HasPrefix(rest, "<?"), strings.HasPrefix(rest, "</"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				return
+			}
+			i += end + 1
+
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			i = p.parseTag(i)
+
+		default:
+			i++
+		}
+	}
+}
+
+func (p *parser) parseTag(start int) int {
+	i := start + 1
+	nameStart := i
+	for i < len(p.text) && !isWhitespace(p.text[i]) && p.text[i] != '/' && p.text[i] != '>' {
+		i++
+	}
+	name := strings.ToLower(p.text[nameStart:i])
+
+	// Parse the attributes
+	var attrs []attribute
+	for {
+		for i < len(p.text) && (isWhitespace(p.text[i]) || p.text[i] == '/') {
+			i++
+		}
+		if i >= len(p.text) {
+			return i
+		}
+		if p.text[i] == '>' {
+			i++
+			break
+		}
+
+		var attr attribute
+		attrStart := i
+		for i < len(p.text) && !isWhitespace(p.text[i]) && p.text[i] != '/' && p.text[i] != '>' && p.text[i] != '=' {
+			i++
+		}
+		if i == attrStart {
+			// Skip over a stray "=" character
+			i++
+			continue
+		}
+		attr.name = strings.ToLower(p.text[attrStart:i])
+
+		// Parse the optional value
+		j := i
+		for j < len(p.text) && isWhitespace(p.text[j]) {
+			j++
+		}
+		if j < len(p.text) && p.text[j] == '=' {
+			j++
+			for j < len(p.text) && isWhitespace(p.text[j]) {
+				j++
+			}
+			valueStart := j
+			if j < len(p.text) && (p.text[j] == '"' || p.text[j] == '\'') {
+				quote := p.text[j]
+				end := strings.IndexByte(p.text[j+1:], quote)
+				if end < 0 {
+					return len(p.text)
+				}
+				attr.value = p.text[j+1 : j+1+end]
+				j += end + 2
+			} else {
+				for j < len(p.text) && !isWhitespace(p.text[j]) && p.text[j] != '>' {
+					j++
+				}
+				attr.value = p.text[valueStart:j]
+			}
+			attr.value = html.UnescapeString(attr.value)
+			attr.valueRange = logger.Range{Loc: logger.Loc{Start: int32(valueStart)}, Len: int32(j - valueStart)}
+			attr.hasValue = true
+			i = j
+		}
+		attrs = append(attrs, attr)
+	}
+	tagRange := logger.Range{Loc: logger.Loc{Start: int32(start)}, Len: int32(i - start)}
+
+	switch name {
+	case "script":
+		if typeAttr, ok := findAttribute(attrs, "type"); ok && strings.EqualFold(strings.TrimSpace(typeAttr.value), "module") {
+			if src, ok := findAttribute(attrs, "src"); ok && src.hasValue && src.value != "" {
+				p.addTag(html_ast.TagModuleScript, ast.ImportHTMLScript, tagRange, src)
+			}
+		}
+
+	case "link":
+		if rel, ok := findAttribute(attrs, "rel"); ok && hasToken(rel.value, "stylesheet") {
+			if href, ok := findAttribute(attrs, "href"); ok && href.hasValue && href.value != "" {
+				p.addTag(html_ast.TagStylesheet, ast.ImportHTMLLink, tagRange, href)
+			}
+		}
+	}
+
+	// Skip over the contents of elements that can't contain other tags
+	switch name {
+	case "script", "style", "textarea", "title":
+		if end := indexOfClosingTag(p.text[i:], name); end >= 0 {
+			i += end
+		} else {
+			i = len(p.text)
+		}
+	}
+
+	return i
+}
+
+func (p *parser) addTag(kind html_ast.TagKind, importKind ast.ImportKind, tagRange logger.Range, attr attribute) {
+	importRecordIndex := uint32(len(p.ast.ImportRecords))
+	p.ast.ImportRecords = append(p.ast.ImportRecords, ast.ImportRecord{
+		Kind:  importKind,
+		Path:  logger.Path{Text: attr.value},
+		Range: attr.valueRange,
+	})
+	p.ast.Tags = append(p.ast.Tags, html_ast.Tag{
+		Kind:              kind,
+		Range:             tagRange,
+		ValueRange:        attr.valueRange,
+		ImportRecordIndex: importRecordIndex,
+	})
+}
+
+func findAttribute(attrs []attribute, name string) (attribute, bool) {
+	for _, attr := range attrs {
+		if attr.name == name {
+			return attr, true
+		}
+	}
+	return attribute{}, false
+}
+
+func hasToken(value string, token string) bool {
+	for _, field := range strings.Fields(value) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOfClosingTag(text string, name string) int {
+	for i := 0; ; {
+		lt := strings.Index(text[i:], "</")
+		if lt < 0 {
+			return -1
+		}
+		i += lt
+		if end := i + 2 + len(name); end <= len(text) && strings.EqualFold(text[i+2:end], name) &&
+			(end == len(text) || isWhitespace(text[end]) || text[end] == '>' || text[end] == '/') {
+			return i
+		}
+		i += 2
+	}
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,60 @@
+package html_parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/html_ast"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func expectTags(t *testing.T, contents string, expected string) {
+	t.Helper()
+	t.Run(contents, func(t *testing.T) {
+		t.Helper()
+		tree := Parse(test.SourceForTest(contents))
+		var sb strings.Builder
+		for _, tag := range tree.Tags {
+			kind := "script"
+			if tag.Kind == html_ast.TagStylesheet {
+				kind = "stylesheet"
+			}
+			record := tree.ImportRecords[tag.ImportRecordIndex]
+			value := contents[tag.ValueRange.Loc.Start:tag.ValueRange.End()]
+			sb.WriteString(fmt.Sprintf("%s %q %s\n", kind, record.Path.Text, value))
+		}
+		test.AssertEqualWithDiff(t, sb.String(), expected)
+	})
+}
+
+func TestScriptTags(t *testing.T) {
+	expectTags(t, `<script type="module" src="./a.js"></script>`, "script \"./a.js\" \"./a.js\"\n")
+	expectTags(t, `<SCRIPT TYPE=MODULE SRC=a.js></SCRIPT>`, "script \"a.js\" a.js\n")
+	expectTags(t, `<script type='module' src = 'a.js' defer></script>`, "script \"a.js\" 'a.js'\n")
+	expectTags(t, `<script src="a.js" type=" module "/>`, "script \"a.js\" \"a.js\"\n")
+	expectTags(t, `<script src="a.js?x=1&amp;y=2" type="module"></script>`, "script \"a.js?x=1&y=2\" \"a.js?x=1&amp;y=2\"\n")
+
+	// Only module scripts with a "src" attribute are bundled
+	expectTags(t, `<script src="a.js"></script>`, "")
+	expectTags(t, `<script type="text/javascript" src="a.js"></script>`, "")
+	expectTags(t, `<script type="module">import "./a.js"</script>`, "")
+	expectTags(t, `<script type="module" src=""></script>`, "")
+}
+
+func TestLinkTags(t *testing.T) {
+	expectTags(t, `<link rel="stylesheet" href="a.css">`, "stylesheet \"a.css\" \"a.css\"\n")
+	expectTags(t, `<link href="a.css" rel="preload stylesheet"/>`, "stylesheet \"a.css\" \"a.css\"\n")
+	expectTags(t, `<link rel="icon" href="a.ico">`, "")
+	expectTags(t, `<link rel="stylesheet">`, "")
+}
+
+func TestSkippedContent(t *testing.T) {
+	expectTags(t, `<!-- <script type="module" src="a.js"></script> -->`, "")
+	expectTags(t, `<script>"<script type='module' src='a.js'></script>"</script>`, "")
+	expectTags(t, `<style>/* <link rel="stylesheet" href="a.css"> */</style>`, "")
+	expectTags(t, `<textarea><link rel="stylesheet" href="a.css"></textarea>`, "")
+	expectTags(t, `<div title="<link rel='stylesheet' href='a.css'>"></div>`, "")
+	expectTags(t, `<script></scripts><link rel="stylesheet" href="a.css"></script><link rel="stylesheet" href="b.css">`,
+		"stylesheet \"b.css\" \"b.css\"\n")
+}
@@ -1589,6 +1589,11 @@ type AST struct {
 	NamedExports            map[string]NamedExport
 	ExportStarImportRecords []uint32
 
+	// This tracks the static property accesses on each import item, such as
+	// "styles.button" for "import styles from './styles.css'". It's used to
+	// remove CSS rules for local names that are never read from JavaScript.
+	ImportItemPropertyAccesses map[ast.Ref]ImportItemPropertyAccesses
+
 	SourceMapComment logger.Span
 
 	// This is a list of ES6 features. They are ranges instead of booleans so
@@ -1601,6 +1606,11 @@ type AST struct {
 	ModuleRef  ast.Ref
 	WrapperRef ast.Ref
 
+	// This is the symbol that "import.meta.hot" was replaced with when hot
+	// module replacement is enabled. It's an argument of the wrapper closure.
+	// It will be "ast.InvalidRef" if "import.meta.hot" was never used.
+	HotRef ast.Ref
+
 	ApproximateLineCount  int32
 	NestedScopeSlotCounts ast.SlotCounts
 	HasLazyExport         bool
@@ -1720,6 +1730,15 @@ type NamedImport struct {
 	IsExported bool
 }
 
+type ImportItemPropertyAccesses struct {
+	Names map[string]bool
+
+	// If this is equal to the use count of the import item symbol, then the
+	// import item is only ever used to read these properties. Otherwise it may
+	// escape (e.g. "{ ...styles }" or "styles[key]").
+	Count uint32
+}
+
 type NamedExport struct {
 	Ref      ast.Ref
 	AliasLoc logger.Loc
@@ -15,6 +15,7 @@ import (
 	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/js_regexp"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/renamer"
 	"github.com/evanw/esbuild/internal/runtime"
@@ -68,6 +69,18 @@ type parser struct {
 	// For strict mode handling
 	hoistedRefForSloppyModeBlockFn map[ast.Ref]ast.Ref
 
+	// Block-scoped symbols that become top-level "var" symbols when "let" and
+	// "const" are unsupported
+	blockScopedTopLevelRefs map[ast.Ref]bool
+
+	// For lowering loops with closures that capture block-scoped symbols
+	capturedBlockScopedRefs map[ast.Ref]bool
+	loweredLoopScopes       map[*js_ast.Scope]bool
+
+	// The number of statements at the start of a function body that came from
+	// lowering its arguments. Class field initializers must come after these.
+	loweredArgStmtCounts map[*js_ast.SBlock]int
+
 	// For lowering private methods
 	privateGetters map[ast.Ref]ast.Ref
 	privateSetters map[ast.Ref]ast.Ref
@@ -124,6 +137,7 @@ type parser struct {
 	isImportItem            map[ast.Ref]bool
 	namedImports            map[ast.Ref]js_ast.NamedImport
 	namedExports            map[string]js_ast.NamedExport
+	importItemProperties    map[ast.Ref]js_ast.ImportItemPropertyAccesses
 	topLevelSymbolToParts   map[ast.Ref][]uint32
 	importNamespaceCCMap    map[importNamespaceCall]bool
 
@@ -226,6 +240,7 @@ type parser struct {
 	requireRef    ast.Ref
 	moduleRef     ast.Ref
 	importMetaRef ast.Ref
+	hotRef        ast.Ref
 	promiseRef    ast.Ref
 	regExpRef     ast.Ref
 	bigIntRef     ast.Ref
@@ -236,6 +251,11 @@ type parser struct {
 	jsxRuntimeImports map[string]ast.LocRef
 	jsxLegacyImports  map[string]ast.LocRef
 
+	// Imports from the module in the "bigintLibrary" option, and "const"
+	// variables that are known to hold big integers from that library
+	bigIntLibraryImports map[string]ast.LocRef
+	bigIntConstRefs      map[ast.Ref]bool
+
 	// For lowering private methods
 	weakMapRef ast.Ref
 	weakSetRef ast.Ref
@@ -467,6 +487,7 @@ type Options struct {
 
 type optionsThatSupportStructuralEquality struct {
 	originalTargetEnv                 string
+	bigIntLibrary                     string
 	moduleTypeData                    js_ast.ModuleTypeData
 	unsupportedJSFeatures             compat.JSFeature
 	unsupportedJSFeatureOverrides     compat.JSFeature
@@ -490,6 +511,8 @@ type optionsThatSupportStructuralEquality struct {
 	treeShaking            bool
 	dropDebugger           bool
 	mangleQuoted           bool
+	hotModuleReplacement   bool
+	lowerTopLevelAwait     bool
 
 	// This is an internal-only option used for the implementation of Yarn PnP
 	decodeHydrateRuntimeStateYarnPnP bool
@@ -518,6 +541,7 @@ func OptionsFromConfig(options *config.Options) Options {
 			unsupportedJSFeatureOverrides:     options.UnsupportedJSFeatureOverrides,
 			unsupportedJSFeatureOverridesMask: options.UnsupportedJSFeatureOverridesMask,
 			originalTargetEnv:                 options.OriginalTargetEnv,
+			bigIntLibrary:                     options.BigIntLibrary,
 			ts:                                options.TS,
 			mode:                              options.Mode,
 			platform:                          options.Platform,
@@ -534,6 +558,8 @@ func OptionsFromConfig(options *config.Options) Options {
 			treeShaking:                       options.TreeShaking,
 			dropDebugger:                      options.DropDebugger,
 			mangleQuoted:                      options.MangleQuoted,
+			hotModuleReplacement:              options.HotModuleReplacement,
+			lowerTopLevelAwait:                options.LowerTopLevelAwait,
 			logPathStyle:                      options.LogPathStyle,
 			codePathStyle:                     options.CodePathStyle,
 		},
@@ -702,6 +728,12 @@ type fnOnlyDataVisit struct {
 	thisCaptureRef      *ast.Ref
 	argumentsCaptureRef *ast.Ref
 
+	// Loop bodies that may be moved into a nested function when lowering
+	// "let" and "const" reference "arguments" through this placeholder. It's
+	// linked to either "argumentsCaptureRef" or the outer "arguments" once we
+	// know whether the loop was lowered or not.
+	loopArgumentsRef *ast.Ref
+
 	// If true, we're inside a static class context where "this" expressions
 	// should be replaced with the class name.
 	shouldReplaceThisWithInnerClassNameRef bool
@@ -1126,6 +1158,12 @@ func jumpStmtsLookTheSame(left js_ast.S, right js_ast.S) bool {
 }
 
 func (p *parser) selectLocalKind(kind js_ast.LocalKind) js_ast.LocalKind {
+	// Use "var" instead of "let" and "const" if they are unsupported. Block-
+	// scoped symbols are given unique names in "hoistSymbols" to make this safe.
+	if (kind == js_ast.LocalLet || kind == js_ast.LocalConst) && p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) {
+		return js_ast.LocalVar
+	}
+
 	// Use "var" instead of "let" and "const" if the variable declaration may
 	// need to be separated from the initializer. This allows us to safely move
 	// this declaration into a nested scope.
@@ -1581,6 +1619,23 @@ func (p *parser) hoistSymbols(scope *js_ast.Scope) {
 			}
 
 			if !symbol.Kind.IsHoisted() {
+				// Block-scoped declarations are converted to "var" declarations when
+				// "let" and "const" are unsupported. Generate these symbols in the
+				// enclosing function scope so they get unique names when renaming.
+				if scope.Kind == js_ast.ScopeBlock && p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) &&
+					(symbol.Kind == ast.SymbolOther || symbol.Kind == ast.SymbolConst || symbol.Kind == ast.SymbolClass) {
+					s := scope.Parent
+					for !s.Kind.StopsHoisting() {
+						s = s.Parent
+					}
+					s.Generated = append(s.Generated, member.Ref)
+					if s == p.moduleScope {
+						if p.blockScopedTopLevelRefs == nil {
+							p.blockScopedTopLevelRefs = make(map[ast.Ref]bool)
+						}
+						p.blockScopedTopLevelRefs[member.Ref] = true
+					}
+				}
 				continue
 			}
 
@@ -1917,7 +1972,6 @@ type deferredErrors struct {
 	// These are errors for expressions
 	invalidExprDefaultValue  logger.Range
 	invalidExprAfterQuestion logger.Range
-	arraySpreadFeature       logger.Range
 
 	// These errors are for arrow functions
 	invalidParens []logger.Range
@@ -1930,9 +1984,6 @@ func (from *deferredErrors) mergeInto(to *deferredErrors) {
 	if from.invalidExprAfterQuestion.Len > 0 {
 		to.invalidExprAfterQuestion = from.invalidExprAfterQuestion
 	}
-	if from.arraySpreadFeature.Len > 0 {
-		to.arraySpreadFeature = from.arraySpreadFeature
-	}
 	if len(from.invalidParens) > 0 {
 		if len(to.invalidParens) > 0 {
 			to.invalidParens = append(to.invalidParens, from.invalidParens...)
@@ -1951,10 +2002,6 @@ func (p *parser) logExprErrors(errors *deferredErrors) {
 		r := errors.invalidExprAfterQuestion
 		p.log.AddError(&p.tracker, r, fmt.Sprintf("Unexpected %q", p.source.Contents[r.Loc.Start:r.Loc.Start+r.Len]))
 	}
-
-	if errors.arraySpreadFeature.Len > 0 {
-		p.markSyntaxFeature(compat.ArraySpread, errors.arraySpreadFeature)
-	}
 }
 
 func (p *parser) logDeferredArrowArgErrors(errors *deferredErrors) {
@@ -2142,7 +2189,6 @@ type propertyOpts struct {
 	decoratorContext decoratorContextFlags
 
 	asyncRange     logger.Range
-	generatorRange logger.Range
 	tsDeclareRange logger.Range
 	classKeyword   logger.Range
 	isAsync        bool
@@ -2192,7 +2238,6 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 
 	case js_lexer.TOpenBracket:
 		flags |= js_ast.PropertyIsComputed
-		p.markSyntaxFeature(compat.ObjectExtensions, p.lexer.Range())
 		p.lexer.Next()
 		wasIdentifier := p.lexer.Token == js_lexer.TIdentifier
 		expr := p.parseExpr(js_ast.LComma)
@@ -2226,7 +2271,6 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 			p.lexer.Unexpected()
 		}
 		opts.isGenerator = true
-		opts.generatorRange = p.lexer.Range()
 		p.lexer.Next()
 		return p.parseProperty(startLoc, js_ast.PropertyMethod, opts, errors)
 
@@ -2538,9 +2582,7 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 
 	// Parse a method expression
 	if p.lexer.Token == js_lexer.TOpenParen || kind.IsMethodDefinition() || opts.isClass {
-		hasError := false
-
-		if !hasError && opts.tsDeclareRange.Len != 0 {
+		if opts.tsDeclareRange.Len != 0 {
 			what := "method"
 			if kind == js_ast.PropertyGetter {
 				what = "getter"
@@ -2548,19 +2590,6 @@ func (p *parser) parseProperty(startLoc logger.Loc, kind js_ast.PropertyKind, op
 				what = "setter"
 			}
 			p.log.AddError(&p.tracker, opts.tsDeclareRange, "\"declare\" cannot be used with a "+what)
-			hasError = true
-		}
-
-		if opts.isAsync && p.markAsyncFn(opts.asyncRange, opts.isGenerator) {
-			hasError = true
-		}
-
-		if !hasError && opts.isGenerator && p.markSyntaxFeature(compat.Generator, opts.generatorRange) {
-			hasError = true
-		}
-
-		if !hasError && p.lexer.Token == js_lexer.TOpenParen && kind != js_ast.PropertyGetter && kind != js_ast.PropertySetter && p.markSyntaxFeature(compat.ObjectExtensions, p.lexer.Range()) {
-			hasError = true
 		}
 
 		loc := p.lexer.Loc()
@@ -3018,7 +3047,6 @@ func (p *parser) parseAsyncPrefixExpr(asyncRange logger.Range, level js_ast.L, f
 				}
 
 				if isArrowFn {
-					p.markAsyncFn(asyncRange, false)
 					ref := p.storeNameInRef(p.lexer.Identifier)
 					arg := js_ast.Arg{Binding: js_ast.Binding{Loc: p.lexer.Loc(), Data: &js_ast.BIdentifier{Ref: ref}}}
 					p.lexer.Next()
@@ -3065,14 +3093,7 @@ func (p *parser) parseAsyncPrefixExpr(asyncRange logger.Range, level js_ast.L, f
 func (p *parser) parseFnExpr(loc logger.Loc, isAsync bool, asyncRange logger.Range) js_ast.Expr {
 	p.lexer.Next()
 	isGenerator := p.lexer.Token == js_lexer.TAsterisk
-	hasError := false
-	if isAsync {
-		hasError = p.markAsyncFn(asyncRange, isGenerator)
-	}
 	if isGenerator {
-		if !hasError {
-			p.markSyntaxFeature(compat.Generator, p.lexer.Range())
-		}
 		p.lexer.Next()
 	}
 	var name *ast.LocRef
@@ -3156,7 +3177,6 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 
 		if isSpread {
 			spreadRange = p.lexer.Range()
-			p.markSyntaxFeature(compat.RestArgument, spreadRange)
 			p.lexer.Next()
 		}
 
@@ -3218,10 +3238,6 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 		var invalidLog invalidLog
 		args := []js_ast.Arg{}
 
-		if isAsync {
-			p.markAsyncFn(opts.asyncRange, false)
-		}
-
 		// First, try converting the expressions to bindings
 		for _, item := range items {
 			isSpread := false
@@ -3277,11 +3293,6 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 				panic(js_lexer.LexerPanic{})
 			}
 
-			// Also report syntax features used in bindings
-			for _, entry := range invalidLog.syntaxFeatures {
-				p.markSyntaxFeature(entry.feature, entry.token)
-			}
-
 			arrow := p.parseArrowBody(args, fnOrArrowDataParse{
 				needsAsyncLoc: loc,
 				await:         await,
@@ -3333,13 +3344,7 @@ func (p *parser) parseParenExpr(loc logger.Loc, level js_ast.L, opts parenExprOp
 }
 
 type invalidLog struct {
-	invalidTokens  []logger.Range
-	syntaxFeatures []syntaxFeature
-}
-
-type syntaxFeature struct {
-	feature compat.JSFeature
-	token   logger.Range
+	invalidTokens []logger.Range
 }
 
 func (p *parser) convertExprToBindingAndInitializer(
@@ -3351,16 +3356,8 @@ func (p *parser) convertExprToBindingAndInitializer(
 		expr = assign.Left
 	}
 	binding, invalidLog := p.convertExprToBinding(expr, invalidLog)
-	if initializerOrNil.Data != nil {
-		equalsRange := p.source.RangeOfOperatorBefore(initializerOrNil.Loc, "=")
-		if isSpread {
-			p.log.AddError(&p.tracker, equalsRange, "A rest argument cannot have a default initializer")
-		} else {
-			invalidLog.syntaxFeatures = append(invalidLog.syntaxFeatures, syntaxFeature{
-				feature: compat.DefaultArgument,
-				token:   equalsRange,
-			})
-		}
+	if initializerOrNil.Data != nil && isSpread {
+		p.log.AddError(&p.tracker, p.source.RangeOfOperatorBefore(initializerOrNil.Loc, "="), "A rest argument cannot have a default initializer")
 	}
 	return binding, initializerOrNil, invalidLog
 }
@@ -3381,17 +3378,12 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 		if e.CommaAfterSpread.Start != 0 {
 			invalidLog.invalidTokens = append(invalidLog.invalidTokens, logger.Range{Loc: e.CommaAfterSpread, Len: 1})
 		}
-		invalidLog.syntaxFeatures = append(invalidLog.syntaxFeatures,
-			syntaxFeature{feature: compat.Destructuring, token: p.source.RangeOfOperatorAfter(expr.Loc, "[")})
 		items := []js_ast.ArrayBinding{}
 		isSpread := false
 		for _, item := range e.Items {
 			if i, ok := item.Data.(*js_ast.ESpread); ok {
 				isSpread = true
 				item = i.Value
-				if _, ok := item.Data.(*js_ast.EIdentifier); !ok {
-					p.markSyntaxFeature(compat.NestedRestBinding, p.source.RangeOfOperatorAfter(item.Loc, "["))
-				}
 			}
 			binding, initializerOrNil, log := p.convertExprToBindingAndInitializer(item, invalidLog, isSpread)
 			invalidLog = log
@@ -3412,8 +3404,6 @@ func (p *parser) convertExprToBinding(expr js_ast.Expr, invalidLog invalidLog) (
 		if e.CommaAfterSpread.Start != 0 {
 			invalidLog.invalidTokens = append(invalidLog.invalidTokens, logger.Range{Loc: e.CommaAfterSpread, Len: 1})
 		}
-		invalidLog.syntaxFeatures = append(invalidLog.syntaxFeatures,
-			syntaxFeature{feature: compat.Destructuring, token: p.source.RangeOfOperatorAfter(expr.Loc, "{")})
 		properties := []js_ast.PropertyBinding{}
 		for _, property := range e.Properties {
 			if property.Kind.IsMethodDefinition() {
@@ -3825,11 +3815,6 @@ func (p *parser) parsePrefix(level js_ast.L, errors *deferredErrors, flags exprF
 				items = append(items, js_ast.Expr{Loc: p.lexer.Loc(), Data: js_ast.EMissingShared})
 
 			case js_lexer.TDotDotDot:
-				if errors != nil {
-					errors.arraySpreadFeature = p.lexer.Range()
-				} else {
-					p.markSyntaxFeature(compat.ArraySpread, p.lexer.Range())
-				}
 				dotsLoc := p.saveExprCommentsHere()
 				p.lexer.Next()
 				item := p.parseExprOrBindings(js_ast.LComma, &selfErrors)
@@ -4987,7 +4972,6 @@ func (p *parser) parseExprOrLetOrUsingStmt(opts parseStmtOpts) (js_ast.Expr, js_
 			if opts.lexicalDecl != lexicalDeclAllowAll {
 				p.forbidLexicalDecl(tokenRange.Loc)
 			}
-			p.markSyntaxFeature(compat.ConstAndLet, tokenRange)
 			decls := p.parseAndDeclareDecls(ast.SymbolOther, opts)
 			return js_ast.Expr{}, js_ast.Stmt{Loc: tokenRange.Loc, Data: &js_ast.SLocal{
 				Kind:     js_ast.LocalLet,
@@ -5067,7 +5051,6 @@ func (p *parser) parseCallArgs() (args []js_ast.Expr, closeParenLoc logger.Loc,
 		loc := p.lexer.Loc()
 		isSpread := p.lexer.Token == js_lexer.TDotDotDot
 		if isSpread {
-			p.markSyntaxFeature(compat.RestArgument, p.lexer.Range())
 			p.lexer.Next()
 		}
 		arg := p.parseExpr(js_ast.LComma)
@@ -5415,7 +5398,6 @@ func (p *parser) parseJSXElement(loc logger.Loc) js_ast.Expr {
 					// behavior. Note that TypeScript's behavior changed in TypeScript 4.5.
 					// Before that, the "..." was omitted instead of being preserved.
 					itemLoc := p.lexer.Loc()
-					p.markSyntaxFeature(compat.RestArgument, p.lexer.Range())
 					p.lexer.Next()
 					nullableChildren = append(nullableChildren, js_ast.Expr{Loc: itemLoc, Data: &js_ast.ESpread{Value: p.parseExpr(js_ast.LLowest)}})
 				} else {
@@ -5943,7 +5925,6 @@ func (p *parser) parseBinding(opts parseBindingOpts) js_ast.Binding {
 		if opts.isUsingStmt {
 			break
 		}
-		p.markSyntaxFeature(compat.Destructuring, p.lexer.Range())
 		p.lexer.Next()
 		isSingleLine := !p.lexer.HasNewlineBefore
 		items := []js_ast.ArrayBinding{}
@@ -5966,11 +5947,6 @@ func (p *parser) parseBinding(opts parseBindingOpts) js_ast.Binding {
 				if p.lexer.Token == js_lexer.TDotDotDot {
 					p.lexer.Next()
 					hasSpread = true
-
-					// This was a bug in the ES2015 spec that was fixed in ES2016
-					if p.lexer.Token != js_lexer.TIdentifier {
-						p.markSyntaxFeature(compat.NestedRestBinding, p.lexer.Range())
-					}
 				}
 
 				p.saveExprCommentsHere()
@@ -6025,7 +6001,6 @@ func (p *parser) parseBinding(opts parseBindingOpts) js_ast.Binding {
 		if opts.isUsingStmt {
 			break
 		}
-		p.markSyntaxFeature(compat.Destructuring, p.lexer.Range())
 		p.lexer.Next()
 		isSingleLine := !p.lexer.HasNewlineBefore
 		properties := []js_ast.PropertyBinding{}
@@ -6183,7 +6158,6 @@ func (p *parser) parseFn(
 		}
 
 		if !fn.HasRestArg && p.lexer.Token == js_lexer.TDotDotDot {
-			p.markSyntaxFeature(compat.RestArgument, p.lexer.Range())
 			p.lexer.Next()
 			fn.HasRestArg = true
 		}
@@ -6231,7 +6205,6 @@ func (p *parser) parseFn(
 
 		var defaultValueOrNil js_ast.Expr
 		if !fn.HasRestArg && p.lexer.Token == js_lexer.TEquals {
-			p.markSyntaxFeature(compat.DefaultArgument, p.lexer.Range())
 			p.lexer.Next()
 			defaultValueOrNil = p.parseExpr(js_ast.LComma)
 		}
@@ -6322,7 +6295,6 @@ func (p *parser) parseClassStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt
 	var name *ast.LocRef
 	classKeyword := p.lexer.Range()
 	if p.lexer.Token == js_lexer.TClass {
-		p.markSyntaxFeature(compat.Class, classKeyword)
 		p.lexer.Next()
 	} else {
 		p.lexer.Expected(js_lexer.TClass)
@@ -6372,7 +6344,6 @@ func (p *parser) parseClassStmt(loc logger.Loc, opts parseStmtOpts) js_ast.Stmt
 
 func (p *parser) parseClassExpr(decorators []js_ast.Decorator) js_ast.Expr {
 	classKeyword := p.lexer.Range()
-	p.markSyntaxFeature(compat.Class, classKeyword)
 	p.lexer.Expect(js_lexer.TClass)
 	var name *ast.LocRef
 
@@ -6683,14 +6654,7 @@ func (p *parser) maybeWarnAboutAssertKeyword(loc logger.Loc) {
 // This assumes the "function" token has already been parsed
 func (p *parser) parseFnStmt(loc logger.Loc, opts parseStmtOpts, isAsync bool, asyncRange logger.Range) js_ast.Stmt {
 	isGenerator := p.lexer.Token == js_lexer.TAsterisk
-	hasError := false
-	if isAsync {
-		hasError = p.markAsyncFn(asyncRange, isGenerator)
-	}
 	if isGenerator {
-		if !hasError {
-			p.markSyntaxFeature(compat.Generator, p.lexer.Range())
-		}
 		p.lexer.Next()
 	}
 
@@ -7425,7 +7389,6 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 		if opts.lexicalDecl != lexicalDeclAllowAll {
 			p.forbidLexicalDecl(loc)
 		}
-		p.markSyntaxFeature(compat.ConstAndLet, p.lexer.Range())
 		p.lexer.Next()
 
 		if p.options.ts.Parse && p.lexer.Token == js_lexer.TEnum {
@@ -7706,17 +7669,8 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			if p.fnOrArrowDataParse.await != allowExpr {
 				p.log.AddError(&p.tracker, awaitRange, "Cannot use \"await\" outside an async function")
 				awaitRange = logger.Range{}
-			} else {
-				didGenerateError := false
-				if p.fnOrArrowDataParse.isTopLevel {
-					p.topLevelAwaitKeyword = awaitRange
-				}
-				if !didGenerateError && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) && p.options.unsupportedJSFeatures.Has(compat.Generator) {
-					// If for-await loops aren't supported, then we only support lowering
-					// if either async/await or generators is supported. Otherwise we
-					// cannot lower for-await loops.
-					p.markSyntaxFeature(compat.ForAwait, awaitRange)
-				}
+			} else if p.fnOrArrowDataParse.isTopLevel {
+				p.topLevelAwaitKeyword = awaitRange
 			}
 			p.lexer.Next()
 		}
@@ -7745,7 +7699,6 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 			initOrNil = js_ast.Stmt{Loc: initLoc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}}
 
 		case js_lexer.TConst:
-			p.markSyntaxFeature(compat.ConstAndLet, p.lexer.Range())
 			p.lexer.Next()
 			decls = p.parseAndDeclareDecls(ast.SymbolConst, parseStmtOpts{})
 			initOrNil = js_ast.Stmt{Loc: initLoc, Data: &js_ast.SLocal{Kind: js_ast.LocalConst, Decls: decls}}
@@ -7784,7 +7737,6 @@ func (p *parser) parseStmt(opts parseStmtOpts) js_ast.Stmt {
 				}
 			}
 			p.forbidInitializers(decls, "of", false)
-			p.markSyntaxFeature(compat.ForOf, p.lexer.Range())
 			p.lexer.Next()
 			value := p.parseExpr(js_ast.LComma)
 			p.lexer.Expect(js_lexer.TCloseParen)
@@ -8588,6 +8540,7 @@ func (p *parser) findSymbol(loc logger.Loc, name string) findSymbolResult {
 	var declareLoc logger.Loc
 	isInsideWithScope := false
 	didForbidArguments := false
+	isInsideNestedFn := false
 	s := p.currentScope
 
 	for {
@@ -8607,6 +8560,19 @@ func (p *parser) findSymbol(loc logger.Loc, name string) findSymbolResult {
 		if member, ok := s.Members[name]; ok {
 			ref = member.Ref
 			declareLoc = member.Loc
+
+			// Remember block-scoped symbols that are captured by a nested function.
+			// Loops containing these need to be lowered specially when "let" and
+			// "const" are unsupported, or when they are inside a generator that's
+			// lowered to a state machine.
+			if isInsideNestedFn && s.Kind == js_ast.ScopeBlock && p.options.unsupportedJSFeatures.Has(compat.ConstAndLet|compat.Generator) {
+				if kind := p.symbols[ref.InnerIndex].Kind; kind == ast.SymbolOther || kind == ast.SymbolConst || kind == ast.SymbolClass {
+					if p.capturedBlockScopedRefs == nil {
+						p.capturedBlockScopedRefs = make(map[ast.Ref]bool)
+					}
+					p.capturedBlockScopedRefs[ref] = true
+				}
+			}
 			break
 		}
 
@@ -8635,6 +8601,9 @@ func (p *parser) findSymbol(loc logger.Loc, name string) findSymbolResult {
 			}
 		}
 
+		if s.Kind == js_ast.ScopeFunctionArgs || s.Kind == js_ast.ScopeClassBody {
+			isInsideNestedFn = true
+		}
 		s = s.Parent
 		if s == nil {
 			// Allocate an "unbound" symbol
@@ -8931,7 +8900,7 @@ func (p *parser) visitStmts(stmts []js_ast.Stmt, kind stmtsKind) []js_ast.Stmt {
 		for _, ref := range p.tempLetsToDeclare {
 			decls = append(decls, js_ast.Decl{Binding: js_ast.Binding{Data: &js_ast.BIdentifier{Ref: ref}}})
 		}
-		before = append(before, js_ast.Stmt{Data: &js_ast.SLocal{Kind: js_ast.LocalLet, Decls: decls}})
+		before = append(before, js_ast.Stmt{Data: &js_ast.SLocal{Kind: p.selectLocalKind(js_ast.LocalLet), Decls: decls}})
 	}
 	p.tempLetsToDeclare = oldTempLetsToDeclare
 
@@ -9957,7 +9926,7 @@ func (p *parser) visitForLoopInit(stmt js_ast.Stmt, isInOrOf bool) js_ast.Stmt {
 func (p *parser) recordDeclaredSymbol(ref ast.Ref) {
 	p.declaredSymbols = append(p.declaredSymbols, js_ast.DeclaredSymbol{
 		Ref:        ref,
-		IsTopLevel: p.currentScope == p.moduleScope,
+		IsTopLevel: p.currentScope == p.moduleScope || p.blockScopedTopLevelRefs[ref],
 	})
 }
 
@@ -10660,6 +10629,13 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 					}
 				}
 
+				// Remember which constants hold big integers from the big integer library
+				if s.Kind == js_ast.LocalConst && p.isKnownBigInt(d.ValueOrNil) {
+					if id, ok := d.Binding.Data.(*js_ast.BIdentifier); ok {
+						p.recordBigIntConst(id.Ref)
+					}
+				}
+
 				// Yarn's PnP data may be stored in a variable: https://github.com/yarnpkg/berry/pull/4320
 				if p.options.decodeHydrateRuntimeStateYarnPnP {
 					if str, ok := d.ValueOrNil.Data.(*js_ast.EString); ok {
@@ -10735,6 +10711,17 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 			}
 		}
 
+		// A "let" declaration without an initializer must reset the variable to
+		// "undefined" when it's converted to "var", since the same "var" may be
+		// declared again later (e.g. in the next iteration of a loop)
+		if s.Kind == js_ast.LocalLet && p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) && p.currentScope.Kind == js_ast.ScopeBlock {
+			for i := range s.Decls {
+				if d := &s.Decls[i]; d.ValueOrNil.Data == nil {
+					d.ValueOrNil = js_ast.Expr{Loc: d.Binding.Loc, Data: js_ast.EUndefinedShared}
+				}
+			}
+		}
+
 		s.Kind = p.selectLocalKind(s.Kind)
 
 		// Potentially relocate "var" declarations to the top level
@@ -10829,7 +10816,9 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 
 	case *js_ast.SWhile:
 		s.Test = p.visitExpr(s.Test)
+		closure := p.beginLoopClosure(nil, s.Body, nil)
 		s.Body = p.visitLoopBody(s.Body)
+		stmts = p.lowerLoopClosure(stmt.Loc, closure, nil, &s.Body, stmts)
 
 		if p.options.minifySyntax {
 			s.Test = p.astHelpers.SimplifyBooleanExpr(s.Test)
@@ -10847,8 +10836,10 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		}
 
 	case *js_ast.SDoWhile:
+		closure := p.beginLoopClosure(nil, s.Body, nil)
 		s.Body = p.visitLoopBody(s.Body)
 		s.Test = p.visitExpr(s.Test)
+		stmts = p.lowerLoopClosure(stmt.Loc, closure, nil, &s.Body, stmts)
 
 		if p.options.minifySyntax {
 			s.Test = p.astHelpers.SimplifyBooleanExpr(s.Test)
@@ -10900,6 +10891,7 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 
 	case *js_ast.SFor:
 		p.pushScopeForVisitPass(js_ast.ScopeBlock, stmt.Loc)
+		headRefs := lexicalLoopHeadRefs(s.InitOrNil)
 		if s.InitOrNil.Data != nil {
 			p.visitForLoopInit(s.InitOrNil, false)
 		}
@@ -10920,6 +10912,7 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		if s.UpdateOrNil.Data != nil {
 			s.UpdateOrNil = p.visitExpr(s.UpdateOrNil)
 		}
+		closure := p.beginLoopClosure(p.currentScope, s.Body, headRefs)
 		s.Body = p.visitLoopBody(s.Body)
 
 		// Potentially relocate "var" declarations to the top level. Note that this
@@ -10938,14 +10931,18 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 
 		p.popScope()
 
+		stmts = p.lowerLoopClosure(stmt.Loc, closure, headRefs, &s.Body, stmts)
+
 		if p.options.minifySyntax {
 			mangleFor(s)
 		}
 
 	case *js_ast.SForIn:
 		p.pushScopeForVisitPass(js_ast.ScopeBlock, stmt.Loc)
+		isLexicalHead := len(lexicalLoopHeadRefs(s.Init)) > 0
 		p.visitForLoopInit(s.Init, true)
 		s.Value = p.visitExpr(s.Value)
+		closure := p.beginLoopClosure(p.currentScope, s.Body, nil)
 		s.Body = p.visitLoopBody(s.Body)
 
 		// Check for a variable initializer
@@ -10974,6 +10971,7 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		p.popScope()
 
 		p.lowerObjectRestInForLoopInit(s.Init, &s.Body)
+		stmts = p.lowerLoopClosure(stmt.Loc, closure, forInOrOfClosureParams(s.Init, isLexicalHead), &s.Body, stmts)
 
 	case *js_ast.SForOf:
 		// Silently remove unsupported top-level "await" in dead code branches
@@ -10987,8 +10985,10 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		}
 
 		p.pushScopeForVisitPass(js_ast.ScopeBlock, stmt.Loc)
+		isLexicalHead := len(lexicalLoopHeadRefs(s.Init)) > 0
 		p.visitForLoopInit(s.Init, true)
 		s.Value = p.visitExpr(s.Value)
+		closure := p.beginLoopClosure(p.currentScope, s.Body, nil)
 		s.Body = p.visitLoopBody(s.Body)
 
 		// Potentially relocate "var" declarations to the top level. Note that this
@@ -11025,11 +11025,19 @@ func (p *parser) visitAndAppendStmt(stmts []js_ast.Stmt, stmt js_ast.Stmt) []js_
 		p.popScope()
 
 		p.lowerObjectRestInForLoopInit(s.Init, &s.Body)
+		stmts = p.lowerLoopClosure(stmt.Loc, closure, forInOrOfClosureParams(s.Init, isLexicalHead), &s.Body, stmts)
 
 		// Lower "for await" if it's unsupported if it's in a lowered async generator
 		if s.Await.Len > 0 && (p.options.unsupportedJSFeatures.Has(compat.ForAwait) ||
 			(p.options.unsupportedJSFeatures.Has(compat.AsyncGenerator) && p.fnOrArrowDataVisit.isGenerator)) {
-			return p.lowerForAwaitLoop(stmt.Loc, s, stmts)
+			return p.lowerForOfLoop(stmt.Loc, s, stmts)
+		}
+
+		// Lower "for-of" using the iterator protocol if it's unsupported. This is
+		// also done for loops containing "yield" in a lowered generator function.
+		if s.Await.Len == 0 && (p.options.unsupportedJSFeatures.Has(compat.ForOf) ||
+			(p.isInLoweredGenerator() && generatorStmtContainsYield(stmt))) {
+			return p.lowerForOfLoop(stmt.Loc, s, stmts)
 		}
 
 	case *js_ast.STry:
@@ -11614,10 +11622,13 @@ const (
 //
 // This is done to make it easier to traverse top-level declarations in the linker
 // during bundling. Now it is sufficient to just scan the top-level statements
-// instead of having to traverse recursively into the statement tree.
+// instead of having to traverse recursively into the statement tree. This is
+// also done for the SystemJS format, which hoists top-level declarations out
+// of the "execute" function.
 func (p *parser) maybeRelocateVarsToTopLevel(decls []js_ast.Decl, mode relocateVarsMode) (js_ast.Stmt, bool) {
-	// Only do this when bundling, and not when the scope is already top-level
-	if p.options.mode != config.ModeBundle || p.currentScope == p.moduleScope {
+	// Only do this when bundling or generating SystemJS, and not when the scope
+	// is already top-level
+	if (p.options.mode != config.ModeBundle && p.options.outputFormat != config.FormatSystem) || p.currentScope == p.moduleScope {
 		return js_ast.Stmt{}, false
 	}
 
@@ -12144,9 +12155,11 @@ func (p *parser) visitClass(nameScopeLoc logger.Loc, class *js_ast.Class, defaul
 				}
 			}
 
+			// Methods of classes lowered to ES5 functions no longer have "super"
 			property.ValueOrNil, _ = p.visitExprInOut(property.ValueOrNil, exprIn{
-				isMethod:               true,
-				isLoweredPrivateMethod: isLoweredPrivateMethod,
+				isMethod:                       true,
+				isLoweredPrivateMethod:         isLoweredPrivateMethod,
+				shouldLowerSuperPropertyAccess: p.options.unsupportedJSFeatures.Has(compat.Class),
 			})
 		}
 
@@ -12649,13 +12662,30 @@ func (p *parser) maybeRewritePropertyAccess(
 	isTemplateTag bool,
 	preferQuotedKey bool,
 ) (js_ast.Expr, bool) {
+	// Track static property accesses on import items when bundling. Like symbol
+	// use counts, these don't include accesses inside dead code regions.
+	if id, ok := target.Data.(*js_ast.EImportIdentifier); ok && p.options.mode == config.ModeBundle && !p.isControlFlowDead {
+		if p.importItemProperties == nil {
+			p.importItemProperties = make(map[ast.Ref]js_ast.ImportItemPropertyAccesses)
+		}
+		accesses := p.importItemProperties[id.Ref]
+		if accesses.Names == nil {
+			accesses.Names = make(map[string]bool)
+		}
+		accesses.Names[name] = true
+		accesses.Count++
+		p.importItemProperties[id.Ref] = accesses
+	}
+
 	if id, ok := target.Data.(*js_ast.EIdentifier); ok {
 		// Rewrite property accesses on explicit namespace imports as an identifier.
 		// This lets us replace them easily in the printer to rebind them to
 		// something else without paying the cost of a whole-tree traversal during
 		// module linking just to rewrite these EDot expressions.
 		if p.options.mode == config.ModeBundle {
-			if importItems, ok := p.importItemsForNamespace[id.Ref]; ok {
+			// Property accesses on deferred imports must go through the namespace
+			// object, since that is what causes the imported module to be evaluated
+			if importItems, ok := p.importItemsForNamespace[id.Ref]; ok && p.importRecords[importItems.importRecordIndex].Phase != ast.DeferPhase {
 				// Cache translation so each property access resolves to the same import
 				item, ok := importItems.entries[name]
 				if !ok {
@@ -12872,8 +12902,9 @@ func (p *parser) maybeRewritePropertyAccess(
 }
 
 type exprIn struct {
-	isMethod               bool
-	isLoweredPrivateMethod bool
+	isMethod                       bool
+	isLoweredPrivateMethod         bool
+	shouldLowerSuperPropertyAccess bool
 
 	// This tells us if there are optional chain expressions (EDot, EIndex, or
 	// ECall) that are chained on to this expression. Because of the way the AST
@@ -13111,10 +13142,10 @@ func containsClosingScriptTag(text string) bool {
 	return false
 }
 
-func (p *parser) isUnsupportedRegularExpression(loc logger.Loc, value string) (pattern string, flags string, isUnsupported bool) {
-	var what string
-	var r logger.Range
-
+// This returns the first unsupported feature in the regular expression, if
+// there is one. The caller is responsible for either lowering the regular
+// expression or reporting the unsupported feature.
+func (p *parser) isUnsupportedRegularExpression(loc logger.Loc, value string) (pattern string, flags string, what string, r logger.Range, isUnsupported bool) {
 	end := strings.LastIndexByte(value, '/')
 	pattern = value[1:end]
 	flags = value[end+1:]
@@ -13173,8 +13204,8 @@ pattern:
 
 		case ')':
 			if parenDepth == 0 {
-				r := logger.Range{Loc: logger.Loc{Start: loc.Start + int32(i)}, Len: 1}
-				p.log.AddError(&p.tracker, r, "Unexpected \")\" in regular expression")
+				p.log.AddError(&p.tracker, logger.Range{Loc: logger.Loc{Start: loc.Start + int32(i)}, Len: 1},
+					"Unexpected \")\" in regular expression")
 				return
 			}
 
@@ -13235,15 +13266,65 @@ pattern:
 		}
 	}
 
-	if isUnsupported {
-		where := config.PrettyPrintTargetEnvironment(p.options.originalTargetEnv, p.options.unsupportedJSFeatureOverridesMask)
-		p.log.AddIDWithNotes(logger.MsgID_JS_UnsupportedRegExp, logger.Debug, &p.tracker, r, fmt.Sprintf("%s in %s", what, where), []logger.MsgData{{
-			Text: "This regular expression literal has been converted to a \"new RegExp()\" constructor " +
-				"to avoid generating code with a syntax error. However, you will need to include a " +
-				"polyfill for \"RegExp\" for your code to have the correct behavior at run-time."}})
+	return
+}
+
+func (p *parser) lowerRegExp(loc logger.Loc, value string) (js_ast.Expr, bool) {
+	pattern, flags, what, r, ok := p.isUnsupportedRegularExpression(loc, value)
+	if !ok {
+		return js_ast.Expr{}, false
 	}
 
-	return
+	// Try to rewrite the regular expression using only supported features
+	result, problem := js_regexp.Lower(pattern, flags, p.options.unsupportedJSFeatures)
+	if problem == nil {
+		regExp := js_ast.Expr{Loc: loc, Data: &js_ast.ERegExp{Value: "/" + result.Pattern + "/" + result.Flags}}
+		if len(result.NamedGroups) == 0 {
+			return regExp, true
+		}
+
+		// "/(?<a>.)/" => "__namedGroups(/(.)/, { a: 1 })"
+		properties := make([]js_ast.Property, 0, len(result.NamedGroups))
+		for _, group := range result.NamedGroups {
+			properties = append(properties, js_ast.Property{
+				Key:        js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(group.Name)}},
+				ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: float64(group.Index)}},
+			})
+		}
+		call := p.callRuntime(loc, "__namedGroups", []js_ast.Expr{regExp, {Loc: loc, Data: &js_ast.EObject{Properties: properties}}})
+		call.Data.(*js_ast.ECall).CanBeUnwrappedIfUnused = true
+		return call, true
+	}
+
+	// Prefer the reason why lowering failed over the first unsupported feature
+	if problem.Text != "" {
+		what = problem.Text
+		r = logger.Range{Loc: logger.Loc{Start: loc.Start + 1 + int32(problem.Start)}, Len: int32(problem.Len)}
+	}
+	where := config.PrettyPrintTargetEnvironment(p.options.originalTargetEnv, p.options.unsupportedJSFeatureOverridesMask)
+	p.log.AddIDWithNotes(logger.MsgID_JS_UnsupportedRegExp, logger.Warning, &p.tracker, r, fmt.Sprintf("%s in %s", what, where), []logger.MsgData{{
+		Text: "This regular expression literal has been converted to a \"new RegExp()\" constructor " +
+			"to avoid generating code with a syntax error. However, you will need to include a " +
+			"polyfill for \"RegExp\" for your code to have the correct behavior at run-time."}})
+
+	// "/pattern/flags" => "new RegExp('pattern', 'flags')"
+	args := []js_ast.Expr{{
+		Loc:  logger.Loc{Start: loc.Start + 1},
+		Data: &js_ast.EString{Value: helpers.StringToUTF16(pattern)},
+	}}
+	if flags != "" {
+		args = append(args, js_ast.Expr{
+			Loc:  logger.Loc{Start: loc.Start + int32(len(pattern)) + 2},
+			Data: &js_ast.EString{Value: helpers.StringToUTF16(flags)},
+		})
+	}
+	regExpRef := p.makeRegExpRef()
+	p.recordUsage(regExpRef)
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ENew{
+		Target:        js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: regExpRef}},
+		Args:          args,
+		CloseParenLoc: logger.Loc{Start: loc.Start + int32(len(value))},
+	}}, true
 }
 
 // This function takes "exprIn" as input from the caller and produces "exprOut"
@@ -13262,6 +13343,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 	case *js_ast.ENull, *js_ast.ESuper, *js_ast.EBoolean, *js_ast.EUndefined, *js_ast.EJSXText:
 
 	case *js_ast.EBigInt:
+		if p.shouldLowerBigInt() {
+			return p.lowerBigIntLiteral(expr.Loc, e.Value), exprOut{}
+		}
 		if p.options.unsupportedJSFeatures.Has(compat.Bigint) {
 			// For ease of implementation, the actual reference of the "BigInt"
 			// symbol is deferred to print time. That means we don't have to
@@ -13276,25 +13360,8 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		e.Ref = p.symbolForMangledProp(p.loadNameFromRef(e.Ref))
 
 	case *js_ast.ERegExp:
-		// "/pattern/flags" => "new RegExp('pattern', 'flags')"
-		if pattern, flags, ok := p.isUnsupportedRegularExpression(expr.Loc, e.Value); ok {
-			args := []js_ast.Expr{{
-				Loc:  logger.Loc{Start: expr.Loc.Start + 1},
-				Data: &js_ast.EString{Value: helpers.StringToUTF16(pattern)},
-			}}
-			if flags != "" {
-				args = append(args, js_ast.Expr{
-					Loc:  logger.Loc{Start: expr.Loc.Start + int32(len(pattern)) + 2},
-					Data: &js_ast.EString{Value: helpers.StringToUTF16(flags)},
-				})
-			}
-			regExpRef := p.makeRegExpRef()
-			p.recordUsage(regExpRef)
-			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENew{
-				Target:        js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: regExpRef}},
-				Args:          args,
-				CloseParenLoc: logger.Loc{Start: expr.Loc.Start + int32(len(e.Value))},
-			}}, exprOut{}
+		if value, ok := p.lowerRegExp(expr.Loc, e.Value); ok {
+			return value, exprOut{}
 		}
 
 	case *js_ast.ENewTarget:
@@ -13439,6 +13506,12 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 					(p.currentScope.Parent == nil && p.willWrapModuleInTryCatchForUsing) {
 					p.log.AddErrorWithNotes(&p.tracker, r,
 						fmt.Sprintf("Cannot assign to %q because it is a constant", name), notes)
+				} else if p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) {
+					// The "const" will be converted into a "var" in this case, so the
+					// lowered code silently performs the assignment instead of throwing
+					where := config.PrettyPrintTargetEnvironment(p.options.originalTargetEnv, p.options.unsupportedJSFeatureOverridesMask)
+					p.log.AddIDWithNotes(logger.MsgID_JS_AssignToConstant, logger.Warning, &p.tracker, r,
+						fmt.Sprintf("This assignment to the constant %q will not throw because \"const\" is converted to \"var\" for %s", name, where), notes)
 				} else {
 					p.log.AddIDWithNotes(logger.MsgID_JS_AssignToConstant, logger.Warning, &p.tracker, r,
 						fmt.Sprintf("This assignment will throw because %q is a constant", name), notes)
@@ -14027,6 +14100,18 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			}
 		}
 
+		// Substitute "import.meta.hot" with the argument passed to the closure
+		// that wraps this module when hot module replacement is enabled
+		if _, ok := e.Target.Data.(*js_ast.EImportMeta); ok && e.Name == "hot" &&
+			p.options.hotModuleReplacement && p.options.mode == config.ModeBundle {
+			if p.hotRef == ast.InvalidRef {
+				p.hotRef = p.newSymbol(ast.SymbolOther, "hot")
+				p.moduleScope.Generated = append(p.moduleScope.Generated, p.hotRef)
+			}
+			p.recordUsage(p.hotRef)
+			return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: p.hotRef}}, exprOut{}
+		}
+
 		// Track ".then().catch()" chains
 		if isCallTarget && p.thenCatchChain.nextTarget == e {
 			if e.Name == "catch" {
@@ -14322,6 +14407,13 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		case js_ast.UnOpTypeof:
 			e.Value, _ = p.visitExprInOut(e.Value, exprIn{assignTarget: e.Op.UnaryAssignTarget()})
 
+			// "typeof 123n" => "'bigint'"
+			if p.shouldLowerBigInt() {
+				if result, ok := p.lowerBigIntUnary(expr.Loc, e); ok {
+					return result, exprOut{}
+				}
+			}
+
 			// Compile-time "typeof" evaluation
 			if typeof, ok := js_ast.TypeofWithoutSideEffects(e.Value.Data); ok {
 				return js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(typeof)}}, exprOut{}
@@ -14365,6 +14457,13 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		default:
 			e.Value, _ = p.visitExprInOut(e.Value, exprIn{assignTarget: e.Op.UnaryAssignTarget()})
 
+			// Convert operators on big integers into calls to the big integer library
+			if p.shouldLowerBigInt() {
+				if result, ok := p.lowerBigIntUnary(expr.Loc, e); ok {
+					return result, exprOut{}
+				}
+			}
+
 			// Post-process the unary expression
 			switch e.Op {
 			case js_ast.UnOpNot:
@@ -14538,7 +14637,6 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			if e.CommaAfterSpread.Start != 0 {
 				p.log.AddError(&p.tracker, logger.Range{Loc: e.CommaAfterSpread, Len: 1}, "Unexpected \",\" after rest pattern")
 			}
-			p.markSyntaxFeature(compat.Destructuring, logger.Range{Loc: expr.Loc, Len: 1})
 		}
 		hasSpread := false
 		for i, item := range e.Items {
@@ -14572,12 +14670,17 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			e.Items = js_ast.InlineSpreadsOfArrayLiterals(e.Items)
 		}
 
+		// Array expressions represent both array literals and binding patterns.
+		// Only lower array spread if we're an array literal, not a binding pattern.
+		if hasSpread && in.assignTarget == js_ast.AssignTargetNone {
+			return p.lowerArraySpread(expr.Loc, e), exprOut{}
+		}
+
 	case *js_ast.EObject:
 		if in.assignTarget != js_ast.AssignTargetNone {
 			if e.CommaAfterSpread.Start != 0 {
 				p.log.AddError(&p.tracker, logger.Range{Loc: e.CommaAfterSpread, Len: 1}, "Unexpected \",\" after rest pattern")
 			}
-			p.markSyntaxFeature(compat.Destructuring, logger.Range{Loc: expr.Loc, Len: 1})
 		}
 
 		hasSpread := false
@@ -14651,8 +14754,11 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				// generate a temporary variable in case this async method contains a
 				// "super" property reference. If that happens, the "super" expression
 				// must be lowered which will need a reference to this object literal.
-				if property.Kind == js_ast.PropertyMethod && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) {
-					if fn, ok := property.ValueOrNil.Data.(*js_ast.EFunction); ok && fn.Fn.IsAsync {
+				// The same is true if object literal methods are being lowered to ES5.
+				lowerSuper := property.Kind.IsMethodDefinition() && p.options.unsupportedJSFeatures.Has(compat.ObjectExtensions)
+				if (property.Kind == js_ast.PropertyMethod && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait|compat.Generator)) || lowerSuper {
+					if fn, ok := property.ValueOrNil.Data.(*js_ast.EFunction); ok && ((fn.Fn.IsAsync && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait)) ||
+						(fn.Fn.IsGenerator && p.options.unsupportedJSFeatures.Has(compat.Generator)) || lowerSuper) {
 						if innerClassNameRef == ast.InvalidRef {
 							innerClassNameRef = p.generateTempRef(tempRefNeedsDeclareMayBeCapturedInsideLoop, "")
 						}
@@ -14668,8 +14774,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 				}
 
 				property.ValueOrNil, _ = p.visitExprInOut(property.ValueOrNil, exprIn{
-					isMethod:     property.Kind.IsMethodDefinition(),
-					assignTarget: in.assignTarget,
+					isMethod:                       property.Kind.IsMethodDefinition(),
+					shouldLowerSuperPropertyAccess: lowerSuper,
+					assignTarget:                   in.assignTarget,
 				})
 
 				p.fnOnlyDataVisit.innerClassNameRef = oldInnerClassNameRef
@@ -14709,7 +14816,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 
 			// Object expressions represent both object literals and binding patterns.
 			// Only lower object spread if we're an object literal, not a binding pattern.
-			value := p.lowerObjectSpread(expr.Loc, e)
+			value := p.lowerObjectExtensions(expr.Loc, e)
 
 			// If we generated and used the temporary variable for a lowered "super"
 			// property reference inside a lowered "async" method, then initialize
@@ -14888,6 +14995,12 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			p.log.AddID(logger.MsgID_JS_UnsupportedDynamicImport, logger.Debug, &p.tracker, r,
 				"This \"import\" expression will not be bundled because the argument is not a string literal")
 
+			// Deferred imports can only be lowered if they are bundled
+			if e.Phase == ast.DeferPhase && p.options.mode == config.ModeBundle && p.options.unsupportedJSFeatures.Has(compat.ImportDefer) {
+				where := config.PrettyPrintTargetEnvironment(p.options.originalTargetEnv, p.options.unsupportedJSFeatureOverridesMask)
+				p.log.AddError(&p.tracker, r, fmt.Sprintf("Deferred imports are not available in %s unless they are bundled", where))
+			}
+
 			// We need to convert this into a call to "require()" if ES6 syntax is
 			// not supported in the current output format. The full conversion:
 			//
@@ -15380,7 +15493,7 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 			if target, loc, private := p.extractPrivateIndex(e.Target); private != nil {
 				// "foo.#bar(123)" => "__privateGet(_a = foo, #bar).call(_a, 123)"
 				targetFunc, targetWrapFunc := p.captureValueWithPossibleSideEffects(target.Loc, 2, target, valueCouldBeMutated)
-				return targetWrapFunc(js_ast.Expr{Loc: target.Loc, Data: &js_ast.ECall{
+				return targetWrapFunc(p.lowerCallSpread(target.Loc, &js_ast.ECall{
 					Target: js_ast.Expr{Loc: target.Loc, Data: &js_ast.EDot{
 						Target:  p.lowerPrivateGet(targetFunc(), loc, private),
 						Name:    "call",
@@ -15389,11 +15502,18 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 					Args:                   append([]js_ast.Expr{targetFunc()}, e.Args...),
 					CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
 					Kind:                   js_ast.TargetWasOriginallyPropertyAccess,
-				}}), exprOut{}
+				}, true /* hasExplicitThisArg */)), exprOut{}
 			}
 			p.maybeLowerSuperPropertyGetInsideCall(e)
 		}
 
+		// Convert calls to "BigInt" into calls to the big integer library
+		if p.shouldLowerBigInt() && e.OptionalChain == js_ast.OptionalChainNone {
+			if result, ok := p.lowerBigIntCall(expr.Loc, e); ok {
+				return result, exprOut{}
+			}
+		}
+
 		// Track calls to require() so we can use them while bundling
 		if p.options.mode != config.ModePassThrough && e.OptionalChain == js_ast.OptionalChainNone {
 			if id, ok := e.Target.Data.(*js_ast.EIdentifier); ok && id.Ref == p.requireRef {
@@ -15425,9 +15545,10 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 								p.importRecordsForCurrentPart = append(p.importRecordsForCurrentPart, importRecordIndex)
 
 								// Currently "require" is not converted into "import" for ESM
-								if p.options.mode != config.ModeBundle && p.options.outputFormat == config.FormatESModule && !omitWarnings {
+								if p.options.mode != config.ModeBundle && (p.options.outputFormat == config.FormatESModule || p.options.outputFormat == config.FormatSystem) && !omitWarnings {
 									r := js_lexer.RangeOfIdentifier(p.source, e.Target.Loc)
-									p.log.AddID(logger.MsgID_JS_UnsupportedRequireCall, logger.Warning, &p.tracker, r, "Converting \"require\" to \"esm\" is currently not supported")
+									p.log.AddID(logger.MsgID_JS_UnsupportedRequireCall, logger.Warning, &p.tracker, r,
+										fmt.Sprintf("Converting \"require\" to %q is currently not supported", p.options.outputFormat.String()))
 								}
 
 								// Create a new expression to represent the operation
@@ -15481,6 +15602,9 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		if !in.hasChainParent {
 			out.thisArgFunc = nil
 			out.thisArgWrapFunc = nil
+			if hasSpread {
+				return p.lowerCallSpread(expr.Loc, e, false /* hasExplicitThisArg */), out
+			}
 		}
 		return expr, out
 
@@ -15505,6 +15629,10 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 
 		p.maybeMarkKnownGlobalConstructorAsPure(e)
 
+		if hasSpread {
+			return p.lowerNewSpread(expr.Loc, e), exprOut{}
+		}
+
 	case *js_ast.EArrow:
 		// Check for a propagated name to keep from the parent context
 		var nameToKeep string
@@ -15591,9 +15719,10 @@ func (p *parser) visitExprInOut(expr js_ast.Expr, in exprIn) (js_ast.Expr, exprO
 		}
 
 		p.visitFn(&e.Fn, expr.Loc, visitFnOpts{
-			isMethod:               in.isMethod,
-			isDerivedClassCtor:     e == p.propDerivedCtorValue,
-			isLoweredPrivateMethod: in.isLoweredPrivateMethod,
+			isMethod:                       in.isMethod,
+			isDerivedClassCtor:             e == p.propDerivedCtorValue,
+			isLoweredPrivateMethod:         in.isLoweredPrivateMethod,
+			shouldLowerSuperPropertyAccess: in.shouldLowerSuperPropertyAccess,
 		})
 		name := e.Fn.Name
 
@@ -15790,6 +15919,13 @@ func (v *binaryExprVisitor) visitRightAndFinish(p *parser) js_ast.Expr {
 	}
 	p.fnOnlyDataVisit.silenceMessageAboutThisBeingUndefined = v.oldSilenceWarningAboutThisBeingUndefined
 
+	// Convert operators on big integers into calls to the big integer library
+	if p.shouldLowerBigInt() {
+		if result, ok := p.lowerBigIntBinary(v.loc, e); ok {
+			return result
+		}
+	}
+
 	// Always put constants consistently on the same side for equality
 	// comparisons to help improve compression. In theory, dictionary-based
 	// compression methods may already have a dictionary entry for code that
@@ -16739,6 +16875,10 @@ func (p *parser) handleIdentifier(loc logger.Loc, e *js_ast.EIdentifier, opts id
 		if isInsideUnsupportedArrow || isInsideUnsupportedAsyncArrow {
 			return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: p.captureArguments()}}
 		}
+		if ref := p.fnOnlyDataVisit.loopArgumentsRef; ref != nil {
+			p.recordUsage(*ref)
+			return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: *ref}}
+		}
 	}
 
 	// Create an error for assigning to an import namespace
@@ -16863,9 +17003,10 @@ func (p *parser) handleIdentifier(loc logger.Loc, e *js_ast.EIdentifier, opts id
 }
 
 type visitFnOpts struct {
-	isMethod               bool
-	isDerivedClassCtor     bool
-	isLoweredPrivateMethod bool
+	isMethod                       bool
+	isDerivedClassCtor             bool
+	isLoweredPrivateMethod         bool
+	shouldLowerSuperPropertyAccess bool
 }
 
 func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc, opts visitFnOpts) {
@@ -16873,10 +17014,12 @@ func (p *parser) visitFn(fn *js_ast.Fn, scopeLoc logger.Loc, opts visitFnOpts) {
 	oldFnOrArrowData := p.fnOrArrowDataVisit
 	oldFnOnlyData := p.fnOnlyDataVisit
 	p.fnOrArrowDataVisit = fnOrArrowDataVisit{
-		isAsync:                        fn.IsAsync,
-		isGenerator:                    fn.IsGenerator,
-		isDerivedClassCtor:             opts.isDerivedClassCtor,
-		shouldLowerSuperPropertyAccess: (fn.IsAsync && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait)) || opts.isLoweredPrivateMethod,
+		isAsync:            fn.IsAsync,
+		isGenerator:        fn.IsGenerator,
+		isDerivedClassCtor: opts.isDerivedClassCtor,
+		shouldLowerSuperPropertyAccess: (fn.IsAsync && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait)) ||
+			(fn.IsGenerator && p.options.unsupportedJSFeatures.Has(compat.Generator)) ||
+			opts.isLoweredPrivateMethod || opts.shouldLowerSuperPropertyAccess,
 	}
 	p.fnOnlyDataVisit = fnOnlyDataVisit{
 		isThisNested:       true,
@@ -17202,7 +17345,10 @@ func (p *parser) scanForImportsAndExports(stmts []js_ast.Stmt) (result importsEx
 					}
 				}
 
-				if s.StarNameLoc != nil {
+				// Deferred namespace imports aren't bound to the exports object of the
+				// imported module when bundling. The linker generates a lazy namespace
+				// object instead.
+				if s.StarNameLoc != nil && (record.Phase != ast.DeferPhase || p.options.mode != config.ModeBundle) {
 					p.namedImports[s.NamespaceRef] = js_ast.NamedImport{
 						AliasIsStar:       true,
 						AliasLoc:          *s.StarNameLoc,
@@ -17428,6 +17574,7 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 		afterArrowBodyLoc:  logger.Loc{Start: -1},
 		firstJSXElementLoc: logger.Loc{Start: -1},
 		importMetaRef:      ast.InvalidRef,
+		hotRef:             ast.InvalidRef,
 		superCtorRef:       ast.InvalidRef,
 
 		// For lowering private methods
@@ -17449,8 +17596,9 @@ func newParser(log logger.Log, source logger.Source, lexer js_lexer.Lexer, optio
 		namedExports:            make(map[string]js_ast.NamedExport),
 
 		// For JSX runtime imports
-		jsxRuntimeImports: make(map[string]ast.LocRef),
-		jsxLegacyImports:  make(map[string]ast.LocRef),
+		jsxRuntimeImports:    make(map[string]ast.LocRef),
+		jsxLegacyImports:     make(map[string]ast.LocRef),
+		bigIntLibraryImports: make(map[string]ast.LocRef),
 
 		// Add "/* @__KEY__ */" comments when mangling properties to support
 		// running esbuild (or other tools like Terser) again on the output.
@@ -18290,6 +18438,17 @@ func (p *parser) toAST(before, parts, after []js_ast.Part, hashbang string, dire
 		before, _ = p.generateImportStmt(path, logger.Range{}, keys, before, p.jsxLegacyImports, nil, nil)
 	}
 
+	// Insert an import statement for the big integer library if we used it
+	if len(p.bigIntLibraryImports) > 0 {
+		keys := sortedKeysOfMapStringLocRef(p.bigIntLibraryImports)
+		before, _ = p.generateImportStmt(p.options.bigIntLibrary, logger.Range{}, keys, before, p.bigIntLibraryImports, nil, nil)
+
+		// "import { default as JSBI } from 'jsbi'" => "import JSBI from 'jsbi'"
+		s := before[len(before)-1].Stmts[0].Data.(*js_ast.SImport)
+		s.DefaultName = &(*s.Items)[0].Name
+		s.Items = nil
+	}
+
 	// Insert imports for each glob pattern
 	for _, glob := range p.globPatternImports {
 		symbols := map[string]ast.LocRef{glob.name: {Loc: glob.approximateRange.Loc, Ref: glob.ref}}
@@ -18478,10 +18637,12 @@ func (p *parser) toAST(before, parts, after []js_ast.Part, hashbang string, dire
 		ExportsRef:                      p.exportsRef,
 		ModuleRef:                       p.moduleRef,
 		WrapperRef:                      wrapperRef,
+		HotRef:                          p.hotRef,
 		Hashbang:                        hashbang,
 		Directives:                      directives,
 		NamedImports:                    p.namedImports,
 		NamedExports:                    p.namedExports,
+		ImportItemPropertyAccesses:      p.importItemProperties,
 		TSEnums:                         p.tsEnums,
 		ConstValues:                     p.constValues,
 		ExprComments:                    p.exprComments,
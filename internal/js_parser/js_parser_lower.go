@@ -6,18 +6,35 @@ package js_parser
 
 import (
 	"fmt"
+	"math"
 
 	"github.com/evanw/esbuild/internal/ast"
 	"github.com/evanw/esbuild/internal/compat"
 	"github.com/evanw/esbuild/internal/config"
 	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/js_lexer"
 	"github.com/evanw/esbuild/internal/logger"
 )
 
 func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (didGenerateError bool) {
 	didGenerateError = true
 
+	// The linker can move top-level await into async module initializers, but
+	// only when bundling and only if async functions are available
+	if feature == compat.TopLevelAwait && p.options.lowerTopLevelAwait && p.options.mode == config.ModeBundle &&
+		!p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) {
+		didGenerateError = false
+		return
+	}
+
+	// The linker can lazily evaluate deferred imports of bundled modules. The
+	// bundler reports an error later on if the imported module is external.
+	if feature == compat.ImportDefer && p.options.mode == config.ModeBundle {
+		didGenerateError = false
+		return
+	}
+
 	if !p.options.unsupportedJSFeatures.Has(feature) {
 		if feature == compat.TopLevelAwait && !p.options.outputFormat.KeepESMImportExportSyntax() {
 			p.log.AddError(&p.tracker, r, fmt.Sprintf(
@@ -33,51 +50,20 @@ func (p *parser) markSyntaxFeature(feature compat.JSFeature, r logger.Range) (di
 	where := config.PrettyPrintTargetEnvironment(p.options.originalTargetEnv, p.options.unsupportedJSFeatureOverridesMask)
 
 	switch feature {
-	case compat.DefaultArgument:
-		name = "default arguments"
-
-	case compat.RestArgument:
-		name = "rest arguments"
-
-	case compat.ArraySpread:
-		name = "array spread"
-
-	case compat.ForOf:
-		name = "for-of loops"
-
 	case compat.ObjectAccessors:
 		name = "object accessors"
 
-	case compat.ObjectExtensions:
-		name = "object literal extensions"
-
-	case compat.Destructuring:
-		name = "destructuring"
-
 	case compat.NewTarget:
 		name = "new.target"
 
 	case compat.ConstAndLet:
-		name = p.source.TextForRange(r)
-
-	case compat.Class:
-		name = "class syntax"
+		// This is only reported for loop variables that can't be given a fresh
+		// binding for each iteration
+		name = "closures over loop variables in generator and async functions"
 
 	case compat.Generator:
 		name = "generator functions"
 
-	case compat.AsyncAwait:
-		name = "async functions"
-
-	case compat.AsyncGenerator:
-		name = "async generator functions"
-
-	case compat.ForAwait:
-		name = "for-await loops"
-
-	case compat.NestedRestBinding:
-		name = "non-identifier array rest patterns"
-
 	case compat.ImportAttributes:
 		p.log.AddError(&p.tracker, r, fmt.Sprintf(
 			"Using an arbitrary value as the second argument to \"import()\" is not possible in %s", where))
@@ -134,7 +120,7 @@ func (p *parser) isStrictMode() bool {
 }
 
 func (p *parser) isStrictModeOutputFormat() bool {
-	return p.options.outputFormat == config.FormatESModule
+	return p.options.outputFormat == config.FormatESModule || p.options.outputFormat == config.FormatSystem
 }
 
 type strictModeFeature uint8
@@ -198,7 +184,7 @@ func (p *parser) markStrictModeFeature(feature strictModeFeature, r logger.Range
 			fmt.Sprintf("%s cannot be used %s", text, where), notes)
 	} else if !canBeTransformed && p.isStrictModeOutputFormat() {
 		p.log.AddError(&p.tracker, r,
-			fmt.Sprintf("%s cannot be used with the \"esm\" output format due to strict mode", text))
+			fmt.Sprintf("%s cannot be used with the %q output format due to strict mode", text, p.options.outputFormat.String()))
 	}
 }
 
@@ -235,20 +221,11 @@ func (p *parser) whyStrictMode(scope *js_ast.Scope) (where string, notes []logge
 	return
 }
 
-func (p *parser) markAsyncFn(asyncRange logger.Range, isGenerator bool) (didGenerateError bool) {
-	// Lowered async functions are implemented in terms of generators. So if
-	// generators aren't supported, async functions aren't supported either.
-	// But if generators are supported, then async functions are unconditionally
-	// supported because we can use generators to implement them.
-	if !p.options.unsupportedJSFeatures.Has(compat.Generator) {
-		return false
-	}
-
-	feature := compat.AsyncAwait
-	if isGenerator {
-		feature = compat.AsyncGenerator
-	}
-	return p.markSyntaxFeature(feature, asyncRange)
+// Lowered async functions are turned into generator functions, so they are
+// also lowered to a state machine if generators are unsupported
+func (p *parser) isInLoweredGenerator() bool {
+	return p.options.unsupportedJSFeatures.Has(compat.Generator) && (p.fnOrArrowDataVisit.isGenerator ||
+		(p.fnOrArrowDataVisit.isAsync && p.options.unsupportedJSFeatures.Has(compat.AsyncAwait)))
 }
 
 func (p *parser) captureThis() ast.Ref {
@@ -283,8 +260,10 @@ func (p *parser) lowerFunction(
 	hasRestArg *bool,
 	isArrow bool,
 ) {
-	// Lower object rest binding patterns in function arguments
-	if p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	// Lower rest arguments, default arguments, and binding patterns in
+	// function arguments
+	if p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread | compat.Destructuring | compat.NestedRestBinding |
+		compat.DefaultArgument | compat.RestArgument) {
 		var prefixStmts []js_ast.Stmt
 
 		// Lower each argument individually instead of lowering all arguments
@@ -318,7 +297,51 @@ func (p *parser) lowerFunction(
 		// thinking that perhaps scope matters more in real-world code than side
 		// effect order.
 		for i, arg := range *args {
-			if bindingHasObjectRest(arg.Binding) {
+			// Lower rest arguments using "arguments":
+			//
+			//   "function foo(a, ...b) {}" => "function foo(a) { var b = [].slice.call(arguments, 1) }"
+			//
+			if *hasRestArg && i+1 == len(*args) && p.options.unsupportedJSFeatures.Has(compat.RestArgument) {
+				loc := arg.Binding.Loc
+				var argumentsRef ast.Ref
+				if !isArrow && p.fnOnlyDataVisit.argumentsRef != nil {
+					argumentsRef = *p.fnOnlyDataVisit.argumentsRef
+				} else {
+					// Arrow functions don't have their own "arguments" but the arrow
+					// function will have been converted to a function expression by now
+					argumentsRef = p.newSymbol(ast.SymbolUnbound, "arguments")
+				}
+				p.recordUsage(argumentsRef)
+				value := js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+					Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+						Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+							Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EArray{}},
+							Name:    "slice",
+							NameLoc: loc,
+						}},
+						Name:    "call",
+						NameLoc: loc,
+					}},
+					Args: []js_ast.Expr{
+						{Loc: loc, Data: &js_ast.EIdentifier{Ref: argumentsRef}},
+						{Loc: loc, Data: &js_ast.ENumber{Value: float64(i)}},
+					},
+					Kind: js_ast.TargetWasOriginallyPropertyAccess,
+				}}
+				target := js_ast.ConvertBindingToExpr(arg.Binding, nil)
+				if decls, ok := p.lowerObjectRestToDecls(target, value, nil); ok {
+					prefixStmts = append(prefixStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}})
+				} else {
+					prefixStmts = append(prefixStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar,
+						Decls: []js_ast.Decl{{Binding: arg.Binding, ValueOrNil: value}}}})
+				}
+				*args = (*args)[:i]
+				*hasRestArg = false
+				break
+			}
+
+			var bindingStmt js_ast.Stmt
+			if p.bindingNeedsLowering(arg.Binding) {
 				ref := p.generateTempRef(tempRefNoDeclare, "")
 				target := js_ast.ConvertBindingToExpr(arg.Binding, nil)
 				init := js_ast.Expr{Loc: arg.Binding.Loc, Data: &js_ast.EIdentifier{Ref: ref}}
@@ -329,14 +352,45 @@ func (p *parser) lowerFunction(
 					(*args)[i].Binding.Data = &js_ast.BIdentifier{Ref: ref}
 
 					// Append a variable declaration to the function body
-					prefixStmts = append(prefixStmts, js_ast.Stmt{Loc: arg.Binding.Loc,
-						Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}})
+					bindingStmt = js_ast.Stmt{Loc: arg.Binding.Loc,
+						Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: decls}}
 				}
 			}
+
+			// Lower default values by checking for "undefined". This must come
+			// before the variable declaration for the binding pattern above.
+			//
+			//   "function foo(a = b) {}" => "function foo(a) { if (a === void 0) a = b; }"
+			//
+			if arg.DefaultOrNil.Data != nil && p.options.unsupportedJSFeatures.Has(compat.DefaultArgument) {
+				loc := arg.DefaultOrNil.Loc
+				id := (*args)[i].Binding.Data.(*js_ast.BIdentifier)
+				p.recordUsage(id.Ref)
+				p.recordUsage(id.Ref)
+				check := js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{
+					Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+						Op:    js_ast.BinOpStrictEq,
+						Left:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: id.Ref}},
+						Right: js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared},
+					}},
+					Yes: js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Assign(
+						js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: id.Ref}}, arg.DefaultOrNil)}},
+				}}
+				prefixStmts = append(prefixStmts, check)
+				(*args)[i].DefaultOrNil = js_ast.Expr{}
+			}
+
+			if bindingStmt.Data != nil {
+				prefixStmts = append(prefixStmts, bindingStmt)
+			}
 		}
 
 		if len(prefixStmts) > 0 {
 			bodyBlock.Stmts = append(prefixStmts, bodyBlock.Stmts...)
+			if p.loweredArgStmtCounts == nil {
+				p.loweredArgStmtCounts = make(map[*js_ast.SBlock]int)
+			}
+			p.loweredArgStmtCounts[bodyBlock] = len(prefixStmts)
 		}
 	}
 
@@ -435,8 +489,16 @@ func (p *parser) lowerFunction(
 
 			// Forward all arguments from the outer function to the inner function
 			if !isArrow {
-				// Normal functions can just use "arguments" to forward everything
-				forwardedArgs = js_ast.Expr{Loc: bodyLoc, Data: &js_ast.EIdentifier{Ref: *p.fnOnlyDataVisit.argumentsRef}}
+				// Normal functions can just use "arguments" to forward everything.
+				// If the inner function will be lowered to a state machine, the
+				// original "arguments" symbol will be redirected to a variable in
+				// the inner function, so a separate symbol is used here.
+				argumentsRef := *p.fnOnlyDataVisit.argumentsRef
+				if p.options.unsupportedJSFeatures.Has(compat.Generator) {
+					argumentsRef = p.newSymbol(ast.SymbolUnbound, "arguments")
+					p.recordUsage(argumentsRef)
+				}
+				forwardedArgs = js_ast.Expr{Loc: bodyLoc, Data: &js_ast.EIdentifier{Ref: argumentsRef}}
 			} else {
 				// Arrow functions can't use "arguments", so we need to forward
 				// the arguments manually.
@@ -483,6 +545,18 @@ func (p *parser) lowerFunction(
 			name = "__async"
 		}
 		*isAsync = false
+
+		// Lower the inner generator function to a state machine if generators
+		// are also unsupported
+		if p.options.unsupportedJSFeatures.Has(compat.Generator) {
+			var argumentsRef *ast.Ref
+			if !isArrow {
+				argumentsRef = p.fnOnlyDataVisit.argumentsRef
+			}
+			p.lowerGeneratorBody(bodyLoc, &fn.Body.Block, p.loweredArgStmtCounts[bodyBlock], argumentsRef)
+			fn.IsGenerator = false
+		}
+
 		callAsync := p.callRuntime(bodyLoc, name, []js_ast.Expr{
 			thisValue,
 			forwardedArgs,
@@ -490,6 +564,12 @@ func (p *parser) lowerFunction(
 		})
 		bodyBlock.Stmts = []js_ast.Stmt{{Loc: bodyLoc, Data: &js_ast.SReturn{ValueOrNil: callAsync}}}
 	}
+
+	// Lower generator functions to a state machine
+	if isGenerator != nil && *isGenerator && p.options.unsupportedJSFeatures.Has(compat.Generator) {
+		p.lowerGeneratorBody(bodyLoc, bodyBlock, p.loweredArgStmtCounts[bodyBlock], p.fnOnlyDataVisit.argumentsRef)
+		*isGenerator = false
+	}
 }
 
 func (p *parser) lowerOptionalChain(expr js_ast.Expr, in exprIn, childOut exprOut) (js_ast.Expr, exprOut) {
@@ -576,7 +656,10 @@ flatten:
 	// Don't lower this if we don't need to. This check must be done here instead
 	// of earlier so we can do the dead code elimination above when the target is
 	// null or undefined.
-	if !p.options.unsupportedJSFeatures.Has(compat.OptionalChain) && !containsPrivateName {
+	// A "yield" in a lowered generator function must not be evaluated if the
+	// chain short-circuits, so the whole chain needs to be lowered too.
+	if !p.options.unsupportedJSFeatures.Has(compat.OptionalChain) && !containsPrivateName &&
+		!(p.isInLoweredGenerator() && generatorExprContainsYield(originalExpr)) {
 		return originalExpr, exprOut{}
 	}
 
@@ -714,7 +797,7 @@ flatten:
 			// a property access, invoke the function using ".call(this, ...args)" to
 			// explicitly provide the value for "this".
 			if i == len(chain)-1 && thisArg.Data != nil {
-				result = js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+				result = p.lowerCallSpread(loc, &js_ast.ECall{
 					Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
 						Target:  result,
 						Name:    "call",
@@ -724,7 +807,7 @@ flatten:
 					CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
 					IsMultiLine:            e.IsMultiLine,
 					Kind:                   js_ast.TargetWasOriginallyPropertyAccess,
-				}}
+				}, true /* hasExplicitThisArg */)
 				break
 			}
 
@@ -733,7 +816,7 @@ flatten:
 			// the property access target that was stashed away earlier as the value
 			// for "this" for the call. Example for this case: "foo.#bar?.()"
 			if privateThisFunc != nil {
-				result = privateThisWrapFunc(js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+				result = privateThisWrapFunc(p.lowerCallSpread(loc, &js_ast.ECall{
 					Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
 						Target:  result,
 						Name:    "call",
@@ -743,18 +826,18 @@ flatten:
 					CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
 					IsMultiLine:            e.IsMultiLine,
 					Kind:                   js_ast.TargetWasOriginallyPropertyAccess,
-				}})
+				}, true /* hasExplicitThisArg */))
 				privateThisFunc = nil
 				break
 			}
 
-			result = js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			result = p.lowerCallSpread(loc, &js_ast.ECall{
 				Target:                 result,
 				Args:                   e.Args,
 				CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
 				IsMultiLine:            e.IsMultiLine,
 				Kind:                   e.Kind,
-			}}
+			}, false /* hasExplicitThisArg */)
 
 		case *js_ast.EUnary:
 			result = js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{
@@ -801,7 +884,7 @@ flatten:
 }
 
 func (p *parser) lowerParenthesizedOptionalChain(loc logger.Loc, e *js_ast.ECall, childOut exprOut) js_ast.Expr {
-	return childOut.thisArgWrapFunc(js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+	return childOut.thisArgWrapFunc(p.lowerCallSpread(loc, &js_ast.ECall{
 		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
 			Target:  e.Target,
 			Name:    "call",
@@ -810,7 +893,7 @@ func (p *parser) lowerParenthesizedOptionalChain(loc logger.Loc, e *js_ast.ECall
 		Args:        append(append(make([]js_ast.Expr, 0, len(e.Args)+1), childOut.thisArgFunc()), e.Args...),
 		IsMultiLine: e.IsMultiLine,
 		Kind:        js_ast.TargetWasOriginallyPropertyAccess,
-	}})
+	}, true /* hasExplicitThisArg */))
 }
 
 func (p *parser) lowerAssignmentOperator(value js_ast.Expr, callback func(js_ast.Expr, js_ast.Expr) js_ast.Expr) js_ast.Expr {
@@ -1046,6 +1129,249 @@ func (p *parser) lowerObjectSpread(loc logger.Loc, e *js_ast.EObject) js_ast.Exp
 	return result
 }
 
+func (p *parser) lowerObjectExtensions(loc logger.Loc, e *js_ast.EObject) js_ast.Expr {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectExtensions) {
+		return p.lowerObjectSpread(loc, e)
+	}
+
+	// Find the first property that can't be represented in an ES5 object literal
+	split := len(e.Properties)
+	for i := range e.Properties {
+		property := &e.Properties[i]
+		if property.Kind == js_ast.PropertySpread {
+			continue
+		}
+
+		// "{ foo() {} }" => "{ foo: function() {} }"
+		if property.Kind == js_ast.PropertyMethod {
+			property.Kind = js_ast.PropertyField
+		}
+
+		// "{ ['foo']: 1 }" => "{ foo: 1 }"
+		if property.Flags.Has(js_ast.PropertyIsComputed) {
+			switch k := property.Key.Data.(type) {
+			case *js_ast.EString:
+				if !helpers.UTF16EqualsString(k.Value, "__proto__") {
+					property.Flags &= ^js_ast.PropertyIsComputed
+				}
+			case *js_ast.ENumber:
+				if !math.Signbit(k.Value) && !math.IsInf(k.Value, 1) {
+					property.Flags &= ^js_ast.PropertyIsComputed
+				}
+			}
+		}
+
+		// Shorthand "__proto__" properties don't set the prototype, so they must
+		// be defined separately once they are no longer written as a shorthand
+		if split == len(e.Properties) {
+			if property.Flags.Has(js_ast.PropertyIsComputed) {
+				split = i
+			} else if str, ok := property.Key.Data.(*js_ast.EString); ok && property.Flags.Has(js_ast.PropertyWasShorthand) &&
+				helpers.UTF16EqualsString(str.Value, "__proto__") {
+				split = i
+			}
+		}
+	}
+	if split == len(e.Properties) {
+		return p.lowerObjectSpread(loc, e)
+	}
+
+	// "{ a, [b]: c, d }" => "(_a = { a }, __defNormalProp(_a, b, c), __defNormalProp(_a, 'd', d), _a)"
+	tempRef := p.generateTempRef(tempRefNeedsDeclare, "")
+	temp := func() js_ast.Expr {
+		p.recordUsage(tempRef)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: tempRef}}
+	}
+	result := js_ast.Assign(temp(), p.lowerObjectSpread(loc, &js_ast.EObject{
+		Properties:   e.Properties[:split],
+		IsSingleLine: e.IsSingleLine,
+	}))
+	for _, property := range e.Properties[split:] {
+		var value js_ast.Expr
+		switch property.Kind {
+		case js_ast.PropertySpread:
+			value = p.callRuntime(property.Loc, "__spreadValues", []js_ast.Expr{temp(), property.ValueOrNil})
+
+		case js_ast.PropertyGetter, js_ast.PropertySetter:
+			getter := js_ast.Expr{Loc: property.Loc, Data: js_ast.EUndefinedShared}
+			setter := getter
+			if property.Kind == js_ast.PropertyGetter {
+				getter = property.ValueOrNil
+			} else {
+				setter = property.ValueOrNil
+			}
+			value = p.callRuntime(property.Loc, "__defAccessor", []js_ast.Expr{temp(), property.Key, getter, setter,
+				{Loc: property.Loc, Data: &js_ast.EBoolean{Value: true}}})
+
+		default:
+			if str, ok := property.Key.Data.(*js_ast.EString); ok && !property.Flags.Has(js_ast.PropertyIsComputed) &&
+				!property.Flags.Has(js_ast.PropertyWasShorthand) && helpers.UTF16EqualsString(str.Value, "__proto__") {
+				// "{ [a]: b, __proto__: c }" => "(_a = {}, __defNormalProp(_a, a, b), _a.__proto__ = c, _a)"
+				value = js_ast.Assign(js_ast.Expr{Loc: property.Loc, Data: &js_ast.EDot{
+					Target:  temp(),
+					Name:    "__proto__",
+					NameLoc: property.Key.Loc,
+				}}, property.ValueOrNil)
+			} else {
+				value = p.callRuntime(property.Loc, "__defNormalProp", []js_ast.Expr{temp(), property.Key, property.ValueOrNil})
+			}
+		}
+		result = js_ast.JoinWithComma(result, value)
+	}
+	return js_ast.JoinWithComma(result, temp())
+}
+
+// This converts a list of array items or call arguments that contains spread
+// elements into a single array expression that doesn't use spread syntax:
+//
+//	"[a, ...b, c]" => "[a].concat(__toArray(b), [c])"
+//
+// If "canReuseArray" is true, then the caller promises not to mutate the
+// resulting array, which lets us avoid a copy when there's a single spread.
+func (p *parser) lowerSpreadItems(loc logger.Loc, items []js_ast.Expr, canReuseArray bool) js_ast.Expr {
+	var parts []js_ast.Expr
+	var pending []js_ast.Expr
+	for _, item := range items {
+		if spread, ok := item.Data.(*js_ast.ESpread); ok {
+			if len(pending) > 0 {
+				parts = append(parts, js_ast.Expr{Loc: pending[0].Loc, Data: &js_ast.EArray{Items: pending, IsSingleLine: true}})
+				pending = nil
+			}
+			parts = append(parts, p.callRuntime(item.Loc, "__toArray", []js_ast.Expr{spread.Value}))
+		} else {
+			pending = append(pending, item)
+		}
+	}
+	if len(pending) > 0 {
+		parts = append(parts, js_ast.Expr{Loc: pending[0].Loc, Data: &js_ast.EArray{Items: pending, IsSingleLine: true}})
+	}
+
+	// "f(...a)" => "f.apply(void 0, __toArray(a))"
+	if canReuseArray && len(parts) == 1 {
+		return parts[0]
+	}
+
+	// "[...a, b]" => "[].concat(__toArray(a), [b])"
+	target := parts[0]
+	args := parts[1:]
+	if _, ok := target.Data.(*js_ast.EArray); !ok {
+		target = js_ast.Expr{Loc: loc, Data: &js_ast.EArray{IsSingleLine: true}}
+		args = parts
+	}
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+			Target:  target,
+			Name:    "concat",
+			NameLoc: loc,
+		}},
+		Args: args,
+		Kind: js_ast.TargetWasOriginallyPropertyAccess,
+	}}
+}
+
+func hasSpreadItem(items []js_ast.Expr) bool {
+	for _, item := range items {
+		if _, ok := item.Data.(*js_ast.ESpread); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) lowerArraySpread(loc logger.Loc, e *js_ast.EArray) js_ast.Expr {
+	if !p.options.unsupportedJSFeatures.Has(compat.ArraySpread) || !hasSpreadItem(e.Items) {
+		return js_ast.Expr{Loc: loc, Data: e}
+	}
+	return p.lowerSpreadItems(loc, e.Items, false /* canReuseArray */)
+}
+
+// This lowers spread arguments in call expressions. If "hasExplicitThisArg" is
+// true, then the call has the form "fn.call(thisArg, ...args)" (which is what
+// other lowering transforms generate) and is transformed into "fn.apply()".
+func (p *parser) lowerCallSpread(loc logger.Loc, e *js_ast.ECall, hasExplicitThisArg bool) js_ast.Expr {
+	if !p.options.unsupportedJSFeatures.Has(compat.ArraySpread) || !hasSpreadItem(e.Args) {
+		return js_ast.Expr{Loc: loc, Data: e}
+	}
+
+	target := e.Target
+	args := e.Args
+	var thisArg js_ast.Expr
+	var wrapFunc func(js_ast.Expr) js_ast.Expr
+
+	if dot, ok := target.Data.(*js_ast.EDot); ok && hasExplicitThisArg {
+		// "a.call(b, ...c)" => "a.apply(b, __toArray(c))"
+		target = dot.Target
+		thisArg = args[0]
+		args = args[1:]
+	} else {
+		switch t := target.Data.(type) {
+		case *js_ast.ESuper:
+			// This is handled when lowering the class
+			return js_ast.Expr{Loc: loc, Data: e}
+
+		case *js_ast.EDot:
+			if _, ok := t.Target.Data.(*js_ast.ESuper); ok {
+				thisArg = js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+				break
+			}
+
+			// "a.b(...c)" => "a.b.apply(a, __toArray(c))"
+			targetFunc, targetWrapFunc := p.captureValueWithPossibleSideEffects(loc, 2, t.Target, valueDefinitelyNotMutated)
+			target = js_ast.Expr{Loc: target.Loc, Data: &js_ast.EDot{
+				Target:  targetFunc(),
+				Name:    t.Name,
+				NameLoc: t.NameLoc,
+			}}
+			thisArg = targetFunc()
+			wrapFunc = targetWrapFunc
+
+		case *js_ast.EIndex:
+			if _, ok := t.Target.Data.(*js_ast.ESuper); ok {
+				thisArg = js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+				break
+			}
+
+			// "a[b](...c)" => "(_a = a)[b].apply(_a, __toArray(c))"
+			targetFunc, targetWrapFunc := p.captureValueWithPossibleSideEffects(loc, 2, t.Target, valueDefinitelyNotMutated)
+			target = js_ast.Expr{Loc: target.Loc, Data: &js_ast.EIndex{
+				Target: targetFunc(),
+				Index:  t.Index,
+			}}
+			thisArg = targetFunc()
+			wrapFunc = targetWrapFunc
+
+		default:
+			// "a(...b)" => "a.apply(void 0, __toArray(b))"
+			thisArg = js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}
+		}
+	}
+
+	result := js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: target.Loc, Data: &js_ast.EDot{
+			Target:  target,
+			Name:    "apply",
+			NameLoc: target.Loc,
+		}},
+		Args:                   []js_ast.Expr{thisArg, p.lowerSpreadItems(loc, args, true /* canReuseArray */)},
+		CanBeUnwrappedIfUnused: e.CanBeUnwrappedIfUnused,
+		IsMultiLine:            e.IsMultiLine,
+		Kind:                   js_ast.TargetWasOriginallyPropertyAccess,
+	}}
+	if wrapFunc != nil {
+		result = wrapFunc(result)
+	}
+	return result
+}
+
+func (p *parser) lowerNewSpread(loc logger.Loc, e *js_ast.ENew) js_ast.Expr {
+	if !p.options.unsupportedJSFeatures.Has(compat.ArraySpread) || !hasSpreadItem(e.Args) {
+		return js_ast.Expr{Loc: loc, Data: e}
+	}
+
+	// "new a(...b)" => "__construct(a, __toArray(b))"
+	return p.callRuntime(loc, "__construct", []js_ast.Expr{e.Target, p.lowerSpreadItems(loc, e.Args, true /* canReuseArray */)})
+}
+
 func (p *parser) maybeLowerAwait(loc logger.Loc, e *js_ast.EAwait) js_ast.Expr {
 	// "await x" turns into "yield __await(x)" when lowering async generator functions
 	if p.fnOrArrowDataVisit.isGenerator && (p.options.unsupportedJSFeatures.Has(compat.AsyncAwait) || p.options.unsupportedJSFeatures.Has(compat.AsyncGenerator)) {
@@ -1067,7 +1393,7 @@ func (p *parser) maybeLowerAwait(loc logger.Loc, e *js_ast.EAwait) js_ast.Expr {
 	return js_ast.Expr{Loc: loc, Data: e}
 }
 
-func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []js_ast.Stmt) []js_ast.Stmt {
+func (p *parser) lowerForOfLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []js_ast.Stmt) []js_ast.Stmt {
 	// This code:
 	//
 	//   for await (let x of y) z()
@@ -1091,11 +1417,24 @@ func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []
 	//
 	// except that "yield" is used instead of "await" if await is unsupported.
 	// This mostly follows TypeScript's implementation of the syntax transform.
+	// Regular "for-of" loops are transformed the same way except that "__iter"
+	// is used instead of "__forAwait" and there is no "await".
 
 	iterRef := p.generateTempRef(tempRefNoDeclare, "iter")
 	moreRef := p.generateTempRef(tempRefNoDeclare, "more")
 	tempRef := p.generateTempRef(tempRefNoDeclare, "temp")
 	errorRef := p.generateTempRef(tempRefNoDeclare, "error")
+	scope := p.currentScope
+	for !scope.Kind.StopsHoisting() {
+		scope = scope.Parent
+	}
+	isTopLevel := scope == p.moduleScope
+	p.declaredSymbols = append(p.declaredSymbols,
+		js_ast.DeclaredSymbol{IsTopLevel: isTopLevel, Ref: iterRef},
+		js_ast.DeclaredSymbol{IsTopLevel: isTopLevel, Ref: moreRef},
+		js_ast.DeclaredSymbol{IsTopLevel: isTopLevel, Ref: tempRef},
+		js_ast.DeclaredSymbol{IsTopLevel: isTopLevel, Ref: errorRef},
+	)
 
 	switch init := loop.Init.Data.(type) {
 	case *js_ast.SLocal:
@@ -1125,7 +1464,7 @@ func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []
 	if block, ok := loop.Body.Data.(*js_ast.SBlock); ok {
 		body = append(body, block.Stmts...)
 		closeBraceLoc = block.CloseBraceLoc
-	} else {
+	} else if _, ok := loop.Body.Data.(*js_ast.SEmpty); !ok {
 		body = append(body, loop.Body)
 	}
 
@@ -1148,8 +1487,12 @@ func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []
 	}}
 
 	// "await" expressions turn into "yield" expressions when lowering
-	awaitIterNext = p.maybeLowerAwait(awaitIterNext.Loc, &js_ast.EAwait{Value: awaitIterNext})
-	awaitTempCallIter = p.maybeLowerAwait(awaitTempCallIter.Loc, &js_ast.EAwait{Value: awaitTempCallIter})
+	iterHelper := "__iter"
+	if loop.Await.Len > 0 {
+		iterHelper = "__forAwait"
+		awaitIterNext = p.maybeLowerAwait(awaitIterNext.Loc, &js_ast.EAwait{Value: awaitIterNext})
+		awaitTempCallIter = p.maybeLowerAwait(awaitTempCallIter.Loc, &js_ast.EAwait{Value: awaitTempCallIter})
+	}
 
 	return append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.STry{
 		BlockLoc: loc,
@@ -1157,7 +1500,7 @@ func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []
 			Stmts: []js_ast.Stmt{{Loc: loc, Data: &js_ast.SFor{
 				InitOrNil: js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{
 					{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: iterRef}},
-						ValueOrNil: p.callRuntime(loc, "__forAwait", []js_ast.Expr{loop.Value})},
+						ValueOrNil: p.callRuntime(loc, iterHelper, []js_ast.Expr{loop.Value})},
 					{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: moreRef}}},
 					{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: tempRef}}},
 					{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: errorRef}}},
@@ -1248,86 +1591,726 @@ func (p *parser) lowerForAwaitLoop(loc logger.Loc, loop *js_ast.SForOf, stmts []
 	}})
 }
 
-func bindingHasObjectRest(binding js_ast.Binding) bool {
-	switch b := binding.Data.(type) {
-	case *js_ast.BArray:
-		for _, item := range b.Items {
-			if bindingHasObjectRest(item.Binding) {
-				return true
-			}
-		}
-	case *js_ast.BObject:
-		for _, property := range b.Properties {
-			if property.IsSpread || bindingHasObjectRest(property.Value) {
-				return true
-			}
-		}
+// Loops that declare block-scoped variables which are captured by a closure
+// need a separate binding for each iteration. This can't be expressed with
+// "var" so when "let" and "const" are unsupported, the loop body is moved into
+// a function that is called once per iteration:
+//
+//	// Input:
+//	for (let i = 0; i < 3; i++) fns.push(() => i)
+//
+//	// Output:
+//	var _loop = function(i) {
+//	  fns.push(function() { return i })
+//	};
+//	for (var i = 0; i < 3; i++) _loop(i);
+//
+// This state is saved before the loop body is visited so we can tell what the
+// loop body does once it has been visited.
+type loopClosureState struct {
+	scope             *js_ast.Scope
+	headRefs          []ast.Ref
+	wasHeadRefMutated []bool
+	oldHasThisUsage   bool
+	oldArgumentsCount uint32
+
+	// A placeholder for "arguments" inside the loop body
+	argumentsRef        *ast.Ref
+	oldLoopArgumentsRef *ast.Ref
+}
+
+// Returns the symbols declared by a "let" or "const" loop initializer
+func lexicalLoopHeadRefs(init js_ast.Stmt) (refs []ast.Ref) {
+	if local, ok := init.Data.(*js_ast.SLocal); ok && (local.Kind == js_ast.LocalLet || local.Kind == js_ast.LocalConst) {
+		js_ast.ForEachIdentifierBindingInDecls(local.Decls, func(loc logger.Loc, b *js_ast.BIdentifier) {
+			refs = append(refs, b.Ref)
+		})
 	}
-	return false
+	return
 }
 
-func exprHasObjectRest(expr js_ast.Expr) bool {
-	switch e := expr.Data.(type) {
-	case *js_ast.EBinary:
-		if e.Op == js_ast.BinOpAssign && exprHasObjectRest(e.Left) {
-			return true
-		}
-	case *js_ast.EArray:
-		for _, item := range e.Items {
-			if exprHasObjectRest(item) {
-				return true
-			}
+// The loop function for "for-in" and "for-of" loops takes the variables in the
+// loop header as arguments. This must be called after object rest lowering,
+// which may have replaced the loop header with a temporary variable.
+func forInOrOfClosureParams(init js_ast.Stmt, isLexicalHead bool) (refs []ast.Ref) {
+	if local, ok := init.Data.(*js_ast.SLocal); ok && isLexicalHead {
+		js_ast.ForEachIdentifierBindingInDecls(local.Decls, func(loc logger.Loc, b *js_ast.BIdentifier) {
+			refs = append(refs, b.Ref)
+		})
+	}
+	return
+}
+
+// Returns true if the body of the current generator or async function will be
+// lowered to a state machine by "lowerGeneratorBody"
+func (p *parser) willLowerToStateMachine() bool {
+	unsupported := p.options.unsupportedJSFeatures
+	if !unsupported.Has(compat.Generator) {
+		return false
+	}
+	if p.fnOrArrowDataVisit.isAsync {
+		if p.fnOrArrowDataVisit.isGenerator {
+			return unsupported.Has(compat.AsyncGenerator)
 		}
-	case *js_ast.EObject:
-		for _, property := range e.Properties {
-			if property.Kind == js_ast.PropertySpread || exprHasObjectRest(property.ValueOrNil) {
-				return true
-			}
+		return unsupported.Has(compat.AsyncAwait)
+	}
+	return true
+}
+
+// This must be called immediately before visiting the loop body. The loop
+// scope is the scope pushed for the loop header, or nil if there isn't one
+// (in which case the loop body's scope is used instead).
+func (p *parser) beginLoopClosure(loopScope *js_ast.Scope, body js_ast.Stmt, headRefs []ast.Ref) *loopClosureState {
+	// Block-scoped variables also lose their per-iteration binding when they
+	// are hoisted out of a state machine, even if "let" and "const" are supported
+	if !p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) &&
+		(!p.fnOrArrowDataVisit.isGenerator && !p.fnOrArrowDataVisit.isAsync || !p.willLowerToStateMachine()) {
+		return nil
+	}
+
+	state := &loopClosureState{
+		scope:           loopScope,
+		headRefs:        headRefs,
+		oldHasThisUsage: p.fnOnlyDataVisit.hasThisUsage,
+	}
+	if loopScope == nil {
+		if _, ok := body.Data.(*js_ast.SBlock); ok {
+			state.scope = p.scopesInOrder[0].scope
 		}
 	}
-	return false
+
+	// Track whether the loop body uses "this" or "arguments" or assigns to the
+	// variables declared in the loop header
+	p.fnOnlyDataVisit.hasThisUsage = false
+	if p.fnOnlyDataVisit.argumentsRef != nil {
+		state.oldArgumentsCount = p.symbolUses[*p.fnOnlyDataVisit.argumentsRef].CountEstimate
+		ref := p.newSymbol(ast.SymbolHoisted, "_arguments")
+		state.argumentsRef = &ref
+		state.oldLoopArgumentsRef = p.fnOnlyDataVisit.loopArgumentsRef
+		p.fnOnlyDataVisit.loopArgumentsRef = &ref
+	}
+	state.wasHeadRefMutated = make([]bool, len(headRefs))
+	for i, ref := range headRefs {
+		symbol := &p.symbols[ref.InnerIndex]
+		state.wasHeadRefMutated[i] = symbol.Flags.Has(ast.CouldPotentiallyBeMutated)
+		symbol.Flags &= ^ast.CouldPotentiallyBeMutated
+	}
+	return state
 }
 
-func (p *parser) lowerObjectRestInDecls(decls []js_ast.Decl) []js_ast.Decl {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
-		return decls
+// This must be called after visiting the whole loop. It returns the loop
+// function declaration (if any) appended to "stmts", and replaces the loop
+// body with a call to that function.
+func (p *parser) lowerLoopClosure(loc logger.Loc, state *loopClosureState, params []ast.Ref, body *js_ast.Stmt, stmts []js_ast.Stmt) []js_ast.Stmt {
+	if state == nil {
+		return stmts
+	}
+
+	// Restore the state from before the loop body was visited
+	usesThis := p.fnOnlyDataVisit.hasThisUsage
+	p.fnOnlyDataVisit.hasThisUsage = state.oldHasThisUsage || usesThis
+	usesArguments := p.fnOnlyDataVisit.argumentsRef != nil &&
+		p.symbolUses[*p.fnOnlyDataVisit.argumentsRef].CountEstimate != state.oldArgumentsCount
+	var mutatedRefs []ast.Ref
+	for i, ref := range state.headRefs {
+		symbol := &p.symbols[ref.InnerIndex]
+		if symbol.Flags.Has(ast.CouldPotentiallyBeMutated) {
+			mutatedRefs = append(mutatedRefs, ref)
+		}
+		if state.wasHeadRefMutated[i] {
+			symbol.Flags |= ast.CouldPotentiallyBeMutated
+		}
 	}
 
-	// Don't do any allocations if there are no object rest patterns. We want as
-	// little overhead as possible in the common case.
-	for i, decl := range decls {
-		if decl.ValueOrNil.Data != nil && bindingHasObjectRest(decl.Binding) {
-			clone := append([]js_ast.Decl{}, decls[:i]...)
-			for _, decl := range decls[i:] {
-				if decl.ValueOrNil.Data != nil {
-					target := js_ast.ConvertBindingToExpr(decl.Binding, nil)
-					if result, ok := p.lowerObjectRestToDecls(target, decl.ValueOrNil, clone); ok {
-						clone = result
-						continue
-					}
-				}
-				clone = append(clone, decl)
+	// Only lower this loop if a closure captures a block-scoped variable. A
+	// loop body containing "yield" or "await" can only be moved into a nested
+	// generator function, which requires the enclosing function to be lowered
+	// to a state machine as well:
+	//
+	//   var _loop = function*(i) {
+	//     fns.push(() => i);
+	//     yield i;
+	//   };
+	//   for (var i = 0; i < 3; i++) yield* _loop(i);
+	//
+	shouldLower := false
+	isGeneratorLoop := false
+	if state.scope != nil {
+		if member, ok := p.firstCapturedBlockScopedRef(state.scope); ok {
+			if (!p.fnOrArrowDataVisit.isGenerator && !p.fnOrArrowDataVisit.isAsync) || !generatorStmtContainsYield(*body) {
+				// Statements without "yield" are left alone by "lowerGeneratorBody"
+				shouldLower = p.options.unsupportedJSFeatures.Has(compat.ConstAndLet)
+			} else if p.willLowerToStateMachine() {
+				shouldLower = true
+				isGeneratorLoop = true
+			} else {
+				p.markSyntaxFeature(compat.ConstAndLet, js_lexer.RangeOfIdentifier(p.source, member.Loc))
 			}
-
-			return clone
 		}
 	}
 
-	return decls
-}
+	// References to "arguments" in the loop body must keep referring to the
+	// outer "arguments" if the loop body is moved into a nested function
+	if state.argumentsRef != nil {
+		p.fnOnlyDataVisit.loopArgumentsRef = state.oldLoopArgumentsRef
+		target := *p.fnOnlyDataVisit.argumentsRef
+		if shouldLower && usesArguments {
+			target = p.captureArguments()
+		} else if state.oldLoopArgumentsRef != nil {
+			target = *state.oldLoopArgumentsRef
+		}
+		p.symbols[state.argumentsRef.InnerIndex].Link = target
+	}
 
-func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stmt) {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
-		return
+	if !shouldLower {
+		return stmts
 	}
 
-	var bodyPrefixStmt js_ast.Stmt
+	if p.loweredLoopScopes == nil {
+		p.loweredLoopScopes = make(map[*js_ast.Scope]bool)
+	}
+	p.loweredLoopScopes[state.scope] = true
+
+	// Rewrite control flow statements that jump out of the loop body
+	ctx := loopClosureContext{ownLabel: ast.InvalidRef, innerLabels: make(map[ast.Ref]bool)}
+	if p.currentScope.Kind == js_ast.ScopeLabel {
+		ctx.ownLabel = p.currentScope.Label.Ref
+	}
+	var bodyStmts []js_ast.Stmt
+	if block, ok := body.Data.(*js_ast.SBlock); ok {
+		bodyStmts = block.Stmts
+	} else {
+		bodyStmts = []js_ast.Stmt{*body}
+	}
+	bodyStmts = p.rewriteLoopClosureStmts(&ctx, bodyStmts, false, false)
+
+	// Copy assignments to variables in the loop header back out of the closure:
+	//
+	//   var _loop = function(i) {
+	//     try {
+	//       i++;
+	//     } finally {
+	//       _i = i;
+	//     }
+	//   };
+	//   for (var i = 0; i < 3; i++) _loop(i), i = _i;
+	//
+	var copyBack js_ast.Expr
+	if len(mutatedRefs) > 0 {
+		var finallyStmts []js_ast.Stmt
+		for _, ref := range mutatedRefs {
+			tempRef := p.generateTempRef(tempRefNeedsDeclare, "")
+			p.recordUsage(tempRef)
+			p.recordUsage(ref)
+			finallyStmts = append(finallyStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Assign(
+				js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: tempRef}},
+				js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}},
+			)}})
+			p.recordUsage(tempRef)
+			p.recordUsage(ref)
+			copyBack = js_ast.JoinWithComma(copyBack, js_ast.Assign(
+				js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}},
+				js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: tempRef}},
+			))
+		}
+		bodyStmts = []js_ast.Stmt{{Loc: loc, Data: &js_ast.STry{
+			BlockLoc: loc,
+			Block:    js_ast.SBlock{Stmts: bodyStmts},
+			Finally:  &js_ast.Finally{Loc: loc, Block: js_ast.SBlock{Stmts: finallyStmts}},
+		}}}
+	}
+
+	// Generate "var _loop = function(...) { ... }". This is declared with "var"
+	// so it's hoisted up to the enclosing function scope.
+	loopRef := p.generateTempRef(tempRefNoDeclare, "_loop")
+	scope := p.currentScope
+	for !scope.Kind.StopsHoisting() {
+		scope = scope.Parent
+	}
+	p.declaredSymbols = append(p.declaredSymbols, js_ast.DeclaredSymbol{IsTopLevel: scope == p.moduleScope, Ref: loopRef})
+	args := make([]js_ast.Arg, len(params))
+	callArgs := make([]js_ast.Expr, 0, len(params)+1)
+	for i, ref := range params {
+		args[i] = js_ast.Arg{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}}
+		p.recordUsage(ref)
+		callArgs = append(callArgs, js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}})
+	}
+	if len(ctx.hoistedVars) > 0 {
+		stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: ctx.hoistedVars}})
+	}
+	loopFn := js_ast.Fn{
+		Args: args,
+		Body: js_ast.FnBody{Loc: body.Loc, Block: js_ast.SBlock{Stmts: bodyStmts}},
+	}
+	if isGeneratorLoop {
+		// References to "arguments" were already redirected above
+		p.lowerGeneratorBody(body.Loc, &loopFn.Body.Block, 0, nil)
+	}
+	stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{{
+		Binding:    js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: loopRef}},
+		ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: loopFn}},
+	}}}})
+
+	// Generate "_loop(...)" or "_loop.call(this, ...)"
+	p.recordUsage(loopRef)
+	callTarget := js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: loopRef}}
+	callKind := js_ast.NormalCall
+	if usesThis {
+		thisValue, hasThisValue := p.valueForThis(loc, false /* shouldLog */, js_ast.AssignTargetNone, false /* isCallTarget */, false /* isDeleteTarget */)
+		if !hasThisValue {
+			thisValue = js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+		}
+		callTarget = js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: callTarget, Name: "call", NameLoc: loc}}
+		callArgs = append([]js_ast.Expr{thisValue}, callArgs...)
+		callKind = js_ast.TargetWasOriginallyPropertyAccess
+	}
+	call := js_ast.Expr{Loc: loc, Data: &js_ast.ECall{Target: callTarget, Args: callArgs, Kind: callKind}}
+	if isGeneratorLoop {
+		call = js_ast.Expr{Loc: loc, Data: &js_ast.EYield{ValueOrNil: call, IsStar: true}}
+	}
+
+	// Forward control flow from the return value of the loop function
+	var loopStmts []js_ast.Stmt
+	if !ctx.hasBreak && !ctx.hasReturn && len(ctx.outerJumps) == 0 {
+		loopStmts = append(loopStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.JoinWithComma(call, copyBack)}})
+	} else {
+		retRef := p.generateTempRef(tempRefNeedsDeclare, "_ret")
+		ret := func() js_ast.Expr {
+			p.recordUsage(retRef)
+			return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: retRef}}
+		}
+		loopStmts = append(loopStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.JoinWithComma(js_ast.Assign(ret(), call), copyBack)}})
+		checkRet := func(value string, yes js_ast.S) {
+			loopStmts = append(loopStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{
+				Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+					Op:    js_ast.BinOpStrictEq,
+					Left:  ret(),
+					Right: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(value)}},
+				}},
+				Yes: js_ast.Stmt{Loc: loc, Data: yes},
+			}})
+		}
+		if ctx.hasBreak {
+			checkRet("break", &js_ast.SBreak{})
+		}
+		for _, jump := range ctx.outerJumps {
+			label := &ast.LocRef{Loc: loc, Ref: jump.label}
+			p.recordUsage(jump.label)
+			if jump.isBreak {
+				checkRet(jump.value, &js_ast.SBreak{Label: label})
+			} else {
+				checkRet(jump.value, &js_ast.SContinue{Label: label})
+			}
+		}
+		if ctx.hasReturn {
+			loopStmts = append(loopStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{
+				Test: js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+					Op:    js_ast.BinOpStrictEq,
+					Left:  js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{Op: js_ast.UnOpTypeof, Value: ret()}},
+					Right: js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("object")}},
+				}},
+				Yes: js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+					Target:  ret(),
+					Name:    "v",
+					NameLoc: loc,
+				}}}},
+			}})
+		}
+	}
+	*body = js_ast.Stmt{Loc: body.Loc, Data: &js_ast.SBlock{Stmts: loopStmts}}
+	return stmts
+}
+
+// Returns the first block-scoped symbol declared in this scope (or any nested
+// scope in the same function) that is captured by a closure. Scopes belonging
+// to nested loops that have already been lowered are skipped.
+func (p *parser) firstCapturedBlockScopedRef(scope *js_ast.Scope) (first js_ast.ScopeMember, ok bool) {
+	if len(p.capturedBlockScopedRefs) == 0 {
+		return
+	}
+	if scope.Kind == js_ast.ScopeBlock {
+		for _, member := range scope.Members {
+			if p.capturedBlockScopedRefs[member.Ref] && (!ok || member.Loc.Start < first.Loc.Start) {
+				first, ok = member, true
+			}
+		}
+	}
+	for _, child := range scope.Children {
+		switch child.Kind {
+		case js_ast.ScopeFunctionArgs, js_ast.ScopeFunctionBody, js_ast.ScopeClassName, js_ast.ScopeClassBody:
+			continue
+		}
+		if p.loweredLoopScopes[child] {
+			continue
+		}
+		if member, found := p.firstCapturedBlockScopedRef(child); found && (!ok || member.Loc.Start < first.Loc.Start) {
+			first, ok = member, true
+		}
+	}
+	return
+}
+
+type loopClosureJump struct {
+	value   string
+	label   ast.Ref
+	isBreak bool
+}
+
+type loopClosureContext struct {
+	innerLabels map[ast.Ref]bool
+	outerJumps  []loopClosureJump
+	hoistedVars []js_ast.Decl
+	ownLabel    ast.Ref
+	hasBreak    bool
+	hasReturn   bool
+}
+
+func (p *parser) rewriteLoopClosureStmts(ctx *loopClosureContext, stmts []js_ast.Stmt, isBreakLocal bool, isContinueLocal bool) []js_ast.Stmt {
+	end := 0
+	for _, stmt := range stmts {
+		stmt = p.rewriteLoopClosureStmt(ctx, stmt, isBreakLocal, isContinueLocal)
+		if _, ok := stmt.Data.(*js_ast.SEmpty); !ok {
+			stmts[end] = stmt
+			end++
+		}
+	}
+	return stmts[:end]
+}
+
+// This moves a statement from the loop body into the loop function. Jumps out
+// of the loop body are turned into return values, and "var" declarations are
+// turned into assignments since they must stay in the enclosing function.
+func (p *parser) rewriteLoopClosureStmt(ctx *loopClosureContext, stmt js_ast.Stmt, isBreakLocal bool, isContinueLocal bool) js_ast.Stmt {
+	loopBody := func(body js_ast.Stmt) js_ast.Stmt {
+		return p.rewriteLoopClosureStmt(ctx, body, true, true)
+	}
+
+	switch s := stmt.Data.(type) {
+	case *js_ast.SBlock:
+		s.Stmts = p.rewriteLoopClosureStmts(ctx, s.Stmts, isBreakLocal, isContinueLocal)
+
+	case *js_ast.SIf:
+		s.Yes = p.rewriteLoopClosureStmt(ctx, s.Yes, isBreakLocal, isContinueLocal)
+		if s.NoOrNil.Data != nil {
+			s.NoOrNil = p.rewriteLoopClosureStmt(ctx, s.NoOrNil, isBreakLocal, isContinueLocal)
+		}
+
+	case *js_ast.SFor:
+		if s.InitOrNil.Data != nil {
+			s.InitOrNil = p.rewriteLoopClosureStmt(ctx, s.InitOrNil, isBreakLocal, isContinueLocal)
+			if _, ok := s.InitOrNil.Data.(*js_ast.SEmpty); ok {
+				s.InitOrNil = js_ast.Stmt{}
+			}
+		}
+		s.Body = loopBody(s.Body)
+
+	case *js_ast.SForIn:
+		s.Init = p.rewriteLoopClosureForInOrOfInit(ctx, s.Init)
+		s.Body = loopBody(s.Body)
+
+	case *js_ast.SForOf:
+		s.Init = p.rewriteLoopClosureForInOrOfInit(ctx, s.Init)
+		s.Body = loopBody(s.Body)
+
+	case *js_ast.SWhile:
+		s.Body = loopBody(s.Body)
+
+	case *js_ast.SDoWhile:
+		s.Body = loopBody(s.Body)
+
+	case *js_ast.SWith:
+		s.Body = p.rewriteLoopClosureStmt(ctx, s.Body, isBreakLocal, isContinueLocal)
+
+	case *js_ast.SSwitch:
+		for i := range s.Cases {
+			s.Cases[i].Body = p.rewriteLoopClosureStmts(ctx, s.Cases[i].Body, true, isContinueLocal)
+		}
+
+	case *js_ast.STry:
+		s.Block.Stmts = p.rewriteLoopClosureStmts(ctx, s.Block.Stmts, isBreakLocal, isContinueLocal)
+		if s.Catch != nil {
+			s.Catch.Block.Stmts = p.rewriteLoopClosureStmts(ctx, s.Catch.Block.Stmts, isBreakLocal, isContinueLocal)
+		}
+		if s.Finally != nil {
+			s.Finally.Block.Stmts = p.rewriteLoopClosureStmts(ctx, s.Finally.Block.Stmts, isBreakLocal, isContinueLocal)
+		}
+
+	case *js_ast.SLabel:
+		ctx.innerLabels[s.Name.Ref] = true
+		s.Stmt = p.rewriteLoopClosureStmt(ctx, s.Stmt, isBreakLocal, isContinueLocal)
+
+	case *js_ast.SLocal:
+		if s.Kind == js_ast.LocalVar && p.declsHaveHoistedSymbol(s.Decls) {
+			var value js_ast.Expr
+			for _, decl := range s.Decls {
+				target := js_ast.ConvertBindingToExpr(decl.Binding, func(loc logger.Loc, ref ast.Ref) js_ast.Expr {
+					ctx.hoistedVars = append(ctx.hoistedVars, js_ast.Decl{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}})
+					p.recordUsage(ref)
+					return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+				})
+				if decl.ValueOrNil.Data != nil {
+					value = js_ast.JoinWithComma(value, js_ast.Assign(target, decl.ValueOrNil))
+				} else {
+					p.ignoreUsage(target.Data.(*js_ast.EIdentifier).Ref)
+				}
+			}
+			if value.Data == nil {
+				return js_ast.Stmt{Loc: stmt.Loc, Data: js_ast.SEmptyShared}
+			}
+			return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: value}}
+		}
+
+	case *js_ast.SBreak:
+		if s.Label == nil {
+			if !isBreakLocal {
+				ctx.hasBreak = true
+				return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: stmt.Loc,
+					Data: &js_ast.EString{Value: helpers.StringToUTF16("break")}}}}
+			}
+		} else if !ctx.innerLabels[s.Label.Ref] {
+			if s.Label.Ref == ctx.ownLabel {
+				ctx.hasBreak = true
+				p.ignoreUsage(s.Label.Ref)
+				return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: stmt.Loc,
+					Data: &js_ast.EString{Value: helpers.StringToUTF16("break")}}}}
+			}
+			return p.loopClosureOuterJump(ctx, stmt.Loc, s.Label.Ref, true)
+		}
+
+	case *js_ast.SContinue:
+		if s.Label == nil {
+			if !isContinueLocal {
+				return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SReturn{}}
+			}
+		} else if !ctx.innerLabels[s.Label.Ref] {
+			if s.Label.Ref == ctx.ownLabel {
+				p.ignoreUsage(s.Label.Ref)
+				return js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SReturn{}}
+			}
+			return p.loopClosureOuterJump(ctx, stmt.Loc, s.Label.Ref, false)
+		}
+
+	case *js_ast.SReturn:
+		// "return x" => "return { v: x }"
+		ctx.hasReturn = true
+		value := s.ValueOrNil
+		if value.Data == nil {
+			value = js_ast.Expr{Loc: stmt.Loc, Data: js_ast.EUndefinedShared}
+		}
+		s.ValueOrNil = js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EObject{Properties: []js_ast.Property{{
+			Key:        js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("v")}},
+			ValueOrNil: value,
+		}}, IsSingleLine: true}}
+	}
+
+	return stmt
+}
+
+func (p *parser) rewriteLoopClosureForInOrOfInit(ctx *loopClosureContext, init js_ast.Stmt) js_ast.Stmt {
+	// "for (var x in y)" => "for (x in y)"
+	if local, ok := init.Data.(*js_ast.SLocal); ok && local.Kind == js_ast.LocalVar && p.declsHaveHoistedSymbol(local.Decls) {
+		target := js_ast.ConvertBindingToExpr(local.Decls[0].Binding, func(loc logger.Loc, ref ast.Ref) js_ast.Expr {
+			ctx.hoistedVars = append(ctx.hoistedVars, js_ast.Decl{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}})
+			p.recordUsage(ref)
+			return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+		})
+		return js_ast.Stmt{Loc: init.Loc, Data: &js_ast.SExpr{Value: target}}
+	}
+	return init
+}
+
+func (p *parser) loopClosureOuterJump(ctx *loopClosureContext, loc logger.Loc, label ast.Ref, isBreak bool) js_ast.Stmt {
+	// "break foo" => "return 'break|foo'"
+	kind := "continue"
+	if isBreak {
+		kind = "break"
+	}
+	value := kind + "|" + p.symbols[label.InnerIndex].OriginalName
+	p.ignoreUsage(label)
+	found := false
+	for _, jump := range ctx.outerJumps {
+		if jump.value == value {
+			found = true
+			break
+		}
+	}
+	if !found {
+		ctx.outerJumps = append(ctx.outerJumps, loopClosureJump{value: value, label: label, isBreak: isBreak})
+	}
+	return js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: loc,
+		Data: &js_ast.EString{Value: helpers.StringToUTF16(value)}}}}
+}
+
+func (p *parser) declsHaveHoistedSymbol(decls []js_ast.Decl) (result bool) {
+	js_ast.ForEachIdentifierBindingInDecls(decls, func(loc logger.Loc, b *js_ast.BIdentifier) {
+		if p.symbols[b.Ref.InnerIndex].Kind == ast.SymbolHoisted {
+			result = true
+		}
+	})
+	return
+}
+
+// Returns true if this binding pattern must be lowered. Object rest patterns
+// can be lowered on their own, but if destructuring itself is unsupported (or
+// if this pattern has a nested rest binding that is unsupported) then the whole
+// binding pattern is lowered.
+func (p *parser) bindingNeedsLowering(binding js_ast.Binding) bool {
+	switch binding.Data.(type) {
+	case *js_ast.BArray, *js_ast.BObject:
+		if p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
+			return true
+		}
+		return p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && bindingHasObjectRest(binding) ||
+			p.options.unsupportedJSFeatures.Has(compat.NestedRestBinding) && bindingHasNestedRestBinding(binding)
+	}
+	return false
+}
+
+// This is the same as "bindingNeedsLowering" but for assignment targets
+func (p *parser) assignTargetNeedsLowering(expr js_ast.Expr) bool {
+	switch expr.Data.(type) {
+	case *js_ast.EArray, *js_ast.EObject:
+		if p.options.unsupportedJSFeatures.Has(compat.Destructuring) {
+			return true
+		}
+		return p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) && exprHasObjectRest(expr) ||
+			p.options.unsupportedJSFeatures.Has(compat.NestedRestBinding) && exprHasNestedRestBinding(expr)
+	}
+	return false
+}
+
+func bindingHasNestedRestBinding(binding js_ast.Binding) bool {
+	switch b := binding.Data.(type) {
+	case *js_ast.BArray:
+		for i, item := range b.Items {
+			if b.HasSpread && i+1 == len(b.Items) {
+				switch item.Binding.Data.(type) {
+				case *js_ast.BArray, *js_ast.BObject:
+					return true
+				}
+			}
+			if bindingHasNestedRestBinding(item.Binding) {
+				return true
+			}
+		}
+	case *js_ast.BObject:
+		for _, property := range b.Properties {
+			if bindingHasNestedRestBinding(property.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func exprHasNestedRestBinding(expr js_ast.Expr) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.EBinary:
+		if e.Op == js_ast.BinOpAssign && exprHasNestedRestBinding(e.Left) {
+			return true
+		}
+	case *js_ast.EArray:
+		for _, item := range e.Items {
+			if spread, ok := item.Data.(*js_ast.ESpread); ok {
+				switch spread.Value.Data.(type) {
+				case *js_ast.EArray, *js_ast.EObject:
+					return true
+				}
+			}
+			if exprHasNestedRestBinding(item) {
+				return true
+			}
+		}
+	case *js_ast.EObject:
+		for _, property := range e.Properties {
+			if property.Kind != js_ast.PropertySpread && exprHasNestedRestBinding(property.ValueOrNil) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func bindingHasObjectRest(binding js_ast.Binding) bool {
+	switch b := binding.Data.(type) {
+	case *js_ast.BArray:
+		for _, item := range b.Items {
+			if bindingHasObjectRest(item.Binding) {
+				return true
+			}
+		}
+	case *js_ast.BObject:
+		for _, property := range b.Properties {
+			if property.IsSpread || bindingHasObjectRest(property.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func exprHasObjectRest(expr js_ast.Expr) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.EBinary:
+		if e.Op == js_ast.BinOpAssign && exprHasObjectRest(e.Left) {
+			return true
+		}
+	case *js_ast.EArray:
+		for _, item := range e.Items {
+			if exprHasObjectRest(item) {
+				return true
+			}
+		}
+	case *js_ast.EObject:
+		for _, property := range e.Properties {
+			if property.Kind == js_ast.PropertySpread || exprHasObjectRest(property.ValueOrNil) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *parser) lowerObjectRestInDecls(decls []js_ast.Decl) []js_ast.Decl {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread | compat.Destructuring | compat.NestedRestBinding) {
+		return decls
+	}
+
+	// Don't do any allocations if there are no object rest patterns. We want as
+	// little overhead as possible in the common case.
+	for i, decl := range decls {
+		if decl.ValueOrNil.Data != nil && p.bindingNeedsLowering(decl.Binding) {
+			clone := append([]js_ast.Decl{}, decls[:i]...)
+			for _, decl := range decls[i:] {
+				if decl.ValueOrNil.Data != nil {
+					target := js_ast.ConvertBindingToExpr(decl.Binding, nil)
+					if result, ok := p.lowerObjectRestToDecls(target, decl.ValueOrNil, clone); ok {
+						clone = result
+						continue
+					}
+				}
+				clone = append(clone, decl)
+			}
+
+			return clone
+		}
+	}
+
+	return decls
+}
+
+func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stmt) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread | compat.Destructuring | compat.NestedRestBinding) {
+		return
+	}
+
+	var bodyPrefixStmt js_ast.Stmt
 
 	switch s := init.Data.(type) {
 	case *js_ast.SExpr:
 		// "for ({...x} in y) {}"
 		// "for ({...x} of y) {}"
-		if exprHasObjectRest(s.Value) {
+		if p.assignTargetNeedsLowering(s.Value) {
 			ref := p.generateTempRef(tempRefNeedsDeclare, "")
 			if expr, ok := p.lowerAssign(s.Value, js_ast.Expr{Loc: init.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, objRestReturnValueIsUnused); ok {
 				p.recordUsage(ref)
@@ -1339,7 +2322,7 @@ func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stm
 	case *js_ast.SLocal:
 		// "for (let {...x} in y) {}"
 		// "for (let {...x} of y) {}"
-		if len(s.Decls) == 1 && bindingHasObjectRest(s.Decls[0].Binding) {
+		if len(s.Decls) == 1 && p.bindingNeedsLowering(s.Decls[0].Binding) {
 			ref := p.generateTempRef(tempRefNoDeclare, "")
 			decl := js_ast.Decl{Binding: s.Decls[0].Binding, ValueOrNil: js_ast.Expr{Loc: init.Loc, Data: &js_ast.EIdentifier{Ref: ref}}}
 			p.recordUsage(ref)
@@ -1354,6 +2337,9 @@ func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stm
 			// If there's already a block, insert at the front
 			stmts := make([]js_ast.Stmt, 0, 1+len(block.Stmts))
 			block.Stmts = append(append(stmts, bodyPrefixStmt), block.Stmts...)
+		} else if _, ok := body.Data.(*js_ast.SEmpty); ok {
+			// An empty body can just be replaced
+			body.Data = &js_ast.SBlock{Stmts: []js_ast.Stmt{bodyPrefixStmt}}
 		} else {
 			// Otherwise, make a block and insert at the front
 			body.Data = &js_ast.SBlock{Stmts: []js_ast.Stmt{bodyPrefixStmt, *body}}
@@ -1362,18 +2348,22 @@ func (p *parser) lowerObjectRestInForLoopInit(init js_ast.Stmt, body *js_ast.Stm
 }
 
 func (p *parser) lowerObjectRestInCatchBinding(catch *js_ast.Catch) {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread | compat.Destructuring | compat.NestedRestBinding) {
 		return
 	}
 
-	if catch.BindingOrNil.Data != nil && bindingHasObjectRest(catch.BindingOrNil) {
+	if catch.BindingOrNil.Data != nil && p.bindingNeedsLowering(catch.BindingOrNil) {
 		ref := p.generateTempRef(tempRefNoDeclare, "")
 		decl := js_ast.Decl{Binding: catch.BindingOrNil, ValueOrNil: js_ast.Expr{Loc: catch.BindingOrNil.Loc, Data: &js_ast.EIdentifier{Ref: ref}}}
 		p.recordUsage(ref)
 		decls := p.lowerObjectRestInDecls([]js_ast.Decl{decl})
 		catch.BindingOrNil.Data = &js_ast.BIdentifier{Ref: ref}
 		stmts := make([]js_ast.Stmt, 0, 1+len(catch.Block.Stmts))
-		stmts = append(stmts, js_ast.Stmt{Loc: catch.BindingOrNil.Loc, Data: &js_ast.SLocal{Kind: js_ast.LocalLet, Decls: decls}})
+		kind := js_ast.LocalLet
+		if p.options.unsupportedJSFeatures.Has(compat.ConstAndLet) {
+			kind = js_ast.LocalVar
+		}
+		stmts = append(stmts, js_ast.Stmt{Loc: catch.BindingOrNil.Loc, Data: &js_ast.SLocal{Kind: kind, Decls: decls}})
 		catch.Block.Stmts = append(stmts, catch.Block.Stmts...)
 	}
 }
@@ -1430,7 +2420,7 @@ func (p *parser) lowerObjectRestHelper(
 	declare generateTempRefArg,
 	mode objRestMode,
 ) (wrapFunc func(js_ast.Expr) js_ast.Expr, ok bool) {
-	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread | compat.Destructuring | compat.NestedRestBinding) {
 		return nil, false
 	}
 
@@ -1441,6 +2431,53 @@ func (p *parser) lowerObjectRestHelper(
 		return nil, false
 	}
 
+	// Capture and return the value of the initializer if this is an assignment
+	// expression and the return value is used:
+	//
+	//   // Input:
+	//   console.log({...x} = x);
+	//
+	//   // Output:
+	//   var _a;
+	//   console.log((x = __objRest(_a = x, []), _a));
+	//
+	// This isn't necessary if the return value is unused:
+	//
+	//   // Input:
+	//   ({...x} = x);
+	//
+	//   // Output:
+	//   x = __objRest(x, []);
+	//
+	captureRootInit := func() {
+		if mode == objRestMustReturnInitExpr {
+			initFunc, initWrapFunc := p.captureValueWithPossibleSideEffects(rootInit.Loc, 2, rootInit, valueCouldBeMutated)
+			rootInit = initFunc()
+			wrapFunc = func(expr js_ast.Expr) js_ast.Expr {
+				return initWrapFunc(js_ast.JoinWithComma(expr, initFunc()))
+			}
+		}
+	}
+
+	captureIntoRef := func(expr js_ast.Expr) ast.Ref {
+		ref := p.generateTempRef(declare, "")
+		assign(js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, expr)
+		p.recordUsage(ref)
+		return ref
+	}
+
+	// Lower the whole binding pattern if destructuring isn't supported at all
+	if p.options.unsupportedJSFeatures.Has(compat.Destructuring) ||
+		(p.options.unsupportedJSFeatures.Has(compat.NestedRestBinding) && exprHasNestedRestBinding(rootExpr)) {
+		captureRootInit()
+		p.lowerBindingPattern(rootExpr, rootInit, assign, captureIntoRef)
+		return wrapFunc, true
+	}
+
+	if !p.options.unsupportedJSFeatures.Has(compat.ObjectRestSpread) {
+		return nil, false
+	}
+
 	// Scan for object rest bindings and initialize rest binding containment
 	containsRestBinding := make(map[js_ast.E]bool)
 	var findRestBindings func(js_ast.Expr) bool
@@ -1477,13 +2514,6 @@ func (p *parser) lowerObjectRestHelper(
 	// If there is at least one rest binding, lower the whole expression
 	var visit func(js_ast.Expr, js_ast.Expr, []func() js_ast.Expr)
 
-	captureIntoRef := func(expr js_ast.Expr) ast.Ref {
-		ref := p.generateTempRef(declare, "")
-		assign(js_ast.Expr{Loc: expr.Loc, Data: &js_ast.EIdentifier{Ref: ref}}, expr)
-		p.recordUsage(ref)
-		return ref
-	}
-
 	lowerObjectRestPattern := func(
 		before []js_ast.Property,
 		binding js_ast.Expr,
@@ -1662,34 +2692,180 @@ func (p *parser) lowerObjectRestHelper(
 		assign(expr, init)
 	}
 
-	// Capture and return the value of the initializer if this is an assignment
-	// expression and the return value is used:
-	//
-	//   // Input:
-	//   console.log({...x} = x);
-	//
-	//   // Output:
-	//   var _a;
-	//   console.log((x = __objRest(_a = x, []), _a));
-	//
-	// This isn't necessary if the return value is unused:
-	//
-	//   // Input:
-	//   ({...x} = x);
-	//
-	//   // Output:
-	//   x = __objRest(x, []);
-	//
-	if mode == objRestMustReturnInitExpr {
-		initFunc, initWrapFunc := p.captureValueWithPossibleSideEffects(rootInit.Loc, 2, rootInit, valueCouldBeMutated)
-		rootInit = initFunc()
-		wrapFunc = func(expr js_ast.Expr) js_ast.Expr {
-			return initWrapFunc(js_ast.JoinWithComma(expr, initFunc()))
+	captureRootInit()
+	visit(rootExpr, rootInit, nil)
+	return wrapFunc, true
+}
+
+// This lowers an entire binding pattern into a sequence of assignments. Unlike
+// the object rest transform above, this doesn't leave any destructuring syntax
+// behind:
+//
+//	"let [a, {b, c = d}] = e" => "let _a = __toArray(e, 2), a = _a[0], _b = _a[1], b = _b.b, _c = _b.c, c = _c === void 0 ? d : _c"
+func (p *parser) lowerBindingPattern(
+	expr js_ast.Expr,
+	init js_ast.Expr,
+	assign func(js_ast.Expr, js_ast.Expr),
+	captureIntoRef func(js_ast.Expr) ast.Ref,
+) {
+	ref := func(ref ast.Ref, loc logger.Loc) js_ast.Expr {
+		p.recordUsage(ref)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+	}
+
+	// Handle default values by checking for "undefined"
+	assignWithDefault := func(target js_ast.Expr, value js_ast.Expr) {
+		if binary, ok := target.Data.(*js_ast.EBinary); ok && binary.Op == js_ast.BinOpAssign {
+			// "[a = b] = c" => "_a = c[0], a = _a === void 0 ? b : _a"
+			valueRef := captureIntoRef(value)
+			value = js_ast.Expr{Loc: value.Loc, Data: &js_ast.EIf{
+				Test: js_ast.Expr{Loc: value.Loc, Data: &js_ast.EBinary{
+					Op:    js_ast.BinOpStrictEq,
+					Left:  ref(valueRef, value.Loc),
+					Right: js_ast.Expr{Loc: value.Loc, Data: js_ast.EUndefinedShared},
+				}},
+				Yes: binary.Right,
+				No:  ref(valueRef, value.Loc),
+			}}
+			target = binary.Left
 		}
+		p.lowerBindingPattern(target, value, assign, captureIntoRef)
 	}
 
-	visit(rootExpr, rootInit, nil)
-	return wrapFunc, true
+	switch e := expr.Data.(type) {
+	case *js_ast.EArray:
+		// Convert the value to an array, stopping early if there's no rest element
+		count := len(e.Items)
+		hasRest := false
+		if count > 0 {
+			if _, ok := e.Items[count-1].Data.(*js_ast.ESpread); ok {
+				hasRest = true
+				count--
+			}
+		}
+		args := []js_ast.Expr{init}
+		if !hasRest {
+			args = append(args, js_ast.Expr{Loc: expr.Loc, Data: &js_ast.ENumber{Value: float64(count)}})
+		}
+		arrayRef := captureIntoRef(p.callRuntime(init.Loc, "__toArray", args))
+
+		for i, item := range e.Items {
+			switch item := item.Data.(type) {
+			case *js_ast.EMissing:
+				continue
+
+			case *js_ast.ESpread:
+				// "[a, ...b] = c" => "_a = __toArray(c), a = _a[0], b = _a.slice(1)"
+				p.lowerBindingPattern(item.Value, js_ast.Expr{Loc: item.Value.Loc, Data: &js_ast.ECall{
+					Target: js_ast.Expr{Loc: item.Value.Loc, Data: &js_ast.EDot{
+						Target:  ref(arrayRef, item.Value.Loc),
+						Name:    "slice",
+						NameLoc: item.Value.Loc,
+					}},
+					Args: []js_ast.Expr{{Loc: item.Value.Loc, Data: &js_ast.ENumber{Value: float64(i)}}},
+					Kind: js_ast.TargetWasOriginallyPropertyAccess,
+				}}, assign, captureIntoRef)
+				continue
+			}
+
+			assignWithDefault(item, js_ast.Expr{Loc: item.Loc, Data: &js_ast.EIndex{
+				Target: ref(arrayRef, item.Loc),
+				Index:  js_ast.Expr{Loc: item.Loc, Data: &js_ast.ENumber{Value: float64(i)}},
+			}})
+		}
+		return
+
+	case *js_ast.EObject:
+		last := len(e.Properties) - 1
+		endsWithRestBinding := last >= 0 && e.Properties[last].Kind == js_ast.PropertySpread
+
+		// Avoid a temporary if the value is only used once
+		if len(e.Properties) > 1 || (endsWithRestBinding && last > 0) {
+			if id, ok := init.Data.(*js_ast.EIdentifier); !ok || p.bindingPatternAssignsTo(expr, id.Ref) {
+				init = ref(captureIntoRef(init), init.Loc)
+			}
+		}
+
+		var capturedKeys []func() js_ast.Expr
+		for _, property := range e.Properties {
+			if property.Kind == js_ast.PropertySpread {
+				// "{a, ...b} = c" => "a = c.a, b = __objRest(c, ['a'])"
+				keysToExclude := make([]js_ast.Expr, len(capturedKeys))
+				for i, capturedKey := range capturedKeys {
+					keysToExclude[i] = capturedKey()
+				}
+				p.lowerBindingPattern(property.ValueOrNil, p.callRuntime(property.ValueOrNil.Loc, "__objRest", []js_ast.Expr{init,
+					{Loc: property.ValueOrNil.Loc, Data: &js_ast.EArray{Items: keysToExclude, IsSingleLine: e.IsSingleLine}}}),
+					assign, captureIntoRef)
+				continue
+			}
+
+			// Save a copy of this key so the rest binding can exclude it
+			key := property.Key
+			if endsWithRestBinding {
+				var capturedKey func() js_ast.Expr
+				key, capturedKey = p.captureKeyForObjectRest(key)
+				capturedKeys = append(capturedKeys, capturedKey)
+			}
+
+			// "{a, b: c} = d" => "a = d.a, c = d.b"
+			var value js_ast.Expr
+			if str, ok := key.Data.(*js_ast.EString); ok && js_ast.IsIdentifierUTF16(str.Value) {
+				value = js_ast.Expr{Loc: key.Loc, Data: &js_ast.EDot{
+					Target:  init,
+					Name:    helpers.UTF16ToString(str.Value),
+					NameLoc: key.Loc,
+				}}
+			} else {
+				value = js_ast.Expr{Loc: key.Loc, Data: &js_ast.EIndex{
+					Target: init,
+					Index:  key,
+				}}
+			}
+			if property.InitializerOrNil.Data != nil {
+				assignWithDefault(js_ast.Assign(property.ValueOrNil, property.InitializerOrNil), value)
+			} else {
+				assignWithDefault(property.ValueOrNil, value)
+			}
+		}
+
+		// An empty object pattern still throws for "null" and "undefined"
+		if len(e.Properties) == 0 {
+			captureIntoRef(p.callRuntime(init.Loc, "__objRest", []js_ast.Expr{init,
+				{Loc: init.Loc, Data: &js_ast.EArray{}}}))
+		}
+		return
+	}
+
+	assign(expr, init)
+}
+
+// Returns true if the binding pattern may assign to the given symbol
+func (p *parser) bindingPatternAssignsTo(expr js_ast.Expr, ref ast.Ref) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.EIdentifier:
+		return e.Ref == ref
+	case *js_ast.EBinary:
+		return e.Op == js_ast.BinOpAssign && p.bindingPatternAssignsTo(e.Left, ref)
+	case *js_ast.ESpread:
+		return p.bindingPatternAssignsTo(e.Value, ref)
+	case *js_ast.EArray:
+		for _, item := range e.Items {
+			if p.bindingPatternAssignsTo(item, ref) {
+				return true
+			}
+		}
+	case *js_ast.EObject:
+		for _, property := range e.Properties {
+			if p.bindingPatternAssignsTo(property.ValueOrNil, ref) {
+				return true
+			}
+		}
+	case *js_ast.EDot, *js_ast.EIndex:
+		// Property accesses could call setters, which could do anything
+		return true
+	}
+	return false
 }
 
 // Save a copy of the key for the call to "__objRest" later on. Certain
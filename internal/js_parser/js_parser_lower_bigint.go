@@ -0,0 +1,260 @@
+package js_parser
+
+import (
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// When the target doesn't support BigInt and the "bigintLibrary" option is
+// set, big integers are lowered to calls into a JSBI-compatible library. This
+// code:
+//
+//   const a = 2n ** 64n
+//   if (a > b) c = -1n
+//
+// is transformed into the following code:
+//
+//   import JSBI from "jsbi"
+//   const a = JSBI.exponentiate(JSBI.BigInt("2"), JSBI.BigInt("64"))
+//   if (a > b) c = JSBI.BigInt("-1")
+//
+// JSBI values are objects, so operators only work if both sides are known to
+// be big integers at compile time. This is the case for literals, for calls to
+// "BigInt()", for the results of other lowered operators, and for "const"
+// variables initialized to any of these. In the example above, "a > b" is left
+// alone because "b" isn't known to be a big integer (a warning is generated).
+
+func (p *parser) shouldLowerBigInt() bool {
+	return p.options.bigIntLibrary != "" && p.options.unsupportedJSFeatures.Has(compat.Bigint)
+}
+
+func (p *parser) importBigIntLibrary(loc logger.Loc) js_ast.Expr {
+	it, ok := p.bigIntLibraryImports["default"]
+	if !ok {
+		it.Loc = loc
+		it.Ref = p.newSymbol(ast.SymbolOther, "JSBI")
+		p.moduleScope.Generated = append(p.moduleScope.Generated, it.Ref)
+		p.isImportItem[it.Ref] = true
+		p.bigIntLibraryImports["default"] = it
+	}
+
+	p.recordUsage(it.Ref)
+	return p.handleIdentifier(loc, &js_ast.EIdentifier{Ref: it.Ref}, identifierOpts{
+		wasOriginallyIdentifier: true,
+	})
+}
+
+func (p *parser) callBigIntLibrary(loc logger.Loc, name string, args ...js_ast.Expr) js_ast.Expr {
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+			Target:  p.importBigIntLibrary(loc),
+			Name:    name,
+			NameLoc: loc,
+		}},
+		Args: args,
+		Kind: js_ast.TargetWasOriginallyPropertyAccess,
+	}}
+}
+
+func (p *parser) lowerBigIntLiteral(loc logger.Loc, value string) js_ast.Expr {
+	// "123n" => "JSBI.BigInt('123')"
+	call := p.callBigIntLibrary(loc, "BigInt", js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16(value)}})
+	call.Data.(*js_ast.ECall).CanBeUnwrappedIfUnused = true
+	return call
+}
+
+// These library functions all return a big integer
+var bigIntLibraryResults = map[string]bool{
+	"BigInt":           true,
+	"add":              true,
+	"asIntN":           true,
+	"asUintN":          true,
+	"bitwiseAnd":       true,
+	"bitwiseNot":       true,
+	"bitwiseOr":        true,
+	"bitwiseXor":       true,
+	"divide":           true,
+	"exponentiate":     true,
+	"leftShift":        true,
+	"multiply":         true,
+	"remainder":        true,
+	"signedRightShift": true,
+	"subtract":         true,
+	"unaryMinus":       true,
+}
+
+func (p *parser) isKnownBigInt(expr js_ast.Expr) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.EIdentifier:
+		return p.bigIntConstRefs[e.Ref]
+
+	case *js_ast.ECall:
+		if dot, ok := e.Target.Data.(*js_ast.EDot); ok && bigIntLibraryResults[dot.Name] {
+			return p.isBigIntLibrary(dot.Target)
+		}
+	}
+	return false
+}
+
+func (p *parser) isBigIntLibrary(expr js_ast.Expr) bool {
+	if it, ok := p.bigIntLibraryImports["default"]; ok {
+		switch e := expr.Data.(type) {
+		case *js_ast.EIdentifier:
+			return e.Ref == it.Ref
+		case *js_ast.EImportIdentifier:
+			return e.Ref == it.Ref
+		}
+	}
+	return false
+}
+
+var bigIntBinaryOps = map[js_ast.OpCode]string{
+	js_ast.BinOpAdd:        "add",
+	js_ast.BinOpSub:        "subtract",
+	js_ast.BinOpMul:        "multiply",
+	js_ast.BinOpDiv:        "divide",
+	js_ast.BinOpRem:        "remainder",
+	js_ast.BinOpPow:        "exponentiate",
+	js_ast.BinOpBitwiseAnd: "bitwiseAnd",
+	js_ast.BinOpBitwiseOr:  "bitwiseOr",
+	js_ast.BinOpBitwiseXor: "bitwiseXor",
+	js_ast.BinOpShl:        "leftShift",
+	js_ast.BinOpShr:        "signedRightShift",
+	js_ast.BinOpLooseEq:    "equal",
+	js_ast.BinOpStrictEq:   "equal",
+	js_ast.BinOpLooseNe:    "notEqual",
+	js_ast.BinOpStrictNe:   "notEqual",
+	js_ast.BinOpLt:         "lessThan",
+	js_ast.BinOpLe:         "lessThanOrEqual",
+	js_ast.BinOpGt:         "greaterThan",
+	js_ast.BinOpGe:         "greaterThanOrEqual",
+}
+
+func (p *parser) lowerBigIntBinary(loc logger.Loc, e *js_ast.EBinary) (js_ast.Expr, bool) {
+	name, ok := bigIntBinaryOps[e.Op]
+	if !ok {
+		return js_ast.Expr{}, false
+	}
+
+	left := p.isKnownBigInt(e.Left)
+	right := p.isKnownBigInt(e.Right)
+
+	// "a + b" => "JSBI.add(a, b)"
+	if left && right {
+		return p.callBigIntLibrary(loc, name, e.Left, e.Right), true
+	}
+
+	// We can't tell what the other operand is, so the operator has to be left
+	// alone. That only works if the other operand turns out to be a string.
+	if left || right {
+		kind := logger.Warning
+		if p.suppressWarningsAboutWeirdCode {
+			kind = logger.Debug
+		}
+		r := p.source.RangeOfOperatorBefore(e.Right.Loc, js_ast.OpTable[e.Op].Text)
+		p.log.AddIDWithNotes(logger.MsgID_JS_BigInt, kind, &p.tracker, r,
+			"This operator can't be converted into a call to the big integer library",
+			[]logger.MsgData{{Text: "Both operands must be known to be big integers at compile time. " +
+				"Consider using \"BigInt()\" to convert the other operand into a big integer."}})
+	}
+	return js_ast.Expr{}, false
+}
+
+func (p *parser) lowerBigIntUnary(loc logger.Loc, e *js_ast.EUnary) (js_ast.Expr, bool) {
+	if !p.isKnownBigInt(e.Value) {
+		return js_ast.Expr{}, false
+	}
+
+	switch e.Op {
+	case js_ast.UnOpNeg:
+		// "-123n" => "JSBI.BigInt('-123')"
+		if call, ok := e.Value.Data.(*js_ast.ECall); ok && call.CanBeUnwrappedIfUnused && len(call.Args) == 1 {
+			if str, ok := call.Args[0].Data.(*js_ast.EString); ok && isDecimalDigits(str.Value) {
+				return p.lowerBigIntLiteral(loc, "-"+helpers.UTF16ToString(str.Value)), true
+			}
+		}
+
+		// "-a" => "JSBI.unaryMinus(a)"
+		return p.callBigIntLibrary(loc, "unaryMinus", e.Value), true
+
+	case js_ast.UnOpCpl:
+		// "~a" => "JSBI.bitwiseNot(a)"
+		return p.callBigIntLibrary(loc, "bitwiseNot", e.Value), true
+
+	case js_ast.UnOpTypeof:
+		// "typeof a" => "(a, 'bigint')"
+		return js_ast.JoinWithComma(
+			p.astHelpers.SimplifyUnusedExpr(e.Value, p.options.unsupportedJSFeatures),
+			js_ast.Expr{Loc: loc, Data: &js_ast.EString{Value: helpers.StringToUTF16("bigint")}},
+		), true
+	}
+
+	return js_ast.Expr{}, false
+}
+
+// This converts calls to the global "BigInt" function and its static methods
+// into calls to the equivalent library functions. It also converts "Number()"
+// calls with big integer arguments.
+func (p *parser) lowerBigIntCall(loc logger.Loc, e *js_ast.ECall) (js_ast.Expr, bool) {
+	for _, arg := range e.Args {
+		if _, ok := arg.Data.(*js_ast.ESpread); ok {
+			return js_ast.Expr{}, false
+		}
+	}
+
+	switch target := e.Target.Data.(type) {
+	case *js_ast.EIdentifier:
+		symbol := &p.symbols[target.Ref.InnerIndex]
+		if symbol.Kind != ast.SymbolUnbound {
+			break
+		}
+		switch symbol.OriginalName {
+		case "BigInt":
+			// "BigInt(a)" => "JSBI.BigInt(a)"
+			if len(e.Args) == 1 {
+				p.ignoreUsage(target.Ref)
+				if p.isKnownBigInt(e.Args[0]) {
+					return e.Args[0], true
+				}
+				return p.callBigIntLibrary(loc, "BigInt", e.Args[0]), true
+			}
+
+		case "Number":
+			// "Number(a)" => "JSBI.toNumber(a)"
+			if len(e.Args) == 1 && p.isKnownBigInt(e.Args[0]) {
+				p.ignoreUsage(target.Ref)
+				return p.callBigIntLibrary(loc, "toNumber", e.Args[0]), true
+			}
+		}
+
+	case *js_ast.EDot:
+		// "BigInt.asIntN(a, b)" => "JSBI.asIntN(a, b)"
+		if id, ok := target.Target.Data.(*js_ast.EIdentifier); ok && (target.Name == "asIntN" || target.Name == "asUintN") && len(e.Args) == 2 {
+			if symbol := &p.symbols[id.Ref.InnerIndex]; symbol.Kind == ast.SymbolUnbound && symbol.OriginalName == "BigInt" && p.isKnownBigInt(e.Args[1]) {
+				p.ignoreUsage(id.Ref)
+				return p.callBigIntLibrary(loc, target.Name, e.Args[0], e.Args[1]), true
+			}
+		}
+	}
+
+	return js_ast.Expr{}, false
+}
+
+func (p *parser) recordBigIntConst(ref ast.Ref) {
+	if p.bigIntConstRefs == nil {
+		p.bigIntConstRefs = make(map[ast.Ref]bool)
+	}
+	p.bigIntConstRefs[ref] = true
+}
+
+func isDecimalDigits(text []uint16) bool {
+	for _, c := range text {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return len(text) > 0
+}
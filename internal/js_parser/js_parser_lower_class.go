@@ -609,6 +609,13 @@ func (p *parser) computeClassLoweringInfo(class *js_ast.Class) (result classLowe
 		result.shimSuperCtorCalls = true
 	}
 
+	// We also need to shim "super()" inside the constructor if the class itself
+	// is being lowered to a function, since all "super()" calls must then be
+	// replaced with calls to the base class
+	if p.options.unsupportedJSFeatures.Has(compat.Class) && class.ExtendsOrNil.Data != nil {
+		result.shimSuperCtorCalls = true
+	}
+
 	return
 }
 
@@ -740,7 +747,11 @@ func (p *parser) lowerClass(stmt js_ast.Stmt, expr js_ast.Expr, result visitClas
 	ctx.enableNameCapture(p, result)
 	ctx.processProperties(p, classLoweringInfo, result)
 	ctx.insertInitializersIntoConstructor(p, classLoweringInfo, result)
-	return ctx.finishAndGenerateCode(p, result)
+	stmts, expr := ctx.finishAndGenerateCode(p, result)
+	if p.options.unsupportedJSFeatures.Has(compat.Class) {
+		stmts, expr = ctx.convertClassToFunction(p, result, stmts, expr)
+	}
+	return stmts, expr
 }
 
 func (ctx *lowerClassContext) enableNameCapture(p *parser, result visitClassResult) {
@@ -2400,6 +2411,265 @@ func (ctx *lowerClassContext) finishAndGenerateCode(p *parser, result visitClass
 	return stmts, js_ast.Expr{}
 }
 
+func (ctx *lowerClassContext) convertClassToFunction(p *parser, result visitClassResult, stmts []js_ast.Stmt, expr js_ast.Expr) ([]js_ast.Stmt, js_ast.Expr) {
+	// If classes are unsupported, the class that was generated above is converted
+	// into a constructor function instead. The result looks something like this:
+	//
+	//   // Before
+	//   class Foo extends Bar {
+	//     constructor() { super(1) }
+	//     foo() { return super.foo() }
+	//   }
+	//
+	//   // After
+	//   var Foo = /* @__PURE__ */ function(_super) {
+	//     function Foo() {
+	//       _super.call(this, 1);
+	//     }
+	//     __inherits(Foo, _super);
+	//     __defMethod(Foo.prototype, "foo", function() {
+	//       return __superGet(Foo.prototype, this, "foo").call(this);
+	//     });
+	//     return Foo;
+	//   }(Bar);
+
+	if expr.Data != nil {
+		return nil, ctx.convertClassExprToFunction(p, result, expr)
+	}
+
+	var newStmts []js_ast.Stmt
+	for _, stmt := range stmts {
+		switch s := stmt.Data.(type) {
+		case *js_ast.SClass:
+			stmt.Data = &js_ast.SLocal{
+				IsExport: s.IsExport,
+				Decls: []js_ast.Decl{{
+					Binding:    js_ast.Binding{Loc: s.Class.Name.Loc, Data: &js_ast.BIdentifier{Ref: s.Class.Name.Ref}},
+					ValueOrNil: ctx.generateClassFunction(p, result, stmt.Loc, &s.Class),
+				}},
+			}
+
+		case *js_ast.SExportDefault:
+			// "export default class {}" => "var x = ...; export { x as default }"
+			if s2, ok := s.Value.Data.(*js_ast.SClass); ok {
+				newStmts = append(newStmts, js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SLocal{
+					Decls: []js_ast.Decl{{
+						Binding:    js_ast.Binding{Loc: s.DefaultName.Loc, Data: &js_ast.BIdentifier{Ref: s.DefaultName.Ref}},
+						ValueOrNil: ctx.generateClassFunction(p, result, stmt.Loc, &s2.Class),
+					}},
+				}})
+				stmt.Data = &js_ast.SExportClause{Items: []js_ast.ClauseItem{{Alias: "default", AliasLoc: s.DefaultName.Loc, Name: s.DefaultName}}}
+			}
+
+		case *js_ast.SLocal:
+			for i, decl := range s.Decls {
+				if decl.ValueOrNil.Data != nil {
+					s.Decls[i].ValueOrNil = ctx.convertClassExprToFunction(p, result, decl.ValueOrNil)
+				}
+			}
+		}
+		newStmts = append(newStmts, stmt)
+	}
+	return newStmts, js_ast.Expr{}
+}
+
+func (ctx *lowerClassContext) convertClassExprToFunction(p *parser, result visitClassResult, expr js_ast.Expr) js_ast.Expr {
+	switch e := expr.Data.(type) {
+	case *js_ast.EClass:
+		return ctx.generateClassFunction(p, result, expr.Loc, &e.Class)
+
+	case *js_ast.EBinary:
+		// The class may be stored in a temporary variable and followed by static
+		// initializers: "(_a = class {}, _a.foo = 1, _a)"
+		e.Left = ctx.convertClassExprToFunction(p, result, e.Left)
+		e.Right = ctx.convertClassExprToFunction(p, result, e.Right)
+
+	case *js_ast.ECall:
+		// The temporary variable may be declared using an arrow function:
+		// "((_a) => (_a = class {}, _a.foo = 1, _a))()"
+		if arrow, ok := e.Target.Data.(*js_ast.EArrow); ok && len(arrow.Body.Block.Stmts) == 1 {
+			if ret, ok := arrow.Body.Block.Stmts[0].Data.(*js_ast.SReturn); ok && ret.ValueOrNil.Data != nil {
+				ret.ValueOrNil = ctx.convertClassExprToFunction(p, result, ret.ValueOrNil)
+			}
+		}
+	}
+	return expr
+}
+
+func (ctx *lowerClassContext) generateClassFunction(p *parser, result visitClassResult, loc logger.Loc, class *js_ast.Class) js_ast.Expr {
+	// The constructor function needs a name so that methods can be attached to it
+	var name ast.LocRef
+	if class.Name != nil {
+		name = *class.Name
+	} else {
+		name = ast.LocRef{Loc: loc, Ref: p.generateTempRef(tempRefNoDeclare, "")}
+	}
+	nameFunc := func() js_ast.Expr {
+		p.recordUsage(name.Ref)
+		return js_ast.Expr{Loc: name.Loc, Data: &js_ast.EIdentifier{Ref: name.Ref}}
+	}
+
+	// The base class is passed in as an argument to the wrapper function
+	superRef := ast.InvalidRef
+	var args []js_ast.Arg
+	var values []js_ast.Expr
+	if class.ExtendsOrNil.Data != nil {
+		superRef = p.newSymbol(ast.SymbolOther, "_super")
+		p.currentScope.Generated = append(p.currentScope.Generated, superRef)
+		args = []js_ast.Arg{{Binding: js_ast.Binding{Loc: class.ExtendsOrNil.Loc, Data: &js_ast.BIdentifier{Ref: superRef}}}}
+		values = []js_ast.Expr{class.ExtendsOrNil}
+	}
+
+	// Generate the constructor function
+	ctor := js_ast.Fn{Body: js_ast.FnBody{Loc: class.BodyLoc}}
+	if ctx.ctor != nil {
+		ctor = ctx.ctor.Fn
+	} else if superRef != ast.InvalidRef {
+		// "constructor(...args) { super(...args) }"
+		ctor.Body.Block.Stmts = []js_ast.Stmt{{Loc: class.BodyLoc, Data: &js_ast.SExpr{Value: p.callSuperConstructorForES5(class.BodyLoc, superRef, nil)}}}
+	}
+	if superRef != ast.InvalidRef {
+		ctx.lowerSuperCallsForES5(p, result, &ctor.Body, superRef)
+	}
+	ctor.Name = &name
+	stmts := []js_ast.Stmt{{Loc: loc, Data: &js_ast.SFunction{Fn: ctor}}}
+	if superRef != ast.InvalidRef {
+		p.recordUsage(superRef)
+		stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: p.callRuntime(loc, "__inherits", []js_ast.Expr{
+			nameFunc(),
+			{Loc: loc, Data: &js_ast.EIdentifier{Ref: superRef}},
+		})}})
+	}
+
+	// Define the methods, getters, and setters as non-enumerable properties
+	for _, prop := range class.Properties {
+		if ctx.ctor != nil && prop.ValueOrNil.Data == ctx.ctor {
+			continue
+		}
+		target := nameFunc()
+		if !prop.Flags.Has(js_ast.PropertyIsStatic) {
+			target = js_ast.Expr{Loc: prop.Loc, Data: &js_ast.EDot{Target: target, Name: "prototype", NameLoc: prop.Loc}}
+		}
+		var value js_ast.Expr
+		switch prop.Kind {
+		case js_ast.PropertyMethod:
+			value = p.callRuntime(prop.Loc, "__defMethod", []js_ast.Expr{target, prop.Key, prop.ValueOrNil})
+		case js_ast.PropertyGetter:
+			value = p.callRuntime(prop.Loc, "__defAccessor", []js_ast.Expr{target, prop.Key, prop.ValueOrNil})
+		case js_ast.PropertySetter:
+			value = p.callRuntime(prop.Loc, "__defAccessor", []js_ast.Expr{target, prop.Key, {Loc: prop.Loc, Data: js_ast.EUndefinedShared}, prop.ValueOrNil})
+		default:
+			panic("Internal error")
+		}
+		stmts = append(stmts, js_ast.Stmt{Loc: prop.Loc, Data: &js_ast.SExpr{Value: value}})
+	}
+	stmts = append(stmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: nameFunc()}})
+
+	return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+			Args: args,
+			Body: js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: stmts}},
+		}}},
+		Args:                   values,
+		CanBeUnwrappedIfUnused: result.canBeRemovedIfUnused,
+	}}
+}
+
+// "super(a, b)" => "_super.call(this, a, b)"
+func (p *parser) callSuperConstructorForES5(loc logger.Loc, superRef ast.Ref, args []js_ast.Expr) js_ast.Expr {
+	p.recordUsage(superRef)
+	target := js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: superRef}}
+	this := js_ast.Expr{Loc: loc, Data: js_ast.EThisShared}
+
+	// Forwarding all arguments can just use "arguments" directly
+	forwardArguments := args == nil
+	if len(args) == 1 {
+		if spread, ok := args[0].Data.(*js_ast.ESpread); ok {
+			if id, ok := spread.Value.Data.(*js_ast.EIdentifier); ok && p.symbols[id.Ref.InnerIndex].OriginalName == "arguments" {
+				forwardArguments = true
+			}
+		}
+	}
+	if forwardArguments {
+		argumentsRef := p.newSymbol(ast.SymbolUnbound, "arguments")
+		p.currentScope.Generated = append(p.currentScope.Generated, argumentsRef)
+		return js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: target, Name: "apply", NameLoc: loc}},
+			Args:   []js_ast.Expr{this, {Loc: loc, Data: &js_ast.EIdentifier{Ref: argumentsRef}}},
+			Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+		}}
+	}
+
+	return p.lowerCallSpread(loc, &js_ast.ECall{
+		Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: target, Name: "call", NameLoc: loc}},
+		Args:   append([]js_ast.Expr{this}, args...),
+		Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+	}, true /* hasExplicitThisArg */)
+}
+
+// All "super()" calls were shimmed with "__super()" calls, which means they
+// are either top-level statements in the constructor or they are inside the
+// generated "__super" helper function (see "insertStmtsAfterSuperCall").
+func (ctx *lowerClassContext) lowerSuperCallsForES5(p *parser, result visitClassResult, body *js_ast.FnBody, superRef ast.Ref) {
+	for _, stmt := range body.Block.Stmts {
+		switch s := stmt.Data.(type) {
+		case *js_ast.SExpr:
+			s.Value, _ = p.lowerSuperCallInCommaChainForES5(s.Value, superRef, false)
+
+		case *js_ast.SLocal:
+			// "var __super = (...args) => { super(...args); ...; return this }"
+			// => "var __super = function() { _super.apply(this, arguments); ...; return this }.bind(this)"
+			if len(s.Decls) != 1 {
+				continue
+			}
+			if id, ok := s.Decls[0].Binding.Data.(*js_ast.BIdentifier); !ok || id.Ref != result.superCtorRef {
+				continue
+			}
+			if arrow, ok := s.Decls[0].ValueOrNil.Data.(*js_ast.EArrow); ok && len(arrow.Body.Block.Stmts) > 0 {
+				// The body may have been mangled into "return super(...args), ..., this"
+				switch s2 := arrow.Body.Block.Stmts[0].Data.(type) {
+				case *js_ast.SExpr:
+					s2.Value, _ = p.lowerSuperCallInCommaChainForES5(s2.Value, superRef, true)
+				case *js_ast.SReturn:
+					s2.ValueOrNil, _ = p.lowerSuperCallInCommaChainForES5(s2.ValueOrNil, superRef, true)
+				}
+				loc := s.Decls[0].ValueOrNil.Loc
+				s.Decls[0].ValueOrNil = js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+					Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+						Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{Body: arrow.Body}}},
+						Name:    "bind",
+						NameLoc: loc,
+					}},
+					Args: []js_ast.Expr{{Loc: loc, Data: js_ast.EThisShared}},
+					Kind: js_ast.TargetWasOriginallyPropertyAccess,
+				}}
+			}
+		}
+	}
+}
+
+func (p *parser) lowerSuperCallInCommaChainForES5(expr js_ast.Expr, superRef ast.Ref, forwardArguments bool) (js_ast.Expr, bool) {
+	switch e := expr.Data.(type) {
+	case *js_ast.ECall:
+		if _, ok := e.Target.Data.(*js_ast.ESuper); ok {
+			args := e.Args
+			if forwardArguments {
+				args = nil
+			}
+			return p.callSuperConstructorForES5(expr.Loc, superRef, args), true
+		}
+
+	case *js_ast.EBinary:
+		if e.Op == js_ast.BinOpComma {
+			if left, ok := p.lowerSuperCallInCommaChainForES5(e.Left, superRef, forwardArguments); ok {
+				e.Left = left
+				return expr, true
+			}
+		}
+	}
+	return expr, false
+}
+
 func cloneKeyForLowerClass(key js_ast.Expr) js_ast.Expr {
 	switch k := key.Data.(type) {
 	case *js_ast.ENumber:
@@ -2429,7 +2699,12 @@ func cloneKeyForLowerClass(key js_ast.Expr) js_ast.Expr {
 func (p *parser) insertStmtsAfterSuperCall(body *js_ast.FnBody, stmtsToInsert []js_ast.Stmt, superCtorRef ast.Ref) {
 	// If this class has no base class, then there's no "super()" call to handle
 	if superCtorRef == ast.InvalidRef || p.symbols[superCtorRef.InnerIndex].UseCountEstimate == 0 {
-		body.Block.Stmts = append(stmtsToInsert, body.Block.Stmts...)
+		// Skip over any statements that were generated when lowering arguments
+		// (e.g. default values) since TypeScript parameter properties use them
+		n := p.loweredArgStmtCounts[&body.Block]
+		stmts := append([]js_ast.Stmt{}, body.Block.Stmts[:n]...)
+		stmts = append(stmts, stmtsToInsert...)
+		body.Block.Stmts = append(stmts, body.Block.Stmts[n:]...)
 		return
 	}
 
@@ -0,0 +1,1503 @@
+package js_parser
+
+import (
+	"github.com/evanw/esbuild/internal/ast"
+	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/js_ast"
+	"github.com/evanw/esbuild/internal/logger"
+)
+
+// Generator functions are lowered to a state machine when they are
+// unsupported. Async functions are lowered to generator functions first, so
+// they go through this transform too. This code:
+//
+//   function* foo(a) {
+//     let x = yield a
+//     return x + 1
+//   }
+//
+// is transformed into the following code:
+//
+//   function foo(a) {
+//     var x;
+//     return __gen(this, function(_) {
+//       for (;;) switch (_.n) {
+//         case 0:
+//           _.n = 1;
+//           return [0, a];
+//         case 1:
+//           x = _.v;
+//           return [2, x + 1];
+//       }
+//     });
+//   }
+//
+// The function body is split into cases at each "yield" and at each jump
+// target of any control flow statement that contains a "yield". Variables
+// are hoisted out of the state machine so they keep their values across
+// calls. Loops that need a fresh binding for each iteration were already
+// moved into a nested generator function by "lowerLoopClosure". The "__gen" runtime helper calls the state machine each time the
+// generator is resumed and interprets the instruction that it returns.
+//
+// Statements that don't contain a "yield" are kept mostly as-is. Only "var"
+// declarations, "return" statements, and "break" and "continue" statements
+// that jump out of the statement need to be rewritten.
+
+const (
+	generatorOpYield      = 0 // "[0, value]"
+	generatorOpYieldStar  = 1 // "[1, value]"
+	generatorOpReturn     = 2 // "[2, value]"
+	generatorOpJump       = 3 // "[3, label, depth]"
+	generatorOpEndFinally = 4 // "[4]"
+)
+
+type generatorLabel struct {
+	// These are patched with the case index once all cases have been generated
+	refs      []*js_ast.ENumber
+	caseIndex int
+}
+
+type generatorJumpTarget struct {
+	labels         []ast.Ref
+	breakLabel     int
+	continueLabel  int // This is -1 for statements that can't be continued
+	depth          int
+	isLoopOrSwitch bool
+}
+
+type generatorLowering struct {
+	p             *parser
+	stateRef      ast.Ref
+	cases         [][]js_ast.Stmt
+	labels        []generatorLabel
+	jumps         []generatorJumpTarget
+	pendingLabels []ast.Ref
+	hoistedDecls  []js_ast.Decl
+	hoistedRefs   map[ast.Ref]bool
+	hoistedFns    []js_ast.Stmt
+	tempRefs      map[ast.Ref]bool
+	tryDepth      int
+	usesContinue  bool
+}
+
+// This is used for statements that don't contain a "yield" and so can be
+// kept as-is inside the state machine, except for a few kinds of statements.
+type generatorRewriteContext struct {
+	innerLabels []ast.Ref
+
+	// This is true if the statement is directly in the state machine, in which
+	// case block-scoped declarations must be hoisted too
+	isTopLevel bool
+
+	// These are true if there's a loop or "switch" statement inside of the
+	// statement that an unlabeled "break" or "continue" would jump to
+	isBreakLocal    bool
+	isContinueLocal bool
+}
+
+// This transforms the body of a generator function in place. The first
+// "prefixCount" statements were generated for lowered function arguments.
+// They are evaluated when the function is called, so they are kept outside
+// of the state machine.
+func (p *parser) lowerGeneratorBody(loc logger.Loc, body *js_ast.SBlock, prefixCount int, argumentsRef *ast.Ref) {
+	g := generatorLowering{
+		p:           p,
+		stateRef:    p.newSymbol(ast.SymbolOther, "_"),
+		cases:       [][]js_ast.Stmt{nil},
+		hoistedRefs: make(map[ast.Ref]bool),
+		tempRefs:    make(map[ast.Ref]bool),
+	}
+	p.currentScope.Generated = append(p.currentScope.Generated, g.stateRef)
+
+	// Keep directives and lowered arguments outside of the state machine
+	var directives []js_ast.Stmt
+	var prefix []js_ast.Stmt
+	stmts := body.Stmts
+	for len(stmts) > 0 {
+		if _, ok := stmts[0].Data.(*js_ast.SDirective); ok {
+			directives = append(directives, stmts[0])
+		} else if len(prefix) < prefixCount {
+			prefix = append(prefix, stmts[0])
+		} else {
+			break
+		}
+		stmts = stmts[1:]
+	}
+
+	// The state machine is a separate function with its own "arguments", so
+	// references to "arguments" must be redirected to a variable outside of it:
+	//
+	//   function* foo() { yield arguments }
+	//
+	//   function foo() {
+	//     var _arguments = arguments;
+	//     return __gen(this, function(_) { return [0, _arguments]; });
+	//   }
+	//
+	var outerStmts []js_ast.Stmt
+	outerStmts = append(outerStmts, directives...)
+	if argumentsRef != nil && p.symbolUses[*argumentsRef].CountEstimate > 0 {
+		ref := p.newSymbol(ast.SymbolOther, "_arguments")
+		p.currentScope.Generated = append(p.currentScope.Generated, ref)
+		p.symbols[argumentsRef.InnerIndex].Link = ref
+		value := p.newSymbol(ast.SymbolUnbound, "arguments")
+		p.recordUsage(ref)
+		p.recordUsage(value)
+		outerStmts = append(outerStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: []js_ast.Decl{{
+			Binding:    js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}},
+			ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: value}},
+		}}}})
+	}
+	outerStmts = append(outerStmts, prefix...)
+
+	g.visitStmts(stmts, true /* isFnBody */)
+
+	// Make sure the last case ends the generator instead of looping forever.
+	// This does nothing if the last case already ends with a jump.
+	g.emit(g.opStmt(body.CloseBraceLoc, generatorOpReturn))
+
+	// Now that all cases exist, fill in the case number for each label
+	for _, label := range g.labels {
+		for _, ref := range label.refs {
+			ref.Value = float64(label.caseIndex)
+		}
+	}
+
+	// Avoid generating a "switch" statement if there's only one case
+	var machine []js_ast.Stmt
+	if len(g.cases) == 1 && !g.usesContinue {
+		machine = g.cases[0]
+	} else {
+		cases := make([]js_ast.Case, len(g.cases))
+		for i, stmts := range g.cases {
+			cases[i] = js_ast.Case{
+				Loc:        loc,
+				ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: float64(i)}},
+				Body:       stmts,
+			}
+		}
+		machine = []js_ast.Stmt{{Loc: loc, Data: &js_ast.SFor{Body: js_ast.Stmt{Loc: loc, Data: &js_ast.SSwitch{
+			Test:          g.stateDot(loc, "n"),
+			Cases:         cases,
+			BodyLoc:       loc,
+			CloseBraceLoc: body.CloseBraceLoc,
+		}}}}}
+	}
+
+	if len(g.hoistedDecls) > 0 {
+		outerStmts = append(outerStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SLocal{Kind: js_ast.LocalVar, Decls: g.hoistedDecls}})
+	}
+	outerStmts = append(outerStmts, g.hoistedFns...)
+	outerStmts = append(outerStmts, js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: p.callRuntime(loc, "__gen", []js_ast.Expr{
+		{Loc: loc, Data: js_ast.EThisShared},
+		{Loc: loc, Data: &js_ast.EFunction{Fn: js_ast.Fn{
+			Args: []js_ast.Arg{{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: g.stateRef}}}},
+			Body: js_ast.FnBody{Loc: loc, Block: js_ast.SBlock{Stmts: machine, CloseBraceLoc: body.CloseBraceLoc}},
+		}}},
+	})}})
+	body.Stmts = outerStmts
+}
+
+func (g *generatorLowering) fail(loc logger.Loc) {
+	g.p.markSyntaxFeature(compat.Generator, logger.Range{Loc: loc})
+}
+
+func (g *generatorLowering) emit(stmt js_ast.Stmt) {
+	// Code after a jump is unreachable until the next label
+	current := g.cases[len(g.cases)-1]
+	if len(current) > 0 {
+		switch current[len(current)-1].Data.(type) {
+		case *js_ast.SReturn, *js_ast.SThrow, *js_ast.SContinue:
+			return
+		}
+	}
+	g.cases[len(g.cases)-1] = append(current, stmt)
+}
+
+func (g *generatorLowering) newLabel() int {
+	g.labels = append(g.labels, generatorLabel{caseIndex: -1})
+	return len(g.labels) - 1
+}
+
+// This starts a new case for the label unless the current case is empty
+func (g *generatorLowering) mark(label int) {
+	if len(g.cases[len(g.cases)-1]) > 0 {
+		g.cases = append(g.cases, nil)
+	}
+	g.labels[label].caseIndex = len(g.cases) - 1
+}
+
+func (g *generatorLowering) labelExpr(loc logger.Loc, label int) js_ast.Expr {
+	number := &js_ast.ENumber{}
+	g.labels[label].refs = append(g.labels[label].refs, number)
+	return js_ast.Expr{Loc: loc, Data: number}
+}
+
+func (g *generatorLowering) stateDot(loc logger.Loc, name string) js_ast.Expr {
+	g.p.recordUsage(g.stateRef)
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EDot{
+		Target:  js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: g.stateRef}},
+		Name:    name,
+		NameLoc: loc,
+	}}
+}
+
+func (g *generatorLowering) isStateValue(expr js_ast.Expr) bool {
+	if dot, ok := expr.Data.(*js_ast.EDot); ok && dot.Name == "v" {
+		if id, ok := dot.Target.Data.(*js_ast.EIdentifier); ok && id.Ref == g.stateRef {
+			return true
+		}
+	}
+	return false
+}
+
+// "return [op, ...args]"
+func (g *generatorLowering) opStmt(loc logger.Loc, op int, args ...js_ast.Expr) js_ast.Stmt {
+	items := []js_ast.Expr{{Loc: loc, Data: &js_ast.ENumber{Value: float64(op)}}}
+	for _, arg := range args {
+		if arg.Data != nil {
+			items = append(items, arg)
+		}
+	}
+	return js_ast.Stmt{Loc: loc, Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Loc: loc, Data: &js_ast.EArray{Items: items, IsSingleLine: true}}}}
+}
+
+// Jumps within the same "try" block just continue the state machine loop.
+// Jumps out of "try" blocks go through the runtime so "finally" blocks run.
+func (g *generatorLowering) jumpStmts(loc logger.Loc, label int, depth int, canUseContinue bool) []js_ast.Stmt {
+	if depth == g.tryDepth && canUseContinue {
+		g.usesContinue = true
+		return []js_ast.Stmt{
+			js_ast.AssignStmt(g.stateDot(loc, "n"), g.labelExpr(loc, label)),
+			{Loc: loc, Data: &js_ast.SContinue{}},
+		}
+	}
+	return []js_ast.Stmt{g.opStmt(loc, generatorOpJump, g.labelExpr(loc, label), js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{Value: float64(depth)}})}
+}
+
+func (g *generatorLowering) emitJump(loc logger.Loc, label int, depth int) {
+	for _, stmt := range g.jumpStmts(loc, label, depth, true /* canUseContinue */) {
+		g.emit(stmt)
+	}
+}
+
+func (g *generatorLowering) emitJumpIf(loc logger.Loc, test js_ast.Expr, label int) {
+	if boolean, ok := test.Data.(*js_ast.EBoolean); ok {
+		if boolean.Value {
+			g.emitJump(loc, label, g.tryDepth)
+		}
+		return
+	}
+	g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SIf{
+		Test: test,
+		Yes:  js_ast.Stmt{Loc: loc, Data: &js_ast.SBlock{Stmts: g.jumpStmts(loc, label, g.tryDepth, true /* canUseContinue */)}},
+	}})
+}
+
+func (g *generatorLowering) emitAssign(ref ast.Ref, value js_ast.Expr) {
+	g.emit(js_ast.AssignStmt(g.identifier(value.Loc, ref), value))
+}
+
+func (g *generatorLowering) emitExprStmt(expr js_ast.Expr) {
+	if expr.Data == nil || g.isStateValue(expr) || g.isSafeToReorder(expr) {
+		return
+	}
+	g.emit(js_ast.Stmt{Loc: expr.Loc, Data: &js_ast.SExpr{Value: expr}})
+}
+
+func (g *generatorLowering) identifier(loc logger.Loc, ref ast.Ref) js_ast.Expr {
+	g.p.recordUsage(ref)
+	return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+}
+
+// Variables are declared outside of the state machine. Block-scoped variables
+// are added to the function's scope so they are renamed to avoid collisions.
+func (g *generatorLowering) hoist(loc logger.Loc, ref ast.Ref) {
+	if g.hoistedRefs[ref] {
+		return
+	}
+	g.hoistedRefs[ref] = true
+	g.hoistedDecls = append(g.hoistedDecls, js_ast.Decl{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}})
+	if !g.tempRefs[ref] && g.p.symbols[ref.InnerIndex].Kind != ast.SymbolHoisted {
+		g.p.currentScope.Generated = append(g.p.currentScope.Generated, ref)
+	}
+}
+
+func (g *generatorLowering) hoistBinding(binding js_ast.Binding) {
+	js_ast.ForEachIdentifierBinding(binding, func(loc logger.Loc, b *js_ast.BIdentifier) {
+		g.hoist(loc, b.Ref)
+	})
+}
+
+func (g *generatorLowering) newTemp(loc logger.Loc) ast.Ref {
+	ref := g.p.generateTempRef(tempRefNoDeclare, "")
+	g.tempRefs[ref] = true
+	g.hoist(loc, ref)
+	return ref
+}
+
+// Values that can't change while the generator is suspended don't need to be
+// saved in a temporary variable
+func (g *generatorLowering) isSafeToReorder(expr js_ast.Expr) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.EIdentifier:
+		if g.tempRefs[e.Ref] {
+			return true
+		}
+		symbol := &g.p.symbols[e.Ref.InnerIndex]
+		return symbol.Kind == ast.SymbolConst || symbol.Kind == ast.SymbolImport
+	case *js_ast.EThis, *js_ast.EPrivateIdentifier, *js_ast.EFunction, *js_ast.EArrow, *js_ast.ERegExp:
+		return true
+	case *js_ast.EInlinedEnum:
+		return g.isSafeToReorder(e.Value)
+	}
+	return js_ast.IsPrimitiveLiteral(expr.Data)
+}
+
+func (g *generatorLowering) saveInTemp(expr js_ast.Expr) js_ast.Expr {
+	if g.isSafeToReorder(expr) {
+		return expr
+	}
+	ref := g.newTemp(expr.Loc)
+	g.emitAssign(ref, expr)
+	return g.identifier(expr.Loc, ref)
+}
+
+// This makes a copy of an expression that is safe to reorder, or of a property
+// access whose parts are safe to reorder
+func (g *generatorLowering) clone(expr js_ast.Expr) js_ast.Expr {
+	switch e := expr.Data.(type) {
+	case *js_ast.EIdentifier:
+		return g.identifier(expr.Loc, e.Ref)
+	case *js_ast.EDot:
+		clone := *e
+		clone.Target = g.clone(e.Target)
+		return js_ast.Expr{Loc: expr.Loc, Data: &clone}
+	case *js_ast.EIndex:
+		clone := *e
+		clone.Target = g.clone(e.Target)
+		clone.Index = g.clone(e.Index)
+		return js_ast.Expr{Loc: expr.Loc, Data: &clone}
+	}
+	return expr
+}
+
+func (g *generatorLowering) findJump(label *ast.LocRef, isContinue bool) (generatorJumpTarget, bool) {
+	for i := len(g.jumps) - 1; i >= 0; i-- {
+		jump := g.jumps[i]
+		if isContinue && jump.continueLabel < 0 {
+			continue
+		}
+		if label == nil {
+			if jump.isLoopOrSwitch {
+				return jump, true
+			}
+			continue
+		}
+		for _, ref := range jump.labels {
+			if ref == label.Ref {
+				return jump, true
+			}
+		}
+	}
+	return generatorJumpTarget{}, false
+}
+
+func (g *generatorLowering) pushJump(breakLabel int, continueLabel int, isLoopOrSwitch bool) {
+	g.jumps = append(g.jumps, generatorJumpTarget{
+		labels:         g.pendingLabels,
+		breakLabel:     breakLabel,
+		continueLabel:  continueLabel,
+		depth:          g.tryDepth,
+		isLoopOrSwitch: isLoopOrSwitch,
+	})
+	g.pendingLabels = nil
+}
+
+func (g *generatorLowering) popJump() {
+	g.jumps = g.jumps[:len(g.jumps)-1]
+}
+
+func (g *generatorLowering) visitStmts(stmts []js_ast.Stmt, isFnBody bool) {
+	// Function declarations are hoisted to the top of their block
+	for _, stmt := range stmts {
+		if s, ok := stmt.Data.(*js_ast.SFunction); ok {
+			if isFnBody {
+				g.hoistedFns = append(g.hoistedFns, stmt)
+			} else {
+				g.hoist(s.Fn.Name.Loc, s.Fn.Name.Ref)
+				g.emitAssign(s.Fn.Name.Ref, js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EFunction{Fn: s.Fn}})
+			}
+		}
+	}
+
+	for _, stmt := range stmts {
+		if _, ok := stmt.Data.(*js_ast.SFunction); !ok {
+			g.visitStmt(stmt)
+		}
+	}
+}
+
+func (g *generatorLowering) visitStmt(stmt js_ast.Stmt) {
+	loc := stmt.Loc
+
+	if !generatorStmtContainsYield(stmt) {
+		switch s := stmt.Data.(type) {
+		case *js_ast.SBreak, *js_ast.SContinue:
+			// Jump directly from the state machine
+			if block, ok := g.rewriteStmt(generatorRewriteContext{isTopLevel: true}, stmt).Data.(*js_ast.SBlock); ok {
+				for _, stmt := range block.Stmts {
+					g.emit(stmt)
+				}
+				return
+			}
+
+		case *js_ast.SLocal:
+			if s.Kind.IsUsing() {
+				g.fail(loc)
+				return
+			}
+		}
+
+		stmt = g.rewriteStmt(generatorRewriteContext{isTopLevel: true}, stmt)
+		if _, ok := stmt.Data.(*js_ast.SEmpty); !ok {
+			g.emit(stmt)
+		}
+		return
+	}
+
+	switch s := stmt.Data.(type) {
+	case *js_ast.SBlock:
+		g.visitStmts(s.Stmts, false /* isFnBody */)
+
+	case *js_ast.SExpr:
+		g.emitExprStmt(g.explode(s.Value))
+
+	case *js_ast.SReturn:
+		g.emit(g.opStmt(loc, generatorOpReturn, g.explode(s.ValueOrNil)))
+
+	case *js_ast.SThrow:
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SThrow{Value: g.explode(s.Value)}})
+
+	case *js_ast.SLocal:
+		if s.Kind.IsUsing() {
+			g.fail(loc)
+			return
+		}
+		for _, decl := range s.Decls {
+			g.hoistBinding(decl.Binding)
+			if decl.ValueOrNil.Data != nil {
+				g.emit(js_ast.AssignStmt(js_ast.ConvertBindingToExpr(decl.Binding, nil), g.explode(decl.ValueOrNil)))
+			} else if s.Kind != js_ast.LocalVar {
+				g.emit(js_ast.AssignStmt(js_ast.ConvertBindingToExpr(decl.Binding, nil), js_ast.Expr{Loc: loc, Data: js_ast.EUndefinedShared}))
+			}
+		}
+
+	case *js_ast.SIf:
+		test := g.explode(s.Test)
+		if !generatorStmtContainsYield(s.Yes) && (s.NoOrNil.Data == nil || !generatorStmtContainsYield(s.NoOrNil)) {
+			ctx := generatorRewriteContext{}
+			clone := *s
+			clone.Test = test
+			clone.Yes = g.rewriteStmt(ctx, s.Yes)
+			if s.NoOrNil.Data != nil {
+				clone.NoOrNil = g.rewriteStmt(ctx, s.NoOrNil)
+			}
+			g.emit(js_ast.Stmt{Loc: loc, Data: &clone})
+			break
+		}
+		end := g.newLabel()
+		if s.NoOrNil.Data == nil {
+			g.emitJumpIf(loc, js_ast.Not(test), end)
+			g.visitStmt(s.Yes)
+		} else {
+			otherwise := g.newLabel()
+			g.emitJumpIf(loc, js_ast.Not(test), otherwise)
+			g.visitStmt(s.Yes)
+			g.emitJump(loc, end, g.tryDepth)
+			g.mark(otherwise)
+			g.visitStmt(s.NoOrNil)
+		}
+		g.mark(end)
+
+	case *js_ast.SWhile:
+		top, end := g.newLabel(), g.newLabel()
+		g.mark(top)
+		g.emitJumpIf(loc, js_ast.Not(g.explode(s.Test)), end)
+		g.pushJump(end, top, true)
+		g.visitStmt(s.Body)
+		g.popJump()
+		g.emitJump(loc, top, g.tryDepth)
+		g.mark(end)
+
+	case *js_ast.SDoWhile:
+		top, next, end := g.newLabel(), g.newLabel(), g.newLabel()
+		g.mark(top)
+		g.pushJump(end, next, true)
+		g.visitStmt(s.Body)
+		g.popJump()
+		g.mark(next)
+		g.emitJumpIf(loc, g.explode(s.Test), top)
+		g.mark(end)
+
+	case *js_ast.SFor:
+		labels := g.pendingLabels
+		g.pendingLabels = nil
+		if s.InitOrNil.Data != nil {
+			g.visitStmt(s.InitOrNil)
+		}
+		top, next, end := g.newLabel(), g.newLabel(), g.newLabel()
+		g.mark(top)
+		if s.TestOrNil.Data != nil {
+			g.emitJumpIf(loc, js_ast.Not(g.explode(s.TestOrNil)), end)
+		}
+		g.pendingLabels = labels
+		g.pushJump(end, next, true)
+		g.visitStmt(s.Body)
+		g.popJump()
+		g.mark(next)
+		if s.UpdateOrNil.Data != nil {
+			g.emitExprStmt(g.explode(s.UpdateOrNil))
+		}
+		g.emitJump(loc, top, g.tryDepth)
+		g.mark(end)
+
+	case *js_ast.SForIn:
+		g.visitForIn(loc, s)
+
+	case *js_ast.SSwitch:
+		labels := g.pendingLabels
+		g.pendingLabels = nil
+		test := g.explode(s.Test)
+		if !g.isSafeToReorder(test) {
+			ref := g.newTemp(test.Loc)
+			g.emitAssign(ref, test)
+			test = g.identifier(test.Loc, ref)
+		}
+		end := g.newLabel()
+		caseLabels := make([]int, len(s.Cases))
+		defaultLabel := end
+		for i, c := range s.Cases {
+			caseLabels[i] = g.newLabel()
+			if c.ValueOrNil.Data == nil {
+				defaultLabel = caseLabels[i]
+				continue
+			}
+			g.emitJumpIf(c.Loc, js_ast.Expr{Loc: c.Loc, Data: &js_ast.EBinary{
+				Op:    js_ast.BinOpStrictEq,
+				Left:  g.clone(test),
+				Right: g.explode(c.ValueOrNil),
+			}}, caseLabels[i])
+		}
+		g.emitJump(loc, defaultLabel, g.tryDepth)
+		g.pendingLabels = labels
+		g.pushJump(end, -1, true)
+		for i, c := range s.Cases {
+			g.mark(caseLabels[i])
+			g.visitStmts(c.Body, false /* isFnBody */)
+		}
+		g.popJump()
+		g.mark(end)
+
+	case *js_ast.SLabel:
+		g.pendingLabels = append(g.pendingLabels, s.Name.Ref)
+		switch s.Stmt.Data.(type) {
+		case *js_ast.SFor, *js_ast.SForIn, *js_ast.SWhile, *js_ast.SDoWhile, *js_ast.SLabel:
+			g.visitStmt(s.Stmt)
+		default:
+			end := g.newLabel()
+			g.pushJump(end, -1, false)
+			g.visitStmt(s.Stmt)
+			g.popJump()
+			g.mark(end)
+		}
+
+	case *js_ast.STry:
+		// "try" blocks are registered with the runtime when they are entered:
+		//
+		//   try { a = yield b } catch (e) { c(e) } finally { d() }
+		//
+		//   _.t.push([2, 3]);
+		//   _.n = 1;
+		//   return [0, b];
+		// case 1:
+		//   a = _.v;
+		//   return [3, 4, 0];
+		// case 2:
+		//   e = _.v;
+		//   c(e);
+		//   return [3, 4, 0];
+		// case 3:
+		//   d();
+		//   return [4];
+		// case 4:
+		//
+		catchLabel, finallyLabel, end := -1, -1, g.newLabel()
+		entry := []js_ast.Expr{{Loc: loc, Data: &js_ast.ENumber{}}, {Loc: loc, Data: &js_ast.ENumber{}}}
+		if s.Catch != nil {
+			catchLabel = g.newLabel()
+			entry[0] = g.labelExpr(loc, catchLabel)
+		}
+		if s.Finally != nil {
+			finallyLabel = g.newLabel()
+			entry[1] = g.labelExpr(loc, finallyLabel)
+		}
+		g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.stateDot(loc, "t"), Name: "push", NameLoc: loc}},
+			Args:   []js_ast.Expr{{Loc: loc, Data: &js_ast.EArray{Items: entry, IsSingleLine: true}}},
+			Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+		}}}})
+		g.tryDepth++
+		g.visitStmts(s.Block.Stmts, false /* isFnBody */)
+		g.emitJump(s.Block.CloseBraceLoc, end, g.tryDepth-1)
+		if s.Catch != nil {
+			g.mark(catchLabel)
+			if s.Catch.BindingOrNil.Data != nil {
+				g.hoistBinding(s.Catch.BindingOrNil)
+				g.emit(js_ast.AssignStmt(js_ast.ConvertBindingToExpr(s.Catch.BindingOrNil, nil), g.stateDot(s.Catch.Loc, "v")))
+			}
+			g.visitStmts(s.Catch.Block.Stmts, false /* isFnBody */)
+			g.emitJump(s.Catch.Block.CloseBraceLoc, end, g.tryDepth-1)
+		}
+		if s.Finally != nil {
+			g.mark(finallyLabel)
+			g.visitStmts(s.Finally.Block.Stmts, false /* isFnBody */)
+			g.emit(g.opStmt(s.Finally.Block.CloseBraceLoc, generatorOpEndFinally))
+		}
+		g.tryDepth--
+		g.mark(end)
+
+	default:
+		// This includes "for-of" loops, "with" statements, and classes
+		g.fail(loc)
+	}
+}
+
+// The keys are collected up front and then iterated over. Keys that are
+// deleted before they are reached are skipped:
+//
+//	for (x in y) yield x
+//
+//	_b = y;
+//	_a = [];
+//	for (_c in _b) _a.push(_c);
+//	_d = 0;
+//
+// case 1:
+//
+//	if (!(_d < _a.length)) { _.n = 3; continue; }
+//	if (!(_a[_d] in _b)) { _.n = 2; continue; }
+//	x = _a[_d];
+//	_.n = 2;
+//	return [0, x];
+//
+// case 2:
+//
+//	_d++;
+//	_.n = 1;
+//	continue;
+//
+// case 3:
+func (g *generatorLowering) visitForIn(loc logger.Loc, s *js_ast.SForIn) {
+	labels := g.pendingLabels
+	g.pendingLabels = nil
+
+	object := g.explode(s.Value)
+	if !g.isSafeToReorder(object) {
+		ref := g.newTemp(object.Loc)
+		g.emitAssign(ref, object)
+		object = g.identifier(object.Loc, ref)
+	}
+	keysRef, keyRef, indexRef := g.newTemp(loc), g.newTemp(loc), g.newTemp(loc)
+	g.emitAssign(keysRef, js_ast.Expr{Loc: loc, Data: &js_ast.EArray{}})
+	g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SForIn{
+		Init:  js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: g.identifier(loc, keyRef)}},
+		Value: g.clone(object),
+		Body: js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: loc, Data: &js_ast.ECall{
+			Target: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.identifier(loc, keysRef), Name: "push", NameLoc: loc}},
+			Args:   []js_ast.Expr{g.identifier(loc, keyRef)},
+			Kind:   js_ast.TargetWasOriginallyPropertyAccess,
+		}}}},
+		IsSingleLineBody: true,
+	}})
+	g.emitAssign(indexRef, js_ast.Expr{Loc: loc, Data: &js_ast.ENumber{}})
+
+	key := func() js_ast.Expr {
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIndex{Target: g.identifier(loc, keysRef), Index: g.identifier(loc, indexRef)}}
+	}
+	top, next, end := g.newLabel(), g.newLabel(), g.newLabel()
+	g.mark(top)
+	g.emitJumpIf(loc, js_ast.Not(js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+		Op:    js_ast.BinOpLt,
+		Left:  g.identifier(loc, indexRef),
+		Right: js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.identifier(loc, keysRef), Name: "length", NameLoc: loc}},
+	}}), end)
+	g.emitJumpIf(loc, js_ast.Not(js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+		Op:    js_ast.BinOpIn,
+		Left:  key(),
+		Right: g.clone(object),
+	}}), next)
+
+	switch init := s.Init.Data.(type) {
+	case *js_ast.SLocal:
+		if len(init.Decls) == 1 {
+			g.hoistBinding(init.Decls[0].Binding)
+			g.emit(js_ast.AssignStmt(js_ast.ConvertBindingToExpr(init.Decls[0].Binding, nil), key()))
+		}
+	case *js_ast.SExpr:
+		g.emit(js_ast.AssignStmt(g.explodeAssignTarget(init.Value, false), key()))
+	}
+
+	g.pendingLabels = labels
+	g.pushJump(end, next, true)
+	g.visitStmt(s.Body)
+	g.popJump()
+	g.mark(next)
+	g.emit(js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: js_ast.Expr{Loc: loc, Data: &js_ast.EUnary{
+		Op:    js_ast.UnOpPostInc,
+		Value: g.identifier(loc, indexRef),
+	}}}})
+	g.emitJump(loc, top, g.tryDepth)
+	g.mark(end)
+}
+
+// This rewrites a statement that doesn't contain a "yield" so that it can be
+// moved into the state machine
+func (g *generatorLowering) rewriteStmt(ctx generatorRewriteContext, stmt js_ast.Stmt) js_ast.Stmt {
+	loc := stmt.Loc
+	nested := ctx
+	nested.isTopLevel = false
+
+	switch s := stmt.Data.(type) {
+	case *js_ast.SBlock:
+		s.Stmts = g.rewriteStmts(nested, s.Stmts)
+
+	case *js_ast.SReturn:
+		return g.opStmt(loc, generatorOpReturn, s.ValueOrNil)
+
+	case *js_ast.SLocal:
+		if s.Kind == js_ast.LocalVar || ctx.isTopLevel {
+			if value := g.rewriteLocal(s); value.Data != nil {
+				return js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: value}}
+			}
+			return js_ast.Stmt{Loc: loc, Data: js_ast.SEmptyShared}
+		}
+
+	case *js_ast.SClass:
+		if ctx.isTopLevel {
+			// "class Foo {}" => "Foo = class Foo {}"
+			g.hoist(s.Class.Name.Loc, s.Class.Name.Ref)
+			return js_ast.AssignStmt(g.identifier(s.Class.Name.Loc, s.Class.Name.Ref), js_ast.Expr{Loc: loc, Data: &js_ast.EClass{Class: s.Class}})
+		}
+
+	case *js_ast.SIf:
+		s.Yes = g.rewriteStmt(nested, s.Yes)
+		if s.NoOrNil.Data != nil {
+			s.NoOrNil = g.rewriteStmt(nested, s.NoOrNil)
+		}
+
+	case *js_ast.SFor:
+		if init, ok := s.InitOrNil.Data.(*js_ast.SLocal); ok && init.Kind == js_ast.LocalVar {
+			if value := g.rewriteLocal(init); value.Data != nil {
+				s.InitOrNil.Data = &js_ast.SExpr{Value: value}
+			} else {
+				s.InitOrNil.Data = nil
+			}
+		}
+		s.Body = g.rewriteStmt(g.loopContext(nested), s.Body)
+
+	case *js_ast.SForIn:
+		g.rewriteForInOrOfInit(&s.Init)
+		s.Body = g.rewriteStmt(g.loopContext(nested), s.Body)
+
+	case *js_ast.SForOf:
+		g.rewriteForInOrOfInit(&s.Init)
+		s.Body = g.rewriteStmt(g.loopContext(nested), s.Body)
+
+	case *js_ast.SWhile:
+		s.Body = g.rewriteStmt(g.loopContext(nested), s.Body)
+
+	case *js_ast.SDoWhile:
+		s.Body = g.rewriteStmt(g.loopContext(nested), s.Body)
+
+	case *js_ast.SWith:
+		s.Body = g.rewriteStmt(nested, s.Body)
+
+	case *js_ast.SLabel:
+		nested.innerLabels = append(nested.innerLabels, s.Name.Ref)
+		s.Stmt = g.rewriteStmt(nested, s.Stmt)
+
+	case *js_ast.STry:
+		s.Block.Stmts = g.rewriteStmts(nested, s.Block.Stmts)
+		if s.Catch != nil {
+			s.Catch.Block.Stmts = g.rewriteStmts(nested, s.Catch.Block.Stmts)
+		}
+		if s.Finally != nil {
+			s.Finally.Block.Stmts = g.rewriteStmts(nested, s.Finally.Block.Stmts)
+		}
+
+	case *js_ast.SSwitch:
+		nested.isBreakLocal = true
+		for i := range s.Cases {
+			s.Cases[i].Body = g.rewriteStmts(nested, s.Cases[i].Body)
+		}
+
+	case *js_ast.SBreak:
+		if s.Label == nil && ctx.isBreakLocal || s.Label != nil && g.isInnerLabel(ctx, s.Label.Ref) {
+			break
+		}
+		if jump, ok := g.findJump(s.Label, false); ok {
+			return js_ast.Stmt{Loc: loc, Data: &js_ast.SBlock{Stmts: g.jumpStmts(loc, jump.breakLabel, jump.depth, !ctx.isContinueLocal)}}
+		}
+
+	case *js_ast.SContinue:
+		if s.Label == nil && ctx.isContinueLocal || s.Label != nil && g.isInnerLabel(ctx, s.Label.Ref) {
+			break
+		}
+		if jump, ok := g.findJump(s.Label, true); ok {
+			return js_ast.Stmt{Loc: loc, Data: &js_ast.SBlock{Stmts: g.jumpStmts(loc, jump.continueLabel, jump.depth, !ctx.isContinueLocal)}}
+		}
+	}
+
+	return stmt
+}
+
+func (g *generatorLowering) rewriteStmts(ctx generatorRewriteContext, stmts []js_ast.Stmt) []js_ast.Stmt {
+	end := 0
+	for _, stmt := range stmts {
+		stmt = g.rewriteStmt(ctx, stmt)
+		if _, ok := stmt.Data.(*js_ast.SEmpty); !ok {
+			stmts[end] = stmt
+			end++
+		}
+	}
+	return stmts[:end]
+}
+
+func (g *generatorLowering) loopContext(ctx generatorRewriteContext) generatorRewriteContext {
+	ctx.isBreakLocal = true
+	ctx.isContinueLocal = true
+	return ctx
+}
+
+func (g *generatorLowering) isInnerLabel(ctx generatorRewriteContext, ref ast.Ref) bool {
+	for _, label := range ctx.innerLabels {
+		if label == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// "var a = 1, b" => "a = 1"
+func (g *generatorLowering) rewriteLocal(s *js_ast.SLocal) (value js_ast.Expr) {
+	for _, decl := range s.Decls {
+		g.hoistBinding(decl.Binding)
+		if decl.ValueOrNil.Data != nil {
+			value = js_ast.JoinWithComma(value, js_ast.Assign(js_ast.ConvertBindingToExpr(decl.Binding, nil), decl.ValueOrNil))
+		} else if s.Kind != js_ast.LocalVar {
+			value = js_ast.JoinWithComma(value, js_ast.Assign(js_ast.ConvertBindingToExpr(decl.Binding, nil),
+				js_ast.Expr{Loc: decl.Binding.Loc, Data: js_ast.EUndefinedShared}))
+		}
+	}
+	return
+}
+
+// "for (var x in y)" => "for (x in y)"
+func (g *generatorLowering) rewriteForInOrOfInit(init *js_ast.Stmt) {
+	if local, ok := init.Data.(*js_ast.SLocal); ok && local.Kind == js_ast.LocalVar && len(local.Decls) == 1 {
+		g.hoistBinding(local.Decls[0].Binding)
+		init.Data = &js_ast.SExpr{Value: js_ast.ConvertBindingToExpr(local.Decls[0].Binding, nil)}
+	}
+}
+
+// This emits the code for everything in the expression before the last
+// "yield" and returns an expression without any "yield" for the rest of it.
+// Values computed before a "yield" are saved in temporary variables when
+// needed to preserve the order of evaluation:
+//
+//	a = b() + (yield c)
+//
+//	_a = b();
+//	_.n = 1;
+//	return [0, c];
+//
+// case 1:
+//
+//	a = _a + _.v;
+func (g *generatorLowering) explode(expr js_ast.Expr) js_ast.Expr {
+	if !generatorExprContainsYield(expr) {
+		return expr
+	}
+	loc := expr.Loc
+
+	switch e := expr.Data.(type) {
+	case *js_ast.EYield:
+		var value js_ast.Expr
+		if e.ValueOrNil.Data != nil {
+			value = g.explode(e.ValueOrNil)
+		}
+		op := generatorOpYield
+		if e.IsStar {
+			op = generatorOpYieldStar
+		}
+		label := g.newLabel()
+		g.emit(js_ast.AssignStmt(g.stateDot(loc, "n"), g.labelExpr(loc, label)))
+		g.emit(g.opStmt(loc, op, value))
+		g.mark(label)
+		return g.stateDot(loc, "v")
+
+	case *js_ast.EBinary:
+		switch e.Op {
+		case js_ast.BinOpComma:
+			g.emitExprStmt(g.explode(e.Left))
+			return g.explode(e.Right)
+
+		case js_ast.BinOpLogicalAnd, js_ast.BinOpLogicalOr, js_ast.BinOpNullishCoalescing:
+			// "a && (yield b)" => "_a = a; if (_a) { _a = yield b }"
+			left := g.explode(e.Left)
+			if !generatorExprContainsYield(e.Right) {
+				e.Left = left
+				return expr
+			}
+			ref := g.newTemp(loc)
+			end := g.newLabel()
+			g.emitAssign(ref, left)
+			g.emitJumpIf(loc, g.shortCircuitTest(loc, e.Op, ref), end)
+			g.emitAssign(ref, g.explode(e.Right))
+			g.mark(end)
+			return g.identifier(loc, ref)
+
+		case js_ast.BinOpLogicalAndAssign, js_ast.BinOpLogicalOrAssign, js_ast.BinOpNullishCoalescingAssign:
+			// "a.b ||= yield c" => "_a = a.b; if (!_a) { _a = a.b = yield c }"
+			target := g.explodeAssignTarget(e.Left, true)
+			if !generatorExprContainsYield(e.Right) {
+				e.Left = target
+				return expr
+			}
+			ref := g.newTemp(loc)
+			end := g.newLabel()
+			g.emitAssign(ref, g.clone(target))
+			op := js_ast.BinOpLogicalAnd
+			if e.Op == js_ast.BinOpLogicalOrAssign {
+				op = js_ast.BinOpLogicalOr
+			} else if e.Op == js_ast.BinOpNullishCoalescingAssign {
+				op = js_ast.BinOpNullishCoalescing
+			}
+			g.emitJumpIf(loc, g.shortCircuitTest(loc, op, ref), end)
+			g.emitAssign(ref, js_ast.Assign(target, g.explode(e.Right)))
+			g.mark(end)
+			return g.identifier(loc, ref)
+
+		case js_ast.BinOpAssign:
+			e.Left = g.explodeAssignTarget(e.Left, generatorExprContainsYield(e.Right))
+			e.Right = g.explode(e.Right)
+			return expr
+		}
+
+		if e.Op.BinaryAssignTarget() == js_ast.AssignTargetUpdate {
+			// "a += yield b" => "_a = a; a = _a + (yield b)"
+			target := g.explodeAssignTarget(e.Left, generatorExprContainsYield(e.Right))
+			if !generatorExprContainsYield(e.Right) {
+				e.Left = target
+				return expr
+			}
+			ref := g.newTemp(loc)
+			g.emitAssign(ref, g.clone(target))
+			return js_ast.Assign(target, js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{
+				Op:    generatorBinaryOpForAssignOp(e.Op),
+				Left:  g.identifier(loc, ref),
+				Right: g.explode(e.Right),
+			}})
+		}
+
+		g.explodeSlots([]*js_ast.Expr{&e.Left, &e.Right})
+		return expr
+
+	case *js_ast.EUnary:
+		if e.Op.UnaryAssignTarget() != js_ast.AssignTargetNone {
+			e.Value = g.explodeAssignTarget(e.Value, false)
+		} else {
+			e.Value = g.explode(e.Value)
+		}
+		return expr
+
+	case *js_ast.EIf:
+		// "a ? yield b : c" => "if (a) { _a = yield b } else { _a = c }"
+		test := g.explode(e.Test)
+		if !generatorExprContainsYield(e.Yes) && !generatorExprContainsYield(e.No) {
+			e.Test = test
+			return expr
+		}
+		ref := g.newTemp(loc)
+		otherwise, end := g.newLabel(), g.newLabel()
+		g.emitJumpIf(loc, js_ast.Not(test), otherwise)
+		g.emitAssign(ref, g.explode(e.Yes))
+		g.emitJump(loc, end, g.tryDepth)
+		g.mark(otherwise)
+		g.emitAssign(ref, g.explode(e.No))
+		g.mark(end)
+		return g.identifier(loc, ref)
+
+	case *js_ast.ECall:
+		if _, ok := e.Target.Data.(*js_ast.ESuper); ok {
+			g.fail(loc)
+			return expr
+		}
+
+		// Method calls must keep the value of "this" if the arguments contain a
+		// "yield", so they are turned into ".call()" instead:
+		//
+		//   a.b(yield c) => "_a = a.b; _a.call(a, yield c)"
+		//
+		argsContainYield := false
+		for _, arg := range e.Args {
+			if generatorExprContainsYield(arg) {
+				argsContainYield = true
+				break
+			}
+		}
+		if argsContainYield && e.OptionalChain == js_ast.OptionalChainNone {
+			var object js_ast.Expr
+			switch target := e.Target.Data.(type) {
+			case *js_ast.EDot:
+				target.Target = g.saveInTemp(g.explode(target.Target))
+				object = target.Target
+			case *js_ast.EIndex:
+				g.explodeSlots([]*js_ast.Expr{&target.Target, &target.Index})
+				target.Target = g.saveInTemp(target.Target)
+				object = target.Target
+			}
+			if object.Data != nil {
+				ref := g.newTemp(loc)
+				g.emitAssign(ref, e.Target)
+				args := make([]*js_ast.Expr, len(e.Args))
+				for i := range e.Args {
+					args[i] = &e.Args[i]
+				}
+				g.explodeSlots(args)
+				e.Target = js_ast.Expr{Loc: loc, Data: &js_ast.EDot{Target: g.identifier(loc, ref), Name: "call", NameLoc: loc}}
+				e.Args = append([]js_ast.Expr{g.clone(object)}, e.Args...)
+				e.Kind = js_ast.TargetWasOriginallyPropertyAccess
+				return expr
+			}
+		}
+
+		if !generatorExprContainsYield(e.Target) {
+			args := make([]*js_ast.Expr, len(e.Args))
+			for i := range e.Args {
+				args[i] = &e.Args[i]
+			}
+			if _, ok := e.Target.Data.(*js_ast.EIdentifier); ok {
+				// Don't save an identifier in a temporary variable since that would
+				// turn a direct "eval" call into an indirect one
+				g.explodeSlots(args)
+			} else {
+				g.explodeSlots(append([]*js_ast.Expr{&e.Target}, args...))
+			}
+			return expr
+		}
+
+		switch target := e.Target.Data.(type) {
+		case *js_ast.EDot:
+			target.Target = g.explode(target.Target)
+		case *js_ast.EIndex:
+			g.explodeSlots([]*js_ast.Expr{&target.Target, &target.Index})
+		default:
+			e.Target = g.explode(e.Target)
+		}
+		return expr
+
+	case *js_ast.ENew:
+		slots := []*js_ast.Expr{&e.Target}
+		for i := range e.Args {
+			slots = append(slots, &e.Args[i])
+		}
+		g.explodeSlots(slots)
+		return expr
+
+	case *js_ast.EArray:
+		slots := make([]*js_ast.Expr, len(e.Items))
+		for i := range e.Items {
+			slots[i] = &e.Items[i]
+		}
+		g.explodeSlots(slots)
+		return expr
+
+	case *js_ast.EObject:
+		var slots []*js_ast.Expr
+		for i := range e.Properties {
+			property := &e.Properties[i]
+			if property.Flags.Has(js_ast.PropertyIsComputed) {
+				slots = append(slots, &property.Key)
+			}
+			if property.ValueOrNil.Data != nil {
+				slots = append(slots, &property.ValueOrNil)
+			}
+			if property.InitializerOrNil.Data != nil {
+				slots = append(slots, &property.InitializerOrNil)
+			}
+		}
+		g.explodeSlots(slots)
+		return expr
+
+	case *js_ast.EDot:
+		e.Target = g.explode(e.Target)
+		return expr
+
+	case *js_ast.EIndex:
+		g.explodeSlots([]*js_ast.Expr{&e.Target, &e.Index})
+		return expr
+
+	case *js_ast.ESpread:
+		e.Value = g.explode(e.Value)
+		return expr
+
+	case *js_ast.ETemplate:
+		var slots []*js_ast.Expr
+		if e.TagOrNil.Data != nil {
+			slots = append(slots, &e.TagOrNil)
+		}
+		for i := range e.Parts {
+			slots = append(slots, &e.Parts[i].Value)
+		}
+		g.explodeSlots(slots)
+		return expr
+
+	case *js_ast.EImportCall:
+		slots := []*js_ast.Expr{&e.Expr}
+		if e.OptionsOrNil.Data != nil {
+			slots = append(slots, &e.OptionsOrNil)
+		}
+		g.explodeSlots(slots)
+		return expr
+
+	case *js_ast.EAnnotation:
+		e.Value = g.explode(e.Value)
+		return expr
+	}
+
+	// This includes classes with a "yield" in a computed property key
+	g.fail(loc)
+	return expr
+}
+
+// This evaluates the expressions in order. Anything evaluated before the last
+// expression containing a "yield" is saved in a temporary variable.
+func (g *generatorLowering) explodeSlots(slots []*js_ast.Expr) {
+	last := -1
+	for i, slot := range slots {
+		if generatorExprContainsYield(*slot) {
+			last = i
+		}
+	}
+	for i := 0; i <= last; i++ {
+		slot := slots[i]
+		*slot = g.explode(*slot)
+		if i < last {
+			if spread, ok := slot.Data.(*js_ast.ESpread); ok {
+				spread.Value = g.saveInTemp(spread.Value)
+			} else {
+				*slot = g.saveInTemp(*slot)
+			}
+		}
+	}
+}
+
+// If "save" is true, the parts of the property access are saved in temporary
+// variables so that the assignment target can be evaluated again later
+func (g *generatorLowering) explodeAssignTarget(target js_ast.Expr, save bool) js_ast.Expr {
+	switch t := target.Data.(type) {
+	case *js_ast.EIdentifier:
+		return target
+
+	case *js_ast.EDot:
+		t.Target = g.explode(t.Target)
+		if save {
+			t.Target = g.saveInTemp(t.Target)
+		}
+		return target
+
+	case *js_ast.EIndex:
+		t.Target = g.explode(t.Target)
+		if save || generatorExprContainsYield(t.Index) {
+			t.Target = g.saveInTemp(t.Target)
+		}
+		t.Index = g.explode(t.Index)
+		if save {
+			t.Index = g.saveInTemp(t.Index)
+		}
+		return target
+
+	case *js_ast.EArray, *js_ast.EObject:
+		// Destructuring assignments are evaluated after the value
+		if generatorExprContainsYield(target) {
+			g.fail(target.Loc)
+		}
+		return target
+	}
+
+	return g.explode(target)
+}
+
+func (g *generatorLowering) shortCircuitTest(loc logger.Loc, op js_ast.OpCode, ref ast.Ref) js_ast.Expr {
+	value := g.identifier(loc, ref)
+	switch op {
+	case js_ast.BinOpLogicalAnd:
+		return js_ast.Not(value)
+	case js_ast.BinOpLogicalOr:
+		return value
+	default:
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EBinary{Op: js_ast.BinOpLooseNe, Left: value, Right: js_ast.Expr{Loc: loc, Data: js_ast.ENullShared}}}
+	}
+}
+
+func generatorBinaryOpForAssignOp(op js_ast.OpCode) js_ast.OpCode {
+	switch op {
+	case js_ast.BinOpAddAssign:
+		return js_ast.BinOpAdd
+	case js_ast.BinOpSubAssign:
+		return js_ast.BinOpSub
+	case js_ast.BinOpMulAssign:
+		return js_ast.BinOpMul
+	case js_ast.BinOpDivAssign:
+		return js_ast.BinOpDiv
+	case js_ast.BinOpRemAssign:
+		return js_ast.BinOpRem
+	case js_ast.BinOpPowAssign:
+		return js_ast.BinOpPow
+	case js_ast.BinOpShlAssign:
+		return js_ast.BinOpShl
+	case js_ast.BinOpShrAssign:
+		return js_ast.BinOpShr
+	case js_ast.BinOpUShrAssign:
+		return js_ast.BinOpUShr
+	case js_ast.BinOpBitwiseOrAssign:
+		return js_ast.BinOpBitwiseOr
+	case js_ast.BinOpBitwiseAndAssign:
+		return js_ast.BinOpBitwiseAnd
+	case js_ast.BinOpBitwiseXorAssign:
+		return js_ast.BinOpBitwiseXor
+	}
+	panic("Internal error")
+}
+
+// These don't look inside nested functions since "yield" can't cross them
+func generatorExprContainsYield(expr js_ast.Expr) bool {
+	switch e := expr.Data.(type) {
+	case *js_ast.EYield:
+		return true
+
+	case *js_ast.EArray:
+		for _, item := range e.Items {
+			if generatorExprContainsYield(item) {
+				return true
+			}
+		}
+
+	case *js_ast.EUnary:
+		return generatorExprContainsYield(e.Value)
+
+	case *js_ast.EBinary:
+		return generatorExprContainsYield(e.Left) || generatorExprContainsYield(e.Right)
+
+	case *js_ast.ENew:
+		if generatorExprContainsYield(e.Target) {
+			return true
+		}
+		for _, arg := range e.Args {
+			if generatorExprContainsYield(arg) {
+				return true
+			}
+		}
+
+	case *js_ast.ECall:
+		if generatorExprContainsYield(e.Target) {
+			return true
+		}
+		for _, arg := range e.Args {
+			if generatorExprContainsYield(arg) {
+				return true
+			}
+		}
+
+	case *js_ast.EDot:
+		return generatorExprContainsYield(e.Target)
+
+	case *js_ast.EIndex:
+		return generatorExprContainsYield(e.Target) || generatorExprContainsYield(e.Index)
+
+	case *js_ast.EClass:
+		return generatorClassContainsYield(&e.Class)
+
+	case *js_ast.EObject:
+		return generatorPropertiesContainYield(e.Properties)
+
+	case *js_ast.EJSXElement:
+		if generatorExprContainsYield(e.TagOrNil) || generatorPropertiesContainYield(e.Properties) {
+			return true
+		}
+		for _, child := range e.NullableChildren {
+			if generatorExprContainsYield(child) {
+				return true
+			}
+		}
+
+	case *js_ast.ESpread:
+		return generatorExprContainsYield(e.Value)
+
+	case *js_ast.ETemplate:
+		if generatorExprContainsYield(e.TagOrNil) {
+			return true
+		}
+		for _, part := range e.Parts {
+			if generatorExprContainsYield(part.Value) {
+				return true
+			}
+		}
+
+	case *js_ast.EInlinedEnum:
+		return generatorExprContainsYield(e.Value)
+
+	case *js_ast.EAnnotation:
+		return generatorExprContainsYield(e.Value)
+
+	case *js_ast.EAwait:
+		// This only happens when the function isn't being lowered to a state
+		// machine, in which case "await" suspends the function like "yield"
+		return true
+
+	case *js_ast.EIf:
+		return generatorExprContainsYield(e.Test) || generatorExprContainsYield(e.Yes) || generatorExprContainsYield(e.No)
+
+	case *js_ast.EImportCall:
+		return generatorExprContainsYield(e.Expr) || generatorExprContainsYield(e.OptionsOrNil)
+	}
+
+	return false
+}
+
+func generatorPropertiesContainYield(properties []js_ast.Property) bool {
+	for _, property := range properties {
+		if generatorExprContainsYield(property.Key) || generatorExprContainsYield(property.ValueOrNil) ||
+			generatorExprContainsYield(property.InitializerOrNil) {
+			return true
+		}
+	}
+	return false
+}
+
+func generatorClassContainsYield(class *js_ast.Class) bool {
+	if generatorExprContainsYield(class.ExtendsOrNil) {
+		return true
+	}
+	for _, property := range class.Properties {
+		if property.Flags.Has(js_ast.PropertyIsComputed) && generatorExprContainsYield(property.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+func generatorBindingContainsYield(binding js_ast.Binding) bool {
+	switch b := binding.Data.(type) {
+	case *js_ast.BArray:
+		for _, item := range b.Items {
+			if generatorBindingContainsYield(item.Binding) || generatorExprContainsYield(item.DefaultValueOrNil) {
+				return true
+			}
+		}
+
+	case *js_ast.BObject:
+		for _, property := range b.Properties {
+			if generatorExprContainsYield(property.Key) || generatorBindingContainsYield(property.Value) ||
+				generatorExprContainsYield(property.DefaultValueOrNil) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func generatorStmtsContainYield(stmts []js_ast.Stmt) bool {
+	for _, stmt := range stmts {
+		if generatorStmtContainsYield(stmt) {
+			return true
+		}
+	}
+	return false
+}
+
+func generatorStmtContainsYield(stmt js_ast.Stmt) bool {
+	switch s := stmt.Data.(type) {
+	case *js_ast.SBlock:
+		return generatorStmtsContainYield(s.Stmts)
+
+	case *js_ast.SExpr:
+		return generatorExprContainsYield(s.Value)
+
+	case *js_ast.SReturn:
+		return generatorExprContainsYield(s.ValueOrNil)
+
+	case *js_ast.SThrow:
+		return generatorExprContainsYield(s.Value)
+
+	case *js_ast.SLocal:
+		for _, decl := range s.Decls {
+			if generatorBindingContainsYield(decl.Binding) || generatorExprContainsYield(decl.ValueOrNil) {
+				return true
+			}
+		}
+
+	case *js_ast.SClass:
+		return generatorClassContainsYield(&s.Class)
+
+	case *js_ast.SIf:
+		return generatorExprContainsYield(s.Test) || generatorStmtContainsYield(s.Yes) ||
+			(s.NoOrNil.Data != nil && generatorStmtContainsYield(s.NoOrNil))
+
+	case *js_ast.SFor:
+		return (s.InitOrNil.Data != nil && generatorStmtContainsYield(s.InitOrNil)) || generatorExprContainsYield(s.TestOrNil) ||
+			generatorExprContainsYield(s.UpdateOrNil) || generatorStmtContainsYield(s.Body)
+
+	case *js_ast.SForIn:
+		return generatorStmtContainsYield(s.Init) || generatorExprContainsYield(s.Value) || generatorStmtContainsYield(s.Body)
+
+	case *js_ast.SForOf:
+		return generatorStmtContainsYield(s.Init) || generatorExprContainsYield(s.Value) || generatorStmtContainsYield(s.Body)
+
+	case *js_ast.SDoWhile:
+		return generatorStmtContainsYield(s.Body) || generatorExprContainsYield(s.Test)
+
+	case *js_ast.SWhile:
+		return generatorExprContainsYield(s.Test) || generatorStmtContainsYield(s.Body)
+
+	case *js_ast.SWith:
+		return generatorExprContainsYield(s.Value) || generatorStmtContainsYield(s.Body)
+
+	case *js_ast.SLabel:
+		return generatorStmtContainsYield(s.Stmt)
+
+	case *js_ast.STry:
+		if generatorStmtsContainYield(s.Block.Stmts) {
+			return true
+		}
+		if s.Catch != nil && (generatorBindingContainsYield(s.Catch.BindingOrNil) || generatorStmtsContainYield(s.Catch.Block.Stmts)) {
+			return true
+		}
+		return s.Finally != nil && generatorStmtsContainYield(s.Finally.Block.Stmts)
+
+	case *js_ast.SSwitch:
+		if generatorExprContainsYield(s.Test) {
+			return true
+		}
+		for _, c := range s.Cases {
+			if generatorExprContainsYield(c.ValueOrNil) || generatorStmtsContainYield(c.Body) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -548,6 +548,275 @@ func TestLowerClassStaticBlocks(t *testing.T) {
 	expectPrintedMangleTarget(t, 2015, "class Foo { static { x } static {} static { y } }", "class Foo {\n}\nx, y;\n")
 }
 
+func TestLowerClassES5(t *testing.T) {
+	expectPrintedTarget(t, 5, "class Foo { constructor(x) { this.x = x } foo() {} static bar() {} get x() {} set x(v) {} }",
+		"var Foo = /* @__PURE__ */ function() {\n  function Foo(x) {\n    this.x = x;\n  }\n  __defMethod(Foo.prototype, \"foo\", function() {\n  });\n  __defMethod(Foo, \"bar\", function() {\n  });\n  __defAccessor(Foo.prototype, \"x\", function() {\n  });\n  __defAccessor(Foo.prototype, \"x\", void 0, function(v) {\n  });\n  return Foo;\n}();\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar {}",
+		"var Foo = function(_super) {\n  function Foo() {\n    _super.apply(this, arguments);\n  }\n  __inherits(Foo, _super);\n  return Foo;\n}(Bar);\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { constructor() { super(1, 2) } }",
+		"var Foo = function(_super) {\n  function Foo() {\n    _super.call(this, 1, 2);\n  }\n  __inherits(Foo, _super);\n  return Foo;\n}(Bar);\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { constructor(...args) { super(...args) } }",
+		"var Foo = function(_super) {\n  function Foo() {\n    var __super = function() {\n      _super.apply(this, arguments);\n      return this;\n    }.bind(this);\n    var args = [].slice.call(arguments, 0);\n    __super.apply(void 0, __toArray(args));\n  }\n  __inherits(Foo, _super);\n  return Foo;\n}(Bar);\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { foo() { return super.foo() } static bar() { super.bar = 1 } }",
+		"var Foo = function(_super) {\n  function Foo() {\n    _super.apply(this, arguments);\n  }\n  __inherits(Foo, _super);\n  __defMethod(Foo.prototype, \"foo\", function() {\n    return __superGet(Foo.prototype, this, \"foo\").call(this);\n  });\n  __defMethod(Foo, \"bar\", function() {\n    __superSet(Foo, this, \"bar\", 1);\n  });\n  return Foo;\n}(Bar);\n")
+	expectPrintedTarget(t, 5, "class Foo extends Bar { x = 1; constructor() { if (y) super(); else super(1) } }",
+		"var Foo = function(_super) {\n  function Foo() {\n    var __super = function() {\n      _super.apply(this, arguments);\n      __publicField(this, \"x\", 1);\n      return this;\n    }.bind(this);\n    if (y) __super();\n    else __super(1);\n  }\n  __inherits(Foo, _super);\n  return Foo;\n}(Bar);\n")
+	expectPrintedTarget(t, 5, "export default class extends Bar {}",
+		"var stdin_default = function(_super) {\n  function _a() {\n    _super.apply(this, arguments);\n  }\n  __inherits(_a, _super);\n  return _a;\n}(Bar);\nexport {\n  stdin_default as default\n};\n")
+	expectPrintedTarget(t, 5, "let Foo = class Bar { static x = Bar }",
+		"var _a;\nvar Foo = (_a = /* @__PURE__ */ function() {\n  function _b() {\n  }\n  return _b;\n}(), __publicField(_a, \"x\", _a), _a);\n")
+	expectPrintedTarget(t, 5, "({ foo() { return super.foo() } })",
+		"var _a;\n_a = { foo: function() {\n  return __superGet(_a, this, \"foo\").call(this);\n} };\n")
+}
+
+func TestLowerBlockScopingES5(t *testing.T) {
+	expectPrintedTarget(t, 5, "{ let x = 1; const y = 2 }",
+		"{\n  var x = 1;\n  var y = 2;\n}\n")
+	expectPrintedTarget(t, 5, "for (let i = 0; i < 3; i++) fns.push(() => i)",
+		"var _loop = function(i) {\n  fns.push(function() {\n    return i;\n  });\n};\nfor (var i = 0; i < 3; i++) {\n  _loop(i);\n}\n")
+	expectPrintedTarget(t, 5, "for (let i = 0; i < 3; i++) { if (i) continue; fns.push(() => i); if (i) break }",
+		"var _ret;\nvar _loop = function(i) {\n  if (i) return;\n  fns.push(function() {\n    return i;\n  });\n  if (i) return \"break\";\n};\nfor (var i = 0; i < 3; i++) {\n  _ret = _loop(i);\n  if (_ret === \"break\")\n    break;\n}\n")
+	expectPrintedTarget(t, 5, "for (const x of y) fns.push(() => x)",
+		"var _loop = function(x) {\n  fns.push(function() {\n    return x;\n  });\n};\ntry {\n  for (var iter = __iter(y), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n    var x = temp.value;\n    _loop(x);\n  }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
+	expectPrintedTarget(t, 5, "function f() { for (let x in y) { if (x) return x; fns.push(() => x) } }",
+		"function f() {\n  var _ret;\n  var _loop = function(x) {\n    if (x) return { v: x };\n    fns.push(function() {\n      return x;\n    });\n  };\n  for (var x in y) {\n    _ret = _loop(x);\n    if (typeof _ret === \"object\")\n      return _ret.v;\n  }\n}\n")
+	expectPrintedTarget(t, 5, "function f() { for (let i = 0; i < 3; i++) fns.push(() => i + arguments[0]) }",
+		"function f() {\n  var _arguments = arguments;\n  var _loop = function(i) {\n    fns.push(function() {\n      return i + _arguments[0];\n    });\n  };\n  for (var i = 0; i < 3; i++) {\n    _loop(i);\n  }\n}\n")
+	expectPrintedTarget(t, 5, "function f() { for (let i = 0; i < 3; i++) { fns.push(() => i); x = arguments.length } }",
+		"function f() {\n  var _arguments = arguments;\n  var _loop = function(i) {\n    fns.push(function() {\n      return i;\n    });\n    x = _arguments.length;\n  };\n  for (var i = 0; i < 3; i++) {\n    _loop(i);\n  }\n}\n")
+	expectPrintedTarget(t, 5, "function f() { for (let i = 0; i < 3; i++) { fns.push(() => i); this.x = i } }",
+		"function f() {\n  var _loop = function(i) {\n    fns.push(function() {\n      return i;\n    });\n    this.x = i;\n  };\n  for (var i = 0; i < 3; i++) {\n    _loop.call(this, i);\n  }\n}\n")
+	expectPrintedTarget(t, 5, "function f() { for (let i = 0; i < 3; i++) x = arguments[i] }",
+		"function f() {\n  for (var i = 0; i < 3; i++) x = arguments[i];\n}\n")
+	expectParseErrorWithUnsupportedFeatures(t, compat.ConstAndLet, "function* f() { for (let i = 0; i < 3; i++) { fns.push(() => i); yield } }",
+		"<stdin>: ERROR: Transforming closures over loop variables in generator and async functions to the configured target environment is not supported yet\n")
+	expectParseErrorWithUnsupportedFeatures(t, compat.ConstAndLet, "async function f() { for (const x of y) { fns.push(() => x); await x } }",
+		"<stdin>: ERROR: Transforming closures over loop variables in generator and async functions to the configured target environment is not supported yet\n")
+	expectPrintedWithUnsupportedFeatures(t, compat.ConstAndLet, "function* f() { for (let i = 0; i < 3; i++) fns.push(() => i) }",
+		"function* f() {\n  var _loop = function(i) {\n    fns.push(() => i);\n  };\n  for (var i = 0; i < 3; i++) {\n    _loop(i);\n  }\n}\n")
+	expectParseErrorWithUnsupportedFeatures(t, compat.ConstAndLet, "function* f() { for (let i = 0; i < 3; i++) yield i }", "")
+}
+
+func TestLowerDestructuringES5(t *testing.T) {
+	expectPrintedTarget(t, 5, "let [a, b = 2, ...c] = d",
+		"var _a = __toArray(d), a = _a[0], _b = _a[1], b = _b === void 0 ? 2 : _b, c = _a.slice(2);\n")
+	expectPrintedTarget(t, 5, "let { a, b: { c }, ...e } = f",
+		"var a = f.a, c = f.b.c, e = __objRest(f, [\"a\", \"b\"]);\n")
+	expectPrintedTarget(t, 5, "for (const [k, v] of m) ;",
+		"try {\n  for (var iter = __iter(m), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n    var _a = temp.value;\n    var _b = __toArray(_a, 2), k = _b[0], v = _b[1];\n  }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
+}
+
+func TestLowerGeneratorES5(t *testing.T) {
+	expectPrintedTarget(t, 5, "function* f() { yield 1; yield 2 }",
+		"function f() {\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          _.n = 1;\n"+
+			"          return [0, 1];\n"+
+			"        case 1:\n"+
+			"          _.n = 2;\n"+
+			"          return [0, 2];\n"+
+			"        case 2:\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { return yield }",
+		"function f() {\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          _.n = 1;\n"+
+			"          return [0];\n"+
+			"        case 1:\n"+
+			"          return [2, _.v];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { while (a) yield b }",
+		"function f() {\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          if (!a) {\n"+
+			"            _.n = 2;\n"+
+			"            continue;\n"+
+			"          }\n"+
+			"          _.n = 1;\n"+
+			"          return [0, b];\n"+
+			"        case 1:\n"+
+			"          _.n = 0;\n"+
+			"          continue;\n"+
+			"        case 2:\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { if (a) yield b; else c() }",
+		"function f() {\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          if (!a) {\n"+
+			"            _.n = 2;\n"+
+			"            continue;\n"+
+			"          }\n"+
+			"          _.n = 1;\n"+
+			"          return [0, b];\n"+
+			"        case 1:\n"+
+			"          _.n = 3;\n"+
+			"          continue;\n"+
+			"        case 2:\n"+
+			"          c();\n"+
+			"        case 3:\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { try { yield a } catch (e) { b(e) } }",
+		"function f() {\n"+
+			"  var e;\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          _.t.push([2, 0]);\n"+
+			"          _.n = 1;\n"+
+			"          return [0, a];\n"+
+			"        case 1:\n"+
+			"          return [3, 3, 0];\n"+
+			"        case 2:\n"+
+			"          e = _.v;\n"+
+			"          b(e);\n"+
+			"          return [3, 3, 0];\n"+
+			"        case 3:\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { try { yield a } finally { b() } }",
+		"function f() {\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          _.t.push([0, 2]);\n"+
+			"          _.n = 1;\n"+
+			"          return [0, a];\n"+
+			"        case 1:\n"+
+			"          return [3, 3, 0];\n"+
+			"        case 2:\n"+
+			"          b();\n"+
+			"          return [4];\n"+
+			"        case 3:\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { yield* a }",
+		"function f() {\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          _.n = 1;\n"+
+			"          return [1, __yieldStar(a)];\n"+
+			"        case 1:\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { a(b(), yield c) }",
+		"function f() {\n"+
+			"  var _a;\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          _a = b();\n"+
+			"          _.n = 1;\n"+
+			"          return [0, c];\n"+
+			"        case 1:\n"+
+			"          a(_a, _.v);\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { a.b(yield c) }",
+		"function f() {\n"+
+			"  var _a, _b;\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          _a = a;\n"+
+			"          _b = _a.b;\n"+
+			"          _.n = 1;\n"+
+			"          return [0, c];\n"+
+			"        case 1:\n"+
+			"          _b.call(_a, _.v);\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { x = a && (yield b) }",
+		"function f() {\n"+
+			"  var _a;\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          _a = a;\n"+
+			"          if (!_a) {\n"+
+			"            _.n = 2;\n"+
+			"            continue;\n"+
+			"          }\n"+
+			"          _.n = 1;\n"+
+			"          return [0, b];\n"+
+			"        case 1:\n"+
+			"          _a = _.v;\n"+
+			"        case 2:\n"+
+			"          x = _a;\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "function* f() { yield arguments[0] }",
+		"function f() {\n"+
+			"  var _arguments = arguments;\n"+
+			"  return __gen(this, function(_) {\n"+
+			"    for (; ; )\n"+
+			"      switch (_.n) {\n"+
+			"        case 0:\n"+
+			"          _.n = 1;\n"+
+			"          return [0, _arguments[0]];\n"+
+			"        case 1:\n"+
+			"          return [2];\n"+
+			"      }\n"+
+			"  });\n"+
+			"}\n")
+	expectPrintedTarget(t, 5, "async function f() { await a; return b }",
+		"function f() {\n"+
+			"  return __async(this, null, function() {\n"+
+			"    return __gen(this, function(_) {\n"+
+			"      for (; ; )\n"+
+			"        switch (_.n) {\n"+
+			"          case 0:\n"+
+			"            _.n = 1;\n"+
+			"            return [0, a];\n"+
+			"          case 1:\n"+
+			"            return [2, b];\n"+
+			"        }\n"+
+			"    });\n"+
+			"  });\n"+
+			"}\n")
+}
+
 func TestLowerOptionalChain(t *testing.T) {
 	expectPrintedTarget(t, 2019, "a?.b.c", "a == null ? void 0 : a.b.c;\n")
 	expectPrintedTarget(t, 2019, "(a?.b).c", "(a == null ? void 0 : a.b).c;\n")
@@ -777,6 +1046,49 @@ func TestLowerBigInt(t *testing.T) {
 	expectPrintedMangleTarget(t, 2019, "({102030405060708090807060504030201n: x} = y)", "({ \"102030405060708090807060504030201\": x } = y);\n")
 }
 
+func TestLowerBigIntLibrary(t *testing.T) {
+	// The library is only used when the target doesn't support big integers
+	expectPrintedBigIntLibrary(t, 2020, "x = 1n + 2n", "x = 1n + 2n;\n")
+
+	expectPrintedBigIntLibrary(t, 2019, "x = 0n", "import JSBI from \"jsbi\";\nx = /* @__PURE__ */ JSBI.BigInt(\"0\");\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = 0xFFn", "import JSBI from \"jsbi\";\nx = /* @__PURE__ */ JSBI.BigInt(\"0xFF\");\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = -123n", "import JSBI from \"jsbi\";\nx = /* @__PURE__ */ JSBI.BigInt(\"-123\");\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = -0xFFn", "import JSBI from \"jsbi\";\nx = JSBI.unaryMinus(/* @__PURE__ */ JSBI.BigInt(\"0xFF\"));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = ~1n", "import JSBI from \"jsbi\";\nx = JSBI.bitwiseNot(/* @__PURE__ */ JSBI.BigInt(\"1\"));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = typeof 1n", "import JSBI from \"jsbi\";\nx = \"bigint\";\n")
+	expectPrintedBigIntLibrary(t, 2019, "const a = 1n; x = typeof a", "import JSBI from \"jsbi\";\nconst a = /* @__PURE__ */ JSBI.BigInt(\"1\");\nx = \"bigint\";\n")
+
+	expectPrintedBigIntLibrary(t, 2019, "x = 1n + 2n", "import JSBI from \"jsbi\";\nx = JSBI.add(/* @__PURE__ */ JSBI.BigInt(\"1\"), /* @__PURE__ */ JSBI.BigInt(\"2\"));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = 1n - 2n * 3n", "import JSBI from \"jsbi\";\n"+
+		"x = JSBI.subtract(/* @__PURE__ */ JSBI.BigInt(\"1\"), JSBI.multiply(/* @__PURE__ */ JSBI.BigInt(\"2\"), /* @__PURE__ */ JSBI.BigInt(\"3\")));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = 2n ** 64n", "import JSBI from \"jsbi\";\nx = JSBI.exponentiate(/* @__PURE__ */ JSBI.BigInt(\"2\"), /* @__PURE__ */ JSBI.BigInt(\"64\"));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = 1n << 2n >> 1n", "import JSBI from \"jsbi\";\n"+
+		"x = JSBI.signedRightShift(JSBI.leftShift(/* @__PURE__ */ JSBI.BigInt(\"1\"), /* @__PURE__ */ JSBI.BigInt(\"2\")), /* @__PURE__ */ JSBI.BigInt(\"1\"));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = 1n === 2n", "import JSBI from \"jsbi\";\nx = JSBI.equal(/* @__PURE__ */ JSBI.BigInt(\"1\"), /* @__PURE__ */ JSBI.BigInt(\"2\"));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = 1n <= 2n", "import JSBI from \"jsbi\";\nx = JSBI.lessThanOrEqual(/* @__PURE__ */ JSBI.BigInt(\"1\"), /* @__PURE__ */ JSBI.BigInt(\"2\"));\n")
+
+	// Constants initialized to big integers are known to be big integers
+	expectPrintedBigIntLibrary(t, 2019, "const a = 1n; x = a * a",
+		"import JSBI from \"jsbi\";\nconst a = /* @__PURE__ */ JSBI.BigInt(\"1\");\nx = JSBI.multiply(a, a);\n")
+	expectPrintedBigIntLibrary(t, 2019, "let a = 1n; x = a * 2n",
+		"import JSBI from \"jsbi\";\nlet a = /* @__PURE__ */ JSBI.BigInt(\"1\");\nx = a * /* @__PURE__ */ JSBI.BigInt(\"2\");\n")
+
+	// Calls to "BigInt" are converted too
+	expectPrintedBigIntLibrary(t, 2019, "x = BigInt(y) + 1n", "import JSBI from \"jsbi\";\nx = JSBI.add(JSBI.BigInt(y), /* @__PURE__ */ JSBI.BigInt(\"1\"));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = BigInt(1n)", "import JSBI from \"jsbi\";\nx = /* @__PURE__ */ JSBI.BigInt(\"1\");\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = BigInt.asUintN(8, 257n)", "import JSBI from \"jsbi\";\nx = JSBI.asUintN(8, /* @__PURE__ */ JSBI.BigInt(\"257\"));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = Number(1n)", "import JSBI from \"jsbi\";\nx = JSBI.toNumber(/* @__PURE__ */ JSBI.BigInt(\"1\"));\n")
+	expectPrintedBigIntLibrary(t, 2019, "x = BigInt(...y)", "x = BigInt(...y);\n")
+	expectPrintedBigIntLibrary(t, 2019, "let BigInt; x = BigInt(y)", "let BigInt;\nx = BigInt(y);\n")
+
+	// Operators are left alone if only one side is known to be a big integer
+	expectParseErrorBigIntLibrary(t, 2019, "x = y + 1n",
+		"<stdin>: WARNING: This operator can't be converted into a call to the big integer library\n"+
+			"NOTE: Both operands must be known to be big integers at compile time. Consider using \"BigInt()\" to convert the other operand into a big integer.\n")
+	expectParseErrorBigIntLibrary(t, 2019, "x = y + z", "")
+	expectParseErrorBigIntLibrary(t, 2019, "x = 1n", "")
+}
+
 func TestLowerExportStarAs(t *testing.T) {
 	expectPrintedTarget(t, 2020, "export * as ns from 'path'", "export * as ns from \"path\";\n")
 	expectPrintedTarget(t, 2019, "export * as ns from 'path'", "import * as ns from \"path\";\nexport { ns };\n")
@@ -788,17 +1100,14 @@ func TestAsyncGeneratorFns(t *testing.T) {
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait, "(async function () {});", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait, "({ async foo() {} });", err)
 
-	err = "<stdin>: ERROR: Transforming generator functions to the configured target environment is not supported yet\n"
 	expectParseErrorWithUnsupportedFeatures(t, compat.Generator, "function* gen() {}", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.Generator, "(function* () {});", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.Generator, "({ *foo() {} });", err)
 
-	err = "<stdin>: ERROR: Transforming async functions to the configured target environment is not supported yet\n"
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait|compat.Generator, "async function gen() {}", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait|compat.Generator, "(async function () {});", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncAwait|compat.Generator, "({ async foo() {} });", err)
 
-	err = ""
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncGenerator, "async function* gen() {}", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncGenerator, "(async function* () {});", err)
 	expectParseErrorWithUnsupportedFeatures(t, compat.AsyncGenerator, "({ async *foo() {} });", err)
@@ -815,10 +1124,7 @@ func TestForAwait(t *testing.T) {
 	// This is ok because for-await can be lowered to yield
 	expectParseErrorWithUnsupportedFeatures(t, compat.ForAwait|compat.AsyncAwait, "async function gen() { for await (x of y) ; }", err)
 
-	// This is not ok because for-await can't be lowered
-	err =
-		"<stdin>: ERROR: Transforming async functions to the configured target environment is not supported yet\n" +
-			"<stdin>: ERROR: Transforming for-await loops to the configured target environment is not supported yet\n"
+	// This is ok because for-await can be lowered to yield, which can be lowered to a state machine
 	expectParseErrorWithUnsupportedFeatures(t, compat.ForAwait|compat.AsyncAwait|compat.Generator, "async function gen() { for await (x of y) ; }", err)
 
 	// Can't use for-await at the top-level without top-level await
@@ -951,3 +1257,41 @@ _y = new WeakMap();
 __privateAdd(Foo, _y, 2);
 `)
 }
+
+func TestLowerRegExp(t *testing.T) {
+	expectPrintedTarget(t, 2017, "x = /a.b/s", "x = /a[\\s\\S]b/;\n")
+	expectPrintedTarget(t, 2018, "x = /a.b/s", "x = /a.b/s;\n")
+	expectPrintedTarget(t, 2017, "x = /a.b/gs", "x = /a[\\s\\S]b/g;\n")
+
+	expectPrintedTarget(t, 2017, "x = /(?<y>\\d+)-(?<m>\\d+)/",
+		"x = /* @__PURE__ */ __namedGroups(/(\\d+)-(\\d+)/, {\n  y: 1,\n  m: 2\n});\n")
+	expectPrintedTarget(t, 2017, "x = /(?<a>.)\\k<a>/", "x = /* @__PURE__ */ __namedGroups(/(.)\\1/, {\n  a: 1\n});\n")
+	expectPrintedTarget(t, 2018, "x = /(?<a>.)\\k<a>/", "x = /(?<a>.)\\k<a>/;\n")
+
+	expectPrintedTarget(t, 2017, "x = /\\p{Script=Ogham}/u", "x = /[\\u1680-\\u169C]/u;\n")
+	expectPrintedTarget(t, 2017, "x = /\\p{ASCII_Hex_Digit}+/u", "x = /[0-9A-Fa-f]+/u;\n")
+	expectPrintedTarget(t, 2017, "x = /\\P{ASCII}/", "x = /\\P{ASCII}/;\n")
+	expectPrintedTarget(t, 5, "x = /\\p{ASCII_Hex_Digit}+/u", "x = /[0-9A-Fa-f]+/;\n")
+	expectPrintedTarget(t, 2018, "x = /\\p{Lu}/u", "x = /\\p{Lu}/u;\n")
+
+	expectPrintedTarget(t, 5, "x = /😀+/u", "x = /(?:\\uD83D\\uDE00)+/;\n")
+	expectPrintedTarget(t, 5, "x = /./u", "x = /(?:[\\uD800-\\uDBFF][\\uDC00-\\uDFFF]|[\\x00-\\t\\v\\f\\x0E-\\u2027\\u202A-\\uFFFF])/;\n")
+	expectPrintedTarget(t, 2015, "x = /./u", "x = /./u;\n")
+	expectPrintedTarget(t, 5, "x = /\\ud83d/u", "x = /\\uD83D(?![\\uDC00-\\uDFFF])/;\n")
+	expectPrintedTarget(t, 5, "x = /\\udf06/u", "x = new RegExp(\"\\\\udf06\", \"u\");\n")
+
+	expectPrintedTarget(t, 2023, "x = /[[a-z]--[aeiou]]/v", "x = /[b-df-hj-np-tv-z]/u;\n")
+	expectPrintedTarget(t, 2023, "x = /[\\d&&[0-4]]/v", "x = /[0-4]/u;\n")
+	expectPrintedTarget(t, 2023, "x = /[\\q{abc|d}x]/v", "x = /(?:abc|[dx])/u;\n")
+	expectPrintedTarget(t, 2024, "x = /[[a-z]--[aeiou]]/v", "x = /[[a-z]--[aeiou]]/v;\n")
+
+	expectParseErrorTarget(t, 2017, "x = /a.b/s", "")
+	expectParseErrorTarget(t, 2017, "x = /(?<=a)b/",
+		"<stdin>: WARNING: Lookbehind assertions in regular expressions are not available in the configured target environment\n"+
+			"NOTE: This regular expression literal has been converted to a \"new RegExp()\" constructor to avoid generating code with a syntax error. However, you will need to include a polyfill for \"RegExp\" for your code to have the correct behavior at run-time.\n")
+	expectPrintedTarget(t, 2017, "x = /(?<=a)b/", "x = new RegExp(\"(?<=a)b\");\n")
+	expectParseErrorTarget(t, 2020, "x = /a/y", "")
+	expectParseErrorTarget(t, 5, "x = /a/y",
+		"<stdin>: WARNING: The regular expression flag \"y\" is not available in the configured target environment\n"+
+			"NOTE: This regular expression literal has been converted to a \"new RegExp()\" constructor to avoid generating code with a syntax error. However, you will need to include a polyfill for \"RegExp\" for your code to have the correct behavior at run-time.\n")
+}
@@ -124,6 +124,26 @@ func expectPrintedMangleTarget(t *testing.T, esVersion int, contents string, exp
 	})
 }
 
+func expectPrintedBigIntLibrary(t *testing.T, esVersion int, contents string, expected string) {
+	t.Helper()
+	expectPrintedCommon(t, contents, expected, config.Options{
+		UnsupportedJSFeatures: compat.UnsupportedJSFeatures(map[compat.Engine]compat.Semver{
+			compat.ES: {Parts: []int{esVersion}},
+		}),
+		BigIntLibrary: "jsbi",
+	})
+}
+
+func expectParseErrorBigIntLibrary(t *testing.T, esVersion int, contents string, expected string) {
+	t.Helper()
+	expectParseErrorCommon(t, contents, expected, config.Options{
+		UnsupportedJSFeatures: compat.UnsupportedJSFeatures(map[compat.Engine]compat.Semver{
+			compat.ES: {Parts: []int{esVersion}},
+		}),
+		BigIntLibrary: "jsbi",
+	})
+}
+
 func expectPrintedASCII(t *testing.T, contents string, expected string) {
 	t.Helper()
 	expectPrintedCommon(t, contents, expected, config.Options{
@@ -1252,6 +1272,10 @@ func TestLocal(t *testing.T) {
 	expectParseError(t, "var x = 0; x++", "")
 	expectParseError(t, "let x = 0; x++", "")
 	expectParseError(t, "const x = 0; x++", errorText)
+
+	expectParseErrorTarget(t, 5, "const x = 0; x = 1", `<stdin>: WARNING: This assignment to the constant "x" will not throw because "const" is converted to "var" for the configured target environment
+<stdin>: NOTE: The symbol "x" was declared a constant here:
+`)
 }
 
 func TestArrays(t *testing.T) {
@@ -6364,82 +6388,73 @@ func TestES5(t *testing.T) {
 	expectPrintedTarget(t, 2015, "if (1) function f() {}", "if (1) {\n  let f = function() {\n  };\n  var f = f;\n}\n")
 	expectPrintedTarget(t, 5, "if (1) function f() {}", "if (1) {\n  var f = function() {\n  };\n  var f = f;\n}\n")
 
-	expectParseErrorTarget(t, 5, "function foo(x = 0) {}",
-		"<stdin>: ERROR: Transforming default arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function(x = 0) {})",
-		"<stdin>: ERROR: Transforming default arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(x = 0) => {}",
-		"<stdin>: ERROR: Transforming default arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo(...x) {}",
-		"<stdin>: ERROR: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function(...x) {})",
-		"<stdin>: ERROR: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(...x) => {}",
-		"<stdin>: ERROR: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "foo(...x)",
-		"<stdin>: ERROR: Transforming rest arguments to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "[...x]",
-		"<stdin>: ERROR: Transforming array spread to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "for (var x of y) ;",
-		"<stdin>: ERROR: Transforming for-of loops to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "function foo(x = 0) {}",
+		"function foo(x) {\n  if (x === void 0)\n    x = 0;\n}\n")
+	expectPrintedTarget(t, 5, "(function(x = 0) {})",
+		"(function(x) {\n  if (x === void 0)\n    x = 0;\n});\n")
+	expectPrintedTarget(t, 5, "(x = 0) => {}",
+		"(function(x) {\n  if (x === void 0)\n    x = 0;\n});\n")
+	expectPrintedTarget(t, 5, "function foo(...x) {}",
+		"function foo() {\n  var x = [].slice.call(arguments, 0);\n}\n")
+	expectPrintedTarget(t, 5, "(function(...x) {})",
+		"(function() {\n  var x = [].slice.call(arguments, 0);\n});\n")
+	expectPrintedTarget(t, 5, "(...x) => {}",
+		"(function() {\n  var x = [].slice.call(arguments, 0);\n});\n")
+	expectPrintedTarget(t, 5, "foo(...x)",
+		"foo.apply(void 0, __toArray(x));\n")
+	expectPrintedTarget(t, 5, "[...x]",
+		"[].concat(__toArray(x));\n")
+	expectPrintedTarget(t, 5, "for (var x of y) ;",
+		"try {\n  for (var iter = __iter(y), more, temp, error; more = !(temp = iter.next()).done; more = false) {\n    var x = temp.value;\n  }\n} catch (temp) {\n  error = [temp];\n} finally {\n  try {\n    more && (temp = iter.return) && temp.call(iter);\n  } finally {\n    if (error)\n      throw error[0];\n  }\n}\n")
 	expectPrintedTarget(t, 5, "({ x })", "({ x: x });\n")
-	expectParseErrorTarget(t, 5, "({ [x]: y })",
-		"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({ x() {} });",
-		"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "({ [x]: y })",
+		"var _a;\n_a = {}, __defNormalProp(_a, x, y), _a;\n")
+	expectPrintedTarget(t, 5, "({ x() {} });",
+		"({ x: function() {\n} });\n")
 	expectParseErrorTarget(t, 5, "({ get x() {} });", "")
 	expectParseErrorTarget(t, 5, "({ set x(x) {} });", "")
-	expectParseErrorTarget(t, 5, "({ get [x]() {} });",
-		"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({ set [x](x) {} });",
-		"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo([]) {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo({}) {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function([]) {})",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function({}) {})",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "([]) => {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({}) => {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "var [] = [];",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "var {} = {};",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "([] = []);",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({} = {});",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "for ([] in []);",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "for ({} in []);",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo([...x]) {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function([...x]) {})",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "([...x]) => {}",
-		"<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function foo([...[x]]) {}",
-		`<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming non-identifier array rest patterns to the configured target environment is not supported yet
-`)
-	expectParseErrorTarget(t, 5, "(function([...[x]]) {})",
-		`<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming non-identifier array rest patterns to the configured target environment is not supported yet
-`)
-	expectParseErrorTarget(t, 5, "([...[x]]) => {}",
-		`<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet
-<stdin>: ERROR: Transforming non-identifier array rest patterns to the configured target environment is not supported yet
-`)
-	expectParseErrorTarget(t, 5, "([...[x]])",
-		"<stdin>: ERROR: Transforming array spread to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "({ get [x]() {} });",
+		"var _b;\n_b = {}, __defAccessor(_b, x, function() {\n}, void 0, true), _b;\n")
+	expectPrintedTarget(t, 5, "({ set [x](x) {} });",
+		"var _b;\n_b = {}, __defAccessor(_b, x, void 0, function(x) {\n}, true), _b;\n")
+	expectPrintedTarget(t, 5, "function foo([]) {}",
+		"function foo(_a) {\n  var _b = __toArray(_a, 0);\n}\n")
+	expectPrintedTarget(t, 5, "function foo({}) {}",
+		"function foo(_a) {\n  var _b = __objRest(_a, []);\n}\n")
+	expectPrintedTarget(t, 5, "(function([]) {})",
+		"(function(_a) {\n  var _b = __toArray(_a, 0);\n});\n")
+	expectPrintedTarget(t, 5, "(function({}) {})",
+		"(function(_a) {\n  var _b = __objRest(_a, []);\n});\n")
+	expectPrintedTarget(t, 5, "([]) => {}",
+		"(function(_a) {\n  var _b = __toArray(_a, 0);\n});\n")
+	expectPrintedTarget(t, 5, "({}) => {}",
+		"(function(_a) {\n  var _b = __objRest(_a, []);\n});\n")
+	expectPrintedTarget(t, 5, "var [] = [];",
+		"var _a = __toArray([], 0);\n")
+	expectPrintedTarget(t, 5, "var {} = {};",
+		"var _a = __objRest({}, []);\n")
+	expectPrintedTarget(t, 5, "([] = []);",
+		"var _a;\n_a = __toArray([], 0);\n")
+	expectPrintedTarget(t, 5, "({} = {});",
+		"var _a;\n_a = __objRest({}, []);\n")
+	expectPrintedTarget(t, 5, "for ([] in []);",
+		"var _a, _b;\nfor (_a in []) {\n  _b = __toArray(_a, 0);\n}\n")
+	expectPrintedTarget(t, 5, "for ({} in []);",
+		"var _a, _b;\nfor (_a in []) {\n  _b = __objRest(_a, []);\n}\n")
+	expectPrintedTarget(t, 5, "function foo([...x]) {}",
+		"function foo(_a) {\n  var _b = __toArray(_a), x = _b.slice(0);\n}\n")
+	expectPrintedTarget(t, 5, "(function([...x]) {})",
+		"(function(_a) {\n  var _b = __toArray(_a), x = _b.slice(0);\n});\n")
+	expectPrintedTarget(t, 5, "([...x]) => {}",
+		"(function(_a) {\n  var _b = __toArray(_a), x = _b.slice(0);\n});\n")
+	expectPrintedTarget(t, 5, "function foo([...[x]]) {}",
+		"function foo(_a) {\n  var _b = __toArray(_a), _c = __toArray(_b.slice(0), 1), x = _c[0];\n}\n")
+	expectPrintedTarget(t, 5, "(function([...[x]]) {})",
+		"(function(_a) {\n  var _b = __toArray(_a), _c = __toArray(_b.slice(0), 1), x = _c[0];\n});\n")
+	expectPrintedTarget(t, 5, "([...[x]]) => {}",
+		"(function(_a) {\n  var _b = __toArray(_a), _c = __toArray(_b.slice(0), 1), x = _c[0];\n});\n")
+	expectPrintedTarget(t, 5, "([...[x]])",
+		"[].concat(__toArray([x]));\n")
 	expectPrintedTarget(t, 5, "`abc`;", "\"abc\";\n")
 	expectPrintedTarget(t, 5, "`a${b}`;", "\"a\".concat(b);\n")
 	expectPrintedTarget(t, 5, "`${a}b`;", "\"\".concat(a, \"b\");\n")
@@ -6458,27 +6473,27 @@ func TestES5(t *testing.T) {
 	expectPrintedTarget(t, 5, "tag`a${b}\\u`;", "var _a;\ntag(_a || (_a = __template([\"a\", void 0], [\"a\", \"\\\\u\"])), b);\n")
 	expectPrintedTarget(t, 5, "tag`\\u${b}c`;", "var _a;\ntag(_a || (_a = __template([void 0, \"c\"], [\"\\\\u\", \"c\"])), b);\n")
 	expectParseErrorTarget(t, 5, "class Foo { constructor() { new.target } }",
-		"<stdin>: ERROR: Transforming class syntax to the configured target environment is not supported yet\n"+
-			"<stdin>: ERROR: Transforming object literal extensions to the configured target environment is not supported yet\n"+
-			"<stdin>: ERROR: Transforming new.target to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "const x = 1;",
-		"<stdin>: ERROR: Transforming const to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "let x = 2;",
-		"<stdin>: ERROR: Transforming let to the configured target environment is not supported yet\n")
+		"<stdin>: ERROR: Transforming new.target to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "const x = 1;",
+		"var x = 1;\n")
+	expectPrintedTarget(t, 5, "let x = 2;",
+		"var x = 2;\n")
 	expectPrintedTarget(t, 5, "async => foo;", "(function(async) {\n  return foo;\n});\n")
 	expectPrintedTarget(t, 5, "x => x;", "(function(x) {\n  return x;\n});\n")
-	expectParseErrorTarget(t, 5, "async () => foo;",
-		"<stdin>: ERROR: Transforming async functions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "class Foo {}",
-		"<stdin>: ERROR: Transforming class syntax to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(class {});",
-		"<stdin>: ERROR: Transforming class syntax to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "function* gen() {}",
-		"<stdin>: ERROR: Transforming generator functions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "(function* () {});",
-		"<stdin>: ERROR: Transforming generator functions to the configured target environment is not supported yet\n")
-	expectParseErrorTarget(t, 5, "({ *foo() {} });",
-		"<stdin>: ERROR: Transforming generator functions to the configured target environment is not supported yet\n")
+	expectPrintedTarget(t, 5, "async () => foo;", "(function() {\n  return __async(null, null, function() {\n    return __gen(this, function(_) {\n      return [2, foo];\n    });\n  });\n});\n")
+	expectPrintedTarget(t, 5, "class Foo {}",
+		"var Foo = /* @__PURE__ */ function() {\n  function Foo() {\n  }\n  return Foo;\n}();\n")
+	expectPrintedTarget(t, 5, "(class {});",
+		"/* @__PURE__ */ (function() {\n  function _a() {\n  }\n  return _a;\n})();\n")
+	expectPrintedTarget(t, 5, "function* gen() {}", "function gen() {\n  return __gen(this, function(_) {\n    return [2];\n  });\n}\n")
+	expectPrintedTarget(t, 5, "(function* () {});", "(function() {\n  return __gen(this, function(_) {\n    return [2];\n  });\n});\n")
+	expectPrintedTarget(t, 5, "({ *foo() {} });", "({ foo: function() {\n  return __gen(this, function(_) {\n    return [2];\n  });\n} });\n")
+
+	// Closures in loops containing "yield" must capture a fresh binding for each iteration
+	expectPrintedTarget(t, 5, "function* f() { for (let i = 0; i < 3; i++) { r.push(() => i); yield i } }",
+		"function f() {\n  var _loop, i;\n  return __gen(this, function(_) {\n    for (; ; )\n      switch (_.n) {\n        case 0:\n          _loop = function(i) {\n            return __gen(this, function(_) {\n              for (; ; )\n                switch (_.n) {\n                  case 0:\n                    r.push(function() {\n                      return i;\n                    });\n                    _.n = 1;\n                    return [0, i];\n                  case 1:\n                    return [2];\n                }\n            });\n          };\n          i = 0;\n        case 1:\n          if (!(i < 3)) {\n            _.n = 3;\n            continue;\n          }\n          _.n = 2;\n          return [1, _loop(i)];\n        case 2:\n          i++;\n          _.n = 1;\n          continue;\n        case 3:\n          return [2];\n      }\n  });\n}\n")
+	expectPrintedTarget(t, 5, "async function f() { for (const v of [1, 2, 3]) { r.push(() => v); await v } }",
+		"function f() {\n  return __async(this, null, function() {\n    var _loop, iter, more, temp, error, v;\n    return __gen(this, function(_) {\n      for (; ; )\n        switch (_.n) {\n          case 0:\n            _loop = function(v) {\n              return __gen(this, function(_) {\n                for (; ; )\n                  switch (_.n) {\n                    case 0:\n                      r.push(function() {\n                        return v;\n                      });\n                      _.n = 1;\n                      return [0, v];\n                    case 1:\n                      return [2];\n                  }\n              });\n            };\n            _.t.push([4, 5]);\n            iter = __iter([1, 2, 3]);\n          case 1:\n            if (!(more = !(temp = iter.next()).done)) {\n              _.n = 3;\n              continue;\n            }\n            v = temp.value;\n            _.n = 2;\n            return [1, _loop(v)];\n          case 2:\n            more = false;\n            _.n = 1;\n            continue;\n          case 3:\n            return [3, 6, 0];\n          case 4:\n            temp = _.v;\n            error = [temp];\n            return [3, 6, 0];\n          case 5:\n            try {\n              more && (temp = iter.return) && temp.call(iter);\n            } finally {\n              if (error)\n                throw error[0];\n            }\n            return [4];\n          case 6:\n            return [2];\n        }\n    });\n  });\n}\n")
 }
 
 func TestASCIIOnly(t *testing.T) {
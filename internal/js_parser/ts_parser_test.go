@@ -2368,8 +2368,8 @@ func TestTSArrow(t *testing.T) {
 	expectPrintedTS(t, "function x(): ({y: z}) {}", "function x() {\n}\n")
 
 	expectParseErrorTargetTS(t, 5, "return check ? (hover = 2, bar) : baz()", "")
-	expectParseErrorTargetTS(t, 5, "return check ? (hover = 2, bar) => 0 : baz()",
-		"<stdin>: ERROR: Transforming default arguments to the configured target environment is not supported yet\n")
+	expectPrintedTargetTS(t, 5, "return check ? (hover = 2, bar) => 0 : baz()",
+		"return check ? function(hover, bar) {\n  if (hover === void 0)\n    hover = 2;\n  return 0;\n} : baz();\n")
 
 	// https://github.com/evanw/esbuild/issues/4027
 	expectPrintedTS(t, "function f(async?) { g(async in x) }", "function f(async) {\n  g(async in x);\n}\n")
@@ -3293,8 +3293,8 @@ func TestTSES5(t *testing.T) {
 	expectPrintedTargetTS(t, 5, "0 ? ({}) : 0", "0 ? {} : 0;\n")
 	expectPrintedTargetTS(t, 2015, "0 ? ([]): 0 => 0 : 0", "0 ? ([]) => 0 : 0;\n")
 	expectPrintedTargetTS(t, 2015, "0 ? ({}): 0 => 0 : 0", "0 ? ({}) => 0 : 0;\n")
-	expectParseErrorTargetTS(t, 5, "0 ? ([]): 0 => 0 : 0", "<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
-	expectParseErrorTargetTS(t, 5, "0 ? ({}): 0 => 0 : 0", "<stdin>: ERROR: Transforming destructuring to the configured target environment is not supported yet\n")
+	expectPrintedTargetTS(t, 5, "0 ? ([]): 0 => 0 : 0", "0 ? function(_a) {\n  var _b = __toArray(_a, 0);\n  return 0;\n} : 0;\n")
+	expectPrintedTargetTS(t, 5, "0 ? ({}): 0 => 0 : 0", "0 ? function(_a) {\n  var _b = __objRest(_a, []);\n  return 0;\n} : 0;\n")
 }
 
 func TestTSUsing(t *testing.T) {
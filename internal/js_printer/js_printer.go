@@ -256,6 +256,12 @@ func (p *printer) printJSXTag(tagOrNil js_ast.Expr) {
 		p.print(helpers.UTF16ToString(e.Value))
 
 	case *js_ast.EIdentifier:
+		if p.isCrossChunkReference(e.Ref) {
+			// "<import_chunk.Foo>"
+			p.addSourceMapping(tagOrNil.Loc)
+			p.printSymbol(e.Ref)
+			break
+		}
 		name := p.renamer.NameForSymbol(e.Ref)
 		p.addSourceMappingForName(tagOrNil.Loc, name, e.Ref)
 		p.print(name)
@@ -285,6 +291,7 @@ type printer struct {
 	extractedLegalComments []string
 	js                     []byte
 	jsonMetadataImports    []string
+	systemImports          []SystemImport
 	binaryExprStack        []binaryExprVisitor
 	options                Options
 	builder                sourcemap.ChunkBuilder
@@ -305,8 +312,13 @@ type printer struct {
 	intToBytesBuffer     [64]byte
 	needsSemicolon       bool
 	wasLazyExport        bool
+	skipSystemLiveExport bool
 	prevOp               js_ast.OpCode
 	moduleType           js_ast.ModuleType
+
+	// Default values in destructuring patterns look like assignments but must
+	// not be wrapped for SystemJS. The whole pattern is handled instead.
+	systemLiveExportDefaults map[*js_ast.EBinary]bool
 }
 
 func (p *printer) print(text string) {
@@ -374,6 +386,37 @@ func (p *printer) mangledPropName(ref ast.Ref) string {
 	return p.renamer.NameForSymbol(ref)
 }
 
+// Symbols from other chunks are accessed through a namespace object for that
+// chunk when the output format doesn't have import statements. Using a
+// property access instead of a local variable preserves live bindings.
+func (p *printer) namespaceAliasForSymbol(ref ast.Ref) *ast.NamespaceAlias {
+	if alias, ok := p.options.CrossChunkNamespaceAliases[ref]; ok {
+		return &alias
+	}
+	return p.symbols.Get(ref).NamespaceAlias
+}
+
+func (p *printer) isCrossChunkReference(ref ast.Ref) bool {
+	if p.options.CrossChunkNamespaceAliases == nil {
+		return false
+	}
+	_, ok := p.options.CrossChunkNamespaceAliases[ast.FollowSymbols(p.symbols, ref)]
+	return ok
+}
+
+// This is for references to symbols generated by the linker, which are never
+// missing and never need to be wrapped when they are the target of a call
+func (p *printer) printSymbol(ref ast.Ref) {
+	ref = ast.FollowSymbols(p.symbols, ref)
+	if alias, ok := p.options.CrossChunkNamespaceAliases[ref]; ok {
+		p.printIdentifier(p.renamer.NameForSymbol(alias.NamespaceRef))
+		p.print(".")
+		p.printIdentifier(alias.Alias)
+		return
+	}
+	p.printIdentifier(p.renamer.NameForSymbol(ref))
+}
+
 func (p *printer) tryToGetImportedEnumValue(target js_ast.Expr, name string) (js_ast.TSEnumValue, bool) {
 	if id, ok := target.Data.(*js_ast.EImportIdentifier); ok {
 		ref := ast.FollowSymbols(p.symbols, id.Ref)
@@ -973,7 +1016,7 @@ func (p *printer) printDecorators(decorators []js_ast.Decorator, defaultMode pri
 					break
 				}
 
-				if symbol.NamespaceAlias != nil && isCallTarget && e.WasOriginallyIdentifier {
+				if p.namespaceAliasForSymbol(ref) != nil && isCallTarget && e.WasOriginallyIdentifier {
 					// "@((0, import_ns.fn)())"
 					break
 				}
@@ -1219,7 +1262,7 @@ func (p *printer) printProperty(property js_ast.Property) {
 			if !p.options.UnsupportedFeatures.Has(compat.ObjectExtensions) && property.ValueOrNil.Data != nil && !p.willPrintExprCommentsAtLoc(property.ValueOrNil.Loc) {
 				switch e := property.ValueOrNil.Data.(type) {
 				case *js_ast.EIdentifier:
-					if name == p.renamer.NameForSymbol(e.Ref) {
+					if name == p.renamer.NameForSymbol(e.Ref) && !p.isCrossChunkReference(e.Ref) {
 						if property.InitializerOrNil.Data != nil {
 							p.printSpace()
 							p.print("=")
@@ -1232,7 +1275,7 @@ func (p *printer) printProperty(property js_ast.Property) {
 				case *js_ast.EImportIdentifier:
 					// Make sure we're not using a property access instead of an identifier
 					ref := ast.FollowSymbols(p.symbols, e.Ref)
-					if symbol := p.symbols.Get(ref); symbol.NamespaceAlias == nil && name == p.renamer.NameForSymbol(ref) &&
+					if p.namespaceAliasForSymbol(ref) == nil && name == p.renamer.NameForSymbol(ref) &&
 						p.options.ConstValues[ref].Kind == js_ast.ConstValueNone {
 						if property.InitializerOrNil.Data != nil {
 							p.printSpace()
@@ -1257,7 +1300,7 @@ func (p *printer) printProperty(property js_ast.Property) {
 			if !p.options.UnsupportedFeatures.Has(compat.ObjectExtensions) && property.ValueOrNil.Data != nil && !p.willPrintExprCommentsAtLoc(property.ValueOrNil.Loc) {
 				switch e := property.ValueOrNil.Data.(type) {
 				case *js_ast.EIdentifier:
-					if canUseShorthandProperty(key.Value, p.renamer.NameForSymbol(e.Ref), property.Flags) {
+					if canUseShorthandProperty(key.Value, p.renamer.NameForSymbol(e.Ref), property.Flags) && !p.isCrossChunkReference(e.Ref) {
 						if p.options.AddSourceMappings {
 							p.addSourceMappingForName(property.Key.Loc, helpers.UTF16ToString(key.Value), e.Ref)
 						}
@@ -1274,7 +1317,7 @@ func (p *printer) printProperty(property js_ast.Property) {
 				case *js_ast.EImportIdentifier:
 					// Make sure we're not using a property access instead of an identifier
 					ref := ast.FollowSymbols(p.symbols, e.Ref)
-					if symbol := p.symbols.Get(ref); symbol.NamespaceAlias == nil && canUseShorthandProperty(key.Value, p.renamer.NameForSymbol(ref), property.Flags) &&
+					if p.namespaceAliasForSymbol(ref) == nil && canUseShorthandProperty(key.Value, p.renamer.NameForSymbol(ref), property.Flags) &&
 						p.options.ConstValues[ref].Kind == js_ast.ConstValueNone {
 						if p.options.AddSourceMappings {
 							p.addSourceMappingForName(property.Key.Loc, helpers.UTF16ToString(key.Value), ref)
@@ -1412,14 +1455,31 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 			wrapWithToESM := record.Flags.Has(ast.WrapWithToESM)
 			if wrapWithToESM {
 				p.printSpaceBeforeIdentifier()
-				p.printIdentifier(p.renamer.NameForSymbol(p.options.ToESMRef))
+				p.printSymbol(p.options.ToESMRef)
 				p.print("(")
 			}
 
+			// The UMD format passes external modules to the factory function
+			if ref, ok := p.options.UMDDependencyRefs[record.Path.Text]; ok {
+				p.printSpaceBeforeIdentifier()
+				p.addSourceMapping(record.Range.Loc)
+				p.printSymbol(ref)
+				p.addJSONMetadataImport(*record, ast.ImportRequire)
+				if wrapWithToESM {
+					if p.moduleType.IsESM() {
+						p.print(",")
+						p.printSpace()
+						p.print("1")
+					}
+					p.print(")")
+				}
+				return
+			}
+
 			// Potentially substitute our own "__require" stub for "require"
 			p.printSpaceBeforeIdentifier()
 			if record.Flags.Has(ast.CallRuntimeRequire) {
-				p.printIdentifier(p.renamer.NameForSymbol(p.options.RuntimeRequireRef))
+				p.printSymbol(p.options.RuntimeRequireRef)
 			} else {
 				p.print("require")
 			}
@@ -1456,17 +1516,43 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 			return
 		}
 
-		// External "import()"
+		// Cross-chunk "import()" in the IIFE format
+		if record.Flags.Has(ast.IsChunkImport) && p.options.OutputFormat == config.FormatIIFE {
+			p.printSpaceBeforeIdentifier()
+			p.printIdentifier(p.renamer.NameForSymbol(p.options.ChunkLoaderRef))
+			p.print("(")
+			p.printPath(importRecordIndex, ast.ImportDynamic)
+			p.print(")")
+
+			// Wrap the exports with "__toESM()" if this is a CommonJS file
+			if record.Flags.Has(ast.WrapWithToESM) {
+				p.print(".then(")
+				p.printSymbol(p.options.ToESMRef)
+				p.print(")")
+			}
+			return
+		}
+
+		// External "import()". Cross-chunk "import()" in the CommonJS format is
+		// converted to "require()" since these chunks don't use ESM syntax.
 		kind := ast.ImportDynamic
-		if !p.options.UnsupportedFeatures.Has(compat.DynamicImport) {
+		useRequire := p.options.UnsupportedFeatures.Has(compat.DynamicImport) ||
+			(record.Flags.Has(ast.IsChunkImport) && p.options.OutputFormat == config.FormatCommonJS)
+		if !useRequire {
 			p.printSpaceBeforeIdentifier()
 			switch phase {
 			case ast.DeferPhase:
-				p.print("import.defer(")
+				// Cross-chunk deferred imports are evaluated eagerly if the target
+				// doesn't support deferred imports
+				if record.Flags.Has(ast.IsChunkImport) && p.options.UnsupportedFeatures.Has(compat.ImportDefer) {
+					p.printDynamicImportKeyword()
+				} else {
+					p.print("import.defer(")
+				}
 			case ast.SourcePhase:
 				p.print("import.source(")
 			default:
-				p.print("import(")
+				p.printDynamicImportKeyword()
 			}
 		} else {
 			kind = ast.ImportRequire
@@ -1478,7 +1564,7 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 			// Wrap this with a call to "__toESM()" if this is a CommonJS file
 			if record.Flags.Has(ast.WrapWithToESM) {
 				p.printSpaceBeforeIdentifier()
-				p.printIdentifier(p.renamer.NameForSymbol(p.options.ToESMRef))
+				p.printSymbol(p.options.ToESMRef)
 				p.print("(")
 				defer func() {
 					if p.moduleType.IsESM() {
@@ -1493,7 +1579,7 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 			// Potentially substitute our own "__require" stub for "require"
 			p.printSpaceBeforeIdentifier()
 			if record.Flags.Has(ast.CallRuntimeRequire) {
-				p.printIdentifier(p.renamer.NameForSymbol(p.options.RuntimeRequireRef))
+				p.printSymbol(p.options.RuntimeRequireRef)
 			} else {
 				p.print("require")
 			}
@@ -1502,8 +1588,7 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 		}
 		isMultiLine := p.willPrintExprCommentsAtLoc(record.Range.Loc) ||
 			p.willPrintExprCommentsAtLoc(closeParenLoc) ||
-			(record.AssertOrWith != nil &&
-				!p.options.UnsupportedFeatures.Has(compat.DynamicImport) &&
+			(record.AssertOrWith != nil && !useRequire &&
 				(!p.options.UnsupportedFeatures.Has(compat.ImportAssertions) ||
 					!p.options.UnsupportedFeatures.Has(compat.ImportAttributes)) &&
 				p.willPrintExprCommentsAtLoc(record.AssertOrWith.OuterOpenBraceLoc))
@@ -1514,7 +1599,7 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 		}
 		p.printExprCommentsAtLoc(record.Range.Loc)
 		p.printPath(importRecordIndex, kind)
-		if !p.options.UnsupportedFeatures.Has(compat.DynamicImport) {
+		if !useRequire {
 			p.printImportCallAssertOrWith(record.AssertOrWith, isMultiLine)
 		}
 		if isMultiLine {
@@ -1540,12 +1625,12 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 	// Internal "import()" of async ESM
 	if record.Kind == ast.ImportDynamic && meta.IsWrapperAsync {
 		p.printSpaceBeforeIdentifier()
-		p.printIdentifier(p.renamer.NameForSymbol(meta.WrapperRef))
+		p.printSymbol(meta.WrapperRef)
 		p.print("()")
 		if meta.ExportsRef != ast.InvalidRef {
 			p.printDotThenPrefix()
 			p.printSpaceBeforeIdentifier()
-			p.printIdentifier(p.renamer.NameForSymbol(meta.ExportsRef))
+			p.printSymbol(meta.ExportsRef)
 			p.printDotThenSuffix()
 		}
 		return
@@ -1559,6 +1644,18 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 		defer p.printDotThenSuffix()
 	}
 
+	// Internal "import defer" or "import.defer()". The wrapper isn't called until
+	// a property of the namespace object is accessed. Modules that use top-level
+	// await are always evaluated immediately instead.
+	if phase == ast.DeferPhase && !meta.IsWrapperAsync {
+		p.printSpaceBeforeIdentifier()
+		p.printSymbol(p.options.DeferNSRef)
+		p.print("(")
+		level = p.printCallbackPrefix()
+		defer p.print(")")
+		defer p.printCallbackSuffix()
+	}
+
 	// Make sure the comma operator is properly wrapped
 	if meta.ExportsRef != ast.InvalidRef && level >= js_ast.LComma {
 		p.print("(")
@@ -1569,13 +1666,13 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 	wrapWithToESM := record.Flags.Has(ast.WrapWithToESM)
 	if wrapWithToESM {
 		p.printSpaceBeforeIdentifier()
-		p.printIdentifier(p.renamer.NameForSymbol(p.options.ToESMRef))
+		p.printSymbol(p.options.ToESMRef)
 		p.print("(")
 	}
 
 	// Call the wrapper
 	p.printSpaceBeforeIdentifier()
-	p.printIdentifier(p.renamer.NameForSymbol(meta.WrapperRef))
+	p.printSymbol(meta.WrapperRef)
 	p.print("()")
 
 	// Return the namespace object if this is an ESM file
@@ -1586,10 +1683,10 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 		// Wrap this with a call to "__toCommonJS()" if this is an ESM file
 		wrapWithTpCJS := record.Flags.Has(ast.WrapWithToCJS)
 		if wrapWithTpCJS {
-			p.printIdentifier(p.renamer.NameForSymbol(p.options.ToCommonJSRef))
+			p.printSymbol(p.options.ToCommonJSRef)
 			p.print("(")
 		}
-		p.printIdentifier(p.renamer.NameForSymbol(meta.ExportsRef))
+		p.printSymbol(meta.ExportsRef)
 		if wrapWithTpCJS {
 			p.print(")")
 		}
@@ -1607,8 +1704,18 @@ func (p *printer) printRequireOrImportExpr(importRecordIndex uint32, level js_as
 }
 
 func (p *printer) printDotThenPrefix() js_ast.L {
+	p.print(".then(")
+	return p.printCallbackPrefix()
+}
+
+func (p *printer) printDotThenSuffix() {
+	p.printCallbackSuffix()
+	p.print(")")
+}
+
+func (p *printer) printCallbackPrefix() js_ast.L {
 	if p.options.UnsupportedFeatures.Has(compat.Arrow) {
-		p.print(".then(function()")
+		p.print("function()")
 		p.printSpace()
 		p.print("{")
 		p.printNewline()
@@ -1618,7 +1725,7 @@ func (p *printer) printDotThenPrefix() js_ast.L {
 		p.printSpace()
 		return js_ast.LLowest
 	} else {
-		p.print(".then(()")
+		p.print("()")
 		p.printSpace()
 		p.print("=>")
 		p.printSpace()
@@ -1626,7 +1733,7 @@ func (p *printer) printDotThenPrefix() js_ast.L {
 	}
 }
 
-func (p *printer) printDotThenSuffix() {
+func (p *printer) printCallbackSuffix() {
 	if p.options.UnsupportedFeatures.Has(compat.Arrow) {
 		if !p.options.MinifyWhitespace {
 			p.print(";")
@@ -1634,9 +1741,7 @@ func (p *printer) printDotThenSuffix() {
 		p.printNewline()
 		p.options.Indent--
 		p.printIndent()
-		p.print("})")
-	} else {
-		p.print(")")
+		p.print("}")
 	}
 }
 
@@ -2022,6 +2127,10 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 
 	p.printExprCommentsAtLoc(expr.Loc)
 
+	if p.options.SystemLiveExports != nil && p.printSystemLiveExportUpdate(expr, level, flags) {
+		return
+	}
+
 	switch e := expr.Data.(type) {
 	case *js_ast.EMissing:
 		p.addSourceMapping(expr.Loc)
@@ -2060,7 +2169,11 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 	case *js_ast.EImportMeta:
 		p.printSpaceBeforeIdentifier()
 		p.addSourceMapping(expr.Loc)
-		p.print("import.meta")
+		if p.options.OutputFormat == config.FormatSystem {
+			p.print("module.meta")
+		} else {
+			p.print("import.meta")
+		}
 
 	case *js_ast.ENameOfSymbol:
 		name := p.mangledPropName(e.Ref)
@@ -2448,7 +2561,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 
 	case *js_ast.ERequireString:
 		p.addSourceMapping(expr.Loc)
-		p.printRequireOrImportExpr(e.ImportRecordIndex, level, flags, e.CloseParenLoc, ast.EvaluationPhase)
+		p.printRequireOrImportExpr(e.ImportRecordIndex, level, flags, e.CloseParenLoc, p.importRecords[e.ImportRecordIndex].Phase)
 
 	case *js_ast.ERequireResolveString:
 		recordLoc := p.importRecords[e.ImportRecordIndex].Range.Loc
@@ -2504,7 +2617,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		case ast.SourcePhase:
 			p.print("import.source(")
 		default:
-			p.print("import(")
+			p.printDynamicImportKeyword()
 		}
 		if isMultiLine {
 			p.printNewline()
@@ -3138,6 +3251,14 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 		p.printNumber(e.Value, level)
 
 	case *js_ast.EIdentifier:
+		if p.isCrossChunkReference(e.Ref) {
+			// "import_chunk.foo"
+			p.printSpaceBeforeIdentifier()
+			p.addSourceMapping(expr.Loc)
+			p.printSymbol(e.Ref)
+			break
+		}
+
 		name := p.renamer.NameForSymbol(e.Ref)
 		wrap := len(p.js) == p.forOfInitStart && (name == "let" ||
 			((flags&isFollowedByOf) != 0 && (flags&isInsideForAwait) == 0 && name == "async"))
@@ -3161,7 +3282,7 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 
 		if symbol.ImportItemStatus == ast.ImportItemMissing {
 			p.printUndefined(expr.Loc, level)
-		} else if symbol.NamespaceAlias != nil {
+		} else if namespaceAlias := p.namespaceAliasForSymbol(ref); namespaceAlias != nil {
 			wrap := p.callTarget == e && e.WasOriginallyIdentifier
 			if wrap {
 				p.print("(0,")
@@ -3169,8 +3290,8 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 			}
 			p.printSpaceBeforeIdentifier()
 			p.addSourceMapping(expr.Loc)
-			p.printIdentifier(p.renamer.NameForSymbol(symbol.NamespaceAlias.NamespaceRef))
-			alias := symbol.NamespaceAlias.Alias
+			p.printIdentifier(p.renamer.NameForSymbol(namespaceAlias.NamespaceRef))
+			alias := namespaceAlias.Alias
 			if !e.PreferQuotedKey && p.canPrintIdentifier(alias) {
 				p.print(".")
 				p.addSourceMappingForName(expr.Loc, alias, ref)
@@ -3308,8 +3429,9 @@ func (p *printer) printExpr(expr js_ast.Expr, level js_ast.L, flags printExprFla
 			left := v.e.Left
 			leftBinary, ok := left.Data.(*js_ast.EBinary)
 
-			// Stop iterating if iteration doesn't apply to the left node
-			if !ok {
+			// Stop iterating if iteration doesn't apply to the left node. Assignments
+			// may need to be wrapped for SystemJS, which happens in "printExpr".
+			if !ok || (p.options.SystemLiveExports != nil && leftBinary.Op.BinaryAssignTarget() != js_ast.AssignTargetNone) {
 				p.printExpr(left, v.leftLevel, v.leftFlags)
 				v.visitRightAndFinish(p)
 				break
@@ -3921,11 +4043,7 @@ func (p *printer) printIndentedComment(text string) {
 	}
 }
 
-func (p *printer) printPath(importRecordIndex uint32, importKind ast.ImportKind) {
-	record := p.importRecords[importRecordIndex]
-	p.addSourceMapping(record.Range.Loc)
-	p.printQuotedUTF8(record.Path.Text, printQuotedNoWrap)
-
+func (p *printer) addJSONMetadataImport(record ast.ImportRecord, importKind ast.ImportKind) {
 	if p.options.NeedsMetafile {
 		external := ""
 		if (record.Flags & ast.ShouldNotBeExternalInMetafile) == 0 {
@@ -3936,6 +4054,13 @@ func (p *printer) printPath(importRecordIndex uint32, importKind ast.ImportKind)
 			helpers.QuoteForJSON(importKind.StringForMetafile(), p.options.ASCIIOnly),
 			external))
 	}
+}
+
+func (p *printer) printPath(importRecordIndex uint32, importKind ast.ImportKind) {
+	record := p.importRecords[importRecordIndex]
+	p.addSourceMapping(record.Range.Loc)
+	p.printQuotedUTF8(record.Path.Text, printQuotedNoWrap)
+	p.addJSONMetadataImport(record, importKind)
 
 	if record.AssertOrWith != nil && importKind == ast.ImportStmt {
 		feature := compat.ImportAttributes
@@ -4174,6 +4299,19 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 		p.printNewline()
 
 	case *js_ast.SExportDefault:
+		if s2, ok := s.Value.Data.(*js_ast.SExpr); ok && p.options.OutputFormat == config.FormatSystem {
+			// "exports('default', value);"
+			p.addSourceMapping(stmt.Loc)
+			p.printIndent()
+			p.printSpaceBeforeIdentifier()
+			p.print("exports(\"default\",")
+			p.printSpace()
+			p.printExpr(s2.Value, js_ast.LComma, 0)
+			p.print(")")
+			p.printSemicolonAfterStatement()
+			return
+		}
+
 		if !p.options.MinifyWhitespace {
 			if s2, ok := s.Value.Data.(*js_ast.SFunction); ok && s2.Fn.HasNoSideEffectsComment {
 				p.printIndent()
@@ -4237,6 +4375,13 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 		}
 
 	case *js_ast.SExportStar:
+		if s.Alias == nil && p.options.OutputFormat == config.FormatSystem {
+			record := p.importRecords[s.ImportRecordIndex]
+			p.addJSONMetadataImport(record, ast.ImportStmt)
+			p.systemImports = append(p.systemImports, SystemImport{Path: record.Path.Text, IsExportStar: true})
+			return
+		}
+
 		p.addSourceMapping(stmt.Loc)
 		p.printIndent()
 		p.printSpaceBeforeIdentifier()
@@ -4257,6 +4402,11 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 		p.printSemicolonAfterStatement()
 
 	case *js_ast.SExportClause:
+		if p.options.OutputFormat == config.FormatSystem {
+			p.printSystemExportClause(stmt.Loc, s)
+			return
+		}
+
 		p.addSourceMapping(stmt.Loc)
 		p.printIndent()
 		p.printSpaceBeforeIdentifier()
@@ -4422,6 +4572,7 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 			p.options.Indent++
 			p.printIndent()
 		}
+		exports := p.systemLiveExportsInForInOrForOfInit(s.Init)
 		p.printForLoopInit(s.Init, forbidIn)
 		p.printSpace()
 		p.printSpaceBeforeIdentifier()
@@ -4439,7 +4590,7 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 			p.printIndent()
 		}
 		p.print(")")
-		p.printBody(s.Body, s.IsSingleLineBody)
+		p.printForInOrForOfBody(exports, s.Body, s.IsSingleLineBody)
 
 	case *js_ast.SForOf:
 		p.addSourceMapping(stmt.Loc)
@@ -4462,6 +4613,7 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 			p.options.Indent++
 			p.printIndent()
 		}
+		exports := p.systemLiveExportsInForInOrForOfInit(s.Init)
 		p.forOfInitStart = len(p.js)
 		p.printForLoopInit(s.Init, flags)
 		p.printSpace()
@@ -4480,7 +4632,7 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 			p.printIndent()
 		}
 		p.print(")")
-		p.printBody(s.Body, s.IsSingleLineBody)
+		p.printForInOrForOfBody(exports, s.Body, s.IsSingleLineBody)
 
 	case *js_ast.SWhile:
 		p.addSourceMapping(stmt.Loc)
@@ -4700,6 +4852,11 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 		p.needsSemicolon = false
 
 	case *js_ast.SImport:
+		if p.options.OutputFormat == config.FormatSystem {
+			p.recordSystemImport(s)
+			return
+		}
+
 		itemCount := 0
 
 		p.addSourceMapping(stmt.Loc)
@@ -4900,6 +5057,290 @@ func (p *printer) printStmt(stmt js_ast.Stmt, flags printStmtFlags) {
 	}
 }
 
+func (p *printer) printDynamicImportKeyword() {
+	if p.options.OutputFormat == config.FormatSystem {
+		p.print("module.import(")
+	} else {
+		p.print("import(")
+	}
+}
+
+func (p *printer) recordSystemImport(s *js_ast.SImport) {
+	record := p.importRecords[s.ImportRecordIndex]
+	p.addJSONMetadataImport(record, ast.ImportStmt)
+	systemImport := SystemImport{Path: record.Path.Text}
+	if s.DefaultName != nil {
+		systemImport.Bindings = append(systemImport.Bindings, SystemImportBinding{
+			Name:  p.renamer.NameForSymbol(s.DefaultName.Ref),
+			Alias: "default",
+		})
+	}
+	if s.Items != nil {
+		for _, item := range *s.Items {
+			systemImport.Bindings = append(systemImport.Bindings, SystemImportBinding{
+				Name:  p.renamer.NameForSymbol(item.Name.Ref),
+				Alias: item.Alias,
+			})
+		}
+	}
+	if s.StarNameLoc != nil {
+		systemImport.Bindings = append(systemImport.Bindings, SystemImportBinding{
+			Name:  p.renamer.NameForSymbol(s.NamespaceRef),
+			Alias: "*",
+		})
+	}
+	p.systemImports = append(p.systemImports, systemImport)
+}
+
+// "export {a, b as c}" => "exports({ a, c: b });"
+func (p *printer) printSystemExportClause(loc logger.Loc, s *js_ast.SExportClause) {
+	properties := make([]js_ast.Property, 0, len(s.Items))
+	for _, item := range s.Items {
+		if p.options.SystemHoistedExports[ast.FollowSymbols(p.symbols, item.Name.Ref)] {
+			continue
+		}
+		properties = append(properties, js_ast.Property{
+			Key:        js_ast.Expr{Loc: item.AliasLoc, Data: &js_ast.EString{Value: helpers.StringToUTF16(item.Alias)}},
+			ValueOrNil: js_ast.Expr{Loc: item.Name.Loc, Data: &js_ast.EImportIdentifier{Ref: item.Name.Ref}},
+		})
+	}
+	if len(properties) == 0 {
+		return
+	}
+	p.addSourceMapping(loc)
+	p.printIndent()
+	p.printSpaceBeforeIdentifier()
+	p.print("exports(")
+	p.printExpr(js_ast.Expr{Loc: loc, Data: &js_ast.EObject{Properties: properties, IsSingleLine: s.IsSingleLine}}, js_ast.LComma, 0)
+	p.print(")")
+	p.printSemicolonAfterStatement()
+}
+
+// Assignments to exported variables in the SystemJS format are wrapped so the
+// new value is passed to "exports()" too. Postfix updates whose value is used
+// need to evaluate to the old value, which looks like this:
+//
+//	[x++, exports("x", x)][0]
+func (p *printer) printSystemLiveExportUpdate(expr js_ast.Expr, level js_ast.L, flags printExprFlags) bool {
+	// Don't wrap the same expression again when it's printed inside the wrapper
+	if p.skipSystemLiveExport {
+		p.skipSystemLiveExport = false
+		return false
+	}
+
+	var target js_ast.Expr
+	switch e := expr.Data.(type) {
+	case *js_ast.EBinary:
+		if e.Op.BinaryAssignTarget() == js_ast.AssignTargetNone || p.systemLiveExportDefaults[e] {
+			return false
+		}
+		target = e.Left
+
+		// Destructuring assignments can update several exported variables. The
+		// value is still the right operand, so the same trick as above is used.
+		if _, ok := target.Data.(*js_ast.EIdentifier); !ok && e.Op == js_ast.BinOpAssign {
+			exports := p.systemLiveExportsInTarget(target, nil)
+			if len(exports) == 0 {
+				return false
+			}
+			if (flags & exprResultIsUnused) != 0 {
+				// "[x] = arr, exports('x', x)"
+				wrap := level >= js_ast.LComma
+				if wrap {
+					p.print("(")
+				}
+				p.skipSystemLiveExport = true
+				p.printExpr(expr, js_ast.LComma, exprResultIsUnused)
+				p.print(",")
+				p.printSpace()
+				p.printSystemLiveExportCalls(exports)
+				if wrap {
+					p.print(")")
+				}
+			} else {
+				// "[[x] = arr, exports('x', x)][0]"
+				p.print("[")
+				p.skipSystemLiveExport = true
+				p.printExpr(expr, js_ast.LComma, 0)
+				p.print(",")
+				p.printSpace()
+				p.printSystemLiveExportCalls(exports)
+				p.print("][0]")
+			}
+			return true
+		}
+
+	case *js_ast.EUnary:
+		if e.Op.UnaryAssignTarget() == js_ast.AssignTargetNone {
+			return false
+		}
+		target = e.Value
+
+		// Turn "x++" into "++x" if the result is unused
+		if !e.Op.IsPrefix() {
+			if (flags & exprResultIsUnused) != 0 {
+				op := js_ast.UnOpPreInc
+				if e.Op == js_ast.UnOpPostDec {
+					op = js_ast.UnOpPreDec
+				}
+				expr.Data = &js_ast.EUnary{Op: op, Value: e.Value}
+			} else {
+				exports := p.systemLiveExportsInTarget(target, nil)
+				if len(exports) == 0 {
+					return false
+				}
+				p.print("[")
+				p.skipSystemLiveExport = true
+				p.printExpr(expr, js_ast.LComma, 0)
+				p.print(",")
+				p.printSpace()
+				p.printSystemLiveExportCalls(exports)
+				p.print("][0]")
+				return true
+			}
+		}
+
+	default:
+		return false
+	}
+
+	id, ok := target.Data.(*js_ast.EIdentifier)
+	if !ok {
+		return false
+	}
+	aliases := p.options.SystemLiveExports[ast.FollowSymbols(p.symbols, id.Ref)]
+	if len(aliases) == 0 {
+		return false
+	}
+
+	// "exports('x', x = value)"
+	wrap := level >= js_ast.LNew || (flags&forbidCall) != 0
+	if wrap {
+		p.print("(")
+	}
+	p.printSpaceBeforeIdentifier()
+	for _, alias := range aliases {
+		p.print("exports(")
+		p.printQuotedUTF8(alias, 0)
+		p.print(",")
+		p.printSpace()
+	}
+	p.skipSystemLiveExport = true
+	p.printExpr(expr, js_ast.LComma, 0)
+	for range aliases {
+		p.print(")")
+	}
+	if wrap {
+		p.print(")")
+	}
+	return true
+}
+
+type systemLiveExport struct {
+	ref     ast.Ref
+	aliases []string
+}
+
+// This finds all exported variables that are assigned to by an assignment
+// target, which may be a destructuring pattern
+func (p *printer) systemLiveExportsInTarget(target js_ast.Expr, exports []systemLiveExport) []systemLiveExport {
+	switch e := target.Data.(type) {
+	case *js_ast.EIdentifier:
+		if aliases := p.options.SystemLiveExports[ast.FollowSymbols(p.symbols, e.Ref)]; len(aliases) > 0 {
+			exports = append(exports, systemLiveExport{ref: e.Ref, aliases: aliases})
+		}
+
+	case *js_ast.EArray:
+		for _, item := range e.Items {
+			exports = p.systemLiveExportsInTarget(item, exports)
+		}
+
+	case *js_ast.EObject:
+		for _, property := range e.Properties {
+			if property.ValueOrNil.Data != nil {
+				exports = p.systemLiveExportsInTarget(property.ValueOrNil, exports)
+			}
+		}
+
+	case *js_ast.ESpread:
+		exports = p.systemLiveExportsInTarget(e.Value, exports)
+
+	case *js_ast.EBinary:
+		// This is a default value such as the "x = 1" in "[x = 1] = arr"
+		if e.Op == js_ast.BinOpAssign {
+			if p.systemLiveExportDefaults == nil {
+				p.systemLiveExportDefaults = make(map[*js_ast.EBinary]bool)
+			}
+			p.systemLiveExportDefaults[e] = true
+			exports = p.systemLiveExportsInTarget(e.Left, exports)
+		}
+	}
+	return exports
+}
+
+// "exports('x', x), exports('y', y)"
+func (p *printer) printSystemLiveExportCalls(exports []systemLiveExport) {
+	p.printSpaceBeforeIdentifier()
+	for i, export := range exports {
+		name := p.renamer.NameForSymbol(export.ref)
+		for j, alias := range export.aliases {
+			if i > 0 || j > 0 {
+				p.print(",")
+				p.printSpace()
+			}
+			p.print("exports(")
+			p.printQuotedUTF8(alias, 0)
+			p.print(",")
+			p.printSpace()
+			p.printIdentifier(name)
+			p.print(")")
+		}
+	}
+}
+
+// The target of a "for-in" or "for-of" loop is assigned to at the start of
+// each iteration, so the new values are passed to "exports()" there:
+//
+//	for (x of arr) {
+//	  exports("x", x);
+//	  ...
+//	}
+func (p *printer) systemLiveExportsInForInOrForOfInit(init js_ast.Stmt) []systemLiveExport {
+	if expr, ok := init.Data.(*js_ast.SExpr); ok && p.options.SystemLiveExports != nil {
+		return p.systemLiveExportsInTarget(expr.Value, nil)
+	}
+	return nil
+}
+
+func (p *printer) printForInOrForOfBody(exports []systemLiveExport, body js_ast.Stmt, isSingleLine bool) {
+	if len(exports) == 0 {
+		p.printBody(body, isSingleLine)
+		return
+	}
+
+	p.printSpace()
+	p.print("{")
+	p.printNewline()
+	p.options.Indent++
+	p.printIndent()
+	p.printSystemLiveExportCalls(exports)
+	p.printSemicolonAfterStatement()
+	if block, ok := body.Data.(*js_ast.SBlock); ok {
+		for _, stmt := range block.Stmts {
+			p.printSemicolonIfNeeded()
+			p.printStmt(stmt, canOmitStatement)
+		}
+	} else if _, ok := body.Data.(*js_ast.SEmpty); !ok {
+		p.printSemicolonIfNeeded()
+		p.printStmt(body, canOmitStatement)
+	}
+	p.options.Indent--
+	p.needsSemicolon = false
+	p.printIndent()
+	p.print("}")
+	p.printNewline()
+}
+
 type Options struct {
 	RequireOrImportMetaForSource func(uint32) RequireOrImportMeta
 
@@ -4912,6 +5353,28 @@ type Options struct {
 	// Property mangling results go here
 	MangledProps map[ast.Ref]string
 
+	// When code splitting with an output format that doesn't have import
+	// statements, symbols from other chunks are printed as property accesses
+	// off of a namespace object for that chunk instead
+	CrossChunkNamespaceAliases map[ast.Ref]ast.NamespaceAlias
+
+	// Cross-chunk "import()" expressions are printed as calls to this function
+	// for the IIFE format, which loads the chunk using a "<script>" tag
+	ChunkLoaderRef ast.Ref
+
+	// The SystemJS format has no live bindings for exports. Instead, every
+	// assignment to one of these symbols is wrapped in a call to "exports()"
+	// with each of the export names for that symbol.
+	SystemLiveExports map[ast.Ref][]string
+
+	// Functions hoisted out of "execute" in the SystemJS format are exported
+	// before "execute" runs, so export clauses omit these symbols.
+	SystemHoistedExports map[ast.Ref]bool
+
+	// The UMD format passes each external module to the factory function as an
+	// argument. This maps each import path to the symbol for that argument.
+	UMDDependencyRefs map[string]ast.Ref
+
 	// This will be present if the input file had a source map. In that case we
 	// want to map all the way back to the original input file(s).
 	InputSourceMap *sourcemap.SourceMap
@@ -4922,6 +5385,7 @@ type Options struct {
 
 	ToCommonJSRef       ast.Ref
 	ToESMRef            ast.Ref
+	DeferNSRef          ast.Ref
 	RuntimeRequireRef   ast.Ref
 	UnsupportedFeatures compat.JSFeature
 	Indent              int
@@ -4946,10 +5410,27 @@ type RequireOrImportMeta struct {
 	IsWrapperAsync bool
 }
 
+// With the SystemJS format, import statements are not printed. Instead, each
+// one becomes a dependency of the "System.register()" call along with a setter
+// function that assigns these bindings from the namespace object.
+type SystemImport struct {
+	Path     string
+	Bindings []SystemImportBinding
+
+	// This is set for "export * from 'path'"
+	IsExportStar bool
+}
+
+type SystemImportBinding struct {
+	Name  string // The name of the local variable
+	Alias string // The name of the export, or "*" for the namespace object
+}
+
 type PrintResult struct {
 	JS                     []byte
 	ExtractedLegalComments []string
 	JSONMetadataImports    []string
+	SystemImports          []SystemImport
 
 	// This source map chunk just contains the VLQ-encoded offsets for the "JS"
 	// field above. It's not a full source map. The bundler will be joining many
@@ -5007,6 +5488,7 @@ func Print(tree js_ast.AST, symbols ast.SymbolMap, r renamer.Renamer, options Op
 		JS:                     p.js,
 		JSONMetadataImports:    p.jsonMetadataImports,
 		ExtractedLegalComments: p.extractedLegalComments,
+		SystemImports:          p.systemImports,
 	}
 	if options.SourceMap != config.SourceMapNone {
 		// This is expensive. Only do this if it's necessary.
@@ -0,0 +1,847 @@
+package js_regexp
+
+// This package contains a parser for the pattern of JavaScript regular
+// expression literals. It exists so that regular expressions that use newer
+// syntax can be rewritten into equivalent regular expressions for older
+// JavaScript engines (see "Lower"). The parsed tree remembers the original
+// source text of each node so that parts of the pattern that don't need to be
+// changed can be printed back out exactly as they were written.
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Mode uint8
+
+const (
+	// Regular expressions without the "u" or "v" flag use the legacy grammar
+	// from Annex B of the specification, which is much more permissive
+	ModeAnnexB Mode = iota
+
+	// The "u" flag
+	ModeUnicode
+
+	// The "v" flag
+	ModeUnicodeSets
+)
+
+func ModeForFlags(flags string) Mode {
+	if strings.IndexByte(flags, 'v') >= 0 {
+		return ModeUnicodeSets
+	}
+	if strings.IndexByte(flags, 'u') >= 0 {
+		return ModeUnicode
+	}
+	return ModeAnnexB
+}
+
+type Pattern struct {
+	Body         Disjunction
+	CaptureCount int
+	HasNames     bool
+}
+
+// Each alternative is a sequence of terms
+type Disjunction [][]Term
+
+type Term struct {
+	Atom Atom
+
+	// This is the original source text of the quantifier (e.g. "*" or "{2,}?")
+	// or empty if this term is not quantified
+	Quantifier string
+}
+
+type Atom interface{ isAtom() }
+
+func (*AChar) isAtom()         {}
+func (*ARange) isAtom()        {}
+func (*ADot) isAtom()          {}
+func (*AAssertion) isAtom()    {}
+func (*AGroup) isAtom()        {}
+func (*ABackref) isAtom()      {}
+func (*AClassEscape) isAtom()  {}
+func (*AProperty) isAtom()     {}
+func (*AClass) isAtom()        {}
+func (*AClassStrings) isAtom() {}
+
+// A single character. For patterns without the "u" or "v" flag, a literal
+// character outside of the BMP is still stored as a single code point here.
+type AChar struct {
+	Raw   string
+	Value rune
+	Start int
+}
+
+// A character range inside a character class such as "a-z"
+type ARange struct {
+	Raw string
+	Lo  rune
+	Hi  rune
+}
+
+type ADot struct{}
+
+// One of "^", "$", "\b", or "\B"
+type AAssertion struct{ Raw string }
+
+type GroupKind uint8
+
+const (
+	GroupCapture GroupKind = iota
+	GroupNonCapture
+	GroupLookahead
+	GroupNegativeLookahead
+	GroupLookbehind
+	GroupNegativeLookbehind
+	GroupModifiers
+)
+
+type AGroup struct {
+	// This is the source text before the body (e.g. "(?<name>" or "(?i:")
+	Prefix string
+	Name   string
+	Body   Disjunction
+	Kind   GroupKind
+
+	// The offset of the prefix within the pattern, for error messages
+	Start int
+
+	// The index of this capture group, starting from 1
+	Index int
+}
+
+type ABackref struct {
+	Raw   string
+	Name  string
+	Index int
+}
+
+// One of "\d", "\D", "\s", "\S", "\w", or "\W"
+type AClassEscape struct{ Kind byte }
+
+// A Unicode property escape such as "\p{L}" or "\P{Script=Greek}"
+type AProperty struct {
+	Raw     string
+	Name    string
+	Value   string
+	Start   int
+	Negated bool
+}
+
+type ClassOp uint8
+
+const (
+	ClassUnion ClassOp = iota
+	ClassIntersection
+	ClassSubtraction
+)
+
+type AClass struct {
+	Raw string
+
+	// Each item is one of "AChar", "ARange", "AClassEscape", "AProperty",
+	// "AClass", or "AClassStrings". Nested classes and strings are only
+	// possible with the "v" flag.
+	Items   []Atom
+	Start   int
+	Op      ClassOp
+	Negated bool
+}
+
+// A "\q{...}" string disjunction inside a character class with the "v" flag
+type AClassStrings struct {
+	Strings [][]rune
+}
+
+type parser struct {
+	pattern      string
+	mode         Mode
+	i            int
+	captureCount int
+	groupCount   int
+	hasNames     bool
+}
+
+type SyntaxError struct {
+	Offset int
+}
+
+func (err *SyntaxError) Error() string {
+	return "Invalid regular expression at offset " + strconv.Itoa(err.Offset)
+}
+
+type syntaxErrorPanic struct{}
+
+// Parse returns the tree for a regular expression pattern. The pattern should
+// not include the surrounding slashes. A syntax error is returned for patterns
+// that this parser doesn't understand, which may also happen for some valid
+// patterns that use obscure legacy syntax.
+func Parse(pattern string, mode Mode) (result Pattern, err error) {
+	p := parser{pattern: pattern, mode: mode}
+	p.scanCaptureGroups()
+
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(syntaxErrorPanic); !ok {
+				panic(r)
+			}
+			err = &SyntaxError{Offset: p.i}
+		}
+	}()
+
+	body := p.parseDisjunction()
+	if p.i < len(p.pattern) {
+		p.fail()
+	}
+	result = Pattern{Body: body, CaptureCount: p.captureCount, HasNames: p.hasNames}
+	return
+}
+
+func (p *parser) fail() {
+	panic(syntaxErrorPanic{})
+}
+
+// Capture groups can be referenced before they are defined, and whether an
+// escape such as "\2" is a back reference depends on how many groups there
+// are in the whole pattern. So count them before parsing.
+func (p *parser) scanCaptureGroups() {
+	pattern := p.pattern
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+
+		case '[':
+			for i++; i < len(pattern) && pattern[i] != ']'; i++ {
+				if pattern[i] == '\\' {
+					i++
+				}
+			}
+
+		case '(':
+			tail := pattern[i+1:]
+			if !strings.HasPrefix(tail, "?") {
+				p.captureCount++
+			} else if strings.HasPrefix(tail, "?<") && !strings.HasPrefix(tail, "?<=") && !strings.HasPrefix(tail, "?<!") {
+				p.captureCount++
+				p.hasNames = true
+			}
+		}
+	}
+}
+
+func (p *parser) peek() rune {
+	if p.i < len(p.pattern) {
+		c, _ := utf8.DecodeRuneInString(p.pattern[p.i:])
+		return c
+	}
+	return -1
+}
+
+func (p *parser) next() rune {
+	if p.i >= len(p.pattern) {
+		p.fail()
+	}
+	c, width := utf8.DecodeRuneInString(p.pattern[p.i:])
+	p.i += width
+	return c
+}
+
+func (p *parser) eat(text string) bool {
+	if strings.HasPrefix(p.pattern[p.i:], text) {
+		p.i += len(text)
+		return true
+	}
+	return false
+}
+
+func (p *parser) isUnicode() bool {
+	return p.mode != ModeAnnexB
+}
+
+func (p *parser) parseDisjunction() Disjunction {
+	alternatives := Disjunction{p.parseAlternative()}
+	for p.eat("|") {
+		alternatives = append(alternatives, p.parseAlternative())
+	}
+	return alternatives
+}
+
+func (p *parser) parseAlternative() (terms []Term) {
+	for p.i < len(p.pattern) {
+		c := p.pattern[p.i]
+		if c == '|' || c == ')' {
+			break
+		}
+		atom, canQuantify := p.parseAtom()
+		term := Term{Atom: atom}
+		if canQuantify {
+			term.Quantifier = p.parseQuantifier()
+		}
+		terms = append(terms, term)
+	}
+	return
+}
+
+func (p *parser) parseQuantifier() string {
+	start := p.i
+	switch p.peek() {
+	case '*', '+', '?':
+		p.i++
+
+	case '{':
+		if !p.scanBraceQuantifier() {
+			return ""
+		}
+
+	default:
+		return ""
+	}
+	p.eat("?")
+	return p.pattern[start:p.i]
+}
+
+// This matches "{n}", "{n,}", or "{n,m}" and leaves the position unchanged
+// if there's no match
+func (p *parser) scanBraceQuantifier() bool {
+	start := p.i
+	p.i++
+	digits := p.scanDigits()
+	if digits != "" && p.eat(",") {
+		p.scanDigits()
+	}
+	if digits == "" || !p.eat("}") {
+		p.i = start
+		return false
+	}
+	return true
+}
+
+func (p *parser) scanDigits() string {
+	start := p.i
+	for p.i < len(p.pattern) && p.pattern[p.i] >= '0' && p.pattern[p.i] <= '9' {
+		p.i++
+	}
+	return p.pattern[start:p.i]
+}
+
+func (p *parser) parseAtom() (atom Atom, canQuantify bool) {
+	start := p.i
+	c := p.next()
+
+	switch c {
+	case '^', '$':
+		return &AAssertion{Raw: p.pattern[start:p.i]}, false
+
+	case '.':
+		return &ADot{}, true
+
+	case '(':
+		return p.parseGroup(start)
+
+	case '[':
+		return p.parseClass(start), true
+
+	case '*', '+', '?':
+		p.i = start
+		p.fail()
+
+	case '{':
+		if p.isUnicode() {
+			p.i = start
+			p.fail()
+		}
+
+		// Annex B allows "{" when it's not a valid quantifier
+		p.i = start
+		if p.scanBraceQuantifier() {
+			p.fail()
+		}
+		p.i++
+
+	case '}', ']':
+		if p.isUnicode() {
+			p.i = start
+			p.fail()
+		}
+
+	case '\\':
+		return p.parseAtomEscape(start)
+	}
+
+	return &AChar{Raw: p.pattern[start:p.i], Value: c, Start: start}, true
+}
+
+func (p *parser) parseGroup(start int) (Atom, bool) {
+	group := &AGroup{Start: start + 1}
+
+	switch {
+	case p.eat("?:"):
+		group.Kind = GroupNonCapture
+
+	case p.eat("?="):
+		group.Kind = GroupLookahead
+
+	case p.eat("?!"):
+		group.Kind = GroupNegativeLookahead
+
+	case p.eat("?<="):
+		group.Kind = GroupLookbehind
+
+	case p.eat("?<!"):
+		group.Kind = GroupNegativeLookbehind
+
+	case p.eat("?<"):
+		group.Kind = GroupCapture
+		group.Name = p.parseGroupName()
+
+	case p.eat("?"):
+		group.Kind = GroupModifiers
+		for p.i < len(p.pattern) && strings.IndexByte("ims-", p.pattern[p.i]) >= 0 {
+			p.i++
+		}
+		if !p.eat(":") {
+			p.fail()
+		}
+
+	default:
+		group.Kind = GroupCapture
+	}
+
+	if group.Kind == GroupCapture {
+		p.groupCount++
+		group.Index = p.groupCount
+	}
+
+	group.Prefix = p.pattern[start:p.i]
+	group.Body = p.parseDisjunction()
+	if !p.eat(")") {
+		p.fail()
+	}
+
+	// Lookbehind assertions can never be quantified, and lookahead assertions
+	// can only be quantified with the legacy grammar from Annex B
+	switch group.Kind {
+	case GroupLookahead, GroupNegativeLookahead:
+		return group, !p.isUnicode()
+	case GroupLookbehind, GroupNegativeLookbehind:
+		return group, false
+	}
+	return group, true
+}
+
+// Group names with escapes are valid but very rare. They aren't supported
+// here because they would need to be decoded to use them with the ".groups"
+// property.
+func (p *parser) parseGroupName() string {
+	start := p.i
+	for p.i < len(p.pattern) {
+		switch p.pattern[p.i] {
+		case '>':
+			name := p.pattern[start:p.i]
+			if name == "" {
+				p.fail()
+			}
+			p.i++
+			return name
+
+		case '\\', '(', ')', '[', ']', '|', '/':
+			p.fail()
+		}
+		p.i++
+	}
+	p.fail()
+	return ""
+}
+
+func (p *parser) parseAtomEscape(start int) (Atom, bool) {
+	c := p.peek()
+
+	switch c {
+	case 'b', 'B':
+		p.i++
+		return &AAssertion{Raw: p.pattern[start:p.i]}, false
+
+	case 'k':
+		if p.isUnicode() || p.hasNames {
+			p.i++
+			if !p.eat("<") {
+				p.fail()
+			}
+			name := p.parseGroupName()
+			return &ABackref{Raw: p.pattern[start:p.i], Name: name}, true
+		}
+
+	case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		digits := p.scanDigits()
+		if index, err := strconv.Atoi(digits); err == nil && index <= p.captureCount {
+			return &ABackref{Raw: p.pattern[start:p.i], Index: index}, true
+		}
+		if p.isUnicode() {
+			p.fail()
+		}
+
+		// Annex B treats this as a legacy octal escape or an identity escape
+		p.i = start + 1
+		if c >= '8' {
+			p.i++
+			return &AChar{Raw: p.pattern[start:p.i], Value: c, Start: start}, true
+		}
+		value := p.parseLegacyOctal()
+		return &AChar{Raw: p.pattern[start:p.i], Value: value, Start: start}, true
+	}
+
+	if atom := p.parseCharacterClassEscape(start); atom != nil {
+		return atom, true
+	}
+	value := p.parseCharacterEscape(false)
+	return &AChar{Raw: p.pattern[start:p.i], Value: value, Start: start}, true
+}
+
+// This handles "\d", "\p{...}", and friends. It returns nil if the escape
+// isn't one of these.
+func (p *parser) parseCharacterClassEscape(start int) Atom {
+	switch c := p.peek(); c {
+	case 'd', 'D', 's', 'S', 'w', 'W':
+		p.i++
+		return &AClassEscape{Kind: byte(c)}
+
+	case 'p', 'P':
+		if !p.isUnicode() {
+			break
+		}
+		p.i++
+		if !p.eat("{") {
+			p.fail()
+		}
+		end := strings.IndexByte(p.pattern[p.i:], '}')
+		if end < 0 {
+			p.fail()
+		}
+		name := p.pattern[p.i : p.i+end]
+		p.i += end + 1
+		property := &AProperty{Raw: p.pattern[start:p.i], Start: start, Negated: c == 'P'}
+		if equals := strings.IndexByte(name, '='); equals >= 0 {
+			property.Name = name[:equals]
+			property.Value = name[equals+1:]
+		} else {
+			property.Name = name
+		}
+		if property.Name == "" {
+			p.fail()
+		}
+		return property
+	}
+	return nil
+}
+
+// This is called after the backslash and returns the value of the escape
+func (p *parser) parseCharacterEscape(inClass bool) rune {
+	start := p.i
+	c := p.next()
+
+	switch c {
+	case 't':
+		return '\t'
+	case 'n':
+		return '\n'
+	case 'v':
+		return '\v'
+	case 'f':
+		return '\f'
+	case 'r':
+		return '\r'
+
+	case 'c':
+		if p.i < len(p.pattern) {
+			if c := p.pattern[p.i]; (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') {
+				p.i++
+				return rune(c) % 32
+			}
+		}
+		if p.isUnicode() {
+			p.fail()
+		}
+
+		// Annex B treats "\c" as a literal backslash followed by "c"
+		p.i = start
+		return '\\'
+
+	case '0':
+		if p.i >= len(p.pattern) || p.pattern[p.i] < '0' || p.pattern[p.i] > '9' {
+			return 0
+		}
+		if p.isUnicode() {
+			p.fail()
+		}
+		p.i = start
+		return p.parseLegacyOctal()
+
+	case 'x':
+		if value, ok := p.parseHex(2); ok {
+			return value
+		}
+		if p.isUnicode() {
+			p.fail()
+		}
+		return 'x'
+
+	case 'u':
+		if p.isUnicode() && p.eat("{") {
+			end := strings.IndexByte(p.pattern[p.i:], '}')
+			if end < 1 {
+				p.fail()
+			}
+			value, err := strconv.ParseUint(p.pattern[p.i:p.i+end], 16, 32)
+			if err != nil || value > utf8.MaxRune {
+				p.fail()
+			}
+			p.i += end + 1
+			return rune(value)
+		}
+		value, ok := p.parseHex(4)
+		if !ok {
+			if p.isUnicode() {
+				p.fail()
+			}
+			return 'u'
+		}
+
+		// Surrogate pairs written as two escapes are a single code point
+		if p.isUnicode() && value >= 0xD800 && value <= 0xDBFF && strings.HasPrefix(p.pattern[p.i:], "\\u") {
+			old := p.i
+			p.i += 2
+			if low, ok := p.parseHex(4); ok && low >= 0xDC00 && low <= 0xDFFF {
+				return (value-0xD800)<<10 + (low - 0xDC00) + 0x10000
+			}
+			p.i = old
+		}
+		return value
+
+	case '-':
+		if inClass {
+			return c
+		}
+	}
+
+	if p.isUnicode() {
+		// Only syntax characters can be escaped in unicode mode
+		if strings.ContainsRune("^$\\.*+?()[]{}|/", c) {
+			return c
+		}
+		if p.mode == ModeUnicodeSets && inClass && strings.ContainsRune("&-!#%,:;<=>@`~", c) {
+			return c
+		}
+		p.i = start
+		p.fail()
+	}
+
+	// Annex B allows escaping anything that's not an identifier character
+	return c
+}
+
+func (p *parser) parseLegacyOctal() rune {
+	value := rune(0)
+	for n := 0; n < 3 && p.i < len(p.pattern); n++ {
+		c := p.pattern[p.i]
+		if c < '0' || c > '7' || value*8+rune(c-'0') > 0xFF {
+			break
+		}
+		value = value*8 + rune(c-'0')
+		p.i++
+	}
+	return value
+}
+
+func (p *parser) parseHex(count int) (rune, bool) {
+	if p.i+count > len(p.pattern) {
+		return 0, false
+	}
+	value, err := strconv.ParseUint(p.pattern[p.i:p.i+count], 16, 32)
+	if err != nil {
+		return 0, false
+	}
+	p.i += count
+	return rune(value), true
+}
+
+func (p *parser) parseClass(start int) *AClass {
+	class := &AClass{Start: start, Negated: p.eat("^")}
+
+	if p.mode == ModeUnicodeSets {
+		p.parseClassSetExpression(class)
+	} else {
+		p.parseClassRanges(class)
+	}
+
+	class.Raw = p.pattern[start:p.i]
+	return class
+}
+
+func (p *parser) parseClassRanges(class *AClass) {
+	for !p.eat("]") {
+		lo := p.parseClassAtom()
+		if p.peek() == '-' && p.i+1 < len(p.pattern) && p.pattern[p.i+1] != ']' {
+			p.i++
+			hi := p.parseClassAtom()
+			loChar, loOK := lo.(*AChar)
+			hiChar, hiOK := hi.(*AChar)
+			if loOK && hiOK {
+				if loChar.Value > hiChar.Value {
+					p.fail()
+				}
+				class.Items = append(class.Items, &ARange{Raw: loChar.Raw + "-" + hiChar.Raw, Lo: loChar.Value, Hi: hiChar.Value})
+				continue
+			}
+			if p.isUnicode() {
+				p.fail()
+			}
+
+			// Annex B treats the "-" as a literal when next to a class escape
+			class.Items = append(class.Items, lo, &AChar{Raw: "-", Value: '-'}, hi)
+			continue
+		}
+		class.Items = append(class.Items, lo)
+	}
+}
+
+func (p *parser) parseClassAtom() Atom {
+	start := p.i
+	c := p.next()
+	if c != '\\' {
+		return &AChar{Raw: p.pattern[start:p.i], Value: c, Start: start}
+	}
+
+	if p.eat("b") {
+		return &AChar{Raw: p.pattern[start:p.i], Value: '\b', Start: start}
+	}
+	if atom := p.parseCharacterClassEscape(start); atom != nil {
+		return atom
+	}
+
+	// Annex B allows "\c" followed by a digit or "_" inside a class
+	if !p.isUnicode() && p.i+1 < len(p.pattern) && p.pattern[p.i] == 'c' {
+		if c := p.pattern[p.i+1]; (c >= '0' && c <= '9') || c == '_' {
+			p.i += 2
+			return &AChar{Raw: p.pattern[start:p.i], Value: rune(c) % 32, Start: start}
+		}
+	}
+
+	// Annex B also allows legacy octal escapes and "\8" inside a class
+	if !p.isUnicode() && p.i < len(p.pattern) && p.pattern[p.i] >= '1' && p.pattern[p.i] <= '9' {
+		if p.pattern[p.i] >= '8' {
+			p.i++
+			return &AChar{Raw: p.pattern[start:p.i], Value: rune(p.pattern[p.i-1]), Start: start}
+		}
+		value := p.parseLegacyOctal()
+		return &AChar{Raw: p.pattern[start:p.i], Value: value, Start: start}
+	}
+
+	value := p.parseCharacterEscape(true)
+	return &AChar{Raw: p.pattern[start:p.i], Value: value, Start: start}
+}
+
+// This implements the class syntax for the "v" flag, which supports nested
+// classes, set intersection with "&&", set subtraction with "--", and string
+// literals with "\q{...}"
+func (p *parser) parseClassSetExpression(class *AClass) {
+	if p.eat("]") {
+		return
+	}
+
+	first := p.parseClassSetOperand(true)
+	switch {
+	case strings.HasPrefix(p.pattern[p.i:], "&&"):
+		class.Op = ClassIntersection
+	case strings.HasPrefix(p.pattern[p.i:], "--"):
+		class.Op = ClassSubtraction
+	}
+
+	if class.Op != ClassUnion {
+		if _, ok := first.(*ARange); ok {
+			p.fail()
+		}
+		operator := "&&"
+		if class.Op == ClassSubtraction {
+			operator = "--"
+		}
+		class.Items = append(class.Items, first)
+		for !p.eat("]") {
+			if !p.eat(operator) {
+				p.fail()
+			}
+			class.Items = append(class.Items, p.parseClassSetOperand(false))
+		}
+		return
+	}
+
+	class.Items = append(class.Items, first)
+	for !p.eat("]") {
+		if strings.HasPrefix(p.pattern[p.i:], "&&") || strings.HasPrefix(p.pattern[p.i:], "--") {
+			p.fail()
+		}
+		class.Items = append(class.Items, p.parseClassSetOperand(true))
+	}
+}
+
+func (p *parser) parseClassSetOperand(allowRange bool) Atom {
+	start := p.i
+
+	if p.eat("[") {
+		return p.parseClass(start)
+	}
+
+	if p.eat("\\q{") {
+		strs := &AClassStrings{}
+		var current []rune
+		for {
+			if p.eat("}") {
+				strs.Strings = append(strs.Strings, current)
+				return strs
+			}
+			if p.eat("|") {
+				strs.Strings = append(strs.Strings, current)
+				current = nil
+				continue
+			}
+			current = append(current, p.parseClassSetCharacter())
+		}
+	}
+
+	if p.eat("\\") {
+		if atom := p.parseCharacterClassEscape(start); atom != nil {
+			return atom
+		}
+		p.i = start
+	}
+
+	lo := p.parseClassSetCharacter()
+	if allowRange && p.peek() == '-' && !strings.HasPrefix(p.pattern[p.i:], "--") {
+		p.i++
+		hi := p.parseClassSetCharacter()
+		if lo > hi {
+			p.fail()
+		}
+		return &ARange{Raw: p.pattern[start:p.i], Lo: lo, Hi: hi}
+	}
+	return &AChar{Raw: p.pattern[start:p.i], Value: lo, Start: start}
+}
+
+func (p *parser) parseClassSetCharacter() rune {
+	c := p.next()
+	switch c {
+	case '\\':
+		if p.eat("b") {
+			return '\b'
+		}
+		return p.parseCharacterEscape(true)
+
+	case '(', ')', '[', ']', '{', '}', '/', '-', '|':
+		p.i -= 1
+		p.fail()
+	}
+	return c
+}
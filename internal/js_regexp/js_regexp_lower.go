@@ -0,0 +1,762 @@
+package js_regexp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/evanw/esbuild/internal/compat"
+)
+
+type NamedGroup struct {
+	Name  string
+	Index int
+}
+
+type Result struct {
+	Pattern string
+	Flags   string
+
+	// Named capture groups are lowered to numbered capture groups. If this is
+	// non-empty, the regular expression must be passed to a run-time helper
+	// that adds the "groups" property to each match using this information.
+	NamedGroups []NamedGroup
+}
+
+// This describes something in the regular expression that can't be lowered.
+// Offsets are relative to the start of the pattern, and the flags are treated
+// as if they start one character after the end of the pattern (i.e. after the
+// "/" character). The text is empty if the pattern couldn't be parsed.
+type Problem struct {
+	Text  string
+	Start int
+	Len   int
+}
+
+type lowerer struct {
+	groupIndices map[string]int
+	problem      *Problem
+	js           []byte
+	namedGroups  []NamedGroup
+	mode         Mode
+	ignoreCase   bool
+	dotAll       bool
+	lowerDotAll  bool
+	lowerNames   bool
+	lowerProps   bool
+	lowerUnicode bool
+	lowerSets    bool
+	lowerBehind  bool
+}
+
+// Lower rewrites a regular expression so that it no longer uses any of the
+// features in "unsupported":
+//
+//   - Named capture groups become numbered capture groups (see "NamedGroups")
+//   - The "s" flag is removed and "." is replaced with "[\s\S]"
+//   - Unicode property escapes are replaced with the equivalent character class
+//   - Character classes with the "v" flag are computed at compile time
+//   - The "u" flag is removed and characters outside of the BMP are replaced
+//     with the equivalent UTF-16 surrogate pairs
+//
+// Some features such as lookbehind assertions and the "y" flag can't be
+// rewritten. A problem is returned in that case.
+func Lower(pattern string, flags string, unsupported compat.JSFeature) (Result, *Problem) {
+	l := lowerer{
+		mode:        ModeForFlags(flags),
+		lowerProps:  unsupported.Has(compat.RegexpUnicodePropertyEscapes),
+		lowerBehind: unsupported.Has(compat.RegexpLookbehindAssertions),
+	}
+
+	// Check the flags first since they determine how the pattern is parsed
+	var flagProblem *Problem
+	newFlags := make([]byte, 0, len(flags))
+	for i := 0; i < len(flags); i++ {
+		c := flags[i]
+		switch c {
+		case 'g', 'm':
+
+		case 'i':
+			l.ignoreCase = true
+
+		case 's':
+			l.dotAll = true
+			if unsupported.Has(compat.RegexpDotAllFlag) {
+				l.lowerDotAll = true
+				continue
+			}
+
+		case 'u':
+			if unsupported.Has(compat.RegexpStickyAndUnicodeFlags) {
+				l.lowerUnicode = true
+				continue
+			}
+
+		case 'v':
+			if unsupported.Has(compat.RegexpSetNotation) {
+				l.lowerSets = true
+				if unsupported.Has(compat.RegexpStickyAndUnicodeFlags) {
+					l.lowerUnicode = true
+					continue
+				}
+				c = 'u'
+			}
+
+		case 'y':
+			if unsupported.Has(compat.RegexpStickyAndUnicodeFlags) && flagProblem == nil {
+				flagProblem = l.flagProblem(pattern, i, c)
+			}
+
+		case 'd':
+			if unsupported.Has(compat.RegexpMatchIndices) && flagProblem == nil {
+				flagProblem = l.flagProblem(pattern, i, c)
+			}
+
+		default:
+			// Unknown flags are never supported
+			if flagProblem == nil {
+				flagProblem = l.flagProblem(pattern, i, c)
+			}
+		}
+		newFlags = append(newFlags, c)
+	}
+
+	// Without the "u" flag there's no way to write a property escape
+	if l.lowerUnicode {
+		l.lowerProps = true
+	}
+
+	tree, err := Parse(pattern, l.mode)
+	if err != nil {
+		return Result{}, &Problem{Start: err.(*SyntaxError).Offset}
+	}
+
+	if tree.HasNames && unsupported.Has(compat.RegexpNamedCaptureGroups) {
+		l.lowerNames = true
+		l.groupIndices = make(map[string]int)
+		if problem := l.collectNamedGroups(tree.Body); problem != nil {
+			return Result{}, problem
+		}
+	}
+
+	l.js = make([]byte, 0, len(pattern))
+	l.printDisjunction(tree.Body)
+	if l.problem != nil {
+		return Result{}, l.problem
+	}
+	if flagProblem != nil {
+		return Result{}, flagProblem
+	}
+
+	return Result{
+		Pattern:     string(l.js),
+		Flags:       string(newFlags),
+		NamedGroups: l.namedGroups,
+	}, nil
+}
+
+func (l *lowerer) flagProblem(pattern string, i int, c byte) *Problem {
+	return &Problem{
+		Text:  fmt.Sprintf("The regular expression flag \"%c\" is not available", c),
+		Start: len(pattern) + 1 + i,
+		Len:   1,
+	}
+}
+
+func (l *lowerer) collectNamedGroups(body Disjunction) *Problem {
+	for _, terms := range body {
+		for _, term := range terms {
+			if group, ok := term.Atom.(*AGroup); ok {
+				if group.Name != "" {
+					if _, ok := l.groupIndices[group.Name]; ok {
+						return &Problem{
+							Text:  "Duplicate named capture groups in regular expressions are not available",
+							Start: group.Start,
+							Len:   len(group.Prefix) - 1,
+						}
+					}
+					l.groupIndices[group.Name] = group.Index
+					l.namedGroups = append(l.namedGroups, NamedGroup{Name: group.Name, Index: group.Index})
+				}
+				if problem := l.collectNamedGroups(group.Body); problem != nil {
+					return problem
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (l *lowerer) printDisjunction(body Disjunction) {
+	for i, terms := range body {
+		if i > 0 {
+			l.js = append(l.js, '|')
+		}
+		for j, term := range terms {
+			followedByDigit := false
+			if j+1 < len(terms) {
+				if c, ok := terms[j+1].Atom.(*AChar); ok && c.Raw[0] >= '0' && c.Raw[0] <= '9' {
+					followedByDigit = true
+				}
+			}
+			l.printTerm(term, followedByDigit)
+		}
+	}
+}
+
+func (l *lowerer) printTerm(term Term, followedByDigit bool) {
+	quantified := term.Quantifier != ""
+
+	switch a := term.Atom.(type) {
+	case *AChar:
+		switch {
+		case l.lowerUnicode && l.ignoreCase && hasUnicodeOnlyCaseVariant(a.Value):
+			l.printSet(singleChar(a.Value).caseFold(), nil, quantified)
+
+		case l.lowerUnicode && a.Value >= 0xD800 && a.Value <= 0xDBFF:
+			// A lone high surrogate must not match the start of a surrogate pair
+			if quantified {
+				l.js = append(l.js, "(?:"...)
+			}
+			l.js = appendCodeUnit(l.js, a.Value)
+			l.js = append(l.js, "(?![\\uDC00-\\uDFFF])"...)
+			if quantified {
+				l.js = append(l.js, ')')
+			}
+
+		case l.lowerUnicode && a.Value >= 0xDC00 && a.Value <= 0xDFFF:
+			// A lone low surrogate must not match the end of a surrogate pair, but
+			// that needs a lookbehind assertion
+			l.failOnLoneLowSurrogate(a.Start, len(a.Raw))
+			l.js = appendCodeUnit(l.js, a.Value)
+
+		case l.lowerUnicode && a.Value > 0xFFFF && l.mode != ModeAnnexB:
+			// A surrogate pair is two characters without the "u" flag
+			if quantified {
+				l.js = append(l.js, "(?:"...)
+				l.printChar(a.Value)
+				l.js = append(l.js, ')')
+			} else {
+				l.printChar(a.Value)
+			}
+
+		case l.lowerUnicode && strings.HasPrefix(a.Raw, "\\u{"):
+			l.printChar(a.Value)
+
+		default:
+			l.js = append(l.js, a.Raw...)
+		}
+
+	case *ADot:
+		if l.lowerUnicode || (l.dotAll && l.lowerDotAll) {
+			set := charSet{{lo: 0, hi: l.maxChar()}}
+			if !l.dotAll {
+				set = set.subtract(lineTerminators)
+			}
+			l.printSet(set, nil, quantified)
+		} else {
+			l.js = append(l.js, '.')
+		}
+
+	case *AAssertion:
+		l.js = append(l.js, a.Raw...)
+
+	case *AGroup:
+		if l.lowerBehind && (a.Kind == GroupLookbehind || a.Kind == GroupNegativeLookbehind) && l.problem == nil {
+			l.problem = &Problem{
+				Text:  "Lookbehind assertions in regular expressions are not available",
+				Start: a.Start,
+				Len:   3,
+			}
+		}
+		if a.Name != "" && l.lowerNames {
+			l.js = append(l.js, '(')
+		} else {
+			l.js = append(l.js, a.Prefix...)
+		}
+		l.printDisjunction(a.Body)
+		l.js = append(l.js, ')')
+
+	case *ABackref:
+		if a.Name != "" && l.lowerNames {
+			// Avoid "\k<a>0" turning into "\10"
+			index := strconv.Itoa(l.groupIndices[a.Name])
+			if followedByDigit {
+				l.js = append(l.js, "(?:\\"+index+")"...)
+			} else {
+				l.js = append(l.js, "\\"+index...)
+			}
+		} else {
+			l.js = append(l.js, a.Raw...)
+		}
+
+	case *AClassEscape:
+		if l.lowerUnicode && (a.Kind < 'a' || (a.Kind == 'w' && l.ignoreCase)) {
+			l.printSet(l.classEscapeSet(a.Kind), nil, quantified)
+		} else {
+			l.js = append(l.js, '\\', a.Kind)
+		}
+
+	case *AProperty:
+		if l.lowerProps {
+			set := l.propertySet(a)
+			if l.lowerUnicode && hasUnpairedLowSurrogates(set) {
+				l.failOnLoneLowSurrogate(a.Start, len(a.Raw))
+			}
+			l.printSet(set, nil, quantified)
+		} else {
+			l.js = append(l.js, a.Raw...)
+		}
+
+	case *AClass:
+		if l.shouldEvaluateClass(a) {
+			set, strs := l.classSet(a)
+			if l.lowerUnicode && hasUnpairedLowSurrogates(set) {
+				l.failOnLoneLowSurrogate(a.Start, len(a.Raw))
+			}
+			l.printSet(set, strs, quantified)
+		} else {
+			l.js = append(l.js, a.Raw...)
+		}
+	}
+
+	l.js = append(l.js, term.Quantifier...)
+}
+
+func (l *lowerer) maxChar() rune {
+	if l.mode == ModeAnnexB {
+		return 0xFFFF
+	}
+	return maxCodePoint
+}
+
+var lineTerminators = charSet{{lo: '\n', hi: '\n'}, {lo: '\r', hi: '\r'}, {lo: 0x2028, hi: 0x2029}}
+
+var whiteSpace = newCharSet([]charRange{
+	{lo: '\t', hi: '\r'},
+	{lo: ' ', hi: ' '},
+	{lo: 0xA0, hi: 0xA0},
+	{lo: 0x1680, hi: 0x1680},
+	{lo: 0x2000, hi: 0x200A},
+	{lo: 0x2028, hi: 0x2029},
+	{lo: 0x202F, hi: 0x202F},
+	{lo: 0x205F, hi: 0x205F},
+	{lo: 0x3000, hi: 0x3000},
+	{lo: 0xFEFF, hi: 0xFEFF},
+})
+
+func (l *lowerer) classEscapeSet(kind byte) charSet {
+	var set charSet
+	switch kind | 0x20 {
+	case 'd':
+		set = charSet{{lo: '0', hi: '9'}}
+	case 's':
+		set = whiteSpace
+	case 'w':
+		set = charSet{{lo: '0', hi: '9'}, {lo: 'A', hi: 'Z'}, {lo: '_', hi: '_'}, {lo: 'a', hi: 'z'}}
+		if l.ignoreCase {
+			set = set.caseFold()
+		}
+	}
+	if kind < 'a' {
+		set = set.complement(l.maxChar())
+	}
+	return set
+}
+
+func (l *lowerer) propertySet(a *AProperty) charSet {
+	set, ok := lookupProperty(a.Name, a.Value)
+	if !ok {
+		if l.problem == nil {
+			l.problem = &Problem{
+				Text:  "Unicode property escapes in regular expressions are not available",
+				Start: a.Start,
+				Len:   len(a.Raw),
+			}
+		}
+		return nil
+	}
+	if l.ignoreCase {
+		set = set.caseFold()
+	}
+	if a.Negated {
+		set = set.complement(maxCodePoint)
+	}
+	return set
+}
+
+func (l *lowerer) shouldEvaluateClass(a *AClass) bool {
+	switch l.mode {
+	case ModeUnicodeSets:
+		return l.lowerSets
+	case ModeUnicode:
+		return l.lowerUnicode || (l.lowerProps && (strings.Contains(a.Raw, "\\p{") || strings.Contains(a.Raw, "\\P{")))
+	}
+	return false
+}
+
+// Characters are case-folded before doing any set operations, which matches
+// what the "v" flag does. Doing this before taking the complement is also
+// required to get the same behavior as "[^...]" with the "i" and "u" flags.
+func (l *lowerer) classSet(a *AClass) (charSet, [][]rune) {
+	var set charSet
+	var strs [][]rune
+
+	for i, item := range a.Items {
+		var itemSet charSet
+		var itemStrs [][]rune
+
+		switch item := item.(type) {
+		case *AChar:
+			itemSet = singleChar(item.Value)
+		case *ARange:
+			itemSet = charSet{{lo: item.Lo, hi: item.Hi}}
+		case *AClassEscape:
+			itemSet = l.classEscapeSet(item.Kind)
+		case *AProperty:
+			itemSet = l.propertySet(item)
+		case *AClass:
+			itemSet, itemStrs = l.classSet(item)
+		case *AClassStrings:
+			for _, str := range item.Strings {
+				if len(str) == 1 {
+					itemSet = itemSet.union(singleChar(str[0]))
+				} else {
+					itemStrs = unionStrings(itemStrs, [][]rune{str})
+				}
+			}
+		}
+
+		if l.ignoreCase {
+			itemSet = itemSet.caseFold()
+		}
+
+		if i == 0 {
+			set, strs = itemSet, itemStrs
+			continue
+		}
+
+		switch a.Op {
+		case ClassUnion:
+			set = set.union(itemSet)
+			strs = unionStrings(strs, itemStrs)
+		case ClassIntersection:
+			set = set.intersect(itemSet)
+			strs = filterStrings(strs, itemStrs, true)
+		case ClassSubtraction:
+			set = set.subtract(itemSet)
+			strs = filterStrings(strs, itemStrs, false)
+		}
+	}
+
+	if a.Negated {
+		if len(strs) > 0 && l.problem == nil {
+			// This is a syntax error
+			l.problem = &Problem{Start: a.Start}
+		}
+		set = set.complement(maxCodePoint)
+	}
+	return set, strs
+}
+
+func indexOfString(strs [][]rune, str []rune) int {
+	for i, other := range strs {
+		if string(other) == string(str) {
+			return i
+		}
+	}
+	return -1
+}
+
+func unionStrings(a [][]rune, b [][]rune) [][]rune {
+	for _, str := range b {
+		if indexOfString(a, str) < 0 {
+			a = append(a, str)
+		}
+	}
+	return a
+}
+
+func filterStrings(a [][]rune, b [][]rune, keepIfInB bool) (result [][]rune) {
+	for _, str := range a {
+		if (indexOfString(b, str) >= 0) == keepIfInB {
+			result = append(result, str)
+		}
+	}
+	return
+}
+
+// This prints a set of characters and strings. If "quantified" is true, the
+// output must be something that a quantifier can be applied to.
+func (l *lowerer) printSet(set charSet, strs [][]rune, quantified bool) {
+	if len(strs) == 0 {
+		l.printCharSet(set, quantified)
+		return
+	}
+
+	// Longer strings must come first since alternatives are tried in order
+	sorted := append([][]rune{}, strs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	l.js = append(l.js, "(?:"...)
+	hasEmpty := false
+	for i, str := range sorted {
+		if len(str) == 0 {
+			hasEmpty = true
+			continue
+		}
+		if i > 0 {
+			l.js = append(l.js, '|')
+		}
+		for _, c := range str {
+			l.printChar(c)
+		}
+	}
+	if len(set) > 0 {
+		if len(sorted) > 1 || !hasEmpty {
+			l.js = append(l.js, '|')
+		}
+		l.printCharSet(set, false)
+	}
+
+	// The empty string matches last because it's the shortest
+	if hasEmpty {
+		l.js = append(l.js, '|')
+	}
+	l.js = append(l.js, ')')
+}
+
+func (l *lowerer) printCharSet(set charSet, quantified bool) {
+	if !l.lowerUnicode {
+		l.printClass(set)
+		return
+	}
+
+	bmp := set.intersect(charSet{{lo: 0, hi: 0xFFFF}})
+	astral := set.intersect(charSet{{lo: 0x10000, hi: maxCodePoint}})
+
+	// A high surrogate in the set should only match by itself when it's not
+	// the start of a surrogate pair. This isn't necessary if every surrogate
+	// pair starting with it is also in the set, since those are tried first.
+	var loneHighs []charRange
+	for _, r := range bmp.intersect(charSet{{lo: 0xD800, hi: 0xDBFF}}) {
+		for c := r.lo; c <= r.hi; c++ {
+			if first := (c-0xD800)<<10 + 0x10000; !astral.containsRange(first, first+0x3FF) {
+				loneHighs = append(loneHighs, charRange{lo: c, hi: c})
+			}
+		}
+	}
+	highs := newCharSet(loneHighs)
+	bmp = bmp.subtract(highs)
+
+	pairs := surrogatePairs(astral)
+	count := len(pairs)
+	if len(bmp) > 0 {
+		count++
+	}
+	if len(highs) > 0 {
+		count++
+	}
+
+	if count == 0 || (count == 1 && len(bmp) > 0) {
+		l.printClass(bmp)
+		return
+	}
+
+	wrap := count > 1 || quantified
+	if wrap {
+		l.js = append(l.js, "(?:"...)
+	}
+	for i, pair := range pairs {
+		if i > 0 {
+			l.js = append(l.js, '|')
+		}
+		l.printCodeUnits(charSet{pair.hi})
+		l.printCodeUnits(pair.lo)
+	}
+	if len(bmp) > 0 {
+		if len(pairs) > 0 {
+			l.js = append(l.js, '|')
+		}
+		l.printClass(bmp)
+	}
+	if len(highs) > 0 {
+		if len(pairs) > 0 || len(bmp) > 0 {
+			l.js = append(l.js, '|')
+		}
+		l.printClass(highs)
+		l.js = append(l.js, "(?![\\uDC00-\\uDFFF])"...)
+	}
+	if wrap {
+		l.js = append(l.js, ')')
+	}
+}
+
+func (l *lowerer) failOnLoneLowSurrogate(start int, length int) {
+	if l.problem == nil {
+		l.problem = &Problem{
+			Text:  "Lone low surrogates in regular expressions with the \"u\" flag are not available",
+			Start: start,
+			Len:   length,
+		}
+	}
+}
+
+// Without the "u" flag, a low surrogate can also match the second half of a
+// surrogate pair. That doesn't change anything if every surrogate pair ending
+// with that low surrogate is also in the set (e.g. for "." or "[^a]"), since
+// the surrogate pair would have matched first.
+func hasUnpairedLowSurrogates(set charSet) bool {
+	lows := set.intersect(charSet{{lo: 0xDC00, hi: 0xDFFF}})
+	if len(lows) == 0 {
+		return false
+	}
+	paired := lows
+	next := rune(0xD800)
+	for _, pair := range surrogatePairs(set.intersect(charSet{{lo: 0x10000, hi: maxCodePoint}})) {
+		if pair.hi.lo != next {
+			return true
+		}
+		paired = paired.intersect(pair.lo)
+		next = pair.hi.hi + 1
+	}
+	return next != 0xDC00 || !paired.equals(lows)
+}
+
+type surrogatePair struct {
+	lo charSet
+	hi charRange
+}
+
+// This converts a set of code points outside the BMP into a list of ranges of
+// high surrogates, each followed by a set of low surrogates
+func surrogatePairs(set charSet) []surrogatePair {
+	var pairs []surrogatePair
+	add := func(hiStart rune, hiEnd rune, loStart rune, loEnd rune) {
+		lo := charSet{{lo: loStart, hi: loEnd}}
+		if n := len(pairs); n > 0 && pairs[n-1].hi.lo == hiStart && pairs[n-1].hi.hi == hiEnd {
+			pairs[n-1].lo = pairs[n-1].lo.union(lo)
+			return
+		}
+		pairs = append(pairs, surrogatePair{hi: charRange{lo: hiStart, hi: hiEnd}, lo: lo})
+	}
+
+	for _, r := range set {
+		hiStart, loStart := splitSurrogates(r.lo)
+		hiEnd, loEnd := splitSurrogates(r.hi)
+		if hiStart == hiEnd {
+			add(hiStart, hiStart, loStart, loEnd)
+			continue
+		}
+		if loStart != 0xDC00 {
+			add(hiStart, hiStart, loStart, 0xDFFF)
+			hiStart++
+		}
+		if loEnd != 0xDFFF {
+			hiEnd--
+		}
+		if hiStart <= hiEnd {
+			add(hiStart, hiEnd, 0xDC00, 0xDFFF)
+		}
+		if loEnd != 0xDFFF {
+			add(hiEnd+1, hiEnd+1, 0xDC00, loEnd)
+		}
+	}
+
+	// Merge adjacent high surrogates that are followed by the same low surrogates
+	merged := pairs[:0]
+	for _, pair := range pairs {
+		if n := len(merged); n > 0 && merged[n-1].hi.hi+1 == pair.hi.lo && merged[n-1].lo.equals(pair.lo) {
+			merged[n-1].hi.hi = pair.hi.hi
+			continue
+		}
+		merged = append(merged, pair)
+	}
+	return merged
+}
+
+func splitSurrogates(c rune) (rune, rune) {
+	c -= 0x10000
+	return 0xD800 + (c >> 10), 0xDC00 + (c & 0x3FF)
+}
+
+func (l *lowerer) printCodeUnits(set charSet) {
+	if len(set) == 1 && set[0].lo == set[0].hi {
+		l.js = appendCodeUnit(l.js, set[0].lo)
+	} else {
+		l.printClass(set)
+	}
+}
+
+func (l *lowerer) printClass(set charSet) {
+	max := rune(maxCodePoint)
+	if l.lowerUnicode || l.mode == ModeAnnexB {
+		max = 0xFFFF
+	}
+	if len(set) == 1 && set[0].lo == 0 && set[0].hi >= max {
+		l.js = append(l.js, "[\\s\\S]"...)
+		return
+	}
+	l.js = append(l.js, '[')
+	for _, r := range set {
+		l.js = appendClassChar(l.js, r.lo)
+		if r.hi > r.lo {
+			if r.hi > r.lo+1 {
+				l.js = append(l.js, '-')
+			}
+			l.js = appendClassChar(l.js, r.hi)
+		}
+	}
+	l.js = append(l.js, ']')
+}
+
+// This prints a character outside of a character class
+func (l *lowerer) printChar(c rune) {
+	if c > 0xFFFF && l.lowerUnicode {
+		hi, lo := splitSurrogates(c)
+		l.js = appendCodeUnit(l.js, hi)
+		l.js = appendCodeUnit(l.js, lo)
+		return
+	}
+	if c < 0x80 && strings.IndexByte("^$\\.*+?()[]{}|/", byte(c)) >= 0 {
+		l.js = append(l.js, '\\', byte(c))
+		return
+	}
+	l.js = appendClassChar(l.js, c)
+}
+
+func appendCodeUnit(js []byte, c rune) []byte {
+	return append(js, fmt.Sprintf("\\u%04X", c)...)
+}
+
+// Characters outside of the BMP are only printed here when the output has the
+// "u" flag, so they can use the "\u{...}" syntax
+func appendClassChar(js []byte, c rune) []byte {
+	switch c {
+	case '\\', ']', '[', '^', '-', '/':
+		return append(js, '\\', byte(c))
+	case '\t':
+		return append(js, "\\t"...)
+	case '\n':
+		return append(js, "\\n"...)
+	case '\v':
+		return append(js, "\\v"...)
+	case '\f':
+		return append(js, "\\f"...)
+	case '\r':
+		return append(js, "\\r"...)
+	}
+	switch {
+	case c >= 0x20 && c < 0x7F:
+		return append(js, byte(c))
+	case c <= 0xFF:
+		return append(js, fmt.Sprintf("\\x%02X", c)...)
+	case c <= 0xFFFF:
+		return appendCodeUnit(js, c)
+	default:
+		return append(js, fmt.Sprintf("\\u{%X}", c)...)
+	}
+}
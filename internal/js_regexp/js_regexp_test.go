@@ -0,0 +1,190 @@
+package js_regexp
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/compat"
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func expectLowered(t *testing.T, unsupported compat.JSFeature, regexp string, expected string) {
+	t.Helper()
+	t.Run(regexp, func(t *testing.T) {
+		t.Helper()
+		end := strings.LastIndexByte(regexp, '/')
+		result, problem := Lower(regexp[1:end], regexp[end+1:], unsupported)
+		text := ""
+		if problem != nil {
+			text = fmt.Sprintf("problem at %d: %s", problem.Start, problem.Text)
+		} else {
+			text = "/" + result.Pattern + "/" + result.Flags
+			for _, group := range result.NamedGroups {
+				text += fmt.Sprintf(" %s=%d", group.Name, group.Index)
+			}
+		}
+		test.AssertEqualWithDiff(t, text, expected)
+	})
+}
+
+func TestParse(t *testing.T) {
+	valid := func(pattern string, mode Mode) {
+		t.Helper()
+		_, err := Parse(pattern, mode)
+		test.AssertEqual(t, err, nil)
+	}
+	invalid := func(pattern string, mode Mode, offset int) {
+		t.Helper()
+		_, err := Parse(pattern, mode)
+		if err, ok := err.(*SyntaxError); ok {
+			test.AssertEqual(t, err.Offset, offset)
+		} else {
+			t.Fatalf("Expected a syntax error for %q", pattern)
+		}
+	}
+
+	valid("a{", ModeAnnexB)
+	valid("a{1", ModeAnnexB)
+	valid("]", ModeAnnexB)
+	valid("\\c", ModeAnnexB)
+	valid("\\1", ModeAnnexB)
+	valid("(?=a)*", ModeAnnexB)
+	valid("[\\w-a]", ModeAnnexB)
+	valid("\\k", ModeAnnexB)
+	valid("(?<a>)\\k<a>", ModeAnnexB)
+	valid("\\u{1F600}", ModeUnicode)
+	valid("[\\p{L}--[a-z]]", ModeUnicodeSets)
+	valid("[[a-z]&&\\q{x|yz}]", ModeUnicodeSets)
+	valid("(?i:a)", ModeUnicode)
+
+	invalid("a{", ModeUnicode, 1)
+	invalid("]", ModeUnicode, 0)
+	invalid("\\c", ModeUnicode, 2)
+	invalid("\\1", ModeUnicode, 2)
+	invalid("(?=a)*", ModeUnicode, 5)
+	invalid("[\\w-a]", ModeUnicode, 5)
+	invalid("\\k", ModeUnicode, 2)
+	invalid("(?<=a)*", ModeAnnexB, 6)
+	invalid("[b-a]", ModeAnnexB, 4)
+	invalid("[a&&b-c]", ModeUnicodeSets, 5)
+	invalid("[a-b&&c]", ModeUnicodeSets, 4)
+	invalid("[(]", ModeUnicodeSets, 1)
+	invalid("(", ModeAnnexB, 1)
+	invalid("a)", ModeAnnexB, 1)
+}
+
+func TestLowerDotAll(t *testing.T) {
+	expectLowered(t, compat.RegexpDotAllFlag, "/a.b/s", "/a[\\s\\S]b/")
+	expectLowered(t, compat.RegexpDotAllFlag, "/[.]/gs", "/[.]/g")
+	expectLowered(t, compat.RegexpDotAllFlag, "/\\./s", "/\\./")
+	expectLowered(t, compat.RegexpDotAllFlag, "/a.b/su", "/a[\\s\\S]b/u")
+	expectLowered(t, compat.RegexpDotAllFlag, "/a.b/", "/a.b/")
+}
+
+func TestLowerNamedGroups(t *testing.T) {
+	expectLowered(t, compat.RegexpNamedCaptureGroups, "/(?<y>\\d+)-(?<m>\\d+)/", "/(\\d+)-(\\d+)/ y=1 m=2")
+	expectLowered(t, compat.RegexpNamedCaptureGroups, "/(a)(?:(?<b>b)|(c))/", "/(a)(?:(b)|(c))/ b=2")
+	expectLowered(t, compat.RegexpNamedCaptureGroups, "/(?<a>.)\\k<a>/", "/(.)\\1/ a=1")
+	expectLowered(t, compat.RegexpNamedCaptureGroups, "/(?<a>.)\\k<a>0/", "/(.)(?:\\1)0/ a=1")
+	expectLowered(t, compat.RegexpNamedCaptureGroups, "/\\k<a>(?<a>.)/u", "/\\1(.)/u a=1")
+	expectLowered(t, compat.RegexpNamedCaptureGroups, "/(?<a>x)|(?<a>y)/", "problem at 9: Duplicate named capture groups in regular expressions are not available")
+}
+
+func TestLowerUnicodePropertyEscapes(t *testing.T) {
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/\\p{ASCII_Hex_Digit}/u", "/[0-9A-Fa-f]/u")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/\\p{AHex}+/u", "/[0-9A-Fa-f]+/u")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/[\\p{AHex}_]/u", "/[0-9A-F_a-f]/u")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/[^\\p{AHex}]/u", "/[\\x00-\\/:-@G-`g-\\u{10FFFF}]/u")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/\\P{ASCII}/u", "/[\\x80-\\u{10FFFF}]/u")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/\\p{Script=Ogham}/u", "/[\\u1680-\\u169C]/u")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/\\p{sc=Ogham}/u", "/[\\u1680-\\u169C]/u")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/\\p{AHex}/iu", "/[0-9A-Fa-f]/iu")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/\\P{AHex}/iu", "/[\\x00-\\/:-@G-`g-\\u{10FFFF}]/iu")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/[a-z]\\p{Emoji}/u",
+		"problem at 5: Unicode property escapes in regular expressions are not available")
+	expectLowered(t, compat.RegexpUnicodePropertyEscapes, "/\\p{Foo}/u",
+		"problem at 0: Unicode property escapes in regular expressions are not available")
+}
+
+func TestLowerUnicodeFlag(t *testing.T) {
+	unicode := compat.RegexpStickyAndUnicodeFlags | compat.RegexpUnicodePropertyEscapes
+	expectLowered(t, unicode, "/abc/u", "/abc/")
+	expectLowered(t, unicode, "/\\u{41}/u", "/A/")
+	expectLowered(t, unicode, "/😀/u", "/\\uD83D\\uDE00/")
+	expectLowered(t, unicode, "/😀+/u", "/(?:\\uD83D\\uDE00)+/")
+	expectLowered(t, unicode, "/\\u{1F600}?/u", "/(?:\\uD83D\\uDE00)?/")
+	expectLowered(t, unicode, "/\\uD83D\\uDE00*/u", "/(?:\\uD83D\\uDE00)*/")
+	expectLowered(t, unicode, "/[😀-😂]/u", "/\\uD83D[\\uDE00-\\uDE02]/")
+	expectLowered(t, unicode, "/[a😀]/u", "/(?:\\uD83D\\uDE00|[a])/")
+	expectLowered(t, unicode, "/[\\u{10000}-\\u{10FFFF}]/u", "/[\\uD800-\\uDBFF][\\uDC00-\\uDFFF]/")
+	expectLowered(t, unicode, "/[\\u{1F000}-\\u{20000}]/u",
+		"/(?:[\\uD83C-\\uD83F][\\uDC00-\\uDFFF]|\\uD840\\uDC00)/")
+	expectLowered(t, unicode, "/[\\u{1F000}-\\u{1F0FF}\\u{1F400}]/u", "/(?:\\uD83C[\\uDC00-\\uDCFF]|\\uD83D\\uDC00)/")
+	expectLowered(t, unicode, "/./u", "/(?:[\\uD800-\\uDBFF][\\uDC00-\\uDFFF]|[\\x00-\\t\\v\\f\\x0E-\\u2027\\u202A-\\uFFFF])/")
+	expectLowered(t, unicode, "/./su", "/(?:[\\uD800-\\uDBFF][\\uDC00-\\uDFFF]|[\\s\\S])/s")
+	expectLowered(t, unicode, "/[^a]/u", "/(?:[\\uD800-\\uDBFF][\\uDC00-\\uDFFF]|[\\x00-`b-\\uFFFF])/")
+	expectLowered(t, unicode, "/\\S/u", "/(?:[\\uD800-\\uDBFF][\\uDC00-\\uDFFF]|[\\x00-\\x08\\x0E-\\x1F!-\\x9F\\xA1-\\u167F\\u1681-\\u1FFF\\u200B-\\u2027\\u202A-\\u202E\\u2030-\\u205E\\u2060-\\u2FFF\\u3001-\\uFEFE\\uFF00-\\uFFFF])/")
+	expectLowered(t, unicode, "/\\d\\s\\w/u", "/\\d\\s\\w/")
+	expectLowered(t, unicode, "/[\\uD83D]/u", "/[\\uD83D](?![\\uDC00-\\uDFFF])/")
+	expectLowered(t, unicode, "/\\uD83D/u", "/\\uD83D(?![\\uDC00-\\uDFFF])/")
+	expectLowered(t, unicode, "/\\uD83D+/u", "/(?:\\uD83D(?![\\uDC00-\\uDFFF]))+/")
+	expectLowered(t, unicode, "/a\\udf06/u", "problem at 1: Lone low surrogates in regular expressions with the \"u\" flag are not available")
+	expectLowered(t, unicode, "/[\\uDC00-\\uDFFF]/u", "problem at 0: Lone low surrogates in regular expressions with the \"u\" flag are not available")
+	expectLowered(t, unicode, "/[^\\u{1F600}]/u", "problem at 0: Lone low surrogates in regular expressions with the \"u\" flag are not available")
+	expectLowered(t, unicode, "/\\P{L}/u", "problem at 0: Lone low surrogates in regular expressions with the \"u\" flag are not available")
+	expectLowered(t, unicode, "/s/iu", "/[Ss\\u017F]/i")
+	expectLowered(t, unicode, "/\\w/iu", "/[0-9A-Z_a-z\\u017F\\u212A]/i")
+	expectLowered(t, unicode, "/\\u{10400}/iu", "/\\uD801[\\uDC00\\uDC28]/i")
+	expectLowered(t, unicode, "/\\u{10400}+/iu", "/(?:\\uD801[\\uDC00\\uDC28])+/i")
+	expectLowered(t, unicode, "/\\p{ASCII}/u", "/[\\x00-\\x7F]/")
+	expectLowered(t, unicode, "/x/y", "problem at 2: The regular expression flag \"y\" is not available")
+}
+
+func TestLowerSetNotation(t *testing.T) {
+	sets := compat.RegexpSetNotation
+	expectLowered(t, sets, "/[[a-z]--[aeiou]]/v", "/[b-df-hj-np-tv-z]/u")
+	expectLowered(t, sets, "/[[a-z]&&[^aeiou]]/v", "/[b-df-hj-np-tv-z]/u")
+	expectLowered(t, sets, "/[\\p{ASCII}&&\\p{L}]/v", "/[A-Za-z]/u")
+	expectLowered(t, sets, "/[\\q{abc|d|}x]/v", "/(?:abc|[dx]|)/u")
+	expectLowered(t, sets, "/[\\q{abc|de}--\\q{de}]/v", "/(?:abc)/u")
+	expectLowered(t, sets, "/[[a-c]--b]/iv", "/[ACac]/iu")
+	expectLowered(t, sets, "/[^\\q{ab}]/v", "problem at 0: ")
+	expectLowered(t, sets, "/[\\p{RGI_Emoji}]/v", "problem at 1: Unicode property escapes in regular expressions are not available")
+	expectLowered(t, sets, "/a(b)|c/v", "/a(b)|c/u")
+	expectLowered(t, sets|compat.RegexpStickyAndUnicodeFlags, "/[\\q{😀}a]/v", "/(?:\\uD83D\\uDE00|[a])/")
+}
+
+func TestLowerUnsupported(t *testing.T) {
+	expectLowered(t, compat.RegexpLookbehindAssertions, "/(?<=x)y/", "problem at 1: Lookbehind assertions in regular expressions are not available")
+	expectLowered(t, compat.RegexpLookbehindAssertions, "/a(?<!x)/", "problem at 2: Lookbehind assertions in regular expressions are not available")
+	expectLowered(t, compat.RegexpMatchIndices, "/x/d", "problem at 2: The regular expression flag \"d\" is not available")
+	expectLowered(t, compat.RegexpDotAllFlag, "/x/sz", "problem at 3: The regular expression flag \"z\" is not available")
+	expectLowered(t, compat.RegexpDotAllFlag, "/(/s", "problem at 1: ")
+}
+
+func TestLookupProperty(t *testing.T) {
+	greek, ok := lookupProperty("Script", "Greek")
+	test.AssertEqual(t, ok, true)
+	grek, ok := lookupProperty("sc", "Grek")
+	test.AssertEqual(t, ok, true)
+	test.AssertEqual(t, greek.equals(grek), true)
+
+	letter, _ := lookupProperty("L", "")
+	upper, _ := lookupProperty("Uppercase_Letter", "")
+	gcUpper, _ := lookupProperty("gc", "Lu")
+	test.AssertEqual(t, upper.equals(gcUpper), true)
+	test.AssertEqual(t, upper.subtract(letter) == nil, true)
+	test.AssertEqual(t, letter.contains('x'), true)
+	test.AssertEqual(t, letter.contains('1'), false)
+
+	assigned, _ := lookupProperty("Assigned", "")
+	unassigned, _ := lookupProperty("Cn", "")
+	test.AssertEqual(t, assigned.union(unassigned).equals(charSet{{lo: 0, hi: maxCodePoint}}), true)
+	test.AssertEqual(t, assigned.intersect(unassigned) == nil, true)
+
+	_, ok = lookupProperty("Emoji", "")
+	test.AssertEqual(t, ok, false)
+	_, ok = lookupProperty("Script_Extensions", "Latin")
+	test.AssertEqual(t, ok, false)
+}
@@ -0,0 +1,449 @@
+package js_regexp
+
+import (
+	"sort"
+	"sync"
+	"unicode"
+)
+
+const maxCodePoint = unicode.MaxRune
+
+type charRange struct {
+	lo rune
+	hi rune
+}
+
+// A set of code points stored as sorted, non-overlapping, non-adjacent ranges
+type charSet []charRange
+
+func newCharSet(ranges []charRange) charSet {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].lo < ranges[j].lo
+	})
+	var set charSet
+	for _, r := range ranges {
+		if n := len(set); n > 0 && r.lo <= set[n-1].hi+1 {
+			if r.hi > set[n-1].hi {
+				set[n-1].hi = r.hi
+			}
+			continue
+		}
+		set = append(set, r)
+	}
+	return set
+}
+
+func singleChar(c rune) charSet {
+	return charSet{{lo: c, hi: c}}
+}
+
+func (a charSet) union(b charSet) charSet {
+	ranges := make([]charRange, 0, len(a)+len(b))
+	ranges = append(ranges, a...)
+	ranges = append(ranges, b...)
+	return newCharSet(ranges)
+}
+
+func (a charSet) intersect(b charSet) charSet {
+	var set charSet
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		lo, hi := a[i].lo, a[i].hi
+		if b[j].lo > lo {
+			lo = b[j].lo
+		}
+		if b[j].hi < hi {
+			hi = b[j].hi
+		}
+		if lo <= hi {
+			set = append(set, charRange{lo: lo, hi: hi})
+		}
+		if a[i].hi < b[j].hi {
+			i++
+		} else {
+			j++
+		}
+	}
+	return set
+}
+
+func (a charSet) complement(max rune) charSet {
+	var set charSet
+	next := rune(0)
+	for _, r := range a {
+		if r.lo > max {
+			break
+		}
+		if r.lo > next {
+			set = append(set, charRange{lo: next, hi: r.lo - 1})
+		}
+		next = r.hi + 1
+	}
+	if next <= max {
+		set = append(set, charRange{lo: next, hi: max})
+	}
+	return set
+}
+
+func (a charSet) subtract(b charSet) charSet {
+	return a.intersect(b.complement(maxCodePoint))
+}
+
+func (a charSet) contains(c rune) bool {
+	i := sort.Search(len(a), func(i int) bool { return a[i].hi >= c })
+	return i < len(a) && a[i].lo <= c
+}
+
+func (a charSet) containsRange(lo rune, hi rune) bool {
+	i := sort.Search(len(a), func(i int) bool { return a[i].hi >= lo })
+	return i < len(a) && a[i].lo <= lo && a[i].hi >= hi
+}
+
+func (a charSet) equals(b charSet) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, r := range a {
+		if r != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func setFromTable(table *unicode.RangeTable) charSet {
+	var ranges []charRange
+	for _, r := range table.R16 {
+		if r.Stride == 1 {
+			ranges = append(ranges, charRange{lo: rune(r.Lo), hi: rune(r.Hi)})
+			continue
+		}
+		for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+			ranges = append(ranges, charRange{lo: c, hi: c})
+		}
+	}
+	for _, r := range table.R32 {
+		if r.Stride == 1 {
+			ranges = append(ranges, charRange{lo: rune(r.Lo), hi: rune(r.Hi)})
+			continue
+		}
+		for c := rune(r.Lo); c <= rune(r.Hi); c += rune(r.Stride) {
+			ranges = append(ranges, charRange{lo: c, hi: c})
+		}
+	}
+	return newCharSet(ranges)
+}
+
+// This is a sorted list of all code points that case-fold to something other
+// than themselves. It's computed once on demand because it's only needed when
+// a case-insensitive regular expression must be rewritten.
+var foldableOnce sync.Once
+var foldable []rune
+
+func foldableCodePoints() []rune {
+	foldableOnce.Do(func() {
+		for c := rune(0); c <= maxCodePoint; c++ {
+			if unicode.SimpleFold(c) != c {
+				foldable = append(foldable, c)
+			}
+		}
+	})
+	return foldable
+}
+
+// This adds all case variants of every code point in the set, which is what
+// the "i" flag does for each character in the pattern
+func (a charSet) caseFold() charSet {
+	points := foldableCodePoints()
+	var extra []charRange
+	for _, r := range a {
+		i := sort.Search(len(points), func(i int) bool { return points[i] >= r.lo })
+		for ; i < len(points) && points[i] <= r.hi; i++ {
+			for c := unicode.SimpleFold(points[i]); c != points[i]; c = unicode.SimpleFold(c) {
+				extra = append(extra, charRange{lo: c, hi: c})
+			}
+		}
+	}
+	if extra == nil {
+		return a
+	}
+	return a.union(newCharSet(extra))
+}
+
+// Returns true if case-insensitive matching of this code point behaves
+// differently with and without the "u" flag. Without the "u" flag, non-ASCII
+// characters are never considered equal to ASCII characters and characters
+// outside the BMP are never considered equal to anything else.
+func hasUnicodeOnlyCaseVariant(c rune) bool {
+	for other := unicode.SimpleFold(c); other != c; other = unicode.SimpleFold(other) {
+		if c > 0x7F || other > 0x7F {
+			return true
+		}
+	}
+	return false
+}
+
+// Each "\p{...}" escape is looked up once and then shared
+var propertyCacheMutex sync.Mutex
+var propertyCache = make(map[string]charSet)
+
+// This returns false for properties that esbuild doesn't have data for. These
+// include properties of strings such as "RGI_Emoji", "Script_Extensions", and
+// a few binary properties that aren't included in Go's "unicode" package.
+func lookupProperty(name string, value string) (charSet, bool) {
+	key := name + "=" + value
+	propertyCacheMutex.Lock()
+	set, ok := propertyCache[key]
+	propertyCacheMutex.Unlock()
+	if ok {
+		return set, set != nil
+	}
+
+	set = computeProperty(name, value)
+	propertyCacheMutex.Lock()
+	propertyCache[key] = set
+	propertyCacheMutex.Unlock()
+	return set, set != nil
+}
+
+func computeProperty(name string, value string) charSet {
+	if value != "" {
+		switch name {
+		case "General_Category", "gc":
+			return generalCategory(value)
+
+		case "Script", "sc":
+			if long, ok := scriptAliases[value]; ok {
+				value = long
+			}
+			if table, ok := unicode.Scripts[value]; ok {
+				return setFromTable(table)
+			}
+		}
+		return nil
+	}
+
+	if set := generalCategory(name); set != nil {
+		return set
+	}
+	return binaryProperty(name)
+}
+
+var categoryAliases = map[string]string{
+	"Cased_Letter":          "LC",
+	"Close_Punctuation":     "Pe",
+	"Connector_Punctuation": "Pc",
+	"Control":               "Cc",
+	"Currency_Symbol":       "Sc",
+	"Dash_Punctuation":      "Pd",
+	"Decimal_Number":        "Nd",
+	"Enclosing_Mark":        "Me",
+	"Final_Punctuation":     "Pf",
+	"Format":                "Cf",
+	"Initial_Punctuation":   "Pi",
+	"Letter":                "L",
+	"Letter_Number":         "Nl",
+	"Line_Separator":        "Zl",
+	"Lowercase_Letter":      "Ll",
+	"Mark":                  "M",
+	"Combining_Mark":        "M",
+	"Math_Symbol":           "Sm",
+	"Modifier_Letter":       "Lm",
+	"Modifier_Symbol":       "Sk",
+	"Nonspacing_Mark":       "Mn",
+	"Number":                "N",
+	"Open_Punctuation":      "Ps",
+	"Other":                 "C",
+	"Other_Letter":          "Lo",
+	"Other_Number":          "No",
+	"Other_Punctuation":     "Po",
+	"Other_Symbol":          "So",
+	"Paragraph_Separator":   "Zp",
+	"Private_Use":           "Co",
+	"Punctuation":           "P",
+	"Separator":             "Z",
+	"Space_Separator":       "Zs",
+	"Spacing_Mark":          "Mc",
+	"Surrogate":             "Cs",
+	"Symbol":                "S",
+	"Titlecase_Letter":      "Lt",
+	"Unassigned":            "Cn",
+	"Uppercase_Letter":      "Lu",
+	"cntrl":                 "Cc",
+	"digit":                 "Nd",
+	"punct":                 "P",
+}
+
+func generalCategory(name string) charSet {
+	if short, ok := categoryAliases[name]; ok {
+		name = short
+	}
+
+	switch name {
+	case "LC":
+		return setFromTable(unicode.Lu).union(setFromTable(unicode.Ll)).union(setFromTable(unicode.Lt))
+
+	case "Cn":
+		return unassigned()
+
+	case "C":
+		// Go's table for "C" doesn't include unassigned code points
+		return setFromTable(unicode.C).union(unassigned())
+	}
+
+	if len(name) <= 2 {
+		if table, ok := unicode.Categories[name]; ok {
+			return setFromTable(table)
+		}
+	}
+	return nil
+}
+
+func unassigned() charSet {
+	var assigned charSet
+	for name, table := range unicode.Categories {
+		if len(name) == 1 {
+			assigned = assigned.union(setFromTable(table))
+		}
+	}
+	return assigned.complement(maxCodePoint)
+}
+
+// These are the short names for some common scripts. Scripts without an
+// entry here can still be referenced using their long names.
+var scriptAliases = map[string]string{
+	"Arab": "Arabic",
+	"Armn": "Armenian",
+	"Beng": "Bengali",
+	"Cyrl": "Cyrillic",
+	"Deva": "Devanagari",
+	"Ethi": "Ethiopic",
+	"Geor": "Georgian",
+	"Grek": "Greek",
+	"Gujr": "Gujarati",
+	"Guru": "Gurmukhi",
+	"Hang": "Hangul",
+	"Hani": "Han",
+	"Hebr": "Hebrew",
+	"Hira": "Hiragana",
+	"Kana": "Katakana",
+	"Khmr": "Khmer",
+	"Knda": "Kannada",
+	"Laoo": "Lao",
+	"Latn": "Latin",
+	"Mlym": "Malayalam",
+	"Mong": "Mongolian",
+	"Mymr": "Myanmar",
+	"Orya": "Oriya",
+	"Sinh": "Sinhala",
+	"Taml": "Tamil",
+	"Telu": "Telugu",
+	"Thai": "Thai",
+	"Tibt": "Tibetan",
+	"Zinh": "Inherited",
+	"Zyyy": "Common",
+}
+
+var binaryPropertyAliases = map[string]string{
+	"AHex":    "ASCII_Hex_Digit",
+	"Alpha":   "Alphabetic",
+	"Bidi_C":  "Bidi_Control",
+	"Dep":     "Deprecated",
+	"Dia":     "Diacritic",
+	"Ext":     "Extender",
+	"Gr_Base": "Grapheme_Base",
+	"Gr_Ext":  "Grapheme_Extend",
+	"Hex":     "Hex_Digit",
+	"IDC":     "ID_Continue",
+	"IDS":     "ID_Start",
+	"IDSB":    "IDS_Binary_Operator",
+	"IDST":    "IDS_Trinary_Operator",
+	"Ideo":    "Ideographic",
+	"Join_C":  "Join_Control",
+	"LOE":     "Logical_Order_Exception",
+	"Lower":   "Lowercase",
+	"NChar":   "Noncharacter_Code_Point",
+	"Pat_Syn": "Pattern_Syntax",
+	"Pat_WS":  "Pattern_White_Space",
+	"QMark":   "Quotation_Mark",
+	"RI":      "Regional_Indicator",
+	"SD":      "Soft_Dotted",
+	"STerm":   "Sentence_Terminal",
+	"Term":    "Terminal_Punctuation",
+	"UIdeo":   "Unified_Ideograph",
+	"Upper":   "Uppercase",
+	"VS":      "Variation_Selector",
+	"space":   "White_Space",
+}
+
+// Go's "unicode" package only has the properties from "PropList.txt". Some of
+// the properties from "DerivedCoreProperties.txt" are derived from those here
+// using the definitions from that file.
+func binaryProperty(name string) charSet {
+	if long, ok := binaryPropertyAliases[name]; ok {
+		name = long
+	}
+
+	table := func(name string) charSet {
+		if table, ok := unicode.Properties[name]; ok {
+			return setFromTable(table)
+		}
+		if table, ok := unicode.Categories[name]; ok {
+			return setFromTable(table)
+		}
+		panic("Internal error: Missing Unicode table " + name)
+	}
+
+	switch name {
+	case "Any":
+		return charSet{{lo: 0, hi: maxCodePoint}}
+
+	case "ASCII":
+		return charSet{{lo: 0, hi: 0x7F}}
+
+	case "Assigned":
+		return unassigned().complement(maxCodePoint)
+
+	case "Alphabetic":
+		return table("L").union(table("Nl")).union(table("Other_Alphabetic"))
+
+	case "Lowercase":
+		return table("Ll").union(table("Other_Lowercase"))
+
+	case "Uppercase":
+		return table("Lu").union(table("Other_Uppercase"))
+
+	case "Cased":
+		return table("Ll").union(table("Other_Lowercase")).union(table("Lu")).union(table("Other_Uppercase")).union(table("Lt"))
+
+	case "Math":
+		return table("Sm").union(table("Other_Math"))
+
+	case "ID_Start":
+		return table("L").union(table("Nl")).union(table("Other_ID_Start")).
+			subtract(table("Pattern_Syntax")).subtract(table("Pattern_White_Space"))
+
+	case "ID_Continue":
+		return table("L").union(table("Nl")).union(table("Other_ID_Start")).
+			union(table("Mn")).union(table("Mc")).union(table("Nd")).union(table("Pc")).union(table("Other_ID_Continue")).
+			subtract(table("Pattern_Syntax")).subtract(table("Pattern_White_Space"))
+
+	case "Grapheme_Extend":
+		return table("Me").union(table("Mn")).union(table("Other_Grapheme_Extend"))
+
+	case "Grapheme_Base":
+		return table("Cc").union(table("Cf")).union(table("Cs")).union(table("Co")).union(unassigned()).
+			union(table("Zl")).union(table("Zp")).union(binaryProperty("Grapheme_Extend")).complement(maxCodePoint)
+
+	case "ASCII_Hex_Digit", "Bidi_Control", "Dash", "Deprecated", "Diacritic", "Extender", "Hex_Digit",
+		"IDS_Binary_Operator", "IDS_Trinary_Operator", "Ideographic", "Join_Control", "Logical_Order_Exception",
+		"Noncharacter_Code_Point", "Pattern_Syntax", "Pattern_White_Space", "Quotation_Mark", "Radical",
+		"Regional_Indicator", "Sentence_Terminal", "Soft_Dotted", "Terminal_Punctuation", "Unified_Ideograph",
+		"Variation_Selector", "White_Space":
+		if table, ok := unicode.Properties[name]; ok {
+			return setFromTable(table)
+		}
+	}
+
+	return nil
+}
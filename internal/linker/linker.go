@@ -31,8 +31,10 @@ import (
 	"github.com/evanw/esbuild/internal/fs"
 	"github.com/evanw/esbuild/internal/graph"
 	"github.com/evanw/esbuild/internal/helpers"
+	"github.com/evanw/esbuild/internal/html_ast"
 	"github.com/evanw/esbuild/internal/js_ast"
 	"github.com/evanw/esbuild/internal/js_lexer"
+	"github.com/evanw/esbuild/internal/js_parser"
 	"github.com/evanw/esbuild/internal/js_printer"
 	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/renamer"
@@ -72,6 +74,17 @@ type linkerContext struct {
 	// We may need to refer to the "__esm" and/or "__commonJS" runtime symbols
 	cjsRuntimeRef ast.Ref
 	esmRuntimeRef ast.Ref
+
+	// "@custom-media" definitions are global, so they are gathered from all
+	// CSS files before any chunks are generated
+
+	// Local CSS names that are never used from JavaScript. Rules that only
+	// match these names are removed from the output.
+	unusedLocalCSSNames map[ast.Ref]bool
+
+	// The chunk loader for code splitting with the IIFE format. It's compiled
+	// once before any chunks are generated since every entry point includes it.
+	chunkLoaderJS string
 }
 
 type partRange struct {
@@ -127,6 +140,10 @@ type chunkInfo struct {
 	isEntryPoint  bool
 
 	isExecutable bool
+
+	// If non-empty, this chunk was created by the "ManualChunks" option or by
+	// a plugin and this name is substituted for "[name]" in the path template.
+	manualChunkName string
 }
 
 type chunkImport struct {
@@ -171,8 +188,15 @@ type intermediateOutput struct {
 
 type chunkRepr interface{ isChunk() }
 
-func (*chunkReprJS) isChunk()  {}
-func (*chunkReprCSS) isChunk() {}
+func (*chunkReprJS) isChunk()   {}
+func (*chunkReprCSS) isChunk()  {}
+func (*chunkReprHTML) isChunk() {}
+
+// An external module that's passed to the UMD factory function as an argument
+type umdDependency struct {
+	path string
+	ref  ast.Ref
+}
 
 type chunkReprJS struct {
 	filesInChunkInOrder []uint32
@@ -184,14 +208,48 @@ type chunkReprJS struct {
 	crossChunkPrefixStmts  []js_ast.Stmt
 	crossChunkSuffixStmts  []js_ast.Stmt
 
+	// For code splitting with output formats that don't have import statements.
+	// Each imported chunk is bound to a namespace object, and symbols from that
+	// chunk are accessed as properties on it to preserve live bindings.
+	crossChunkNamespaces       []crossChunkNamespace
+	crossChunkNamespaceAliases map[ast.Ref]ast.NamespaceAlias
+	chunkLoaderRef             ast.Ref
+
+	// For the SystemJS format, which needs every assignment to an exported
+	// symbol to also pass the new value to "exports()"
+	systemLiveExports map[ast.Ref][]string
+
+	// Exported functions that were hoisted out of "execute" and exported there
+	systemHoistedExports map[ast.Ref]bool
+
+	// For the UMD format, which passes external modules to the factory function
+	umdDependencies   []umdDependency
+	umdDependencyRefs map[string]ast.Ref
+
 	cssChunkIndex uint32
 	hasCSSChunk   bool
+
+	// For hot module replacement
+	hmrModules []hmrModule
+}
+
+type crossChunkNamespace struct {
+	// This is invalid if nothing is imported from the chunk
+	ref               ast.Ref
+	importRecordIndex uint32
+}
+
+type hmrModule struct {
+	id   string
+	code []byte
 }
 
 type chunkReprCSS struct {
 	importsInChunkInOrder []cssImportOrder
 }
 
+type chunkReprHTML struct{}
+
 type externalImportCSS struct {
 	path                   logger.Path
 	conditions             []css_ast.ImportConditions
@@ -270,8 +328,15 @@ func Link(
 		c.esmRuntimeRef = runtimeRepr.AST.NamedExports["__esmMin"].Ref
 	}
 
+	// Hot module replacement uses a different ESM wrapper that can be re-run
+	if c.options.HotModuleReplacement {
+		c.esmRuntimeRef = runtimeRepr.AST.NamedExports["__esmHot"].Ref
+	}
+
+	// Note: This includes the entry points that code splitting generates for
+	// "import()" expressions, since their exports can be observed too
 	var additionalFiles []graph.OutputFile
-	for _, entryPoint := range entryPoints {
+	for _, entryPoint := range c.graph.EntryPoints() {
 		file := &c.graph.Files[entryPoint.SourceIndex].InputFile
 		switch repr := file.Repr.(type) {
 		case *graph.JSRepr:
@@ -285,10 +350,11 @@ func Link(
 
 			// Entry points with ES6 exports must generate an exports object when
 			// targeting non-ES6 formats. Note that the IIFE format only needs this
-			// when the global name is present, since that's the only way the exports
-			// can actually be observed externally.
-			if repr.AST.ExportKeyword.Len > 0 && (options.OutputFormat == config.FormatCommonJS ||
-				(options.OutputFormat == config.FormatIIFE && len(options.GlobalName) > 0)) {
+			// when the global name is present or when code splitting is enabled,
+			// since those are the only ways the exports can actually be observed
+			// externally (the latter via "import()" of an entry point chunk).
+			if repr.AST.ExportKeyword.Len > 0 && (options.OutputFormat == config.FormatCommonJS || options.OutputFormat == config.FormatUMD ||
+				(options.OutputFormat == config.FormatIIFE && (len(options.GlobalName) > 0 || options.CodeSplitting))) {
 				repr.AST.UsesExportsRef = true
 				repr.Meta.ForceIncludeExportsForEntryPoint = true
 			}
@@ -321,6 +387,10 @@ func Link(
 
 	c.treeShakingAndCodeSplitting()
 
+	if c.options.TreeShaking && c.options.Mode == config.ModeBundle && !c.options.HotModuleReplacement {
+		c.findUnusedLocalCSSNames()
+	}
+
 	if c.options.Mode == config.ModePassThrough {
 		for _, entryPoint := range c.graph.EntryPoints() {
 			c.preventExportsFromBeingRenamed(entryPoint.SourceIndex)
@@ -329,6 +399,9 @@ func Link(
 
 	c.computeChunks()
 	c.computeCrossChunkDependencies()
+	if c.options.OutputFormat == config.FormatUMD {
+		c.computeUMDDependencies()
+	}
 
 	// Merge mangled properties before chunks are generated since the names must
 	// be consistent across all chunks, or the generated code will break
@@ -346,6 +419,10 @@ func Link(
 	// won't hit concurrent map mutation hazards
 	ast.FollowAllSymbols(c.graph.Symbols)
 
+	if c.options.CSSModuleTypes != config.CSSModuleTypesNone {
+		additionalFiles = append(additionalFiles, c.generateCSSModuleTypes()...)
+	}
+
 	return c.generateChunksInParallel(additionalFiles)
 }
 
@@ -488,12 +565,62 @@ func (c *linkerContext) mangleLocalCSS(usedLocalNames map[string]bool) {
 	}
 	sort.Sort(sorted)
 
-	// Rename all local names to avoid collisions
+	nameCounts := make(map[string]uint32)
+	avoidCollisions := func(name string) string {
+		// If the name is already in use, generate a new name by appending a number
+		if globalNames[name] || usedLocalNames[name] {
+			// To avoid O(n^2) behavior, the number must start off being the number
+			// that we used last time there was a collision with this name. Otherwise
+			// if there are many collisions with the same name, each name collision
+			// would have to increment the counter past all previous name collisions
+			// which is a O(n^2) time algorithm.
+			tries, ok := nameCounts[name]
+			if !ok {
+				tries = 1
+			}
+			prefix := name
+
+			// Keep incrementing the number until the name is unused
+			for {
+				tries++
+				name = prefix + strconv.Itoa(int(tries))
+
+				// Make sure this new name is unused
+				if !globalNames[name] && !usedLocalNames[name] {
+					// Store the count so we can start here next time instead of starting
+					// from 1. This means we avoid O(n^2) behavior.
+					nameCounts[prefix] = tries
+					break
+				}
+			}
+		}
+		return name
+	}
+
+	// Names from plugins and from the "LocalCSSNames" template take precedence
+	// over generated names, so assign them first
+	customNames := c.customLocalCSSNames(sorted)
+	if customNames != nil {
+		for i, symbolCount := range sorted {
+			if name := customNames[i]; name != "" {
+				name = avoidCollisions(name)
+
+				// Turn this local name into a global one
+				mangledProps[symbolCount.Ref] = name
+				usedLocalNames[name] = true
+			}
+		}
+	}
+
+	// Rename all other local names to avoid collisions
 	if c.options.MinifyIdentifiers {
 		minifier := ast.DefaultNameMinifierCSS.ShuffleByCharFreq(freq)
 		nextName := 0
 
-		for _, symbolCount := range sorted {
+		for i, symbolCount := range sorted {
+			if customNames != nil && customNames[i] != "" {
+				continue
+			}
 			name := minifier.NumberToMinifiedName(nextName)
 			for globalNames[name] || usedLocalNames[name] {
 				nextName++
@@ -505,66 +632,207 @@ func (c *linkerContext) mangleLocalCSS(usedLocalNames map[string]bool) {
 			usedLocalNames[name] = true
 		}
 	} else {
-		nameCounts := make(map[string]uint32)
-
-		for _, symbolCount := range sorted {
+		for i, symbolCount := range sorted {
+			if customNames != nil && customNames[i] != "" {
+				continue
+			}
 			symbol := c.graph.Symbols.Get(symbolCount.Ref)
 			name := fmt.Sprintf("%s_%s", c.graph.Files[symbolCount.Ref.SourceIndex].InputFile.Source.IdentifierName, symbol.OriginalName)
+			name = avoidCollisions(name)
 
-			// If the name is already in use, generate a new name by appending a number
-			if globalNames[name] || usedLocalNames[name] {
-				// To avoid O(n^2) behavior, the number must start off being the number
-				// that we used last time there was a collision with this name. Otherwise
-				// if there are many collisions with the same name, each name collision
-				// would have to increment the counter past all previous name collisions
-				// which is a O(n^2) time algorithm.
-				tries, ok := nameCounts[name]
-				if !ok {
-					tries = 1
-				}
-				prefix := name
+			// Turn this local name into a global one
+			mangledProps[symbolCount.Ref] = name
+			usedLocalNames[name] = true
+		}
+	}
+}
 
-				// Keep incrementing the number until the name is unused
-				for {
-					tries++
-					name = prefix + strconv.Itoa(int(tries))
+// Local CSS names can be customized by plugins and by the "LocalCSSNames"
+// template. This returns nil if neither is present. Otherwise it returns the
+// custom name for each symbol, or an empty string to use the default name.
+func (c *linkerContext) customLocalCSSNames(sorted renamer.StableSymbolCountArray) []string {
+	hasPlugins := false
+	for _, plugin := range c.options.Plugins {
+		if len(plugin.OnLocalCSSName) > 0 {
+			hasPlugins = true
+			break
+		}
+	}
+	if !hasPlugins && len(c.options.LocalCSSNameTemplate) == 0 {
+		return nil
+	}
 
-					// Make sure this new name is unused
-					if !globalNames[name] && !usedLocalNames[name] {
-						// Store the count so we can start here next time instead of starting
-						// from 1. This means we avoid O(n^2) behavior.
-						nameCounts[prefix] = tries
-						break
-					}
+	// Plugin callbacks may be slow, so run them in parallel
+	names := make([]string, len(sorted))
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(sorted))
+	for i, symbolCount := range sorted {
+		go func(i int, ref ast.Ref) {
+			local := c.graph.Symbols.Get(ref).OriginalName
+			if hasPlugins {
+				names[i] = c.pluginLocalCSSName(ref.SourceIndex, local)
+			}
+			if names[i] == "" && len(c.options.LocalCSSNameTemplate) > 0 {
+				names[i] = c.templateLocalCSSName(ref.SourceIndex, local)
+			}
+			waitGroup.Done()
+		}(i, symbolCount.Ref)
+	}
+	waitGroup.Wait()
+	return names
+}
+
+func (c *linkerContext) pluginLocalCSSName(sourceIndex uint32, local string) string {
+	path := c.graph.Files[sourceIndex].InputFile.Source.KeyPath
+
+	for _, plugin := range c.options.Plugins {
+		for _, onLocalCSSName := range plugin.OnLocalCSSName {
+			if !config.PluginAppliesToPath(path, onLocalCSSName.Filter, onLocalCSSName.Namespace) {
+				continue
+			}
+			result := onLocalCSSName.Callback(config.OnLocalCSSNameArgs{Path: path, Local: local})
+			pluginName := result.PluginName
+			if pluginName == "" {
+				pluginName = plugin.Name
+			}
+			didLogError := false
+			for _, msg := range result.Msgs {
+				if msg.PluginName == "" {
+					msg.PluginName = pluginName
+				}
+				if msg.Kind == logger.Error {
+					didLogError = true
+				}
+				c.log.AddMsg(msg)
+			}
+			if didLogError {
+				return ""
+			}
+			if result.ThrownError != nil {
+				c.log.AddMsg(logger.Msg{
+					PluginName: pluginName,
+					Kind:       logger.Error,
+					Data: logger.MsgData{
+						Text:       result.ThrownError.Error(),
+						UserDetail: result.ThrownError,
+					},
+				})
+				return ""
+			}
+			if result.Name != "" {
+				if !css_lexer.WouldStartIdentifierWithoutEscapes(result.Name) || sanitizeLocalCSSName(result.Name) != result.Name {
+					c.log.AddMsg(logger.Msg{
+						PluginName: pluginName,
+						Kind:       logger.Error,
+						Data:       logger.MsgData{Text: fmt.Sprintf("Invalid local CSS name: %q", result.Name)},
+					})
+					return ""
 				}
+				return result.Name
 			}
+		}
+	}
 
-			// Turn this local name into a global one
-			mangledProps[symbolCount.Ref] = name
-			usedLocalNames[name] = true
+	return ""
+}
+
+// The "[dir]" and "[hash]" placeholders are derived from the path relative to
+// the current working directory instead of the output base directory. That
+// way names don't change when a build has a different set of entry points,
+// which is important when the same CSS is bundled by several separate builds
+// (e.g. one for the server and one for the browser).
+func (c *linkerContext) templateLocalCSSName(sourceIndex uint32, local string) string {
+	keyPath := c.graph.Files[sourceIndex].InputFile.Source.KeyPath
+	relPath := keyPath.Text
+	if keyPath.Namespace == "file" {
+		if rel, ok := c.fs.Rel(c.fs.Cwd(), keyPath.Text); ok {
+			relPath = strings.ReplaceAll(rel, "\\", "/")
+		}
+	}
+	dir, base, _ := logger.PlatformIndependentPathDirBaseExt(relPath)
+	if keyPath.Namespace != "file" {
+		dir = ""
+	}
+
+	var hash string
+	template := c.options.LocalCSSNameTemplate
+	if config.HasPlaceholder(template, config.HashPlaceholder) {
+		h := xxhash.New()
+		h.Write([]byte(keyPath.Namespace))
+		h.Write([]byte{0})
+		h.Write([]byte(relPath))
+		h.Write([]byte{0})
+		h.Write([]byte(local))
+		hash = bundler.HashForFileName(h.Sum(nil))
+	}
+
+	name := config.TemplateToString(config.SubstituteTemplate(template, config.PathPlaceholders{
+		Dir:   &dir,
+		Name:  &base,
+		Hash:  &hash,
+		Local: &local,
+	}))
+	name = sanitizeLocalCSSName(name)
+
+	// Make sure the name is a valid identifier (e.g. the hash may start with a digit)
+	if !css_lexer.WouldStartIdentifierWithoutEscapes(name) {
+		name = "_" + name
+	}
+	return name
+}
+
+// Characters that aren't valid in a CSS identifier are replaced with "_"
+func sanitizeLocalCSSName(name string) string {
+	for i, c := range name {
+		if !css_lexer.IsNameContinue(c) || c == 0 {
+			sb := strings.Builder{}
+			sb.WriteString(name[:i])
+			for _, c := range name[i:] {
+				if css_lexer.IsNameContinue(c) && c != 0 {
+					sb.WriteRune(c)
+				} else {
+					sb.WriteByte('_')
+				}
+			}
+			return sb.String()
 		}
 	}
+	return name
 }
 
 // Currently the automatic chunk generation algorithm should by construction
 // never generate chunks that import each other since files are allocated to
 // chunks based on which entry points they are reachable from.
 //
-// This will change in the future when we allow manual chunk labels. But before
-// we allow manual chunk labels, we'll need to rework module initialization to
-// allow code splitting chunks to be lazily-initialized.
-//
-// Since that work hasn't been finished yet, cycles in the chunk import graph
-// can cause initialization bugs. So let's forbid these cycles for now to guard
-// against code splitting bugs that could cause us to generate buggy chunks.
+// Manual chunks don't have this property since they group files together
+// regardless of reachability. Files they import are pulled into them to avoid
+// most cycles, but some can't be avoided (e.g. when two manual chunks import
+// each other). Code splitting chunks aren't lazily-initialized yet, so cycles
+// in the chunk import graph can cause initialization bugs. So let's forbid
+// these cycles for now to guard against generating buggy chunks.
 func (c *linkerContext) enforceNoCyclicChunkImports() {
 	var validate func(int, map[int]int) bool
+	var stack []int
 
 	// DFS memoization with 3-colors, more space efficient
 	// 0: white (unvisited), 1: gray (visiting), 2: black (visited)
 	colors := make(map[int]int)
 	validate = func(chunkIndex int, colors map[int]int) bool {
 		if colors[chunkIndex] == 1 {
+			// Blame the cycle on a manual chunk if there is one, since that's the
+			// only way for this to happen that isn't a bug in esbuild
+			for i := len(stack) - 1; i >= 0; i-- {
+				if name := c.chunks[stack[i]].manualChunkName; name != "" {
+					c.log.AddErrorWithNotes(nil, logger.Range{},
+						fmt.Sprintf("Cannot create the manual chunk %q because it results in a circular import between chunks", name),
+						[]logger.MsgData{{Text: "This happens when code in a manual chunk imports code that's in another chunk which " +
+							"imports the manual chunk. You may be able to fix this by moving the shared code into a manual chunk too."}})
+					return true
+				}
+				if stack[i] == chunkIndex {
+					break
+				}
+			}
 			c.log.AddError(nil, logger.Range{}, "Internal error: generated chunks contain a circular import")
 			return true
 		}
@@ -574,6 +842,7 @@ func (c *linkerContext) enforceNoCyclicChunkImports() {
 		}
 
 		colors[chunkIndex] = 1
+		stack = append(stack, chunkIndex)
 
 		for _, chunkImport := range c.chunks[chunkIndex].crossChunkImports {
 			// Ignore cycles caused by dynamic "import()" expressions. These are fine
@@ -590,6 +859,7 @@ func (c *linkerContext) enforceNoCyclicChunkImports() {
 		}
 
 		colors[chunkIndex] = 2
+		stack = stack[:len(stack)-1]
 		return false
 	}
 
@@ -610,6 +880,9 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 	// in general uncomputable at this point because paths have hashes that
 	// include information about chunk dependencies, and chunk dependencies
 	// can be cyclic due to dynamic imports).
+	if c.options.CodeSplitting && c.options.OutputFormat == config.FormatIIFE && !c.options.OmitRuntimeForTests {
+		c.chunkLoaderJS = c.compileChunkLoader()
+	}
 	generateWaitGroup := sync.WaitGroup{}
 	generateWaitGroup.Add(len(c.chunks))
 	for chunkIndex := range c.chunks {
@@ -618,6 +891,8 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 			go c.generateChunkJS(chunkIndex, &generateWaitGroup)
 		case *chunkReprCSS:
 			go c.generateChunkCSS(chunkIndex, &generateWaitGroup)
+		case *chunkReprHTML:
+			go c.generateChunkHTML(chunkIndex, &generateWaitGroup)
 		}
 	}
 	c.enforceNoCyclicChunkImports()
@@ -687,6 +962,12 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 					return c.pathBetweenChunks(finalRelDir, finalRelPathForImport)
 				})
 
+			// Split off the code for each module for hot module replacement
+			var hmr *graph.HMRChunk
+			if chunkRepr, ok := chunk.chunkRepr.(*chunkReprJS); ok && c.options.HotModuleReplacement && chunk.isEntryPoint {
+				hmr = c.generateHMRChunk(chunkRepr, chunk.sourceIndex, outputContentsJoiner.Done(), finalRelDir)
+			}
+
 			// Generate the optional legal comments file for this chunk
 			if len(chunk.externalLegalComments) > 0 {
 				finalRelPathForLegalComments := chunk.finalRelPath + ".LEGAL.txt"
@@ -769,6 +1050,7 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 				Contents:          outputContents,
 				JSONMetadataChunk: jsonMetadataChunk,
 				IsExecutable:      chunk.isExecutable,
+				HMR:               hmr,
 			})
 
 			results[chunkIndex] = outputFiles
@@ -791,6 +1073,40 @@ func (c *linkerContext) generateChunksInParallel(additionalFiles []graph.OutputF
 	return outputFiles
 }
 
+// The code for each module appears in the chunk in order, so this does a
+// single forward scan over the chunk to find each one. Everything else in the
+// chunk is hashed together. Modules can't be swapped out individually if that
+// hash changes (e.g. if a module was added or a top-level symbol was renamed).
+// Code that can't be found is left in the hash, which just means that any
+// change to that module will cause a full reload instead.
+func (c *linkerContext) generateHMRChunk(
+	chunkRepr *chunkReprJS,
+	entrySourceIndex uint32,
+	contents []byte,
+	finalRelDir string,
+) *graph.HMRChunk {
+	hmr := &graph.HMRChunk{
+		EntryID: c.graph.Files[entrySourceIndex].InputFile.Source.PrettyPaths.Select(c.options.CodePathStyle),
+		Modules: make([]graph.HMRModule, 0, len(chunkRepr.hmrModules)),
+	}
+	hash := xxhash.New()
+	for _, module := range chunkRepr.hmrModules {
+		codeJoiner, _ := c.substituteFinalPaths(c.breakOutputIntoPieces(module.code),
+			func(finalRelPathForImport string) string {
+				return c.pathBetweenChunks(finalRelDir, finalRelPathForImport)
+			})
+		code := codeJoiner.Done()
+		if i := bytes.Index(contents, code); i >= 0 {
+			hash.Write(contents[:i])
+			contents = contents[i+len(code):]
+		}
+		hmr.Modules = append(hmr.Modules, graph.HMRModule{ID: module.id, Code: code})
+	}
+	hash.Write(contents)
+	hmr.ShellHash = hash.Sum64()
+	return hmr
+}
+
 // Given a set of output pieces (i.e. a buffer already divided into the spans
 // between import paths), substitute the final import paths in and then join
 // everything into a single byte buffer.
@@ -949,7 +1265,7 @@ func (c *linkerContext) computeCrossChunkDependencies() {
 								otherChunkIndex := c.graph.Files[record.SourceIndex.GetIndex()].EntryPointChunkIndex
 								record.Path.Text = c.chunks[otherChunkIndex].uniqueKey
 								record.SourceIndex = ast.Index32{}
-								record.Flags |= ast.ShouldNotBeExternalInMetafile | ast.ContainsUniqueKey
+								record.Flags |= ast.ShouldNotBeExternalInMetafile | ast.ContainsUniqueKey | ast.IsChunkImport
 
 								// Track this cross-chunk dynamic import so we make sure to
 								// include its hash when we're calculating the hashes of all
@@ -1126,7 +1442,7 @@ func (c *linkerContext) computeCrossChunkDependencies() {
 
 		chunkRepr.exportsToOtherChunks = make(map[ast.Ref]string)
 		switch c.options.OutputFormat {
-		case config.FormatESModule:
+		case config.FormatESModule, config.FormatSystem:
 			r := renamer.ExportRenamer{}
 			var items []js_ast.ClauseItem
 			for _, export := range c.sortedCrossChunkExportItems(chunkMetas[chunkIndex].exports) {
@@ -1145,6 +1461,46 @@ func (c *linkerContext) computeCrossChunkDependencies() {
 				}}}
 			}
 
+		case config.FormatCommonJS, config.FormatIIFE:
+			// Export getters instead of values so that other chunks observe any
+			// later changes to these symbols. Object literal getters are used
+			// instead of the "__export" helper from the runtime because tree
+			// shaking has already happened, so the runtime can't be changed.
+			r := renamer.ExportRenamer{}
+			var properties []js_ast.Property
+			for _, export := range c.sortedCrossChunkExportItems(chunkMetas[chunkIndex].exports) {
+				var alias string
+				if c.options.MinifyIdentifiers {
+					alias = r.NextMinifiedName()
+				} else {
+					alias = r.NextRenamedName(c.graph.Symbols.Get(export.Ref).OriginalName)
+				}
+				properties = append(properties, js_ast.Property{
+					Kind: js_ast.PropertyGetter,
+					Key:  js_ast.Expr{Data: &js_ast.EString{Value: helpers.StringToUTF16(alias)}},
+					ValueOrNil: js_ast.Expr{Data: &js_ast.EFunction{Fn: js_ast.Fn{Body: js_ast.FnBody{Block: js_ast.SBlock{Stmts: []js_ast.Stmt{
+						{Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: export.Ref}}}},
+					}}}}}},
+				})
+				chunkRepr.exportsToOtherChunks[export.Ref] = alias
+			}
+			if len(properties) > 0 {
+				value := js_ast.Expr{Data: &js_ast.EObject{Properties: properties, IsSingleLine: c.options.MinifyWhitespace}}
+				if c.options.OutputFormat == config.FormatCommonJS {
+					// "module.exports = { get a() { return a } };"
+					chunkRepr.crossChunkSuffixStmts = []js_ast.Stmt{js_ast.AssignStmt(
+						js_ast.Expr{Data: &js_ast.EDot{
+							Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.unboundModuleRef}},
+							Name:   "exports",
+						}},
+						value,
+					)}
+				} else {
+					// "return { get a() { return a } };"
+					chunkRepr.crossChunkSuffixStmts = []js_ast.Stmt{{Data: &js_ast.SReturn{ValueOrNil: value}}}
+				}
+			}
+
 		default:
 			panic("Internal error")
 		}
@@ -1162,9 +1518,15 @@ func (c *linkerContext) computeCrossChunkDependencies() {
 
 		var crossChunkPrefixStmts []js_ast.Stmt
 
+		// The IIFE format loads chunks asynchronously, so it needs a function to
+		// load other chunks for dynamic "import()" expressions
+		if c.options.OutputFormat == config.FormatIIFE {
+			chunkRepr.chunkLoaderRef = c.graph.GenerateNewSymbol(runtime.SourceIndex, ast.SymbolOther, "__import")
+		}
+
 		for _, crossChunkImport := range c.sortedCrossChunkImports(chunkRepr.importsFromOtherChunks) {
 			switch c.options.OutputFormat {
-			case config.FormatESModule:
+			case config.FormatESModule, config.FormatSystem:
 				var items []js_ast.ClauseItem
 				for _, item := range crossChunkImport.sortedImportItems {
 					items = append(items, js_ast.ClauseItem{Name: ast.LocRef{Ref: item.ref}, Alias: item.exportAlias})
@@ -1187,6 +1549,55 @@ func (c *linkerContext) computeCrossChunkDependencies() {
 					}})
 				}
 
+			case config.FormatCommonJS, config.FormatIIFE:
+				importKind := ast.ImportRequire
+				if c.options.OutputFormat == config.FormatIIFE {
+					importKind = ast.ImportStmt
+				}
+				importRecordIndex := uint32(len(chunk.crossChunkImports))
+				chunk.crossChunkImports = append(chunk.crossChunkImports, chunkImport{
+					importKind: importKind,
+					chunkIndex: crossChunkImport.chunkIndex,
+				})
+				namespaceRef := ast.InvalidRef
+				if len(crossChunkImport.sortedImportItems) > 0 {
+					otherChunk := &c.chunks[crossChunkImport.chunkIndex]
+					name := "chunk"
+					if otherChunk.manualChunkName != "" {
+						name = otherChunk.manualChunkName
+					}
+					namespaceRef = c.graph.GenerateNewSymbol(runtime.SourceIndex, ast.SymbolOther, js_ast.EnsureValidIdentifier("import_"+name))
+					if chunkRepr.crossChunkNamespaceAliases == nil {
+						chunkRepr.crossChunkNamespaceAliases = make(map[ast.Ref]ast.NamespaceAlias)
+					}
+					for _, item := range crossChunkImport.sortedImportItems {
+						chunkRepr.crossChunkNamespaceAliases[ast.FollowSymbols(c.graph.Symbols, item.ref)] = ast.NamespaceAlias{NamespaceRef: namespaceRef, Alias: item.exportAlias}
+					}
+				}
+				chunkRepr.crossChunkNamespaces = append(chunkRepr.crossChunkNamespaces, crossChunkNamespace{
+					ref:               namespaceRef,
+					importRecordIndex: importRecordIndex,
+				})
+
+				// The IIFE format passes the namespace objects as arguments instead
+				if c.options.OutputFormat != config.FormatCommonJS {
+					break
+				}
+				require := js_ast.Expr{Data: &js_ast.ERequireString{ImportRecordIndex: importRecordIndex}}
+				if namespaceRef != ast.InvalidRef {
+					// "var import_chunk = require('./chunk.js');"
+					crossChunkPrefixStmts = append(crossChunkPrefixStmts, js_ast.Stmt{Data: &js_ast.SLocal{
+						Kind: js_ast.LocalVar,
+						Decls: []js_ast.Decl{{
+							Binding:    js_ast.Binding{Data: &js_ast.BIdentifier{Ref: namespaceRef}},
+							ValueOrNil: require,
+						}},
+					}})
+				} else {
+					// "require('./chunk.js');"
+					crossChunkPrefixStmts = append(crossChunkPrefixStmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: require}})
+				}
+
 			default:
 				panic("Internal error")
 			}
@@ -1369,6 +1780,23 @@ func (c *linkerContext) scanImportsAndExports() {
 
 			c.validateComposesFromProperties(file, repr)
 
+			// Validate cross-file "@value ... from" imports
+			for _, value := range repr.AST.Values {
+				imported, ok := repr.AST.ImportedValues[value.Name]
+				if !ok {
+					continue
+				}
+				if record := repr.AST.ImportRecords[imported.ImportRecordIndex]; record.SourceIndex.IsValid() {
+					otherFile := &c.graph.Files[record.SourceIndex.GetIndex()]
+					if otherRepr, ok := otherFile.InputFile.Repr.(*graph.CSSRepr); ok && !hasICSSValue(otherRepr.AST, imported.Alias) {
+						c.log.AddError(file.LineColumnTracker(),
+							css_lexer.RangeOfIdentifier(file.InputFile.Source, imported.AliasLoc),
+							fmt.Sprintf("No value named %q is defined in %q", imported.Alias,
+								otherFile.InputFile.Source.PrettyPaths.Select(c.options.LogPathStyle)))
+					}
+				}
+			}
+
 		case *graph.JSRepr:
 			for importRecordIndex := range repr.AST.ImportRecords {
 				record := &repr.AST.ImportRecords[importRecordIndex]
@@ -1419,6 +1847,17 @@ func (c *linkerContext) scanImportsAndExports() {
 						otherRepr.AST.ExportsKind = js_ast.ExportsCommonJS
 					}
 
+					// Files that are imported with "import defer" must be wrapped so
+					// that their evaluation can be delayed until they are first used
+					if record.Phase == ast.DeferPhase {
+						if otherRepr.AST.ExportsKind == js_ast.ExportsESM {
+							otherRepr.Meta.Wrap = graph.WrapESM
+						} else {
+							otherRepr.Meta.Wrap = graph.WrapCJS
+							otherRepr.AST.ExportsKind = js_ast.ExportsCommonJS
+						}
+					}
+
 				case ast.ImportRequire:
 					// Files that are imported with require() must be wrapped so that
 					// they can be lazily-evaluated
@@ -1447,8 +1886,9 @@ func (c *linkerContext) scanImportsAndExports() {
 			// that uses CommonJS features will need to be wrapped, even though the
 			// resulting wrapper won't be invoked by other files. An exception is made
 			// for entry point files in CommonJS format (or when in pass-through mode).
-			if repr.AST.ExportsKind == js_ast.ExportsCommonJS && (!file.IsEntryPoint() ||
-				c.options.OutputFormat == config.FormatIIFE || c.options.OutputFormat == config.FormatESModule) {
+			if repr.AST.ExportsKind == js_ast.ExportsCommonJS && (!file.IsEntryPoint() || c.options.OutputFormat == config.FormatIIFE ||
+				c.options.OutputFormat == config.FormatESModule || c.options.OutputFormat == config.FormatSystem ||
+				c.options.OutputFormat == config.FormatUMD) {
 				repr.Meta.Wrap = graph.WrapCJS
 			}
 		}
@@ -1463,12 +1903,18 @@ func (c *linkerContext) scanImportsAndExports() {
 	// bundle time.
 	c.timer.Begin("Step 2")
 	for _, sourceIndex := range c.graph.ReachableFiles {
-		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		file := &c.graph.Files[sourceIndex]
+		repr, ok := file.InputFile.Repr.(*graph.JSRepr)
 		if !ok {
 			continue
 		}
 
-		if repr.Meta.Wrap != graph.WrapNone {
+		// Hot module replacement needs every module to be in a closure that can
+		// be re-run, so wrap everything reachable from each entry point. Lowered
+		// top-level await also needs closures so that the module and everything
+		// it imports can be initialized in order from an async function.
+		if repr.Meta.Wrap != graph.WrapNone || (c.options.HotModuleReplacement && file.IsEntryPoint()) ||
+			(repr.Meta.IsAsyncOrHasAsyncDependency && c.isLoweringTopLevelAwait()) {
 			c.recursivelyWrapDependencies(sourceIndex)
 		}
 
@@ -1766,7 +2212,7 @@ func (c *linkerContext) scanImportsAndExports() {
 		// Pre-generate symbols for re-exports CommonJS symbols in case they
 		// are necessary later. This is done now because the symbols map cannot be
 		// mutated later due to parallelism.
-		if file.IsEntryPoint() && c.options.OutputFormat == config.FormatESModule {
+		if file.IsEntryPoint() && (c.options.OutputFormat == config.FormatESModule || c.options.OutputFormat == config.FormatSystem) {
 			copies := make([]ast.Ref, len(repr.Meta.SortedAndFilteredExportAliases))
 			for i, alias := range repr.Meta.SortedAndFilteredExportAliases {
 				copies[i] = c.graph.GenerateNewSymbol(sourceIndex, ast.SymbolOther, "export_"+alias)
@@ -1877,6 +2323,17 @@ func (c *linkerContext) scanImportsAndExports() {
 			if repr.Meta.ForceIncludeExportsForEntryPoint {
 				c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, entryPointPartIndex, "__toCommonJS", 1)
 			}
+
+			// Entry points with lowered top-level await expose their evaluation
+			if c.isExposingTopLevelAwaitPromise(repr) {
+				c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, entryPointPartIndex, "__tla", 1)
+			}
+
+			// Entry points start listening for updates when hot module replacement
+			// is enabled
+			if c.options.HotModuleReplacement {
+				c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, entryPointPartIndex, "__hmrConnect", 1)
+			}
 		}
 
 		// Encode import-specific constraints in the dependency graph
@@ -1884,6 +2341,7 @@ func (c *linkerContext) scanImportsAndExports() {
 			toESMUses := uint32(0)
 			toCommonJSUses := uint32(0)
 			runtimeRequireUses := uint32(0)
+			deferNSUses := uint32(0)
 
 			// Imports of wrapped files must depend on the wrapper
 			for _, importRecordIndex := range part.ImportRecordIndices {
@@ -1895,8 +2353,12 @@ func (c *linkerContext) scanImportsAndExports() {
 					if record.Kind == ast.ImportRequire || !c.options.OutputFormat.KeepESMImportExportSyntax() ||
 						(record.Kind == ast.ImportDynamic && c.options.UnsupportedJSFeatures.Has(compat.DynamicImport)) {
 						// We should use "__require" instead of "require" if we're not
-						// generating a CommonJS output file, since it won't exist otherwise
-						if config.ShouldCallRuntimeRequire(c.options.Mode, c.options.OutputFormat) {
+						// generating a CommonJS output file, since it won't exist otherwise.
+						// Cross-chunk "import()" in the IIFE format uses the chunk loader.
+						// External modules in the UMD format are passed as arguments.
+						if config.ShouldCallRuntimeRequire(c.options.Mode, c.options.OutputFormat) &&
+							!(record.SourceIndex.IsValid() && c.options.OutputFormat == config.FormatIIFE) &&
+							!(record.Kind != ast.ImportDynamic && c.options.OutputFormat == config.FormatUMD) {
 							record.Flags |= ast.CallRuntimeRequire
 							runtimeRequireUses++
 						}
@@ -1940,12 +2402,17 @@ func (c *linkerContext) scanImportsAndExports() {
 						toESMUses++
 					}
 
+					// Deferred imports are evaluated lazily by the "__deferNS" wrapper
+					if record.Phase == ast.DeferPhase && !otherRepr.Meta.IsAsyncOrHasAsyncDependency {
+						deferNSUses++
+					}
+
 					// If this is an ESM wrapper, also depend on the exports object
 					// since the final code will contain an inline reference to it.
 					// This must be done for "require()" and "import()" expressions
 					// but does not need to be done for "import" statements since
 					// those just cause us to reference the exports directly.
-					if otherRepr.Meta.Wrap == graph.WrapESM && record.Kind != ast.ImportStmt {
+					if otherRepr.Meta.Wrap == graph.WrapESM && (record.Kind != ast.ImportStmt || record.Phase == ast.DeferPhase) {
 						c.graph.GenerateSymbolImportAndUse(sourceIndex, uint32(partIndex), otherRepr.AST.ExportsRef, 1, otherSourceIndex)
 
 						// If this is a "require()" call, then we should add the
@@ -1980,6 +2447,10 @@ func (c *linkerContext) scanImportsAndExports() {
 			// "__toCommonJS" symbol from the runtime to wrap the exports object
 			c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, uint32(partIndex), "__toCommonJS", toCommonJSUses)
 
+			// If there's a deferred import of a wrapped module, then we're going to
+			// need the "__deferNS" symbol from the runtime to delay evaluating it
+			c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, uint32(partIndex), "__deferNS", deferNSUses)
+
 			// If there are unbundled calls to "require()" and we're not generating
 			// code for node, then substitute a "__require" wrapper for "require".
 			c.graph.GenerateRuntimeSymbolImportAndUse(sourceIndex, uint32(partIndex), "__require", runtimeRequireUses)
@@ -2100,6 +2571,100 @@ func (c *linkerContext) validateComposesFromProperties(rootFile *graph.LinkerFil
 	}
 }
 
+func hasICSSValue(tree css_ast.AST, name string) bool {
+	for _, value := range tree.Values {
+		if value.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type icssValueKey struct {
+	name        string
+	sourceIndex uint32
+}
+
+// This resolves "@value" names across files. Any URL tokens in values from
+// other files are given new import records in the file being generated.
+type icssValueResolver struct {
+	c                 *linkerContext
+	visiting          map[icssValueKey]bool
+	importRecords     []ast.ImportRecord
+	targetSourceIndex uint32
+}
+
+func (c *linkerContext) newICSSValueResolver(sourceIndex uint32, importRecords []ast.ImportRecord) *icssValueResolver {
+	return &icssValueResolver{
+		c:                 c,
+		visiting:          make(map[icssValueKey]bool),
+		importRecords:     append([]ast.ImportRecord{}, importRecords...),
+		targetSourceIndex: sourceIndex,
+	}
+}
+
+func (r *icssValueResolver) resolve(sourceIndex uint32, name string) ([]css_ast.Token, bool) {
+	repr, ok := r.c.graph.Files[sourceIndex].InputFile.Repr.(*graph.CSSRepr)
+	if !ok {
+		return nil, false
+	}
+
+	// Avoid infinite recursion for import cycles
+	key := icssValueKey{name: name, sourceIndex: sourceIndex}
+	if r.visiting[key] {
+		return nil, false
+	}
+	r.visiting[key] = true
+	defer delete(r.visiting, key)
+
+	// Follow imports to the file that defines the value
+	if imported, ok := repr.AST.ImportedValues[name]; ok {
+		if record := repr.AST.ImportRecords[imported.ImportRecordIndex]; record.SourceIndex.IsValid() {
+			return r.resolve(record.SourceIndex.GetIndex(), imported.Alias)
+		}
+		return nil, false
+	}
+
+	for _, value := range repr.AST.Values {
+		if value.Name != name {
+			continue
+		}
+		tokens := value.Value
+		if sourceIndex != r.targetSourceIndex {
+			tokens, r.importRecords = css_ast.CloneTokensWithImportRecords(tokens, repr.AST.ImportRecords, nil, r.importRecords)
+		}
+
+		// The value may reference values imported from other files
+		if len(repr.AST.ImportedValues) > 0 {
+			tokens, _ = css_parser.SubstituteICSSValuesInTokens(tokens, func(name string) ([]css_ast.Token, bool) {
+				if _, ok := repr.AST.ImportedValues[name]; ok {
+					return r.resolve(sourceIndex, name)
+				}
+				return nil, false
+			})
+		}
+		return tokens, true
+	}
+	return nil, false
+}
+
+func (c *linkerContext) substituteImportedICSSValues(
+	sourceIndex uint32, rules []css_ast.Rule, importRecords []ast.ImportRecord,
+) ([]css_ast.Rule, []ast.ImportRecord) {
+	repr := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.CSSRepr)
+	r := c.newICSSValueResolver(sourceIndex, importRecords)
+	rules, ok := css_parser.SubstituteICSSValues(rules, func(name string) ([]css_ast.Token, bool) {
+		if _, ok := repr.AST.ImportedValues[name]; ok {
+			return r.resolve(sourceIndex, name)
+		}
+		return nil, false
+	})
+	if !ok {
+		return rules, importRecords
+	}
+	return rules, r.importRecords
+}
+
 func (c *linkerContext) generateCodeForLazyExport(sourceIndex uint32) {
 	file := &c.graph.Files[sourceIndex]
 	repr := file.InputFile.Repr.(*graph.JSRepr)
@@ -2171,6 +2736,23 @@ func (c *linkerContext) generateCodeForLazyExport(sourceIndex uint32) {
 				})
 			}
 
+			// Also export the values from "@value" rules and ":export" blocks
+			for _, value := range css.AST.Values {
+				r := c.newICSSValueResolver(cssSourceIndex, css.AST.ImportRecords)
+				tokens, ok := r.resolve(cssSourceIndex, value.Name)
+				if !ok {
+					continue
+				}
+				text := css_printer.PrintTokens(tokens, r.importRecords, c.graph.Symbols, css_printer.Options{
+					ASCIIOnly:  c.options.ASCIIOnly,
+					LocalNames: c.mangledProps,
+				})
+				exports.Properties = append(exports.Properties, js_ast.Property{
+					Key:        js_ast.Expr{Loc: value.NameLoc, Data: &js_ast.EString{Value: helpers.StringToUTF16(value.Name)}},
+					ValueOrNil: js_ast.Expr{Loc: value.NameLoc, Data: &js_ast.EString{Value: helpers.StringToUTF16(text)}},
+				})
+			}
+
 			lazyValue.Data = &exports
 		}
 	}
@@ -2255,34 +2837,312 @@ func (c *linkerContext) generateCodeForLazyExport(sourceIndex uint32) {
 	}}}
 }
 
-func (c *linkerContext) createExportsForFile(sourceIndex uint32) {
-	////////////////////////////////////////////////////////////////////////////////
-	// WARNING: This method is run in parallel over all files. Do not mutate data
-	// for other files within this method or you will create a data race.
-	////////////////////////////////////////////////////////////////////////////////
-
-	file := &c.graph.Files[sourceIndex]
-	repr := file.InputFile.Repr.(*graph.JSRepr)
-
-	// Generate a getter per export
-	properties := []js_ast.Property{}
-	nsExportDependencies := []js_ast.Dependency{}
-	nsExportSymbolUses := make(map[ast.Ref]js_ast.SymbolUse)
-	for _, alias := range repr.Meta.SortedAndFilteredExportAliases {
-		export := repr.Meta.ResolvedExports[alias]
+// This writes a TypeScript declaration file next to each CSS module that's
+// imported from JavaScript. The types match the object that the JavaScript
+// stub for the CSS module exports (see "generateCodeForLazyExport"):
+//
+//	declare const styles: {
+//	  readonly button: string;
+//	};
+//	export default styles;
+//	export declare const button: string;
+//
+// Each name is typed as a string instead of as the renamed string so that
+// the declaration file doesn't change when the naming scheme changes.
+func (c *linkerContext) generateCSSModuleTypes() (outputFiles []graph.OutputFile) {
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		if !ok || !repr.CSSSourceIndex.IsValid() {
+			continue
+		}
+		cssSourceIndex := repr.CSSSourceIndex.GetIndex()
+		cssFile := &c.graph.Files[cssSourceIndex].InputFile
+		css, ok := cssFile.Repr.(*graph.CSSRepr)
+		if !ok || (cssFile.Loader != config.LoaderLocalCSS && cssFile.Loader != config.LoaderGlobalCSS) || cssFile.Source.KeyPath.Namespace != "file" {
+			continue
+		}
 
-		// If this is an export of an import, reference the symbol that the import
-		// was eventually resolved to. We need to do this because imports have
-		// already been resolved by this point, so we can't generate a new import
-		// and have that be resolved later.
-		if importData, ok := c.graph.Files[export.SourceIndex].InputFile.Repr.(*graph.JSRepr).Meta.ImportsToBind[export.Ref]; ok {
-			export.Ref = importData.Ref
-			export.SourceIndex = importData.SourceIndex
-			nsExportDependencies = append(nsExportDependencies, importData.ReExports...)
+		// Don't write files into packages, which aren't ours to modify
+		if helpers.IsInsideNodeModules(cssFile.Source.KeyPath.Text) {
+			continue
 		}
 
-		// Exports of imports need EImportIdentifier in case they need to be re-
-		// written to a property access later on
+		// Generate the declaration file path from the CSS file path
+		absPath := cssFile.Source.KeyPath.Text
+		dir, base := c.fs.Dir(absPath), c.fs.Base(absPath)
+		switch c.options.CSSModuleTypes {
+		case config.CSSModuleTypesDTS:
+			absPath += ".d.ts"
+		case config.CSSModuleTypesDCSSTS:
+			ext := c.fs.Ext(base)
+			absPath = c.fs.Join(dir, base[:len(base)-len(ext)]+".d"+ext+".ts")
+		}
+
+		// Collect the exported names in the same order as the JavaScript stub
+		type exportedName struct {
+			name     string
+			composes []string
+		}
+		var names []exportedName
+		for _, local := range css.AST.LocalSymbols {
+			var composes []string
+			if item, ok := css.AST.Composes[local.Ref]; ok {
+				for _, name := range item.ImportedNames {
+					if record := css.AST.ImportRecords[name.ImportRecordIndex]; record.SourceIndex.IsValid() {
+						otherPath := c.graph.Files[record.SourceIndex.GetIndex()].InputFile.Source.KeyPath
+						text := otherPath.Text
+						if otherPath.Namespace == "file" {
+							if rel, ok := c.fs.Rel(dir, text); ok {
+								text = strings.ReplaceAll(rel, "\\", "/")
+								if !strings.HasPrefix(text, "../") {
+									text = "./" + text
+								}
+							}
+						}
+						composes = append(composes, fmt.Sprintf("%s from %s",
+							helpers.QuoteForJSON(name.Alias, c.options.ASCIIOnly),
+							helpers.QuoteForJSON(text, c.options.ASCIIOnly)))
+					}
+				}
+				for _, name := range item.Names {
+					composes = append(composes, string(helpers.QuoteForJSON(c.graph.Symbols.Get(name.Ref).OriginalName, c.options.ASCIIOnly)))
+				}
+			}
+			names = append(names, exportedName{name: c.graph.Symbols.Get(local.Ref).OriginalName, composes: composes})
+		}
+		for _, value := range css.AST.Values {
+			names = append(names, exportedName{name: value.Name})
+		}
+
+		sb := strings.Builder{}
+		sb.WriteString(fmt.Sprintf("// This file was generated by esbuild from %s\n",
+			helpers.QuoteForJSON(base, c.options.ASCIIOnly)))
+		sb.WriteString("declare const styles: {\n")
+		for _, item := range names {
+			if len(item.composes) > 0 {
+				sb.WriteString(fmt.Sprintf("  /** Composes %s */\n", strings.Join(item.composes, ", ")))
+			}
+			key := item.name
+			if !js_ast.IsIdentifier(key) {
+				key = string(helpers.QuoteForJSON(key, c.options.ASCIIOnly))
+			}
+			sb.WriteString(fmt.Sprintf("  readonly %s: string;\n", key))
+		}
+		sb.WriteString("};\nexport default styles;\n")
+
+		// Only names that are valid identifiers can be declared as named exports
+		for _, item := range names {
+			if item.name != "default" && js_ast.IsIdentifier(item.name) &&
+				js_lexer.Keywords[item.name] == 0 && !js_lexer.StrictModeReservedWords[item.name] {
+				sb.WriteString(fmt.Sprintf("export declare const %s: string;\n", item.name))
+			}
+		}
+
+		// The whole declaration file is generated from the CSS file
+		contents := []byte(sb.String())
+		var jsonMetadataChunk string
+		if c.options.NeedsMetafile {
+			jsonMetadataChunk = fmt.Sprintf(
+				"{\n      \"imports\": [],\n      \"exports\": [],\n      \"inputs\": {\n        %s: {\n          \"bytesInOutput\": %d\n        }\n      },\n      \"bytes\": %d\n    }",
+				helpers.QuoteForJSON(cssFile.Source.PrettyPaths.Select(c.options.MetafilePathStyle), c.options.ASCIIOnly),
+				len(contents),
+				len(contents),
+			)
+		}
+		outputFiles = append(outputFiles, graph.OutputFile{
+			AbsPath:           absPath,
+			Contents:          contents,
+			JSONMetadataChunk: jsonMetadataChunk,
+		})
+	}
+	return
+}
+
+// Local CSS names that are never read from JavaScript can't appear in the
+// DOM, so rules that only match those names can be removed. This is only done
+// for CSS files that are imported from JavaScript. Names are considered used
+// if they are imported by name, read as a static property of the default
+// import, or composed by another name that's used. If the file is imported
+// for its side effects only, imported as a namespace, imported dynamically,
+// or if the imported object escapes in any other way (e.g. "{ ...styles }" or
+// "styles[key]"), then all names in that file are considered used.
+func (c *linkerContext) findUnusedLocalCSSNames() {
+	c.timer.Begin("Find unused local CSS names")
+	defer c.timer.End("Find unused local CSS names")
+
+	// Find the CSS files whose local names are only visible from JavaScript
+	candidates := make(map[uint32]uint32) // JS stub source index => CSS source index
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		file := &c.graph.Files[sourceIndex]
+		if repr, ok := file.InputFile.Repr.(*graph.JSRepr); ok && repr.CSSSourceIndex.IsValid() && !file.IsEntryPoint() {
+			cssSourceIndex := repr.CSSSourceIndex.GetIndex()
+			cssFile := &c.graph.Files[cssSourceIndex]
+			if (cssFile.InputFile.Loader == config.LoaderLocalCSS || cssFile.InputFile.Loader == config.LoaderGlobalCSS) &&
+				!cssFile.IsEntryPoint() && repr.AST.ExportsKind != js_ast.ExportsCommonJS {
+				candidates[sourceIndex] = cssSourceIndex
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	usedNames := make(map[uint32]map[string]bool) // CSS source index => names
+	escaped := make(map[uint32]bool)              // CSS source index => true
+	markUsed := func(cssSourceIndex uint32, name string) {
+		names := usedNames[cssSourceIndex]
+		if names == nil {
+			names = make(map[string]bool)
+			usedNames[cssSourceIndex] = names
+		}
+		names[name] = true
+	}
+
+	// Scan all live JavaScript files for uses of the candidates
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		file := &c.graph.Files[sourceIndex]
+		repr, ok := file.InputFile.Repr.(*graph.JSRepr)
+		if !ok || !file.IsLive {
+			continue
+		}
+
+		for _, record := range repr.AST.ImportRecords {
+			if !record.SourceIndex.IsValid() {
+				continue
+			}
+			if cssSourceIndex, ok := candidates[record.SourceIndex.GetIndex()]; ok && record.Kind != ast.ImportStmt {
+				// "require()" and "import()" return the whole object
+				escaped[cssSourceIndex] = true
+			}
+		}
+
+		for _, importRecordIndex := range repr.AST.ExportStarImportRecords {
+			if record := &repr.AST.ImportRecords[importRecordIndex]; record.SourceIndex.IsValid() {
+				if cssSourceIndex, ok := candidates[record.SourceIndex.GetIndex()]; ok {
+					escaped[cssSourceIndex] = true
+				}
+			}
+		}
+
+		// Be conservative about namespace imports and side-effect imports. A
+		// namespace object can be passed around in ways that aren't tracked, and
+		// a side-effect import means the names are used somewhere that esbuild
+		// can't see (e.g. in HTML).
+		for _, part := range repr.AST.Parts {
+			for _, stmt := range part.Stmts {
+				if s, ok := stmt.Data.(*js_ast.SImport); ok && (s.StarNameLoc != nil || (s.DefaultName == nil && (s.Items == nil || len(*s.Items) == 0))) {
+					if record := &repr.AST.ImportRecords[s.ImportRecordIndex]; record.SourceIndex.IsValid() {
+						if cssSourceIndex, ok := candidates[record.SourceIndex.GetIndex()]; ok {
+							escaped[cssSourceIndex] = true
+						}
+					}
+				}
+			}
+		}
+
+		for ref, named := range repr.AST.NamedImports {
+			record := &repr.AST.ImportRecords[named.ImportRecordIndex]
+			if !record.SourceIndex.IsValid() {
+				continue
+			}
+			cssSourceIndex, ok := candidates[record.SourceIndex.GetIndex()]
+			if !ok {
+				continue
+			}
+			if named.IsExported || named.AliasIsStar {
+				escaped[cssSourceIndex] = true
+			} else if named.Alias != "default" {
+				markUsed(cssSourceIndex, named.Alias)
+			} else if accesses, ok := repr.AST.ImportItemPropertyAccesses[ref]; ok && accesses.Count == c.graph.Symbols.Get(ref).UseCountEstimate {
+				for name := range accesses.Names {
+					markUsed(cssSourceIndex, name)
+				}
+			} else if c.graph.Symbols.Get(ref).UseCountEstimate > 0 {
+				escaped[cssSourceIndex] = true
+			}
+		}
+	}
+
+	// Names composed by a used name are also used. All names in files that
+	// aren't candidates are used because they may be visible somewhere else.
+	used := make(map[ast.Ref]bool)
+	var visitName func(repr *graph.CSSRepr, ref ast.Ref)
+	visitName = func(repr *graph.CSSRepr, ref ast.Ref) {
+		if used[ref] {
+			return
+		}
+		used[ref] = true
+		if composes, ok := repr.AST.Composes[ref]; ok {
+			for _, name := range composes.ImportedNames {
+				if record := repr.AST.ImportRecords[name.ImportRecordIndex]; record.SourceIndex.IsValid() {
+					if otherRepr, ok := c.graph.Files[record.SourceIndex.GetIndex()].InputFile.Repr.(*graph.CSSRepr); ok {
+						if otherName, ok := otherRepr.AST.LocalScope[name.Alias]; ok {
+							visitName(otherRepr, otherName.Ref)
+						}
+					}
+				}
+			}
+			for _, name := range composes.Names {
+				visitName(repr, name.Ref)
+			}
+		}
+	}
+	isCandidate := make(map[uint32]bool)
+	for _, cssSourceIndex := range candidates {
+		isCandidate[cssSourceIndex] = true
+	}
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		if repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.CSSRepr); ok {
+			names := usedNames[sourceIndex]
+			for _, local := range repr.AST.LocalSymbols {
+				if !isCandidate[sourceIndex] || escaped[sourceIndex] || names[c.graph.Symbols.Get(local.Ref).OriginalName] {
+					visitName(repr, local.Ref)
+				}
+			}
+		}
+	}
+
+	for cssSourceIndex := range isCandidate {
+		repr := c.graph.Files[cssSourceIndex].InputFile.Repr.(*graph.CSSRepr)
+		for _, local := range repr.AST.LocalSymbols {
+			if !used[local.Ref] {
+				if c.unusedLocalCSSNames == nil {
+					c.unusedLocalCSSNames = make(map[ast.Ref]bool)
+				}
+				c.unusedLocalCSSNames[local.Ref] = true
+			}
+		}
+	}
+}
+
+func (c *linkerContext) createExportsForFile(sourceIndex uint32) {
+	////////////////////////////////////////////////////////////////////////////////
+	// WARNING: This method is run in parallel over all files. Do not mutate data
+	// for other files within this method or you will create a data race.
+	////////////////////////////////////////////////////////////////////////////////
+
+	file := &c.graph.Files[sourceIndex]
+	repr := file.InputFile.Repr.(*graph.JSRepr)
+
+	// Generate a getter per export
+	properties := []js_ast.Property{}
+	nsExportDependencies := []js_ast.Dependency{}
+	nsExportSymbolUses := make(map[ast.Ref]js_ast.SymbolUse)
+	for _, alias := range repr.Meta.SortedAndFilteredExportAliases {
+		export := repr.Meta.ResolvedExports[alias]
+
+		// If this is an export of an import, reference the symbol that the import
+		// was eventually resolved to. We need to do this because imports have
+		// already been resolved by this point, so we can't generate a new import
+		// and have that be resolved later.
+		if importData, ok := c.graph.Files[export.SourceIndex].InputFile.Repr.(*graph.JSRepr).Meta.ImportsToBind[export.Ref]; ok {
+			export.Ref = importData.Ref
+			export.SourceIndex = importData.SourceIndex
+			nsExportDependencies = append(nsExportDependencies, importData.ReExports...)
+		}
+
+		// Exports of imports need EImportIdentifier in case they need to be re-
+		// written to a property access later on
 		var value js_ast.Expr
 		if c.graph.Symbols.Get(export.Ref).NamespaceAlias != nil {
 			value = js_ast.Expr{Data: &js_ast.EImportIdentifier{Ref: export.Ref}}
@@ -2901,6 +3761,28 @@ func (c *linkerContext) maybeCorrectObviousTypo(repr *graph.JSRepr, name string,
 	}
 }
 
+// Top-level await is only lowered when it can't be used directly in the output
+func (c *linkerContext) isLoweringTopLevelAwait() bool {
+	return c.options.LowerTopLevelAwait && (!c.options.OutputFormat.KeepESMImportExportSyntax() ||
+		c.options.UnsupportedJSFeatures.Has(compat.TopLevelAwait))
+}
+
+// The evaluation of an entry point with lowered top-level await is an async
+// function call. Output formats with a CommonJS-style exports object store
+// the returned promise on that object so that other code can wait for it.
+func (c *linkerContext) isExposingTopLevelAwaitPromise(repr *graph.JSRepr) bool {
+	if repr.Meta.Wrap != graph.WrapESM || !repr.Meta.IsAsyncOrHasAsyncDependency || !c.isLoweringTopLevelAwait() {
+		return false
+	}
+	switch c.options.OutputFormat {
+	case config.FormatCommonJS:
+		return true
+	case config.FormatIIFE, config.FormatUMD:
+		return repr.Meta.ForceIncludeExportsForEntryPoint
+	}
+	return false
+}
+
 func (c *linkerContext) recursivelyWrapDependencies(sourceIndex uint32) {
 	repr := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
 	if repr.Meta.DidWrapDependencies {
@@ -3865,12 +4747,173 @@ func isConditionalImportRedundant(earlier []css_ast.ImportConditions, later []cs
 	return true
 }
 
+// Manual chunks override the chunk that a file would normally end up in based
+// on which entry points can reach it. Plugins are asked first and then the
+// patterns from the "ManualChunks" option are checked in order. Files imported
+// by a file in a manual chunk are pulled into that chunk too unless they have
+// been assigned elsewhere. Otherwise the manual chunk would have to import them
+// from a chunk that itself imports the manual chunk, which would be a cycle.
+func (c *linkerContext) computeManualChunks() map[uint32]string {
+	hasPlugins := false
+	for _, plugin := range c.options.Plugins {
+		if len(plugin.OnManualChunk) > 0 {
+			hasPlugins = true
+			break
+		}
+	}
+	if !c.options.CodeSplitting || (len(c.options.ManualChunks) == 0 && !hasPlugins) {
+		return nil
+	}
+
+	// Entry points always get their own chunk, and the runtime is handled
+	// separately because almost every chunk may end up depending on it
+	var candidates []uint32
+	isCandidate := make(map[uint32]bool)
+	for _, sourceIndex := range c.graph.ReachableFiles {
+		if file := &c.graph.Files[sourceIndex]; file.IsLive && !file.IsEntryPoint() && sourceIndex != runtime.SourceIndex {
+			if _, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
+				candidates = append(candidates, sourceIndex)
+				isCandidate[sourceIndex] = true
+			}
+		}
+	}
+
+	// Plugin callbacks may be slow, so run them in parallel
+	names := make([]string, len(candidates))
+	waitGroup := sync.WaitGroup{}
+	waitGroup.Add(len(candidates))
+	for i, sourceIndex := range candidates {
+		go func(i int, sourceIndex uint32) {
+			names[i] = c.manualChunkNameForFile(sourceIndex)
+			waitGroup.Done()
+		}(i, sourceIndex)
+	}
+	waitGroup.Wait()
+
+	manualChunks := make(map[uint32]string)
+	for i, sourceIndex := range candidates {
+		if names[i] != "" {
+			manualChunks[sourceIndex] = names[i]
+		}
+	}
+	if len(manualChunks) == 0 {
+		return nil
+	}
+
+	// Pull dependencies into the chunk of the first file that imports them. The
+	// traversal is done in sorted chunk name order for determinism.
+	sortedNames := make([]string, 0, len(manualChunks))
+	seenNames := make(map[string]bool)
+	for _, sourceIndex := range candidates {
+		if name, ok := manualChunks[sourceIndex]; ok && !seenNames[name] {
+			seenNames[name] = true
+			sortedNames = append(sortedNames, name)
+		}
+	}
+	sort.Strings(sortedNames)
+	var visit func(uint32, string)
+	visit = func(sourceIndex uint32, name string) {
+		repr := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr)
+		pull := func(otherSourceIndex uint32) {
+			if isCandidate[otherSourceIndex] {
+				if _, ok := manualChunks[otherSourceIndex]; !ok {
+					manualChunks[otherSourceIndex] = name
+					visit(otherSourceIndex, name)
+				}
+			}
+		}
+		for _, record := range repr.AST.ImportRecords {
+			if record.SourceIndex.IsValid() && !c.isExternalDynamicImport(&record, sourceIndex) {
+				pull(record.SourceIndex.GetIndex())
+			}
+		}
+		for _, part := range repr.AST.Parts {
+			if part.IsLive {
+				for _, dependency := range part.Dependencies {
+					pull(dependency.SourceIndex)
+				}
+			}
+		}
+	}
+	for _, name := range sortedNames {
+		for i, sourceIndex := range candidates {
+			if names[i] == name {
+				visit(sourceIndex, name)
+			}
+		}
+	}
+
+	return manualChunks
+}
+
+func (c *linkerContext) manualChunkNameForFile(sourceIndex uint32) string {
+	path := c.graph.Files[sourceIndex].InputFile.Source.KeyPath
+
+	for _, plugin := range c.options.Plugins {
+		for _, onManualChunk := range plugin.OnManualChunk {
+			if !config.PluginAppliesToPath(path, onManualChunk.Filter, onManualChunk.Namespace) {
+				continue
+			}
+			result := onManualChunk.Callback(config.OnManualChunkArgs{Path: path})
+			pluginName := result.PluginName
+			if pluginName == "" {
+				pluginName = plugin.Name
+			}
+			didLogError := false
+			for _, msg := range result.Msgs {
+				if msg.PluginName == "" {
+					msg.PluginName = pluginName
+				}
+				if msg.Kind == logger.Error {
+					didLogError = true
+				}
+				c.log.AddMsg(msg)
+			}
+			if didLogError {
+				return ""
+			}
+			if result.ThrownError != nil {
+				c.log.AddMsg(logger.Msg{
+					PluginName: pluginName,
+					Kind:       logger.Error,
+					Data: logger.MsgData{
+						Text:       result.ThrownError.Error(),
+						UserDetail: result.ThrownError,
+					},
+				})
+				return ""
+			}
+			if result.ChunkName != "" {
+				if !config.IsValidManualChunkName(result.ChunkName) {
+					c.log.AddMsg(logger.Msg{
+						PluginName: pluginName,
+						Kind:       logger.Error,
+						Data:       logger.MsgData{Text: fmt.Sprintf("Invalid manual chunk name: %q", result.ChunkName)},
+					})
+					return ""
+				}
+				return result.ChunkName
+			}
+		}
+	}
+
+	for _, chunk := range c.options.ManualChunks {
+		if chunk.Matches(path) {
+			return chunk.Name
+		}
+	}
+	return ""
+}
+
 func (c *linkerContext) computeChunks() {
 	c.timer.Begin("Compute chunks")
 	defer c.timer.End("Compute chunks")
 
 	jsChunks := make(map[string]chunkInfo)
 	cssChunks := make(map[string]chunkInfo)
+	htmlChunks := make(map[string]chunkInfo)
+	manualJSChunks := make(map[string]chunkInfo)
+	manualChunks := c.computeManualChunks()
 
 	// Create chunks for entry points
 	for i, entryPoint := range c.graph.EntryPoints() {
@@ -3934,6 +4977,10 @@ func (c *linkerContext) computeChunks() {
 				importsInChunkInOrder: order,
 			}
 			cssChunks[key] = chunk
+
+		case *graph.HTMLRepr:
+			chunk.chunkRepr = &chunkReprHTML{}
+			htmlChunks[key] = chunk
 		}
 	}
 
@@ -3941,6 +4988,25 @@ func (c *linkerContext) computeChunks() {
 	for _, sourceIndex := range c.graph.ReachableFiles {
 		if file := &c.graph.Files[sourceIndex]; file.IsLive {
 			if _, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
+				// Files in manual chunks are grouped by name instead of by entry bits.
+				// Every entry point that can reach any of these files will import the
+				// chunk. When manual chunks are present, the runtime is also given its
+				// own chunk (with an empty name) since manual chunks can't import it
+				// from a chunk that imports them.
+				if name, ok := manualChunks[sourceIndex]; ok || (manualChunks != nil && sourceIndex == runtime.SourceIndex) {
+					chunk, ok := manualJSChunks[name]
+					if !ok {
+						chunk.entryBits = helpers.NewBitSet(uint(len(c.graph.EntryPoints())))
+						chunk.filesWithPartsInChunk = make(map[uint32]bool)
+						chunk.chunkRepr = &chunkReprJS{}
+						chunk.manualChunkName = name
+						manualJSChunks[name] = chunk
+					}
+					chunk.entryBits.Union(file.EntryBits)
+					chunk.filesWithPartsInChunk[uint32(sourceIndex)] = true
+					continue
+				}
+
 				key := file.EntryBits.String()
 				chunk, ok := jsChunks[key]
 				if !ok {
@@ -3956,8 +5022,8 @@ func (c *linkerContext) computeChunks() {
 
 	// Sort the chunks for determinism. This matters because we use chunk indices
 	// as sorting keys in a few places.
-	sortedChunks := make([]chunkInfo, 0, len(jsChunks)+len(cssChunks))
-	sortedKeys := make([]string, 0, len(jsChunks)+len(cssChunks))
+	sortedChunks := make([]chunkInfo, 0, len(jsChunks)+len(manualJSChunks)+len(cssChunks)+len(htmlChunks))
+	sortedKeys := make([]string, 0, len(jsChunks)+len(manualJSChunks)+len(cssChunks)+len(htmlChunks))
 	for key := range jsChunks {
 		sortedKeys = append(sortedKeys, key)
 	}
@@ -3971,6 +5037,14 @@ func (c *linkerContext) computeChunks() {
 		sortedChunks = append(sortedChunks, chunk)
 	}
 	sortedKeys = sortedKeys[:0]
+	for key := range manualJSChunks {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		sortedChunks = append(sortedChunks, manualJSChunks[key])
+	}
+	sortedKeys = sortedKeys[:0]
 	for key := range cssChunks {
 		sortedKeys = append(sortedKeys, key)
 	}
@@ -3982,6 +5056,14 @@ func (c *linkerContext) computeChunks() {
 		}
 		sortedChunks = append(sortedChunks, chunk)
 	}
+	sortedKeys = sortedKeys[:0]
+	for key := range htmlChunks {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+	for _, key := range sortedKeys {
+		sortedChunks = append(sortedChunks, htmlChunks[key])
+	}
 
 	// Map from the entry point file to its chunk. We will need this later if
 	// a file contains a dynamic import to this entry point, since we'll need
@@ -4003,6 +5085,32 @@ func (c *linkerContext) computeChunks() {
 		}
 	}
 
+	// HTML chunks reference the chunks for the files in their tags. These are
+	// all entry points, so their chunk indices are known at this point.
+	for chunkIndex := range sortedChunks {
+		chunk := &sortedChunks[chunkIndex]
+		if _, ok := chunk.chunkRepr.(*chunkReprHTML); ok {
+			repr := c.graph.Files[chunk.sourceIndex].InputFile.Repr.(*graph.HTMLRepr)
+			for _, record := range repr.AST.ImportRecords {
+				if record.SourceIndex.IsValid() {
+					otherChunkIndex := c.graph.Files[record.SourceIndex.GetIndex()].EntryPointChunkIndex
+					chunk.crossChunkImports = append(chunk.crossChunkImports, chunkImport{
+						chunkIndex: otherChunkIndex,
+						importKind: record.Kind,
+					})
+
+					// Scripts that import CSS also cause a stylesheet tag to be generated
+					if otherRepr, ok := sortedChunks[otherChunkIndex].chunkRepr.(*chunkReprJS); ok && otherRepr.hasCSSChunk {
+						chunk.crossChunkImports = append(chunk.crossChunkImports, chunkImport{
+							chunkIndex: otherRepr.cssChunkIndex,
+							importKind: ast.ImportHTMLLink,
+						})
+					}
+				}
+			}
+		}
+	}
+
 	// Determine the order of JS files (and parts) within the chunk ahead of time
 	for _, chunk := range sortedChunks {
 		if chunkRepr, ok := chunk.chunkRepr.(*chunkReprJS); ok {
@@ -4026,6 +5134,8 @@ func (c *linkerContext) computeChunks() {
 			stdExt = c.options.OutputExtensionJS
 		case *chunkReprCSS:
 			stdExt = c.options.OutputExtensionCSS
+		case *chunkReprHTML:
+			stdExt = ".html"
 		}
 
 		// Compute the template substitutions
@@ -4069,6 +5179,9 @@ func (c *linkerContext) computeChunks() {
 		} else {
 			dir = "/"
 			base = "chunk"
+			if chunk.manualChunkName != "" {
+				base = chunk.manualChunkName
+			}
 			ext = stdExt
 			template = c.options.ChunkPathTemplate
 		}
@@ -4167,7 +5280,7 @@ func (c *linkerContext) findImportedPartsInJSOrder(chunk *chunkInfo) (js []uint3
 		file := &c.graph.Files[sourceIndex]
 
 		if repr, ok := file.InputFile.Repr.(*graph.JSRepr); ok {
-			isFileInThisChunk := chunk.entryBits.Equals(file.EntryBits)
+			isFileInThisChunk := chunk.filesWithPartsInChunk[sourceIndex]
 
 			// Wrapped files can't be split because they are all inside the wrapper
 			canFileBeSplit := repr.Meta.Wrap == graph.WrapNone
@@ -4292,15 +5405,36 @@ func (c *linkerContext) shouldRemoveImportExportStmt(
 			break
 		}
 
-		// Replace the statement with a call to "init()"
-		value := js_ast.Expr{Loc: loc, Data: &js_ast.ECall{Target: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: otherRepr.AST.WrapperRef}}}}
-		if otherRepr.Meta.IsAsyncOrHasAsyncDependency {
-			// This currently evaluates sibling dependencies in serial instead of in
-			// parallel, which is incorrect. This should be changed to store a promise
-			// and await all stored promises after all imports but before any code.
-			value.Data = &js_ast.EAwait{Value: value}
+		// Replace the statement with a call to "init()". Deferred imports don't
+		// need this unless the module uses top-level await, since those must
+		// still be evaluated immediately.
+		if record.Phase != ast.DeferPhase || otherRepr.Meta.IsAsyncOrHasAsyncDependency {
+			value := js_ast.Expr{Loc: loc, Data: &js_ast.ECall{Target: js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: otherRepr.AST.WrapperRef}}}}
+			if otherRepr.Meta.IsAsyncOrHasAsyncDependency {
+				// This currently evaluates sibling dependencies in serial instead of in
+				// parallel, which is incorrect. This should be changed to store a promise
+				// and await all stored promises after all imports but before any code.
+				value.Data = &js_ast.EAwait{Value: value}
+			}
+			stmtList.insideWrapperPrefix = append(stmtList.insideWrapperPrefix, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: value}})
+		}
+
+		// Deferred namespace imports aren't bound to the exports object, so they
+		// need a variable. The printer turns this into something like this:
+		//
+		//   var ns = __deferNS(() => (init_foo(), foo_exports));
+		//
+		if record.Phase == ast.DeferPhase {
+			stmtList.insideWrapperPrefix = append(stmtList.insideWrapperPrefix, js_ast.Stmt{
+				Loc: loc,
+				Data: &js_ast.SLocal{Decls: []js_ast.Decl{{
+					Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: namespaceRef}},
+					ValueOrNil: js_ast.Expr{Loc: record.Range.Loc, Data: &js_ast.ERequireString{
+						ImportRecordIndex: importRecordIndex,
+					}},
+				}}},
+			})
 		}
-		stmtList.insideWrapperPrefix = append(stmtList.insideWrapperPrefix, js_ast.Stmt{Loc: loc, Data: &js_ast.SExpr{Value: value}})
 	}
 
 	return true
@@ -4635,6 +5769,18 @@ type compileResultJS struct {
 	// This is the line and column offset since the previous JavaScript string
 	// or the start of the file if this is the first JavaScript string.
 	generatedOffset sourcemap.LineColumnOffset
+
+	// When hot module replacement is enabled, this is the code that the
+	// development server sends to re-register this module's closure. It's
+	// always a substring of the code in "JS" above.
+	hmrCode []byte
+
+	// With the SystemJS format, top-level declarations are hoisted out of the
+	// "execute" function into the function passed to "System.register()". This
+	// is the code for them along with the symbols of any hoisted functions that
+	// are exported there.
+	systemHoisted        js_printer.PrintResult
+	systemHoistedExports []ast.Ref
 }
 
 func (c *linkerContext) requireOrImportMetaForSource(sourceIndex uint32) (meta js_printer.RequireOrImportMeta) {
@@ -4651,10 +5797,12 @@ func (c *linkerContext) requireOrImportMetaForSource(sourceIndex uint32) (meta j
 
 func (c *linkerContext) generateCodeForFileInChunkJS(
 	r renamer.Renamer,
+	chunkRepr *chunkReprJS,
 	waitGroup *sync.WaitGroup,
 	partRange partRange,
 	toCommonJSRef ast.Ref,
 	toESMRef ast.Ref,
+	deferNSRef ast.Ref,
 	runtimeRequireRef ast.Ref,
 	result *compileResultJS,
 	dataForSourceMaps []bundler.DataForSourceMap,
@@ -4666,6 +5814,7 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	nsExportPartIndex := js_ast.NSExportPartIndex
 	needsWrapper := false
 	stmtList := stmtList{}
+	var hmrValue js_ast.Expr
 
 	// The top-level directive must come first (the non-wrapped case is handled
 	// by the chunk generation code, although only for the entry point)
@@ -4853,6 +6002,7 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 
 			// Hoist all top-level "var" and "function" declarations out of the closure
 			var decls []js_ast.Decl
+			var hoistedFunctions []js_ast.Stmt
 			end := 0
 			for _, stmt := range stmts {
 				switch s := stmt.Data.(type) {
@@ -4875,6 +6025,18 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 					stmt = js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: value}}
 
 				case *js_ast.SFunction:
+					// Functions must stay inside the closure when hot module replacement
+					// is enabled so that re-running the closure picks up the new code.
+					// They are still assigned first to preserve hoisting semantics.
+					if c.options.HotModuleReplacement && s.Fn.Name != nil {
+						ref := s.Fn.Name.Ref
+						decls = append(decls, js_ast.Decl{Binding: js_ast.Binding{Loc: s.Fn.Name.Loc, Data: &js_ast.BIdentifier{Ref: ref}}})
+						hoistedFunctions = append(hoistedFunctions, js_ast.AssignStmt(
+							js_ast.Expr{Loc: s.Fn.Name.Loc, Data: &js_ast.EIdentifier{Ref: ref}},
+							js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EFunction{Fn: s.Fn}},
+						))
+						continue
+					}
 					stmtList.outsideWrapperPrefix = append(stmtList.outsideWrapperPrefix, stmt)
 					continue
 				}
@@ -4883,9 +6045,28 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 				end++
 			}
 			stmts = stmts[:end]
+			if len(hoistedFunctions) > 0 {
+				stmts = append(hoistedFunctions, stmts...)
+			}
 
 			var esmArgs []js_ast.Expr
-			if c.options.ProfilerNames {
+			if c.options.HotModuleReplacement {
+				// "__esmHot('file.js', (hot) => { ... })"
+				var args []js_ast.Arg
+				if repr.AST.HotRef != ast.InvalidRef {
+					args = []js_ast.Arg{{Binding: js_ast.Binding{Data: &js_ast.BIdentifier{Ref: repr.AST.HotRef}}}}
+				}
+				var fn js_ast.Expr
+				if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+					fn = js_ast.Expr{Data: &js_ast.EFunction{Fn: js_ast.Fn{Args: args, Body: js_ast.FnBody{Block: js_ast.SBlock{Stmts: stmts}}, IsAsync: isAsync}}}
+				} else {
+					fn = js_ast.Expr{Data: &js_ast.EArrow{Args: args, Body: js_ast.FnBody{Block: js_ast.SBlock{Stmts: stmts}}, IsAsync: isAsync}}
+				}
+				esmArgs = []js_ast.Expr{
+					{Data: &js_ast.EString{Value: helpers.StringToUTF16(file.InputFile.Source.PrettyPaths.Select(c.options.CodePathStyle))}},
+					fn,
+				}
+			} else if c.options.ProfilerNames {
 				// "__esm({ 'file.js'() { ... } })"
 				kind := js_ast.PropertyField
 				if !c.options.UnsupportedJSFeatures.Has(compat.ObjectExtensions) {
@@ -4907,6 +6088,9 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.esmRuntimeRef}},
 				Args:   esmArgs,
 			}}
+			if c.options.HotModuleReplacement {
+				hmrValue = value
+			}
 
 			// "var foo, bar;"
 			if !c.options.MinifySyntax && len(decls) > 0 {
@@ -4926,6 +6110,13 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		}
 	}
 
+	// Hoist top-level declarations out of "execute" for the SystemJS format
+	var systemHoistedStmts []js_ast.Stmt
+	var systemHoistedExports []ast.Ref
+	if c.options.OutputFormat == config.FormatSystem {
+		stmts, systemHoistedStmts, systemHoistedExports = c.hoistSystemDeclarations(stmts, chunkRepr.systemLiveExports)
+	}
+
 	// Only generate a source map if needed
 	var addSourceMappings bool
 	var inputSourceMap *sourcemap.SourceMap
@@ -4936,11 +6127,8 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		lineOffsetTables = dataForSourceMaps[partRange.sourceIndex].LineOffsetTables
 	}
 
-	// Indent the file if everything is wrapped in an IIFE
-	indent := 0
-	if c.options.OutputFormat == config.FormatIIFE {
-		indent++
-	}
+	// Indent the file if everything is wrapped in a function
+	indent := c.indentForOutputFormat()
 
 	// Convert the AST to JavaScript code
 	printOptions := js_printer.Options{
@@ -4953,6 +6141,7 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		ASCIIOnly:                    c.options.ASCIIOnly,
 		ToCommonJSRef:                toCommonJSRef,
 		ToESMRef:                     toESMRef,
+		DeferNSRef:                   deferNSRef,
 		RuntimeRequireRef:            runtimeRequireRef,
 		TSEnums:                      c.graph.TSEnums,
 		ConstValues:                  c.graph.ConstValues,
@@ -4964,14 +6153,38 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 		LineOffsetTables:             lineOffsetTables,
 		RequireOrImportMetaForSource: c.requireOrImportMetaForSource,
 		MangledProps:                 c.mangledProps,
+		CrossChunkNamespaceAliases:   chunkRepr.crossChunkNamespaceAliases,
+		ChunkLoaderRef:               chunkRepr.chunkLoaderRef,
+		SystemLiveExports:            chunkRepr.systemLiveExports,
+		UMDDependencyRefs:            chunkRepr.umdDependencyRefs,
 		NeedsMetafile:                c.options.NeedsMetafile,
 	}
 	tree := repr.AST
 	tree.Directives = nil // This is handled elsewhere
 	tree.Parts = []js_ast.Part{{Stmts: stmts}}
 	*result = compileResultJS{
-		PrintResult: js_printer.Print(tree, c.graph.Symbols, r, printOptions),
-		sourceIndex: partRange.sourceIndex,
+		PrintResult:          js_printer.Print(tree, c.graph.Symbols, r, printOptions),
+		sourceIndex:          partRange.sourceIndex,
+		systemHoistedExports: systemHoistedExports,
+	}
+
+	// The hoisted declarations are indented one level less than "execute"
+	if len(systemHoistedStmts) > 0 {
+		printOptions.Indent = 1
+		printOptions.NeedsMetafile = false
+		tree.Parts = []js_ast.Part{{Stmts: systemHoistedStmts}}
+		result.systemHoisted = js_printer.Print(tree, c.graph.Symbols, r, printOptions)
+	}
+
+	// Print the module's closure by itself for hot module replacement. This is
+	// printed with the same options so that it's a substring of the full code.
+	if hmrValue.Data != nil {
+		printOptions.AddSourceMappings = false
+		printOptions.NeedsMetafile = false
+		tree.Parts = []js_ast.Part{{Stmts: []js_ast.Stmt{{Data: &js_ast.SExpr{Value: hmrValue}}}}}
+		code := js_printer.Print(tree, c.graph.Symbols, r, printOptions).JS
+		code = bytes.TrimSpace(code)
+		result.hmrCode = bytes.TrimSuffix(code, []byte{';'})
 	}
 
 	if file.InputFile.Loader == config.LoaderFile {
@@ -4982,14 +6195,111 @@ func (c *linkerContext) generateCodeForFileInChunkJS(
 	waitGroup.Done()
 }
 
-func (c *linkerContext) generateEntryPointTailJS(
-	r renamer.Renamer,
-	toCommonJSRef ast.Ref,
-	toESMRef ast.Ref,
+// The SystemJS format runs the code in "execute" after all dependencies have
+// been bound, but modules in an import cycle may call each other's exported
+// functions before that. So top-level declarations are moved into the outer
+// function and the hoisted functions are exported right away, like this:
+//
+//	System.register([], function(exports, module) {
+//	  "use strict";
+//	  var x;
+//	  function foo() {
+//	    return x;
+//	  }
+//	  exports({ foo });
+//	  return {
+//	    execute: function() {
+//	      x = 1;
+//	    }
+//	  };
+//	});
+//
+// This returns the remaining statements for "execute", the hoisted statements,
+// and the symbols of the hoisted functions that were exported.
+func (c *linkerContext) hoistSystemDeclarations(
+	stmts []js_ast.Stmt,
+	liveExports map[ast.Ref][]string,
+) ([]js_ast.Stmt, []js_ast.Stmt, []ast.Ref) {
+	// Leave everything in place if a "using" declaration is at the top level,
+	// since it can't be hoisted and the hoisted functions may reference it
+	for _, stmt := range stmts {
+		if s, ok := stmt.Data.(*js_ast.SLocal); ok && s.Kind.IsUsing() {
+			return stmts, nil, nil
+		}
+	}
+
+	var decls []js_ast.Decl
+	var hoistedFunctions []js_ast.Stmt
+	var exportItems []js_ast.ClauseItem
+	var exportRefs []ast.Ref
+	wrapIdentifier := func(loc logger.Loc, ref ast.Ref) js_ast.Expr {
+		decls = append(decls, js_ast.Decl{Binding: js_ast.Binding{Loc: loc, Data: &js_ast.BIdentifier{Ref: ref}}})
+		return js_ast.Expr{Loc: loc, Data: &js_ast.EIdentifier{Ref: ref}}
+	}
+	end := 0
+	for _, stmt := range stmts {
+		switch s := stmt.Data.(type) {
+		case *js_ast.SLocal:
+			// "let x = 1" => "x = 1"
+			var value js_ast.Expr
+			for _, decl := range s.Decls {
+				binding := js_ast.ConvertBindingToExpr(decl.Binding, wrapIdentifier)
+				if decl.ValueOrNil.Data != nil {
+					value = js_ast.JoinWithComma(value, js_ast.Assign(binding, decl.ValueOrNil))
+				}
+			}
+			if value.Data == nil {
+				continue
+			}
+			stmt = js_ast.Stmt{Loc: stmt.Loc, Data: &js_ast.SExpr{Value: value}}
+
+		case *js_ast.SClass:
+			// "class Foo {}" => "Foo = class Foo {}"
+			name := wrapIdentifier(s.Class.Name.Loc, s.Class.Name.Ref)
+			stmt = js_ast.AssignStmt(name, js_ast.Expr{Loc: stmt.Loc, Data: &js_ast.EClass{Class: s.Class}})
+
+		case *js_ast.SFunction:
+			hoistedFunctions = append(hoistedFunctions, stmt)
+			if s.Fn.Name != nil {
+				ref := ast.FollowSymbols(c.graph.Symbols, s.Fn.Name.Ref)
+				if aliases := liveExports[ref]; len(aliases) > 0 {
+					for _, alias := range aliases {
+						exportItems = append(exportItems, js_ast.ClauseItem{Alias: alias, Name: *s.Fn.Name})
+					}
+					exportRefs = append(exportRefs, ref)
+				}
+			}
+			continue
+		}
+
+		stmts[end] = stmt
+		end++
+	}
+	stmts = stmts[:end]
+
+	// "var x, Foo; function foo() {} exports({ foo });"
+	var hoisted []js_ast.Stmt
+	if len(decls) > 0 {
+		hoisted = append(hoisted, js_ast.Stmt{Data: &js_ast.SLocal{Decls: decls}})
+	}
+	hoisted = append(hoisted, hoistedFunctions...)
+	if len(exportItems) > 0 {
+		hoisted = append(hoisted, js_ast.Stmt{Data: &js_ast.SExportClause{Items: exportItems, IsSingleLine: true}})
+	}
+	return stmts, hoisted, exportRefs
+}
+
+func (c *linkerContext) generateEntryPointTailJS(
+	r renamer.Renamer,
+	chunkRepr *chunkReprJS,
+	toCommonJSRef ast.Ref,
+	toESMRef ast.Ref,
 	sourceIndex uint32,
 ) (result compileResultJS) {
 	file := &c.graph.Files[sourceIndex]
 	repr := file.InputFile.Repr.(*graph.JSRepr)
+	runtimeMembers := c.graph.Files[runtime.SourceIndex].InputFile.Repr.(*graph.JSRepr).AST.ModuleScope.Members
+	tlaRef := ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__tla"].Ref)
 	var stmts []js_ast.Stmt
 
 	switch c.options.OutputFormat {
@@ -5002,9 +6312,9 @@ func (c *linkerContext) generateEntryPointTailJS(
 			}}}})
 		}
 
-	case config.FormatIIFE:
+	case config.FormatIIFE, config.FormatUMD:
 		if repr.Meta.Wrap == graph.WrapCJS {
-			if len(c.options.GlobalName) > 0 {
+			if len(c.options.GlobalName) > 0 || c.options.CodeSplitting || c.options.OutputFormat == config.FormatUMD {
 				// "return require_foo();"
 				stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SReturn{ValueOrNil: js_ast.Expr{Data: &js_ast.ECall{
 					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
@@ -5015,6 +6325,20 @@ func (c *linkerContext) generateEntryPointTailJS(
 					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
 				}}}})
 			}
+		} else if c.isExposingTopLevelAwaitPromise(repr) {
+			// "return __tla(__toCommonJS(exports), init_foo());"
+			stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SReturn{
+				ValueOrNil: js_ast.Expr{Data: &js_ast.ECall{
+					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: tlaRef}},
+					Args: []js_ast.Expr{
+						{Data: &js_ast.ECall{
+							Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: toCommonJSRef}},
+							Args:   []js_ast.Expr{{Data: &js_ast.EIdentifier{Ref: repr.AST.ExportsRef}}},
+						}},
+						{Data: &js_ast.ECall{Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}}}},
+					},
+				}},
+			}})
 		} else {
 			if repr.Meta.Wrap == graph.WrapESM {
 				// "init_foo();"
@@ -5046,6 +6370,18 @@ func (c *linkerContext) generateEntryPointTailJS(
 					Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}},
 				}},
 			))
+		} else if c.isExposingTopLevelAwaitPromise(repr) {
+			// "__tla(module.exports, init_foo());"
+			stmts = append(stmts, js_ast.Stmt{Data: &js_ast.SExpr{Value: js_ast.Expr{Data: &js_ast.ECall{
+				Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: tlaRef}},
+				Args: []js_ast.Expr{
+					{Data: &js_ast.EDot{
+						Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: c.unboundModuleRef}},
+						Name:   "exports",
+					}},
+					{Data: &js_ast.ECall{Target: js_ast.Expr{Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}}}},
+				},
+			}}}})
 		} else {
 			if repr.Meta.Wrap == graph.WrapESM {
 				// "init_foo();"
@@ -5140,7 +6476,7 @@ func (c *linkerContext) generateEntryPointTailJS(
 			}
 		}
 
-	case config.FormatESModule:
+	case config.FormatESModule, config.FormatSystem:
 		if repr.Meta.Wrap == graph.WrapCJS {
 			// "export default require_foo();"
 			stmts = append(stmts, js_ast.Stmt{
@@ -5150,7 +6486,7 @@ func (c *linkerContext) generateEntryPointTailJS(
 							Data: &js_ast.EIdentifier{Ref: repr.AST.WrapperRef}}}}}}}})
 		} else {
 			if repr.Meta.Wrap == graph.WrapESM {
-				if repr.Meta.IsAsyncOrHasAsyncDependency {
+				if repr.Meta.IsAsyncOrHasAsyncDependency && !c.isLoweringTopLevelAwait() {
 					// "await init_foo();"
 					stmts = append(stmts, js_ast.Stmt{
 						Data: &js_ast.SExpr{Value: js_ast.Expr{
@@ -5279,11 +6615,8 @@ func (c *linkerContext) generateEntryPointTailJS(
 	tree.Directives = nil
 	tree.Parts = []js_ast.Part{{Stmts: stmts}}
 
-	// Indent the file if everything is wrapped in an IIFE
-	indent := 0
-	if c.options.OutputFormat == config.FormatIIFE {
-		indent++
-	}
+	// Indent the file if everything is wrapped in a function
+	indent := c.indentForOutputFormat()
 
 	// Convert the AST to JavaScript code
 	printOptions := js_printer.Options{
@@ -5300,6 +6633,8 @@ func (c *linkerContext) generateEntryPointTailJS(
 		UnsupportedFeatures:          c.options.UnsupportedJSFeatures,
 		RequireOrImportMetaForSource: c.requireOrImportMetaForSource,
 		MangledProps:                 c.mangledProps,
+		CrossChunkNamespaceAliases:   chunkRepr.crossChunkNamespaceAliases,
+		SystemHoistedExports:         chunkRepr.systemHoistedExports,
 	}
 	result.PrintResult = js_printer.Print(tree, c.graph.Symbols, r, printOptions)
 	return
@@ -5335,6 +6670,12 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 		reservedNames["module"] = 1
 	}
 
+	// The SystemJS format passes these to the function that wraps the code
+	if c.options.OutputFormat == config.FormatSystem {
+		reservedNames["exports"] = 1
+		reservedNames["module"] = 1
+	}
+
 	// These are used to implement bundling, and need to be free for use
 	if c.options.Mode != config.ModePassThrough {
 		reservedNames["require"] = 1
@@ -5342,13 +6683,38 @@ func (c *linkerContext) renameSymbolsInChunk(chunk *chunkInfo, filesInOrder []ui
 	}
 	timer.End("Compute reserved names")
 
-	// Make sure imports get a chance to be renamed too
+	// Make sure imports get a chance to be renamed too. Output formats without
+	// import statements only declare a namespace object for each other chunk.
 	var sortedImportsFromOtherChunks stableRefArray
-	for _, imports := range chunk.chunkRepr.(*chunkReprJS).importsFromOtherChunks {
-		for _, item := range imports {
+	if chunkRepr := chunk.chunkRepr.(*chunkReprJS); c.options.OutputFormat.KeepESMImportExportSyntax() {
+		for _, imports := range chunkRepr.importsFromOtherChunks {
+			for _, item := range imports {
+				sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
+					StableSourceIndex: c.graph.StableSourceIndices[item.ref.SourceIndex],
+					Ref:               item.ref,
+				})
+			}
+		}
+	} else if c.options.CodeSplitting {
+		for _, namespace := range chunkRepr.crossChunkNamespaces {
+			if namespace.ref != ast.InvalidRef {
+				sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
+					StableSourceIndex: c.graph.StableSourceIndices[namespace.ref.SourceIndex],
+					Ref:               namespace.ref,
+				})
+			}
+		}
+		if c.options.OutputFormat == config.FormatIIFE {
 			sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
-				StableSourceIndex: c.graph.StableSourceIndices[item.ref.SourceIndex],
-				Ref:               item.ref,
+				StableSourceIndex: c.graph.StableSourceIndices[chunkRepr.chunkLoaderRef.SourceIndex],
+				Ref:               chunkRepr.chunkLoaderRef,
+			})
+		}
+	} else if c.options.OutputFormat == config.FormatUMD {
+		for _, dep := range chunkRepr.umdDependencies {
+			sortedImportsFromOtherChunks = append(sortedImportsFromOtherChunks, stableRef{
+				StableSourceIndex: c.graph.StableSourceIndices[dep.ref.SourceIndex],
+				Ref:               dep.ref,
 			})
 		}
 	}
@@ -5571,6 +6937,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	runtimeMembers := c.graph.Files[runtime.SourceIndex].InputFile.Repr.(*graph.JSRepr).AST.ModuleScope.Members
 	toCommonJSRef := ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__toCommonJS"].Ref)
 	toESMRef := ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__toESM"].Ref)
+	deferNSRef := ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__deferNS"].Ref)
 	runtimeRequireRef := ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__require"].Ref)
 	r := c.renameSymbolsInChunk(chunk, chunkRepr.filesInChunkInOrder, timer)
 	dataForSourceMaps := c.dataForSourceMaps()
@@ -5584,6 +6951,11 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	// never change the "../" count.
 	chunkAbsDir := c.fs.Dir(c.fs.Join(c.options.AbsOutputDir, config.TemplateToString(chunk.finalTemplate)))
 
+	// This must be done before the files are printed since the printer uses it
+	if c.options.OutputFormat == config.FormatSystem {
+		chunkRepr.systemLiveExports = c.computeSystemLiveExports(chunk)
+	}
+
 	// Generate JavaScript for each file in parallel
 	timer.Begin("Print JavaScript files")
 	waitGroup := sync.WaitGroup{}
@@ -5599,26 +6971,41 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		waitGroup.Add(1)
 		go c.generateCodeForFileInChunkJS(
 			r,
+			chunkRepr,
 			&waitGroup,
 			partRange,
 			toCommonJSRef,
 			toESMRef,
+			deferNSRef,
 			runtimeRequireRef,
 			compileResult,
 			dataForSourceMaps,
 		)
 	}
 
+	// With the SystemJS format, exported functions that were hoisted out of
+	// "execute" are already exported there. The export clauses printed below
+	// need to omit them, so wait until the files have been printed.
+	if c.options.OutputFormat == config.FormatSystem {
+		waitGroup.Wait()
+		for _, compileResult := range compileResults {
+			for _, ref := range compileResult.systemHoistedExports {
+				if chunkRepr.systemHoistedExports == nil {
+					chunkRepr.systemHoistedExports = make(map[ast.Ref]bool)
+				}
+				chunkRepr.systemHoistedExports[ref] = true
+			}
+		}
+	}
+
 	// Also generate the cross-chunk binding code
 	var crossChunkPrefix []byte
 	var crossChunkSuffix []byte
 	var jsonMetadataImports []string
+	var crossChunkSystemImports []js_printer.SystemImport
 	{
-		// Indent the file if everything is wrapped in an IIFE
-		indent := 0
-		if c.options.OutputFormat == config.FormatIIFE {
-			indent++
-		}
+		// Indent the file if everything is wrapped in a function
+		indent := c.indentForOutputFormat()
 		printOptions := js_printer.Options{
 			Indent:            indent,
 			OutputFormat:      c.options.OutputFormat,
@@ -5627,6 +7014,8 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 			MinifySyntax:      c.options.MinifySyntax,
 			LineLimit:         c.options.LineLimit,
 			NeedsMetafile:     c.options.NeedsMetafile,
+
+			SystemHoistedExports: chunkRepr.systemHoistedExports,
 		}
 		crossChunkImportRecords := make([]ast.ImportRecord, len(chunk.crossChunkImports))
 		for i, chunkImport := range chunk.crossChunkImports {
@@ -5642,6 +7031,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		}, c.graph.Symbols, r, printOptions)
 		crossChunkPrefix = crossChunkResult.JS
 		jsonMetadataImports = crossChunkResult.JSONMetadataImports
+		crossChunkSystemImports = crossChunkResult.SystemImports
 		crossChunkSuffix = js_printer.Print(js_ast.AST{
 			Parts: []js_ast.Part{{Stmts: chunkRepr.crossChunkSuffixStmts}},
 		}, c.graph.Symbols, r, printOptions).JS
@@ -5652,6 +7042,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	if chunk.isEntryPoint {
 		entryPointTail = c.generateEntryPointTailJS(
 			r,
+			chunkRepr,
 			toCommonJSRef,
 			toESMRef,
 			chunk.sourceIndex,
@@ -5704,7 +7095,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	if chunk.isEntryPoint {
 		repr := c.graph.Files[chunk.sourceIndex].InputFile.Repr.(*graph.JSRepr)
 		for _, directive := range repr.AST.Directives {
-			if directive != "use strict" || c.options.OutputFormat != config.FormatESModule {
+			if directive != "use strict" || (c.options.OutputFormat != config.FormatESModule && c.options.OutputFormat != config.FormatSystem) {
 				quoted := string(helpers.QuoteForJSON(directive, c.options.ASCIIOnly)) + ";" + newline
 				prevOffset.AdvanceString(quoted)
 				j.AddString(quoted)
@@ -5717,19 +7108,84 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	if c.options.OutputFormat == config.FormatIIFE {
 		var text string
 		indent = "  "
-		if len(c.options.GlobalName) > 0 {
-			text = c.generateGlobalNamePrefix()
-		}
-		if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
-			text += "(function()" + space + "{" + newline
+		if c.options.CodeSplitting {
+			var imports []string
+			text, imports = c.generateChunkRegistrationPrefix(chunk, r)
+			jsonMetadataImports = append(jsonMetadataImports, imports...)
 		} else {
-			text += "(()" + space + "=>" + space + "{" + newline
+			if len(c.options.GlobalName) > 0 {
+				text = c.generateGlobalNamePrefix()
+			}
+			if c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+				text += "(function()" + space + "{" + newline
+			} else {
+				text += "(()" + space + "=>" + space + "{" + newline
+			}
 		}
 		prevOffset.AdvanceString(text)
 		j.AddString(text)
 		newlineBeforeComment = false
 	}
 
+	// Optionally wrap with a UMD factory function
+	if c.options.OutputFormat == config.FormatUMD {
+		text := c.generateUMDPrefix(chunk, r)
+		indent = "  "
+		prevOffset.AdvanceString(text)
+		j.AddString(text)
+		newlineBeforeComment = false
+	}
+
+	// Optionally wrap with "System.register()"
+	var compileResultsForSourceMap []compileResultForSourceMap
+	if c.options.OutputFormat == config.FormatSystem {
+		var systemImports []js_printer.SystemImport
+		systemImports = append(systemImports, crossChunkSystemImports...)
+		for _, compileResult := range compileResults {
+			systemImports = append(systemImports, compileResult.SystemImports...)
+		}
+		systemImports = append(systemImports, entryPointTail.SystemImports...)
+		text, rest := c.generateSystemRegisterPrefix(chunk, systemImports)
+		indent = "      "
+		prevOffset.AdvanceString(text)
+		j.AddString(text)
+
+		// Put the hoisted declarations before "execute"
+		for _, compileResult := range compileResults {
+			hoisted := compileResult.systemHoisted
+			if len(hoisted.JS) == 0 {
+				continue
+			}
+			if c.graph.Files[compileResult.sourceIndex].InputFile.OmitFromSourceMapsAndMetafile || hoisted.SourceMapChunk.ShouldIgnore {
+				prevOffset.AdvanceBytes(hoisted.JS)
+
+				// Include a null entry in the source map
+				if c.options.SourceMap != config.SourceMapNone {
+					if n := len(compileResultsForSourceMap); n > 0 && !compileResultsForSourceMap[n-1].isNullEntry {
+						compileResultsForSourceMap = append(compileResultsForSourceMap, compileResultForSourceMap{
+							sourceIndex: compileResult.sourceIndex,
+							isNullEntry: true,
+						})
+					}
+				}
+			} else {
+				if c.options.SourceMap != config.SourceMapNone {
+					compileResultsForSourceMap = append(compileResultsForSourceMap, compileResultForSourceMap{
+						sourceMapChunk:  hoisted.SourceMapChunk,
+						generatedOffset: prevOffset,
+						sourceIndex:     compileResult.sourceIndex,
+					})
+				}
+				prevOffset = sourcemap.LineColumnOffset{}
+			}
+			j.AddBytes(hoisted.JS)
+		}
+
+		prevOffset.AdvanceString(rest)
+		j.AddString(rest)
+		newlineBeforeComment = false
+	}
+
 	// Put the cross-chunk prefix inside the IIFE
 	if len(crossChunkPrefix) > 0 {
 		newlineBeforeComment = true
@@ -5812,7 +7268,6 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 	}
 
 	// Concatenate the generated JavaScript chunks together
-	var compileResultsForSourceMap []compileResultForSourceMap
 	var legalCommentList []legalCommentEntry
 	var metaOrder []uint32
 	var metaBytes map[uint32][][]byte
@@ -5895,7 +7350,11 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 				if !ok {
 					metaOrder = append(metaOrder, compileResult.sourceIndex)
 				}
-				metaBytes[compileResult.sourceIndex] = append(bytes, compileResult.JS)
+				bytes = append(bytes, compileResult.JS)
+				if len(compileResult.systemHoisted.JS) > 0 {
+					bytes = append(bytes, compileResult.systemHoisted.JS)
+				}
+				metaBytes[compileResult.sourceIndex] = bytes
 			}
 		}
 
@@ -5903,6 +7362,29 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		if len(compileResult.JS) > 0 {
 			newlineBeforeComment = true
 		}
+
+		// Remember the code for each module for hot module replacement
+		if compileResult.hmrCode != nil {
+			chunkRepr.hmrModules = append(chunkRepr.hmrModules, hmrModule{
+				id:   c.graph.Files[compileResult.sourceIndex].InputFile.Source.PrettyPaths.Select(c.options.CodePathStyle),
+				code: compileResult.hmrCode,
+			})
+		}
+	}
+
+	// Start listening for hot module replacement updates before the entry point
+	// runs. The update code must be evaluated in the top-level scope of this
+	// chunk since it references other top-level symbols, so a direct "eval" is
+	// passed to the runtime.
+	if c.options.HotModuleReplacement && chunk.isEntryPoint {
+		entry := c.graph.Files[chunk.sourceIndex].InputFile.Source.PrettyPaths.Select(c.options.CodePathStyle)
+		connect := r.NameForSymbol(ast.FollowSymbols(c.graph.Symbols, runtimeMembers["__hmrConnect"].Ref))
+		quoted := helpers.QuoteForJSON(entry, c.options.ASCIIOnly)
+		if c.options.MinifyWhitespace {
+			j.AddString(fmt.Sprintf("%s(%s,function(){return eval(arguments[0])});", connect, quoted))
+		} else {
+			j.AddString(fmt.Sprintf("%s%s(%s, function() {\n%s  return eval(arguments[0]);\n%s});\n", indent, connect, quoted, indent, indent))
+		}
 	}
 
 	// Stick the entry point tail at the end of the file. Deliberately don't
@@ -5920,7 +7402,25 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 
 	// Optionally wrap with an IIFE
 	if c.options.OutputFormat == config.FormatIIFE {
-		j.AddString("})();" + newline)
+		if c.options.CodeSplitting {
+			j.AddString("}]);" + newline)
+		} else {
+			j.AddString("})();" + newline)
+		}
+	}
+
+	// Optionally wrap with a UMD factory function
+	if c.options.OutputFormat == config.FormatUMD {
+		j.AddString("});" + newline)
+	}
+
+	// Optionally wrap with "System.register()"
+	if c.options.OutputFormat == config.FormatSystem {
+		if c.options.MinifyWhitespace {
+			j.AddString("}}});")
+		} else {
+			j.AddString("    }\n  };\n});\n")
+		}
 	}
 
 	// Make sure the file ends with a newline
@@ -5942,7 +7442,7 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 
 	if c.options.SourceMap != config.SourceMapNone {
 		timer.Begin("Generate source map")
-		canHaveShifts := chunk.intermediateOutput.pieces != nil
+		canHaveShifts := chunk.intermediateOutput.pieces != nil || c.hasOnOutputCallbacks()
 		chunk.outputSourceMap = c.generateSourceMapForChunk(compileResultsForSourceMap, chunkAbsDir, dataForSourceMaps, canHaveShifts)
 		timer.End("Generate source map")
 	}
@@ -5981,11 +7481,500 @@ func (c *linkerContext) generateChunkJS(chunkIndex int, chunkWaitGroup *sync.Wai
 		}
 	}
 
+	c.runOnOutputCallbacks(chunk)
 	c.generateIsolatedHashInParallel(chunk)
 	chunk.isExecutable = isExecutable
 	chunkWaitGroup.Done()
 }
 
+// Code splitting with the IIFE format wraps each chunk in a function that is
+// registered with the chunk loader from the runtime. The loader runs it after
+// all chunks it depends on have been run and passes their exports as arguments:
+//
+//	(self.esbuildChunks = self.esbuildChunks || []).push([self.document ? document.currentScript.src : location.href, ["./chunk.js"], (__import, import_chunk) => {
+//	  ...
+//	}]);
+//
+// Entry point chunks also include the loader itself since they are the chunks
+// that are loaded first. The first argument is used to implement "import()".
+func (c *linkerContext) generateChunkRegistrationPrefix(chunk *chunkInfo, r renamer.Renamer) (string, []string) {
+	chunkRepr := chunk.chunkRepr.(*chunkReprJS)
+	space := " "
+	comma := ", "
+	newline := "\n"
+	if c.options.MinifyWhitespace {
+		space = ""
+		comma = ","
+		newline = ""
+	}
+
+	// Chunks that are imported for their exports come first so that the
+	// arguments line up, followed by the chunks only imported for side effects
+	namespaces := make([]crossChunkNamespace, 0, len(chunkRepr.crossChunkNamespaces))
+	for _, namespace := range chunkRepr.crossChunkNamespaces {
+		if namespace.ref != ast.InvalidRef {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	for _, namespace := range chunkRepr.crossChunkNamespaces {
+		if namespace.ref == ast.InvalidRef {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	var sb strings.Builder
+	var jsonMetadataImports []string
+	if chunk.isEntryPoint && !c.options.OmitRuntimeForTests {
+		sb.WriteString(c.chunkLoaderJS)
+	}
+	sb.WriteString("(" + runtime.ChunkRegistry + space + "=" + space + runtime.ChunkRegistry + space + "||" + space + "[]).push([self.document" +
+		space + "?" + space + "document.currentScript.src" + space + ":" + space + "location.href," + space + "[")
+	for i, namespace := range namespaces {
+		if i > 0 {
+			sb.WriteString(comma)
+		}
+		chunkImport := chunk.crossChunkImports[namespace.importRecordIndex]
+		uniqueKey := c.chunks[chunkImport.chunkIndex].uniqueKey
+		sb.WriteString("\"" + uniqueKey + "\"")
+		if c.options.NeedsMetafile {
+			jsonMetadataImports = append(jsonMetadataImports, chunkMetadataImport(uniqueKey, chunkImport.importKind, c.options.ASCIIOnly))
+		}
+	}
+	sb.WriteString("]," + space)
+	if !c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+		sb.WriteString("(")
+	} else {
+		sb.WriteString("function(")
+	}
+	sb.WriteString(r.NameForSymbol(chunkRepr.chunkLoaderRef))
+	for _, namespace := range namespaces {
+		if namespace.ref != ast.InvalidRef {
+			sb.WriteString(comma)
+			sb.WriteString(r.NameForSymbol(namespace.ref))
+		}
+	}
+	if !c.options.UnsupportedJSFeatures.Has(compat.Arrow) {
+		sb.WriteString(")" + space + "=>" + space + "{" + newline)
+	} else {
+		sb.WriteString(")" + space + "{" + newline)
+	}
+	return sb.String(), jsonMetadataImports
+}
+
+// The chunk loader is stored as JavaScript source code in the runtime package.
+// It's parsed and printed separately from everything else since it must run
+// before any chunks, but with the same settings so that it's lowered and
+// minified like the rest of the output.
+func (c *linkerContext) compileChunkLoader() string {
+	log := logger.NewDeferLog(logger.DeferLogAll, nil)
+	source := runtime.ChunkLoaderSource()
+	tree, ok := js_parser.Parse(log, source, js_parser.OptionsFromConfig(&config.Options{
+		UnsupportedJSFeatures: c.options.UnsupportedJSFeatures,
+		MinifySyntax:          c.options.MinifySyntax,
+		MinifyIdentifiers:     c.options.MinifyIdentifiers,
+	}))
+	if log.HasErrors() || !ok {
+		msgs := "Internal error: failed to parse chunk loader:\n"
+		for _, msg := range log.Done() {
+			msgs += msg.String(logger.OutputOptions{IncludeSource: true}, logger.TerminalInfo{})
+		}
+		panic(msgs[:len(msgs)-1])
+	}
+
+	symbols := ast.NewSymbolMap(int(source.Index) + 1)
+	symbols.SymbolsForSource[source.Index] = tree.Symbols
+	reservedNames := renamer.ComputeReservedNames([]*js_ast.Scope{tree.ModuleScope}, symbols)
+
+	// The loader only has nested symbols, so only those need to be renamed
+	var r renamer.Renamer
+	if c.options.MinifyIdentifiers {
+		minifyRenamer := renamer.NewMinifyRenamer(symbols, tree.NestedScopeSlotCounts, reservedNames)
+		var topLevelSymbols renamer.StableSymbolCountArray
+		stableSourceIndices := make([]uint32, source.Index+1)
+		for _, part := range tree.Parts {
+			minifyRenamer.AccumulateSymbolUseCounts(&topLevelSymbols, part.SymbolUses, stableSourceIndices)
+			for _, declared := range part.DeclaredSymbols {
+				minifyRenamer.AccumulateSymbolCount(&topLevelSymbols, declared.Ref, 1, stableSourceIndices)
+			}
+		}
+		sort.Sort(topLevelSymbols)
+		minifyRenamer.AllocateTopLevelSymbolSlots(topLevelSymbols)
+		minifier := ast.DefaultNameMinifierJS
+		if tree.CharFreq != nil {
+			minifier = minifier.ShuffleByCharFreq(*tree.CharFreq)
+		}
+		minifyRenamer.AssignNamesByFrequency(&minifier)
+		r = minifyRenamer
+	} else {
+		numberRenamer := renamer.NewNumberRenamer(symbols, reservedNames)
+		numberRenamer.AssignNamesByScope(map[uint32][]*js_ast.Scope{source.Index: tree.ModuleScope.Children})
+		r = numberRenamer
+	}
+
+	result := js_printer.Print(tree, symbols, r, js_printer.Options{
+		UnsupportedFeatures: c.options.UnsupportedJSFeatures,
+		MinifyWhitespace:    c.options.MinifyWhitespace,
+		MinifySyntax:        c.options.MinifySyntax,
+		MinifyIdentifiers:   c.options.MinifyIdentifiers,
+		ASCIIOnly:           c.options.ASCIIOnly,
+		LineLimit:           c.options.LineLimit,
+	})
+	return string(result.JS)
+}
+
+// The UMD format passes each external module to the factory function as an
+// argument, since it could come from "define()", "require()", or a global
+// variable. This generates a symbol for each argument so it can be renamed.
+func (c *linkerContext) computeUMDDependencies() {
+	warnedPaths := make(map[string]bool)
+	for chunkIndex := range c.chunks {
+		chunkRepr, ok := c.chunks[chunkIndex].chunkRepr.(*chunkReprJS)
+		if !ok {
+			continue
+		}
+		chunkRepr.umdDependencyRefs = make(map[string]ast.Ref)
+		for _, partRange := range chunkRepr.partsInChunkInOrder {
+			file := &c.graph.Files[partRange.sourceIndex]
+			repr := file.InputFile.Repr.(*graph.JSRepr)
+			for partIndex := partRange.partIndexBegin; partIndex < partRange.partIndexEnd; partIndex++ {
+				for _, importRecordIndex := range repr.AST.Parts[partIndex].ImportRecordIndices {
+					record := &repr.AST.ImportRecords[importRecordIndex]
+					if record.SourceIndex.IsValid() || record.Flags.Has(ast.IsUnused) ||
+						(record.Kind != ast.ImportStmt && record.Kind != ast.ImportRequire) {
+						continue
+					}
+					path := record.Path.Text
+					if _, ok := chunkRepr.umdDependencyRefs[path]; ok {
+						continue
+					}
+					name := js_ast.GenerateNonUniqueNameFromPath(path)
+					ref := c.graph.GenerateNewSymbol(runtime.SourceIndex, ast.SymbolOther, name)
+					chunkRepr.umdDependencyRefs[path] = ref
+					chunkRepr.umdDependencies = append(chunkRepr.umdDependencies, umdDependency{path: path, ref: ref})
+
+					// Warn about guessing the global variable, but only once per path
+					if external := c.options.UMDExternals[path]; len(external.Global) == 0 && !warnedPaths[path] {
+						warnedPaths[path] = true
+						c.log.AddIDWithNotes(logger.MsgID_Bundler_MissingUMDGlobal, logger.Warning, file.LineColumnTracker(), record.Range,
+							fmt.Sprintf("Guessing the global variable %q for the external module %q", name, path),
+							[]logger.MsgData{{Text: "You can use the \"umdExternals\" setting to configure the global variable " +
+								"that the \"umd\" format uses for this module when there is no module loader."}})
+					}
+				}
+			}
+		}
+	}
+}
+
+// Chunks in the UMD format look like this:
+//
+//	(function(root, factory) {
+//	  if (typeof define === "function" && define.amd) {
+//	    define(["react"], factory);
+//	  } else if (typeof module === "object" && module.exports) {
+//	    module.exports = factory(require("react"));
+//	  } else {
+//	    root.MyLib = factory(root.React);
+//	  }
+//	})(typeof self !== "undefined" ? self : this, function(react) {
+//	  ...
+//	});
+func (c *linkerContext) generateUMDPrefix(chunk *chunkInfo, r renamer.Renamer) string {
+	chunkRepr := chunk.chunkRepr.(*chunkReprJS)
+	space := " "
+	comma := ", "
+	newline := "\n"
+	indent := "  "
+	if c.options.MinifyWhitespace {
+		space = ""
+		comma = ","
+		newline = ""
+		indent = ""
+	}
+
+	// Each way of loading the code uses a different name for each dependency
+	var amd, cjs, globals []string
+	for _, dep := range chunkRepr.umdDependencies {
+		external := c.options.UMDExternals[dep.path]
+		amdID := dep.path
+		if external.AMD != "" {
+			amdID = external.AMD
+		}
+		cjsName := dep.path
+		if external.CommonJS != "" {
+			cjsName = external.CommonJS
+		}
+		global := external.Global
+		if len(global) == 0 {
+			global = []string{js_ast.GenerateNonUniqueNameFromPath(dep.path)}
+		}
+		amd = append(amd, string(helpers.QuoteForJSON(amdID, c.options.ASCIIOnly)))
+		cjs = append(cjs, fmt.Sprintf("require(%s)", helpers.QuoteForJSON(cjsName, c.options.ASCIIOnly)))
+		globals = append(globals, c.globalPropertyPath("root", global))
+	}
+
+	var sb strings.Builder
+	sb.WriteString("(function(root," + space + "factory)" + space + "{" + newline)
+	sb.WriteString(indent + "if" + space + "(typeof define" + space + "===" + space + "\"function\"" + space + "&&" + space + "define.amd)" + space + "{" + newline)
+	sb.WriteString(indent + indent + "define([" + strings.Join(amd, comma) + "]," + space + "factory);" + newline)
+	sb.WriteString(indent + "}" + space + "else if" + space + "(typeof module" + space + "===" + space + "\"object\"" + space + "&&" + space + "module.exports)" + space + "{" + newline)
+	sb.WriteString(indent + indent + "module.exports" + space + "=" + space + "factory(" + strings.Join(cjs, comma) + ");" + newline)
+	sb.WriteString(indent + "}" + space + "else" + space + "{" + newline)
+	factory := "factory(" + strings.Join(globals, comma) + ")"
+	if globalName := c.options.GlobalName; len(globalName) > 0 {
+		// Create any missing parent objects of the global name
+		for i := 1; i < len(globalName); i++ {
+			parent := c.globalPropertyPath("root", globalName[:i])
+			sb.WriteString(indent + indent + parent + space + "=" + space + parent + space + "||" + space + "{};" + newline)
+		}
+		sb.WriteString(indent + indent + c.globalPropertyPath("root", globalName) + space + "=" + space + factory + ";" + newline)
+	} else {
+		sb.WriteString(indent + indent + factory + ";" + newline)
+	}
+	sb.WriteString(indent + "}" + newline)
+	sb.WriteString("})(typeof self" + space + "!==" + space + "\"undefined\"" + space + "?" + space + "self" + space + ":" + space + "this," + space + "function(")
+	for i, dep := range chunkRepr.umdDependencies {
+		if i > 0 {
+			sb.WriteString(comma)
+		}
+		sb.WriteString(r.NameForSymbol(dep.ref))
+	}
+	sb.WriteString(")" + space + "{" + newline)
+	return sb.String()
+}
+
+// This generates "root.a.b" or "root[\"a-b\"]" for the path ["a", "b"]
+func (c *linkerContext) globalPropertyPath(root string, path []string) string {
+	text := root
+	for _, name := range path {
+		text += c.propertyAccess(name)
+	}
+	return text
+}
+
+// The code for each file is indented when it's wrapped in a function
+func (c *linkerContext) indentForOutputFormat() int {
+	switch c.options.OutputFormat {
+	case config.FormatIIFE, config.FormatUMD:
+		return 1
+	case config.FormatSystem:
+		// "System.register([], function() { return { execute: function() {"
+		return 3
+	}
+	return 0
+}
+
+// The SystemJS format doesn't have live bindings. Instead, the new value of an
+// export is passed to "exports()" each time it changes. This returns the export
+// names for each symbol exported from this chunk so that the printer can wrap
+// assignments to these symbols.
+func (c *linkerContext) computeSystemLiveExports(chunk *chunkInfo) map[ast.Ref][]string {
+	chunkRepr := chunk.chunkRepr.(*chunkReprJS)
+	liveExports := make(map[ast.Ref][]string)
+
+	// Exports to other chunks
+	for ref, alias := range chunkRepr.exportsToOtherChunks {
+		ref = ast.FollowSymbols(c.graph.Symbols, ref)
+		liveExports[ref] = append(liveExports[ref], alias)
+	}
+
+	// Exports from the entry point
+	if chunk.isEntryPoint {
+		if repr := c.graph.Files[chunk.sourceIndex].InputFile.Repr.(*graph.JSRepr); repr.Meta.Wrap != graph.WrapCJS {
+			for _, alias := range repr.Meta.SortedAndFilteredExportAliases {
+				export := repr.Meta.ResolvedExports[alias]
+				if importData, ok := c.graph.Files[export.SourceIndex].InputFile.Repr.(*graph.JSRepr).Meta.ImportsToBind[export.Ref]; ok {
+					export.Ref = importData.Ref
+				}
+
+				// Exports of imports from CommonJS modules are copied into a new
+				// variable by the entry point tail, so they never change
+				if c.graph.Symbols.Get(export.Ref).NamespaceAlias != nil {
+					continue
+				}
+				ref := ast.FollowSymbols(c.graph.Symbols, export.Ref)
+				liveExports[ref] = append(liveExports[ref], alias)
+			}
+		}
+	}
+
+	// Sort for determinism since map iteration order is random
+	for _, aliases := range liveExports {
+		sort.Strings(aliases)
+	}
+	return liveExports
+}
+
+// Chunks in the SystemJS format look like this:
+//
+//	System.register(["./chunk.js"], function(exports, module) {
+//	  "use strict";
+//	  var foo;
+//	  ...
+//	  return {
+//	    setters: [function(m) {
+//	      foo = m.foo;
+//	    }],
+//	    execute: function() {
+//	      ...
+//	    }
+//	  };
+//	});
+//
+// Import statements have been removed by the printer. Each imported path is
+// a dependency and each imported binding is assigned by a setter function.
+// This returns the code before and after the hoisted top-level declarations.
+func (c *linkerContext) generateSystemRegisterPrefix(chunk *chunkInfo, systemImports []js_printer.SystemImport) (string, string) {
+	space := " "
+	comma := ", "
+	newline := "\n"
+	indent := "  "
+	if c.options.MinifyWhitespace {
+		space = ""
+		comma = ","
+		newline = ""
+		indent = ""
+	}
+
+	// Merge imports of the same path together since each dependency only has
+	// a single setter function
+	var deps []js_printer.SystemImport
+	depIndices := make(map[string]int)
+	for _, systemImport := range systemImports {
+		if i, ok := depIndices[systemImport.Path]; ok {
+			deps[i].Bindings = append(deps[i].Bindings, systemImport.Bindings...)
+			deps[i].IsExportStar = deps[i].IsExportStar || systemImport.IsExportStar
+		} else {
+			depIndices[systemImport.Path] = len(deps)
+			deps = append(deps, js_printer.SystemImport{
+				Path:         systemImport.Path,
+				Bindings:     append([]js_printer.SystemImportBinding{}, systemImport.Bindings...),
+				IsExportStar: systemImport.IsExportStar,
+			})
+		}
+	}
+
+	// Avoid generating temporary names that are the same as an imported name
+	usedNames := make(map[string]bool)
+	for _, dep := range deps {
+		for _, binding := range dep.Bindings {
+			usedNames[binding.Name] = true
+		}
+	}
+	tempName := func(name string) string {
+		for i := 2; usedNames[name]; i++ {
+			name = fmt.Sprintf("%s%d", name[:1], i)
+		}
+		return name
+	}
+	moduleName := tempName("m")
+	exportsName := tempName("e")
+	keyName := tempName("k")
+
+	var sb strings.Builder
+	sb.WriteString("System.register([")
+	for i, dep := range deps {
+		if i > 0 {
+			sb.WriteString(comma)
+		}
+		sb.Write(helpers.QuoteForJSON(dep.Path, c.options.ASCIIOnly))
+	}
+	sb.WriteString("]," + space + "function(exports," + space + "module)" + space + "{" + newline)
+	sb.WriteString(indent + "\"use strict\";" + newline)
+
+	// Declare the imported bindings in the outer function so that both the
+	// setters and the code in "execute" can reference them
+	isFirst := true
+	for _, dep := range deps {
+		for _, binding := range dep.Bindings {
+			if isFirst {
+				sb.WriteString(indent + "var ")
+				isFirst = false
+			} else {
+				sb.WriteString(comma)
+			}
+			sb.WriteString(binding.Name)
+		}
+	}
+	if !isFirst {
+		sb.WriteString(";" + newline)
+	}
+
+	// The declarations hoisted out of "execute" go between these two strings
+	prefix := sb.String()
+	sb.Reset()
+
+	sb.WriteString(indent + "return" + space + "{" + newline)
+	if len(deps) > 0 {
+		sb.WriteString(indent + indent + "setters:" + space + "[")
+		for i, dep := range deps {
+			if i > 0 {
+				sb.WriteString(comma)
+			}
+			if len(dep.Bindings) == 0 && !dep.IsExportStar {
+				sb.WriteString("null")
+				continue
+			}
+			inner := indent + indent + indent
+			sb.WriteString("function(" + moduleName + ")" + space + "{" + newline)
+			for _, binding := range dep.Bindings {
+				sb.WriteString(inner + binding.Name + space + "=" + space + moduleName)
+				if binding.Alias != "*" {
+					sb.WriteString(c.propertyAccess(binding.Alias))
+				}
+				sb.WriteString(";" + newline)
+			}
+			if dep.IsExportStar {
+				// "var e = {}; for (var k in m) if (k !== 'default') e[k] = m[k]; exports(e);"
+				sb.WriteString(inner + "var " + exportsName + space + "=" + space + "{};" + newline)
+				sb.WriteString(inner + "for" + space + "(var " + keyName + " in " + moduleName + ")" + space + "if" + space + "(" + keyName + space + "!==" + space + "\"default\"")
+				if chunk.isEntryPoint {
+					repr := c.graph.Files[chunk.sourceIndex].InputFile.Repr.(*graph.JSRepr)
+					for _, alias := range repr.Meta.SortedAndFilteredExportAliases {
+						if alias == "default" {
+							continue
+						}
+						sb.WriteString(space + "&&" + space + keyName + space + "!==" + space)
+						sb.Write(helpers.QuoteForJSON(alias, c.options.ASCIIOnly))
+					}
+				}
+				sb.WriteString(")" + space + exportsName + "[" + keyName + "]" + space + "=" + space + moduleName + "[" + keyName + "];" + newline)
+				sb.WriteString(inner + "exports(" + exportsName + ");" + newline)
+			}
+			sb.WriteString(indent + indent + "}")
+		}
+		sb.WriteString("]," + newline)
+	}
+	sb.WriteString(indent + indent + "execute:" + space)
+	if c.isSystemExecuteAsync(chunk) {
+		sb.WriteString("async ")
+	}
+	sb.WriteString("function()" + space + "{" + newline)
+	return prefix, sb.String()
+}
+
+func (c *linkerContext) propertyAccess(name string) string {
+	if js_printer.CanEscapeIdentifier(name, c.options.UnsupportedJSFeatures, c.options.ASCIIOnly) {
+		if c.options.ASCIIOnly {
+			name = string(js_printer.QuoteIdentifier(nil, name, c.options.UnsupportedJSFeatures))
+		}
+		return "." + name
+	}
+	return fmt.Sprintf("[%s]", helpers.QuoteForJSON(name, c.options.ASCIIOnly))
+}
+
+// The "execute" function must be async if any code in the chunk uses a
+// top-level await, since it's no longer at the top level of the module
+func (c *linkerContext) isSystemExecuteAsync(chunk *chunkInfo) bool {
+	for _, sourceIndex := range chunk.chunkRepr.(*chunkReprJS).filesInChunkInOrder {
+		if repr, ok := c.graph.Files[sourceIndex].InputFile.Repr.(*graph.JSRepr); ok &&
+			(repr.Meta.IsAsyncOrHasAsyncDependency || repr.AST.LiveTopLevelAwaitKeyword.Len > 0) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *linkerContext) generateGlobalNamePrefix() string {
 	var text string
 	globalName := c.options.GlobalName
@@ -6080,6 +8069,26 @@ type compileResultCSS struct {
 	hasCharset  bool
 }
 
+// This walks the files in the chunk in CSS import order (not in discovery
+// order) so that when the same "@custom-media" name is defined more than once,
+// the definition that comes last in the final CSS file is the one that wins.
+func (c *linkerContext) collectCustomMediaDefinitions(order []cssImportOrder) map[string]*css_ast.RAtCustomMedia {
+	definitions := make(map[string]*css_ast.RAtCustomMedia)
+	for _, entry := range order {
+		if entry.kind != cssImportSourceIndex {
+			continue
+		}
+		repr := c.graph.Files[entry.sourceIndex].InputFile.Repr.(*graph.CSSRepr)
+		for _, rule := range repr.AST.Rules {
+			if r, ok := rule.Data.(*css_ast.RAtCustomMedia); ok {
+				// Later definitions replace earlier ones
+				definitions[r.Name] = r
+			}
+		}
+	}
+	return definitions
+}
+
 func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.WaitGroup) {
 	defer c.recoverInternalError(chunkWaitGroup, runtime.SourceIndex)
 
@@ -6097,6 +8106,11 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 	compileResults := make([]compileResultCSS, len(chunkRepr.importsInChunkInOrder))
 	dataForSourceMaps := c.dataForSourceMaps()
 
+	var customMediaDefinitions map[string]*css_ast.RAtCustomMedia
+	if c.options.UnsupportedCSSFeatures.Has(compat.CustomMedia) {
+		customMediaDefinitions = c.collectCustomMediaDefinitions(chunkRepr.importsInChunkInOrder)
+	}
+
 	// Note: This contains placeholders instead of what the placeholders are
 	// substituted with. That should be fine though because this should only
 	// ever be used for figuring out how many "../" to add to a relative path
@@ -6206,6 +8220,21 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 
 			rules, ast.ImportRecords = wrapRulesWithConditions(rules, ast.ImportRecords, entry.conditions, entry.conditionImportRecords)
 
+			// Substitute "@value" names that were imported from other files
+			if len(ast.ImportedValues) > 0 {
+				rules, ast.ImportRecords = c.substituteImportedICSSValues(entry.sourceIndex, rules, ast.ImportRecords)
+			}
+
+			// Remove rules for local names that are never used from JavaScript
+			if len(c.unusedLocalCSSNames) > 0 {
+				rules = css_parser.RemoveRulesWithUnusedLocalNames(rules, c.unusedLocalCSSNames)
+			}
+
+			// Substitute "@custom-media" definitions into "@media" rules
+			if c.options.UnsupportedCSSFeatures.Has(compat.CustomMedia) {
+				rules = css_parser.SubstituteCustomMedia(rules, customMediaDefinitions, c.options.MinifyWhitespace)
+			}
+
 			// Remove top-level duplicate rules across files
 			if c.options.MinifySyntax {
 				rules = remover.RemoveDuplicateRulesInPlace(entry.sourceIndex, rules, ast.ImportRecords)
@@ -6215,6 +8244,22 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 			asts[i] = ast
 		}
 	}
+
+	// Cascade layers can only be lowered once the order of all layers in the
+	// output file is known, which is only the case after all files are ready
+	if c.options.UnsupportedCSSFeatures.Has(compat.CascadeLayers) {
+		css_parser.LowerCascadeLayers(asts)
+	}
+
+	// Merge style rules across files now that they have all been joined together
+	if c.options.MinifySyntax {
+		css_parser.MergeRules(asts, css_parser.MergeRulesOptions{
+			Symbols:                 c.graph.Symbols,
+			UnsupportedCSSFeatures:  c.options.UnsupportedCSSFeatures,
+			MinifyWhitespace:        c.options.MinifyWhitespace,
+			AllowMergingAcrossFiles: c.options.SourceMap == config.SourceMapNone,
+		})
+	}
 	timer.End("Prepare CSS ASTs")
 
 	// Generate CSS for each file in parallel
@@ -6416,7 +8461,7 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 
 	if c.options.SourceMap != config.SourceMapNone {
 		timer.Begin("Generate source map")
-		canHaveShifts := chunk.intermediateOutput.pieces != nil
+		canHaveShifts := chunk.intermediateOutput.pieces != nil || c.hasOnOutputCallbacks()
 		chunk.outputSourceMap = c.generateSourceMapForChunk(compileResultsForSourceMap, chunkAbsDir, dataForSourceMaps, canHaveShifts)
 		timer.End("Generate source map")
 	}
@@ -6452,10 +8497,125 @@ func (c *linkerContext) generateChunkCSS(chunkIndex int, chunkWaitGroup *sync.Wa
 		}
 	}
 
+	c.runOnOutputCallbacks(chunk)
 	c.generateIsolatedHashInParallel(chunk)
 	chunkWaitGroup.Done()
 }
 
+func (c *linkerContext) generateChunkHTML(chunkIndex int, chunkWaitGroup *sync.WaitGroup) {
+	defer c.recoverInternalError(chunkWaitGroup, runtime.SourceIndex)
+
+	chunk := &c.chunks[chunkIndex]
+	file := &c.graph.Files[chunk.sourceIndex]
+	repr := file.InputFile.Repr.(*graph.HTMLRepr)
+	contents := file.InputFile.Source.Contents
+	var jsonMetadataImports []string
+
+	// HTML files aren't transformed. The only change is that the URLs in each
+	// tag are replaced with the unique key for the corresponding chunk, which
+	// is substituted with the final path to that chunk later on.
+	j := helpers.Joiner{}
+	end := 0
+	for _, tag := range repr.AST.Tags {
+		record := &repr.AST.ImportRecords[tag.ImportRecordIndex]
+		if !record.SourceIndex.IsValid() {
+			continue
+		}
+		otherChunk := &c.chunks[c.graph.Files[record.SourceIndex.GetIndex()].EntryPointChunkIndex]
+		j.AddString(contents[end:tag.Range.Loc.Start])
+
+		// Scripts may need other output files that the browser wouldn't otherwise
+		// know about. Insert tags for those in front of the script tag.
+		if tag.Kind == html_ast.TagModuleScript {
+			if otherRepr, ok := otherChunk.chunkRepr.(*chunkReprJS); ok {
+				indent := "\n" + indentationBefore(contents, tag.Range.Loc.Start)
+				if otherRepr.hasCSSChunk {
+					cssChunk := &c.chunks[otherRepr.cssChunkIndex]
+					j.AddString(fmt.Sprintf("<link rel=\"stylesheet\" href=\"%s\">%s", cssChunk.uniqueKey, indent))
+					jsonMetadataImports = append(jsonMetadataImports, chunkMetadataImport(cssChunk.uniqueKey, ast.ImportHTMLLink, c.options.ASCIIOnly))
+				}
+				for _, preloadChunkIndex := range c.findStaticallyImportedChunks(c.graph.Files[record.SourceIndex.GetIndex()].EntryPointChunkIndex) {
+					preloadChunk := &c.chunks[preloadChunkIndex]
+					j.AddString(fmt.Sprintf("<link rel=\"modulepreload\" href=\"%s\">%s", preloadChunk.uniqueKey, indent))
+					jsonMetadataImports = append(jsonMetadataImports, chunkMetadataImport(preloadChunk.uniqueKey, ast.ImportHTMLLink, c.options.ASCIIOnly))
+				}
+			}
+		}
+
+		j.AddString(contents[tag.Range.Loc.Start:tag.ValueRange.Loc.Start])
+		j.AddString("\"")
+		j.AddString(otherChunk.uniqueKey)
+		j.AddString("\"")
+		jsonMetadataImports = append(jsonMetadataImports, chunkMetadataImport(otherChunk.uniqueKey, record.Kind, c.options.ASCIIOnly))
+		end = int(tag.ValueRange.End())
+	}
+	j.AddString(contents[end:])
+	chunk.intermediateOutput = c.breakJoinerIntoPieces(j)
+
+	// The final output size is not known until the final import paths are
+	// substituted into the output pieces generated above
+	if c.options.NeedsMetafile {
+		jMeta := helpers.Joiner{}
+		jMeta.AddString("{\n      \"imports\": [")
+		for i, json := range jsonMetadataImports {
+			if i > 0 {
+				jMeta.AddString(",")
+			}
+			jMeta.AddString(json)
+		}
+		if len(jsonMetadataImports) > 0 {
+			jMeta.AddString("\n      ")
+		}
+		inputPath := helpers.QuoteForJSON(file.InputFile.Source.PrettyPaths.Select(c.options.MetafilePathStyle), c.options.ASCIIOnly)
+		jMeta.AddString(fmt.Sprintf("],\n      \"entryPoint\": %s,\n      \"inputs\": {", inputPath))
+		chunk.jsonMetadataChunkCallback = func(finalOutputSize int) helpers.Joiner {
+			jMeta.AddString(fmt.Sprintf("\n        %s: {\n          \"bytesInOutput\": %d\n        }\n      },\n      \"bytes\": %d\n    }",
+				inputPath, finalOutputSize, finalOutputSize))
+			return jMeta
+		}
+	}
+
+	c.runOnOutputCallbacks(chunk)
+	c.generateIsolatedHashInParallel(chunk)
+	chunkWaitGroup.Done()
+}
+
+// This returns the chunks that are imported by the given chunk using static
+// imports, either directly or indirectly, in the order they are first seen.
+// These can be preloaded since they will definitely be needed.
+func (c *linkerContext) findStaticallyImportedChunks(chunkIndex uint32) (result []uint32) {
+	visited := map[uint32]bool{chunkIndex: true}
+	var visit func(uint32)
+	visit = func(chunkIndex uint32) {
+		for _, chunkImport := range c.chunks[chunkIndex].crossChunkImports {
+			if chunkImport.importKind != ast.ImportDynamic && !visited[chunkImport.chunkIndex] {
+				visited[chunkImport.chunkIndex] = true
+				result = append(result, chunkImport.chunkIndex)
+				visit(chunkImport.chunkIndex)
+			}
+		}
+	}
+	visit(chunkIndex)
+	return
+}
+
+func chunkMetadataImport(uniqueKey string, kind ast.ImportKind, asciiOnly bool) string {
+	return fmt.Sprintf("\n        {\n          \"path\": %s,\n          \"kind\": %s\n        }",
+		helpers.QuoteForJSON(uniqueKey, asciiOnly),
+		helpers.QuoteForJSON(kind.StringForMetafile(), asciiOnly))
+}
+
+// Returns the whitespace in front of the given offset if it's the first
+// non-whitespace character on its line. Otherwise this returns "".
+func indentationBefore(contents string, offset int32) string {
+	lineStart := strings.LastIndexAny(contents[:offset], "\r\n") + 1
+	indent := contents[lineStart:offset]
+	if strings.TrimLeft(indent, " \t") != "" {
+		return ""
+	}
+	return indent
+}
+
 func wrapRulesWithConditions(
 	rules []css_ast.Rule, importRecords []ast.ImportRecord,
 	conditions []css_ast.ImportConditions, conditionImportRecords []ast.ImportRecord,
@@ -6789,6 +8949,216 @@ func (c *linkerContext) breakOutputIntoPieces(output []byte) intermediateOutput
 	return intermediateOutput{pieces: pieces}
 }
 
+func (c *linkerContext) hasOnOutputCallbacks() bool {
+	for _, plugin := range c.options.Plugins {
+		if len(plugin.OnOutput) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Plugins can transform each chunk after it has been linked. This happens
+// before the chunk is hashed so that the hash in the file name reflects the
+// transformed code. The unique keys for other output files are still in the
+// code at this point, so the final paths are substituted in afterward. Any
+// source maps returned by plugins are chained onto the chunk's source map.
+func (c *linkerContext) runOnOutputCallbacks(chunk *chunkInfo) {
+	if !c.hasOnOutputCallbacks() {
+		return
+	}
+
+	var contents string
+	if chunk.intermediateOutput.pieces == nil {
+		contents = string(chunk.intermediateOutput.joiner.Done())
+	} else {
+		sb := strings.Builder{}
+		for _, piece := range chunk.intermediateOutput.pieces {
+			sb.Write(piece.data)
+			switch piece.kind {
+			case outputPieceAssetIndex:
+				sb.WriteString(c.graph.Files[piece.index].InputFile.UniqueKeyForAdditionalFile)
+			case outputPieceChunkIndex:
+				sb.WriteString(c.chunks[piece.index].uniqueKey)
+			}
+		}
+		contents = sb.String()
+	}
+
+	// Note: The source map is always split into pieces when there are "OnOutput"
+	// callbacks. Chaining source maps only changes the mappings in the middle.
+	hasSourceMap := c.options.SourceMap != config.SourceMapNone && chunk.outputSourceMap.HasContent()
+	var sourceMap string
+	if hasSourceMap {
+		sourceMap = string(chunk.outputSourceMap.Finalize([]sourcemap.SourceMapShift{{}}))
+	}
+
+	args := config.OnOutputArgs{
+		Path: c.fs.Join(c.options.AbsOutputDir, config.TemplateToString(chunk.finalTemplate)),
+	}
+	if chunk.isEntryPoint {
+		args.EntryPoint = c.graph.Files[chunk.sourceIndex].InputFile.Source.KeyPath.Text
+	}
+	switch chunkRepr := chunk.chunkRepr.(type) {
+	case *chunkReprJS:
+		for _, sourceIndex := range chunkRepr.filesInChunkInOrder {
+			if sourceIndex != runtime.SourceIndex {
+				args.Modules = append(args.Modules, c.graph.Files[sourceIndex].InputFile.Source.KeyPath.Text)
+			}
+		}
+	case *chunkReprCSS:
+		for _, entry := range chunkRepr.importsInChunkInOrder {
+			if entry.kind == cssImportSourceIndex {
+				args.Modules = append(args.Modules, c.graph.Files[entry.sourceIndex].InputFile.Source.KeyPath.Text)
+			}
+		}
+	case *chunkReprHTML:
+		args.Modules = []string{args.EntryPoint}
+	}
+
+	didChange := false
+	var changedBy []string
+	for _, plugin := range c.options.Plugins {
+		for _, onOutput := range plugin.OnOutput {
+			if !onOutput.Filter.MatchString(args.Path) {
+				continue
+			}
+			args.Contents = contents
+			args.SourceMap = sourceMap
+			result := onOutput.Callback(args)
+			pluginName := result.PluginName
+			if pluginName == "" {
+				pluginName = plugin.Name
+			}
+
+			// An error stops the remaining callbacks from running, so the changes
+			// from earlier callbacks never make it into the output file either
+			var notes []logger.MsgData
+			if len(changedBy) > 0 {
+				what := "plugin"
+				if len(changedBy) > 1 {
+					what = "plugins"
+				}
+				notes = append(notes, logger.MsgData{Text: fmt.Sprintf(
+					"The changes made to this file by earlier \"onOutput\" callbacks from %s %s were discarded",
+					what, helpers.StringArrayToQuotedCommaSeparatedString(changedBy))})
+			}
+
+			didLogError := false
+			for _, msg := range result.Msgs {
+				if msg.PluginName == "" {
+					msg.PluginName = pluginName
+				}
+				if msg.Kind == logger.Error {
+					msg.Notes = append(msg.Notes, notes...)
+					didLogError = true
+				}
+				c.log.AddMsg(msg)
+			}
+			if didLogError {
+				return
+			}
+			if result.ThrownError != nil {
+				c.log.AddMsg(logger.Msg{
+					PluginName: pluginName,
+					Kind:       logger.Error,
+					Data: logger.MsgData{
+						Text:       result.ThrownError.Error(),
+						UserDetail: result.ThrownError,
+					},
+					Notes: notes,
+				})
+				return
+			}
+
+			// If new code is returned without a source map, the code is assumed
+			// to have the same layout and the existing source map is kept as-is
+			changed := false
+			if hasSourceMap && result.SourceMap != nil {
+				mappings, ok := c.chainSourceMaps(sourceMap, *result.SourceMap, args.Path, pluginName, notes)
+				if !ok {
+					return
+				}
+				prefix := chunk.outputSourceMap.Prefix
+				suffix := chunk.outputSourceMap.Suffix
+				sourceMap = string(prefix) + string(mappings) + string(suffix)
+				changed = true
+			}
+			if result.Contents != nil {
+				contents = *result.Contents
+				changed = true
+			}
+			if changed {
+				isNew := true
+				for _, name := range changedBy {
+					if name == pluginName {
+						isNew = false
+						break
+					}
+				}
+				if isNew {
+					changedBy = append(changedBy, pluginName)
+				}
+				didChange = true
+			}
+		}
+	}
+
+	if didChange {
+		j := helpers.Joiner{}
+		j.AddString(contents)
+		chunk.intermediateOutput = c.breakJoinerIntoPieces(j)
+		if hasSourceMap {
+			// Make new allocations since "Finalize" may append to "Prefix" in place
+			prefixLen := len(chunk.outputSourceMap.Prefix)
+			suffixLen := len(chunk.outputSourceMap.Suffix)
+			chunk.outputSourceMap = sourcemap.SourceMapPieces{
+				Prefix:   []byte(sourceMap[:prefixLen]),
+				Mappings: []byte(sourceMap[prefixLen : len(sourceMap)-suffixLen]),
+				Suffix:   []byte(sourceMap[len(sourceMap)-suffixLen:]),
+			}
+		}
+	}
+}
+
+// This maps each location in the output of a plugin back through the chunk's
+// current source map and returns the new "mappings" field. The indices into
+// the "sources" and "names" arrays of the current source map are unchanged.
+func (c *linkerContext) chainSourceMaps(current string, fromPlugin string, path string, pluginName string, extraNotes []logger.MsgData) ([]byte, bool) {
+	parse := func(contents string) (*sourcemap.SourceMap, []logger.Msg) {
+		log := logger.NewDeferLog(logger.DeferLogNoVerboseOrDebug, nil)
+		sm := js_parser.ParseSourceMap(log, logger.Source{
+			KeyPath:     logger.Path{Text: path + ".map"},
+			PrettyPaths: logger.PrettyPaths{Abs: path + ".map", Rel: path + ".map"},
+			Contents:    contents,
+		})
+		return sm, log.Done()
+	}
+
+	outer, msgs := parse(fromPlugin)
+	if outer == nil && len(msgs) > 0 {
+		notes := make([]logger.MsgData, 0, len(msgs)+len(extraNotes))
+		for _, msg := range msgs {
+			notes = append(notes, logger.MsgData{Text: msg.Data.Text})
+		}
+		notes = append(notes, extraNotes...)
+		prettyPaths := resolver.MakePrettyPaths(c.fs, logger.Path{Text: path, Namespace: "file"})
+		c.log.AddMsg(logger.Msg{
+			PluginName: pluginName,
+			Kind:       logger.Error,
+			Data:       logger.MsgData{Text: fmt.Sprintf("Invalid source map returned for %q", prettyPaths.Select(c.options.LogPathStyle))},
+			Notes:      notes,
+		})
+		return nil, false
+	}
+
+	var mappings []sourcemap.Mapping
+	if inner, _ := parse(current); inner != nil && outer != nil {
+		mappings = sourcemap.ChainMappings(inner, outer)
+	}
+	return sourcemap.EncodeMappings(mappings), true
+}
+
 func (c *linkerContext) generateIsolatedHashInParallel(chunk *chunkInfo) {
 	// Compute the hash in parallel. This is a speedup when it turns out the hash
 	// isn't needed (well, as long as there are threads to spare).
@@ -54,7 +54,9 @@ const (
 	MsgID_CSS_JSCommentInCSS
 	MsgID_CSS_UndefinedComposesFrom
 	MsgID_CSS_UnsupportedAtCharset
+	MsgID_CSS_UnsupportedAtMedia
 	MsgID_CSS_UnsupportedAtNamespace
+	MsgID_CSS_UnsupportedAtScope
 	MsgID_CSS_UnsupportedCSSProperty
 	MsgID_CSS_UnsupportedCSSNesting
 
@@ -65,6 +67,7 @@ const (
 	MsgID_Bundler_IgnoredBareImport
 	MsgID_Bundler_IgnoredDynamicImport
 	MsgID_Bundler_ImportIsUndefined
+	MsgID_Bundler_MissingUMDGlobal
 	MsgID_Bundler_RequireResolveNotExternal
 
 	// Source maps
@@ -180,8 +183,12 @@ func StringToMsgIDs(str string, logLevel LogLevel, overrides map[MsgID]LogLevel)
 		overrides[MsgID_CSS_UndefinedComposesFrom] = logLevel
 	case "unsupported-@charset":
 		overrides[MsgID_CSS_UnsupportedAtCharset] = logLevel
+	case "unsupported-@media":
+		overrides[MsgID_CSS_UnsupportedAtMedia] = logLevel
 	case "unsupported-@namespace":
 		overrides[MsgID_CSS_UnsupportedAtNamespace] = logLevel
+	case "unsupported-@scope":
+		overrides[MsgID_CSS_UnsupportedAtScope] = logLevel
 	case "unsupported-css-property":
 		overrides[MsgID_CSS_UnsupportedCSSProperty] = logLevel
 	case "unsupported-css-nesting":
@@ -200,6 +207,8 @@ func StringToMsgIDs(str string, logLevel LogLevel, overrides map[MsgID]LogLevel)
 		overrides[MsgID_Bundler_IgnoredDynamicImport] = logLevel
 	case "import-is-undefined":
 		overrides[MsgID_Bundler_ImportIsUndefined] = logLevel
+	case "missing-umd-global":
+		overrides[MsgID_Bundler_MissingUMDGlobal] = logLevel
 	case "require-resolve-not-external":
 		overrides[MsgID_Bundler_RequireResolveNotExternal] = logLevel
 
@@ -312,8 +321,12 @@ func MsgIDToString(id MsgID) string {
 		return "undefined-composes-from"
 	case MsgID_CSS_UnsupportedAtCharset:
 		return "unsupported-@charset"
+	case MsgID_CSS_UnsupportedAtMedia:
+		return "unsupported-@media"
 	case MsgID_CSS_UnsupportedAtNamespace:
 		return "unsupported-@namespace"
+	case MsgID_CSS_UnsupportedAtScope:
+		return "unsupported-@scope"
 	case MsgID_CSS_UnsupportedCSSProperty:
 		return "unsupported-css-property"
 	case MsgID_CSS_UnsupportedCSSNesting:
@@ -332,6 +345,8 @@ func MsgIDToString(id MsgID) string {
 		return "ignored-dynamic-import"
 	case MsgID_Bundler_ImportIsUndefined:
 		return "import-is-undefined"
+	case MsgID_Bundler_MissingUMDGlobal:
+		return "missing-umd-global"
 	case MsgID_Bundler_RequireResolveNotExternal:
 		return "require-resolve-not-external"
 
@@ -490,6 +490,16 @@ func (res *Resolver) Resolve(sourceDir string, importPath string, kind ast.Impor
 		}, debugMeta
 	}
 
+	// Root-relative URLs in HTML files such as "<script src="/src/main.js">"
+	// are relative to the directory containing the HTML file (like in Vite)
+	// instead of being relative to the root of the file system
+	if kind.IsFromHTML() && strings.HasPrefix(importPath, "/") {
+		importPath = "." + importPath
+		if r.debugLogs != nil {
+			r.debugLogs.addNote(fmt.Sprintf("Resolving the root-relative URL %q relative to the HTML file", importPath[1:]))
+		}
+	}
+
 	// Fail now if there is no directory to resolve in. This can happen for
 	// virtual modules (e.g. stdin) if a resolve directory is not specified.
 	if sourceDir == "" {
@@ -987,8 +997,9 @@ func (r resolverQuery) resolveWithoutSymlinks(sourceDir string, sourceDirInfo *d
 
 	// Check both relative and package paths for CSS URL tokens, with relative
 	// paths taking precedence over package paths to match Webpack behavior.
+	// HTML attributes are URLs too, so they are handled the same way.
 	isPackagePath := IsPackagePath(importPath)
-	checkRelative := !isPackagePath || r.kind.IsFromCSS()
+	checkRelative := !isPackagePath || r.kind.IsFromCSS() || r.kind.IsFromHTML()
 	checkPackage := isPackagePath
 
 	if checkRelative {
@@ -2327,7 +2338,7 @@ func (r resolverQuery) esmResolveAlgorithm(
 		conditions = r.esmConditionsImport
 	case ast.ImportRequire, ast.ImportRequireResolve:
 		conditions = r.esmConditionsRequire
-	case ast.ImportEntryPoint:
+	case ast.ImportEntryPoint, ast.ImportHTMLScript:
 		// Treat entry points as imports instead of requires for consistency with
 		// Webpack and Rollup. More information:
 		//
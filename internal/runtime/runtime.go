@@ -57,9 +57,6 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 	//   __spreadArray
 	//   __spreadArrays
 	//   __values
-	//
-	// Note: The "__objRest" function has a for-of loop which requires ES6, but
-	// transforming destructuring to ES5 isn't even supported so it's ok.
 	text := `
 		var __create = Object.create
 		var __freeze = Object.freeze
@@ -72,15 +69,13 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 		var __getProtoOf = Object.getPrototypeOf
 		var __hasOwnProp = Object.prototype.hasOwnProperty
 		var __propIsEnum = Object.prototype.propertyIsEnumerable
-		var __reflectGet = Reflect.get
-		var __reflectSet = Reflect.set
 
 		var __knownSymbol = (name, symbol) => (symbol = Symbol[name]) ? symbol : Symbol.for('Symbol.' + name)
 		var __typeError = msg => { throw TypeError(msg) }
 
 		export var __pow = Math.pow
 
-		var __defNormalProp = (obj, key, value) => key in obj
+		export var __defNormalProp = (obj, key, value) => key in obj
 			? __defProp(obj, key, {enumerable: true, configurable: true, writable: true, value})
 			: obj[key] = value
 
@@ -167,6 +162,37 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 			return target
 		}
 
+		// For iterating over values when targeting ES5, which is used by lowered
+		// "for-of" loops, array spread, and array destructuring. This falls back to
+		// treating the value as array-like if "Symbol.iterator" doesn't exist.
+		export var __iter = (obj, method) => typeof Symbol === 'function' && (method = obj[Symbol.iterator])
+			? method.call(obj)
+			: (method = 0, { next: () => method < obj.length ? { value: obj[method++], done: false } : { value: void 0, done: true } })
+		export var __toArray = (obj, n) => {
+			if (Array.isArray(obj)) return obj
+			for (var it = __iter(obj), array = [], step; n !== 0 && !(step = it.next()).done; n--) array.push(step.value)
+			if (n === 0 && it.return) it.return()
+			return array
+		}
+		export var __construct = (target, args) => new (Function.prototype.bind.apply(target, [null].concat(args)))()
+
+		// For lowering classes to ES5
+		export var __inherits = (child, parent) => {
+			if (typeof parent !== 'function' && parent !== null)
+				__typeError('Class extends value ' + parent + ' is not a constructor or null')
+			child.prototype = __create(parent && parent.prototype, { constructor: { value: child, writable: true, configurable: true } })
+			if (!parent) return
+			if (Object.setPrototypeOf) Object.setPrototypeOf(child, parent)
+			else if ({ __proto__: [] } instanceof Array) child.__proto__ = parent
+			else for (var key in parent) if (__hasOwnProp.call(parent, key)) child[key] = parent[key]
+		}
+		export var __defMethod = (obj, key, value) => __defProp(obj, key, { value, writable: true, configurable: true })
+		export var __defAccessor = (obj, key, get, set, enumerable, desc) => (
+			desc = { enumerable, configurable: true },
+			get && (desc.get = get),
+			set && (desc.set = set),
+			__defProp(obj, key, desc))
+
 		// This is for lazily-initialized ESM code. This has two implementations, a
 		// compact one for minified code and a verbose one that generates friendly
 		// names in V8's profiler and in stack traces.
@@ -175,6 +201,29 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 		}
 		export var __esmMin = (fn, res) => () => (fn && (res = fn(fn = 0)), res)
 
+		// This is used for "import defer" when bundling. The callback initializes
+		// the module and returns its namespace object, which only happens once a
+		// property of the deferred namespace object is accessed. Symbol keys and
+		// the "then" key never trigger evaluation (so "await" doesn't either). If
+		// "Proxy" is missing, the module is just evaluated immediately instead.
+		export var __deferNS = (fn, ns) => {
+			var get = () => ns || (ns = fn()), skip, target
+			if (typeof Proxy === 'undefined') return get()
+			skip = key => typeof key === 'symbol' || key === 'then'
+			target = __create(null)
+			if (typeof Symbol !== 'undefined') __defProp(target, __knownSymbol('toStringTag'), { value: 'Deferred Module' })
+			return new Proxy(target, {
+				get: (target, key) => skip(key) ? target[key] : get()[key],
+				has: (target, key) => skip(key) ? key in target : key in get(),
+				ownKeys: target => __getOwnPropNames(get()).filter(key => !skip(key)).concat(__getOwnPropSymbols(target)),
+				getOwnPropertyDescriptor: (target, key) => skip(key) ? __getOwnPropDesc(target, key) :
+					key in get() ? { value: ns[key], writable: true, enumerable: true, configurable: true } : void 0,
+				defineProperty: () => false,
+				deleteProperty: () => false,
+				set: () => false,
+			})
+		}
+
 		// Wraps a CommonJS closure and returns a require() function. This has two
 		// implementations, a compact one for minified code and a verbose one that
 		// generates friendly names in V8's profiler and in stack traces.
@@ -183,6 +232,81 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 		}
 		export var __commonJSMin = (cb, mod) => () => (mod || cb((mod = {exports: {}}).exports, mod), mod.exports)
 
+		// This is used instead of "__esm" for hot module replacement. Modules are
+		// stored in a registry by id. Calling this again with the same id (which
+		// is what the code sent by the development server does) replaces the
+		// closure but returns the same "init_*" function as before.
+		var __hmrModules = {}
+		var __hmrStack = []
+		var __hmrCount = 0
+		var __hmrEval
+		export var __esmHot = (id, fn, mod) => {
+			if (mod = __hmrModules[id]) return mod.fn = fn, mod.init
+			mod = __hmrModules[id] = { fn, last: fn, data: {}, parents: {}, order: __hmrCount++ }
+			return mod.init = () => {
+				var parent = __hmrStack[__hmrStack.length - 1], hot
+				if (parent !== void 0) mod.parents[parent] = 1
+				if (mod.fn) {
+					fn = mod.last = mod.fn
+					mod.fn = 0
+					hot = {
+						data: mod.data,
+						accept: cb => { mod.accept = cb || 1 },
+						dispose: cb => { mod.dispose = cb },
+					}
+					__hmrStack.push(id)
+					try {
+						mod.res = fn(hot)
+					} finally {
+						__hmrStack.pop()
+					}
+				}
+				return mod.res
+			}
+		}
+		var __hmrApply = modules => {
+			var seen = {}, queue = [], rerun = [], accepted = [], mod, id, i
+			for (i = 0; i < modules.length; i++) {
+				if (!__hmrModules[id = modules[i].id]) return location.reload()
+				__hmrEval(modules[i].code + '\n//# sourceURL=' + id)
+				queue.push(id)
+			}
+
+			// Walk up the import graph to the closest modules that accept updates
+			while (queue.length) {
+				mod = __hmrModules[id = queue.pop()]
+				if (seen[id]) continue
+				seen[id] = 1
+				rerun.push(mod)
+				if (mod.accept) continue
+				id = Object.keys(mod.parents)
+				if (!id.length) return location.reload()
+				for (i = 0; i < id.length; i++) queue.push(id[i])
+			}
+
+			// Re-run affected modules in their original evaluation order
+			rerun.sort((a, b) => a.order - b.order)
+			for (i = 0; i < rerun.length; i++) {
+				mod = rerun[i]
+				if (typeof mod.accept === 'function') accepted.push(mod.accept)
+				mod.data = {}
+				if (mod.dispose) mod.dispose(mod.data)
+				if (!mod.fn) mod.fn = mod.last
+				mod.accept = mod.dispose = 0
+			}
+			for (i = 0; i < rerun.length; i++) rerun[i].init()
+			for (i = 0; i < accepted.length; i++) accepted[i]()
+		}
+		export var __hmrConnect = (entry, evaluate) => {
+			__hmrEval = evaluate
+			if (typeof EventSource !== 'undefined')
+				new EventSource('/esbuild').addEventListener('hmr', e => {
+					var chunks = JSON.parse(e.data).updated
+					for (var i = 0; i < chunks.length; i++)
+						if (chunks[i].entry === entry) __hmrApply(chunks[i].modules)
+				})
+		}
+
 		// Used to implement ESM exports both for "require()" and "import * as"
 		export var __export = (target, all) => {
 			for (var name in all)
@@ -246,6 +370,12 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 		// to "true", which overwrites any existing export named "__esModule".
 		export var __toCommonJS = mod => __copyProps(__defProp({}, '__esModule', { value: true }), mod)
 
+		// This is used for entry points with lowered top-level await. A promise for
+		// the evaluation of the entry point is stored in the non-enumerable "__tla"
+		// property of its CommonJS exports object, so code that uses "require()" can
+		// wait for the exports to be filled in: "await require('./out.js').__tla"
+		export var __tla = (mod, promise) => __defProp(mod, '__tla', { value: promise.then(() => mod) })
+
 		// For TypeScript experimental decorators
 		// - kind === undefined: class
 		// - kind === 1: method, parameter
@@ -361,11 +491,29 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 		`
 	}
 
-	text += `
-		// For "super" property accesses
-		export var __superGet = (cls, obj, key) => __reflectGet(__getProtoOf(cls), key, obj)
-		export var __superSet = (cls, obj, key, val) => (__reflectSet(__getProtoOf(cls), key, val, obj), val)
-	`
+	// For "super" property accesses. The ES5 versions can't use "Reflect" so
+	// they walk the prototype chain manually to find the property descriptor.
+	if !unsupportedJSFeatures.Has(compat.Class) {
+		text += `
+			var __reflectGet = Reflect.get
+			var __reflectSet = Reflect.set
+			export var __superGet = (cls, obj, key) => __reflectGet(__getProtoOf(cls), key, obj)
+			export var __superSet = (cls, obj, key, val) => (__reflectSet(__getProtoOf(cls), key, val, obj), val)
+		`
+	} else {
+		text += `
+			var __superDesc = (cls, key, proto, desc) => {
+				for (proto = __getProtoOf(cls); proto && !(desc = __getOwnPropDesc(proto, key)); ) proto = __getProtoOf(proto)
+				return desc
+			}
+			export var __superGet = (cls, obj, key, desc) => (desc = __superDesc(cls, key))
+				? desc.get ? desc.get.call(obj) : desc.value
+				: void 0
+			export var __superSet = (cls, obj, key, val, desc) => (
+				(desc = __superDesc(cls, key)) && desc.set ? desc.set.call(obj, val) : obj[key] = val,
+				val)
+		`
+	}
 
 	if !unsupportedJSFeatures.Has(compat.ObjectAccessors) {
 		text += `
@@ -387,8 +535,130 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 		// For lowering tagged template literals
 		export var __template = (cooked, raw) => __freeze(__defProp(cooked, 'raw', { value: __freeze(raw || cooked.slice()) }))
 
+		// For lowering named capture groups in regular expressions. The names map
+		// to the indices of the numbered capture groups that replaced them. The
+		// groups are added by patching "exec" on the instance. "replace" is also
+		// patched since "$<name>" and the "groups" argument need native support,
+		// and so is "matchAll" since it uses a copy of the regular expression.
+		export var __namedGroups = (regExp, names) => {
+			var exec = regExp.exec, replace, matchAll, groupsFor = match => {
+				var groups = __create(null), name
+				for (name in names) groups[name] = match[names[name]]
+				return groups
+			}
+			regExp.exec = function (str) {
+				var match = exec.call(this, str)
+				if (match) match.groups = groupsFor(match)
+				return match
+			}
+			if (typeof Symbol === 'function' && (replace = Symbol.replace)) {
+				// "$<name>" => "$01" and "(match, p1, offset, str)" => "(match, p1, offset, str, groups)"
+				regExp[replace] = function (str, replacement) {
+					return RegExp.prototype[replace].call(this, str, typeof replacement === 'function'
+						? function () {
+							var args = [].slice.call(arguments)
+							if (typeof args[args.length - 1] !== 'object') args.push(groupsFor(args))
+							return replacement.apply(this, args)
+						}
+						: (replacement + '').replace(/\$(\$|<([^>]*)>)/g, (text, _, name) => name === void 0 ? text :
+							__hasOwnProp.call(names, name) ? '$' + (names[name] < 10 ? '0' : '') + names[name] : ''))
+				}
+			}
+			if (typeof Symbol === 'function' && (matchAll = Symbol.matchAll)) {
+				regExp[matchAll] = function (str) {
+					var copy = __namedGroups(new RegExp(this), names), done, it
+					copy.lastIndex = this.lastIndex
+					str += ''
+					it = {
+						next() {
+							var match = !done && copy.exec(str), i
+							if (!match) return done = 1, { value: void 0, done: true }
+							if (!copy.global) done = 1
+							else if (match[0] === '') i = copy.lastIndex, copy.lastIndex = i + (copy.unicode && str.codePointAt(i) > 0xFFFF ? 2 : 1)
+							return { value: match, done: false }
+						},
+					}
+					it[Symbol.iterator] = () => it
+					return it
+				}
+			}
+			return regExp
+		}
+
+		// This helps for lowering generator functions to ES5. The body is a state
+		// machine that is re-entered at case "_.n" and returns an instruction:
+		// "[0, x]" yields, "[1, x]" delegates with "yield*", "[2, x]" returns,
+		// "[3, n, depth]" jumps out of "try" blocks nested deeper than "depth",
+		// and "[4]" ends a "finally" block. Each "try" block that contains a
+		// "yield" pushes "[catch, finally]" onto "_.t" when it's entered.
+		export var __gen = (__this, body) => {
+			var state = { n: 0, t: [] }, running, done, delegate, it = {}
+			var resume = (kind, value) => {
+				if (running) __typeError('Generator is already running')
+				if (done) {
+					if (kind === 1) throw value
+					return { value: kind === 2 ? value : void 0, done: true }
+				}
+				running = 1
+				try {
+					for (var op, entry, depth; ;) {
+						if (delegate) {
+							try {
+								if (op = delegate[kind ? kind > 1 ? 'return' : 'throw' : 'next']) {
+									if (!((op = op.call(delegate, value)) instanceof Object)) __typeError('Object expected')
+									if (!op.done) return op
+									value = op.value
+									if (kind < 2) kind = 0
+								} else if (kind === 1) {
+									(op = delegate.return) && op.call(delegate)
+									__typeError('The iterator does not provide a "throw" method')
+								}
+							} catch (e) {
+								kind = 1, value = e
+							}
+							delegate = 0
+						}
+						if (!kind) {
+							try {
+								state.v = value
+								op = body.call(__this, state)
+								if (op[0] < 2) {
+									if (!op[0]) return { value: op[1], done: false }
+									delegate = __iter(op[1]), value = void 0
+									continue
+								}
+								if (op[0] > 3) op = state.t.pop()[3]
+								kind = op[0], value = op[1], depth = op[2]
+							} catch (e) {
+								kind = 1, value = e
+							}
+						}
+						if (kind) {
+							entry = state.t[state.t.length - 1]
+							if (kind > 2 && state.t.length <= depth) state.n = value, kind = 0, value = void 0
+							else if (!entry) {
+								done = 1
+								if (kind === 1) throw value
+								return { value, done: true }
+							}
+							else if (kind === 1 && !entry[2] && entry[0]) entry[2] = 1, state.n = entry[0], kind = 0
+							else if ((entry[2] | 0) < 2 && entry[1]) entry[2] = 2, entry[3] = [kind, value, depth], state.n = entry[1], kind = 0
+							else state.t.pop()
+						}
+					}
+				} finally {
+					running = 0
+				}
+			}
+			it.next = v => resume(0, v)
+			it.throw = v => resume(1, v)
+			it.return = v => resume(2, v)
+			if (typeof Symbol === 'function') it[Symbol.iterator] = () => it
+			return it
+		}
+
 		// This helps for lowering async functions
-		export var __async = (__this, __arguments, generator) => {
+		export var __async =(__this, __arguments, generator) => {
 			return new Promise((resolve, reject) => {
 				var fulfilled = value => {
 					try {
@@ -547,6 +817,117 @@ func Source(unsupportedJSFeatures compat.JSFeature) logger.Source {
 	}
 }
 
+// Code splitting with the IIFE format can't use "import" statements to link
+// chunks together. Instead, each chunk registers itself by pushing its URL,
+// the paths of the chunks it depends on, and a function containing its code
+// onto this global array. The chunk's code is run once its dependencies have
+// been run, and the value it returns is passed to the chunks that import it.
+const ChunkRegistry = "self.esbuildChunks"
+
+// This is injected at the start of every entry point chunk when code splitting
+// with the IIFE format. It replaces "push" on the chunk registry with a function
+// that loads each chunk's dependencies before running it. Chunks are loaded
+// using "<script>" tags in the browser and using "importScripts" in a web worker.
+// It must work in old browsers, so it deliberately only uses ES5 syntax (other
+// than "Promise" for dynamic "import()"). It's parsed and printed using the
+// same settings as the rest of the output, so it's minified when that's enabled.
+const chunkLoader = `(function(chunks) {
+  if (chunks.registry) return;
+  var registry = chunks.registry = {};
+  var doc = self.document, link = doc && doc.createElement("a"), loading;
+  var resolve = function(base, path) {
+    if (!link) return new URL(path, base).href;
+    link.href = base.slice(0, base.lastIndexOf("/") + 1) + path;
+    return link.href;
+  };
+  var get = function(url) {
+    return registry[url] || (registry[url] = { listeners: [] });
+  };
+  var finish = function(chunk, error) {
+    chunk.done = true;
+    chunk.error = error;
+    chunk.listeners.splice(0).forEach(function(listener) {
+      listener(chunk);
+    });
+  };
+  var request = function(url, listener) {
+    var chunk = get(url), script, parent;
+    if (chunk.done) return listener(chunk);
+    chunk.listeners.push(listener);
+    if (chunk.started) return;
+    chunk.started = true;
+    if (doc) {
+      script = doc.createElement("script");
+      script.src = url;
+      script.onerror = function() {
+        finish(chunk, new Error("Failed to load chunk " + url));
+      };
+      doc.head.appendChild(script);
+      return;
+    }
+
+    // Web workers don't have "document" but can load scripts synchronously.
+    // The chunk can't determine its own URL, so it's remembered here instead.
+    parent = loading;
+    loading = url;
+    try {
+      importScripts(url);
+    } catch (error) {
+      finish(chunk, error);
+    }
+    loading = parent;
+  };
+  var define = function(args) {
+    var url = loading || args[0], deps = args[1], chunk = get(url), pending = deps.length + 1;
+    var values = [function(path) {
+      return new Promise(function(ok, fail) {
+        request(resolve(url, path), function(dep) {
+          if (dep.error) fail(dep.error);
+          else ok(dep.exports);
+        });
+      });
+    }];
+    var next = function(dep) {
+      if (chunk.done) return;
+      if (dep && dep.error) return finish(chunk, dep.error);
+      if (--pending) return;
+      try {
+        chunk.exports = args[2].apply(void 0, values) || {};
+      } catch (error) {
+        setTimeout(function() {
+          throw error;
+        });
+        return finish(chunk, error);
+      }
+      finish(chunk);
+    };
+    if (chunk.defined) return;
+    chunk.defined = chunk.started = true;
+    deps.forEach(function(path, i) {
+      request(resolve(url, path), function(dep) {
+        values[i + 1] = dep.exports;
+        next(dep);
+      });
+    });
+    next();
+  };
+  chunks.forEach(define);
+  chunks.push = function() {
+    [].forEach.call(arguments, define);
+  };
+})(` + ChunkRegistry + ` = ` + ChunkRegistry + ` || []);
+`
+
+func ChunkLoaderSource() logger.Source {
+	return logger.Source{
+		Index:          SourceIndex,
+		KeyPath:        logger.Path{Text: "<chunk-loader>"},
+		PrettyPaths:    logger.PrettyPaths{Abs: "<chunk-loader>", Rel: "<chunk-loader>"},
+		IdentifierName: "chunkLoader",
+		Contents:       chunkLoader,
+	}
+}
+
 // The TypeScript decorator transform behaves similar to the official
 // TypeScript compiler.
 //
@@ -31,3 +31,16 @@ func TestUnsupportedFeatures(t *testing.T) {
 		})
 	}
 }
+
+func TestChunkLoader(t *testing.T) {
+	log := logger.NewDeferLog(logger.DeferLogAll, nil)
+	js_parser.Parse(log, runtime.ChunkLoaderSource(), js_parser.OptionsFromConfig(&config.Options{}))
+
+	if log.HasErrors() {
+		msgs := "Internal error: failed to parse chunk loader:\n"
+		for _, msg := range log.Done() {
+			msgs += msg.String(logger.OutputOptions{IncludeSource: true}, logger.TerminalInfo{})
+		}
+		t.Fatal(msgs[:len(msgs)-1])
+	}
+}
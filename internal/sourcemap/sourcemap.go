@@ -40,6 +40,15 @@ type SourceContent struct {
 }
 
 func (sm *SourceMap) Find(line int32, column int32) *Mapping {
+	// Match the behavior of the popular "source-map" library from Mozilla
+	if mapping := sm.findClosestBefore(line, column); mapping != nil && mapping.GeneratedLine == line {
+		return mapping
+	}
+	return nil
+}
+
+// Unlike "Find", this doesn't require the mapping to be on the same line
+func (sm *SourceMap) findClosestBefore(line int32, column int32) *Mapping {
 	mappings := sm.Mappings
 
 	// Binary search
@@ -59,13 +68,32 @@ func (sm *SourceMap) Find(line int32, column int32) *Mapping {
 
 	// Handle search failure
 	if index > 0 {
-		mapping := &mappings[index-1]
+		return &mappings[index-1]
+	}
+	return nil
+}
+
+func (sm *SourceMap) findFirstOnLine(line int32) *Mapping {
+	mappings := sm.Mappings
 
-		// Match the behavior of the popular "source-map" library from Mozilla
-		if mapping.GeneratedLine == line {
-			return mapping
+	// Binary search
+	count := len(mappings)
+	index := 0
+	for count > 0 {
+		step := count / 2
+		i := index + step
+		if mappings[i].GeneratedLine < line {
+			index = i + 1
+			count -= step + 1
+		} else {
+			count = step
 		}
 	}
+
+	// Handle search failure
+	if index < len(mappings) && mappings[index].GeneratedLine == line {
+		return &mappings[index]
+	}
 	return nil
 }
 
@@ -493,6 +521,126 @@ func appendMappingToBuffer(
 	return buffer, nameOffset
 }
 
+// This generates the contents of the "mappings" field from parsed mappings,
+// which must already be sorted by generated position. Indices into "sources"
+// and "names" are written out unchanged.
+func EncodeMappings(mappings []Mapping) []byte {
+	var buffer []byte
+	var lastByte byte
+	prevState := SourceMapState{}
+	generatedLine := int32(0)
+
+	for _, mapping := range mappings {
+		// Handle line breaks in between this mapping and the previous one
+		if mapping.GeneratedLine > generatedLine {
+			buffer = append(buffer, bytes.Repeat([]byte{';'}, int(mapping.GeneratedLine-generatedLine))...)
+			generatedLine = mapping.GeneratedLine
+			prevState.GeneratedColumn = 0
+			lastByte = ';'
+		}
+
+		currentState := SourceMapState{
+			GeneratedColumn: int(mapping.GeneratedColumn),
+			SourceIndex:     int(mapping.SourceIndex),
+			OriginalLine:    int(mapping.OriginalLine),
+			OriginalColumn:  int(mapping.OriginalColumn),
+			OriginalName:    prevState.OriginalName,
+		}
+		if mapping.OriginalName.IsValid() {
+			currentState.OriginalName = int(mapping.OriginalName.GetIndex())
+			currentState.HasOriginalName = true
+		}
+
+		buffer, _ = appendMappingToBuffer(buffer, lastByte, prevState, currentState, false)
+		lastByte = buffer[len(buffer)-1]
+		prevState = currentState
+	}
+
+	return buffer
+}
+
+// This maps each location in the generated code of "outer" back through
+// "inner", whose generated code must be the original code of "outer". Only the
+// first source of "outer" is used, so mappings for other sources are dropped.
+// The returned mappings use the "sources" and "names" arrays of "inner".
+func ChainMappings(inner *SourceMap, outer *SourceMap) []Mapping {
+	mappings := make([]Mapping, 0, len(outer.Mappings))
+	for _, mapping := range outer.Mappings {
+		if mapping.SourceIndex != 0 {
+			continue
+		}
+		original := inner.Find(mapping.OriginalLine, mapping.OriginalColumn)
+		if original == nil {
+			// Code before the first mapping on a line (e.g. indentation) belongs
+			// with that mapping. Mappings on earlier lines aren't used since they
+			// are likely for unrelated code.
+			if original = inner.findFirstOnLine(mapping.OriginalLine); original == nil {
+				continue
+			}
+		}
+
+		// Skip mappings that don't add any information
+		if n := len(mappings); n > 0 {
+			if prev := &mappings[n-1]; prev.GeneratedLine == mapping.GeneratedLine && prev.SourceIndex == original.SourceIndex &&
+				prev.OriginalLine == original.OriginalLine && prev.OriginalColumn == original.OriginalColumn {
+				continue
+			}
+		}
+
+		mappings = append(mappings, Mapping{
+			GeneratedLine:   mapping.GeneratedLine,
+			GeneratedColumn: mapping.GeneratedColumn,
+			SourceIndex:     original.SourceIndex,
+			OriginalLine:    original.OriginalLine,
+			OriginalColumn:  original.OriginalColumn,
+			OriginalName:    original.OriginalName,
+		})
+	}
+	return mappings
+}
+
+// This is used to pass a parsed source map to code outside of esbuild
+func (sm *SourceMap) JSON(asciiOnly bool) []byte {
+	j := helpers.Joiner{}
+	j.AddString("{\n  \"version\": 3,\n  \"sources\": [")
+	for i, source := range sm.Sources {
+		if i != 0 {
+			j.AddString(", ")
+		}
+		j.AddBytes(helpers.QuoteForJSON(source, asciiOnly))
+	}
+	j.AddString("]")
+
+	if len(sm.SourcesContent) > 0 {
+		j.AddString(",\n  \"sourcesContent\": [")
+		for i, content := range sm.SourcesContent {
+			if i != 0 {
+				j.AddString(", ")
+			}
+			if content.Quoted != "" && !asciiOnly {
+				j.AddString(content.Quoted)
+			} else if content.Value != nil {
+				j.AddBytes(helpers.QuoteForJSON(helpers.UTF16ToString(content.Value), asciiOnly))
+			} else {
+				j.AddString("null")
+			}
+		}
+		j.AddString("]")
+	}
+
+	j.AddString(",\n  \"mappings\": \"")
+	j.AddBytes(EncodeMappings(sm.Mappings))
+	j.AddString("\",\n  \"names\": [")
+	for i, name := range sm.Names {
+		if i != 0 {
+			j.AddString(", ")
+		}
+		j.AddBytes(helpers.QuoteForJSON(name, asciiOnly))
+	}
+	j.AddString("]\n}\n")
+	return j.Done()
+}
+
 type LineOffsetTable struct {
 	// The source map specification is very loose and does not specify what
 	// column numbers actually mean. The popular "source-map" library from Mozilla
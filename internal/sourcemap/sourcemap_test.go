@@ -0,0 +1,41 @@
+package sourcemap
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/evanw/esbuild/internal/test"
+)
+
+func TestChainMappings(t *testing.T) {
+	inner := &SourceMap{
+		Sources: []string{"a.js", "b.js"},
+		Mappings: []Mapping{
+			{GeneratedLine: 0, GeneratedColumn: 0, SourceIndex: 0, OriginalLine: 10, OriginalColumn: 0},
+			{GeneratedLine: 0, GeneratedColumn: 4, SourceIndex: 1, OriginalLine: 20, OriginalColumn: 2},
+			{GeneratedLine: 2, GeneratedColumn: 2, SourceIndex: 1, OriginalLine: 30, OriginalColumn: 0},
+		},
+	}
+	outer := &SourceMap{
+		Sources: []string{"<inner>", "other.js"},
+		Mappings: []Mapping{
+			// This is mapped through "inner"
+			{GeneratedLine: 0, GeneratedColumn: 0, SourceIndex: 0, OriginalLine: 0, OriginalColumn: 5},
+
+			// This is dropped because it's for another source of "outer"
+			{GeneratedLine: 1, GeneratedColumn: 0, SourceIndex: 1, OriginalLine: 0, OriginalColumn: 0},
+
+			// This is dropped because "inner" has nothing on that line
+			{GeneratedLine: 2, GeneratedColumn: 0, SourceIndex: 0, OriginalLine: 1, OriginalColumn: 3},
+
+			// This comes before the first mapping on that line in "inner"
+			{GeneratedLine: 3, GeneratedColumn: 0, SourceIndex: 0, OriginalLine: 2, OriginalColumn: 0},
+		},
+	}
+
+	text := ""
+	for _, m := range ChainMappings(inner, outer) {
+		text += fmt.Sprintf("%d:%d => %s:%d:%d\n", m.GeneratedLine, m.GeneratedColumn, inner.Sources[m.SourceIndex], m.OriginalLine, m.OriginalColumn)
+	}
+	test.AssertEqualWithDiff(t, text, "0:0 => b.js:20:2\n3:0 => b.js:30:0\n")
+}
@@ -146,8 +146,10 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   let sourceRoot = getFlag(options, keys, 'sourceRoot', mustBeString)
   let sourcesContent = getFlag(options, keys, 'sourcesContent', mustBeBoolean)
   let target = getFlag(options, keys, 'target', mustBeStringOrArrayOfStrings)
+  let bigintLibrary = getFlag(options, keys, 'bigintLibrary', mustBeString)
   let format = getFlag(options, keys, 'format', mustBeString)
   let globalName = getFlag(options, keys, 'globalName', mustBeString)
+  let umdExternals = getFlag(options, keys, 'umdExternals', mustBeObject)
   let mangleProps = getFlag(options, keys, 'mangleProps', mustBeRegExp)
   let reserveProps = getFlag(options, keys, 'reserveProps', mustBeRegExp)
   let mangleQuoted = getFlag(options, keys, 'mangleQuoted', mustBeBoolean)
@@ -180,8 +182,30 @@ function pushCommonFlags(flags: string[], options: CommonOptions, keys: OptionKe
   if (sourceRoot !== void 0) flags.push(`--source-root=${sourceRoot}`)
   if (sourcesContent !== void 0) flags.push(`--sources-content=${sourcesContent}`)
   if (target) flags.push(`--target=${validateAndJoinStringArray(Array.isArray(target) ? target : [target], 'target')}`)
+  if (bigintLibrary) flags.push(`--bigint-library=${bigintLibrary}`)
   if (format) flags.push(`--format=${format}`)
   if (globalName) flags.push(`--global-name=${globalName}`)
+  if (umdExternals) {
+    for (let path in umdExternals) {
+      if (path.indexOf('=') >= 0) throw new Error(`Invalid import path in UMD externals: ${path}`)
+      let value = umdExternals[path]
+      if (typeof value === 'string') {
+        flags.push(`--umd-external:${path}=${value}`)
+        continue
+      }
+      if (mustBeObject(value) !== null) throw new Error(`Expected value for UMD external ${quote(path)} to be a string or an object`)
+      let parts: string[] = []
+      let subKeys: OptionKeys = {}
+      let amd = getFlag(value, subKeys, 'amd', mustBeString)
+      let commonjs = getFlag(value, subKeys, 'commonjs', mustBeString)
+      let global = getFlag(value, subKeys, 'global', mustBeString)
+      checkForInvalidFlags(value, subKeys, `in UMD external ${quote(path)}`)
+      if (amd) parts.push(`amd:${amd}`)
+      if (commonjs) parts.push(`cjs:${commonjs}`)
+      if (global) parts.push(`global:${global}`)
+      flags.push(`--umd-external:${path}=${validateAndJoinStringArray(parts, 'UMD external')}`)
+    }
+  }
   if (platform) flags.push(`--platform=${platform}`)
   if (tsconfigRaw) flags.push(`--tsconfig-raw=${typeof tsconfigRaw === 'string' ? tsconfigRaw : JSON.stringify(tsconfigRaw)}`)
 
@@ -258,6 +282,8 @@ function flagsForBuildOptions(
   let sourcemap = getFlag(options, keys, 'sourcemap', mustBeStringOrBoolean)
   let bundle = getFlag(options, keys, 'bundle', mustBeBoolean)
   let splitting = getFlag(options, keys, 'splitting', mustBeBoolean)
+  let hmr = getFlag(options, keys, 'hmr', mustBeBoolean)
+  let lowerTopLevelAwait = getFlag(options, keys, 'lowerTopLevelAwait', mustBeBoolean)
   let preserveSymlinks = getFlag(options, keys, 'preserveSymlinks', mustBeBoolean)
   let metafile = getFlag(options, keys, 'metafile', mustBeBoolean)
   let outfile = getFlag(options, keys, 'outfile', mustBeString)
@@ -277,11 +303,15 @@ function flagsForBuildOptions(
   let entryNames = getFlag(options, keys, 'entryNames', mustBeString)
   let chunkNames = getFlag(options, keys, 'chunkNames', mustBeString)
   let assetNames = getFlag(options, keys, 'assetNames', mustBeString)
+  let localCSSNames = getFlag(options, keys, 'localCSSNames', mustBeString)
+  let cssModuleTypes = getFlag(options, keys, 'cssModuleTypes', mustBeString)
+  let manualChunks = getFlag(options, keys, 'manualChunks', mustBeObject)
   let inject = getFlag(options, keys, 'inject', mustBeArrayOfStrings)
   let banner = getFlag(options, keys, 'banner', mustBeObject)
   let footer = getFlag(options, keys, 'footer', mustBeObject)
   let entryPoints = getFlag(options, keys, 'entryPoints', mustBeEntryPoints)
   let absWorkingDir = getFlag(options, keys, 'absWorkingDir', mustBeString)
+  let cacheDir = getFlag(options, keys, 'cacheDir', mustBeString)
   let stdin = getFlag(options, keys, 'stdin', mustBeObject)
   let write = getFlag(options, keys, 'write', mustBeBoolean) ?? writeDefault; // Default to true if not specified
   let allowOverwrite = getFlag(options, keys, 'allowOverwrite', mustBeBoolean)
@@ -293,11 +323,14 @@ function flagsForBuildOptions(
   if (bundle) flags.push('--bundle')
   if (allowOverwrite) flags.push('--allow-overwrite')
   if (splitting) flags.push('--splitting')
+  if (hmr) flags.push('--hmr')
+  if (lowerTopLevelAwait) flags.push('--lower-top-level-await')
   if (preserveSymlinks) flags.push('--preserve-symlinks')
   if (metafile) flags.push(`--metafile`)
   if (outfile) flags.push(`--outfile=${outfile}`)
   if (outdir) flags.push(`--outdir=${outdir}`)
   if (outbase) flags.push(`--outbase=${outbase}`)
+  if (cacheDir) flags.push(`--cache-dir=${cacheDir}`)
   if (tsconfig) flags.push(`--tsconfig=${tsconfig}`)
   if (packages) flags.push(`--packages=${packages}`)
   if (resolveExtensions) flags.push(`--resolve-extensions=${validateAndJoinStringArray(resolveExtensions, 'resolve extension')}`)
@@ -305,6 +338,8 @@ function flagsForBuildOptions(
   if (entryNames) flags.push(`--entry-names=${entryNames}`)
   if (chunkNames) flags.push(`--chunk-names=${chunkNames}`)
   if (assetNames) flags.push(`--asset-names=${assetNames}`)
+  if (localCSSNames) flags.push(`--local-css-names=${localCSSNames}`)
+  if (cssModuleTypes) flags.push(`--css-module-types=${cssModuleTypes}`)
   if (mainFields) flags.push(`--main-fields=${validateAndJoinStringArray(mainFields, 'main field')}`)
   if (conditions) flags.push(`--conditions=${validateAndJoinStringArray(conditions, 'condition')}`)
   if (external) for (let name of external) flags.push(`--external:${validateStringValue(name, 'external')}`)
@@ -314,6 +349,14 @@ function flagsForBuildOptions(
       flags.push(`--alias:${old}=${validateStringValue(alias[old], 'alias', old)}`)
     }
   }
+  if (manualChunks) {
+    for (let name in manualChunks) {
+      if (name.indexOf('=') >= 0) throw new Error(`Invalid manual chunk name: ${name}`)
+      let patterns = manualChunks[name]
+      if (!Array.isArray(patterns)) throw new Error(`Expected value for manual chunk ${quote(name)} to be an array of strings`)
+      for (let pattern of patterns) flags.push(`--manual-chunk:${name}=${validateStringValue(pattern, 'manual chunk', name)}`)
+    }
+  }
   if (banner) {
     for (let type in banner) {
       if (type.indexOf('=') >= 0) throw new Error(`Invalid banner file type: ${type}`)
@@ -1207,6 +1250,42 @@ let handlePlugins = async (
     },
   } = {}
 
+  let onTransformCallbacks: {
+    [id: number]: {
+      name: string,
+      note: () => types.Note | undefined,
+      callback: (args: types.OnTransformArgs) =>
+        (types.OnTransformResult | null | undefined | Promise<types.OnTransformResult | null | undefined>),
+    },
+  } = {}
+
+  let onManualChunkCallbacks: {
+    [id: number]: {
+      name: string,
+      note: () => types.Note | undefined,
+      callback: (args: types.OnManualChunkArgs) =>
+        (types.OnManualChunkResult | null | undefined | Promise<types.OnManualChunkResult | null | undefined>),
+    },
+  } = {}
+
+  let onOutputCallbacks: {
+    [id: number]: {
+      name: string,
+      note: () => types.Note | undefined,
+      callback: (args: types.OnOutputArgs) =>
+        (types.OnOutputResult | null | undefined | Promise<types.OnOutputResult | null | undefined>),
+    },
+  } = {}
+
+  let onLocalCSSNameCallbacks: {
+    [id: number]: {
+      name: string,
+      note: () => types.Note | undefined,
+      callback: (args: types.OnLocalCSSNameArgs) =>
+        (types.OnLocalCSSNameResult | null | undefined | Promise<types.OnLocalCSSNameResult | null | undefined>),
+    },
+  } = {}
+
   let onDisposeCallbacks: (() => void)[] = []
   let nextCallbackID = 0
   let i = 0
@@ -1232,6 +1311,10 @@ let handlePlugins = async (
         onEnd: false,
         onResolve: [],
         onLoad: [],
+        onTransform: [],
+        onManualChunk: [],
+        onOutput: [],
+        onLocalCSSName: [],
       }
       i++
 
@@ -1325,6 +1408,57 @@ let handlePlugins = async (
           plugin.onLoad.push({ id, filter: jsRegExpToGoRegExp(filter), namespace: namespace || '' })
         },
 
+        onTransform(options, callback) {
+          let registeredText = `This error came from the "onTransform" callback registered here:`
+          let registeredNote = extractCallerV8(new Error(registeredText), streamIn, 'onTransform')
+          let keys: OptionKeys = {}
+          let filter = getFlag(options, keys, 'filter', mustBeRegExp)
+          let namespace = getFlag(options, keys, 'namespace', mustBeString)
+          checkForInvalidFlags(options, keys, `in onTransform() call for plugin ${quote(name)}`)
+          if (filter == null) throw new Error(`onTransform() call is missing a filter`)
+          let id = nextCallbackID++
+          onTransformCallbacks[id] = { name: name!, callback, note: registeredNote }
+          plugin.onTransform.push({ id, filter: jsRegExpToGoRegExp(filter), namespace: namespace || '' })
+        },
+
+        onManualChunk(options, callback) {
+          let registeredText = `This error came from the "onManualChunk" callback registered here:`
+          let registeredNote = extractCallerV8(new Error(registeredText), streamIn, 'onManualChunk')
+          let keys: OptionKeys = {}
+          let filter = getFlag(options, keys, 'filter', mustBeRegExp)
+          let namespace = getFlag(options, keys, 'namespace', mustBeString)
+          checkForInvalidFlags(options, keys, `in onManualChunk() call for plugin ${quote(name)}`)
+          if (filter == null) throw new Error(`onManualChunk() call is missing a filter`)
+          let id = nextCallbackID++
+          onManualChunkCallbacks[id] = { name: name!, callback, note: registeredNote }
+          plugin.onManualChunk.push({ id, filter: jsRegExpToGoRegExp(filter), namespace: namespace || '' })
+        },
+
+        onOutput(options, callback) {
+          let registeredText = `This error came from the "onOutput" callback registered here:`
+          let registeredNote = extractCallerV8(new Error(registeredText), streamIn, 'onOutput')
+          let keys: OptionKeys = {}
+          let filter = getFlag(options, keys, 'filter', mustBeRegExp)
+          checkForInvalidFlags(options, keys, `in onOutput() call for plugin ${quote(name)}`)
+          if (filter == null) throw new Error(`onOutput() call is missing a filter`)
+          let id = nextCallbackID++
+          onOutputCallbacks[id] = { name: name!, callback, note: registeredNote }
+          plugin.onOutput.push({ id, filter: jsRegExpToGoRegExp(filter) })
+        },
+
+        onLocalCSSName(options, callback) {
+          let registeredText = `This error came from the "onLocalCSSName" callback registered here:`
+          let registeredNote = extractCallerV8(new Error(registeredText), streamIn, 'onLocalCSSName')
+          let keys: OptionKeys = {}
+          let filter = getFlag(options, keys, 'filter', mustBeRegExp)
+          let namespace = getFlag(options, keys, 'namespace', mustBeString)
+          checkForInvalidFlags(options, keys, `in onLocalCSSName() call for plugin ${quote(name)}`)
+          if (filter == null) throw new Error(`onLocalCSSName() call is missing a filter`)
+          let id = nextCallbackID++
+          onLocalCSSNameCallbacks[id] = { name: name!, callback, note: registeredNote }
+          plugin.onLocalCSSName.push({ id, filter: jsRegExpToGoRegExp(filter), namespace: namespace || '' })
+        },
+
         onDispose(callback) {
           onDisposeCallbacks.push(callback)
         },
@@ -1476,6 +1610,144 @@ let handlePlugins = async (
     sendResponse(id, response as any)
   }
 
+  requestCallbacks['on-transform'] = async (id, request: protocol.OnTransformRequest) => {
+    let response: protocol.OnTransformResponse = {}
+    let { name, callback, note } = onTransformCallbacks[request.id]
+    try {
+      let result = await callback({
+        path: request.path,
+        namespace: request.namespace,
+        suffix: request.suffix,
+        contents: request.contents,
+        loader: request.loader,
+        sourceMap: request.sourceMap,
+      })
+
+      if (result != null) {
+        if (typeof result !== 'object') throw new Error(`Expected onTransform() callback in plugin ${quote(name)} to return an object`)
+        let keys: OptionKeys = {}
+        let pluginName = getFlag(result, keys, 'pluginName', mustBeString)
+        let contents = getFlag(result, keys, 'contents', mustBeString)
+        let sourceMap = getFlag(result, keys, 'sourceMap', mustBeString)
+        let errors = getFlag(result, keys, 'errors', mustBeArray)
+        let warnings = getFlag(result, keys, 'warnings', mustBeArray)
+        checkForInvalidFlags(result, keys, `from onTransform() callback in plugin ${quote(name)}`)
+
+        if (pluginName != null) response.pluginName = pluginName
+        if (contents != null) response.contents = contents
+        if (sourceMap != null) response.sourceMap = sourceMap
+        if (errors != null) response.errors = sanitizeMessages(errors, 'errors', details, name, undefined)
+        if (warnings != null) response.warnings = sanitizeMessages(warnings, 'warnings', details, name, undefined)
+      }
+    } catch (e) {
+      response = { errors: [extractErrorMessageV8(e, streamIn, details, note && note(), name)] }
+    }
+    sendResponse(id, response as any)
+  }
+
+  requestCallbacks['on-manual-chunk'] = async (id, request: protocol.OnManualChunkRequest) => {
+    let response: protocol.OnManualChunkResponse = {}, name = '', callback, note
+    for (let id of request.ids) {
+      try {
+        ({ name, callback, note } = onManualChunkCallbacks[id])
+        let result = await callback({
+          path: request.path,
+          namespace: request.namespace,
+        })
+
+        if (result != null) {
+          if (typeof result !== 'object') throw new Error(`Expected onManualChunk() callback in plugin ${quote(name)} to return an object`)
+          let keys: OptionKeys = {}
+          let pluginName = getFlag(result, keys, 'pluginName', mustBeString)
+          let chunkName = getFlag(result, keys, 'chunkName', mustBeString)
+          let errors = getFlag(result, keys, 'errors', mustBeArray)
+          let warnings = getFlag(result, keys, 'warnings', mustBeArray)
+          checkForInvalidFlags(result, keys, `from onManualChunk() callback in plugin ${quote(name)}`)
+
+          response.id = id
+          if (pluginName != null) response.pluginName = pluginName
+          if (chunkName != null) response.chunkName = chunkName
+          if (errors != null) response.errors = sanitizeMessages(errors, 'errors', details, name, undefined)
+          if (warnings != null) response.warnings = sanitizeMessages(warnings, 'warnings', details, name, undefined)
+          if (chunkName) break
+        }
+      } catch (e) {
+        response = { id, errors: [extractErrorMessageV8(e, streamIn, details, note && note(), name)] }
+        break
+      }
+    }
+    sendResponse(id, response as any)
+  }
+
+  requestCallbacks['on-local-css-name'] = async (id, request: protocol.OnLocalCSSNameRequest) => {
+    let response: protocol.OnLocalCSSNameResponse = {}, name = '', callback, note
+    for (let id of request.ids) {
+      try {
+        ({ name, callback, note } = onLocalCSSNameCallbacks[id])
+        let result = await callback({
+          path: request.path,
+          namespace: request.namespace,
+          local: request.local,
+        })
+
+        if (result != null) {
+          if (typeof result !== 'object') throw new Error(`Expected onLocalCSSName() callback in plugin ${quote(name)} to return an object`)
+          let keys: OptionKeys = {}
+          let pluginName = getFlag(result, keys, 'pluginName', mustBeString)
+          let localName = getFlag(result, keys, 'name', mustBeString)
+          let errors = getFlag(result, keys, 'errors', mustBeArray)
+          let warnings = getFlag(result, keys, 'warnings', mustBeArray)
+          checkForInvalidFlags(result, keys, `from onLocalCSSName() callback in plugin ${quote(name)}`)
+
+          response.id = id
+          if (pluginName != null) response.pluginName = pluginName
+          if (localName != null) response.name = localName
+          if (errors != null) response.errors = sanitizeMessages(errors, 'errors', details, name, undefined)
+          if (warnings != null) response.warnings = sanitizeMessages(warnings, 'warnings', details, name, undefined)
+          if (localName) break
+        }
+      } catch (e) {
+        response = { id, errors: [extractErrorMessageV8(e, streamIn, details, note && note(), name)] }
+        break
+      }
+    }
+    sendResponse(id, response as any)
+  }
+
+  requestCallbacks['on-output'] = async (id, request: protocol.OnOutputRequest) => {
+    let response: protocol.OnOutputResponse = {}
+    let { name, callback, note } = onOutputCallbacks[request.id]
+    try {
+      let result = await callback({
+        path: request.path,
+        contents: request.contents,
+        sourceMap: request.sourceMap,
+        entryPoint: request.entryPoint,
+        modules: request.modules,
+      })
+
+      if (result != null) {
+        if (typeof result !== 'object') throw new Error(`Expected onOutput() callback in plugin ${quote(name)} to return an object`)
+        let keys: OptionKeys = {}
+        let pluginName = getFlag(result, keys, 'pluginName', mustBeString)
+        let contents = getFlag(result, keys, 'contents', mustBeString)
+        let sourceMap = getFlag(result, keys, 'sourceMap', mustBeString)
+        let errors = getFlag(result, keys, 'errors', mustBeArray)
+        let warnings = getFlag(result, keys, 'warnings', mustBeArray)
+        checkForInvalidFlags(result, keys, `from onOutput() callback in plugin ${quote(name)}`)
+
+        if (pluginName != null) response.pluginName = pluginName
+        if (contents != null) response.contents = contents
+        if (sourceMap != null) response.sourceMap = sourceMap
+        if (errors != null) response.errors = sanitizeMessages(errors, 'errors', details, name, undefined)
+        if (warnings != null) response.warnings = sanitizeMessages(warnings, 'warnings', details, name, undefined)
+      }
+    } catch (e) {
+      response = { errors: [extractErrorMessageV8(e, streamIn, details, note && note(), name)] }
+    }
+    sendResponse(id, response as any)
+  }
+
   let runOnEndCallbacks: RunOnEndCallbacks = (result, done) => done([], [])
 
   if (onEndCallbacks.length > 0) {
@@ -45,6 +45,10 @@ export interface BuildPlugin {
   onEnd: boolean
   onResolve: { id: number, filter: string, namespace: string }[]
   onLoad: { id: number, filter: string, namespace: string }[]
+  onTransform: { id: number, filter: string, namespace: string }[]
+  onManualChunk: { id: number, filter: string, namespace: string }[]
+  onOutput: { id: number, filter: string }[]
+  onLocalCSSName: { id: number, filter: string, namespace: string }[]
 }
 
 export interface BuildResponse {
@@ -245,6 +249,86 @@ export interface OnLoadResponse {
   watchDirs?: string[]
 }
 
+export interface OnTransformRequest {
+  command: 'on-transform'
+  key: number
+  id: number
+  path: string
+  namespace: string
+  suffix: string
+  contents: string
+  loader: types.Loader
+  sourceMap?: string
+}
+
+export interface OnTransformResponse {
+  pluginName?: string
+
+  errors?: types.PartialMessage[]
+  warnings?: types.PartialMessage[]
+
+  contents?: string
+  sourceMap?: string
+}
+
+export interface OnManualChunkRequest {
+  command: 'on-manual-chunk'
+  key: number
+  ids: number[]
+  path: string
+  namespace: string
+}
+
+export interface OnManualChunkResponse {
+  id?: number
+  pluginName?: string
+
+  errors?: types.PartialMessage[]
+  warnings?: types.PartialMessage[]
+
+  chunkName?: string
+}
+
+export interface OnLocalCSSNameRequest {
+  command: 'on-local-css-name'
+  key: number
+  ids: number[]
+  path: string
+  namespace: string
+  local: string
+}
+
+export interface OnLocalCSSNameResponse {
+  id?: number
+  pluginName?: string
+
+  errors?: types.PartialMessage[]
+  warnings?: types.PartialMessage[]
+
+  name?: string
+}
+
+export interface OnOutputRequest {
+  command: 'on-output'
+  key: number
+  id: number
+  path: string
+  contents: string
+  sourceMap?: string
+  entryPoint: string
+  modules: string[]
+}
+
+export interface OnOutputResponse {
+  pluginName?: string
+
+  errors?: types.PartialMessage[]
+  warnings?: types.PartialMessage[]
+
+  contents?: string
+  sourceMap?: string
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 export interface Packet {
@@ -1,11 +1,20 @@
 export type Platform = 'browser' | 'node' | 'neutral'
-export type Format = 'iife' | 'cjs' | 'esm'
-export type Loader = 'base64' | 'binary' | 'copy' | 'css' | 'dataurl' | 'default' | 'empty' | 'file' | 'js' | 'json' | 'jsx' | 'local-css' | 'text' | 'ts' | 'tsx'
+export type Format = 'iife' | 'cjs' | 'esm' | 'system' | 'umd'
+export type Loader = 'base64' | 'binary' | 'copy' | 'css' | 'dataurl' | 'default' | 'empty' | 'file' | 'html' | 'js' | 'json' | 'jsx' | 'local-css' | 'text' | 'ts' | 'tsx'
 export type LogLevel = 'verbose' | 'debug' | 'info' | 'warning' | 'error' | 'silent'
 export type Charset = 'ascii' | 'utf8'
 export type Drop = 'console' | 'debugger'
 export type AbsPaths = 'code' | 'log' | 'metafile'
 
+export interface UMDExternal {
+  /** The module identifier passed to "define()" */
+  amd?: string
+  /** The module name passed to "require()" */
+  commonjs?: string
+  /** The global variable, such as "React" or "MyLib.utils" */
+  global?: string
+}
+
 interface CommonOptions {
   /** Documentation: https://esbuild.github.io/api/#sourcemap */
   sourcemap?: boolean | 'linked' | 'inline' | 'external' | 'both'
@@ -20,10 +29,14 @@ interface CommonOptions {
   format?: Format
   /** Documentation: https://esbuild.github.io/api/#global-name */
   globalName?: string
+  /** Documentation: https://esbuild.github.io/api/#umd-externals */
+  umdExternals?: Record<string, string | UMDExternal>
   /** Documentation: https://esbuild.github.io/api/#target */
   target?: string | string[]
   /** Documentation: https://esbuild.github.io/api/#supported */
   supported?: Record<string, boolean>
+  /** Documentation: https://esbuild.github.io/api/#bigint-library */
+  bigintLibrary?: string
   /** Documentation: https://esbuild.github.io/api/#platform */
   platform?: Platform
 
@@ -115,6 +128,10 @@ export interface BuildOptions extends CommonOptions {
   bundle?: boolean
   /** Documentation: https://esbuild.github.io/api/#splitting */
   splitting?: boolean
+  /** Documentation: https://esbuild.github.io/api/#hmr */
+  hmr?: boolean
+  /** Documentation: https://esbuild.github.io/api/#lower-top-level-await */
+  lowerTopLevelAwait?: boolean
   /** Documentation: https://esbuild.github.io/api/#preserve-symlinks */
   preserveSymlinks?: boolean
   /** Documentation: https://esbuild.github.io/api/#outfile */
@@ -155,6 +172,12 @@ export interface BuildOptions extends CommonOptions {
   chunkNames?: string
   /** Documentation: https://esbuild.github.io/api/#asset-names */
   assetNames?: string
+  /** Documentation: https://esbuild.github.io/api/#local-css-names */
+  localCSSNames?: string
+  /** Documentation: https://esbuild.github.io/api/#css-module-types */
+  cssModuleTypes?: 'none' | 'd.ts' | 'd.css.ts'
+  /** Documentation: https://esbuild.github.io/api/#manual-chunks */
+  manualChunks?: Record<string, string[]>
   /** Documentation: https://esbuild.github.io/api/#inject */
   inject?: string[]
   /** Documentation: https://esbuild.github.io/api/#banner */
@@ -169,6 +192,8 @@ export interface BuildOptions extends CommonOptions {
   plugins?: Plugin[]
   /** Documentation: https://esbuild.github.io/api/#working-directory */
   absWorkingDir?: string
+  /** Documentation: https://esbuild.github.io/api/#cache-dir */
+  cacheDir?: string
   /** Documentation: https://esbuild.github.io/api/#node-paths */
   nodePaths?: string[]; // The "NODE_PATH" variable from Node.js
 }
@@ -322,6 +347,22 @@ export interface PluginBuild {
   onLoad(options: OnLoadOptions, callback: (args: OnLoadArgs) =>
     (OnLoadResult | null | undefined | Promise<OnLoadResult | null | undefined>)): void
 
+  /** Documentation: https://esbuild.github.io/plugins/#on-transform */
+  onTransform(options: OnTransformOptions, callback: (args: OnTransformArgs) =>
+    (OnTransformResult | null | undefined | Promise<OnTransformResult | null | undefined>)): void
+
+  /** Documentation: https://esbuild.github.io/plugins/#on-manual-chunk */
+  onManualChunk(options: OnManualChunkOptions, callback: (args: OnManualChunkArgs) =>
+    (OnManualChunkResult | null | undefined | Promise<OnManualChunkResult | null | undefined>)): void
+
+  /** Documentation: https://esbuild.github.io/plugins/#on-output */
+  onOutput(options: OnOutputOptions, callback: (args: OnOutputArgs) =>
+    (OnOutputResult | null | undefined | Promise<OnOutputResult | null | undefined>)): void
+
+  /** Documentation: https://esbuild.github.io/plugins/#on-local-css-name */
+  onLocalCSSName(options: OnLocalCSSNameOptions, callback: (args: OnLocalCSSNameArgs) =>
+    (OnLocalCSSNameResult | null | undefined | Promise<OnLocalCSSNameResult | null | undefined>)): void
+
   /** Documentation: https://esbuild.github.io/plugins/#on-dispose */
   onDispose(callback: () => void): void
 
@@ -406,6 +447,10 @@ export type ImportKind =
   | 'composes-from'
   | 'url-token'
 
+  // HTML
+  | 'script-tag'
+  | 'link-tag'
+
 /** Documentation: https://esbuild.github.io/plugins/#on-resolve-results */
 export interface OnResolveResult {
   pluginName?: string
@@ -455,6 +500,105 @@ export interface OnLoadResult {
   watchDirs?: string[]
 }
 
+/** Documentation: https://esbuild.github.io/plugins/#on-transform-options */
+export interface OnTransformOptions {
+  filter: RegExp
+  namespace?: string
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-transform-arguments */
+export interface OnTransformArgs {
+  path: string
+  namespace: string
+  suffix: string
+  contents: string
+  loader: Loader
+  sourceMap: string | undefined
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-transform-results */
+export interface OnTransformResult {
+  pluginName?: string
+
+  errors?: PartialMessage[]
+  warnings?: PartialMessage[]
+
+  contents?: string
+  sourceMap?: string
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-manual-chunk-options */
+export interface OnManualChunkOptions {
+  filter: RegExp
+  namespace?: string
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-manual-chunk-arguments */
+export interface OnManualChunkArgs {
+  path: string
+  namespace: string
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-manual-chunk-results */
+export interface OnManualChunkResult {
+  pluginName?: string
+
+  errors?: PartialMessage[]
+  warnings?: PartialMessage[]
+
+  chunkName?: string
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-local-css-name-options */
+export interface OnLocalCSSNameOptions {
+  filter: RegExp
+  namespace?: string
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-local-css-name-arguments */
+export interface OnLocalCSSNameArgs {
+  path: string
+  namespace: string
+  local: string
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-local-css-name-results */
+export interface OnLocalCSSNameResult {
+  pluginName?: string
+
+  errors?: PartialMessage[]
+  warnings?: PartialMessage[]
+
+  name?: string
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-output-options */
+export interface OnOutputOptions {
+  filter: RegExp
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-output-arguments */
+export interface OnOutputArgs {
+  /** Not final: "[hash]" is still present since the hash includes your changes */
+  path: string
+  /** Paths to other output files are placeholders that must be left as-is */
+  contents: string
+  sourceMap: string | undefined
+  entryPoint: string
+  modules: string[]
+}
+
+/** Documentation: https://esbuild.github.io/plugins/#on-output-results */
+export interface OnOutputResult {
+  pluginName?: string
+
+  errors?: PartialMessage[]
+  warnings?: PartialMessage[]
+
+  contents?: string
+  sourceMap?: string
+}
+
 export interface PartialMessage {
   id?: string
   pluginName?: string
@@ -149,6 +149,7 @@ const (
 	LoaderEmpty
 	LoaderFile
 	LoaderGlobalCSS
+	LoaderHTML
 	LoaderJS
 	LoaderJSON
 	LoaderJSX
@@ -174,6 +175,25 @@ const (
 	FormatIIFE
 	FormatCommonJS
 	FormatESModule
+	FormatSystem
+	FormatUMD
+)
+
+// This configures how an external module is loaded by the UMD format. Each
+// empty field defaults to the import path, except for "Global" which defaults
+// to a name derived from the import path.
+type UMDExternal struct {
+	AMD      string // The module identifier passed to "define()"
+	CommonJS string // The module name passed to "require()"
+	Global   string // The global variable, such as "React" or "MyLib.utils"
+}
+
+type CSSModuleTypes uint8
+
+const (
+	CSSModuleTypesNone   CSSModuleTypes = iota
+	CSSModuleTypesDTS                   // "button.module.css" => "button.module.css.d.ts"
+	CSSModuleTypesDCSSTS                // "button.module.css" => "button.module.d.css.ts"
 )
 
 type Packages uint8
@@ -287,9 +307,11 @@ type BuildOptions struct {
 	SourceRoot     string         // Documentation: https://esbuild.github.io/api/#source-root
 	SourcesContent SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
 
-	Target    Target          // Documentation: https://esbuild.github.io/api/#target
-	Engines   []Engine        // Documentation: https://esbuild.github.io/api/#target
-	Supported map[string]bool // Documentation: https://esbuild.github.io/api/#supported
+	Target             Target          // Documentation: https://esbuild.github.io/api/#target
+	Engines            []Engine        // Documentation: https://esbuild.github.io/api/#target
+	Supported          map[string]bool // Documentation: https://esbuild.github.io/api/#supported
+	LowerTopLevelAwait bool            // Documentation: https://esbuild.github.io/api/#lower-top-level-await
+	BigIntLibrary      string          // Documentation: https://esbuild.github.io/api/#bigint-library
 
 	MangleProps       string                 // Documentation: https://esbuild.github.io/api/#mangle-props
 	ReserveProps      string                 // Documentation: https://esbuild.github.io/api/#mangle-props
@@ -317,15 +339,19 @@ type BuildOptions struct {
 	Pure      []string          // Documentation: https://esbuild.github.io/api/#pure
 	KeepNames bool              // Documentation: https://esbuild.github.io/api/#keep-names
 
+	UMDExternals map[string]UMDExternal // Documentation: https://esbuild.github.io/api/#umd-externals
+
 	GlobalName        string            // Documentation: https://esbuild.github.io/api/#global-name
 	Bundle            bool              // Documentation: https://esbuild.github.io/api/#bundle
 	PreserveSymlinks  bool              // Documentation: https://esbuild.github.io/api/#preserve-symlinks
 	Splitting         bool              // Documentation: https://esbuild.github.io/api/#splitting
+	HMR               bool              // Documentation: https://esbuild.github.io/api/#hmr
 	Outfile           string            // Documentation: https://esbuild.github.io/api/#outfile
 	Metafile          bool              // Documentation: https://esbuild.github.io/api/#metafile
 	Outdir            string            // Documentation: https://esbuild.github.io/api/#outdir
 	Outbase           string            // Documentation: https://esbuild.github.io/api/#outbase
 	AbsWorkingDir     string            // Documentation: https://esbuild.github.io/api/#working-directory
+	CacheDir          string            // Documentation: https://esbuild.github.io/api/#cache-dir
 	Platform          Platform          // Documentation: https://esbuild.github.io/api/#platform
 	Format            Format            // Documentation: https://esbuild.github.io/api/#format
 	External          []string          // Documentation: https://esbuild.github.io/api/#external
@@ -344,9 +370,12 @@ type BuildOptions struct {
 	Footer            map[string]string // Documentation: https://esbuild.github.io/api/#footer
 	NodePaths         []string          // Documentation: https://esbuild.github.io/api/#node-paths
 
-	EntryNames string // Documentation: https://esbuild.github.io/api/#entry-names
-	ChunkNames string // Documentation: https://esbuild.github.io/api/#chunk-names
-	AssetNames string // Documentation: https://esbuild.github.io/api/#asset-names
+	EntryNames     string              // Documentation: https://esbuild.github.io/api/#entry-names
+	ChunkNames     string              // Documentation: https://esbuild.github.io/api/#chunk-names
+	AssetNames     string              // Documentation: https://esbuild.github.io/api/#asset-names
+	LocalCSSNames  string              // Documentation: https://esbuild.github.io/api/#local-css-names
+	CSSModuleTypes CSSModuleTypes      // Documentation: https://esbuild.github.io/api/#css-module-types
+	ManualChunks   map[string][]string // Documentation: https://esbuild.github.io/api/#manual-chunks
 
 	EntryPoints         []string     // Documentation: https://esbuild.github.io/api/#entry-points
 	EntryPointsAdvanced []EntryPoint // Documentation: https://esbuild.github.io/api/#entry-points
@@ -419,13 +448,15 @@ type TransformOptions struct {
 	SourceRoot     string         // Documentation: https://esbuild.github.io/api/#source-root
 	SourcesContent SourcesContent // Documentation: https://esbuild.github.io/api/#sources-content
 
-	Target    Target          // Documentation: https://esbuild.github.io/api/#target
-	Engines   []Engine        // Documentation: https://esbuild.github.io/api/#target
-	Supported map[string]bool // Documentation: https://esbuild.github.io/api/#supported
+	Target        Target          // Documentation: https://esbuild.github.io/api/#target
+	Engines       []Engine        // Documentation: https://esbuild.github.io/api/#target
+	Supported     map[string]bool // Documentation: https://esbuild.github.io/api/#supported
+	BigIntLibrary string          // Documentation: https://esbuild.github.io/api/#bigint-library
 
-	Platform   Platform // Documentation: https://esbuild.github.io/api/#platform
-	Format     Format   // Documentation: https://esbuild.github.io/api/#format
-	GlobalName string   // Documentation: https://esbuild.github.io/api/#global-name
+	Platform     Platform               // Documentation: https://esbuild.github.io/api/#platform
+	Format       Format                 // Documentation: https://esbuild.github.io/api/#format
+	GlobalName   string                 // Documentation: https://esbuild.github.io/api/#global-name
+	UMDExternals map[string]UMDExternal // Documentation: https://esbuild.github.io/api/#umd-externals
 
 	MangleProps       string                 // Documentation: https://esbuild.github.io/api/#mangle-props
 	ReserveProps      string                 // Documentation: https://esbuild.github.io/api/#mangle-props
@@ -584,6 +615,18 @@ type PluginBuild struct {
 	// Documentation: https://esbuild.github.io/plugins/#on-load
 	OnLoad func(options OnLoadOptions, callback func(OnLoadArgs) (OnLoadResult, error))
 
+	// Documentation: https://esbuild.github.io/plugins/#on-transform
+	OnTransform func(options OnTransformOptions, callback func(OnTransformArgs) (OnTransformResult, error))
+
+	// Documentation: https://esbuild.github.io/plugins/#on-manual-chunk
+	OnManualChunk func(options OnManualChunkOptions, callback func(OnManualChunkArgs) (OnManualChunkResult, error))
+
+	// Documentation: https://esbuild.github.io/plugins/#on-output
+	OnOutput func(options OnOutputOptions, callback func(OnOutputArgs) (OnOutputResult, error))
+
+	// Documentation: https://esbuild.github.io/plugins/#on-local-css-name
+	OnLocalCSSName func(options OnLocalCSSNameOptions, callback func(OnLocalCSSNameArgs) (OnLocalCSSNameResult, error))
+
 	// Documentation: https://esbuild.github.io/plugins/#on-dispose
 	OnDispose func(callback func())
 }
@@ -688,6 +731,119 @@ type OnLoadResult struct {
 	WatchDirs  []string
 }
 
+// Documentation: https://esbuild.github.io/plugins/#on-transform-options
+type OnTransformOptions struct {
+	Filter    string
+	Namespace string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-transform-arguments
+type OnTransformArgs struct {
+	Path      string
+	Namespace string
+	Suffix    string
+	Contents  string
+	Loader    Loader
+
+	// This is only present if source maps are enabled and an earlier callback
+	// returned a source map. It maps the contents back to the original file.
+	SourceMap string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-transform-results
+type OnTransformResult struct {
+	PluginName string
+
+	Errors   []Message
+	Warnings []Message
+
+	// If the contents are replaced without a source map, the contents are
+	// assumed to have the same layout as before. A returned source map maps
+	// the new contents back to the contents that were passed to the callback.
+	Contents  *string
+	SourceMap *string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-manual-chunk-options
+type OnManualChunkOptions struct {
+	Filter    string
+	Namespace string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-manual-chunk-arguments
+type OnManualChunkArgs struct {
+	Path      string
+	Namespace string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-manual-chunk-results
+type OnManualChunkResult struct {
+	PluginName string
+
+	Errors   []Message
+	Warnings []Message
+
+	// If this is non-empty, the file is forced into the chunk with this name.
+	// Otherwise the next callback is tried, then the "ManualChunks" option.
+	ChunkName string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-local-css-name-options
+type OnLocalCSSNameOptions struct {
+	Filter    string
+	Namespace string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-local-css-name-arguments
+type OnLocalCSSNameArgs struct {
+	Path      string
+	Namespace string
+	Local     string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-local-css-name-results
+type OnLocalCSSNameResult struct {
+	PluginName string
+
+	Errors   []Message
+	Warnings []Message
+
+	// If this is non-empty, the local name is renamed to this name. Otherwise
+	// the next callback is tried, then the "LocalCSSNames" option.
+	Name string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-output-options
+type OnOutputOptions struct {
+	Filter string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-output-arguments
+type OnOutputArgs struct {
+	// The final hash isn't known yet, so "[hash]" is left in the path. Paths to
+	// other output files in the contents are temporary unique keys that must be
+	// preserved. They are replaced with the final paths after hashing.
+	Path      string
+	Contents  string
+	SourceMap string
+
+	EntryPoint string
+	Modules    []string
+}
+
+// Documentation: https://esbuild.github.io/plugins/#on-output-results
+type OnOutputResult struct {
+	PluginName string
+
+	Errors   []Message
+	Warnings []Message
+
+	// If the contents are replaced without a source map, the existing source
+	// map is kept as-is. A returned source map is chained onto the existing one.
+	Contents  *string
+	SourceMap *string
+}
+
 type ResolveKind uint8
 
 const (
@@ -700,6 +856,8 @@ const (
 	ResolveCSSImportRule
 	ResolveCSSComposesFrom
 	ResolveCSSURLToken
+	ResolveHTMLScriptTag
+	ResolveHTMLLinkTag
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -44,7 +44,22 @@ func validatePathTemplate(template string) []config.PathTemplate {
 		return nil
 	}
 	template = "./" + strings.ReplaceAll(template, "\\", "/")
+	return parsePathTemplate(template, false /* allowLocal */)
+}
 
+func validateLocalCSSNames(log logger.Log, template string) []config.PathTemplate {
+	if template == "" {
+		return nil
+	}
+	parts := parsePathTemplate(template, true /* allowLocal */)
+	if config.HasPlaceholder(parts, config.ExtPlaceholder) {
+		log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid local CSS names template (\"[ext]\" is not supported): %q", template))
+		return nil
+	}
+	return parts
+}
+
+func parsePathTemplate(template string, allowLocal bool) []config.PathTemplate {
 	parts := make([]config.PathTemplate, 0, 4)
 	search := 0
 
@@ -77,6 +92,10 @@ func validatePathTemplate(template string) []config.PathTemplate {
 			placeholder = config.ExtPlaceholder
 			search += len("[ext]")
 
+		case allowLocal && strings.HasPrefix(tail, "[local]"):
+			placeholder = config.LocalPlaceholder
+			search += len("[local]")
+
 		default:
 			// Skip past the "[" so we don't find it again
 			search++
@@ -128,6 +147,10 @@ func validateFormat(value Format) config.Format {
 		return config.FormatCommonJS
 	case FormatESModule:
 		return config.FormatESModule
+	case FormatSystem:
+		return config.FormatSystem
+	case FormatUMD:
+		return config.FormatUMD
 	default:
 		panic("Invalid format")
 	}
@@ -227,6 +250,19 @@ func validateExternalPackages(value Packages) bool {
 	}
 }
 
+func validateCSSModuleTypes(value CSSModuleTypes) config.CSSModuleTypes {
+	switch value {
+	case CSSModuleTypesNone:
+		return config.CSSModuleTypesNone
+	case CSSModuleTypesDTS:
+		return config.CSSModuleTypesDTS
+	case CSSModuleTypesDCSSTS:
+		return config.CSSModuleTypesDCSSTS
+	default:
+		panic("Invalid CSS module types")
+	}
+}
+
 func validateTreeShaking(value TreeShaking, bundle bool, format Format) bool {
 	switch value {
 	case TreeShakingDefault:
@@ -236,7 +272,7 @@ func validateTreeShaking(value TreeShaking, bundle bool, format Format) bool {
 		// code to the bundle, you should be doing that by including it in the
 		// bundle instead of concatenating it afterward, so we also assume tree
 		// shaking is safe then. Otherwise we assume tree shaking is not safe.
-		return bundle || format == FormatIIFE
+		return bundle || format == FormatIIFE || format == FormatUMD
 	case TreeShakingFalse:
 		return false
 	case TreeShakingTrue:
@@ -266,6 +302,8 @@ func validateLoader(value Loader) config.Loader {
 		return config.LoaderFile
 	case LoaderGlobalCSS:
 		return config.LoaderGlobalCSS
+	case LoaderHTML:
+		return config.LoaderHTML
 	case LoaderJS:
 		return config.LoaderJS
 	case LoaderJSON:
@@ -287,6 +325,47 @@ func validateLoader(value Loader) config.Loader {
 	}
 }
 
+func convertLoaderToPublic(loader config.Loader) Loader {
+	switch loader {
+	case config.LoaderBase64:
+		return LoaderBase64
+	case config.LoaderBinary:
+		return LoaderBinary
+	case config.LoaderCopy:
+		return LoaderCopy
+	case config.LoaderCSS:
+		return LoaderCSS
+	case config.LoaderDataURL:
+		return LoaderDataURL
+	case config.LoaderDefault:
+		return LoaderDefault
+	case config.LoaderEmpty:
+		return LoaderEmpty
+	case config.LoaderFile:
+		return LoaderFile
+	case config.LoaderGlobalCSS:
+		return LoaderGlobalCSS
+	case config.LoaderHTML:
+		return LoaderHTML
+	case config.LoaderJS:
+		return LoaderJS
+	case config.LoaderJSON, config.LoaderWithTypeJSON:
+		return LoaderJSON
+	case config.LoaderJSX:
+		return LoaderJSX
+	case config.LoaderLocalCSS:
+		return LoaderLocalCSS
+	case config.LoaderText:
+		return LoaderText
+	case config.LoaderTS, config.LoaderTSNoAmbiguousLessThan:
+		return LoaderTS
+	case config.LoaderTSX:
+		return LoaderTSX
+	default:
+		return LoaderNone
+	}
+}
+
 func extractPathStyle(absPaths AbsPaths, flag AbsPaths) logger.PathStyle {
 	if (absPaths & flag) != 0 {
 		return logger.AbsPath
@@ -297,9 +376,15 @@ func extractPathStyle(absPaths AbsPaths, flag AbsPaths) logger.PathStyle {
 
 var versionRegex = regexp.MustCompile(`^([0-9]+)(?:\.([0-9]+))?(?:\.([0-9]+))?(-[A-Za-z0-9]+(?:\.[A-Za-z0-9]+)*)?$`)
 
-func validateFeatures(log logger.Log, target Target, engines []Engine) (compat.JSFeature, compat.CSSFeature, map[css_ast.D]compat.CSSPrefix, string) {
+func validateFeatures(log logger.Log, target Target, engines []Engine) (
+	compat.JSFeature,
+	compat.CSSFeature,
+	map[css_ast.D]compat.CSSPrefix,
+	map[compat.CSSPrefixedSyntax]compat.CSSPrefix,
+	string,
+) {
 	if target == DefaultTarget && len(engines) == 0 {
-		return 0, 0, nil, ""
+		return 0, 0, nil, nil, ""
 	}
 
 	constraints := make(map[compat.Engine]compat.Semver)
@@ -367,7 +452,8 @@ func validateFeatures(log logger.Log, target Target, engines []Engine) (compat.J
 	sort.Strings(targets)
 	targetEnv := helpers.StringArrayToQuotedCommaSeparatedString(targets)
 
-	return compat.UnsupportedJSFeatures(constraints), compat.UnsupportedCSSFeatures(constraints), compat.CSSPrefixData(constraints), targetEnv
+	return compat.UnsupportedJSFeatures(constraints), compat.UnsupportedCSSFeatures(constraints),
+		compat.CSSPrefixData(constraints), compat.CSSSyntaxPrefixData(constraints), targetEnv
 }
 
 func validateSupported(log logger.Log, supported map[string]bool) (
@@ -394,6 +480,27 @@ func validateSupported(log logger.Log, supported map[string]bool) (
 	return
 }
 
+func validateUMDExternals(log logger.Log, externals map[string]UMDExternal) map[string]config.UMDExternal {
+	if len(externals) == 0 {
+		return nil
+	}
+	paths := make([]string, 0, len(externals))
+	for path := range externals {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths) // Sort for determinism
+	result := make(map[string]config.UMDExternal, len(externals))
+	for _, path := range paths {
+		external := externals[path]
+		result[path] = config.UMDExternal{
+			AMD:      external.AMD,
+			CommonJS: external.CommonJS,
+			Global:   validateGlobalName(log, external.Global, fmt.Sprintf("(UMD global for %q)", path)),
+		}
+	}
+	return result
+}
+
 func validateGlobalName(log logger.Log, text string, path string) []string {
 	if text != "" {
 		source := logger.Source{
@@ -495,6 +602,76 @@ func validateAlias(log logger.Log, fs fs.FS, alias map[string]string) map[string
 	return valid
 }
 
+func validateManualChunks(log logger.Log, fs fs.FS, manualChunks map[string][]string) []config.ManualChunk {
+	if len(manualChunks) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(manualChunks))
+	for name := range manualChunks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	valid := make([]config.ManualChunk, 0, len(names))
+
+	for _, name := range names {
+		if !config.IsValidManualChunkName(name) {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid manual chunk name: %q", name))
+			continue
+		}
+		chunk := config.ManualChunk{Name: name}
+
+		for _, pattern := range manualChunks[name] {
+			var sb strings.Builder
+			sb.WriteByte('^')
+
+			if strings.HasPrefix(pattern, ".") || strings.HasPrefix(pattern, "/") || fs.IsAbs(pattern) || strings.ContainsRune(pattern, '*') {
+				// Path patterns are relative to the working directory. A path without
+				// any wildcards matches that file as well as everything inside it.
+				absPattern := pattern
+				if !fs.IsAbs(absPattern) {
+					absPattern = fs.Join(fs.Cwd(), absPattern)
+				}
+				absPattern = strings.ReplaceAll(absPattern, "\\", "/") // Avoid problems with Windows-style slashes
+				glob := helpers.ParseGlobPattern(absPattern)
+				for i, part := range glob {
+					sb.WriteString(regexp.QuoteMeta(part.Prefix))
+					switch part.Wildcard {
+					case helpers.GlobAllExceptSlash:
+						sb.WriteString("[^/]*")
+					case helpers.GlobAllIncludingSlash:
+						// Let "a/**/b" also match "a/b"
+						if next := &glob[i+1]; strings.HasPrefix(next.Prefix, "/") {
+							sb.WriteString("(?:.*/)?")
+							next.Prefix = next.Prefix[1:]
+						} else {
+							sb.WriteString(".*")
+						}
+					}
+				}
+				if len(glob) == 1 {
+					sb.WriteString("(?:/.*)?")
+				}
+			} else if path.Clean(strings.ReplaceAll(pattern, "\\", "/")) == pattern {
+				// Package names match anything inside that package
+				sb.WriteString(".*/node_modules/")
+				sb.WriteString(regexp.QuoteMeta(pattern))
+				sb.WriteString("/.*")
+			} else {
+				log.AddError(nil, logger.Range{}, fmt.Sprintf("Invalid pattern for manual chunk %q: %q", name, pattern))
+				continue
+			}
+
+			sb.WriteByte('$')
+			chunk.Patterns = append(chunk.Patterns, regexp.MustCompile(sb.String()))
+		}
+
+		valid = append(valid, chunk)
+	}
+
+	return valid
+}
+
 func isValidExtension(ext string) bool {
 	return len(ext) >= 2 && ext[0] == '.' && ext[len(ext)-1] != '.'
 }
@@ -719,6 +896,16 @@ func validatePath(log logger.Log, fs fs.FS, relPath string, pathKind string) str
 	return absPath
 }
 
+func validateBigIntLibrary(log logger.Log, fs fs.FS, path string, bundle bool) string {
+	// Like "inject", relative paths are relative to the working directory
+	// instead of to each file that uses a big integer. This only makes sense
+	// when bundling since otherwise the path ends up in the output.
+	if bundle && (strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")) {
+		return validatePath(log, fs, path, "bigint library path")
+	}
+	return path
+}
+
 func validateOutputExtensions(log logger.Log, outExtensions map[string]string) (js string, css string) {
 	for key, value := range outExtensions {
 		if !isValidExtension(value) {
@@ -914,6 +1101,15 @@ func contextImpl(buildOpts BuildOptions) (*internalContext, []Message) {
 		panic("Mutating \"AbsWorkingDir\" is not allowed")
 	}
 
+	// The on-disk cache is shared by all builds from this context
+	if cacheDir := validatePath(log, realFS, buildOpts.CacheDir, "cache directory"); cacheDir != "" {
+		if disk, err := cache.NewDiskCache(cacheDir, api_helpers.ESBuildVersion); err != nil {
+			log.AddError(nil, logger.Range{}, fmt.Sprintf("Cannot use cache directory %q: %s", cacheDir, err.Error()))
+		} else {
+			caches.EnableDiskCache(disk)
+		}
+	}
+
 	// If we have errors already, then refuse to build any further. This only
 	// happens when the build options themselves contain validation errors.
 	msgs := log.Done()
@@ -1006,7 +1202,7 @@ func (ctx *internalContext) rebuild() rebuildState {
 	var newHashes map[string]string
 	build.state, newHashes = rebuildImpl(args, oldHashes)
 	if handler != nil {
-		handler.broadcastBuildResult(build.state.result, newHashes)
+		handler.broadcastBuildResult(build.state.result, newHashes, build.state.hmrChunks)
 	}
 	if watcher != nil {
 		watcher.setWatchData(build.state.watchData)
@@ -1236,7 +1432,7 @@ func validateBuildOptions(
 	options config.Options,
 	entryPoints []bundler.EntryPoint,
 ) {
-	jsFeatures, cssFeatures, cssPrefixData, targetEnv := validateFeatures(log, buildOpts.Target, buildOpts.Engines)
+	jsFeatures, cssFeatures, cssPrefixData, cssSyntaxPrefixData, targetEnv := validateFeatures(log, buildOpts.Target, buildOpts.Engines)
 	jsOverrides, jsMask, cssOverrides, cssMask := validateSupported(log, buildOpts.Supported)
 	outJS, outCSS := validateOutputExtensions(log, buildOpts.OutExtension)
 	bannerJS, bannerCSS := validateBannerOrFooter(log, "banner", buildOpts.Banner)
@@ -1246,6 +1442,7 @@ func validateBuildOptions(
 	defines, injectedDefines := validateDefines(log, buildOpts.Define, buildOpts.Pure, platform, true /* isBuildAPI */, minify, buildOpts.Drop)
 	options = config.Options{
 		CSSPrefixData:                      cssPrefixData,
+		CSSSyntaxPrefixData:                cssSyntaxPrefixData,
 		UnsupportedJSFeatures:              jsFeatures.ApplyOverrides(jsOverrides, jsMask),
 		UnsupportedCSSFeatures:             cssFeatures.ApplyOverrides(cssOverrides, cssMask),
 		UnsupportedJSFeatureOverrides:      jsOverrides,
@@ -1283,7 +1480,12 @@ func validateBuildOptions(
 		IgnoreDCEAnnotations:  buildOpts.IgnoreAnnotations,
 		TreeShaking:           validateTreeShaking(buildOpts.TreeShaking, buildOpts.Bundle, buildOpts.Format),
 		GlobalName:            validateGlobalName(log, buildOpts.GlobalName, "(global name)"),
+		UMDExternals:          validateUMDExternals(log, buildOpts.UMDExternals),
 		CodeSplitting:         buildOpts.Splitting,
+		ManualChunks:          validateManualChunks(log, realFS, buildOpts.ManualChunks),
+		HotModuleReplacement:  buildOpts.HMR,
+		LowerTopLevelAwait:    buildOpts.LowerTopLevelAwait,
+		BigIntLibrary:         validateBigIntLibrary(log, realFS, buildOpts.BigIntLibrary, buildOpts.Bundle),
 		OutputFormat:          validateFormat(buildOpts.Format),
 		AbsOutputFile:         validatePath(log, realFS, buildOpts.Outfile, "outfile path"),
 		AbsOutputDir:          validatePath(log, realFS, buildOpts.Outdir, "outdir path"),
@@ -1292,6 +1494,8 @@ func validateBuildOptions(
 		EntryPathTemplate:     validatePathTemplate(buildOpts.EntryNames),
 		ChunkPathTemplate:     validatePathTemplate(buildOpts.ChunkNames),
 		AssetPathTemplate:     validatePathTemplate(buildOpts.AssetNames),
+		LocalCSSNameTemplate:  validateLocalCSSNames(log, buildOpts.LocalCSSNames),
+		CSSModuleTypes:        validateCSSModuleTypes(buildOpts.CSSModuleTypes),
 		OutputExtensionJS:     outJS,
 		OutputExtensionCSS:    outCSS,
 		ExtensionToLoader:     validateLoaders(log, buildOpts.Loader),
@@ -1419,9 +1623,36 @@ func validateBuildOptions(
 		options.Conditions = []string{"module"}
 	}
 
-	// Code splitting is experimental and currently only enabled for ES6 modules
-	if options.CodeSplitting && options.OutputFormat != config.FormatESModule {
-		log.AddError(nil, logger.Range{}, "Splitting currently only works with the \"esm\" format")
+	// Code splitting needs an output format that can load other chunks
+	if options.CodeSplitting {
+		if options.OutputFormat == config.FormatPreserve {
+			log.AddError(nil, logger.Range{}, "Splitting requires the \"esm\", \"cjs\", \"iife\", or \"system\" format")
+		} else if options.OutputFormat == config.FormatIIFE && len(options.GlobalName) > 0 {
+			log.AddError(nil, logger.Range{}, "Cannot use \"globalName\" with splitting and the \"iife\" format")
+		} else if options.OutputFormat == config.FormatUMD {
+			log.AddError(nil, logger.Range{}, "Splitting is not supported with the \"umd\" format")
+		}
+	}
+
+	// Manual chunks only make sense when there are chunks to put files in
+	if len(options.ManualChunks) > 0 && !options.CodeSplitting {
+		log.AddError(nil, logger.Range{}, "Cannot use \"manualChunks\" without \"splitting\"")
+	}
+
+	// Lowering top-level await relies on the linker wrapping modules
+	if options.LowerTopLevelAwait && !buildOpts.Bundle {
+		log.AddError(nil, logger.Range{}, "Lowering top-level await requires bundling to be enabled")
+	}
+
+	// Hot module replacement relies on the linker wrapping every module
+	if options.HotModuleReplacement {
+		if !buildOpts.Bundle {
+			log.AddError(nil, logger.Range{}, "Hot module replacement requires bundling to be enabled")
+		} else if options.CodeSplitting {
+			log.AddError(nil, logger.Range{}, "Hot module replacement currently doesn't work with splitting")
+		} else if options.OutputFormat != config.FormatESModule && options.OutputFormat != config.FormatIIFE {
+			log.AddError(nil, logger.Range{}, "Hot module replacement currently only works with the \"esm\" and \"iife\" formats")
+		}
 	}
 
 	// Code splitting is experimental and currently only enabled for ES6 modules
@@ -1460,6 +1691,9 @@ type rebuildState struct {
 	result    BuildResult
 	watchData fs.WatchData
 	options   config.Options
+
+	// This is only used by the dev server for hot module replacement
+	hmrChunks map[string]*graph.HMRChunk
 }
 
 func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, map[string]string) {
@@ -1518,6 +1752,7 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 
 	// Populate the results to return
 	var hashBytes [8]byte
+	var hmrChunks map[string]*graph.HMRChunk
 	result.OutputFiles = make([]OutputFile, len(results))
 	newHashes = make(map[string]string)
 	for i, item := range results {
@@ -1534,6 +1769,12 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 			Hash:     hash,
 		}
 		newHashes[item.AbsPath] = hash
+		if item.HMR != nil {
+			if hmrChunks == nil {
+				hmrChunks = make(map[string]*graph.HMRChunk)
+			}
+			hmrChunks[item.AbsPath] = item.HMR
+		}
 	}
 
 	// Write output files before "OnEnd" callbacks run so they can expect
@@ -1676,6 +1917,7 @@ func rebuildImpl(args rebuildArgs, oldHashes map[string]string) (rebuildState, m
 		result:    result,
 		options:   args.options,
 		watchData: watchData,
+		hmrChunks: hmrChunks,
 	}, newHashes
 }
 
@@ -1702,13 +1944,14 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 	}
 
 	// Convert and validate the transformOpts
-	jsFeatures, cssFeatures, cssPrefixData, targetEnv := validateFeatures(log, transformOpts.Target, transformOpts.Engines)
+	jsFeatures, cssFeatures, cssPrefixData, cssSyntaxPrefixData, targetEnv := validateFeatures(log, transformOpts.Target, transformOpts.Engines)
 	jsOverrides, jsMask, cssOverrides, cssMask := validateSupported(log, transformOpts.Supported)
 	platform := validatePlatform(transformOpts.Platform)
 	defines, injectedDefines := validateDefines(log, transformOpts.Define, transformOpts.Pure, platform, false /* isBuildAPI */, false /* minify */, transformOpts.Drop)
 	mangleCache := cloneMangleCache(log, transformOpts.MangleCache)
 	options := config.Options{
 		CSSPrefixData:                      cssPrefixData,
+		CSSSyntaxPrefixData:                cssSyntaxPrefixData,
 		UnsupportedJSFeatures:              jsFeatures.ApplyOverrides(jsOverrides, jsMask),
 		UnsupportedCSSFeatures:             cssFeatures.ApplyOverrides(cssOverrides, cssMask),
 		UnsupportedJSFeatureOverrides:      jsOverrides,
@@ -1735,6 +1978,7 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		ExcludeSourcesContent: transformOpts.SourcesContent == SourcesContentExclude,
 		OutputFormat:          validateFormat(transformOpts.Format),
 		GlobalName:            validateGlobalName(log, transformOpts.GlobalName, "(global name)"),
+		UMDExternals:          validateUMDExternals(log, transformOpts.UMDExternals),
 		MinifySyntax:          transformOpts.MinifySyntax,
 		MinifyWhitespace:      transformOpts.MinifyWhitespace,
 		MinifyIdentifiers:     transformOpts.MinifyIdentifiers,
@@ -1749,6 +1993,7 @@ func transformImpl(input string, transformOpts TransformOptions) TransformResult
 		TreeShaking:           validateTreeShaking(transformOpts.TreeShaking, false /* bundle */, transformOpts.Format),
 		AbsOutputFile:         transformOpts.Sourcefile + "-out",
 		KeepNames:             transformOpts.KeepNames,
+		BigIntLibrary:         validateBigIntLibrary(log, nil, transformOpts.BigIntLibrary, false /* bundle */),
 		CodePathStyle:         extractPathStyle(transformOpts.AbsPaths, CodeAbsPath),
 		LogPathStyle:          extractPathStyle(transformOpts.AbsPaths, LogAbsPath),
 		MetafilePathStyle:     extractPathStyle(transformOpts.AbsPaths, MetafileAbsPath),
@@ -1894,6 +2139,10 @@ func importKindToResolveKind(kind ast.ImportKind) ResolveKind {
 		return ResolveCSSComposesFrom
 	case ast.ImportURL:
 		return ResolveCSSURLToken
+	case ast.ImportHTMLScript:
+		return ResolveHTMLScriptTag
+	case ast.ImportHTMLLink:
+		return ResolveHTMLLinkTag
 	default:
 		panic("Internal error")
 	}
@@ -1917,6 +2166,10 @@ func resolveKindToImportKind(kind ResolveKind) ast.ImportKind {
 		return ast.ImportComposesFrom
 	case ResolveCSSURLToken:
 		return ast.ImportURL
+	case ResolveHTMLScriptTag:
+		return ast.ImportHTMLScript
+	case ResolveHTMLLinkTag:
+		return ast.ImportHTMLLink
 	default:
 		panic("Internal error")
 	}
@@ -2000,6 +2253,139 @@ func (impl *pluginImpl) onResolve(options OnResolveOptions, callback func(OnReso
 	})
 }
 
+func (impl *pluginImpl) onTransform(options OnTransformOptions, callback func(OnTransformArgs) (OnTransformResult, error)) {
+	filter, err := config.CompileFilterForPlugin(impl.plugin.Name, "OnTransform", options.Filter)
+	if filter == nil {
+		impl.log.AddError(nil, logger.Range{}, err.Error())
+		return
+	}
+
+	impl.plugin.OnTransform = append(impl.plugin.OnTransform, config.OnTransform{
+		Filter:    filter,
+		Namespace: options.Namespace,
+		Callback: func(args config.OnTransformArgs) (result config.OnTransformResult) {
+			response, err := callback(OnTransformArgs{
+				Path:      args.Path.Text,
+				Namespace: args.Path.Namespace,
+				Suffix:    args.Path.IgnoredSuffix,
+				Contents:  args.Contents,
+				Loader:    convertLoaderToPublic(args.Loader),
+				SourceMap: args.SourceMap,
+			})
+			result.PluginName = response.PluginName
+
+			if err != nil {
+				result.ThrownError = err
+				return
+			}
+
+			result.Contents = response.Contents
+			result.SourceMap = response.SourceMap
+
+			// Convert log messages
+			result.Msgs = convertErrorsAndWarningsToInternal(response.Errors, response.Warnings)
+			return
+		},
+	})
+}
+
+func (impl *pluginImpl) onManualChunk(options OnManualChunkOptions, callback func(OnManualChunkArgs) (OnManualChunkResult, error)) {
+	filter, err := config.CompileFilterForPlugin(impl.plugin.Name, "OnManualChunk", options.Filter)
+	if filter == nil {
+		impl.log.AddError(nil, logger.Range{}, err.Error())
+		return
+	}
+
+	impl.plugin.OnManualChunk = append(impl.plugin.OnManualChunk, config.OnManualChunk{
+		Filter:    filter,
+		Namespace: options.Namespace,
+		Callback: func(args config.OnManualChunkArgs) (result config.OnManualChunkResult) {
+			response, err := callback(OnManualChunkArgs{
+				Path:      args.Path.Text,
+				Namespace: args.Path.Namespace,
+			})
+			result.PluginName = response.PluginName
+
+			if err != nil {
+				result.ThrownError = err
+				return
+			}
+
+			result.ChunkName = response.ChunkName
+
+			// Convert log messages
+			result.Msgs = convertErrorsAndWarningsToInternal(response.Errors, response.Warnings)
+			return
+		},
+	})
+}
+
+func (impl *pluginImpl) onLocalCSSName(options OnLocalCSSNameOptions, callback func(OnLocalCSSNameArgs) (OnLocalCSSNameResult, error)) {
+	filter, err := config.CompileFilterForPlugin(impl.plugin.Name, "OnLocalCSSName", options.Filter)
+	if filter == nil {
+		impl.log.AddError(nil, logger.Range{}, err.Error())
+		return
+	}
+
+	impl.plugin.OnLocalCSSName = append(impl.plugin.OnLocalCSSName, config.OnLocalCSSName{
+		Filter:    filter,
+		Namespace: options.Namespace,
+		Callback: func(args config.OnLocalCSSNameArgs) (result config.OnLocalCSSNameResult) {
+			response, err := callback(OnLocalCSSNameArgs{
+				Path:      args.Path.Text,
+				Namespace: args.Path.Namespace,
+				Local:     args.Local,
+			})
+			result.PluginName = response.PluginName
+
+			if err != nil {
+				result.ThrownError = err
+				return
+			}
+
+			result.Name = response.Name
+
+			// Convert log messages
+			result.Msgs = convertErrorsAndWarningsToInternal(response.Errors, response.Warnings)
+			return
+		},
+	})
+}
+
+func (impl *pluginImpl) onOutput(options OnOutputOptions, callback func(OnOutputArgs) (OnOutputResult, error)) {
+	filter, err := config.CompileFilterForPlugin(impl.plugin.Name, "OnOutput", options.Filter)
+	if filter == nil {
+		impl.log.AddError(nil, logger.Range{}, err.Error())
+		return
+	}
+
+	impl.plugin.OnOutput = append(impl.plugin.OnOutput, config.OnOutput{
+		Filter: filter,
+		Callback: func(args config.OnOutputArgs) (result config.OnOutputResult) {
+			response, err := callback(OnOutputArgs{
+				Path:       args.Path,
+				Contents:   args.Contents,
+				SourceMap:  args.SourceMap,
+				EntryPoint: args.EntryPoint,
+				Modules:    args.Modules,
+			})
+			result.PluginName = response.PluginName
+
+			if err != nil {
+				result.ThrownError = err
+				return
+			}
+
+			result.Contents = response.Contents
+			result.SourceMap = response.SourceMap
+
+			// Convert log messages
+			result.Msgs = convertErrorsAndWarningsToInternal(response.Errors, response.Warnings)
+			return
+		},
+	})
+}
+
 func (impl *pluginImpl) onLoad(options OnLoadOptions, callback func(OnLoadArgs) (OnLoadResult, error)) {
 	filter, err := config.CompileFilterForPlugin(impl.plugin.Name, "OnLoad", options.Filter)
 	if filter == nil {
@@ -2175,6 +2561,10 @@ func loadPlugins(initialOptions *BuildOptions, fs fs.FS, log logger.Log, caches
 			OnDispose:      onDispose,
 			OnResolve:      impl.onResolve,
 			OnLoad:         impl.onLoad,
+			OnTransform:    impl.onTransform,
+			OnManualChunk:  impl.onManualChunk,
+			OnOutput:       impl.onOutput,
+			OnLocalCSSName: impl.onLocalCSSName,
 		})
 
 		plugins = append(plugins, impl.plugin)
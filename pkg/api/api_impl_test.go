@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/logger"
 	"github.com/evanw/esbuild/internal/test"
 )
 
@@ -55,3 +57,51 @@ func TestStripDirPrefix(t *testing.T) {
 	expectFailure(`C:\foo\bar`, `C:\fo`, `\/`)
 	expectFailure(`C:/foo/bar`, `C:\foo`, `\/`)
 }
+
+func TestValidateManualChunks(t *testing.T) {
+	mockFS := fs.MockFS(nil, fs.MockUnix, "/project")
+
+	expectMatches := func(pattern string, path string, expected bool) {
+		t.Helper()
+		t.Run(fmt.Sprintf("pattern=%s path=%s", pattern, path), func(t *testing.T) {
+			t.Helper()
+			log := logger.NewDeferLog(logger.DeferLogAll, nil)
+			chunks := validateManualChunks(log, mockFS, map[string][]string{"chunk": {pattern}})
+			if log.HasErrors() || len(chunks) != 1 {
+				t.Fatalf("Unexpected failure")
+			}
+			test.AssertEqual(t, chunks[0].Matches(logger.Path{Text: path, Namespace: "file"}), expected)
+		})
+	}
+
+	expectError := func(manualChunks map[string][]string, expected string) {
+		t.Helper()
+		t.Run(expected, func(t *testing.T) {
+			t.Helper()
+			log := logger.NewDeferLog(logger.DeferLogAll, nil)
+			validateManualChunks(log, mockFS, manualChunks)
+			msgs := log.Done()
+			if len(msgs) != 1 {
+				t.Fatalf("Expected one message")
+			}
+			test.AssertEqualWithDiff(t, msgs[0].Data.Text, expected)
+		})
+	}
+
+	expectMatches("react", "/project/node_modules/react/index.js", true)
+	expectMatches("react", "/project/node_modules/react-dom/index.js", false)
+	expectMatches("react", "/project/node_modules/.pnpm/react@18/node_modules/react/index.js", true)
+	expectMatches("@scope/pkg", "/project/node_modules/@scope/pkg/lib/a.js", true)
+	expectMatches("./src/utils", "/project/src/utils/a.js", true)
+	expectMatches("./src/utils", "/project/src/utils.js", false)
+	expectMatches("./src/*.js", "/project/src/a.js", true)
+	expectMatches("./src/*.js", "/project/src/lib/a.js", false)
+	expectMatches("src/**/*.js", "/project/src/lib/a.js", true)
+	expectMatches("src/**/*.js", "/project/src/a.js", true)
+	expectMatches("src/**/*.js", "/project/srca.js", false)
+	expectMatches("/other/**", "/other/a/b.js", true)
+
+	expectError(map[string][]string{"": {"react"}}, "Invalid manual chunk name: \"\"")
+	expectError(map[string][]string{"a/b": {"react"}}, "Invalid manual chunk name: \"a/b\"")
+	expectError(map[string][]string{"vendor": {"react/../x"}}, "Invalid pattern for manual chunk \"vendor\": \"react/../x\"")
+}
@@ -14,6 +14,7 @@ package api
 // build results.
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"net"
@@ -29,6 +30,7 @@ import (
 	"time"
 
 	"github.com/evanw/esbuild/internal/fs"
+	"github.com/evanw/esbuild/internal/graph"
 	"github.com/evanw/esbuild/internal/helpers"
 	"github.com/evanw/esbuild/internal/logger"
 )
@@ -53,6 +55,7 @@ type apiHandler struct {
 	serveWaitGroup   sync.WaitGroup
 	activeStreams    []chan serverSentEvent
 	currentHashes    map[string]string
+	currentHMRChunks map[string]*graph.HMRChunk
 	mutex            sync.Mutex
 }
 
@@ -463,12 +466,13 @@ func (h *apiHandler) serveEventStream(start time.Time, req *http.Request, res ht
 	res.Write([]byte("500 - Event stream error"))
 }
 
-func (h *apiHandler) broadcastBuildResult(result BuildResult, newHashes map[string]string) {
+func (h *apiHandler) broadcastBuildResult(result BuildResult, newHashes map[string]string, newHMRChunks map[string]*graph.HMRChunk) {
 	h.mutex.Lock()
 
 	var added []string
 	var removed []string
 	var updated []string
+	var hmrUpdates []string
 
 	urlForPath := func(absPath string) (string, bool) {
 		if relPath, ok := stripDirPrefix(absPath, h.absOutputDir, "\\/"); ok {
@@ -488,7 +492,9 @@ func (h *apiHandler) broadcastBuildResult(result BuildResult, newHashes map[stri
 	// make it appear as if all files were removed when there is a build error.
 	if len(result.Errors) == 0 {
 		oldHashes := h.currentHashes
+		oldHMRChunks := h.currentHMRChunks
 		h.currentHashes = newHashes
+		h.currentHMRChunks = newHMRChunks
 
 		for absPath, newHash := range newHashes {
 			if oldHash, ok := oldHashes[absPath]; !ok {
@@ -496,7 +502,13 @@ func (h *apiHandler) broadcastBuildResult(result BuildResult, newHashes map[stri
 					added = append(added, url)
 				}
 			} else if newHash != oldHash {
-				if url, ok := urlForPath(absPath); ok {
+				// Only send the modules that changed if the page can swap them out.
+				// Otherwise this is reported as a normal change to the file.
+				if json, ok := hmrUpdateForChunk(oldHMRChunks[absPath], newHMRChunks[absPath]); ok {
+					if json != "" {
+						hmrUpdates = append(hmrUpdates, json)
+					}
+				} else if url, ok := urlForPath(absPath); ok {
 					updated = append(updated, url)
 				}
 			}
@@ -551,9 +563,57 @@ func (h *apiHandler) broadcastBuildResult(result BuildResult, newHashes map[stri
 		}
 	}
 
+	// Hot module replacement updates are sent as a separate event. These are
+	// handled by the runtime code in each chunk instead of by user code.
+	if len(hmrUpdates) > 0 {
+		sort.Strings(hmrUpdates)
+		json := fmt.Sprintf("{\"updated\":[%s]}", strings.Join(hmrUpdates, ","))
+		for _, stream := range h.activeStreams {
+			stream <- serverSentEvent{event: "hmr", data: json}
+		}
+	}
+
 	h.mutex.Unlock()
 }
 
+// This returns false if the modules in the chunk can't be swapped out at
+// run-time, in which case the page needs to reload the whole chunk instead.
+// Otherwise this returns the JSON for the modules that changed, or the empty
+// string if none of them changed.
+func hmrUpdateForChunk(oldChunk *graph.HMRChunk, newChunk *graph.HMRChunk) (string, bool) {
+	if oldChunk == nil || newChunk == nil || oldChunk.EntryID != newChunk.EntryID || oldChunk.ShellHash != newChunk.ShellHash {
+		return "", false
+	}
+
+	oldCode := make(map[string][]byte, len(oldChunk.Modules))
+	for _, module := range oldChunk.Modules {
+		oldCode[module.ID] = module.Code
+	}
+
+	var sb strings.Builder
+	for _, module := range newChunk.Modules {
+		if code, ok := oldCode[module.ID]; ok && bytes.Equal(code, module.Code) {
+			continue
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("{\"entry\":")
+			sb.Write(helpers.QuoteForJSON(newChunk.EntryID, false))
+			sb.WriteString(",\"modules\":[")
+		} else {
+			sb.WriteRune(',')
+		}
+		sb.WriteString("{\"id\":")
+		sb.Write(helpers.QuoteForJSON(module.ID, false))
+		sb.WriteString(",\"code\":")
+		sb.Write(helpers.QuoteForJSON(string(module.Code), false))
+		sb.WriteRune('}')
+	}
+	if sb.Len() > 0 {
+		sb.WriteString("]}")
+	}
+	return sb.String(), true
+}
+
 // Handle enough of the range specification so that video playback works in Safari
 func parseRangeHeader(r string, contentLength int) (int, int, bool) {
 	if strings.HasPrefix(r, "bytes=") {
@@ -3,7 +3,11 @@
 
 package api
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/evanw/esbuild/internal/graph"
+)
 
 // Remove the serve API in the WebAssembly build. This removes 2.7mb of stuff.
 
@@ -14,7 +18,7 @@ func (*internalContext) Serve(ServeOptions) (ServeResult, error) {
 type apiHandler struct {
 }
 
-func (*apiHandler) broadcastBuildResult(BuildResult, map[string]string) {
+func (*apiHandler) broadcastBuildResult(BuildResult, map[string]string, map[string]*graph.HMRChunk) {
 }
 
 func (*apiHandler) stop() {
@@ -114,6 +114,20 @@ func parseOptionsImpl(
 				buildOpts.Splitting = value
 			}
 
+		case isBoolFlag(arg, "--hmr") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.HMR = value
+			}
+
+		case isBoolFlag(arg, "--lower-top-level-await") && buildOpts != nil:
+			if value, err := parseBoolFlag(arg, true); err != nil {
+				return parseOptionsExtras{}, err
+			} else {
+				buildOpts.LowerTopLevelAwait = value
+			}
+
 		case isBoolFlag(arg, "--allow-overwrite") && buildOpts != nil:
 			if value, err := parseBoolFlag(arg, true); err != nil {
 				return parseOptionsExtras{}, err
@@ -430,6 +444,9 @@ func parseOptionsImpl(
 		case strings.HasPrefix(arg, "--outbase=") && buildOpts != nil:
 			buildOpts.Outbase = arg[len("--outbase="):]
 
+		case strings.HasPrefix(arg, "--cache-dir=") && buildOpts != nil:
+			buildOpts.CacheDir = arg[len("--cache-dir="):]
+
 		case strings.HasPrefix(arg, "--tsconfig=") && buildOpts != nil:
 			buildOpts.Tsconfig = arg[len("--tsconfig="):]
 
@@ -449,6 +466,27 @@ func parseOptionsImpl(
 		case strings.HasPrefix(arg, "--asset-names=") && buildOpts != nil:
 			buildOpts.AssetNames = arg[len("--asset-names="):]
 
+		case strings.HasPrefix(arg, "--local-css-names=") && buildOpts != nil:
+			buildOpts.LocalCSSNames = arg[len("--local-css-names="):]
+
+		case strings.HasPrefix(arg, "--css-module-types=") && buildOpts != nil:
+			value := arg[len("--css-module-types="):]
+			var cssModuleTypes api.CSSModuleTypes
+			switch value {
+			case "none":
+				cssModuleTypes = api.CSSModuleTypesNone
+			case "d.ts":
+				cssModuleTypes = api.CSSModuleTypesDTS
+			case "d.css.ts":
+				cssModuleTypes = api.CSSModuleTypesDCSSTS
+			default:
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value, arg),
+					"Valid values are \"none\", \"d.ts\", or \"d.css.ts\".",
+				)
+			}
+			buildOpts.CSSModuleTypes = cssModuleTypes
+
 		case strings.HasPrefix(arg, "--define:"):
 			value := arg[len("--define:"):]
 			equals := strings.IndexByte(value, '=')
@@ -634,10 +672,14 @@ func parseOptionsImpl(
 				format = api.FormatCommonJS
 			case "esm":
 				format = api.FormatESModule
+			case "system":
+				format = api.FormatSystem
+			case "umd":
+				format = api.FormatUMD
 			default:
 				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
 					fmt.Sprintf("Invalid value %q in %q", value, arg),
-					"Valid values are \"iife\", \"cjs\", or \"esm\".",
+					"Valid values are \"iife\", \"cjs\", \"esm\", \"system\", or \"umd\".",
 				)
 			}
 			if buildOpts != nil {
@@ -683,6 +725,52 @@ func parseOptionsImpl(
 			}
 			buildOpts.Alias[value[:equals]] = value[equals+1:]
 
+		case strings.HasPrefix(arg, "--manual-chunk:") && buildOpts != nil:
+			value := arg[len("--manual-chunk:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the chunk name and the pattern. "+
+						"For example, \"--manual-chunk:vendor=react\" puts the \"react\" package into a chunk called \"vendor\".",
+				)
+			}
+			if buildOpts.ManualChunks == nil {
+				buildOpts.ManualChunks = make(map[string][]string)
+			}
+			name := value[:equals]
+			buildOpts.ManualChunks[name] = append(buildOpts.ManualChunks[name], value[equals+1:])
+
+		case strings.HasPrefix(arg, "--umd-external:"):
+			value := arg[len("--umd-external:"):]
+			equals := strings.IndexByte(value, '=')
+			if equals == -1 {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Missing \"=\" in %q", arg),
+					"You need to use \"=\" to specify both the import path and how to load it. "+
+						"For example, \"--umd-external:react=React\" loads \"react\" from the global variable \"React\".",
+				)
+			}
+			external, ok := parseUMDExternal(value[equals+1:])
+			if !ok {
+				return parseOptionsExtras{}, cli_helpers.MakeErrorWithNote(
+					fmt.Sprintf("Invalid value %q in %q", value[equals+1:], arg),
+					"Valid values are either a global variable name or a comma-separated list of "+
+						"\"amd:\", \"cjs:\", and \"global:\" entries such as \"amd:lib/react,global:React\".",
+				)
+			}
+			if buildOpts != nil {
+				if buildOpts.UMDExternals == nil {
+					buildOpts.UMDExternals = make(map[string]api.UMDExternal)
+				}
+				buildOpts.UMDExternals[value[:equals]] = external
+			} else {
+				if transformOpts.UMDExternals == nil {
+					transformOpts.UMDExternals = make(map[string]api.UMDExternal)
+				}
+				transformOpts.UMDExternals[value[:equals]] = external
+			}
+
 		case strings.HasPrefix(arg, "--jsx="):
 			value := arg[len("--jsx="):]
 			var mode api.JSX
@@ -721,6 +809,14 @@ func parseOptionsImpl(
 				transformOpts.JSXFragment = value
 			}
 
+		case strings.HasPrefix(arg, "--bigint-library="):
+			value := arg[len("--bigint-library="):]
+			if buildOpts != nil {
+				buildOpts.BigIntLibrary = value
+			} else {
+				transformOpts.BigIntLibrary = value
+			}
+
 		case strings.HasPrefix(arg, "--jsx-import-source="):
 			value := arg[len("--jsx-import-source="):]
 			if buildOpts != nil {
@@ -855,82 +951,90 @@ func parseOptionsImpl(
 
 		default:
 			bare := map[string]bool{
-				"allow-overwrite":    true,
-				"bundle":             true,
-				"ignore-annotations": true,
-				"jsx-dev":            true,
-				"jsx-side-effects":   true,
-				"keep-names":         true,
-				"minify-identifiers": true,
-				"minify-syntax":      true,
-				"minify-whitespace":  true,
-				"minify":             true,
-				"preserve-symlinks":  true,
-				"sourcemap":          true,
-				"splitting":          true,
-				"watch":              true,
+				"allow-overwrite":       true,
+				"bundle":                true,
+				"hmr":                   true,
+				"ignore-annotations":    true,
+				"jsx-dev":               true,
+				"jsx-side-effects":      true,
+				"keep-names":            true,
+				"lower-top-level-await": true,
+				"minify-identifiers":    true,
+				"minify-syntax":         true,
+				"minify-whitespace":     true,
+				"minify":                true,
+				"preserve-symlinks":     true,
+				"sourcemap":             true,
+				"splitting":             true,
+				"watch":                 true,
 			}
 
 			equals := map[string]bool{
-				"abs-paths":          true,
-				"allow-overwrite":    true,
-				"asset-names":        true,
-				"banner":             true,
-				"bundle":             true,
-				"certfile":           true,
-				"charset":            true,
-				"chunk-names":        true,
-				"color":              true,
-				"conditions":         true,
-				"cors-origin":        true,
-				"drop-labels":        true,
-				"entry-names":        true,
-				"footer":             true,
-				"format":             true,
-				"global-name":        true,
-				"ignore-annotations": true,
-				"jsx-factory":        true,
-				"jsx-fragment":       true,
-				"jsx-import-source":  true,
-				"jsx":                true,
-				"keep-names":         true,
-				"keyfile":            true,
-				"legal-comments":     true,
-				"loader":             true,
-				"log-level":          true,
-				"log-limit":          true,
-				"main-fields":        true,
-				"mangle-cache":       true,
-				"mangle-props":       true,
-				"mangle-quoted":      true,
-				"metafile":           true,
-				"minify-identifiers": true,
-				"minify-syntax":      true,
-				"minify-whitespace":  true,
-				"minify":             true,
-				"outbase":            true,
-				"outdir":             true,
-				"outfile":            true,
-				"packages":           true,
-				"platform":           true,
-				"preserve-symlinks":  true,
-				"public-path":        true,
-				"reserve-props":      true,
-				"resolve-extensions": true,
-				"serve-fallback":     true,
-				"serve":              true,
-				"servedir":           true,
-				"source-root":        true,
-				"sourcefile":         true,
-				"sourcemap":          true,
-				"sources-content":    true,
-				"splitting":          true,
-				"target":             true,
-				"tree-shaking":       true,
-				"tsconfig-raw":       true,
-				"tsconfig":           true,
-				"watch":              true,
-				"watch-delay":        true,
+				"abs-paths":             true,
+				"allow-overwrite":       true,
+				"asset-names":           true,
+				"banner":                true,
+				"bigint-library":        true,
+				"bundle":                true,
+				"cache-dir":             true,
+				"certfile":              true,
+				"charset":               true,
+				"chunk-names":           true,
+				"color":                 true,
+				"conditions":            true,
+				"cors-origin":           true,
+				"css-module-types":      true,
+				"drop-labels":           true,
+				"entry-names":           true,
+				"footer":                true,
+				"format":                true,
+				"global-name":           true,
+				"hmr":                   true,
+				"ignore-annotations":    true,
+				"jsx-factory":           true,
+				"jsx-fragment":          true,
+				"jsx-import-source":     true,
+				"jsx":                   true,
+				"keep-names":            true,
+				"keyfile":               true,
+				"legal-comments":        true,
+				"loader":                true,
+				"local-css-names":       true,
+				"log-level":             true,
+				"log-limit":             true,
+				"lower-top-level-await": true,
+				"main-fields":           true,
+				"mangle-cache":          true,
+				"mangle-props":          true,
+				"mangle-quoted":         true,
+				"metafile":              true,
+				"minify-identifiers":    true,
+				"minify-syntax":         true,
+				"minify-whitespace":     true,
+				"minify":                true,
+				"outbase":               true,
+				"outdir":                true,
+				"outfile":               true,
+				"packages":              true,
+				"platform":              true,
+				"preserve-symlinks":     true,
+				"public-path":           true,
+				"reserve-props":         true,
+				"resolve-extensions":    true,
+				"serve-fallback":        true,
+				"serve":                 true,
+				"servedir":              true,
+				"source-root":           true,
+				"sourcefile":            true,
+				"sourcemap":             true,
+				"sources-content":       true,
+				"splitting":             true,
+				"target":                true,
+				"tree-shaking":          true,
+				"tsconfig-raw":          true,
+				"tsconfig":              true,
+				"watch":                 true,
+				"watch-delay":           true,
 			}
 
 			colon := map[string]bool{
@@ -943,9 +1047,11 @@ func parseOptionsImpl(
 				"inject":        true,
 				"loader":        true,
 				"log-override":  true,
+				"manual-chunk":  true,
 				"out-extension": true,
 				"pure":          true,
 				"supported":     true,
+				"umd-external":  true,
 			}
 
 			note := ""
@@ -1125,6 +1231,28 @@ func splitWithEmptyCheck(s string, sep string) []string {
 	return strings.Split(s, sep)
 }
 
+// This parses either "Global" or "amd:id,cjs:name,global:Global"
+func parseUMDExternal(text string) (api.UMDExternal, bool) {
+	var external api.UMDExternal
+	if text != "" && !strings.ContainsRune(text, ':') {
+		external.Global = text
+		return external, true
+	}
+	for _, part := range strings.Split(text, ",") {
+		switch {
+		case strings.HasPrefix(part, "amd:"):
+			external.AMD = part[len("amd:"):]
+		case strings.HasPrefix(part, "cjs:"):
+			external.CommonJS = part[len("cjs:"):]
+		case strings.HasPrefix(part, "global:"):
+			external.Global = part[len("global:"):]
+		default:
+			return api.UMDExternal{}, false
+		}
+	}
+	return external, true
+}
+
 type analyzeMode uint8
 
 const (
@@ -1165,6 +1165,22 @@ tests.push(
   }),
 )
 
+// Check named capture group lowering
+for (const target of ['--target=es5', '--target=es2017']) {
+  tests.push(test(['in.js', '--outfile=node.js', target], {
+    'in.js': `
+      var re = /(?<year>\\d{4})-(?<month>\\d{2})/g
+      var str = '2020-01 2021-02'
+      if (str.replace(re, '$<month>/$<year>$$<year>$<nope>') !== '01/2020$<year> 02/2021$<year>') throw 'fail: replace string'
+      if (str.replace(re, '$<year>0') !== '20200 20210') throw 'fail: replace digit'
+      if (str.replace(re, function () { return arguments[arguments.length - 1].month }) !== '01 02') throw 'fail: replace function'
+      var all = Array.from(str.matchAll(re), function (m) { return m.groups.year + m.groups.month + '@' + m.index })
+      if (all.join() !== '202001@0,202102@8') throw 'fail: matchAll'
+      if (re.exec(str).groups.year !== '2020' || str.match(/(?<x>\\d)/).groups.x !== '2') throw 'fail: exec'
+    `,
+  }))
+}
+
 // Check big integer lowering
 for (const minify of [[], '--minify']) {
   for (const target of [[], ['--target=es6']]) {
@@ -8515,6 +8531,76 @@ tests.push(
   }),
 )
 
+// Deferred import tests
+for (const format of ['esm', 'cjs', 'iife']) {
+  tests.push(
+    test(['in.js', '--outfile=node.js', '--bundle', '--format=' + format], {
+      'in.js': `
+        import defer * as esm from './esm.js'
+        import defer * as cjs from './cjs.js'
+        globalThis.deferTrace = []
+        deferTrace.push(1)
+        if (Object.prototype.toString.call(esm) !== '[object Deferred Module]' || esm.then !== void 0) throw 'fail'
+        deferTrace.push(2)
+        if (esm.foo !== 123 || cjs.bar !== 234) throw 'fail'
+        import.defer('./dyn.js').then(ns => {
+          deferTrace.push(5)
+          if (ns.baz !== 345 || deferTrace.join(',') !== '1,2,3,4,5,6') throw 'fail'
+        })
+      `,
+      'esm.js': `deferTrace.push(3); export let foo = 123`,
+      'cjs.js': `deferTrace.push(4); exports.bar = 234`,
+      'dyn.js': `deferTrace.push(6); export let baz = 345`,
+    }),
+
+    // Without "Proxy" and "Symbol", the module is evaluated immediately instead
+    test(['in.js', '--outfile=node.js', '--bundle', '--format=' + format, '--banner:js=var Proxy, Symbol;'], {
+      'in.js': `
+        import defer * as esm from './esm.js'
+        if (globalThis.deferTrace.join(',') !== '1' || esm.foo !== 123) throw 'fail'
+      `,
+      'esm.js': `(globalThis.deferTrace = []).push(1); export let foo = 123`,
+    }),
+  )
+}
+
+// Lowered top-level await tests
+tests.push(
+  test(['in.js', '--outfile=out.js', '--bundle', '--format=cjs', '--lower-top-level-await'], {
+    'in.js': `import { value } from './async.js'; export { value }`,
+    'async.js': `export let value = await new Promise(resolve => setTimeout(() => resolve(123), 10))`,
+    'node.js': `
+      exports.async = async () => {
+        const out = require('./out')
+        if (out.value !== void 0 || Object.keys(out).join(',') !== 'value') throw 'fail'
+        if (await out.__tla !== out || out.value !== 123) throw 'fail'
+      }
+    `,
+  }, { async: true }),
+  test(['in.js', '--outfile=out.js', '--bundle', '--format=iife', '--global-name=lib', '--lower-top-level-await'], {
+    'in.js': `import { value } from './async.js'; export { value }`,
+    'async.js': `export let value = await new Promise(resolve => setTimeout(() => resolve(123), 10))`,
+    'node.js': `
+      exports.async = async () => {
+        const code = require('fs').readFileSync(__dirname + '/out.js', 'utf8')
+        const lib = new Function(code + '; return lib')()
+        if (lib.value !== void 0) throw 'fail'
+        if (await lib.__tla !== lib || lib.value !== 123) throw 'fail'
+      }
+    `,
+  }, { async: true }),
+  test(['in.js', '--outfile=out.js', '--bundle', '--format=cjs', '--lower-top-level-await'], {
+    'in.js': `await Promise.reject(new Error('boom'))`,
+    'node.js': `
+      exports.async = async () => {
+        const out = require('./out')
+        try { await out.__tla } catch (e) { if (e.message === 'boom') return }
+        throw 'fail'
+      }
+    `,
+  }, { async: true }),
+)
+
 // Test the alias feature
 tests.push(
   test(['in.js', '--outfile=node.js', '--bundle', '--alias:foo=./bar/baz'], {